@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/boring-registry/boring-registry/pkg/admin"
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/spf13/cobra"
+)
+
+var flagAnnotations annotationChanges
+
+func init() {
+	rootCmd.AddCommand(annotateCmd)
+	annotateCmd.AddCommand(annotateModuleCmd, annotateProviderCmd)
+
+	annotateCmd.PersistentFlags().BoolVar(&flagAnnotations.deprecate, "deprecate", false, "Mark the version as deprecated")
+	annotateCmd.PersistentFlags().BoolVar(&flagAnnotations.undeprecate, "undeprecate", false, "Remove the deprecation of the version")
+	annotateCmd.PersistentFlags().StringVar(&flagAnnotations.reason, "deprecation-reason", "", "The reason of the deprecation, which implies --deprecate")
+	annotateCmd.PersistentFlags().StringVar(&flagAnnotations.link, "deprecation-link", "", "A link to the replacement of the deprecated version, which implies --deprecate")
+	annotateCmd.PersistentFlags().StringToStringVar(&flagAnnotations.labels, "label", nil, "Labels to set on the version in the form of key=value")
+	annotateCmd.PersistentFlags().StringSliceVar(&flagAnnotations.removeLabels, "remove-label", nil, "Keys of labels to remove from the version")
+	annotateCmd.PersistentFlags().BoolVar(&flagAnnotations.clear, "clear", false, "Remove all annotations of the version before applying the other flags")
+}
+
+var annotateCmd = &cobra.Command{
+	Use:   "annotate",
+	Short: "Manage the deprecation and labels of module and provider versions",
+	Long: `Manage the deprecation and labels of module and provider versions.
+The annotations of the version are printed as JSON, after the changes of the flags were applied`,
+}
+
+var annotateModuleCmd = &cobra.Command{
+	Use:          "module NAMESPACE/NAME/PROVIDER/VERSION",
+	Short:        "Manage the annotations of a module version",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, name, provider, version, err := parseModuleVersion(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		svc, err := annotationService(ctx)
+		if err != nil {
+			return err
+		}
+
+		return annotate(flagAnnotations,
+			func() (*core.Annotations, error) {
+				return svc.GetModuleAnnotations(ctx, namespace, name, provider, version)
+			},
+			func(a core.Annotations) (*core.Annotations, error) {
+				return svc.SetModuleAnnotations(ctx, namespace, name, provider, version, a)
+			},
+		)
+	},
+}
+
+var annotateProviderCmd = &cobra.Command{
+	Use:          "provider NAMESPACE/NAME/VERSION",
+	Short:        "Manage the annotations of a provider version",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		parts := strings.Split(args[0], "/")
+		if len(parts) != 3 || slices.Contains(parts, "") {
+			return fmt.Errorf("invalid provider version %q, expected <namespace>/<name>/<version>", args[0])
+		}
+		namespace, name, version := parts[0], parts[1], parts[2]
+
+		ctx := context.Background()
+		svc, err := annotationService(ctx)
+		if err != nil {
+			return err
+		}
+
+		return annotate(flagAnnotations,
+			func() (*core.Annotations, error) {
+				return svc.GetProviderAnnotations(ctx, namespace, name, version)
+			},
+			func(a core.Annotations) (*core.Annotations, error) {
+				return svc.SetProviderAnnotations(ctx, namespace, name, version, a)
+			},
+		)
+	},
+}
+
+func annotationService(ctx context.Context) (admin.Service, error) {
+	s, err := setupStorage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up storage: %w", err)
+	}
+	return admin.NewService(s), nil
+}
+
+// annotate applies the changes to the annotations of a version and prints the result
+func annotate(changes annotationChanges, get func() (*core.Annotations, error), set func(core.Annotations) (*core.Annotations, error)) error {
+	a, err := get()
+	if err != nil {
+		return err
+	}
+
+	if !changes.empty() {
+		updated, err := changes.apply(*a)
+		if err != nil {
+			return err
+		}
+		if a, err = set(updated); err != nil {
+			return err
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a)
+}
+
+// annotationChanges are the changes of the annotations of a version, which are set with the flags of the annotate command
+type annotationChanges struct {
+	deprecate    bool
+	undeprecate  bool
+	reason       string
+	link         string
+	labels       map[string]string
+	removeLabels []string
+	clear        bool
+}
+
+func (c annotationChanges) empty() bool {
+	return !c.deprecate && !c.undeprecate && c.reason == "" && c.link == "" && len(c.labels) == 0 && len(c.removeLabels) == 0 && !c.clear
+}
+
+// apply returns a copy of the annotations with the changes
+func (c annotationChanges) apply(a core.Annotations) (core.Annotations, error) {
+	deprecate := c.deprecate || c.reason != "" || c.link != ""
+	if deprecate && c.undeprecate {
+		return core.Annotations{}, errors.New("a version can't be deprecated and undeprecated at once")
+	}
+
+	var updated core.Annotations
+	if !c.clear {
+		if a.Deprecation != nil {
+			d := *a.Deprecation
+			updated.Deprecation = &d
+		}
+		for k, v := range a.Labels {
+			if updated.Labels == nil {
+				updated.Labels = map[string]string{}
+			}
+			updated.Labels[k] = v
+		}
+	}
+
+	if c.undeprecate {
+		updated.Deprecation = nil
+	} else if deprecate {
+		if updated.Deprecation == nil {
+			updated.Deprecation = &core.Deprecation{}
+		}
+		if c.reason != "" {
+			updated.Deprecation.Reason = c.reason
+		}
+		if c.link != "" {
+			updated.Deprecation.Link = c.link
+		}
+	}
+
+	for k, v := range c.labels {
+		if updated.Labels == nil {
+			updated.Labels = map[string]string{}
+		}
+		updated.Labels[k] = v
+	}
+	for _, k := range c.removeLabels {
+		delete(updated.Labels, k)
+	}
+	if len(updated.Labels) == 0 {
+		updated.Labels = nil
+	}
+
+	return updated, nil
+}
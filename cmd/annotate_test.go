@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotationChangesApply(t *testing.T) {
+	t.Parallel()
+
+	existing := core.Annotations{
+		Deprecation: &core.Deprecation{Reason: "use 2.x"},
+		Labels:      map[string]string{"team": "networking", "tier": "1"},
+	}
+
+	testCases := []struct {
+		name        string
+		changes     annotationChanges
+		expected    core.Annotations
+		expectedErr bool
+	}{
+		{
+			name:    "deprecation link keeps the reason",
+			changes: annotationChanges{link: "https://example.com/vpc/2.0.0"},
+			expected: core.Annotations{
+				Deprecation: &core.Deprecation{Reason: "use 2.x", Link: "https://example.com/vpc/2.0.0"},
+				Labels:      map[string]string{"team": "networking", "tier": "1"},
+			},
+		},
+		{
+			name:    "undeprecate and change labels",
+			changes: annotationChanges{undeprecate: true, labels: map[string]string{"tier": "2"}, removeLabels: []string{"team"}},
+			expected: core.Annotations{
+				Labels: map[string]string{"tier": "2"},
+			},
+		},
+		{
+			name:    "clear",
+			changes: annotationChanges{clear: true, deprecate: true},
+			expected: core.Annotations{
+				Deprecation: &core.Deprecation{},
+			},
+		},
+		{
+			name:        "deprecate and undeprecate",
+			changes:     annotationChanges{reason: "outdated", undeprecate: true},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.False(t, tc.changes.empty())
+
+			a, err := tc.changes.apply(existing)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, a)
+		})
+	}
+
+	// The existing annotations aren't modified
+	assert.Equal(t, "use 2.x", existing.Deprecation.Reason)
+	assert.Len(t, existing.Labels, 2)
+	assert.True(t, annotationChanges{}.empty())
+}
@@ -2,67 +2,94 @@ package cmd
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/module"
 
 	"github.com/hashicorp/go-version"
+	"github.com/ulikunitz/xz"
 )
 
 const (
 	moduleSpecFileName = "boring-registry.hcl"
 )
 
+// archiveModTime is the modification time of all files in module archives.
+// It's the earliest time that can be represented in zip archives.
+var archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// archiveModules discovers the module specs in root and publishes the modules concurrently
 func archiveModules(root string, storage module.Storage) error {
+	paths := []string{filepath.Join(root, moduleSpecFileName)}
 	if flagRecursive {
+		paths = nil
 		err := filepath.Walk(root, func(path string, fi os.FileInfo, _ error) error {
 			// FYI we conciously ignore all walk-related errors
 
-			if fi.Name() != moduleSpecFileName {
+			if fi == nil || fi.Name() != moduleSpecFileName {
 				return nil
 			}
-			if processErr := processModule(path, storage); processErr != nil {
-				return fmt.Errorf("failed to process module at %s:\n%w", path, processErr)
-			}
-
+			paths = append(paths, path)
 			return nil
 		})
-		return err
+		if err != nil {
+			return err
+		}
 	}
 
-	path := filepath.Join(root, moduleSpecFileName)
-	if processErr := processModule(path, storage); processErr != nil {
-		return fmt.Errorf("failed to process module at %s:\n%w", path, processErr)
+	p := newPublisher(flagParallelism)
+	for _, path := range paths {
+		path := path
+		p.Go(path, func() (*publishResult, error) {
+			return processModule(path, storage)
+		})
 	}
-	return nil
+	return p.Wait()
 }
 
-func processModule(path string, storage module.Storage) error {
+func processModule(path string, storage module.Storage) (*publishResult, error) {
 	spec, err := module.ParseFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	slog.Debug("parsed module spec", slog.String("path", path), slog.String("name", spec.Name()))
 
+	moduleRoot := filepath.Dir(path)
+	return publishModule(spec, os.DirFS(moduleRoot), func(filter *module.FileFilter) ([]string, error) {
+		return moduleFiles(moduleRoot, filter)
+	}, storage)
+}
+
+// publishModule uploads a module version with the files of fsys, unless it doesn't meet the version constraints or already exists.
+// The files to archive are listed with the filter of the module and only archived if the module is uploaded.
+// Existing versions are compared by the checksum of their files, and errModuleChanged is returned if the content changed.
+func publishModule(spec *module.Spec, fsys fs.FS, listFiles func(*module.FileFilter) ([]string, error), storage module.Storage) (*publishResult, error) {
+	result := &publishResult{Module: spec.Name(), Status: publishSkipped}
+
 	// Check if the module meets version constraints
 	if versionConstraintsSemver != nil {
 		ok, err := meetsSemverConstraints(spec)
 		if err != nil {
-			return err
+			return result, err
 		} else if !ok {
 			// Skip the module, as it didn't pass the version constraints
 			slog.Info("module doesn't meet semver version constraints, skipped", slog.String("name", spec.Name()))
-			return nil
+			result.Reason = "doesn't meet the semver version constraints"
+			return result, nil
 		}
 	}
 
@@ -70,52 +97,127 @@ func processModule(path string, storage module.Storage) error {
 		if !meetsRegexConstraints(spec) {
 			// Skip the module, as it didn't pass the regex version constraints
 			slog.Info("module doesn't meet regex version constraints, skipped", slog.String("name", spec.Name()))
-			return nil
+			result.Reason = "doesn't meet the regex version constraints"
+			return result, nil
+		}
+	}
+
+	filter, err := module.NewFileFilter(fsys, spec)
+	if err != nil {
+		return result, err
+	}
+	files, err := listFiles(filter)
+	if err != nil {
+		return result, err
+	}
+	if result.Checksum, err = module.Checksum(fsys, files); err != nil {
+		return result, err
+	}
+
+	if flagDryRun {
+		var listing strings.Builder
+		fmt.Fprintf(&listing, "%s (%s)\n", spec.Name(), moduleArchiveFormat(spec))
+		for _, f := range files {
+			fmt.Fprintf(&listing, "  %s\n", f)
 		}
+		result.listing = listing.String()
+		result.Reason = "dry run"
+		return result, nil
 	}
 
 	ctx := context.Background()
 	if res, err := storage.GetModule(ctx, spec.Metadata.Namespace, spec.Metadata.Name, spec.Metadata.Provider, spec.Metadata.Version); err == nil {
+		result.DownloadURL = res.DownloadURL
+
+		// The checksum is only known for versions that were uploaded with it
+		existing, err := storage.GetModuleDetails(ctx, spec.Metadata.Namespace, spec.Metadata.Name, spec.Metadata.Provider, spec.Metadata.Version)
+		if err != nil && !errors.Is(err, module.ErrModuleDetailsNotFound) {
+			return result, err
+		}
+		verified := existing != nil && existing.Checksum != ""
+		if verified && existing.Checksum != result.Checksum {
+			slog.Error("module content changed without a version bump", slog.String("name", spec.Name()), slog.String("checksum", result.Checksum), slog.String("stored_checksum", existing.Checksum))
+			return result, fmt.Errorf("%w: the checksum is %s, but %s is stored", errModuleChanged, result.Checksum, existing.Checksum)
+		} else if !verified {
+			slog.Warn("module was uploaded without a checksum, its content can't be compared", slog.String("name", spec.Name()), slog.String("checksum", result.Checksum))
+		}
+
 		if flagIgnoreExistingModule {
 			slog.Info("module already exists", slog.String("download_url", res.DownloadURL))
-			return nil
+			result.Reason = "unchanged"
+			if !verified {
+				result.Reason = "not compared, as no checksum is stored"
+			}
+			return result, nil
 		} else {
 			slog.Error("module already exists", slog.String("download_url", res.DownloadURL))
-			return errors.New("module already exists")
+			return result, errors.New("module already exists")
 		}
 	}
 
-	moduleRoot := filepath.Dir(path)
+	details, err := module.ParseDetails(fsys, files)
+	if err != nil {
+		return result, fmt.Errorf("failed to parse module details: %w", err)
+	}
+	details.Checksum = result.Checksum
 
-	buf, err := archiveModule(moduleRoot)
+	docs, err := module.ParseDocs(fsys, files)
 	if err != nil {
-		return err
+		return result, fmt.Errorf("failed to capture module documentation: %w", err)
 	}
 
-	res, err := storage.UploadModule(ctx, spec.Metadata.Namespace, spec.Metadata.Name, spec.Metadata.Provider, spec.Metadata.Version, buf)
+	format := moduleArchiveFormat(spec)
+	buf, err := archiveModule(fsys, files, format)
 	if err != nil {
-		return err
+		return result, err
+	}
+
+	res, err := storage.UploadModule(ctx, spec.Metadata.Namespace, spec.Metadata.Name, spec.Metadata.Provider, spec.Metadata.Version, format, buf)
+	if err != nil {
+		return result, err
+	}
+	if err := uploadModuleSidecars(ctx, storage, spec.Metadata, details, docs); err != nil {
+		return result, err
 	}
 
 	slog.Info("module successfully uploaded", slog.String("download_url", res.DownloadURL))
 
+	result.Status = publishUploaded
+	result.DownloadURL = res.DownloadURL
+	return result, nil
+}
+
+// uploadModuleSidecars uploads the details, documentation and dependencies of a module version, once its archive was stored.
+// Only the upload that stored the archive writes the sidecars, so that neither a failed nor a concurrent upload of the version changes them.
+func uploadModuleSidecars(ctx context.Context, storage module.Storage, m module.Metadata, details *module.Details, docs *module.Docs) error {
+	if err := storage.UploadModuleDetails(ctx, m.Namespace, m.Name, m.Provider, m.Version, details); err != nil {
+		return fmt.Errorf("failed to upload module details: %w", err)
+	}
+	if err := storage.UploadModuleDocs(ctx, m.Namespace, m.Name, m.Provider, m.Version, docs); err != nil {
+		return fmt.Errorf("failed to upload module documentation: %w", err)
+	}
+	if err := storage.UploadModuleDependencies(ctx, m.Namespace, m.Name, m.Provider, m.Version, details.Dependencies()); err != nil {
+		return fmt.Errorf("failed to upload module dependencies: %w", err)
+	}
 	return nil
+}
 
+// moduleArchiveFormat returns the archive format of the spec, which takes precedence over the --archive-format flag
+func moduleArchiveFormat(spec *module.Spec) string {
+	if spec.Package != nil && spec.Package.Format != "" {
+		return spec.Package.Format
+	}
+	return flagArchiveFormat
 }
 
-func archiveModule(root string) (io.Reader, error) {
-	buf := new(bytes.Buffer)
+// moduleFiles lists the regular files of the module in the directory root that pass the filter
+func moduleFiles(root string, filter *module.FileFilter) ([]string, error) {
 	// ensure the src actually exists before trying to tar it
 	if _, err := os.Stat(root); err != nil {
-		return buf, fmt.Errorf("unable to tar files - %v", err.Error())
+		return nil, fmt.Errorf("unable to tar files - %v", err.Error())
 	}
 
-	gw := gzip.NewWriter(buf)
-	defer gw.Close()
-
-	tw := tar.NewWriter(gw)
-	defer tw.Close()
-
+	var files []string
 	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
 		// return on any error
 		if err != nil {
@@ -127,36 +229,157 @@ func archiveModule(root string) (io.Reader, error) {
 			return nil
 		}
 
-		// create a new dir/file header
-		header, err := tar.FileInfoHeader(fi, fi.Name())
+		// update the name to correctly reflect the desired destination when untaring
+		name := filepath.ToSlash(archiveFileHeaderName(path, root))
+		if filter.Match(name) {
+			files = append(files, name)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// moduleFilesFS lists the regular files of fsys that pass the filter, e.g. of the tree of a git tag
+func moduleFilesFS(fsys fs.FS, filter *module.FileFilter) ([]string, error) {
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// update the name to correctly reflect the desired destination when untaring
-		header.Name = archiveFileHeaderName(path, root)
+		// return on non-regular files
+		if !d.Type().IsRegular() {
+			return nil
+		}
 
-		if err := tw.WriteHeader(header); err != nil {
-			return err
+		if filter.Match(path) {
+			files = append(files, path)
 		}
+		return nil
+	})
+
+	return files, err
+}
+
+// archiveModule creates a reproducible archive of the files of fsys in the given format.
+// The entries are sorted and their metadata is normalised, so that the same files always result in the same archive.
+func archiveModule(fsys fs.FS, files []string, format string) (io.Reader, error) {
+	files = append([]string{}, files...)
+	sort.Strings(files)
+
+	buf := new(bytes.Buffer)
+	var err error
+	switch format {
+	case "tar.gz", "tgz":
+		err = writeTarGz(buf, fsys, files)
+	case "tar":
+		err = writeTar(buf, fsys, files)
+	case "tar.xz", "txz":
+		err = writeTarXz(buf, fsys, files)
+	case "zip":
+		err = writeZip(buf, fsys, files)
+	default:
+		return nil, fmt.Errorf("module archives can't be created in the %s format", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func writeTarGz(w io.Writer, fsys fs.FS, files []string) error {
+	gw := gzip.NewWriter(w)
+	if err := writeTar(gw, fsys, files); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// xzConfig are the fixed settings of the xz compression, so that the same tar archive always results in the same tar.xz archive
+var xzConfig = xz.WriterConfig{
+	DictCap:   8 << 20,
+	BlockSize: 32 << 20,
+	CheckSum:  xz.CRC64,
+}
+
+func writeTarXz(w io.Writer, fsys fs.FS, files []string) error {
+	xw, err := xzConfig.NewWriter(w)
+	if err != nil {
+		return fmt.Errorf("failed to create the xz writer: %w", err)
+	}
+	if err := writeTar(xw, fsys, files); err != nil {
+		return err
+	}
+	return xw.Close()
+}
 
-		data, err := os.Open(path)
+func writeTar(w io.Writer, fsys fs.FS, files []string) error {
+	tw := tar.NewWriter(w)
+	for _, name := range files {
+		err := copyArchiveFile(fsys, name, func(fi fs.FileInfo) (io.Writer, error) {
+			header := &tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     name,
+				Size:     fi.Size(),
+				Mode:     int64(archiveFileMode(fi)),
+				ModTime:  archiveModTime,
+			}
+			return tw, tw.WriteHeader(header)
+		})
 		if err != nil {
 			return err
 		}
+	}
+	return tw.Close()
+}
 
-		if _, err := io.Copy(tw, data); err != nil {
+func writeZip(w io.Writer, fsys fs.FS, files []string) error {
+	zw := zip.NewWriter(w)
+	for _, name := range files {
+		err := copyArchiveFile(fsys, name, func(fi fs.FileInfo) (io.Writer, error) {
+			header := &zip.FileHeader{
+				Name:     name,
+				Method:   zip.Deflate,
+				Modified: archiveModTime,
+			}
+			header.SetMode(archiveFileMode(fi))
+			return zw.CreateHeader(header)
+		})
+		if err != nil {
 			return err
 		}
+	}
+	return zw.Close()
+}
 
-		// manually close here after each file operation; deferring would cause each file close
-		// to wait until all operations have completed.
-		data.Close()
+// copyArchiveFile copies the file into the writer for its archive entry
+func copyArchiveFile(fsys fs.FS, name string, entry func(fs.FileInfo) (io.Writer, error)) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-		return nil
-	})
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
 
-	return buf, err
+	w, err := entry(fi)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// archiveFileMode only preserves the executable bit, as the owner, permissions and modification time differ between checkouts
+func archiveFileMode(fi fs.FileInfo) fs.FileMode {
+	if fi.Mode().Perm()&0o111 != 0 {
+		return 0o755
+	}
+	return 0o644
 }
 
 // meetsSemverConstraints checks whether a module version matches the semver version constraints.
@@ -1,9 +1,21 @@
 package cmd
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/module"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/ulikunitz/xz"
 )
 
 func TestArchiveFileHeaderName(t *testing.T) {
@@ -55,3 +67,90 @@ func TestArchiveFileHeaderName(t *testing.T) {
 	}
 
 }
+
+func TestArchiveModule(t *testing.T) {
+	t.Parallel()
+
+	write := func(root string, modTime time.Time) {
+		for name, content := range map[string]string{
+			"main.tf":             `resource "null_resource" "this" {}`,
+			"modules/vpc/main.tf": `variable "cidr" {}`,
+			"terraform.tfvars":    `cidr = "10.0.0.0/16"`,
+			".git/config":         "[core]",
+			".terraformignore":    "modules/vpc/test/",
+			"modules/vpc/test/a":  "fixture",
+		} {
+			p := filepath.Join(root, filepath.FromSlash(name))
+			assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+			assert.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+			assert.NoError(t, os.Chtimes(p, modTime, modTime))
+		}
+	}
+
+	archive := func(root, format string) ([]byte, []string) {
+		filter, err := module.NewFileFilter(os.DirFS(root), nil)
+		assert.NoError(t, err)
+		files, err := moduleFiles(root, filter)
+		assert.NoError(t, err)
+		r, err := archiveModule(os.DirFS(root), files, format)
+		assert.NoError(t, err)
+		b, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		return b, files
+	}
+
+	first, second := t.TempDir(), t.TempDir()
+	write(first, time.Now())
+	write(second, time.Now().Add(-time.Hour))
+
+	for _, format := range []string{"tar.gz", "tar", "zip", "tar.xz"} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			a, files := archive(first, format)
+			b, _ := archive(second, format)
+			assert.Equal(t, []string{".terraformignore", "main.tf", "modules/vpc/main.tf"}, files)
+			assert.Equal(t, a, b, "archives of the same files should be identical")
+
+			var names []string
+			switch format {
+			case "zip":
+				zr, err := zip.NewReader(bytes.NewReader(a), int64(len(a)))
+				assert.NoError(t, err)
+				for _, f := range zr.File {
+					assert.Equal(t, fs.FileMode(0o644), f.Mode())
+					assert.True(t, f.Modified.Equal(archiveModTime))
+					names = append(names, f.Name)
+				}
+			default:
+				var r io.Reader = bytes.NewReader(a)
+				switch format {
+				case "tar.gz":
+					gr, err := gzip.NewReader(r)
+					assert.NoError(t, err)
+					r = gr
+				case "tar.xz":
+					xr, err := xz.NewReader(r)
+					assert.NoError(t, err)
+					r = xr
+				}
+				tr := tar.NewReader(r)
+				for {
+					header, err := tr.Next()
+					if err == io.EOF {
+						break
+					}
+					assert.NoError(t, err)
+					assert.Equal(t, int64(0o644), header.Mode)
+					assert.Equal(t, 0, header.Uid)
+					assert.Equal(t, "", header.Uname)
+					assert.True(t, header.ModTime.Equal(archiveModTime))
+					names = append(names, header.Name)
+				}
+			}
+			assert.Equal(t, files, names)
+		})
+	}
+
+	_, err := archiveModule(os.DirFS(first), nil, "tar.bz2")
+	assert.Error(t, err)
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// configFile is the configuration file given with --config, which is nil if none is given
+var configFile *config.Config
+
+// flagProviderNetworkMirrorUpstreams can only be configured in the configuration file, as it's structured
+var flagProviderNetworkMirrorUpstreams []config.Upstream
+
+// flagReplication can only be configured in the configuration file, as its destination is another storage backend
+var flagReplication *config.Replication
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the configuration file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration file given with --config",
+	Long: `Validate the configuration file given with --config against the schema of the configuration.
+The file is validated before any command runs, so that this command only has to report the result`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagConfig == "" {
+			return fmt.Errorf("no configuration file given, set --config")
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", flagConfig)
+		return nil
+	},
+}
+
+// configFlags applies the configuration file to the flags, which haven't been set explicitly by flags or environment variables
+type configFlags struct {
+	flags *pflag.FlagSet
+}
+
+func (f configFlags) explicit(name string) bool {
+	flag := f.flags.Lookup(name)
+	return flag != nil && flag.Changed
+}
+
+// string applies the value, if it's set in the configuration file
+func (f configFlags) string(name string, dst *string, value string) {
+	if value != "" && !f.explicit(name) {
+		*dst = value
+	}
+}
+
+func (f configFlags) bool(name string, dst *bool, value *bool) {
+	if value != nil && !f.explicit(name) {
+		*dst = *value
+	}
+}
+
+func (f configFlags) duration(name string, dst *time.Duration, value string) {
+	if value != "" && !f.explicit(name) {
+		*dst = config.Duration(value)
+	}
+}
+
+// strings always applies the value, so that a reloaded configuration file can remove all values
+func (f configFlags) strings(name string, dst *[]string, value []string) {
+	if !f.explicit(name) {
+		*dst = value
+	}
+}
+
+// loadConfigFile loads the configuration file given with --config and applies it to the flags
+func loadConfigFile(cmd *cobra.Command) error {
+	if flagConfig == "" {
+		return nil
+	}
+
+	c, err := config.Load(flagConfig)
+	if err != nil {
+		return err
+	}
+
+	f := configFlags{flags: cmd.Flags()}
+	applyConfig(f, c)
+	applyReloadableConfig(f, c)
+	configFile = c
+	return nil
+}
+
+// applyConfig applies the settings that require a restart to take effect
+func applyConfig(f configFlags, c *config.Config) {
+	f.bool("json", &flagJSON, c.Log.JSON)
+
+	if s3 := c.Storage.S3; s3 != nil {
+		f.string("storage-s3-bucket", &flagS3Bucket, s3.Bucket)
+		f.string("storage-s3-prefix", &flagS3Prefix, s3.Prefix)
+		f.string("storage-s3-region", &flagS3Region, s3.Region)
+		f.string("storage-s3-endpoint", &flagS3Endpoint, s3.Endpoint)
+		f.bool("storage-s3-pathstyle", &flagS3PathStyle, s3.PathStyle)
+		f.duration("storage-s3-signedurl-expiry", &flagS3SignedURLExpiry, s3.SignedURLExpiry)
+	}
+	if gcs := c.Storage.GCS; gcs != nil {
+		f.string("storage-gcs-bucket", &flagGCSBucket, gcs.Bucket)
+		f.string("storage-gcs-prefix", &flagGCSPrefix, gcs.Prefix)
+		f.string("storage-gcs-sa-email", &flagGCSServiceAccount, gcs.ServiceAccount)
+		f.duration("storage-gcs-signedurl-expiry", &flagGCSSignedURLExpiry, gcs.SignedURLExpiry)
+	}
+	if azure := c.Storage.Azure; azure != nil {
+		f.string("storage-azure-account", &flagAzureStorageAccount, azure.Account)
+		f.string("storage-azure-container", &flagAzureStorageContainer, azure.Container)
+		f.string("storage-azure-prefix", &flagAzureStoragePrefix, azure.Prefix)
+		f.duration("storage-azure-signedurl-expiry", &flagAzureStorageSignedURLExpiry, azure.SignedURLExpiry)
+	}
+	if cache := c.Storage.SignedURLCache; cache != nil {
+		f.duration("storage-signedurl-cache-min-validity", &flagStorageSignedURLCacheMinValidity, cache.MinValidity)
+	}
+	if cdn := c.Storage.CDN; cdn != nil {
+		f.string("storage-cdn-url", &flagStorageCDNURL, cdn.URL)
+		f.string("storage-cdn-signer", &flagStorageCDNSigner, cdn.Signer)
+		f.string("storage-cdn-key-id", &flagStorageCDNKeyID, cdn.KeyID)
+		f.string("storage-cdn-key-file", &flagStorageCDNKeyFile, cdn.KeyFile)
+	}
+
+	f.bool("network-mirror", &flagProviderNetworkMirrorEnabled, c.Mirror.Enabled)
+	f.bool("network-mirror-pull-through", &flagProviderNetworkMirrorPullThroughEnabled, c.Mirror.PullThrough)
+	flagProviderNetworkMirrorUpstreams = c.Mirror.Upstreams
+	flagReplication = c.Replication
+
+	if replica := c.Replica; replica != nil {
+		f.string("replica-upstream", &flagReplicaUpstream, replica.Upstream)
+		f.string("replica-upstream-token", &flagReplicaUpstreamToken, replica.Token)
+		f.duration("replica-upstream-timeout", &flagReplicaUpstreamTimeout, replica.Timeout)
+		f.string("replica-trusted-keys-file", &flagReplicaTrustedKeysFile, replica.TrustedKeysFile)
+	}
+
+	if login := c.Login; login != nil {
+		f.string("login-client", &flagLoginClient, login.Client)
+		f.string("login-authz", &flagLoginAuthz, login.Authz)
+		f.string("login-token", &flagLoginToken, login.Token)
+		if len(login.GrantTypes) > 0 && !f.explicit("login-grant-types") {
+			flagLoginGrantTypes = login.GrantTypes
+		}
+		if len(login.Ports) > 0 && !f.explicit("login-ports") {
+			flagLoginPorts = login.Ports
+		}
+		if len(login.Scopes) > 0 && !f.explicit("login-scopes") {
+			flagLoginScopes = login.Scopes
+		}
+	}
+}
+
+// applyReloadableConfig applies the settings that are reloaded on SIGHUP
+func applyReloadableConfig(f configFlags, c *config.Config) {
+	if c.Log.Level != "" && !flagDebug {
+		var level slog.Level
+		// The level has been validated already
+		_ = level.UnmarshalText([]byte(c.Log.Level))
+		logLevel.Set(level)
+	}
+
+	f.strings("auth-static-token", &flagAuthStaticTokens, c.Auth.StaticTokens)
+	var oktaIssuer string
+	var oktaClaims []string
+	if okta := c.Auth.Okta; okta != nil {
+		oktaIssuer = okta.Issuer
+		for key, value := range okta.Claims {
+			oktaClaims = append(oktaClaims, fmt.Sprintf("%s=%s", key, value))
+		}
+		sort.Strings(oktaClaims)
+	}
+	if !f.explicit("auth-okta-issuer") {
+		flagAuthOktaIssuer = oktaIssuer
+	}
+	f.strings("auth-okta-claims", &flagAuthOktaClaims, oktaClaims)
+	f.strings("admin-static-token", &flagAdminStaticTokens, c.Admin.StaticTokens)
+
+	p := c.Mirror.Policy
+	f.strings("network-mirror-allow", &flagProviderNetworkMirrorAllow, p.Allow)
+	f.strings("network-mirror-deny", &flagProviderNetworkMirrorDeny, p.Deny)
+	if !f.explicit("network-mirror-version-constraints") {
+		flagProviderNetworkMirrorVersionConstraints = p.VersionConstraints
+	}
+	// Pre-releases are allowed by default, which has to be restored when the setting is removed from the file
+	allowPrereleases := true
+	if p.AllowPrereleases != nil {
+		allowPrereleases = *p.AllowPrereleases
+	}
+	f.bool("network-mirror-allow-prereleases", &flagProviderNetworkMirrorAllowPrereleases, &allowPrereleases)
+	f.strings("network-mirror-platforms", &flagProviderNetworkMirrorPlatforms, p.Platforms)
+}
+
+// reloadConfigFile applies the reloadable settings of the configuration file to the tenants of the running server.
+// The previous settings are kept, if the configuration file is invalid.
+func reloadConfigFile(cmd *cobra.Command) error {
+	c, err := config.Load(flagConfig)
+	if err != nil {
+		return err
+	}
+
+	f := configFlags{flags: cmd.Flags()}
+	previous, level := flagsConfig(), logLevel.Level()
+	applyReloadableConfig(f, c)
+
+	if err := reloadTenants(c); err != nil {
+		applyReloadableConfig(f, previous)
+		logLevel.Set(level)
+		return err
+	}
+	configFile = c
+	return nil
+}
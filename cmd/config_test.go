@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/boring-registry/boring-registry/pkg/config"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyReloadableConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	authTokens, adminTokens, allowPrereleases, level := flagAuthStaticTokens, flagAdminStaticTokens, flagProviderNetworkMirrorAllowPrereleases, logLevel.Level()
+	t.Cleanup(func() {
+		flagAuthStaticTokens, flagAdminStaticTokens, flagProviderNetworkMirrorAllowPrereleases = authTokens, adminTokens, allowPrereleases
+		logLevel.Set(level)
+	})
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringSliceVar(&flagAuthStaticTokens, "auth-static-token", nil, "")
+	flags.StringSliceVar(&flagAdminStaticTokens, "admin-static-token", nil, "")
+	flags.BoolVar(&flagProviderNetworkMirrorAllowPrereleases, "network-mirror-allow-prereleases", true, "")
+	if err := flags.Set("admin-static-token", "explicit"); err != nil {
+		t.Fatal(err)
+	}
+
+	prereleases := false
+	c := &config.Config{
+		Log:   &config.Log{Level: "error"},
+		Auth:  &config.Auth{StaticTokens: []string{"foo"}},
+		Admin: &config.Admin{StaticTokens: []string{"admin"}},
+		Mirror: &config.Mirror{
+			Policy: &config.Policy{AllowPrereleases: &prereleases},
+		},
+	}
+	applyReloadableConfig(configFlags{flags: flags}, c)
+
+	assert.Equal([]string{"foo"}, flagAuthStaticTokens)
+	// Flags that are set explicitly take precedence over the configuration file
+	assert.Equal([]string{"explicit"}, flagAdminStaticTokens)
+	assert.False(flagProviderNetworkMirrorAllowPrereleases)
+	assert.Equal(slog.LevelError, logLevel.Level())
+
+	// Settings that are removed from the file fall back to the defaults of the flags
+	c.Auth.StaticTokens = nil
+	c.Mirror.Policy.AllowPrereleases = nil
+	applyReloadableConfig(configFlags{flags: flags}, c)
+
+	assert.Nil(flagAuthStaticTokens)
+	assert.True(flagProviderNetworkMirrorAllowPrereleases)
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/boring-registry/boring-registry/pkg/module"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/cobra"
+)
+
+var moduleDependentsCmd = &cobra.Command{
+	Use:   "dependents NAMESPACE/NAME/PROVIDER",
+	Short: "Report the module versions that depend on a module",
+	Long: `Report the module versions that depend on a module.
+The version constraint of every dependent is resolved against the stored versions of the module,
+and dependents that resolve to a version that is deprecated by its annotations or by --deprecated-versions are flagged`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, name, provider, err := parseModule(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		s, err := setupStorage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to set up storage: %w", err)
+		}
+
+		report, err := dependentsReport(ctx, s, namespace, name, provider, flagDependentsDeprecatedVersions)
+		if err != nil {
+			return err
+		}
+		if err := writeDependentsReport(os.Stdout, report); err != nil {
+			return err
+		}
+
+		if flagDependentsFailOnDeprecated {
+			for _, d := range report {
+				if d.Deprecated {
+					return fmt.Errorf("dependents of %s/%s/%s are pinned to deprecated versions", namespace, name, provider)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// dependentReport is a dependent of a module with the version its constraint resolves to
+type dependentReport struct {
+	module.ModuleDependent
+	// Resolved is the newest version of the module that meets the version constraint, it's empty if no version does
+	Resolved   string
+	Deprecated bool
+}
+
+// dependentsReport resolves the version constraints of the dependents of a module against its stored versions
+func dependentsReport(ctx context.Context, storage module.Storage, namespace, name, provider string, deprecated []string) ([]dependentReport, error) {
+	dependents, err := storage.ListModuleDependents(ctx, namespace, name, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions version.Collection
+	if len(dependents) > 0 {
+		modules, err := storage.ListModuleVersions(ctx, namespace, name, provider)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range modules {
+			if v, err := version.NewVersion(m.Version); err == nil {
+				versions = append(versions, v)
+			}
+		}
+		// The newest version is resolved first
+		slices.SortFunc(versions, func(a, b *version.Version) int { return b.Compare(a) })
+	}
+
+	// Versions are deprecated by their annotations or by the flag
+	annotations, err := storage.GetModuleAnnotations(ctx, namespace, name, provider)
+	if err != nil {
+		return nil, err
+	}
+	for v, a := range annotations {
+		if a.Deprecation != nil {
+			deprecated = append(deprecated, v)
+		}
+	}
+
+	var deprecatedVersions []*version.Version
+	for _, s := range deprecated {
+		v, err := version.NewVersion(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deprecated version %q: %w", s, err)
+		}
+		deprecatedVersions = append(deprecatedVersions, v)
+	}
+
+	report := make([]dependentReport, 0, len(dependents))
+	for _, d := range dependents {
+		r := dependentReport{ModuleDependent: d}
+
+		// Module calls without a version constraint use the newest version
+		var constraints version.Constraints
+		if d.VersionConstraint != "" {
+			if constraints, err = version.NewConstraint(d.VersionConstraint); err != nil {
+				return nil, fmt.Errorf("invalid version constraint of %s/%s/%s/%s: %w", d.Namespace, d.Name, d.Provider, d.Version, err)
+			}
+		}
+		for _, v := range versions {
+			if constraints.Check(v) {
+				r.Resolved = v.Original()
+				r.Deprecated = slices.ContainsFunc(deprecatedVersions, v.Equal)
+				break
+			}
+		}
+
+		report = append(report, r)
+	}
+
+	return report, nil
+}
+
+func writeDependentsReport(w io.Writer, report []dependentReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODULE\tVERSION\tHOSTNAME\tCONSTRAINT\tRESOLVED\tDEPRECATED")
+	for _, r := range report {
+		hostname, constraint, resolved, deprecated := r.Hostname, r.VersionConstraint, r.Resolved, ""
+		if hostname == "" {
+			hostname = "-"
+		}
+		if constraint == "" {
+			constraint = "-"
+		}
+		if resolved == "" {
+			resolved = "-"
+		}
+		if r.Deprecated {
+			deprecated = "yes"
+		}
+		fmt.Fprintf(tw, "%s/%s/%s\t%s\t%s\t%s\t%s\t%s\n", r.Namespace, r.Name, r.Provider, r.Version, hostname, constraint, resolved, deprecated)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependentsReport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := module.NewInmemStorage()
+	for _, v := range []string{"1.0.0", "1.1.0", "2.0.0"} {
+		_, err := storage.UploadModule(ctx, "networking", "vpc", "aws", v, "", strings.NewReader(""))
+		assert.NoError(t, err)
+	}
+
+	dependents := map[string]string{
+		"1.0.0": "~> 1.0",
+		"2.0.0": ">= 2.0.0",
+		"3.0.0": "",
+		"4.0.0": "~> 3.0",
+	}
+	for v, constraint := range dependents {
+		_, err := storage.UploadModule(ctx, "acme", "service", "aws", v, "", strings.NewReader(""))
+		assert.NoError(t, err)
+		err = storage.UploadModuleDependencies(ctx, "acme", "service", "aws", v, []module.ModuleDependency{
+			{Hostname: "registry.example.com", Namespace: "networking", Name: "vpc", Provider: "aws", Version: constraint},
+		})
+		assert.NoError(t, err)
+	}
+
+	err := storage.UpdateModuleAnnotations(ctx, "networking", "vpc", "aws", func(annotations core.VersionAnnotations) {
+		annotations["1.1.0"] = core.Annotations{Deprecation: &core.Deprecation{Reason: "use 2.x"}}
+	})
+	assert.NoError(t, err)
+
+	report, err := dependentsReport(ctx, storage, "networking", "vpc", "aws", []string{"1.0.0"})
+	assert.NoError(t, err)
+
+	var resolved, deprecated []string
+	for _, r := range report {
+		resolved = append(resolved, r.Version+"="+r.Resolved)
+		if r.Deprecated {
+			deprecated = append(deprecated, r.Version)
+		}
+	}
+	assert.Equal(t, []string{"4.0.0=", "3.0.0=2.0.0", "2.0.0=2.0.0", "1.0.0=1.1.0"}, resolved)
+	assert.Equal(t, []string{"1.0.0"}, deprecated)
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeDependentsReport(&buf, report))
+	assert.Contains(t, buf.String(), "acme/service/aws  1.0.0    registry.example.com")
+
+	_, err = dependentsReport(ctx, storage, "networking", "vpc", "aws", []string{"latest"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
+	"github.com/boring-registry/boring-registry/pkg/provider"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/cobra"
+)
+
+var flagDownloadsUnusedFor time.Duration
+
+func init() {
+	rootCmd.AddCommand(downloadsCmd)
+	downloadsCmd.AddCommand(downloadsReportCmd)
+
+	downloadsReportCmd.Flags().DurationVar(&flagDownloadsUnusedFor, "unused-for", 0, "Only report versions that haven't been downloaded for the given duration, e.g. 4380h for six months")
+}
+
+var downloadsCmd = &cobra.Command{
+	Use:   "downloads",
+	Short: "Report the downloads of module and provider versions",
+}
+
+var downloadsReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report the downloads of all module and provider versions",
+	Long: `Report the downloads of all module and provider versions in the last week, month and year.
+The downloads are recorded by servers that run with --download-stats, versions without recorded downloads are reported as well`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		s, err := setupStorage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to set up storage: %w", err)
+		}
+
+		report, err := downloadsReport(ctx, s, s, time.Now(), flagDownloadsUnusedFor)
+		if err != nil {
+			return err
+		}
+		return writeDownloadsReport(os.Stdout, report)
+	},
+}
+
+// versionDownloads are the downloads of a module or provider version
+type versionDownloads struct {
+	// Type is either module or provider
+	Type    string
+	ID      string
+	Version string
+	core.DownloadSummary
+	// LastDownload is the day of the most recent download, it's zero if no download was recorded
+	LastDownload time.Time
+}
+
+// downloadsReport returns the downloads of all stored module and provider versions.
+// If unusedFor is set, only the versions without downloads since now minus unusedFor are returned.
+func downloadsReport(ctx context.Context, modules module.Storage, providers provider.Storage, now time.Time, unusedFor time.Duration) ([]versionDownloads, error) {
+	var report []versionDownloads
+	add := func(typ, id string, versions []string, stats core.DownloadStats) {
+		sortVersions(versions)
+		for _, v := range versions {
+			d := versionDownloads{
+				Type:            typ,
+				ID:              id,
+				Version:         v,
+				DownloadSummary: stats[v].Summary(now),
+				LastDownload:    stats[v].LastDownload(),
+			}
+			if unusedFor > 0 && !d.LastDownload.IsZero() && now.Sub(d.LastDownload) < unusedFor {
+				continue
+			}
+			report = append(report, d)
+		}
+	}
+
+	ms, err := modules.ListModules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list modules: %w", err)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].ID(false) < ms[j].ID(false) })
+	for _, m := range ms {
+		moduleVersions, err := modules.ListModuleVersions(ctx, m.Namespace, m.Name, m.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions of module %s: %w", m.ID(false), err)
+		}
+		stats, err := modules.GetModuleDownloadStats(ctx, m.Namespace, m.Name, m.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get download stats of module %s: %w", m.ID(false), err)
+		}
+
+		versions := make([]string, 0, len(moduleVersions))
+		for _, v := range moduleVersions {
+			versions = append(versions, v.Version)
+		}
+		add("module", m.ID(false), versions, stats)
+	}
+
+	ps, err := providers.ListProviders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list providers: %w", err)
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].Namespace != ps[j].Namespace {
+			return ps[i].Namespace < ps[j].Namespace
+		}
+		return ps[i].Name < ps[j].Name
+	})
+	for _, p := range ps {
+		id := fmt.Sprintf("%s/%s", p.Namespace, p.Name)
+		providerVersions, err := providers.ListProviderVersions(ctx, p.Namespace, p.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions of provider %s: %w", id, err)
+		}
+		stats, err := providers.GetProviderDownloadStats(ctx, p.Namespace, p.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get download stats of provider %s: %w", id, err)
+		}
+
+		versions := make([]string, 0, len(providerVersions.Versions))
+		for _, v := range providerVersions.Versions {
+			versions = append(versions, v.Version)
+		}
+		add("provider", id, versions, stats)
+	}
+
+	return report, nil
+}
+
+// sortVersions sorts the versions in ascending order, versions that aren't semantic versions are sorted last
+func sortVersions(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, errA := version.NewVersion(versions[i])
+		b, errB := version.NewVersion(versions[j])
+		if errA != nil || errB != nil {
+			return errA == nil
+		}
+		return a.LessThan(b)
+	})
+}
+
+func writeDownloadsReport(w io.Writer, report []versionDownloads) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tID\tVERSION\tWEEK\tMONTH\tYEAR\tTOTAL\tLAST DOWNLOAD")
+	for _, d := range report {
+		lastDownload := "-"
+		if !d.LastDownload.IsZero() {
+			lastDownload = d.LastDownload.Format(time.DateOnly)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n", d.Type, d.ID, d.Version, d.Week, d.Month, d.Year, d.Total, lastDownload)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
+	"github.com/boring-registry/boring-registry/pkg/provider"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeProviderStorage struct {
+	provider.Storage
+	versions map[string][]string
+	stats    map[string]core.DownloadStats
+}
+
+func (s *fakeProviderStorage) ListProviders(_ context.Context) ([]core.Provider, error) {
+	var providers []core.Provider
+	for id := range s.versions {
+		namespace, name, _ := strings.Cut(id, "/")
+		providers = append(providers, core.Provider{Namespace: namespace, Name: name})
+	}
+	return providers, nil
+}
+
+func (s *fakeProviderStorage) ListProviderVersions(_ context.Context, namespace, name string) (*core.ProviderVersions, error) {
+	versions := &core.ProviderVersions{}
+	for _, v := range s.versions[namespace+"/"+name] {
+		versions.Versions = append(versions.Versions, core.ProviderVersion{Namespace: namespace, Name: name, Version: v})
+	}
+	return versions, nil
+}
+
+func (s *fakeProviderStorage) GetProviderDownloadStats(_ context.Context, namespace, name string) (core.DownloadStats, error) {
+	if stats, ok := s.stats[namespace+"/"+name]; ok {
+		return stats, nil
+	}
+	return core.DownloadStats{}, nil
+}
+
+func TestDownloadsReport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	modules := module.NewInmemStorage()
+	for _, v := range []string{"1.10.0", "1.2.0", "2.0.0"} {
+		_, err := modules.UploadModule(ctx, "networking", "vpc", "aws", v, "", strings.NewReader(""))
+		assert.NoError(t, err)
+	}
+	stats := core.DownloadStats{}
+	stats.Record("1.2.0", now.AddDate(-1, 0, 0), 5)
+	stats.Record("2.0.0", now.AddDate(0, 0, -1), 3)
+	stats.Record("2.0.0", now.AddDate(0, 0, -20), 2)
+	assert.NoError(t, modules.MergeModuleDownloadStats(ctx, "networking", "vpc", "aws", stats))
+
+	providerStats := core.DownloadStats{}
+	providerStats.Record("0.1.0", now, 1)
+	providers := &fakeProviderStorage{
+		versions: map[string][]string{"hashicorp/random": {"0.1.0"}},
+		stats:    map[string]core.DownloadStats{"hashicorp/random": providerStats},
+	}
+
+	report, err := downloadsReport(ctx, modules, providers, now, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []versionDownloads{
+		{Type: "module", ID: "networking/vpc/aws", Version: "1.2.0", DownloadSummary: core.DownloadSummary{Total: 5}, LastDownload: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{Type: "module", ID: "networking/vpc/aws", Version: "1.10.0"},
+		{Type: "module", ID: "networking/vpc/aws", Version: "2.0.0", DownloadSummary: core.DownloadSummary{Week: 3, Month: 5, Year: 5, Total: 5}, LastDownload: time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)},
+		{Type: "provider", ID: "hashicorp/random", Version: "0.1.0", DownloadSummary: core.DownloadSummary{Week: 1, Month: 1, Year: 1, Total: 1}, LastDownload: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+	}, report)
+
+	// Only the versions that haven't been downloaded in the last six months are reported
+	report, err = downloadsReport(ctx, modules, providers, now, 4380*time.Hour)
+	assert.NoError(t, err)
+	var unused []string
+	for _, d := range report {
+		unused = append(unused, d.Version)
+	}
+	assert.Equal(t, []string{"1.2.0", "1.10.0"}, unused)
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeDownloadsReport(&buf, report))
+	assert.Contains(t, buf.String(), "module  networking/vpc/aws  1.2.0    0     0      0     5      2023-07-01")
+	assert.Contains(t, buf.String(), "module  networking/vpc/aws  1.10.0   0     0      0     0      -")
+}
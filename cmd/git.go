@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/boring-registry/boring-registry/pkg/git"
+	"github.com/boring-registry/boring-registry/pkg/module"
+
+	"github.com/hashicorp/go-version"
+)
+
+var (
+	// gitTagPattern matches tags like v1.4.0, 1.4.0 or vpc/v1.4.0 in monorepos
+	gitTagPattern = regexp.MustCompile(`^(?:(.+)/)?v?(\d+\.\d+\.\d+(?:[-+].*)?)$`)
+
+	// moduleRepositoryPattern matches repository names following the convention terraform-<provider>-<name>
+	moduleRepositoryPattern = regexp.MustCompile(`^terraform-([a-z0-9]+)-(.+)$`)
+)
+
+// archiveModulesFromGit uploads the modules in root with the versions derived from the git tags of the repository.
+// Each version is archived from the tree of its tag instead of the working directory.
+func archiveModulesFromGit(root string, storage module.Storage) error {
+	repo, err := git.Open(root)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(repo.WorkTree(), abs)
+	if err != nil {
+		return err
+	}
+
+	// The module directories are relative to the root of the repository
+	dirs := []string{filepath.ToSlash(rel)}
+	if flagRecursive {
+		dirs = nil
+		err := filepath.Walk(root, func(p string, fi os.FileInfo, _ error) error {
+			// FYI we conciously ignore all walk-related errors
+
+			if fi == nil || fi.Name() != moduleSpecFileName {
+				return nil
+			}
+			abs, err := filepath.Abs(filepath.Dir(p))
+			if err != nil {
+				return err
+			}
+			dir, err := filepath.Rel(repo.WorkTree(), abs)
+			if err != nil {
+				return err
+			}
+			dirs = append(dirs, filepath.ToSlash(dir))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	tags, err := repo.Tags()
+	if err != nil {
+		return fmt.Errorf("failed to read tags: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	// The repository isn't safe for concurrent use, therefore the tags are published one after the other
+	p := newPublisher(1)
+	for _, dir := range dirs {
+		found := false
+		for _, tag := range tags {
+			v, ok := moduleVersionFromTag(tag.Name, dir)
+			if !ok {
+				continue
+			}
+
+			// Only the release at HEAD is uploaded, unless all historic tags are requested
+			if !flagGitAllTags && tag.Commit != head {
+				continue
+			}
+			found = true
+
+			tag := tag
+			p.Go(fmt.Sprintf("%s@%s", dir, tag.Name), func() (*publishResult, error) {
+				return processGitTag(repo, tag, dir, v, storage)
+			})
+		}
+
+		if !found {
+			slog.Info("no matching git tag found for module, skipped", slog.String("path", dir))
+		}
+	}
+
+	return p.Wait()
+}
+
+// moduleVersionFromTag returns the version of the module in dir, if the tag belongs to the module.
+// Tags are prefixed with the full path of the module directory, e.g. modules/vpc/v1.4.0,
+// or aren't prefixed at all in case the module is at the root of the repository.
+// The name of the directory alone isn't matched, as modules/vpc and legacy/vpc would share their tags.
+func moduleVersionFromTag(tag, dir string) (string, bool) {
+	m := gitTagPattern.FindStringSubmatch(tag)
+	if m == nil {
+		return "", false
+	}
+	prefix, v := m[1], m[2]
+
+	if _, err := version.NewSemver(v); err != nil {
+		return "", false
+	}
+
+	if prefix == "" {
+		return v, dir == "."
+	}
+	return v, prefix == dir
+}
+
+func processGitTag(repo *git.Repository, tag git.Tag, dir, version string, storage module.Storage) (*publishResult, error) {
+	fsys, err := repo.TreeFS(tag.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir != "." {
+		if _, err := fs.Stat(fsys, dir); err != nil {
+			return nil, fmt.Errorf("module directory doesn't exist in the tag: %w", err)
+		}
+		if fsys, err = fs.Sub(fsys, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	spec, err := gitModuleSpec(fsys, repo, dir, version)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("derived module spec from git tag", slog.String("tag", tag.Name), slog.String("name", spec.Name()))
+
+	return publishModule(spec, fsys, func(filter *module.FileFilter) ([]string, error) {
+		return moduleFilesFS(fsys, filter)
+	}, storage)
+}
+
+// gitModuleSpec reads the module spec from the tree of the tag.
+// Without a spec, the name and provider are derived from the terraform-<provider>-<name> naming convention.
+func gitModuleSpec(fsys fs.FS, repo *git.Repository, dir, version string) (*module.Spec, error) {
+	f, err := fsys.Open(moduleSpecFileName)
+	if err == nil {
+		defer f.Close()
+		return module.ParseWithVersion(f, version)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	name := path.Base(dir)
+	if dir == "." {
+		name = filepath.Base(repo.WorkTree())
+	}
+
+	m := moduleRepositoryPattern.FindStringSubmatch(name)
+	if m == nil {
+		return nil, fmt.Errorf("%s doesn't exist and %s doesn't follow the terraform-<provider>-<name> naming convention", moduleSpecFileName, name)
+	}
+	if flagGitNamespace == "" {
+		return nil, fmt.Errorf("the --git-namespace flag is required for modules without %s", moduleSpecFileName)
+	}
+
+	spec := &module.Spec{
+		Metadata: module.Metadata{
+			Namespace: flagGitNamespace,
+			Name:      m[2],
+			Provider:  m[1],
+			Version:   version,
+		},
+	}
+	return spec, spec.Validate()
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleVersionFromTag(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		tag     string
+		dir     string
+		version string
+		ok      bool
+	}{
+		{
+			name:    "tag at the root of the repository",
+			tag:     "v1.4.0",
+			dir:     ".",
+			version: "1.4.0",
+			ok:      true,
+		},
+		{
+			name:    "tag without v prefix",
+			tag:     "1.4.0-rc1",
+			dir:     ".",
+			version: "1.4.0-rc1",
+			ok:      true,
+		},
+		{
+			name: "unprefixed tag for a nested module",
+			tag:  "v1.4.0",
+			dir:  "modules/vpc",
+		},
+		{
+			name: "tag prefixed with the module name only",
+			tag:  "vpc/v1.4.0",
+			dir:  "modules/vpc",
+		},
+		{
+			name:    "tag prefixed with the module directory",
+			tag:     "modules/vpc/v1.4.0",
+			dir:     "modules/vpc",
+			version: "1.4.0",
+			ok:      true,
+		},
+		{
+			name: "tag of another module",
+			tag:  "subnet/v1.4.0",
+			dir:  "modules/vpc",
+		},
+		{
+			name: "tag of a module with the same name",
+			tag:  "legacy/vpc/v1.4.0",
+			dir:  "modules/vpc",
+		},
+		{
+			name: "tag that isn't a version",
+			tag:  "vpc/latest",
+			dir:  "modules/vpc",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			version, ok := moduleVersionFromTag(tc.tag, tc.dir)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, tc.version, version)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/mirror"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagMirrorGCDryRun       bool
+	flagMirrorGCKeepLatest   int
+	flagMirrorGCMaxUnusedAge time.Duration
+	flagMirrorGCPins         []string
+
+	flagMirrorVerifyRepair bool
+
+	flagMirrorExportOutput    string
+	flagMirrorExportProviders []string
+)
+
+func init() {
+	rootCmd.AddCommand(mirrorCmd)
+	mirrorCmd.AddCommand(mirrorGCCmd, mirrorVerifyCmd, mirrorExportCmd, mirrorImportCmd)
+
+	mirrorGCCmd.Flags().BoolVar(&flagMirrorGCDryRun, "dry-run", false, "Print the files that would be deleted without deleting them")
+	mirrorGCCmd.Flags().IntVar(&flagMirrorGCKeepLatest, "keep-latest", 0, "Number of most recent versions to retain per provider. All versions are retained if set to 0")
+	mirrorGCCmd.Flags().DurationVar(&flagMirrorGCMaxUnusedAge, "max-unused-age", 0, "Delete platforms of providers that haven't been requested for the given duration, e.g. 2160h for 90 days. Disabled if set to 0")
+	mirrorGCCmd.Flags().StringArrayVar(&flagMirrorGCPins, "pin", nil, "Providers in the form of <hostname>/<namespace>/<name>[@<version constraints>] that are never deleted, e.g. registry.terraform.io/hashicorp/aws@~> 5.0")
+
+	mirrorVerifyCmd.Flags().BoolVar(&flagMirrorVerifyRepair, "repair", false, "Delete orphaned files and copy inconsistent providers again from the upstream registry")
+
+	mirrorExportCmd.Flags().StringVarP(&flagMirrorExportOutput, "output", "o", "", "Directory or .tar.gz file to export the filesystem mirror to")
+	mirrorExportCmd.Flags().StringSliceVar(&flagMirrorExportProviders, "provider", nil, "Only export providers matching the patterns in the form of <hostname>/<namespace>/<name>, e.g. registry.terraform.io/hashicorp/*")
+	_ = mirrorExportCmd.MarkFlagRequired("output")
+}
+
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror",
+	Short: "Manage the provider network mirror",
+}
+
+var mirrorGCCmd = &cobra.Command{
+	Use:          "gc",
+	Short:        "Delete mirrored providers according to retention rules",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		s, err := setupStorage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to set up storage: %w", err)
+		}
+
+		gc, err := mirror.NewGarbageCollector(s,
+			mirror.WithKeepLatest(flagMirrorGCKeepLatest),
+			mirror.WithMaxUnusedAge(flagMirrorGCMaxUnusedAge),
+			mirror.WithPins(flagMirrorGCPins...),
+		)
+		if err != nil {
+			return err
+		}
+
+		result, err := gc.Run(ctx, flagMirrorGCDryRun)
+		if result != nil {
+			printGarbageCollectionResult(result)
+		}
+		return err
+	},
+}
+
+var mirrorVerifyCmd = &cobra.Command{
+	Use:          "verify",
+	Short:        "Verify mirrored providers against their SHA256SUMS and signatures",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		s, err := setupStorage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to set up storage: %w", err)
+		}
+
+		var options []mirror.VerifierOption
+		if flagMirrorVerifyRepair {
+			options = append(options, mirror.WithUpstreamRepair())
+		}
+
+		result, err := mirror.NewVerifier(s, options...).Run(ctx, flagMirrorVerifyRepair)
+		if result != nil {
+			printVerificationResult(result, flagMirrorVerifyRepair)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, issue := range result.Issues {
+			if !issue.Repaired {
+				return fmt.Errorf("found %d inconsistencies in the mirror", len(result.Issues))
+			}
+		}
+		return nil
+	},
+}
+
+var mirrorExportCmd = &cobra.Command{
+	Use:          "export",
+	Short:        "Export mirrored providers as a Terraform filesystem mirror",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		s, err := setupStorage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to set up storage: %w", err)
+		}
+
+		var w mirror.FileWriter
+		if isTarGz(flagMirrorExportOutput) {
+			f, err := os.Create(flagMirrorExportOutput)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = mirror.NewTarGzWriter(f)
+		} else {
+			w = mirror.NewDirectoryWriter(flagMirrorExportOutput)
+		}
+
+		result, err := mirror.Export(ctx, s, w, mirror.WithExportedProviders(flagMirrorExportProviders...))
+		if err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", flagMirrorExportOutput, err)
+		}
+
+		fmt.Printf("Exported %d archives to %s\n", len(result.Providers), flagMirrorExportOutput)
+		return nil
+	},
+}
+
+var mirrorImportCmd = &cobra.Command{
+	Use:          "import <directory|file.tar.gz>",
+	Short:        "Import providers from a Terraform filesystem mirror",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		s, err := setupStorage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to set up storage: %w", err)
+		}
+
+		dir := args[0]
+		if isTarGz(dir) {
+			f, err := os.Open(dir)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			if dir, err = os.MkdirTemp("", "boring-registry-import-*"); err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+			if err := mirror.ExtractTarGz(f, dir); err != nil {
+				return fmt.Errorf("failed to extract %s: %w", args[0], err)
+			}
+		}
+
+		result, err := mirror.Import(ctx, s, os.DirFS(dir))
+		if result != nil {
+			printImportResult(result)
+		}
+		return err
+	},
+}
+
+func isTarGz(name string) bool {
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+func printImportResult(result *mirror.ImportResult) {
+	if len(result.Skipped) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "PROVIDER\tVERSION\tSKIPPED")
+		for _, s := range result.Skipped {
+			_, _ = fmt.Fprintf(w, "%s/%s/%s\t%s\t%s\n", s.Provider.Hostname, s.Provider.Namespace, s.Provider.Name, s.Provider.Version, s.Reason)
+		}
+		_ = w.Flush()
+		fmt.Println()
+	}
+
+	fmt.Printf("Imported %d archives, skipped %d versions\n", len(result.Providers), len(result.Skipped))
+	if len(result.Unsigned) > 0 {
+		fmt.Printf("%d versions were imported without a SHA256SUMS.sig, `mirror verify` reports them as missing-signature\n", len(result.Unsigned))
+	}
+}
+
+func printVerificationResult(result *mirror.VerificationResult, repair bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "PROVIDER\tVERSION\tFILE\tISSUE\tMESSAGE"
+	if repair {
+		header += "\tREPAIR"
+	}
+	_, _ = fmt.Fprintln(w, header)
+	for _, issue := range result.Issues {
+		line := fmt.Sprintf("%s/%s/%s\t%s\t%s\t%s\t%s", issue.Provider.Hostname, issue.Provider.Namespace, issue.Provider.Name, issue.Provider.Version, issue.FileName, issue.Kind, issue.Message)
+		if repair {
+			if issue.Repaired {
+				line += "\trepaired"
+			} else {
+				line += fmt.Sprintf("\tfailed: %v", issue.RepairErr)
+			}
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+	_ = w.Flush()
+
+	fmt.Printf("\nVerified %d archives, found %d issues\n", result.Archives, len(result.Issues))
+}
+
+func printGarbageCollectionResult(result *mirror.GarbageCollectionResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PROVIDER\tFILE\tSIZE\tREASON")
+	for _, d := range result.Deletions {
+		_, _ = fmt.Fprintf(w, "%s/%s/%s\t%s\t%d\t%s\n", d.Provider.Hostname, d.Provider.Namespace, d.Provider.Name, d.FileName, d.Size, d.Reason)
+	}
+	_ = w.Flush()
+
+	verb := "Deleted"
+	if result.DryRun {
+		verb = "Would delete"
+	}
+	fmt.Printf("\n%s %d files (%d bytes)\n", verb, len(result.Deletions), result.FreedBytes())
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/boring-registry/boring-registry/pkg/git"
+	"github.com/boring-registry/boring-registry/pkg/module"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagModuleSnapshotArchiveFormat  string
+	flagDependentsDeprecatedVersions []string
+	flagDependentsFailOnDeprecated   bool
+)
+
+func init() {
+	rootCmd.AddCommand(moduleCmd)
+	moduleCmd.AddCommand(moduleSourceCmd, moduleSnapshotCmd, moduleDependentsCmd)
+
+	moduleSnapshotCmd.Flags().StringVar(&flagModuleSnapshotArchiveFormat, "archive-format", "", "The archive format of the snapshot, which defaults to the format in the package block of boring-registry.hcl or tar.gz")
+	moduleDependentsCmd.Flags().StringSliceVar(&flagDependentsDeprecatedVersions, "deprecated-versions", nil, "Versions of the module that are deprecated in addition to the versions that are deprecated by their annotations")
+	moduleDependentsCmd.Flags().BoolVar(&flagDependentsFailOnDeprecated, "fail-on-deprecated", false, "Return an error if a dependent is pinned to deprecated versions")
+}
+
+var moduleCmd = &cobra.Command{
+	Use:   "module",
+	Short: "Manage module versions",
+}
+
+var moduleSourceCmd = &cobra.Command{
+	Use:   "source NAMESPACE/NAME/PROVIDER/VERSION SOURCE",
+	Short: "Register a module version that is downloaded from a git repository",
+	Long: `Register a module version that is downloaded from a git repository instead of an archive in the storage.
+The source has to be a go-getter git source with a pinned ref, e.g. git::https://github.com/acme/terraform-aws-vpc.git?ref=v1.2.0`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, name, provider, version, err := parseModuleVersion(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		s, err := setupStorage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to set up storage: %w", err)
+		}
+
+		m, err := s.UploadModuleSource(ctx, namespace, name, provider, version, args[1])
+		if err != nil {
+			return err
+		}
+		slog.Info("module source successfully registered", slog.String("name", m.ID(true)), slog.String("source", m.Source))
+		return nil
+	},
+}
+
+var moduleSnapshotCmd = &cobra.Command{
+	Use:   "snapshot NAMESPACE/NAME/PROVIDER/VERSION",
+	Short: "Convert a module version with a git source into a stored archive",
+	Long: `Convert a module version with a git source into a stored archive.
+The ref of the source is fetched with a depth of 1 without the git binary and archived like an uploaded module, before the source is removed`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, name, provider, version, err := parseModuleVersion(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		s, err := setupStorage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to set up storage: %w", err)
+		}
+
+		return snapshotModule(ctx, s, namespace, name, provider, version)
+	},
+}
+
+// parseModule parses a module in the form of <namespace>/<name>/<provider>
+func parseModule(s string) (namespace, name, provider string, err error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 || slices.Contains(parts, "") {
+		return "", "", "", fmt.Errorf("invalid module %q, expected <namespace>/<name>/<provider>", s)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// parseModuleVersion parses a module version in the form of <namespace>/<name>/<provider>/<version>
+func parseModuleVersion(s string) (namespace, name, provider, version string, err error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 4 || slices.Contains(parts, "") {
+		return "", "", "", "", fmt.Errorf("invalid module version %q, expected <namespace>/<name>/<provider>/<version>", s)
+	}
+	return parts[0], parts[1], parts[2], parts[3], nil
+}
+
+// snapshotModule archives the git source of a module version and replaces the source with the archive
+func snapshotModule(ctx context.Context, storage module.Storage, namespace, name, provider, version string) error {
+	m, err := storage.GetModule(ctx, namespace, name, provider, version)
+	if err != nil {
+		return err
+	}
+	if m.Source == "" {
+		return fmt.Errorf("module %s is already stored as an archive", m.ID(true))
+	}
+
+	source, err := module.ParseGitSource(m.Source)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "boring-registry-snapshot-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	repo, commit, err := git.Fetch(ctx, dir, source.Remote, source.Ref)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	// Refs of annotated tags point to the tag object instead of the commit
+	if commit, err = repo.Peel(commit); err != nil {
+		return err
+	}
+	fsys, err := repo.TreeFS(commit)
+	if err != nil {
+		return err
+	}
+	if source.Subdir != "" {
+		if _, err := fs.Stat(fsys, source.Subdir); err != nil {
+			return fmt.Errorf("module directory doesn't exist at %s: %w", source.Ref, err)
+		}
+		if fsys, err = fs.Sub(fsys, source.Subdir); err != nil {
+			return err
+		}
+	}
+
+	spec := &module.Spec{
+		Metadata: module.Metadata{Namespace: namespace, Name: name, Provider: provider, Version: version},
+	}
+	// The package block of the spec applies to the snapshot as well, its metadata is ignored
+	if f, err := fsys.Open(moduleSpecFileName); err == nil {
+		defer f.Close()
+		parsed, err := module.ParseWithVersion(f, version)
+		if err != nil {
+			return err
+		}
+		spec.Package = parsed.Package
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	format := flagModuleSnapshotArchiveFormat
+	if format == "" {
+		format = module.DefaultArchiveFormat
+		if spec.Package != nil && spec.Package.Format != "" {
+			format = spec.Package.Format
+		}
+	}
+
+	filter, err := module.NewFileFilter(fsys, spec)
+	if err != nil {
+		return err
+	}
+	files, err := moduleFilesFS(fsys, filter)
+	if err != nil {
+		return err
+	}
+	details, err := module.ParseDetails(fsys, files)
+	if err != nil {
+		return fmt.Errorf("failed to parse module details: %w", err)
+	}
+	if details.Checksum, err = module.Checksum(fsys, files); err != nil {
+		return err
+	}
+	docs, err := module.ParseDocs(fsys, files)
+	if err != nil {
+		return fmt.Errorf("failed to capture module documentation: %w", err)
+	}
+	archive, err := archiveModule(fsys, files, format)
+	if err != nil {
+		return err
+	}
+
+	res, err := storage.UploadModule(ctx, namespace, name, provider, version, format, archive)
+	if err != nil {
+		return err
+	}
+	if err := uploadModuleSidecars(ctx, storage, spec.Metadata, details, docs); err != nil {
+		return err
+	}
+	if err := storage.DeleteModuleSource(ctx, namespace, name, provider, version); err != nil {
+		return err
+	}
+
+	slog.Info("module source successfully converted into an archive", slog.String("source", m.Source), slog.String("download_url", res.DownloadURL))
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/boring-registry/boring-registry/pkg/git/gittest"
+	"github.com/boring-registry/boring-registry/pkg/module"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseModuleVersion(t *testing.T) {
+	t.Parallel()
+
+	namespace, name, provider, version, err := parseModuleVersion("acme/vpc/aws/1.2.0")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"acme", "vpc", "aws", "1.2.0"}, []string{namespace, name, provider, version})
+
+	for _, s := range []string{"acme/vpc/aws", "acme/vpc/aws/1.2.0/extra", "acme//aws/1.2.0"} {
+		_, _, _, _, err := parseModuleVersion(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSnapshotModule(t *testing.T) {
+	r := gittest.Init(t, t.TempDir())
+	commit := gittest.Commit(t, r, map[string]string{
+		"modules/vpc/main.tf":         `variable "cidr" {}`,
+		"modules/vpc/test/fixture.tf": `# excluded by the package block`,
+		"modules/vpc/boring-registry.hcl": `
+metadata {
+  namespace = "acme"
+  name      = "vpc"
+  provider  = "aws"
+}
+
+package {
+  exclude = ["test/"]
+  format  = "zip"
+}`,
+	}, "Initial commit")
+	gittest.Tag(t, r, "vpc/v1.0.0", commit, "Release vpc 1.0.0")
+
+	ctx := context.Background()
+	storage := module.NewInmemStorage()
+	source := "git::" + gittest.Serve(t, r) + "//modules/vpc?ref=vpc/v1.0.0"
+	_, err := storage.UploadModuleSource(ctx, "acme", "vpc", "aws", "1.0.0", source)
+	assert.NoError(t, err)
+
+	assert.NoError(t, snapshotModule(ctx, storage, "acme", "vpc", "aws", "1.0.0"))
+
+	m, err := storage.GetModule(ctx, "acme", "vpc", "aws", "1.0.0")
+	assert.NoError(t, err)
+	assert.Empty(t, m.Source)
+	assert.Equal(t, "zip", m.ArchiveFormat)
+
+	details, err := storage.GetModuleDetails(ctx, "acme", "vpc", "aws", "1.0.0")
+	assert.NoError(t, err)
+	assert.Len(t, details.Root.Inputs, 1)
+
+	// Archived versions can't be snapshotted again
+	assert.Error(t, snapshotModule(ctx, storage, "acme", "vpc", "aws", "1.0.0"))
+}
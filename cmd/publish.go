@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// publishStatus is the outcome of publishing a module version
+type publishStatus string
+
+const (
+	publishUploaded publishStatus = "uploaded"
+	publishSkipped  publishStatus = "skipped"
+	// publishConflict means that the content of an existing version changed without a version bump
+	publishConflict publishStatus = "conflict"
+	publishFailed   publishStatus = "failed"
+)
+
+// errModuleChanged is returned if the content of an existing module version changed without a version bump
+var errModuleChanged = errors.New("module content changed without a version bump")
+
+// publishResult is the entry of a module version in the publish report
+type publishResult struct {
+	Module      string        `json:"module,omitempty"`
+	Path        string        `json:"path"`
+	Status      publishStatus `json:"-"`
+	Reason      string        `json:"reason,omitempty"`
+	Checksum    string        `json:"checksum,omitempty"`
+	DownloadURL string        `json:"download_url,omitempty"`
+
+	// listing is the output of a dry run, which is printed once all modules are processed
+	listing string
+	err     error
+}
+
+// publishReport is the machine-readable summary of an upload for CI pipelines
+type publishReport struct {
+	Uploaded  []publishResult `json:"uploaded"`
+	Skipped   []publishResult `json:"skipped"`
+	Conflicts []publishResult `json:"conflicts"`
+	Failed    []publishResult `json:"failed"`
+}
+
+// publisher publishes module versions concurrently and records their results for the report.
+// The results are reported in the order of the jobs, independent of the order in which they finish.
+type publisher struct {
+	group errgroup.Group
+
+	mu      sync.Mutex
+	results []*publishResult
+}
+
+// newPublisher returns a publisher that runs up to parallelism jobs at once
+func newPublisher(parallelism int) *publisher {
+	p := &publisher{}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	p.group.SetLimit(parallelism)
+	return p
+}
+
+// Go runs the job for the module at path, once less than parallelism jobs are running
+func (p *publisher) Go(path string, job func() (*publishResult, error)) {
+	p.mu.Lock()
+	i := len(p.results)
+	p.results = append(p.results, nil)
+	p.mu.Unlock()
+
+	p.group.Go(func() error {
+		result, err := job()
+		p.record(i, path, result, err)
+		return nil
+	})
+}
+
+func (p *publisher) record(i int, path string, result *publishResult, err error) {
+	if result == nil {
+		result = &publishResult{}
+	}
+	result.Path = path
+
+	if err != nil {
+		result.Reason = err.Error()
+		result.err = fmt.Errorf("failed to process module at %s:\n%w", path, err)
+		if errors.Is(err, errModuleChanged) {
+			result.Status = publishConflict
+		} else {
+			result.Status = publishFailed
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.results[i] = result
+}
+
+// Wait waits for all jobs, prints the output of a dry run and writes the report.
+// It returns the errors of all failed and conflicting modules.
+func (p *publisher) Wait() error {
+	_ = p.group.Wait()
+
+	report := publishReport{
+		Uploaded:  []publishResult{},
+		Skipped:   []publishResult{},
+		Conflicts: []publishResult{},
+		Failed:    []publishResult{},
+	}
+	var errs []error
+	for _, result := range p.results {
+		fmt.Print(result.listing)
+
+		switch result.Status {
+		case publishUploaded:
+			report.Uploaded = append(report.Uploaded, *result)
+		case publishSkipped:
+			report.Skipped = append(report.Skipped, *result)
+		case publishConflict:
+			report.Conflicts = append(report.Conflicts, *result)
+		default:
+			report.Failed = append(report.Failed, *result)
+		}
+		if result.err != nil {
+			errs = append(errs, result.err)
+		}
+	}
+
+	slog.Info("finished publishing modules",
+		slog.Int("uploaded", len(report.Uploaded)),
+		slog.Int("skipped", len(report.Skipped)),
+		slog.Int("conflicts", len(report.Conflicts)),
+		slog.Int("failed", len(report.Failed)),
+	)
+
+	if flagReport != "" {
+		if err := writePublishReport(flagReport, &report); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// writePublishReport writes the report as JSON to the file, or to stdout if the name is -
+func writePublishReport(name string, report *publishReport) error {
+	var w io.Writer = os.Stdout
+	if name != "-" {
+		f, err := os.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to create report: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
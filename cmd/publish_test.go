@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveModulesRecursive(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		assert.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	spec := func(name, version string) string {
+		return `
+metadata {
+  namespace = "acme"
+  name      = "` + name + `"
+  provider  = "aws"
+  version   = "` + version + `"
+}`
+	}
+	for _, name := range []string{"vpc", "subnet", "nat"} {
+		write(name+"/main.tf", `variable "cidr" {}`)
+		write(name+"/boring-registry.hcl", spec(name, "1.0.0"))
+	}
+
+	report := filepath.Join(t.TempDir(), "report.json")
+	flagRecursive, flagIgnoreExistingModule, flagParallelism, flagReport = true, true, 2, report
+	t.Cleanup(func() {
+		flagRecursive, flagIgnoreExistingModule, flagParallelism, flagReport = false, false, 4, ""
+	})
+
+	storage := module.NewInmemStorage()
+	assert.NoError(t, archiveModules(root, storage))
+
+	readReport := func() publishReport {
+		var r publishReport
+		b, err := os.ReadFile(report)
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(b, &r))
+		return r
+	}
+	r := readReport()
+	assert.Len(t, r.Uploaded, 3)
+	assert.Empty(t, r.Skipped)
+	for _, res := range r.Uploaded {
+		assert.NotEmpty(t, res.Checksum)
+	}
+
+	// The content of the subnet module changes without a version bump, the nat module gets a new version
+	write("subnet/outputs.tf", `output "id" {}`)
+	write("nat/boring-registry.hcl", spec("nat", "1.1.0"))
+
+	err := archiveModules(root, storage)
+	assert.ErrorIs(t, err, errModuleChanged)
+
+	r = readReport()
+	assert.Len(t, r.Uploaded, 1)
+	assert.Equal(t, "acme/nat/aws/1.1.0", r.Uploaded[0].Module)
+	assert.Len(t, r.Skipped, 1)
+	assert.Equal(t, "acme/vpc/aws/1.0.0", r.Skipped[0].Module)
+	assert.Equal(t, "unchanged", r.Skipped[0].Reason)
+	assert.Len(t, r.Conflicts, 1)
+	assert.Equal(t, filepath.Join(root, "subnet", moduleSpecFileName), r.Conflicts[0].Path)
+	assert.Empty(t, r.Failed)
+}
+
+// failingUploadStorage fails the next upload of module details
+type failingUploadStorage struct {
+	module.Storage
+	fail bool
+}
+
+func (s *failingUploadStorage) UploadModuleDetails(ctx context.Context, namespace, name, provider, version string, details *module.Details) error {
+	if s.fail {
+		s.fail = false
+		return errors.New("upload failed")
+	}
+	return s.Storage.UploadModuleDetails(ctx, namespace, name, provider, version, details)
+}
+
+// racingUploadStorage stores the archive of a concurrent upload of the same version right before each upload
+type racingUploadStorage struct {
+	module.Storage
+}
+
+func (s *racingUploadStorage) UploadModule(ctx context.Context, namespace, name, provider, version, format string, body io.Reader) (core.Module, error) {
+	if _, err := s.Storage.UploadModule(ctx, namespace, name, provider, version, format, strings.NewReader("concurrent")); err != nil {
+		return core.Module{}, err
+	}
+	return s.Storage.UploadModule(ctx, namespace, name, provider, version, format, body)
+}
+
+func writeTestModule(t *testing.T) string {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "main.tf"), []byte(`module "subnet" { source = "registry.example.com/acme/subnet/aws" }`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, moduleSpecFileName), []byte(`
+metadata {
+  namespace = "acme"
+  name      = "vpc"
+  provider  = "aws"
+  version   = "1.0.0"
+}`), 0o644))
+	return root
+}
+
+func TestArchiveModules_sidecars(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("failed upload of the details", func(t *testing.T) {
+		storage := &failingUploadStorage{Storage: module.NewInmemStorage(), fail: true}
+		assert.Error(t, archiveModules(writeTestModule(t), storage))
+
+		// The sidecars are uploaded after the archive, so the version exists without its details
+		_, err := storage.GetModule(ctx, "acme", "vpc", "aws", "1.0.0")
+		assert.NoError(t, err)
+		_, err = storage.GetModuleDetails(ctx, "acme", "vpc", "aws", "1.0.0")
+		assert.ErrorIs(t, err, module.ErrModuleDetailsNotFound)
+	})
+
+	t.Run("concurrent upload", func(t *testing.T) {
+		storage := &racingUploadStorage{Storage: module.NewInmemStorage()}
+		assert.ErrorIs(t, archiveModules(writeTestModule(t), storage), module.ErrModuleAlreadyExists)
+
+		// The upload that lost the race doesn't store the sidecars of the version that was stored concurrently
+		_, err := storage.GetModuleDetails(ctx, "acme", "vpc", "aws", "1.0.0")
+		assert.ErrorIs(t, err, module.ErrModuleDetailsNotFound)
+		_, err = storage.GetModuleDocs(ctx, "acme", "vpc", "aws", "1.0.0")
+		assert.ErrorIs(t, err, module.ErrModuleDocsNotFound)
+		dependents, err := storage.ListModuleDependents(ctx, "acme", "subnet", "aws")
+		assert.NoError(t, err)
+		assert.Empty(t, dependents)
+	})
+}
+
+func TestPublisher_order(t *testing.T) {
+	report := filepath.Join(t.TempDir(), "report.json")
+	flagReport = report
+	t.Cleanup(func() { flagReport = "" })
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = stdout })
+
+	// The later jobs finish first, but their results are reported in the order of the jobs
+	p := newPublisher(4)
+	for i, name := range []string{"vpc", "subnet", "nat", "igw"} {
+		name, delay := name, time.Duration(4-i)*10*time.Millisecond
+		p.Go(name, func() (*publishResult, error) {
+			time.Sleep(delay)
+			return &publishResult{Module: name, Status: publishSkipped, listing: name + "\n"}, nil
+		})
+	}
+	assert.NoError(t, p.Wait())
+
+	assert.NoError(t, w.Close())
+	os.Stdout = stdout
+	out, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "vpc\nsubnet\nnat\nigw\n", string(out))
+
+	var res publishReport
+	b, err := os.ReadFile(report)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(b, &res))
+	var paths []string
+	for _, s := range res.Skipped {
+		paths = append(paths, s.Path)
+	}
+	assert.Equal(t, []string{"vpc", "subnet", "nat", "igw"}, paths)
+}
+
+func TestArchiveModules_withoutChecksum(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "main.tf"), []byte(`variable "cidr" {}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, moduleSpecFileName), []byte(`
+metadata {
+  namespace = "acme"
+  name      = "vpc"
+  provider  = "aws"
+  version   = "1.0.0"
+}`), 0o644))
+
+	report := filepath.Join(t.TempDir(), "report.json")
+	flagIgnoreExistingModule, flagReport = true, report
+	t.Cleanup(func() { flagIgnoreExistingModule, flagReport = false, "" })
+
+	// The version was uploaded by an earlier version of the boring-registry, which didn't store a checksum
+	storage := module.NewInmemStorage()
+	_, err := storage.UploadModule(context.Background(), "acme", "vpc", "aws", "1.0.0", "", bytes.NewReader([]byte("archive")))
+	assert.NoError(t, err)
+
+	assert.NoError(t, archiveModules(root, storage))
+
+	var r publishReport
+	b, err := os.ReadFile(report)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(b, &r))
+	if assert.Len(t, r.Skipped, 1) {
+		assert.Equal(t, "not compared, as no checksum is stored", r.Skipped[0].Reason)
+		assert.NotEmpty(t, r.Skipped[0].Checksum)
+	}
+}
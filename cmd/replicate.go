@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/config"
+	"github.com/boring-registry/boring-registry/pkg/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagReplicateDryRun   bool
+	flagReplicateInterval time.Duration
+	flagReplicateTenant   string
+)
+
+func init() {
+	rootCmd.AddCommand(replicateCmd)
+
+	replicateCmd.Flags().BoolVar(&flagReplicateDryRun, "dry-run", false, "Print the objects that would be copied or deleted without copying or deleting them")
+	replicateCmd.Flags().DurationVar(&flagReplicateInterval, "interval", 0, "Replicate continuously in the given interval until the command is interrupted. Replicates once if set to 0")
+	replicateCmd.Flags().StringVar(&flagReplicateTenant, "tenant", config.DefaultTenant, "Tenant of the configuration file whose storage is replicated")
+}
+
+var replicateCmd = &cobra.Command{
+	Use:   "replicate",
+	Short: "Copy the registry to the destination storage of the replication",
+	Long: `Copy the modules, provider releases, signing keys and mirrored providers to the destination storage,
+which is configured in the replication section of the configuration file given with --config.
+Only new and changed objects are copied, and the size and checksum of each copied object are verified in the destination.
+The download stats and access logs aren't replicated, and the annotations are merged into the destination.
+Only one replica replicates to the destination at a time`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		source, replication, err := replicationSource(ctx, flagReplicateTenant)
+		if err != nil {
+			return err
+		}
+		replicator, err := newReplicator(ctx, source, replication)
+		if err != nil {
+			return err
+		}
+
+		if flagReplicateInterval > 0 {
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			replicator.Schedule(ctx, flagReplicateInterval)
+			return nil
+		}
+
+		result, err := replicator.Run(ctx, flagReplicateDryRun)
+		if result != nil {
+			printReplicationResult(result)
+		}
+		return err
+	},
+}
+
+// replicationSource returns the storage and the replication of the tenant
+func replicationSource(ctx context.Context, name string) (storage.Storage, *config.Replication, error) {
+	if configFile == nil {
+		return nil, nil, fmt.Errorf("the replication is configured in the configuration file, set --config")
+	}
+
+	if name == config.DefaultTenant {
+		if flagReplication == nil {
+			return nil, nil, fmt.Errorf("the configuration file doesn't configure a replication")
+		}
+		s, err := setupStorage(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to set up storage: %w", err)
+		}
+		return s, flagReplication, nil
+	}
+
+	for _, t := range configFile.Tenants {
+		if t.Name != name {
+			continue
+		}
+		if t.Replication == nil {
+			return nil, nil, fmt.Errorf("the configuration file doesn't configure a replication for tenant %s", name)
+		}
+		s, err := newStorage(ctx, t.Name, t.Storage, defaultStorageMetrics())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to set up storage: %w", err)
+		}
+		return s, t.Replication, nil
+	}
+	return nil, nil, fmt.Errorf("the configuration file doesn't configure tenant %s", name)
+}
+
+// newReplicator returns a Replicator, which copies the objects of the source to the destination of the replication
+func newReplicator(ctx context.Context, source storage.Storage, c *config.Replication) (*storage.Replicator, error) {
+	destination, err := newStorageBackend(ctx, c.Destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the destination storage: %w", err)
+	}
+
+	src, ok := source.(storage.ObjectStorage)
+	if !ok {
+		return nil, fmt.Errorf("the storage backend doesn't support replication")
+	}
+	dst, ok := destination.(storage.ObjectStorage)
+	if !ok {
+		return nil, fmt.Errorf("the destination storage backend doesn't support replication")
+	}
+
+	options := []storage.ReplicatorOption{storage.WithReplicationScopes(c.Scopes...)}
+	if c.Concurrency > 0 {
+		options = append(options, storage.WithReplicationConcurrency(c.Concurrency))
+	}
+	if c.Delete != nil {
+		options = append(options, storage.WithReplicatedDeletions(*c.Delete))
+	}
+	return storage.NewReplicator(src, dst, options...)
+}
+
+func printReplicationResult(result *storage.ReplicationResult) {
+	if len(result.Copied) > 0 || len(result.Deleted) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "OBJECT\tSIZE\tACTION")
+		for _, o := range result.Copied {
+			_, _ = fmt.Fprintf(w, "%s\t%d\tcopy\n", o.Key, o.Size)
+		}
+		for _, key := range result.Deleted {
+			_, _ = fmt.Fprintf(w, "%s\t\tdelete\n", key)
+		}
+		_ = w.Flush()
+		fmt.Println()
+	}
+
+	copied, deleted := "Copied", "deleted"
+	if result.DryRun {
+		copied, deleted = "Would copy", "delete"
+	}
+	fmt.Printf("%s %d objects (%d bytes), %s %d objects, %d objects are unchanged\n", copied, len(result.Copied), result.CopiedBytes(), deleted, len(result.Deleted), result.Unchanged)
+}
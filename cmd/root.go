@@ -7,6 +7,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/boring-registry/boring-registry/pkg/events"
+	"github.com/boring-registry/boring-registry/pkg/storage"
+	"github.com/boring-registry/boring-registry/pkg/tenant"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -18,8 +22,9 @@ const (
 )
 
 var (
-	flagJSON  bool
-	flagDebug bool
+	flagJSON   bool
+	flagDebug  bool
+	flagConfig string
 
 	// S3 options.
 	flagS3Bucket          string
@@ -40,6 +45,23 @@ var (
 	flagAzureStorageContainer       string
 	flagAzureStoragePrefix          string
 	flagAzureStorageSignedURLExpiry time.Duration
+
+	// Download URL options of all storage backends.
+	flagStorageSignedURLCacheMinValidity time.Duration
+	flagStorageCDNURL                    string
+	flagStorageCDNSigner                 string
+	flagStorageCDNKeyID                  string
+	flagStorageCDNKeyFile                string
+
+	// Webhook options.
+	flagWebhookURLs           []string
+	flagWebhookSecret         string
+	flagWebhookEvents         []string
+	flagWebhookTenants        []string
+	flagWebhookRetries        int
+	flagWebhookRetryBackoff   time.Duration
+	flagWebhookTimeout        time.Duration
+	flagWebhookDeadLetterFile string
 )
 
 var rootCmd = &cobra.Command{
@@ -50,6 +72,10 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
+		if err := loadConfigFile(cmd); err != nil {
+			return err
+		}
+
 		setupLogger()
 
 		if flagDebug {
@@ -61,7 +87,10 @@ var rootCmd = &cobra.Command{
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	// The events of the command are delivered before exiting, even if the command failed halfway
+	closeEventBus()
+	if err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -70,6 +99,8 @@ func Execute() {
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&flagJSON, "json", false, "Enable json logging")
 	rootCmd.PersistentFlags().BoolVar(&flagDebug, "debug", false, "Enable debug logging")
+	rootCmd.PersistentFlags().StringVar(&flagConfig, "config", "", `Path to a YAML or HCL configuration file. Flags and environment variables that are set take precedence over the file.
+The log level, tokens and mirror policy are reloaded from the file on SIGHUP`)
 	rootCmd.PersistentFlags().StringVar(&flagS3Bucket, "storage-s3-bucket", "", "S3 bucket to use for the registry")
 	rootCmd.PersistentFlags().StringVar(&flagS3Prefix, "storage-s3-prefix", "", "S3 bucket prefix to use for the registry")
 	rootCmd.PersistentFlags().StringVar(&flagS3Region, "storage-s3-region", "", "S3 bucket region to use for the registry")
@@ -86,6 +117,20 @@ For GCS presigned URLs this SA needs the iam.serviceAccountTokenCreator role.`)
 	rootCmd.PersistentFlags().StringVar(&flagAzureStorageContainer, "storage-azure-container", "", "Azure Storage Container to use for the registry")
 	rootCmd.PersistentFlags().StringVar(&flagAzureStoragePrefix, "storage-azure-prefix", "", "Azure Storage prefix to use for the registry")
 	rootCmd.PersistentFlags().DurationVar(&flagAzureStorageSignedURLExpiry, "storage-azure-signedurl-expiry", 5*time.Minute, "Generate Azure Storage signed URL valid for X seconds.")
+	rootCmd.PersistentFlags().DurationVar(&flagStorageSignedURLCacheMinValidity, "storage-signedurl-cache-min-validity", 0, `Reuse a signed download URL of an object until it's valid for less than this duration.
+Signed URLs aren't reused if 0, and are only reused if the signed URL expiry is longer`)
+	rootCmd.PersistentFlags().StringVar(&flagStorageCDNURL, "storage-cdn-url", "", "Base URL of a CDN, whose origin is the storage backend. Download URLs are returned on the CDN instead of the storage backend if set")
+	rootCmd.PersistentFlags().StringVar(&flagStorageCDNSigner, "storage-cdn-signer", "", fmt.Sprintf("Signer of the download URLs on the CDN, one of %v. The URLs aren't signed if empty", storage.CDNSigners))
+	rootCmd.PersistentFlags().StringVar(&flagStorageCDNKeyID, "storage-cdn-key-id", "", "Key pair ID of CloudFront or key name of Cloud CDN, with which the download URLs on the CDN are signed")
+	rootCmd.PersistentFlags().StringVar(&flagStorageCDNKeyFile, "storage-cdn-key-file", "", "File with the PEM encoded private key of CloudFront or the base64url encoded key of Cloud CDN")
+	rootCmd.PersistentFlags().StringSliceVar(&flagWebhookURLs, "webhook-url", nil, "URLs to which the events of published, mirrored and deleted modules and providers are delivered")
+	rootCmd.PersistentFlags().StringVar(&flagWebhookSecret, "webhook-secret", "", "Secret with which the bodies of the webhook requests are signed in the X-Boring-Registry-Signature-256 header")
+	rootCmd.PersistentFlags().StringSliceVar(&flagWebhookEvents, "webhook-events", nil, fmt.Sprintf("Types of events that are delivered to the webhooks, one of %v. All events are delivered if empty", events.Types))
+	rootCmd.PersistentFlags().StringSliceVar(&flagWebhookTenants, "webhook-tenants", nil, "Tenants whose events are delivered to the webhooks, the default tenant is named default. The events of all tenants are delivered if empty")
+	rootCmd.PersistentFlags().IntVar(&flagWebhookRetries, "webhook-retries", 5, "Number of times a failed webhook delivery is retried")
+	rootCmd.PersistentFlags().DurationVar(&flagWebhookRetryBackoff, "webhook-retry-backoff", time.Second, "Duration before the first retry of a failed webhook delivery, which is doubled for each further retry")
+	rootCmd.PersistentFlags().DurationVar(&flagWebhookTimeout, "webhook-timeout", 10*time.Second, "Timeout of a single webhook delivery attempt")
+	rootCmd.PersistentFlags().StringVar(&flagWebhookDeadLetterFile, "webhook-dead-letter-file", "", "File to which the events that couldn't be delivered are appended as JSON lines. They are only logged if empty")
 }
 
 func initializeConfig(cmd *cobra.Command) error {
@@ -96,10 +141,13 @@ func initializeConfig(cmd *cobra.Command) error {
 	return nil
 }
 
+// logLevel is the level of the default logger, which can be changed at runtime by reloading the configuration file
+var logLevel = new(slog.LevelVar)
+
 func setupLogger() {
-	handlerOptions := &slog.HandlerOptions{}
+	handlerOptions := &slog.HandlerOptions{Level: logLevel}
 	if flagDebug {
-		handlerOptions.Level = slog.LevelDebug
+		logLevel.Set(slog.LevelDebug)
 		handlerOptions.AddSource = true
 	}
 
@@ -113,6 +161,8 @@ func setupLogger() {
 	if hostname, err := os.Hostname(); err == nil {
 		handler = handler.WithAttrs([]slog.Attr{slog.String("hostname", hostname)})
 	}
+	// Requests of tenants are logged with the name of the tenant
+	handler = tenant.NewLogHandler(handler)
 	slog.SetDefault(slog.New(handler))
 }
 
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -10,18 +9,25 @@ import (
 	"net/http/pprof"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/boring-registry/boring-registry/pkg/auth"
+	"github.com/boring-registry/boring-registry/pkg/admin"
+	"github.com/boring-registry/boring-registry/pkg/config"
 	"github.com/boring-registry/boring-registry/pkg/core"
-	"github.com/boring-registry/boring-registry/pkg/discovery"
+	"github.com/boring-registry/boring-registry/pkg/downloads"
+	"github.com/boring-registry/boring-registry/pkg/events"
+	"github.com/boring-registry/boring-registry/pkg/health"
 	"github.com/boring-registry/boring-registry/pkg/mirror"
 	"github.com/boring-registry/boring-registry/pkg/module"
 	o11y "github.com/boring-registry/boring-registry/pkg/observability"
 	"github.com/boring-registry/boring-registry/pkg/provider"
 	"github.com/boring-registry/boring-registry/pkg/proxy"
 	"github.com/boring-registry/boring-registry/pkg/storage"
+	"github.com/boring-registry/boring-registry/pkg/tenant"
+	"github.com/boring-registry/boring-registry/pkg/ui"
+	"github.com/boring-registry/boring-registry/version"
 
 	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
@@ -41,6 +47,8 @@ var (
 	prefixProviders = fmt.Sprintf("%s/providers", prefix)
 	prefixMirror    = fmt.Sprintf("%s/mirror", prefix)
 	prefixProxy     = fmt.Sprintf("%s/proxy", prefix)
+	prefixAdmin     = fmt.Sprintf("%s/admin", prefix)
+	prefixUI        = "/ui"
 )
 
 var (
@@ -54,6 +62,10 @@ var (
 	flagTelemetryListenAddr string
 	flagModuleArchiveFormat string
 
+	// Health checks.
+	flagHealthCacheTTL     time.Duration
+	flagHealthCheckTimeout time.Duration
+
 	// Login options.
 	flagLoginIssuer     string
 	flagLoginClient     string
@@ -63,6 +75,12 @@ var (
 	flagLoginToken      string
 	flagLoginPorts      []int
 
+	// Read-through replica options.
+	flagReplicaUpstream        string
+	flagReplicaUpstreamToken   string
+	flagReplicaUpstreamTimeout time.Duration
+	flagReplicaTrustedKeysFile string
+
 	// Static auth.
 	flagAuthStaticTokens []string
 
@@ -70,9 +88,46 @@ var (
 	flagAuthOktaIssuer string
 	flagAuthOktaClaims []string
 
+	// Admin API.
+	flagAdminStaticTokens []string
+
+	// Web UI.
+	flagUI             bool
+	flagUISecureCookie bool
+
+	// Download stats.
+	flagDownloadStats              bool
+	flagDownloadStatsFlushInterval time.Duration
+
+	// Tracing.
+	flagTracingOTLPEndpoint string
+	flagTracingOTLPInsecure bool
+	flagTracingSampleRatio  float64
+	flagTracingPropagators  []string
+
 	// Provider Network Mirror
-	flagProviderNetworkMirrorEnabled            bool
-	flagProviderNetworkMirrorPullThroughEnabled bool
+	flagProviderNetworkMirrorEnabled               bool
+	flagProviderNetworkMirrorPullThroughEnabled    bool
+	flagProviderNetworkMirrorUpstreamTimeout       time.Duration
+	flagProviderNetworkMirrorFallbackOnServerError bool
+	flagProviderNetworkMirrorCacheTTL              time.Duration
+	flagProviderNetworkMirrorCacheStaleTTL         time.Duration
+	flagProviderNetworkMirrorCacheNegativeTTL      time.Duration
+	flagProviderNetworkMirrorHealthCheckUpstream   string
+
+	// Provider Network Mirror policy
+	flagProviderNetworkMirrorAllow              []string
+	flagProviderNetworkMirrorDeny               []string
+	flagProviderNetworkMirrorVersionConstraints string
+	flagProviderNetworkMirrorAllowPrereleases   bool
+	flagProviderNetworkMirrorPlatforms          []string
+
+	// Provider Network Mirror retention
+	flagProviderNetworkMirrorAccessLogInterval time.Duration
+	flagProviderNetworkMirrorGCInterval        time.Duration
+	flagProviderNetworkMirrorGCKeepLatest      int
+	flagProviderNetworkMirrorGCMaxUnusedAge    time.Duration
+	flagProviderNetworkMirrorGCPins            []string
 )
 
 var serverCmd = &cobra.Command{
@@ -82,9 +137,28 @@ var serverCmd = &cobra.Command{
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		shutdownTracing, err := o11y.SetupTracing(ctx, o11y.TracingConfig{
+			Endpoint:       flagTracingOTLPEndpoint,
+			Insecure:       flagTracingOTLPInsecure,
+			SampleRatio:    flagTracingSampleRatio,
+			Propagators:    flagTracingPropagators,
+			ServiceVersion: version.Version,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to setup tracing: %w", err)
+		}
+		defer func() {
+			// The remaining spans are flushed after the servers have been shut down
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				slog.Error("failed to shut down tracing", slog.String("error", err.Error()))
+			}
+		}()
+
 		group, ctx := errgroup.WithContext(ctx)
 
-		mux, err := serveMux(ctx)
+		mux, telemetryMux, err := serveMux(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to setup server: %w", err)
 		}
@@ -93,14 +167,14 @@ var serverCmd = &cobra.Command{
 			Addr:         flagListenAddr,
 			ReadTimeout:  5 * time.Second,
 			WriteTimeout: 5 * time.Second,
-			Handler:      mux,
+			Handler:      o11y.NewTracingHandler(mux),
 		}
 
 		telemetryServer := &http.Server{
 			Addr:         flagTelemetryListenAddr,
 			ReadTimeout:  5 * time.Second,
 			WriteTimeout: 5 * time.Second,
-			Handler:      mux,
+			Handler:      telemetryMux,
 		}
 
 		sigint := make(chan os.Signal, 1)
@@ -117,6 +191,28 @@ var serverCmd = &cobra.Command{
 			return nil
 		})
 
+		// Reload handler.
+		if flagConfig != "" {
+			sighup := make(chan os.Signal, 1)
+			signal.Notify(sighup, syscall.SIGHUP)
+
+			group.Go(func() error {
+				defer signal.Stop(sighup)
+				for {
+					select {
+					case <-sighup:
+						if err := reloadConfigFile(cmd); err != nil {
+							slog.Error("failed to reload configuration file, keeping the previous configuration", slog.String("error", err.Error()))
+							continue
+						}
+						slog.Info("reloaded configuration file", slog.String("path", flagConfig))
+					case <-ctx.Done():
+						return nil
+					}
+				}
+			})
+		}
+
 		// Server handler.
 		group.Go(func() error {
 			<-ctx.Done()
@@ -184,7 +280,11 @@ func init() {
 	serverCmd.Flags().StringVar(&flagTLSCertFile, "tls-cert-file", "", "TLS certificate to serve")
 	serverCmd.Flags().StringVar(&flagListenAddr, "listen-address", ":5601", "Address to listen on")
 	serverCmd.Flags().StringVar(&flagTelemetryListenAddr, "listen-telemetry-address", ":7801", "Telemetry address to listen on")
-	serverCmd.Flags().StringVar(&flagModuleArchiveFormat, "storage-module-archive-format", storage.DefaultModuleArchiveFormat, "Archive file format for modules, specified without the leading dot")
+	serverCmd.Flags().StringVar(&flagModuleArchiveFormat, "storage-module-archive-format", storage.DefaultModuleArchiveFormat, "Default archive file format for modules, specified without the leading dot. Modules in other formats are served as well")
+
+	// Health check options.
+	serverCmd.Flags().DurationVar(&flagHealthCacheTTL, "health-cache-ttl", 10*time.Second, "Duration for which the results of the readiness checks are cached. Setting it to 0 disables the cache")
+	serverCmd.Flags().DurationVar(&flagHealthCheckTimeout, "health-check-timeout", 3*time.Second, "Timeout after which a readiness check is considered to have failed")
 
 	// Proxy options.
 	serverCmd.PersistentFlags().BoolVar(&flagProxy, "download-proxy", false, "Enable proxying download request to remote storage")
@@ -196,6 +296,23 @@ func init() {
 	serverCmd.Flags().StringVar(&flagAuthOktaIssuer, "auth-okta-issuer", "", "Okta issuer")
 	serverCmd.Flags().StringSliceVar(&flagAuthOktaClaims, "auth-okta-claims", nil, "Okta claims to validate")
 
+	// Admin API options.
+	serverCmd.Flags().StringSliceVar(&flagAdminStaticTokens, "admin-static-token", nil, "Static API token to protect the admin API, which is only served if at least one token is set")
+
+	// Web UI options.
+	serverCmd.Flags().BoolVar(&flagUI, "ui", false, "Serve the read-only web UI at /ui/, which is protected by the same tokens as the registry")
+	serverCmd.Flags().BoolVar(&flagUISecureCookie, "ui-secure-cookie", false, "Always mark the session cookie of the web UI as Secure, e.g. if TLS is terminated by a proxy that doesn't set the X-Forwarded-Proto header")
+
+	// Download stats options.
+	serverCmd.Flags().BoolVar(&flagDownloadStats, "download-stats", false, "Record the daily downloads of module and provider versions in the storage backend")
+	serverCmd.Flags().DurationVar(&flagDownloadStatsFlushInterval, "download-stats-flush-interval", time.Minute, "Interval in which the recorded downloads are written to the storage backend")
+
+	// Tracing options.
+	serverCmd.Flags().StringVar(&flagTracingOTLPEndpoint, "tracing-otlp-endpoint", "", "Endpoint of the OTLP/HTTP receiver in the form of <host>:<port> to export traces to. Traces aren't exported if unset")
+	serverCmd.Flags().BoolVar(&flagTracingOTLPInsecure, "tracing-otlp-insecure", false, "Disable TLS for the connection to the OTLP/HTTP receiver")
+	serverCmd.Flags().Float64Var(&flagTracingSampleRatio, "tracing-sample-ratio", 1, "Ratio of traces that are sampled, unless the trace context of the request has already been sampled")
+	serverCmd.Flags().StringSliceVar(&flagTracingPropagators, "tracing-propagators", []string{o11y.PropagatorTraceContext, o11y.PropagatorBaggage}, "Formats in which the trace context is extracted from requests and injected into upstream requests. Supported are tracecontext and baggage")
+
 	// Terraform Login Protocol options.
 	serverCmd.Flags().StringVar(&flagLoginClient, "login-client", "", "The client_id value to use when making requests")
 	serverCmd.Flags().StringSliceVar(&flagLoginGrantTypes, "login-grant-types", []string{"authz_code"}, "An array describing a set of OAuth 2.0 grant types")
@@ -204,130 +321,210 @@ func init() {
 	serverCmd.Flags().IntSliceVar(&flagLoginPorts, "login-ports", []int{10000, 10010}, "Inclusive range of TCP ports that Terraform may use")
 	serverCmd.Flags().StringSliceVar(&flagLoginScopes, "login-scopes", nil, "List of scopes")
 
+	// Read-through replica options
+	serverCmd.Flags().StringVar(&flagReplicaUpstream, "replica-upstream", "", "Hostname of the central registry, from which module and provider versions that are missing in the storage are read through and stored. The registry isn't a replica if unset")
+	serverCmd.Flags().StringVar(&flagReplicaUpstreamToken, "replica-upstream-token", "", "Token to authenticate the requests to the central registry")
+	serverCmd.Flags().DurationVar(&flagReplicaUpstreamTimeout, "replica-upstream-timeout", 10*time.Second, "Timeout for the requests of module and provider metadata to the central registry")
+	serverCmd.Flags().StringVar(&flagReplicaTrustedKeysFile, "replica-trusted-keys-file", "", "File with the ASCII armored public keys, which are trusted to sign the providers of the central registry in addition to the signing keys of the namespaces")
+
 	// Provider Network Mirror options
 	serverCmd.Flags().BoolVar(&flagProviderNetworkMirrorEnabled, "network-mirror", true, "Enable the provider network mirror")
 	serverCmd.Flags().BoolVar(&flagProviderNetworkMirrorPullThroughEnabled, "network-mirror-pull-through", false, "Enable the pull-through provider network mirror. This setting takes no effect if network-mirror is disabled")
+	serverCmd.Flags().DurationVar(&flagProviderNetworkMirrorUpstreamTimeout, "network-mirror-upstream-timeout", 10*time.Second, "Timeout for requests of the pull-through mirror to the upstream registry")
+	serverCmd.Flags().BoolVar(&flagProviderNetworkMirrorFallbackOnServerError, "network-mirror-fallback-on-server-error", false, "Serve from the mirror in case the upstream registry responds with a 5xx status code")
+	serverCmd.Flags().DurationVar(&flagProviderNetworkMirrorCacheTTL, "network-mirror-cache-ttl", 5*time.Minute, "Duration for which upstream metadata is cached by the pull-through mirror. Setting it to 0 disables the cache")
+	serverCmd.Flags().DurationVar(&flagProviderNetworkMirrorCacheStaleTTL, "network-mirror-cache-stale-ttl", time.Hour, "Duration after the cache TTL during which stale upstream metadata is served while being refreshed in the background")
+	serverCmd.Flags().DurationVar(&flagProviderNetworkMirrorCacheNegativeTTL, "network-mirror-cache-negative-ttl", time.Minute, "Duration for which upstream 404 responses are cached by the pull-through mirror")
+	serverCmd.Flags().StringVar(&flagProviderNetworkMirrorHealthCheckUpstream, "network-mirror-health-check-upstream", "", "Hostname of the upstream registry whose service discovery is part of the readiness check of the pull-through mirror, e.g. registry.terraform.io. The check is disabled if empty")
+
+	// Provider Network Mirror policy options
+	serverCmd.Flags().StringSliceVar(&flagProviderNetworkMirrorAllow, "network-mirror-allow", nil, "Patterns in the form of <hostname>/<namespace>/<name> of providers that are allowed in the network mirror, e.g. registry.terraform.io/hashicorp/*")
+	serverCmd.Flags().StringSliceVar(&flagProviderNetworkMirrorDeny, "network-mirror-deny", nil, "Patterns in the form of <hostname>/<namespace>/<name> of providers that are denied in the network mirror")
+	serverCmd.Flags().StringVar(&flagProviderNetworkMirrorVersionConstraints, "network-mirror-version-constraints", "", "Version constraints that providers in the network mirror have to meet, e.g. \">= 1.0, < 2.0\"")
+	serverCmd.Flags().BoolVar(&flagProviderNetworkMirrorAllowPrereleases, "network-mirror-allow-prereleases", true, "Allow pre-release versions of providers in the network mirror")
+	serverCmd.Flags().StringSliceVar(&flagProviderNetworkMirrorPlatforms, "network-mirror-platforms", nil, "Platforms in the form of <os>_<arch> that are allowed in the network mirror, e.g. linux_amd64")
+
+	// Provider Network Mirror retention options
+	serverCmd.Flags().DurationVar(&flagProviderNetworkMirrorAccessLogInterval, "network-mirror-access-log-interval", time.Minute, "Interval in which the last access of mirrored providers is written to the storage backend")
+	serverCmd.Flags().DurationVar(&flagProviderNetworkMirrorGCInterval, "network-mirror-gc-interval", 0, "Interval in which the garbage collection of mirrored providers runs. Disabled if set to 0")
+	serverCmd.Flags().IntVar(&flagProviderNetworkMirrorGCKeepLatest, "network-mirror-gc-keep-latest", 0, "Number of most recent versions to retain per mirrored provider. All versions are retained if set to 0")
+	serverCmd.Flags().DurationVar(&flagProviderNetworkMirrorGCMaxUnusedAge, "network-mirror-gc-max-unused-age", 0, "Delete platforms of mirrored providers that haven't been requested for the given duration. Disabled if set to 0")
+	serverCmd.Flags().StringArrayVar(&flagProviderNetworkMirrorGCPins, "network-mirror-gc-pin", nil, "Providers in the form of <hostname>/<namespace>/<name>[@<version constraints>] that are never deleted by the garbage collection")
 }
 
+// defaultStorageMetrics are shared by all storage backends that are set up by the command, unless tenants are served.
+// They are created on first use, as metrics without the tenant label can't be registered once tenants are served.
+var defaultStorageMetrics = sync.OnceValue(o11y.NewStorageMetrics)
+
 // TODO(oliviermichaelis): move to root, as the storage flags are defined in root?
 func setupStorage(ctx context.Context) (storage.Storage, error) {
+	return newStorage(ctx, config.DefaultTenant, storageFromFlags(), defaultStorageMetrics())
+}
+
+// newStorage returns the storage of the tenant, whose events are published with the name of the tenant
+func newStorage(ctx context.Context, tenant string, c *config.Storage, metrics *o11y.StorageMetrics) (storage.Storage, error) {
+	s, err := newStorageBackend(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	s = storage.NewInstrumentedStorage(s, metrics)
+
+	bus, err := setupEventBus()
+	if err != nil {
+		return nil, err
+	}
+	if bus != nil {
+		var publisher events.Publisher = bus
+		if tenant != config.DefaultTenant {
+			publisher = events.NewTenantPublisher(bus, tenant)
+		}
+		s = storage.NewEventStorage(s, publisher)
+	}
+	return s, nil
+}
+
+// newStorageBackend returns the configured storage backend.
+// Settings that aren't configured fall back to the values of the corresponding flags.
+func newStorageBackend(ctx context.Context, c *config.Storage) (storage.Storage, error) {
+	cdn, err := newCDN(c.CDN)
+	if err != nil {
+		return nil, err
+	}
+	var cacheMinValidity time.Duration
+	if c.SignedURLCache != nil {
+		cacheMinValidity = config.Duration(c.SignedURLCache.MinValidity)
+	}
+
 	switch {
-	case flagS3Bucket != "":
+	case c.S3 != nil:
+		pathStyle := flagS3PathStyle
+		if c.S3.PathStyle != nil {
+			pathStyle = *c.S3.PathStyle
+		}
 		return storage.NewS3Storage(ctx,
-			flagS3Bucket,
-			storage.WithS3StorageBucketPrefix(flagS3Prefix),
-			storage.WithS3StorageBucketRegion(flagS3Region),
-			storage.WithS3StorageBucketEndpoint(flagS3Endpoint),
-			storage.WithS3StoragePathStyle(flagS3PathStyle),
+			c.S3.Bucket,
+			storage.WithS3StorageBucketPrefix(c.S3.Prefix),
+			storage.WithS3StorageBucketRegion(c.S3.Region),
+			storage.WithS3StorageBucketEndpoint(c.S3.Endpoint),
+			storage.WithS3StoragePathStyle(pathStyle),
 			storage.WithS3ArchiveFormat(flagModuleArchiveFormat),
-			storage.WithS3StorageSignedUrlExpiry(flagS3SignedURLExpiry),
+			storage.WithS3StorageSignedUrlExpiry(durationOr(c.S3.SignedURLExpiry, flagS3SignedURLExpiry)),
+			storage.WithS3StorageSignedURLCache(cacheMinValidity),
+			storage.WithS3StorageCDN(cdn),
 		)
-	case flagGCSBucket != "":
-		return storage.NewGCSStorage(flagGCSBucket,
-			storage.WithGCSStorageBucketPrefix(flagGCSPrefix),
-			storage.WithGCSServiceAccount(flagGCSServiceAccount),
-			storage.WithGCSSignedUrlExpiry(flagGCSSignedURLExpiry),
+	case c.GCS != nil:
+		return storage.NewGCSStorage(c.GCS.Bucket,
+			storage.WithGCSStorageBucketPrefix(c.GCS.Prefix),
+			storage.WithGCSServiceAccount(c.GCS.ServiceAccount),
+			storage.WithGCSSignedUrlExpiry(durationOr(c.GCS.SignedURLExpiry, flagGCSSignedURLExpiry)),
 			storage.WithGCSArchiveFormat(flagModuleArchiveFormat),
+			storage.WithGCSSignedURLCache(cacheMinValidity),
+			storage.WithGCSCDN(cdn),
 		)
-	case flagAzureStorageContainer != "":
-		return storage.NewAzureStorage(flagAzureStorageAccount,
-			flagAzureStorageContainer,
-			storage.WithAzureStoragePrefix(flagAzureStoragePrefix),
+	case c.Azure != nil:
+		return storage.NewAzureStorage(c.Azure.Account,
+			c.Azure.Container,
+			storage.WithAzureStoragePrefix(c.Azure.Prefix),
 			storage.WithAzureStorageArchiveFormat(flagModuleArchiveFormat),
-			storage.WithAzureStorageSignedUrlExpiry(flagAzureStorageSignedURLExpiry),
+			storage.WithAzureStorageSignedUrlExpiry(durationOr(c.Azure.SignedURLExpiry, flagAzureStorageSignedURLExpiry)),
+			storage.WithAzureStorageSignedURLCache(cacheMinValidity),
+			storage.WithAzureStorageCDN(cdn),
 		)
 	default:
 		return nil, errors.New("storage provider is not specified")
 	}
 }
 
-func serveMux(ctx context.Context) (*http.ServeMux, error) {
-	mux := http.NewServeMux()
-
-	options := []discovery.Option{
-		discovery.WithModulesV1(fmt.Sprintf("%s/", prefixModules)),
-		discovery.WithProvidersV1(fmt.Sprintf("%s/", prefixProviders)),
+// newCDN returns the CDN, for which the download URLs are signed, or nil if none is configured
+func newCDN(c *config.CDN) (*storage.CDN, error) {
+	if c == nil {
+		return nil, nil
 	}
 
-	if flagLoginClient != "" {
-		login := &discovery.LoginV1{
-			Client: flagLoginClient,
-		}
-
-		if flagLoginGrantTypes != nil {
-			login.GrantTypes = flagLoginGrantTypes
-		}
-
-		if flagLoginAuthz != "" {
-			login.Authz = flagLoginAuthz
-		}
-
-		if flagLoginToken != "" {
-			login.Token = flagLoginToken
-		}
-
-		if flagLoginPorts != nil {
-			login.Ports = flagLoginPorts
+	var signer storage.CDNSigner
+	if c.Signer != "" {
+		key, err := os.ReadFile(c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the key of the CDN: %w", err)
 		}
-
-		if flagLoginScopes != nil {
-			login.Scopes = flagLoginScopes
+		signer, err = storage.NewCDNSigner(c.Signer, c.KeyID, key)
+		if err != nil {
+			return nil, err
 		}
-
-		options = append(options, discovery.WithLoginV1(login))
 	}
+	return storage.NewCDN(c.URL, signer)
+}
 
-	terraformJSON, err := json.Marshal(discovery.New(options...))
-	if err != nil {
-		return nil, err
+// durationOr returns the parsed duration of a validated setting, or the fallback if it isn't set
+func durationOr(value string, fallback time.Duration) time.Duration {
+	if d := config.Duration(value); d > 0 {
+		return d
 	}
+	return fallback
+}
 
-	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Content-type", "application/json")
-		w.Write(terraformJSON)
-	})
-
-	metrics := o11y.NewMetrics(nil)
-	instrumentation := o11y.NewMiddleware(metrics.Http)
+// serveMux returns the handlers of the main and the telemetry listener.
+// Only the telemetry listener reports the results of the readiness checks, the main listener reports whether they succeeded.
+func serveMux(ctx context.Context) (*http.ServeMux, *http.ServeMux, error) {
+	mux := http.NewServeMux()
 
 	registerMetrics(mux)
 
-	s, err := setupStorage(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	proxyUrlService := core.NewProxyUrlService(flagProxy, prefixProxy)
+	tenants := setupTenants()
 
-	if err := registerModule(mux, s, metrics.Module, instrumentation, proxyUrlService); err != nil {
-		return nil, err
-	}
-
-	if err := registerProvider(mux, s, metrics.Provider, instrumentation, proxyUrlService); err != nil {
-		return nil, err
+	// Metrics and health checks are only labelled with the tenant if tenants are served, so that they remain the same otherwise
+	multiTenant := len(tenants) > 1 || tenants[0].name != config.DefaultTenant
+	router := tenant.NewRouter()
+	checks := []health.Option{
+		health.WithCacheTTL(flagHealthCacheTTL),
+		health.WithTimeout(flagHealthCheckTimeout),
 	}
 
-	if flagProxy {
-		if err := registerProxy(mux, s, metrics.Proxy, instrumentation); err != nil {
-			return nil, err
+	for _, t := range tenants {
+		var metrics *o11y.ServerMetrics
+		var storageMetrics *o11y.StorageMetrics
+		var prefix string
+		if multiTenant {
+			metrics, storageMetrics, prefix = o11y.NewTenantMetrics(t.name, nil), o11y.NewTenantStorageMetrics(t.name), t.name+"/"
+		} else {
+			metrics, storageMetrics = o11y.NewMetrics(nil), defaultStorageMetrics()
 		}
-	}
 
-	if flagProviderNetworkMirrorEnabled {
-		var svc mirror.Service
-		if flagProviderNetworkMirrorPullThroughEnabled {
-			copier := mirror.NewCopier(ctx, s)
-			svc = mirror.NewPullThroughMirror(s, copier)
-		} else {
-			svc = mirror.NewMirror(s)
+		handler, s, err := t.serveMux(ctx, metrics, storageMetrics)
+		if err != nil {
+			if !multiTenant {
+				return nil, nil, err
+			}
+			return nil, nil, fmt.Errorf("failed to set up tenant %s: %w", t.name, err)
 		}
+		checks = append(checks, t.healthChecks(s, prefix)...)
 
-		if err := registerMirror(mux, s, svc, metrics.Mirror, instrumentation); err != nil {
-			return nil, err
+		if !multiTenant {
+			mux.Handle("/", handler)
+			continue
+		}
+		if t.name == config.DefaultTenant {
+			router.HandleDefault(t.name, handler)
+		} else if err := router.Handle(t.name, t.hostnames, handler); err != nil {
+			return nil, nil, err
 		}
+		slog.Info("serving tenant", slog.String("tenant", t.name), slog.Any("hostnames", t.hostnames))
 	}
 
-	return mux, nil
+	if multiTenant {
+		mux.Handle("/", router)
+	}
+	serverTenants = tenants
+
+	checker := health.NewChecker(checks...)
+	mux.Handle("/healthz", health.LivenessHandler())
+	mux.Handle("/readyz", health.StatusHandler(checker))
+
+	telemetryMux := http.NewServeMux()
+	telemetryMux.Handle("/readyz", health.ReadinessHandler(checker))
+	telemetryMux.Handle("/", mux)
+
+	return mux, telemetryMux, nil
 }
 
 func registerMetrics(mux *http.ServeMux) {
@@ -344,10 +541,14 @@ func registerMetrics(mux *http.ServeMux) {
 	mux.Handle("/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
 }
 
-func registerModule(mux *http.ServeMux, s storage.Storage, metrics *o11y.ModuleMetrics, instrumentation o11y.Middleware, proxyUrlService core.ProxyUrlService) error {
+func registerModule(mux *http.ServeMux, s module.Storage, metrics *o11y.ModuleMetrics, instrumentation o11y.Middleware, proxyUrlService core.ProxyUrlService, tracker *downloads.Tracker, authMiddleware endpoint.Middleware) error {
 	service := module.NewService(s, proxyUrlService)
 	{
+		if tracker != nil {
+			service = module.DownloadTrackingMiddleware(tracker)(service)
+		}
 		service = module.LoggingMiddleware()(service)
+		service = module.TracingMiddleware()(service)
 	}
 
 	opts := []httptransport.ServerOption{
@@ -363,7 +564,7 @@ func registerModule(mux *http.ServeMux, s storage.Storage, metrics *o11y.ModuleM
 			prefixModules,
 			module.MakeHandler(
 				service,
-				authMiddleware(),
+				authMiddleware,
 				metrics,
 				instrumentation,
 				opts...,
@@ -374,24 +575,14 @@ func registerModule(mux *http.ServeMux, s storage.Storage, metrics *o11y.ModuleM
 	return nil
 }
 
-func authMiddleware() endpoint.Middleware {
-	var providers []auth.Provider
-
-	if flagAuthStaticTokens != nil {
-		providers = append(providers, auth.NewStaticProvider(flagAuthStaticTokens...))
-	}
-
-	if flagAuthOktaIssuer != "" {
-		providers = append(providers, auth.NewOktaProvider(flagAuthOktaIssuer, flagAuthOktaClaims...))
-	}
-
-	return auth.Middleware(providers...)
-}
-
-func registerProvider(mux *http.ServeMux, s storage.Storage, metrics *o11y.ProviderMetrics, instrumentation o11y.Middleware, proxyUrlService core.ProxyUrlService) error {
+func registerProvider(mux *http.ServeMux, s provider.Storage, metrics *o11y.ProviderMetrics, instrumentation o11y.Middleware, proxyUrlService core.ProxyUrlService, tracker *downloads.Tracker, authMiddleware endpoint.Middleware) error {
 	service := provider.NewService(s, proxyUrlService)
 	{
+		if tracker != nil {
+			service = provider.DownloadTrackingMiddleware(tracker)(service)
+		}
 		service = provider.LoggingMiddleware()(service)
+		service = provider.TracingMiddleware()(service)
 	}
 
 	opts := []httptransport.ServerOption{
@@ -407,7 +598,7 @@ func registerProvider(mux *http.ServeMux, s storage.Storage, metrics *o11y.Provi
 			prefixProviders,
 			provider.MakeHandler(
 				service,
-				authMiddleware(),
+				authMiddleware,
 				metrics,
 				instrumentation,
 				opts...,
@@ -418,8 +609,62 @@ func registerProvider(mux *http.ServeMux, s storage.Storage, metrics *o11y.Provi
 	return nil
 }
 
-func registerMirror(mux *http.ServeMux, s storage.Storage, svc mirror.Service, metrics *o11y.MirrorMetrics, instrumentation o11y.Middleware) error {
-	service := mirror.LoggingMiddleware()(svc)
+func registerAdmin(mux *http.ServeMux, s storage.Storage, instrumentation o11y.Middleware, authMiddleware endpoint.Middleware) error {
+	opts := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(admin.ErrorEncoder),
+		httptransport.ServerBefore(
+			httptransport.PopulateRequestContext,
+		),
+	}
+
+	// The admin API is protected by its own tokens, as the tokens of the registry only grant read access
+	mux.Handle(
+		fmt.Sprintf(`%s/`, prefixAdmin),
+		http.StripPrefix(
+			prefixAdmin,
+			admin.MakeHandler(
+				admin.NewService(s),
+				authMiddleware,
+				instrumentation,
+				opts...,
+			),
+		),
+	)
+
+	return nil
+}
+
+func registerUI(mux *http.ServeMux, s storage.Storage, instrumentation o11y.Middleware, proxyUrlService core.ProxyUrlService, authMiddleware endpoint.Middleware, mirrorEnabled bool) error {
+	var catalog mirror.CatalogService
+	if mirrorEnabled {
+		catalog = mirror.NewCatalogService(s)
+	}
+
+	service := ui.NewService(
+		module.TracingMiddleware()(module.LoggingMiddleware()(module.NewService(s, proxyUrlService))),
+		provider.TracingMiddleware()(provider.LoggingMiddleware()(provider.NewService(s, proxyUrlService))),
+		catalog,
+	)
+
+	mux.Handle(
+		fmt.Sprintf(`%s/`, prefixUI),
+		http.StripPrefix(
+			prefixUI,
+			ui.MakeHandler(
+				service,
+				authMiddleware,
+				instrumentation,
+				prefixUI,
+				flagUISecureCookie,
+			),
+		),
+	)
+
+	return nil
+}
+
+func registerMirror(mux *http.ServeMux, s storage.Storage, svc mirror.Service, metrics *o11y.MirrorMetrics, instrumentation o11y.Middleware, authMiddleware endpoint.Middleware) error {
+	service := mirror.TracingMiddleware()(mirror.LoggingMiddleware()(svc))
 
 	opts := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(mirror.ErrorEncoder),
@@ -434,7 +679,7 @@ func registerMirror(mux *http.ServeMux, s storage.Storage, svc mirror.Service, m
 			prefixMirror,
 			mirror.MakeHandler(
 				service,
-				authMiddleware(),
+				authMiddleware,
 				metrics,
 				instrumentation,
 				opts...,
@@ -0,0 +1,507 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/boring-registry/boring-registry/pkg/auth"
+	"github.com/boring-registry/boring-registry/pkg/config"
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/discovery"
+	"github.com/boring-registry/boring-registry/pkg/downloads"
+	"github.com/boring-registry/boring-registry/pkg/health"
+	"github.com/boring-registry/boring-registry/pkg/mirror"
+	"github.com/boring-registry/boring-registry/pkg/module"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+	"github.com/boring-registry/boring-registry/pkg/provider"
+	"github.com/boring-registry/boring-registry/pkg/replica"
+	"github.com/boring-registry/boring-registry/pkg/storage"
+	"github.com/boring-registry/boring-registry/pkg/tenant"
+)
+
+// registryTenant is a registry with its own storage, authentication, discovery document and network mirror,
+// which is served for the hostnames of the tenant.
+// The default tenant is configured with flags and serves all hostnames that aren't served by another tenant.
+type registryTenant struct {
+	name      string
+	hostnames []string
+	config    *config.Config
+
+	// registryAuth and adminAuth verify the tokens of the tenant. Their providers are replaced when the configuration file is reloaded.
+	registryAuth *auth.Providers
+	adminAuth    *auth.Providers
+	// mirrorPolicy is nil if the network mirror isn't served. Its rules are replaced when the configuration file is reloaded.
+	mirrorPolicy *mirror.Policy
+}
+
+// serverTenants are the tenants served by the server, which are updated when the configuration file is reloaded
+var serverTenants []*registryTenant
+
+// setupTenants returns the default tenant and the tenants of the configuration file.
+// The default tenant is omitted if tenants are configured, but the flags don't configure a storage backend.
+func setupTenants() []*registryTenant {
+	defaults := flagsConfig()
+
+	var tenants []*registryTenant
+	if configFile == nil || len(configFile.Tenants) == 0 || defaults.Storage.S3 != nil || defaults.Storage.GCS != nil || defaults.Storage.Azure != nil {
+		tenants = append(tenants, newTenant(config.DefaultTenant, nil, defaults))
+	}
+	if configFile != nil {
+		for _, t := range configFile.Tenants {
+			tenants = append(tenants, newTenant(t.Name, t.Hostnames, tenantConfig(defaults, t)))
+		}
+	}
+	return tenants
+}
+
+func newTenant(name string, hostnames []string, c *config.Config) *registryTenant {
+	return &registryTenant{
+		name:         name,
+		hostnames:    hostnames,
+		config:       c,
+		registryAuth: auth.NewProviders(),
+		adminAuth:    auth.NewProviders(),
+	}
+}
+
+// flagsConfig returns the settings of the default tenant, which are configured with flags
+func flagsConfig() *config.Config {
+	claims := map[string]string{}
+	for _, claim := range flagAuthOktaClaims {
+		if key, value, ok := strings.Cut(claim, "="); ok {
+			claims[key] = value
+		}
+	}
+
+	c := &config.Config{
+		Log:     &config.Log{},
+		Storage: storageFromFlags(),
+		Auth: &config.Auth{
+			StaticTokens: flagAuthStaticTokens,
+		},
+		Admin: &config.Admin{
+			StaticTokens: flagAdminStaticTokens,
+		},
+		Mirror: &config.Mirror{
+			Enabled:     boolPtr(flagProviderNetworkMirrorEnabled),
+			PullThrough: boolPtr(flagProviderNetworkMirrorPullThroughEnabled),
+			Policy: &config.Policy{
+				Allow:              flagProviderNetworkMirrorAllow,
+				Deny:               flagProviderNetworkMirrorDeny,
+				VersionConstraints: flagProviderNetworkMirrorVersionConstraints,
+				AllowPrereleases:   boolPtr(flagProviderNetworkMirrorAllowPrereleases),
+				Platforms:          flagProviderNetworkMirrorPlatforms,
+			},
+			Upstreams: flagProviderNetworkMirrorUpstreams,
+		},
+		Replication: flagReplication,
+	}
+	if flagAuthOktaIssuer != "" {
+		c.Auth.Okta = &config.Okta{Issuer: flagAuthOktaIssuer, Claims: claims}
+	}
+	if flagReplicaUpstream != "" {
+		c.Replica = &config.Replica{
+			Upstream:        flagReplicaUpstream,
+			Token:           flagReplicaUpstreamToken,
+			Timeout:         flagReplicaUpstreamTimeout.String(),
+			TrustedKeysFile: flagReplicaTrustedKeysFile,
+		}
+	}
+	if flagLoginClient != "" {
+		c.Login = &config.Login{
+			Client:     flagLoginClient,
+			GrantTypes: flagLoginGrantTypes,
+			Authz:      flagLoginAuthz,
+			Token:      flagLoginToken,
+			Ports:      flagLoginPorts,
+			Scopes:     flagLoginScopes,
+		}
+	}
+	return c
+}
+
+// storageFromFlags returns the storage backend that is configured with flags
+func storageFromFlags() *config.Storage {
+	s := &config.Storage{}
+	switch {
+	case flagS3Bucket != "":
+		s.S3 = &config.S3{
+			Bucket:          flagS3Bucket,
+			Prefix:          flagS3Prefix,
+			Region:          flagS3Region,
+			Endpoint:        flagS3Endpoint,
+			PathStyle:       boolPtr(flagS3PathStyle),
+			SignedURLExpiry: flagS3SignedURLExpiry.String(),
+		}
+	case flagGCSBucket != "":
+		s.GCS = &config.GCS{
+			Bucket:          flagGCSBucket,
+			Prefix:          flagGCSPrefix,
+			ServiceAccount:  flagGCSServiceAccount,
+			SignedURLExpiry: flagGCSSignedURLExpiry.String(),
+		}
+	case flagAzureStorageContainer != "":
+		s.Azure = &config.Azure{
+			Account:         flagAzureStorageAccount,
+			Container:       flagAzureStorageContainer,
+			Prefix:          flagAzureStoragePrefix,
+			SignedURLExpiry: flagAzureStorageSignedURLExpiry.String(),
+		}
+	}
+	if flagStorageSignedURLCacheMinValidity > 0 {
+		s.SignedURLCache = &config.SignedURLCache{MinValidity: flagStorageSignedURLCacheMinValidity.String()}
+	}
+	if flagStorageCDNURL != "" {
+		s.CDN = &config.CDN{
+			URL:     flagStorageCDNURL,
+			Signer:  flagStorageCDNSigner,
+			KeyID:   flagStorageCDNKeyID,
+			KeyFile: flagStorageCDNKeyFile,
+		}
+	}
+	return s
+}
+
+// tenantConfig returns the settings of the tenant, which replace the settings of the default tenant.
+// The settings of the network mirror are replaced individually, so that a tenant can e.g. enable the pull-through mirror with the default policy.
+func tenantConfig(defaults *config.Config, t config.Tenant) *config.Config {
+	c := *defaults
+	c.Storage = t.Storage
+	c.Replication = t.Replication
+	c.Replica = t.Replica
+	// The tokens of the default tenant are never accepted, the configuration requires the auth of every tenant
+	c.Auth = t.Auth
+	if c.Auth == nil {
+		c.Auth = &config.Auth{}
+	}
+	if t.Admin != nil {
+		c.Admin = t.Admin
+	}
+	if t.Login != nil {
+		c.Login = t.Login
+	}
+	if m := t.Mirror; m != nil {
+		merged := *defaults.Mirror
+		if m.Enabled != nil {
+			merged.Enabled = m.Enabled
+		}
+		if m.PullThrough != nil {
+			merged.PullThrough = m.PullThrough
+		}
+		if m.Policy != nil {
+			merged.Policy = m.Policy
+		}
+		if len(m.Upstreams) > 0 {
+			merged.Upstreams = m.Upstreams
+		}
+		c.Mirror = &merged
+	}
+	return &c
+}
+
+// serveMux sets up the storage and serves the registry of the tenant
+func (t *registryTenant) serveMux(ctx context.Context, metrics *o11y.ServerMetrics, storageMetrics *o11y.StorageMetrics) (*http.ServeMux, storage.Storage, error) {
+	mux := http.NewServeMux()
+
+	terraformJSON, err := json.Marshal(discoveryDocument(t.config.Login))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "application/json")
+		w.Write(terraformJSON)
+	})
+
+	instrumentation := o11y.NewMiddleware(metrics.Http)
+
+	s, err := newStorage(ctx, t.name, t.config.Storage, storageMetrics)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proxyUrlService := core.NewProxyUrlService(flagProxy, prefixProxy)
+
+	var tracker *downloads.Tracker
+	if flagDownloadStats {
+		tracker = downloads.NewTracker(s)
+		go tracker.Run(ctx, flagDownloadStatsFlushInterval)
+	}
+
+	if r := t.config.Replication; r != nil && r.Interval != "" {
+		replicator, err := newReplicator(ctx, s, r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid replication: %w", err)
+		}
+		// The logs of the replication are labelled with the tenant like its requests
+		go replicator.Schedule(tenant.NewContext(ctx, t.name), config.Duration(r.Interval))
+	}
+
+	t.registryAuth.Set(authProviders(t.config.Auth)...)
+	authMiddleware := auth.ReloadableMiddleware(t.registryAuth)
+
+	// An edge registry reads the missing module and provider versions through from the central registry.
+	// The other parts of the registry, e.g. the UI and the admin API, only serve what is stored already.
+	var moduleStorage module.Storage = s
+	var providerStorage provider.Storage = s
+	if r := t.config.Replica; r != nil {
+		upstream := replica.NewUpstream(r.Upstream, replica.WithToken(r.Token), replica.WithTimeout(config.Duration(r.Timeout)))
+		var options []replica.ProviderStorageOption
+		if r.TrustedKeysFile != "" {
+			b, err := os.ReadFile(r.TrustedKeysFile)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read the trusted keys of the replica: %w", err)
+			}
+			keys, err := replica.ParseTrustedKeys(b)
+			if err != nil {
+				return nil, nil, err
+			}
+			options = append(options, replica.WithTrustedKeys(keys))
+		}
+		moduleStorage = replica.NewModuleStorage(s, upstream)
+		providerStorage = replica.NewProviderStorage(s, upstream, options...)
+	}
+
+	if err := registerModule(mux, moduleStorage, metrics.Module, instrumentation, proxyUrlService, tracker, authMiddleware); err != nil {
+		return nil, nil, err
+	}
+
+	if err := registerProvider(mux, providerStorage, metrics.Provider, instrumentation, proxyUrlService, tracker, authMiddleware); err != nil {
+		return nil, nil, err
+	}
+
+	if tokens := t.config.Admin.StaticTokens; len(tokens) > 0 {
+		t.adminAuth.Set(auth.NewStaticProvider(tokens...))
+		if err := registerAdmin(mux, s, instrumentation, auth.ReloadableMiddleware(t.adminAuth)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	mirrorEnabled := *t.config.Mirror.Enabled
+
+	if flagUI {
+		if err := registerUI(mux, s, instrumentation, proxyUrlService, authMiddleware, mirrorEnabled); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if flagProxy {
+		if err := registerProxy(mux, s, metrics.Proxy, instrumentation); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if mirrorEnabled {
+		policy, err := newMirrorPolicy(t.config.Mirror.Policy)
+		if err != nil {
+			return nil, nil, err
+		}
+		t.mirrorPolicy = policy
+
+		var svc mirror.Service
+		if *t.config.Mirror.PullThrough {
+			copier := mirror.NewCopier(ctx, s, mirror.WithCopierPolicy(policy))
+			svc = mirror.NewPullThroughMirror(s, copier,
+				mirror.WithUpstreamTimeout(flagProviderNetworkMirrorUpstreamTimeout),
+				mirror.WithFallbackOnServerError(flagProviderNetworkMirrorFallbackOnServerError),
+				mirror.WithCache(mirror.CacheConfig{
+					TTL:         flagProviderNetworkMirrorCacheTTL,
+					StaleTTL:    flagProviderNetworkMirrorCacheStaleTTL,
+					NegativeTTL: flagProviderNetworkMirrorCacheNegativeTTL,
+				}),
+				mirror.WithUpstreamRoutes(t.config.Mirror.Routes()),
+			)
+		} else {
+			svc = mirror.NewMirror(s)
+		}
+		svc = mirror.PolicyMiddleware(policy)(svc)
+
+		tracker := mirror.NewAccessTracker(s)
+		go tracker.Run(ctx, flagProviderNetworkMirrorAccessLogInterval)
+		svc = mirror.AccessTrackingMiddleware(tracker)(svc)
+
+		if flagProviderNetworkMirrorGCInterval > 0 {
+			gc, err := mirror.NewGarbageCollector(s,
+				mirror.WithKeepLatest(flagProviderNetworkMirrorGCKeepLatest),
+				mirror.WithMaxUnusedAge(flagProviderNetworkMirrorGCMaxUnusedAge),
+				mirror.WithPins(flagProviderNetworkMirrorGCPins...),
+			)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid network mirror garbage collection: %w", err)
+			}
+			go gc.Schedule(ctx, flagProviderNetworkMirrorGCInterval)
+		}
+
+		if err := registerMirror(mux, s, svc, metrics.Mirror, instrumentation, authMiddleware); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return mux, s, nil
+}
+
+// healthChecks returns the readiness checks of the tenant, whose names are prefixed with the tenant if tenants are served.
+// The readiness checks verify that the storage is reachable, that download URLs can be signed and that the signing keys can be parsed.
+// The service discovery of the upstream registry is checked as well, if the pull-through mirror is enabled and an upstream is configured.
+func (t *registryTenant) healthChecks(s storage.Storage, prefix string) []health.Option {
+	var options []health.Option
+
+	if checker, ok := s.(storage.HealthChecker); ok {
+		options = append(options,
+			health.WithCheck(prefix+"storage", checker.CheckReachable),
+			health.WithCheck(prefix+"presign", checker.CheckPresign),
+		)
+	}
+	options = append(options, health.WithCheck(prefix+"signing-keys", provider.NewSigningKeysCheck(s).Check))
+
+	if *t.config.Mirror.Enabled && *t.config.Mirror.PullThrough && flagProviderNetworkMirrorHealthCheckUpstream != "" {
+		hostname := flagProviderNetworkMirrorHealthCheckUpstream
+		if host, ok := t.config.Mirror.Routes()[strings.ToLower(hostname)]; ok {
+			hostname = host
+		}
+		options = append(options, health.WithCheck(prefix+"upstream", func(ctx context.Context) error {
+			return mirror.CheckUpstream(ctx, hostname)
+		}))
+	}
+
+	return options
+}
+
+// validateReload returns an error, if the reloadable settings of the tenant can't be replaced with the settings of c
+func (t *registryTenant) validateReload(c *config.Config) (*mirror.Policy, error) {
+	// The admin API is only registered on start, so it would be served without authentication
+	if t.adminAuth.Len() > 0 && len(c.Admin.StaticTokens) == 0 {
+		return nil, fmt.Errorf("the admin tokens of tenant %s cannot be removed while its admin API is served", t.name)
+	}
+	// Removing all tokens by mistake would serve the registry without authentication, which requires a restart instead
+	if t.registryAuth.Len() > 0 && len(authProviders(c.Auth)) == 0 {
+		return nil, fmt.Errorf("the authentication of tenant %s cannot be disabled by a reload, restart the server instead", t.name)
+	}
+
+	if t.mirrorPolicy == nil {
+		return nil, nil
+	}
+	policy, err := newMirrorPolicy(c.Mirror.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %s: %w", t.name, err)
+	}
+	return policy, nil
+}
+
+// reload replaces the tokens and the mirror policy of the tenant.
+// All other settings of the tenant require a restart.
+func (t *registryTenant) reload(c *config.Config, policy *mirror.Policy) {
+	logger := slog.Default().With(slog.String("tenant", t.name))
+
+	t.registryAuth.Set(authProviders(c.Auth)...)
+
+	if t.adminAuth.Len() > 0 {
+		t.adminAuth.Set(auth.NewStaticProvider(c.Admin.StaticTokens...))
+	} else if len(c.Admin.StaticTokens) > 0 {
+		logger.Warn("the admin API is only served after a restart")
+	}
+
+	if policy != nil {
+		t.mirrorPolicy.Update(policy)
+	}
+	t.config = c
+}
+
+// reloadTenants replaces the reloadable settings of the served tenants, after the settings of all tenants have been validated
+func reloadTenants(c *config.Config) error {
+	defaults := flagsConfig()
+	configs := map[string]*config.Config{config.DefaultTenant: defaults}
+	for _, t := range c.Tenants {
+		configs[t.Name] = tenantConfig(defaults, t)
+	}
+
+	policies := make([]*mirror.Policy, len(serverTenants))
+	for i, t := range serverTenants {
+		next, ok := configs[t.name]
+		if !ok {
+			return fmt.Errorf("tenant %s cannot be removed without a restart", t.name)
+		}
+		policy, err := t.validateReload(next)
+		if err != nil {
+			return err
+		}
+		policies[i] = policy
+	}
+
+	for i, t := range serverTenants {
+		t.reload(configs[t.name], policies[i])
+		delete(configs, t.name)
+	}
+
+	delete(configs, config.DefaultTenant)
+	for name := range configs {
+		slog.Warn("the added tenant is only served after a restart", slog.String("tenant", name))
+	}
+	return nil
+}
+
+func discoveryDocument(login *config.Login) *discovery.Discovery {
+	options := []discovery.Option{
+		discovery.WithModulesV1(fmt.Sprintf("%s/", prefixModules)),
+		discovery.WithProvidersV1(fmt.Sprintf("%s/", prefixProviders)),
+	}
+
+	if login != nil {
+		options = append(options, discovery.WithLoginV1(&discovery.LoginV1{
+			Client:     login.Client,
+			GrantTypes: login.GrantTypes,
+			Authz:      login.Authz,
+			Token:      login.Token,
+			Ports:      login.Ports,
+			Scopes:     login.Scopes,
+		}))
+	}
+
+	return discovery.New(options...)
+}
+
+func authProviders(a *config.Auth) []auth.Provider {
+	var providers []auth.Provider
+
+	if a.StaticTokens != nil {
+		providers = append(providers, auth.NewStaticProvider(a.StaticTokens...))
+	}
+
+	if okta := a.Okta; okta != nil && okta.Issuer != "" {
+		var claims []string
+		for key, value := range okta.Claims {
+			claims = append(claims, fmt.Sprintf("%s=%s", key, value))
+		}
+		sort.Strings(claims)
+		providers = append(providers, auth.NewOktaProvider(okta.Issuer, claims...))
+	}
+
+	return providers
+}
+
+func newMirrorPolicy(p *config.Policy) (*mirror.Policy, error) {
+	// Pre-releases are allowed by default
+	allowPrereleases := p.AllowPrereleases == nil || *p.AllowPrereleases
+
+	policy, err := mirror.NewPolicy(
+		mirror.WithAllowedProviders(p.Allow...),
+		mirror.WithDeniedProviders(p.Deny...),
+		mirror.WithVersionConstraints(p.VersionConstraints),
+		mirror.WithPrereleases(allowPrereleases),
+		mirror.WithPlatforms(p.Platforms...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network mirror policy: %w", err)
+	}
+	return policy, nil
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/boring-registry/boring-registry/pkg/auth"
+	"github.com/boring-registry/boring-registry/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	enabled, pullThrough := true, false
+	defaults := &config.Config{
+		Storage: &config.Storage{S3: &config.S3{Bucket: "default"}},
+		Auth:    &config.Auth{StaticTokens: []string{"default"}},
+		Admin:   &config.Admin{StaticTokens: []string{"admin"}},
+		Login:   &config.Login{Client: "default"},
+		Mirror: &config.Mirror{
+			Enabled:     &enabled,
+			PullThrough: &pullThrough,
+			Policy:      &config.Policy{Allow: []string{"registry.terraform.io/*/*"}},
+		},
+		Replication: &config.Replication{Destination: &config.Storage{GCS: &config.GCS{Bucket: "replica"}}},
+		Replica:     &config.Replica{Upstream: "registry.example.com"},
+	}
+
+	pullThroughTenant := true
+	c := tenantConfig(defaults, config.Tenant{
+		Name:      "payments",
+		Hostnames: []string{"registry.payments.example.com"},
+		Storage:   &config.Storage{GCS: &config.GCS{Bucket: "payments"}},
+		Auth:      &config.Auth{StaticTokens: []string{"payments"}},
+		Mirror:    &config.Mirror{PullThrough: &pullThroughTenant},
+	})
+
+	assert.Equal(&config.Storage{GCS: &config.GCS{Bucket: "payments"}}, c.Storage)
+	// Sections of the tenant replace the defaults as a whole
+	assert.Equal([]string{"payments"}, c.Auth.StaticTokens)
+	assert.Equal([]string{"admin"}, c.Admin.StaticTokens)
+	assert.Equal("default", c.Login.Client)
+	// The replication isn't inherited, as its destination belongs to the default tenant
+	assert.Nil(c.Replication)
+	// The replica isn't inherited, as the tenant is read through from another tenant of the central registry
+	assert.Nil(c.Replica)
+	// The settings of the mirror are inherited individually
+	assert.True(*c.Mirror.Enabled)
+	assert.True(*c.Mirror.PullThrough)
+	assert.Equal([]string{"registry.terraform.io/*/*"}, c.Mirror.Policy.Allow)
+	// The tokens of the default tenant are never accepted by another tenant
+	c = tenantConfig(defaults, config.Tenant{Name: "platform", Storage: &config.Storage{GCS: &config.GCS{Bucket: "platform"}}})
+	assert.Empty(c.Auth.StaticTokens)
+
+	// The defaults are left untouched
+	assert.False(*defaults.Mirror.PullThrough)
+	assert.Equal("default", defaults.Storage.S3.Bucket)
+}
+
+func TestRegistryTenant_validateReload(t *testing.T) {
+	assert := assert.New(t)
+
+	tenant := newTenant("payments", nil, &config.Config{})
+	tenant.registryAuth.Set(auth.NewStaticProvider("payments"))
+
+	// The authentication of the registry can't be removed by a reload
+	_, err := tenant.validateReload(&config.Config{Auth: &config.Auth{}, Admin: &config.Admin{}})
+	assert.ErrorContains(err, "cannot be disabled by a reload")
+
+	_, err = tenant.validateReload(&config.Config{Auth: &config.Auth{StaticTokens: []string{"rotated"}}, Admin: &config.Admin{}})
+	assert.NoError(err)
+
+	// The admin tokens can't be removed while the admin API is served
+	tenant.adminAuth.Set(auth.NewStaticProvider("admin"))
+	_, err = tenant.validateReload(&config.Config{Auth: &config.Auth{StaticTokens: []string{"rotated"}}, Admin: &config.Admin{}})
+	assert.ErrorContains(err, "admin tokens")
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
 	"github.com/boring-registry/boring-registry/pkg/provider"
 
 	"github.com/hashicorp/go-version"
@@ -28,6 +29,13 @@ var (
 	flagIgnoreExistingModule     bool
 	flagVersionConstraintsRegex  string
 	flagVersionConstraintsSemver string
+	flagVersionFromGit           bool
+	flagGitAllTags               bool
+	flagGitNamespace             string
+	flagDryRun                   bool
+	flagArchiveFormat            string
+	flagParallelism              int
+	flagReport                   string
 
 	// upload provider flags
 	flagFileSha256Sums       string
@@ -60,6 +68,16 @@ Can be combined with the -version-constraints-semver flag`)
 	uploadCmd.PersistentFlags().StringVar(&flagVersionConstraintsSemver, "version-constraints-semver", "", `Limit the module versions that are eligible for upload with version constraints.
 The version string has to be formatted as a string literal containing one or more conditions, which are separated by commas.
 Can be combined with the -version-constrained-regex flag`)
+	uploadCmd.PersistentFlags().BoolVar(&flagVersionFromGit, "version-from-git", false, `Derive the module versions from the git tags of the repository instead of boring-registry.hcl.
+Tags are either named like v1.4.0 for modules at the root of the repository, or prefixed with the module directory or name like vpc/v1.4.0.
+Each version is archived from the tree of its tag`)
+	uploadCmd.PersistentFlags().BoolVar(&flagGitAllTags, "git-all-tags", false, `Upload all tagged versions instead of only the tag pointing at HEAD.
+Can be combined with the -version-constraints-semver flag to upload a range of historic versions`)
+	uploadCmd.PersistentFlags().StringVar(&flagGitNamespace, "git-namespace", "", "The namespace of modules without boring-registry.hcl, whose name and provider are derived from the terraform-<provider>-<name> naming convention")
+	uploadCmd.PersistentFlags().StringVar(&flagArchiveFormat, "archive-format", module.DefaultArchiveFormat, "The archive format of modules (tar.gz, tgz, tar.xz, txz, tar or zip), unless the format is set in the package block of boring-registry.hcl")
+	uploadCmd.PersistentFlags().IntVar(&flagParallelism, "parallelism", 4, "The number of modules that are uploaded concurrently")
+	uploadCmd.PersistentFlags().StringVar(&flagReport, "report", "", "Write a JSON report of the uploaded, skipped, conflicting and failed modules to the file, or to stdout if set to -")
+	uploadCmd.PersistentFlags().BoolVar(&flagDryRun, "dry-run", false, "List the files that would be packaged for each module without uploading them")
 }
 
 // uploadCmd uploads modules for legacy reasons.
@@ -85,9 +103,13 @@ var uploadProviderCmd = &cobra.Command{
 }
 
 func uploadModule(cmd *cobra.Command, args []string) error {
-	storageBackend, err := setupStorage(context.Background())
-	if err != nil {
-		return fmt.Errorf("failed to set up storage: %w", err)
+	// A dry run doesn't access the storage, so it doesn't require any storage configuration
+	var storageBackend module.Storage
+	if !flagDryRun {
+		var err error
+		if storageBackend, err = setupStorage(context.Background()); err != nil {
+			return fmt.Errorf("failed to set up storage: %w", err)
+		}
 	}
 
 	if len(args) == 0 {
@@ -98,6 +120,10 @@ func uploadModule(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if !module.IsArchiveFormat(flagArchiveFormat) {
+		return fmt.Errorf("unsupported archive format %q, expected one of %v", flagArchiveFormat, module.ArchiveFormats)
+	}
+
 	// Validate the semver version constraints
 	if flagVersionConstraintsSemver != "" {
 		constraints, err := version.NewConstraint(flagVersionConstraintsSemver)
@@ -116,6 +142,10 @@ func uploadModule(cmd *cobra.Command, args []string) error {
 		versionConstraintsRegex = constraints
 	}
 
+	if flagVersionFromGit {
+		return archiveModulesFromGit(args[0], storageBackend)
+	}
+
 	return archiveModules(args[0], storageBackend)
 }
 
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/events"
+
+	"github.com/spf13/cobra"
+)
+
+// eventBusCloseTimeout is how long the pending events are delivered when the command terminates
+const eventBusCloseTimeout = 30 * time.Second
+
+// eventBus is shared by all storages that are set up by the command, so that its events are delivered before exiting
+var eventBus *events.Bus
+
+func init() {
+	rootCmd.AddCommand(webhookCmd)
+	webhookCmd.AddCommand(webhookPingCmd)
+}
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Manage the webhooks to which events are delivered",
+}
+
+var webhookPingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Deliver a ping event to the configured webhooks",
+	Long: `Deliver a ping event once to each webhook configured with --webhook-url, without retrying failed deliveries.
+The command fails if any webhook didn't respond with a 2xx status code`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		webhooks, err := setupWebhooks()
+		if err != nil {
+			return err
+		}
+		if len(webhooks) == 0 {
+			return fmt.Errorf("no webhook configured, set --webhook-url")
+		}
+
+		ctx := context.Background()
+		var errs []error
+		for i, w := range webhooks {
+			err := w.Deliver(ctx, events.NewPingEvent())
+			_ = w.Close(ctx)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to deliver ping event to webhook %d: %w", i+1, err))
+				continue
+			}
+			slog.Info("successfully delivered ping event", slog.Int("webhook", i+1))
+		}
+		return errors.Join(errs...)
+	},
+}
+
+func setupWebhooks() ([]*events.Webhook, error) {
+	deadLetters := events.NewLogDeadLetterLog()
+	if flagWebhookDeadLetterFile != "" {
+		deadLetters = events.NewFileDeadLetterLog(flagWebhookDeadLetterFile)
+	}
+
+	var webhooks []*events.Webhook
+	for _, u := range flagWebhookURLs {
+		w, err := events.NewWebhook(u,
+			events.WithSecret(flagWebhookSecret),
+			events.WithEventTypes(flagWebhookEvents...),
+			events.WithTenants(flagWebhookTenants...),
+			events.WithRetries(flagWebhookRetries),
+			events.WithRetryBackoff(flagWebhookRetryBackoff),
+			events.WithTimeout(flagWebhookTimeout),
+			events.WithDeadLetterLog(deadLetters),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up webhook: %w", err)
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, nil
+}
+
+// setupEventBus returns the event bus of the command, which is nil if no webhook is configured
+func setupEventBus() (*events.Bus, error) {
+	if eventBus != nil || len(flagWebhookURLs) == 0 {
+		return eventBus, nil
+	}
+
+	webhooks, err := setupWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	subscribers := make([]events.Subscriber, 0, len(webhooks))
+	for _, w := range webhooks {
+		subscribers = append(subscribers, w)
+	}
+	eventBus = events.NewBus(subscribers...)
+	return eventBus, nil
+}
+
+// closeEventBus delivers the pending events, if the command has set up the event bus
+func closeEventBus() {
+	if eventBus == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventBusCloseTimeout)
+	defer cancel()
+	if err := eventBus.Close(ctx); err != nil {
+		slog.Error("failed to deliver pending events", slog.String("err", err.Error()))
+	}
+}
@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type moduleVersionRequest struct {
+	namespace   string
+	name        string
+	provider    string
+	version     string
+	annotations core.Annotations
+}
+
+type providerVersionRequest struct {
+	namespace   string
+	name        string
+	version     string
+	annotations core.Annotations
+}
+
+// noContentResponse is the response of endpoints without a response body
+type noContentResponse struct{}
+
+func getModuleAnnotationsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(moduleVersionRequest)
+
+		return svc.GetModuleAnnotations(ctx, req.namespace, req.name, req.provider, req.version)
+	}
+}
+
+func setModuleAnnotationsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(moduleVersionRequest)
+
+		return svc.SetModuleAnnotations(ctx, req.namespace, req.name, req.provider, req.version, req.annotations)
+	}
+}
+
+func deleteModuleAnnotationsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(moduleVersionRequest)
+
+		return noContentResponse{}, svc.DeleteModuleAnnotations(ctx, req.namespace, req.name, req.provider, req.version)
+	}
+}
+
+func getProviderAnnotationsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(providerVersionRequest)
+
+		return svc.GetProviderAnnotations(ctx, req.namespace, req.name, req.version)
+	}
+}
+
+func setProviderAnnotationsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(providerVersionRequest)
+
+		return svc.SetProviderAnnotations(ctx, req.namespace, req.name, req.version, req.annotations)
+	}
+}
+
+func deleteProviderAnnotationsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(providerVersionRequest)
+
+		return noContentResponse{}, svc.DeleteProviderAnnotations(ctx, req.namespace, req.name, req.version)
+	}
+}
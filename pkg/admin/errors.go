@@ -0,0 +1,10 @@
+package admin
+
+import "errors"
+
+var (
+	// ErrVersionNotFound is returned for annotations of module or provider versions that don't exist
+	ErrVersionNotFound = errors.New("failed to locate version")
+	// ErrInvalidAnnotations is returned for annotations that can't be stored
+	ErrInvalidAnnotations = errors.New("invalid annotations")
+)
@@ -0,0 +1,166 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
+)
+
+// Storage is the subset of the storage that's required to manage the annotations of module and provider versions
+type Storage interface {
+	GetModule(ctx context.Context, namespace, name, provider, version string) (core.Module, error)
+	GetModuleAnnotations(ctx context.Context, namespace, name, provider string) (core.VersionAnnotations, error)
+	UpdateModuleAnnotations(ctx context.Context, namespace, name, provider string, update func(core.VersionAnnotations)) error
+	ListProviderVersions(ctx context.Context, namespace, name string) (*core.ProviderVersions, error)
+	GetProviderAnnotations(ctx context.Context, namespace, name string) (core.VersionAnnotations, error)
+	UpdateProviderAnnotations(ctx context.Context, namespace, name string, update func(core.VersionAnnotations)) error
+}
+
+// Service manages the annotations of module and provider versions.
+// Setting annotations replaces the existing annotations of the version.
+type Service interface {
+	GetModuleAnnotations(ctx context.Context, namespace, name, provider, version string) (*core.Annotations, error)
+	SetModuleAnnotations(ctx context.Context, namespace, name, provider, version string, annotations core.Annotations) (*core.Annotations, error)
+	DeleteModuleAnnotations(ctx context.Context, namespace, name, provider, version string) error
+	GetProviderAnnotations(ctx context.Context, namespace, name, version string) (*core.Annotations, error)
+	SetProviderAnnotations(ctx context.Context, namespace, name, version string, annotations core.Annotations) (*core.Annotations, error)
+	DeleteProviderAnnotations(ctx context.Context, namespace, name, version string) error
+}
+
+type service struct {
+	storage Storage
+}
+
+// NewService returns a fully initialized Service.
+func NewService(storage Storage) Service {
+	return &service{
+		storage: storage,
+	}
+}
+
+func (s *service) GetModuleAnnotations(ctx context.Context, namespace, name, provider, version string) (*core.Annotations, error) {
+	if err := s.moduleExists(ctx, namespace, name, provider, version); err != nil {
+		return nil, err
+	}
+
+	annotations, err := s.storage.GetModuleAnnotations(ctx, namespace, name, provider)
+	if err != nil {
+		return nil, err
+	}
+	a := annotations[version]
+	return &a, nil
+}
+
+func (s *service) SetModuleAnnotations(ctx context.Context, namespace, name, provider, version string, a core.Annotations) (*core.Annotations, error) {
+	if err := validateAnnotations(a); err != nil {
+		return nil, err
+	}
+	if err := s.moduleExists(ctx, namespace, name, provider, version); err != nil {
+		return nil, err
+	}
+
+	// All versions of a module share one object, which is updated with a conditional write, as other replicas and the CLI update it as well
+	if err := s.storage.UpdateModuleAnnotations(ctx, namespace, name, provider, setAnnotations(version, a)); err != nil {
+		return nil, err
+	}
+
+	slog.Info("module annotations updated", slog.String("module", fmt.Sprintf("%s/%s/%s/%s", namespace, name, provider, version)), slog.Bool("deprecated", a.Deprecation != nil))
+	return &a, nil
+}
+
+func (s *service) DeleteModuleAnnotations(ctx context.Context, namespace, name, provider, version string) error {
+	_, err := s.SetModuleAnnotations(ctx, namespace, name, provider, version, core.Annotations{})
+	return err
+}
+
+func (s *service) GetProviderAnnotations(ctx context.Context, namespace, name, version string) (*core.Annotations, error) {
+	if err := s.providerExists(ctx, namespace, name, version); err != nil {
+		return nil, err
+	}
+
+	annotations, err := s.storage.GetProviderAnnotations(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	a := annotations[version]
+	return &a, nil
+}
+
+func (s *service) SetProviderAnnotations(ctx context.Context, namespace, name, version string, a core.Annotations) (*core.Annotations, error) {
+	if err := validateAnnotations(a); err != nil {
+		return nil, err
+	}
+	if err := s.providerExists(ctx, namespace, name, version); err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.UpdateProviderAnnotations(ctx, namespace, name, setAnnotations(version, a)); err != nil {
+		return nil, err
+	}
+
+	slog.Info("provider annotations updated", slog.String("provider", fmt.Sprintf("%s/%s/%s", namespace, name, version)), slog.Bool("deprecated", a.Deprecation != nil))
+	return &a, nil
+}
+
+func (s *service) DeleteProviderAnnotations(ctx context.Context, namespace, name, version string) error {
+	_, err := s.SetProviderAnnotations(ctx, namespace, name, version, core.Annotations{})
+	return err
+}
+
+// setAnnotations returns an update that replaces the annotations of the version, versions with empty annotations are removed
+func setAnnotations(version string, a core.Annotations) func(core.VersionAnnotations) {
+	return func(annotations core.VersionAnnotations) {
+		if a.IsZero() {
+			delete(annotations, version)
+		} else {
+			annotations[version] = a
+		}
+	}
+}
+
+func (s *service) moduleExists(ctx context.Context, namespace, name, provider, version string) error {
+	if _, err := s.storage.GetModule(ctx, namespace, name, provider, version); errors.Is(err, module.ErrModuleNotFound) {
+		return fmt.Errorf("%w: %s/%s/%s/%s", ErrVersionNotFound, namespace, name, provider, version)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *service) providerExists(ctx context.Context, namespace, name, version string) error {
+	res, err := s.storage.ListProviderVersions(ctx, namespace, name)
+	var providerError *core.ProviderError
+	if errors.As(err, &providerError) && providerError.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s/%s/%s", ErrVersionNotFound, namespace, name, version)
+	} else if err != nil {
+		return err
+	}
+
+	for _, v := range res.Versions {
+		if v.Version == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s/%s/%s", ErrVersionNotFound, namespace, name, version)
+}
+
+func validateAnnotations(a core.Annotations) error {
+	if a.Deprecation != nil && a.Deprecation.Link != "" {
+		u, err := url.Parse(a.Deprecation.Link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: the deprecation link %q isn't an http(s) URL", ErrInvalidAnnotations, a.Deprecation.Link)
+		}
+	}
+	for k := range a.Labels {
+		if k == "" {
+			return fmt.Errorf("%w: label keys must not be empty", ErrInvalidAnnotations)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/stretchr/testify/assert"
+)
+
+type testStorage struct {
+	module.Storage
+	providers   map[string][]string
+	annotations map[string]core.VersionAnnotations
+}
+
+func newTestStorage() *testStorage {
+	return &testStorage{
+		Storage:     module.NewInmemStorage(),
+		providers:   map[string][]string{"acme/dummy": {"1.0.0", "1.1.0"}},
+		annotations: map[string]core.VersionAnnotations{},
+	}
+}
+
+func (s *testStorage) ListProviderVersions(_ context.Context, namespace, name string) (*core.ProviderVersions, error) {
+	versions, ok := s.providers[namespace+"/"+name]
+	if !ok {
+		return nil, &core.ProviderError{Reason: "failed to find matching providers", Provider: &core.Provider{Namespace: namespace, Name: name}, StatusCode: http.StatusNotFound}
+	}
+	res := &core.ProviderVersions{}
+	for _, v := range versions {
+		res.Versions = append(res.Versions, core.ProviderVersion{Namespace: namespace, Name: name, Version: v})
+	}
+	return res, nil
+}
+
+func (s *testStorage) GetProviderAnnotations(_ context.Context, namespace, name string) (core.VersionAnnotations, error) {
+	annotations := core.VersionAnnotations{}
+	for v, a := range s.annotations[namespace+"/"+name] {
+		annotations[v] = a
+	}
+	return annotations, nil
+}
+
+func (s *testStorage) UpdateProviderAnnotations(_ context.Context, namespace, name string, update func(core.VersionAnnotations)) error {
+	if s.annotations[namespace+"/"+name] == nil {
+		s.annotations[namespace+"/"+name] = core.VersionAnnotations{}
+	}
+	update(s.annotations[namespace+"/"+name])
+	return nil
+}
+
+func TestService_ModuleAnnotations(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		ctx     = context.Background()
+		storage = newTestStorage()
+		svc     = NewService(storage)
+	)
+	_, err := storage.UploadModule(ctx, "acme", "vpc", "aws", "1.0.0", "", strings.NewReader(""))
+	assert.NoError(err)
+
+	_, err = svc.SetModuleAnnotations(ctx, "acme", "vpc", "aws", "2.0.0", core.Annotations{Labels: map[string]string{"team": "networking"}})
+	assert.ErrorIs(err, ErrVersionNotFound)
+
+	_, err = svc.SetModuleAnnotations(ctx, "acme", "vpc", "aws", "1.0.0", core.Annotations{Deprecation: &core.Deprecation{Link: "vpc/2.0.0"}})
+	assert.ErrorIs(err, ErrInvalidAnnotations)
+
+	expected := core.Annotations{Deprecation: &core.Deprecation{Reason: "use 2.x", Link: "https://example.com/vpc"}}
+	_, err = svc.SetModuleAnnotations(ctx, "acme", "vpc", "aws", "1.0.0", expected)
+	assert.NoError(err)
+
+	a, err := svc.GetModuleAnnotations(ctx, "acme", "vpc", "aws", "1.0.0")
+	assert.NoError(err)
+	assert.Equal(&expected, a)
+
+	assert.NoError(svc.DeleteModuleAnnotations(ctx, "acme", "vpc", "aws", "1.0.0"))
+	annotations, err := storage.GetModuleAnnotations(ctx, "acme", "vpc", "aws")
+	assert.NoError(err)
+	assert.Empty(annotations)
+}
+
+func TestMakeHandler_ProviderAnnotations(t *testing.T) {
+	storage := newTestStorage()
+	handler := MakeHandler(
+		NewService(storage),
+		func(next endpoint.Endpoint) endpoint.Endpoint { return next },
+		o11y.NewMiddleware(o11y.NewMetrics(nil).Http),
+		httptransport.ServerErrorEncoder(ErrorEncoder),
+	)
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "set annotations",
+			method:         http.MethodPut,
+			path:           "/providers/acme/dummy/1.0.0/annotations",
+			body:           `{"deprecation":{"reason":"use 1.1.0"},"labels":{"support":"none"}}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"deprecation":{"reason":"use 1.1.0"},"labels":{"support":"none"}}`,
+		},
+		{
+			name:           "get annotations",
+			method:         http.MethodGet,
+			path:           "/providers/acme/dummy/1.0.0/annotations",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"deprecation":{"reason":"use 1.1.0"},"labels":{"support":"none"}}`,
+		},
+		{
+			name:           "unknown field",
+			method:         http.MethodPut,
+			path:           "/providers/acme/dummy/1.0.0/annotations",
+			body:           `{"deprecated":true}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown version",
+			method:         http.MethodGet,
+			path:           "/providers/acme/dummy/2.0.0/annotations",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unknown provider",
+			method:         http.MethodGet,
+			path:           "/providers/acme/unknown/1.0.0/annotations",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "delete annotations",
+			method:         http.MethodDelete,
+			path:           "/providers/acme/dummy/1.0.0/annotations",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "get deleted annotations",
+			method:         http.MethodGet,
+			path:           "/providers/acme/dummy/1.0.0/annotations",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{}`,
+		},
+	}
+
+	// The test cases depend on each other and aren't run in parallel
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
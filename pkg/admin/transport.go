@@ -0,0 +1,137 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+type muxVar string
+
+const (
+	varNamespace muxVar = "namespace"
+	varName      muxVar = "name"
+	varProvider  muxVar = "provider"
+	varVersion   muxVar = "version"
+)
+
+// MakeHandler returns a fully initialized http.Handler.
+func MakeHandler(svc Service, auth endpoint.Middleware, instrumentation o11y.Middleware, options ...httptransport.ServerOption) http.Handler {
+	r := mux.NewRouter().StrictSlash(true)
+
+	handle := func(method, path string, e endpoint.Endpoint, dec httptransport.DecodeRequestFunc, enc httptransport.EncodeResponseFunc, vars ...muxVar) {
+		r.Methods(method).Path(path).Handler(
+			instrumentation.WrapHandler(
+				httptransport.NewServer(
+					auth(e),
+					dec,
+					enc,
+					append(
+						options,
+						httptransport.ServerBefore(extractMuxVars(vars...)),
+						httptransport.ServerBefore(jwt.HTTPToContext()),
+					)...,
+				),
+			),
+		)
+	}
+
+	moduleVars := []muxVar{varNamespace, varName, varProvider, varVersion}
+	modulePath := `/modules/{namespace}/{name}/{provider}/{version}/annotations`
+	handle("GET", modulePath, getModuleAnnotationsEndpoint(svc), decodeModuleVersionRequest, httptransport.EncodeJSONResponse, moduleVars...)
+	handle("PUT", modulePath, setModuleAnnotationsEndpoint(svc), decodeModuleVersionRequest, httptransport.EncodeJSONResponse, moduleVars...)
+	handle("DELETE", modulePath, deleteModuleAnnotationsEndpoint(svc), decodeModuleVersionRequest, encodeNoContentResponse, moduleVars...)
+
+	providerVars := []muxVar{varNamespace, varName, varVersion}
+	providerPath := `/providers/{namespace}/{name}/{version}/annotations`
+	handle("GET", providerPath, getProviderAnnotationsEndpoint(svc), decodeProviderVersionRequest, httptransport.EncodeJSONResponse, providerVars...)
+	handle("PUT", providerPath, setProviderAnnotationsEndpoint(svc), decodeProviderVersionRequest, httptransport.EncodeJSONResponse, providerVars...)
+	handle("DELETE", providerPath, deleteProviderAnnotationsEndpoint(svc), decodeProviderVersionRequest, encodeNoContentResponse, providerVars...)
+
+	return r
+}
+
+func decodeModuleVersionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req moduleVersionRequest
+	for k, v := range map[muxVar]*string{varNamespace: &req.namespace, varName: &req.name, varProvider: &req.provider, varVersion: &req.version} {
+		s, ok := ctx.Value(k).(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", core.ErrVarMissing, k)
+		}
+		*v = s
+	}
+
+	if r.Method == http.MethodPut {
+		if err := decodeAnnotations(r, &req.annotations); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func decodeProviderVersionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req providerVersionRequest
+	for k, v := range map[muxVar]*string{varNamespace: &req.namespace, varName: &req.name, varVersion: &req.version} {
+		s, ok := ctx.Value(k).(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", core.ErrVarMissing, k)
+		}
+		*v = s
+	}
+
+	if r.Method == http.MethodPut {
+		if err := decodeAnnotations(r, &req.annotations); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func decodeAnnotations(r *http.Request, a *core.Annotations) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(a); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAnnotations, err)
+	}
+	return nil
+}
+
+func encodeNoContentResponse(_ context.Context, w http.ResponseWriter, _ interface{}) error {
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// ErrorEncoder translates domain specific errors to HTTP status codes
+func ErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
+	if errors.Is(err, ErrVersionNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+	} else if errors.Is(err, ErrInvalidAnnotations) {
+		w.WriteHeader(http.StatusBadRequest)
+	} else {
+		w.WriteHeader(core.GenericError(err))
+	}
+
+	core.HandleErrorResponse(err, w)
+}
+
+func extractMuxVars(keys ...muxVar) httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		for _, k := range keys {
+			if v, ok := mux.Vars(r)[string(k)]; ok {
+				ctx = context.WithValue(ctx, k, v)
+			}
+		}
+
+		return ctx
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
 
@@ -40,3 +41,43 @@ func Middleware(providers ...Provider) endpoint.Middleware {
 		}
 	}
 }
+
+// Providers are the providers of a ReloadableMiddleware, which can be replaced at runtime, e.g. when the configuration is reloaded
+type Providers struct {
+	mu        sync.RWMutex
+	providers []Provider
+}
+
+// Set replaces the providers
+func (p *Providers) Set(providers ...Provider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.providers = providers
+}
+
+// Len returns the number of providers
+func (p *Providers) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.providers)
+}
+
+func (p *Providers) current() []Provider {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.providers
+}
+
+// NewProviders returns Providers with the given initial providers
+func NewProviders(providers ...Provider) *Providers {
+	return &Providers{providers: providers}
+}
+
+// ReloadableMiddleware behaves like Middleware, but verifies each request with the current providers
+func ReloadableMiddleware(providers *Providers) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			return Middleware(providers.current()...)(next)(ctx, request)
+		}
+	}
+}
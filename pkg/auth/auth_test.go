@@ -52,3 +52,23 @@ func TestAuthMiddleware(t *testing.T) {
 func nopEndpoint(ctx context.Context, request interface{}) (interface{}, error) {
 	return true, nil
 }
+
+func TestReloadableMiddleware(t *testing.T) {
+	assert := assert.New(t)
+
+	providers := NewProviders(NewStaticProvider("foo"))
+	e := ReloadableMiddleware(providers)(nopEndpoint)
+	ctx := context.WithValue(context.Background(), jwt.JWTContextKey, "foo")
+
+	_, err := e(ctx, nil)
+	assert.NoError(err)
+
+	providers.Set(NewStaticProvider("bar"))
+	_, err = e(ctx, nil)
+	assert.Error(err)
+
+	// Requests aren't authenticated once all providers are removed
+	providers.Set()
+	_, err = e(context.Background(), nil)
+	assert.NoError(err)
+}
@@ -0,0 +1,521 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/mirror"
+	"github.com/boring-registry/boring-registry/pkg/storage"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"gopkg.in/yaml.v3"
+)
+
+// LogLevels are the supported values of log.level
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// Config is the configuration file of the boring-registry.
+// Every setting is optional and takes precedence over the default of the corresponding flag,
+// but flags and environment variables that are set explicitly take precedence over the configuration file.
+type Config struct {
+	Log     *Log     `yaml:"log" hcl:"log,block"`
+	Storage *Storage `yaml:"storage" hcl:"storage,block"`
+	Auth    *Auth    `yaml:"auth" hcl:"auth,block"`
+	Admin   *Admin   `yaml:"admin" hcl:"admin,block"`
+	Login   *Login   `yaml:"login" hcl:"login,block"`
+	Mirror  *Mirror  `yaml:"mirror" hcl:"mirror,block"`
+	// Replication copies the objects of the storage to another storage backend
+	Replication *Replication `yaml:"replication" hcl:"replication,block"`
+	// Replica reads the modules and providers, which are missing in the storage, through from a central registry
+	Replica *Replica `yaml:"replica" hcl:"replica,block"`
+	Tenants []Tenant `yaml:"tenants" hcl:"tenant,block"`
+}
+
+// Log configures the logging. The level is reloadable.
+type Log struct {
+	Level string `yaml:"level" hcl:"level,optional"`
+	JSON  *bool  `yaml:"json" hcl:"json,optional"`
+}
+
+// Storage configures exactly one storage backend
+type Storage struct {
+	S3    *S3    `yaml:"s3" hcl:"s3,block"`
+	GCS   *GCS   `yaml:"gcs" hcl:"gcs,block"`
+	Azure *Azure `yaml:"azure" hcl:"azure,block"`
+	// SignedURLCache reuses the signed download URLs of the storage backend
+	SignedURLCache *SignedURLCache `yaml:"signed_url_cache" hcl:"signed_url_cache,block"`
+	// CDN returns download URLs on the domain of a CDN, whose origin is the storage backend
+	CDN *CDN `yaml:"cdn" hcl:"cdn,block"`
+}
+
+type S3 struct {
+	Bucket          string `yaml:"bucket" hcl:"bucket"`
+	Prefix          string `yaml:"prefix" hcl:"prefix,optional"`
+	Region          string `yaml:"region" hcl:"region,optional"`
+	Endpoint        string `yaml:"endpoint" hcl:"endpoint,optional"`
+	PathStyle       *bool  `yaml:"path_style" hcl:"path_style,optional"`
+	SignedURLExpiry string `yaml:"signed_url_expiry" hcl:"signed_url_expiry,optional"`
+}
+
+type GCS struct {
+	Bucket          string `yaml:"bucket" hcl:"bucket"`
+	Prefix          string `yaml:"prefix" hcl:"prefix,optional"`
+	ServiceAccount  string `yaml:"service_account" hcl:"service_account,optional"`
+	SignedURLExpiry string `yaml:"signed_url_expiry" hcl:"signed_url_expiry,optional"`
+}
+
+type Azure struct {
+	Account         string `yaml:"account" hcl:"account"`
+	Container       string `yaml:"container" hcl:"container"`
+	Prefix          string `yaml:"prefix" hcl:"prefix,optional"`
+	SignedURLExpiry string `yaml:"signed_url_expiry" hcl:"signed_url_expiry,optional"`
+}
+
+// SignedURLCache reuses a signed download URL of an object until it's valid for less than MinValidity
+type SignedURLCache struct {
+	MinValidity string `yaml:"min_validity" hcl:"min_validity"`
+}
+
+// CDN signs the download URLs for the CDN at URL instead of the storage backend.
+// The URLs aren't signed if Signer is empty.
+type CDN struct {
+	URL    string `yaml:"url" hcl:"url"`
+	Signer string `yaml:"signer" hcl:"signer,optional"`
+	// KeyID is the key pair ID of CloudFront or the key name of Cloud CDN
+	KeyID string `yaml:"key_id" hcl:"key_id,optional"`
+	// KeyFile contains the PEM encoded private key of CloudFront or the base64url encoded key of Cloud CDN
+	KeyFile string `yaml:"key_file" hcl:"key_file,optional"`
+}
+
+// Auth configures the providers that verify the tokens of the registry. It's reloadable.
+type Auth struct {
+	StaticTokens []string `yaml:"static_tokens" hcl:"static_tokens,optional"`
+	Okta         *Okta    `yaml:"okta" hcl:"okta,block"`
+}
+
+type Okta struct {
+	Issuer string `yaml:"issuer" hcl:"issuer"`
+	// Claims have to be present in the tokens with the given values
+	Claims map[string]string `yaml:"claims" hcl:"claims,optional"`
+}
+
+// Admin configures the tokens of the admin API. It's reloadable.
+type Admin struct {
+	StaticTokens []string `yaml:"static_tokens" hcl:"static_tokens,optional"`
+}
+
+// Login configures the login.v1 service of the discovery document, with which Terraform obtains tokens
+type Login struct {
+	Client     string   `yaml:"client" hcl:"client"`
+	GrantTypes []string `yaml:"grant_types" hcl:"grant_types,optional"`
+	Authz      string   `yaml:"authz" hcl:"authz,optional"`
+	Token      string   `yaml:"token" hcl:"token,optional"`
+	Ports      []int    `yaml:"ports" hcl:"ports,optional"`
+	Scopes     []string `yaml:"scopes" hcl:"scopes,optional"`
+}
+
+// Mirror configures the provider network mirror. The policy is reloadable.
+type Mirror struct {
+	Enabled     *bool      `yaml:"enabled" hcl:"enabled,optional"`
+	PullThrough *bool      `yaml:"pull_through" hcl:"pull_through,optional"`
+	Policy      *Policy    `yaml:"policy" hcl:"policy,block"`
+	Upstreams   []Upstream `yaml:"upstreams" hcl:"upstream,block"`
+}
+
+type Policy struct {
+	Allow              []string `yaml:"allow" hcl:"allow,optional"`
+	Deny               []string `yaml:"deny" hcl:"deny,optional"`
+	VersionConstraints string   `yaml:"version_constraints" hcl:"version_constraints,optional"`
+	AllowPrereleases   *bool    `yaml:"allow_prereleases" hcl:"allow_prereleases,optional"`
+	Platforms          []string `yaml:"platforms" hcl:"platforms,optional"`
+}
+
+// Upstream routes the requests of the pull-through mirror for the registry at Hostname to Host
+type Upstream struct {
+	Hostname string `yaml:"hostname" hcl:"hostname"`
+	Host     string `yaml:"host" hcl:"host"`
+}
+
+// Replication copies the modules, providers and mirrored providers to another storage backend, e.g. from S3 to GCS
+type Replication struct {
+	Destination *Storage `yaml:"destination" hcl:"destination,block"`
+	// Interval in which the server replicates in the background. The server doesn't replicate if it's empty
+	Interval    string   `yaml:"interval" hcl:"interval,optional"`
+	Scopes      []string `yaml:"scopes" hcl:"scopes,optional"`
+	Delete      *bool    `yaml:"delete" hcl:"delete,optional"`
+	Concurrency int      `yaml:"concurrency" hcl:"concurrency,optional"`
+}
+
+// Replica serves the registry as an edge registry of the central registry at Upstream.
+// Module and provider versions, which are missing in the storage, are read through from the upstream and stored when they're requested.
+type Replica struct {
+	// Upstream is the hostname of the central registry, optionally with a port
+	Upstream string `yaml:"upstream" hcl:"upstream"`
+	// Token authenticates the requests to the upstream
+	Token   string `yaml:"token" hcl:"token,optional"`
+	Timeout string `yaml:"timeout" hcl:"timeout,optional"`
+	// TrustedKeysFile contains the ASCII armored public keys, which are trusted to sign the providers of the upstream
+	TrustedKeysFile string `yaml:"trusted_keys_file" hcl:"trusted_keys_file,optional"`
+}
+
+// Tenant is a registry with its own storage and authentication, which is served for requests whose Host header matches one of its hostnames.
+// The other sections are optional and replace the corresponding sections of the default tenant, which serves all other hostnames.
+// Omitted sections are inherited from the default tenant.
+type Tenant struct {
+	Name      string   `yaml:"name" hcl:"name,label"`
+	Hostnames []string `yaml:"hostnames" hcl:"hostnames"`
+	Storage   *Storage `yaml:"storage" hcl:"storage,block"`
+	// Auth isn't inherited, so that the tokens of the default tenant aren't accepted by another tenant
+	Auth   *Auth   `yaml:"auth" hcl:"auth,block"`
+	Admin  *Admin  `yaml:"admin" hcl:"admin,block"`
+	Login  *Login  `yaml:"login" hcl:"login,block"`
+	Mirror *Mirror `yaml:"mirror" hcl:"mirror,block"`
+	// Replication isn't inherited, as the destination of the default tenant can't be shared with the storage of another tenant
+	Replication *Replication `yaml:"replication" hcl:"replication,block"`
+	// Replica isn't inherited, as the tenants of the edge registry are read through from different tenants of the central registry
+	Replica *Replica `yaml:"replica" hcl:"replica,block"`
+}
+
+// DefaultTenant is the name of the tenant that is configured outside of tenants and serves all hostnames without a tenant
+const DefaultTenant = "default"
+
+// tenantName is used as a metric label and log attribute, so it's restricted to a simple format
+var tenantName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// Routes returns the hosts to which the upstream registries are routed, keyed by their lowercase hostname
+func (m *Mirror) Routes() map[string]string {
+	routes := make(map[string]string, len(m.Upstreams))
+	for _, u := range m.Upstreams {
+		routes[strings.ToLower(u.Hostname)] = u.Host
+	}
+	return routes
+}
+
+// Load reads and validates the configuration file at path.
+// Files with the extension .yaml or .yml are parsed as YAML, .hcl and .json files as HCL.
+// Unknown settings are an error, so that typos don't go unnoticed.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	c := &Config{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(b))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse configuration file %s: %w", path, err)
+		}
+	case ".hcl", ".json":
+		if err := hclsimple.Decode(filepath.Base(path), b, nil, c); err != nil {
+			return nil, fmt.Errorf("failed to parse configuration file: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported configuration file %s, expected the extension .yaml, .yml, .hcl or .json", path)
+	}
+
+	c.setDefaults()
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// setDefaults sets the omitted sections, so that they don't have to be checked for nil
+func (c *Config) setDefaults() {
+	if c.Log == nil {
+		c.Log = &Log{}
+	}
+	if c.Storage == nil {
+		c.Storage = &Storage{}
+	}
+	if c.Auth == nil {
+		c.Auth = &Auth{}
+	}
+	if c.Admin == nil {
+		c.Admin = &Admin{}
+	}
+	if c.Mirror == nil {
+		c.Mirror = &Mirror{}
+	}
+	if c.Mirror.Policy == nil {
+		c.Mirror.Policy = &Policy{}
+	}
+}
+
+// Validate ensures that the configuration is valid
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Log.Level != "" && !contains(LogLevels, c.Log.Level) {
+		errs = append(errs, fmt.Errorf("log.level %q is not one of %v", c.Log.Level, LogLevels))
+	}
+
+	if c.Storage.configured() > 1 {
+		errs = append(errs, errors.New("storage can only configure one of s3, gcs or azure"))
+	}
+	errs = append(errs, c.Storage.validate("storage")...)
+	errs = append(errs, c.Auth.validate("auth")...)
+	errs = append(errs, c.Admin.validate("admin")...)
+	errs = append(errs, c.Login.validate("login")...)
+	errs = append(errs, c.Mirror.validate("mirror")...)
+	errs = append(errs, c.Replication.validate("replication")...)
+	errs = append(errs, c.Replica.validate("replica")...)
+	errs = append(errs, c.validateTenants()...)
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) validateTenants() []error {
+	var errs []error
+
+	names := map[string]bool{}
+	hostnames := map[string]string{}
+	for i, t := range c.Tenants {
+		key := fmt.Sprintf("tenants[%d]", i)
+		if !tenantName.MatchString(t.Name) {
+			errs = append(errs, fmt.Errorf("%s.name %q must consist of lowercase letters, digits and dashes", key, t.Name))
+		} else if t.Name == DefaultTenant || names[t.Name] {
+			errs = append(errs, fmt.Errorf("%s.name %q is not unique", key, t.Name))
+		}
+		names[t.Name] = true
+
+		if len(t.Hostnames) == 0 {
+			errs = append(errs, fmt.Errorf("%s.hostnames cannot be empty", key))
+		}
+		for _, h := range t.Hostnames {
+			hostname := strings.ToLower(h)
+			if hostname == "" || strings.ContainsAny(hostname, ":/") {
+				errs = append(errs, fmt.Errorf("%s.hostnames contains the invalid hostname %q, expected a hostname without port", key, h))
+				continue
+			}
+			if other, ok := hostnames[hostname]; ok {
+				errs = append(errs, fmt.Errorf("%s.hostnames: hostname %s is already served by tenant %s", key, h, other))
+			}
+			hostnames[hostname] = t.Name
+		}
+
+		// Each tenant requires its own storage, so that the registries are isolated from each other
+		if t.Storage == nil || t.Storage.configured() != 1 {
+			errs = append(errs, fmt.Errorf("%s.storage has to configure exactly one of s3, gcs or azure", key))
+		} else {
+			errs = append(errs, t.Storage.validate(key+".storage")...)
+		}
+		// An omitted auth section would otherwise serve the tenant without authentication
+		if t.Auth == nil {
+			errs = append(errs, fmt.Errorf("%s.auth is required, configure an empty auth section to serve the tenant without authentication", key))
+		}
+		errs = append(errs, t.Auth.validate(key+".auth")...)
+		errs = append(errs, t.Admin.validate(key+".admin")...)
+		errs = append(errs, t.Login.validate(key+".login")...)
+		errs = append(errs, t.Mirror.validate(key+".mirror")...)
+		errs = append(errs, t.Replication.validate(key+".replication")...)
+		errs = append(errs, t.Replica.validate(key+".replica")...)
+	}
+
+	return errs
+}
+
+// configured returns the number of configured storage backends
+func (s *Storage) configured() int {
+	configured := 0
+	for _, backend := range []bool{s.S3 != nil, s.GCS != nil, s.Azure != nil} {
+		if backend {
+			configured++
+		}
+	}
+	return configured
+}
+
+func (s *Storage) validate(key string) []error {
+	var errs []error
+
+	if s.S3 != nil {
+		if s.S3.Bucket == "" {
+			errs = append(errs, fmt.Errorf("%s.s3.bucket cannot be empty", key))
+		}
+		errs = append(errs, validateDuration(key+".s3.signed_url_expiry", s.S3.SignedURLExpiry)...)
+	}
+	if s.GCS != nil {
+		if s.GCS.Bucket == "" {
+			errs = append(errs, fmt.Errorf("%s.gcs.bucket cannot be empty", key))
+		}
+		errs = append(errs, validateDuration(key+".gcs.signed_url_expiry", s.GCS.SignedURLExpiry)...)
+	}
+	if s.Azure != nil {
+		if s.Azure.Account == "" {
+			errs = append(errs, fmt.Errorf("%s.azure.account cannot be empty", key))
+		}
+		if s.Azure.Container == "" {
+			errs = append(errs, fmt.Errorf("%s.azure.container cannot be empty", key))
+		}
+		errs = append(errs, validateDuration(key+".azure.signed_url_expiry", s.Azure.SignedURLExpiry)...)
+	}
+	if c := s.SignedURLCache; c != nil {
+		if c.MinValidity == "" {
+			errs = append(errs, fmt.Errorf("%s.signed_url_cache.min_validity cannot be empty", key))
+		}
+		errs = append(errs, validateDuration(key+".signed_url_cache.min_validity", c.MinValidity)...)
+	}
+	if c := s.CDN; c != nil {
+		if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.cdn.url %q is not an http or https URL", key, c.URL))
+		}
+		if c.Signer != "" {
+			if !contains(storage.CDNSigners, c.Signer) {
+				errs = append(errs, fmt.Errorf("%s.cdn.signer %q is not one of %v", key, c.Signer, storage.CDNSigners))
+			}
+			if c.KeyID == "" || c.KeyFile == "" {
+				errs = append(errs, fmt.Errorf("%s.cdn requires key_id and key_file for the signer %s", key, c.Signer))
+			}
+		}
+	}
+
+	return errs
+}
+
+// validate ensures that the Auth is valid, unless it's omitted
+func (a *Auth) validate(key string) []error {
+	if a == nil {
+		return nil
+	}
+
+	var errs []error
+	errs = append(errs, validateTokens(key+".static_tokens", a.StaticTokens)...)
+	if okta := a.Okta; okta != nil {
+		if u, err := url.Parse(okta.Issuer); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.okta.issuer %q is not an https URL", key, okta.Issuer))
+		}
+	}
+	return errs
+}
+
+func (a *Admin) validate(key string) []error {
+	if a == nil {
+		return nil
+	}
+	return validateTokens(key+".static_tokens", a.StaticTokens)
+}
+
+func (l *Login) validate(key string) []error {
+	if l == nil {
+		return nil
+	}
+	if l.Client == "" {
+		return []error{fmt.Errorf("%s.client cannot be empty", key)}
+	}
+	return nil
+}
+
+func (m *Mirror) validate(key string) []error {
+	if m == nil {
+		return nil
+	}
+
+	var errs []error
+	if p := m.Policy; p != nil {
+		if _, err := mirror.NewPolicy(
+			mirror.WithAllowedProviders(p.Allow...),
+			mirror.WithDeniedProviders(p.Deny...),
+			mirror.WithVersionConstraints(p.VersionConstraints),
+			mirror.WithPlatforms(p.Platforms...),
+		); err != nil {
+			errs = append(errs, fmt.Errorf("%s.policy: %w", key, err))
+		}
+	}
+
+	hostnames := map[string]bool{}
+	for i, u := range m.Upstreams {
+		if u.Hostname == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.upstreams[%d] requires a hostname and a host", key, i))
+			continue
+		}
+		hostname := strings.ToLower(u.Hostname)
+		if hostnames[hostname] {
+			errs = append(errs, fmt.Errorf("%s.upstreams[%d]: hostname %s is routed more than once", key, i, u.Hostname))
+		}
+		hostnames[hostname] = true
+	}
+
+	return errs
+}
+
+func (r *Replication) validate(key string) []error {
+	if r == nil {
+		return nil
+	}
+
+	var errs []error
+	if r.Destination == nil || r.Destination.configured() != 1 {
+		errs = append(errs, fmt.Errorf("%s.destination has to configure exactly one of s3, gcs or azure", key))
+	} else {
+		errs = append(errs, r.Destination.validate(key+".destination")...)
+	}
+	errs = append(errs, validateDuration(key+".interval", r.Interval)...)
+	for _, scope := range r.Scopes {
+		if !contains(storage.ReplicationScopes, scope) {
+			errs = append(errs, fmt.Errorf("%s.scopes contains %q, which is not one of %v", key, scope, storage.ReplicationScopes))
+		}
+	}
+	if r.Concurrency < 0 {
+		errs = append(errs, fmt.Errorf("%s.concurrency cannot be negative", key))
+	}
+	return errs
+}
+
+func (r *Replica) validate(key string) []error {
+	if r == nil {
+		return nil
+	}
+
+	var errs []error
+	if r.Upstream == "" || strings.Contains(r.Upstream, "/") {
+		errs = append(errs, fmt.Errorf("%s.upstream %q has to be the hostname of the central registry without scheme and path", key, r.Upstream))
+	}
+	errs = append(errs, validateDuration(key+".timeout", r.Timeout)...)
+	return errs
+}
+
+func validateTokens(key string, tokens []string) []error {
+	for _, t := range tokens {
+		if strings.TrimSpace(t) == "" {
+			return []error{fmt.Errorf("%s cannot contain empty tokens", key)}
+		}
+	}
+	return nil
+}
+
+func validateDuration(key, value string) []error {
+	if value == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+		return []error{fmt.Errorf("%s %q is not a positive duration, e.g. 5m", key, value)}
+	}
+	return nil
+}
+
+// Duration returns the parsed duration of a validated setting, or zero if it isn't set
+func Duration(value string) time.Duration {
+	d, _ := time.ParseDuration(value)
+	return d
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
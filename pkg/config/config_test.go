@@ -0,0 +1,308 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlConfig = `
+log:
+  level: warn
+  json: true
+storage:
+  s3:
+    bucket: boring-registry
+    region: eu-central-1
+    signed_url_expiry: 10m
+  signed_url_cache:
+    min_validity: 2m
+  cdn:
+    url: https://downloads.example.com
+    signer: cloudfront
+    key_id: K2JCJMDEHXQW5F
+    key_file: /etc/boring-registry/cloudfront.pem
+auth:
+  static_tokens: [foo, bar]
+  okta:
+    issuer: https://example.okta.com/oauth2/default
+    claims:
+      aud: boring-registry
+admin:
+  static_tokens: [admin]
+mirror:
+  pull_through: true
+  policy:
+    allow: [registry.terraform.io/hashicorp/*]
+    version_constraints: ">= 1.0"
+    allow_prereleases: false
+    platforms: [linux_amd64]
+  upstreams:
+    - hostname: Registry.Terraform.io
+      host: terraform-proxy.example.com
+replication:
+  destination:
+    gcs:
+      bucket: boring-registry-replica
+  interval: 15m
+  scopes: [modules, providers]
+replica:
+  upstream: registry.example.com
+  token: edge
+  timeout: 30s
+`
+
+const hclConfig = `
+log {
+  level = "warn"
+  json  = true
+}
+
+storage {
+  s3 {
+    bucket            = "boring-registry"
+    region            = "eu-central-1"
+    signed_url_expiry = "10m"
+  }
+
+  signed_url_cache {
+    min_validity = "2m"
+  }
+
+  cdn {
+    url      = "https://downloads.example.com"
+    signer   = "cloudfront"
+    key_id   = "K2JCJMDEHXQW5F"
+    key_file = "/etc/boring-registry/cloudfront.pem"
+  }
+}
+
+auth {
+  static_tokens = ["foo", "bar"]
+
+  okta {
+    issuer = "https://example.okta.com/oauth2/default"
+    claims = {
+      aud = "boring-registry"
+    }
+  }
+}
+
+admin {
+  static_tokens = ["admin"]
+}
+
+mirror {
+  pull_through = true
+
+  policy {
+    allow               = ["registry.terraform.io/hashicorp/*"]
+    version_constraints = ">= 1.0"
+    allow_prereleases   = false
+    platforms           = ["linux_amd64"]
+  }
+
+  upstream {
+    hostname = "Registry.Terraform.io"
+    host     = "terraform-proxy.example.com"
+  }
+}
+
+replication {
+  destination {
+    gcs {
+      bucket = "boring-registry-replica"
+    }
+  }
+  interval = "15m"
+  scopes   = ["modules", "providers"]
+}
+
+replica {
+  upstream = "registry.example.com"
+  token    = "edge"
+  timeout  = "30s"
+}
+`
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "yaml", file: "config.yaml", content: yamlConfig},
+		{name: "hcl", file: "config.hcl", content: hclConfig},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			c, err := Load(writeConfig(t, tc.file, tc.content))
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal("warn", c.Log.Level)
+			assert.True(*c.Log.JSON)
+			assert.Equal("boring-registry", c.Storage.S3.Bucket)
+			assert.Equal(10*time.Minute, Duration(c.Storage.S3.SignedURLExpiry))
+			assert.Nil(c.Storage.GCS)
+			assert.Equal(2*time.Minute, Duration(c.Storage.SignedURLCache.MinValidity))
+			assert.Equal(&CDN{URL: "https://downloads.example.com", Signer: "cloudfront", KeyID: "K2JCJMDEHXQW5F", KeyFile: "/etc/boring-registry/cloudfront.pem"}, c.Storage.CDN)
+			assert.Equal([]string{"foo", "bar"}, c.Auth.StaticTokens)
+			assert.Equal(map[string]string{"aud": "boring-registry"}, c.Auth.Okta.Claims)
+			assert.Equal([]string{"admin"}, c.Admin.StaticTokens)
+			assert.Nil(c.Mirror.Enabled)
+			assert.True(*c.Mirror.PullThrough)
+			assert.Equal(">= 1.0", c.Mirror.Policy.VersionConstraints)
+			assert.False(*c.Mirror.Policy.AllowPrereleases)
+			assert.Equal(map[string]string{"registry.terraform.io": "terraform-proxy.example.com"}, c.Mirror.Routes())
+			assert.Equal("boring-registry-replica", c.Replication.Destination.GCS.Bucket)
+			assert.Equal(15*time.Minute, Duration(c.Replication.Interval))
+			assert.Equal([]string{"modules", "providers"}, c.Replication.Scopes)
+			assert.Nil(c.Replication.Delete)
+			assert.Equal("registry.example.com", c.Replica.Upstream)
+			assert.Equal("edge", c.Replica.Token)
+			assert.Equal(30*time.Second, Duration(c.Replica.Timeout))
+		})
+	}
+}
+
+const tenantsConfig = `
+tenants:
+  - name: payments
+    hostnames: [Registry.Payments.example.com]
+    storage:
+      gcs:
+        bucket: payments-registry
+    auth:
+      static_tokens: [payments]
+    login:
+      client: payments
+`
+
+const tenantsHCLConfig = `
+tenant "payments" {
+  hostnames = ["Registry.Payments.example.com"]
+
+  storage {
+    gcs {
+      bucket = "payments-registry"
+    }
+  }
+
+  auth {
+    static_tokens = ["payments"]
+  }
+
+  login {
+    client = "payments"
+  }
+}
+`
+
+func TestLoad_tenants(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "yaml", file: "config.yaml", content: tenantsConfig},
+		{name: "hcl", file: "config.hcl", content: tenantsHCLConfig},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			c, err := Load(writeConfig(t, tc.file, tc.content))
+			if !assert.NoError(err) || !assert.Len(c.Tenants, 1) {
+				return
+			}
+
+			tenant := c.Tenants[0]
+			assert.Equal("payments", tenant.Name)
+			assert.Equal([]string{"Registry.Payments.example.com"}, tenant.Hostnames)
+			assert.Equal("payments-registry", tenant.Storage.GCS.Bucket)
+			assert.Equal("payments", tenant.Login.Client)
+			assert.Equal([]string{"payments"}, tenant.Auth.StaticTokens)
+		})
+	}
+}
+
+func TestLoad_empty(t *testing.T) {
+	c, err := Load(writeConfig(t, "config.yml", ""))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Omitted sections are set, so that they don't have to be checked for nil
+	assert.NotNil(t, c.Log)
+	assert.NotNil(t, c.Storage)
+	assert.NotNil(t, c.Auth)
+	assert.NotNil(t, c.Admin)
+	assert.NotNil(t, c.Mirror.Policy)
+}
+
+func TestLoad_invalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "unknown yaml setting", file: "config.yaml", content: "auth:\n  static_token: [foo]\n"},
+		{name: "unknown hcl setting", file: "config.hcl", content: "auth {\n  static_token = [\"foo\"]\n}\n"},
+		{name: "unsupported extension", file: "config.toml", content: ""},
+		{name: "invalid log level", file: "config.yaml", content: "log:\n  level: verbose\n"},
+		{name: "multiple storage backends", file: "config.yaml", content: "storage:\n  s3:\n    bucket: foo\n  gcs:\n    bucket: bar\n"},
+		{name: "missing bucket", file: "config.yaml", content: "storage:\n  gcs:\n    prefix: foo\n"},
+		{name: "invalid duration", file: "config.yaml", content: "storage:\n  azure:\n    account: foo\n    container: bar\n    signed_url_expiry: -5m\n"},
+		{name: "signed url cache without min validity", file: "config.yaml", content: "storage:\n  signed_url_cache: {}\n"},
+		{name: "cdn without scheme", file: "config.yaml", content: "storage:\n  cdn:\n    url: downloads.example.com\n"},
+		{name: "unknown cdn signer", file: "config.yaml", content: "storage:\n  cdn:\n    url: https://downloads.example.com\n    signer: fastly\n    key_id: a\n    key_file: b\n"},
+		{name: "cdn signer without key", file: "config.yaml", content: "storage:\n  cdn:\n    url: https://downloads.example.com\n    signer: cloud-cdn\n"},
+		{name: "empty token", file: "config.yaml", content: "admin:\n  static_tokens: [\"\"]\n"},
+		{name: "okta issuer without https", file: "config.yaml", content: "auth:\n  okta:\n    issuer: http://example.okta.com\n"},
+		{name: "invalid policy", file: "config.yaml", content: "mirror:\n  policy:\n    platforms: [linux]\n"},
+		{name: "upstream without host", file: "config.yaml", content: "mirror:\n  upstreams:\n    - hostname: registry.terraform.io\n"},
+		{name: "duplicate upstream", file: "config.yaml", content: "mirror:\n  upstreams:\n    - hostname: registry.terraform.io\n      host: a.example.com\n    - hostname: REGISTRY.terraform.io\n      host: b.example.com\n"},
+		{name: "login without client", file: "config.yaml", content: "login:\n  authz: /authorize\n"},
+		{name: "invalid tenant name", file: "config.yaml", content: "tenants:\n  - name: Payments\n    hostnames: [a.example.com]\n    storage:\n      gcs:\n        bucket: foo\n"},
+		{name: "default tenant name", file: "config.yaml", content: "tenants:\n  - name: default\n    hostnames: [a.example.com]\n    storage:\n      gcs:\n        bucket: foo\n"},
+		{name: "duplicate tenant name", file: "config.yaml", content: "tenants:\n  - name: a\n    hostnames: [a.example.com]\n    storage:\n      gcs:\n        bucket: foo\n  - name: a\n    hostnames: [b.example.com]\n    storage:\n      gcs:\n        bucket: bar\n"},
+		{name: "duplicate tenant hostname", file: "config.yaml", content: "tenants:\n  - name: a\n    hostnames: [a.example.com]\n    storage:\n      gcs:\n        bucket: foo\n  - name: b\n    hostnames: [A.example.com]\n    storage:\n      gcs:\n        bucket: bar\n"},
+		{name: "tenant hostname with port", file: "config.yaml", content: "tenants:\n  - name: a\n    hostnames: [a.example.com:443]\n    storage:\n      gcs:\n        bucket: foo\n"},
+		{name: "tenant without hostnames", file: "config.yaml", content: "tenants:\n  - name: a\n    storage:\n      gcs:\n        bucket: foo\n"},
+		{name: "replication without destination", file: "config.yaml", content: "replication:\n  interval: 5m\n"},
+		{name: "invalid replication scope", file: "config.yaml", content: "replication:\n  destination:\n    gcs:\n      bucket: foo\n  scopes: [signing-keys]\n"},
+		{name: "invalid replication interval", file: "config.yaml", content: "replication:\n  destination:\n    gcs:\n      bucket: foo\n  interval: daily\n"},
+		{name: "replica without upstream", file: "config.yaml", content: "replica:\n  token: edge\n"},
+		{name: "replica upstream with scheme", file: "config.yaml", content: "replica:\n  upstream: https://registry.example.com\n"},
+		{name: "tenant without storage", file: "config.yaml", content: "tenants:\n  - name: a\n    hostnames: [a.example.com]\n    auth: {}\n"},
+		{name: "tenant without auth", file: "config.yaml", content: "tenants:\n  - name: a\n    hostnames: [a.example.com]\n    storage:\n      gcs:\n        bucket: foo\n"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tc.file, tc.content))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package core
+
+// Annotations are the metadata of a module or provider version, which are managed independently of the uploaded artifacts
+type Annotations struct {
+	// Deprecation marks the version as deprecated, it's nil for versions that aren't deprecated
+	Deprecation *Deprecation      `json:"deprecation,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// Deprecation is the deprecation info of a version, as it's returned in the version listings of the registry protocols
+type Deprecation struct {
+	Reason string `json:"reason,omitempty"`
+	// Link points to the replacement or to further information
+	Link string `json:"link,omitempty"`
+}
+
+// IsZero reports whether the version has neither a deprecation nor labels
+func (a Annotations) IsZero() bool {
+	return a.Deprecation == nil && len(a.Labels) == 0
+}
+
+// VersionAnnotations are the annotations of the versions of a module or provider, keyed by version
+type VersionAnnotations map[string]Annotations
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"time"
+)
+
+// downloadDayFormat is the format of the days under which the downloads are counted, which are always in UTC
+const downloadDayFormat = time.DateOnly
+
+// DownloadStats are the daily downloads of the versions of a module or provider, keyed by version
+type DownloadStats map[string]DailyDownloads
+
+// DailyDownloads are the download counts of a version, keyed by the day in the form of 2006-01-02
+type DailyDownloads map[string]int64
+
+// Record adds count downloads of the version on the day of t
+func (s DownloadStats) Record(version string, t time.Time, count int64) {
+	if s[version] == nil {
+		s[version] = DailyDownloads{}
+	}
+	s[version][t.UTC().Format(downloadDayFormat)] += count
+}
+
+// Merge adds the downloads of other to the stats
+func (s DownloadStats) Merge(other DownloadStats) {
+	for version, days := range other {
+		if s[version] == nil {
+			s[version] = DailyDownloads{}
+		}
+		for day, count := range days {
+			s[version][day] += count
+		}
+	}
+}
+
+// Summary sums up the downloads of all versions
+func (s DownloadStats) Summary(now time.Time) DownloadSummary {
+	var summary DownloadSummary
+	for _, days := range s {
+		summary.add(days.Summary(now))
+	}
+	return summary
+}
+
+// Summary sums up the downloads of the last 7, 30 and 365 days, including the day of now, and of all time
+func (d DailyDownloads) Summary(now time.Time) DownloadSummary {
+	today, _ := time.Parse(downloadDayFormat, now.UTC().Format(downloadDayFormat))
+
+	var summary DownloadSummary
+	for day, count := range d {
+		t, err := time.Parse(downloadDayFormat, day)
+		if err != nil {
+			continue
+		}
+
+		summary.Total += count
+		age := today.Sub(t)
+		if age < 0 {
+			continue
+		}
+		if age < 7*24*time.Hour {
+			summary.Week += count
+		}
+		if age < 30*24*time.Hour {
+			summary.Month += count
+		}
+		if age < 365*24*time.Hour {
+			summary.Year += count
+		}
+	}
+	return summary
+}
+
+// LastDownload returns the day of the most recent download, which is zero if the version was never downloaded
+func (d DailyDownloads) LastDownload() time.Time {
+	var last time.Time
+	for day, count := range d {
+		t, err := time.Parse(downloadDayFormat, day)
+		if err != nil || count == 0 {
+			continue
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// DownloadSummary are the downloads of a module or provider in the format of the downloads summary of the public registry
+type DownloadSummary struct {
+	Week  int64 `json:"week"`
+	Month int64 `json:"month"`
+	Year  int64 `json:"year"`
+	Total int64 `json:"total"`
+}
+
+func (s *DownloadSummary) add(other DownloadSummary) {
+	s.Week += other.Week
+	s.Month += other.Month
+	s.Year += other.Year
+	s.Total += other.Total
+}
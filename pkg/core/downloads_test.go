@@ -0,0 +1,53 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadStats_Summary(t *testing.T) {
+	now := time.Date(2024, 7, 1, 23, 0, 0, 0, time.UTC)
+
+	stats := DownloadStats{}
+	stats.Record("1.0.0", now, 1)
+	stats.Record("1.0.0", now.AddDate(0, 0, -6), 2)
+	stats.Record("1.0.0", now.AddDate(0, 0, -7), 4)
+	stats.Record("2.0.0", now.AddDate(0, 0, -29), 8)
+	stats.Record("2.0.0", now.AddDate(0, 0, -364), 16)
+	stats.Record("2.0.0", now.AddDate(0, 0, -365), 32)
+	// Downloads in the future, e.g. recorded by a replica with a skewed clock, only count towards the total
+	stats.Record("2.0.0", now.AddDate(0, 0, 1), 64)
+
+	assert.Equal(t, DownloadSummary{Week: 3, Month: 15, Year: 31, Total: 127}, stats.Summary(now))
+	assert.Equal(t, DownloadSummary{Week: 3, Month: 7, Year: 7, Total: 7}, stats["1.0.0"].Summary(now))
+	assert.Equal(t, DownloadSummary{}, stats["3.0.0"].Summary(now))
+}
+
+func TestDownloadStats_Merge(t *testing.T) {
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	stats := DownloadStats{}
+	stats.Record("1.0.0", day, 1)
+
+	other := DownloadStats{}
+	other.Record("1.0.0", day, 2)
+	other.Record("1.0.0", day.AddDate(0, 0, 1), 3)
+	other.Record("2.0.0", day, 4)
+
+	stats.Merge(other)
+	assert.Equal(t, DownloadStats{
+		"1.0.0": {"2024-07-01": 3, "2024-07-02": 3},
+		"2.0.0": {"2024-07-01": 4},
+	}, stats)
+}
+
+func TestDailyDownloads_LastDownload(t *testing.T) {
+	assert.True(t, DailyDownloads(nil).LastDownload().IsZero())
+	assert.True(t, DailyDownloads{"2024-07-01": 0}.LastDownload().IsZero())
+	assert.Equal(t,
+		time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC),
+		DailyDownloads{"2024-07-02": 1, "2024-06-01": 5, "invalid": 1}.LastDownload(),
+	)
+}
@@ -20,6 +20,7 @@ var (
 	// Storage errors
 	ErrObjectNotFound      = errors.New("failed to locate object")
 	ErrObjectAlreadyExists = errors.New("object already exists")
+	ErrObjectModified      = errors.New("object was modified concurrently")
 )
 
 type ProviderError struct {
@@ -9,6 +9,12 @@ type Module struct {
 	Provider    string `json:"provider"`
 	Version     string `json:"version"`
 	DownloadURL string `json:"download_url"`
+	// ArchiveFormat is the format of the module archive, e.g. tar.gz or zip
+	ArchiveFormat string `json:"archive_format,omitempty"`
+	// Source is the go-getter source of module versions that aren't stored as an archive, e.g. git::https://example.com/vpc.git?ref=v1.0.0
+	Source string `json:"source,omitempty"`
+	// Annotations are only set in version listings
+	Annotations *Annotations `json:"annotations,omitempty"`
 }
 
 // ID returns the module metadata in a compact format.
@@ -170,6 +170,9 @@ type ProviderVersion struct {
 	Version   string     `json:"version,omitempty"`
 	Protocols []string   `json:"protocols,omitempty"`
 	Platforms []Platform `json:"platforms,omitempty"`
+
+	Deprecation *Deprecation      `json:"deprecation,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
 }
 
 // Platform is a copy from provider.Platform
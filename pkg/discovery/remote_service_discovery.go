@@ -8,6 +8,10 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -69,8 +73,12 @@ type RemoteServiceDiscovery struct {
 }
 
 // Resolve returns the relative provider path for a given URL
-func (r *RemoteServiceDiscovery) Resolve(ctx context.Context, host string) (*DiscoveredRemoteService, error) {
+func (r *RemoteServiceDiscovery) Resolve(ctx context.Context, host string) (_ *DiscoveredRemoteService, err error) {
 	existing, ok := r.m.Load(host)
+
+	ctx, span := o11y.StartSpan(ctx, "discovery.Resolve", attribute.String("host", host), attribute.Bool("cached", ok))
+	defer func() { o11y.EndSpan(span, err) }()
+
 	if !ok {
 		discovered, err := r.discover(ctx, host)
 		if err != nil {
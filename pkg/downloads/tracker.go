@@ -0,0 +1,138 @@
+package downloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+)
+
+// Storage stores the download stats of modules and providers.
+// The downloads are merged into the stored stats with conditional writes, as every replica flushes its own downloads.
+type Storage interface {
+	MergeModuleDownloadStats(ctx context.Context, namespace, name, provider string, stats core.DownloadStats) error
+	MergeProviderDownloadStats(ctx context.Context, namespace, name string, stats core.DownloadStats) error
+}
+
+// artifact identifies a module or provider independent of its version.
+// The provider is empty for providers, as they are identified by namespace and name only.
+type artifact struct {
+	module    bool
+	namespace string
+	name      string
+	provider  string
+}
+
+func (a artifact) String() string {
+	if a.module {
+		return fmt.Sprintf("module %s/%s/%s", a.namespace, a.name, a.provider)
+	}
+	return fmt.Sprintf("provider %s/%s", a.namespace, a.name)
+}
+
+// Tracker counts the downloads of module and provider versions.
+// The downloads are kept in memory and merged periodically into the download stats in the storage backend,
+// so that they survive restarts and are shared by all replicas.
+type Tracker struct {
+	storage Storage
+	logger  *slog.Logger
+	now     func() time.Time
+
+	mu      sync.Mutex
+	pending map[artifact]core.DownloadStats
+}
+
+// RecordModuleDownload counts a download of the module version
+func (t *Tracker) RecordModuleDownload(namespace, name, provider, version string) {
+	t.record(artifact{module: true, namespace: namespace, name: name, provider: provider}, version)
+}
+
+// RecordProviderDownload counts a download of the provider version, independent of its platform
+func (t *Tracker) RecordProviderDownload(namespace, name, version string) {
+	t.record(artifact{namespace: namespace, name: name}, version)
+}
+
+func (t *Tracker) record(a artifact, version string) {
+	if version == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats, ok := t.pending[a]
+	if !ok {
+		stats = core.DownloadStats{}
+		t.pending[a] = stats
+	}
+	stats.Record(version, t.now(), 1)
+}
+
+// Flush merges the pending downloads into the download stats in the storage backend
+func (t *Tracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = map[artifact]core.DownloadStats{}
+	t.mu.Unlock()
+
+	var errs []error
+	for a, stats := range pending {
+		if err := t.flush(ctx, a, stats); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush download stats of %s: %w", a, err))
+
+			// Keep the downloads, so that they are written with the next flush
+			t.mu.Lock()
+			if next, ok := t.pending[a]; ok {
+				next.Merge(stats)
+			} else {
+				t.pending[a] = stats
+			}
+			t.mu.Unlock()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Tracker) flush(ctx context.Context, a artifact, pending core.DownloadStats) error {
+	if a.module {
+		return t.storage.MergeModuleDownloadStats(ctx, a.namespace, a.name, a.provider, pending)
+	}
+	return t.storage.MergeProviderDownloadStats(ctx, a.namespace, a.name, pending)
+}
+
+// Run flushes the pending downloads in the given interval until the context is canceled
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Flush the remaining downloads before terminating
+			flushCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := t.Flush(flushCtx); err != nil {
+				t.logger.Error("failed to flush download stats", slog.String("err", err.Error()))
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				t.logger.Error("failed to flush download stats", slog.String("err", err.Error()))
+			}
+		}
+	}
+}
+
+// NewTracker returns a Tracker that writes the download stats to the storage backend
+func NewTracker(s Storage) *Tracker {
+	return &Tracker{
+		storage: s,
+		logger:  slog.Default().With(slog.String("component", "download-tracker")),
+		now:     time.Now,
+		pending: map[artifact]core.DownloadStats{},
+	}
+}
@@ -0,0 +1,65 @@
+package downloads
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStorage struct {
+	module.Storage
+	providers map[string]core.DownloadStats
+	err       error
+}
+
+func (s *fakeStorage) MergeProviderDownloadStats(_ context.Context, namespace, name string, stats core.DownloadStats) error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.providers[namespace+"/"+name] == nil {
+		s.providers[namespace+"/"+name] = core.DownloadStats{}
+	}
+	s.providers[namespace+"/"+name].Merge(stats)
+	return nil
+}
+
+func TestTracker_Flush(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeStorage{
+		Storage:   module.NewInmemStorage(),
+		providers: map[string]core.DownloadStats{},
+	}
+	tracker := NewTracker(storage)
+	tracker.now = func() time.Time { return time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC) }
+
+	tracker.RecordModuleDownload("networking", "vpc", "aws", "1.0.0")
+	tracker.RecordModuleDownload("networking", "vpc", "aws", "1.0.0")
+	tracker.RecordModuleDownload("networking", "vpc", "aws", "")
+	tracker.RecordProviderDownload("hashicorp", "random", "0.1.0")
+	assert.NoError(t, tracker.Flush(ctx))
+
+	// The downloads are added to the stored stats
+	tracker.RecordModuleDownload("networking", "vpc", "aws", "1.0.0")
+	assert.NoError(t, tracker.Flush(ctx))
+
+	stats, err := storage.GetModuleDownloadStats(ctx, "networking", "vpc", "aws")
+	assert.NoError(t, err)
+	assert.Equal(t, core.DownloadStats{"1.0.0": {"2024-07-01": 3}}, stats)
+	assert.Equal(t, core.DownloadStats{"0.1.0": {"2024-07-01": 1}}, storage.providers["hashicorp/random"])
+
+	// Downloads that couldn't be written are kept for the next flush
+	storage.err = errors.New("bucket is unreachable")
+	tracker.RecordProviderDownload("hashicorp", "random", "0.1.0")
+	assert.ErrorContains(t, tracker.Flush(ctx), "failed to flush download stats of provider hashicorp/random")
+
+	storage.err = nil
+	tracker.RecordProviderDownload("hashicorp", "random", "0.1.0")
+	assert.NoError(t, tracker.Flush(ctx))
+	assert.Equal(t, core.DownloadStats{"0.1.0": {"2024-07-01": 3}}, storage.providers["hashicorp/random"])
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Publisher emits events to the subscribers
+type Publisher interface {
+	Publish(e Event)
+}
+
+// Subscriber receives the published events
+type Subscriber interface {
+	// Receive accepts an Event for delivery and must not block the publisher
+	Receive(e Event)
+	// Close delivers the pending events until the context is done
+	Close(ctx context.Context) error
+}
+
+// Bus publishes each Event to all of its subscribers
+type Bus struct {
+	subscribers []Subscriber
+	logger      *slog.Logger
+}
+
+// Publish hands the Event to all subscribers
+func (b *Bus) Publish(e Event) {
+	b.logger.Debug("publishing event", slog.String("id", e.ID), slog.String("type", string(e.Type)))
+	for _, s := range b.subscribers {
+		s.Receive(e)
+	}
+}
+
+// Close waits until the subscribers have delivered the pending events or the context is done
+func (b *Bus) Close(ctx context.Context) error {
+	var errs []error
+	for _, s := range b.subscribers {
+		if err := s.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type tenantPublisher struct {
+	next   Publisher
+	tenant string
+}
+
+func (p *tenantPublisher) Publish(e Event) {
+	e.Tenant = p.tenant
+	p.next.Publish(e)
+}
+
+// NewTenantPublisher returns a Publisher, which sets the tenant of the events before publishing them to next
+func NewTenantPublisher(next Publisher, tenant string) Publisher {
+	return &tenantPublisher{
+		next:   next,
+		tenant: tenant,
+	}
+}
+
+// NewBus returns a Bus which publishes events to the given subscribers
+func NewBus(subscribers ...Subscriber) *Bus {
+	return &Bus{
+		subscribers: subscribers,
+		logger:      slog.Default().With(slog.String("component", "event-bus")),
+	}
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetter is an Event that couldn't be delivered to a webhook
+type DeadLetter struct {
+	Webhook  string    `json:"webhook"`
+	Event    Event     `json:"event"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// DeadLetterLog records the events that couldn't be delivered, so that they can be inspected and delivered again
+type DeadLetterLog interface {
+	Write(d DeadLetter) error
+}
+
+type slogDeadLetterLog struct {
+	logger *slog.Logger
+}
+
+func (l *slogDeadLetterLog) Write(d DeadLetter) error {
+	event, err := json.Marshal(d.Event)
+	if err != nil {
+		return err
+	}
+
+	l.logger.Error("failed to deliver event",
+		slog.String("webhook", d.Webhook),
+		slog.Int("attempts", d.Attempts),
+		slog.String("err", d.Error),
+		slog.String("event", string(event)),
+	)
+	return nil
+}
+
+// NewLogDeadLetterLog returns a DeadLetterLog which logs the events that couldn't be delivered
+func NewLogDeadLetterLog() DeadLetterLog {
+	return &slogDeadLetterLog{
+		logger: slog.Default().With(slog.String("component", "dead-letter-log")),
+	}
+}
+
+type fileDeadLetterLog struct {
+	mu   sync.Mutex
+	path string
+	next DeadLetterLog
+}
+
+func (l *fileDeadLetterLog) Write(d DeadLetter) error {
+	// The dead letter is logged even if it can't be appended to the file
+	if err := l.next.Write(d); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write dead letter log: %w", err)
+	}
+	return nil
+}
+
+// NewFileDeadLetterLog returns a DeadLetterLog which logs the events that couldn't be delivered
+// and appends them as JSON lines to the file at path
+func NewFileDeadLetterLog(path string) DeadLetterLog {
+	return &fileDeadLetterLog{
+		path: path,
+		next: NewLogDeadLetterLog(),
+	}
+}
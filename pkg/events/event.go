@@ -0,0 +1,110 @@
+package events
+
+import (
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/google/uuid"
+)
+
+// Type is the type of an Event
+type Type string
+
+const (
+	// ModulePublished is emitted when a module version is uploaded or registered with a git source
+	ModulePublished Type = "module.published"
+	// ProviderPublished is emitted when the SHA256SUMS signature, as the last file of a provider release, is uploaded
+	ProviderPublished Type = "provider.published"
+	// MirrorProviderCopied is emitted when the archive of a provider platform is copied into the network mirror
+	MirrorProviderCopied Type = "mirror.provider.copied"
+	// MirrorProviderDeleted is emitted when the archive of a provider platform is deleted from the network mirror
+	MirrorProviderDeleted Type = "mirror.provider.deleted"
+	// Ping is only sent to test the delivery to webhooks
+	Ping Type = "ping"
+)
+
+// Types are all types of events, which can be subscribed to
+var Types = []Type{ModulePublished, ProviderPublished, MirrorProviderCopied, MirrorProviderDeleted, Ping}
+
+// DefaultTenant is the name of the tenant of events without a tenant, which is the same as config.DefaultTenant
+const DefaultTenant = "default"
+
+// Event is a change in the registry that is delivered to the subscribers
+type Event struct {
+	ID   string    `json:"id"`
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	// Tenant is the name of the tenant whose registry changed, it's empty for the default tenant
+	Tenant   string    `json:"tenant,omitempty"`
+	Module   *Module   `json:"module,omitempty"`
+	Provider *Provider `json:"provider,omitempty"`
+}
+
+// Module is the module version an Event refers to
+type Module struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Provider  string `json:"provider"`
+	Version   string `json:"version"`
+}
+
+// Provider is the provider version an Event refers to.
+// The hostname and the platform are only set for mirrored providers.
+type Provider struct {
+	Hostname  string `json:"hostname,omitempty"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	OS        string `json:"os,omitempty"`
+	Arch      string `json:"arch,omitempty"`
+}
+
+func newEvent(t Type) Event {
+	return Event{
+		ID:   uuid.NewString(),
+		Type: t,
+		Time: time.Now().UTC(),
+	}
+}
+
+// NewModuleEvent returns an Event of the given type for a module version
+func NewModuleEvent(t Type, namespace, name, provider, version string) Event {
+	e := newEvent(t)
+	e.Module = &Module{
+		Namespace: namespace,
+		Name:      name,
+		Provider:  provider,
+		Version:   version,
+	}
+	return e
+}
+
+// NewProviderEvent returns an Event of the given type for a provider version
+func NewProviderEvent(t Type, p *core.Provider) Event {
+	e := newEvent(t)
+	e.Provider = &Provider{
+		Hostname:  p.Hostname,
+		Namespace: p.Namespace,
+		Name:      p.Name,
+		Version:   p.Version,
+		OS:        p.OS,
+		Arch:      p.Arch,
+	}
+	return e
+}
+
+// NewPingEvent returns an Event without a module or provider, which tests the delivery to webhooks
+func NewPingEvent() Event {
+	return newEvent(Ping)
+}
+
+// ParseType returns the Type of the given name, or false if there's no such type
+func ParseType(name string) (Type, bool) {
+	for _, t := range Types {
+		if string(t) == name {
+			return t, true
+		}
+	}
+	return "", false
+}
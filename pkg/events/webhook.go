@@ -0,0 +1,353 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+)
+
+const (
+	// HeaderEvent contains the type of the delivered event
+	HeaderEvent = "X-Boring-Registry-Event"
+	// HeaderDelivery contains the ID of the delivered event, which is the same for all delivery attempts
+	HeaderDelivery = "X-Boring-Registry-Delivery"
+	// HeaderSignature contains the HMAC-SHA256 signature of the request body in the form of sha256=<hex>
+	HeaderSignature = "X-Boring-Registry-Signature-256"
+
+	signaturePrefix = "sha256="
+
+	defaultRetries      = 5
+	defaultRetryBackoff = time.Second
+	maxRetryBackoff     = time.Minute
+	defaultTimeout      = 10 * time.Second
+	defaultQueueSize    = 1000
+)
+
+var errQueueFull = errors.New("the delivery queue is full")
+var errClosed = errors.New("the webhook is closed")
+
+// Sign returns the signature of the body in the form of sha256=<hex>, which is sent in the HeaderSignature
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether the signature was created for the body with the secret
+func VerifySignature(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Webhook is a Subscriber which delivers events as JSON to an HTTP endpoint.
+// Events are delivered one after another in the order they were received.
+// Failed deliveries are retried with an exponential backoff, before the event is written to the DeadLetterLog.
+type Webhook struct {
+	url string
+	// name is the URL without the password, which is used in logs and dead letters
+	name         string
+	secret       []byte
+	types        map[Type]bool
+	tenants      map[string]bool
+	client       *http.Client
+	retries      int
+	retryBackoff time.Duration
+	queueSize    int
+	deadLetters  DeadLetterLog
+	logger       *slog.Logger
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan Event
+	done   chan struct{}
+	// ctx is canceled if the pending events can't be delivered before the webhook is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Receive queues the Event for delivery, if the webhook is subscribed to its type and tenant.
+// The Event is written to the DeadLetterLog right away, if the queue is full.
+func (w *Webhook) Receive(e Event) {
+	if len(w.types) > 0 && !w.types[e.Type] {
+		return
+	}
+	tenant := e.Tenant
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+	if len(w.tenants) > 0 && !w.tenants[tenant] {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		w.deadLetter(e, 0, errClosed)
+		return
+	}
+
+	select {
+	case w.queue <- e:
+	default:
+		w.deadLetter(e, 0, errQueueFull)
+	}
+}
+
+// Close waits until the queued events are delivered.
+// Pending deliveries are aborted and written to the DeadLetterLog once the context is done.
+func (w *Webhook) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+		return fmt.Errorf("failed to deliver the pending events to %s: %w", w.name, ctx.Err())
+	}
+}
+
+func (w *Webhook) run() {
+	defer close(w.done)
+	defer w.cancel()
+
+	for e := range w.queue {
+		attempts, err := w.deliverWithRetries(w.ctx, e)
+		if err != nil {
+			w.deadLetter(e, attempts, err)
+			continue
+		}
+		w.logger.Debug("delivered event", slog.String("id", e.ID), slog.String("type", string(e.Type)), slog.Int("attempts", attempts))
+	}
+}
+
+func (w *Webhook) deliverWithRetries(ctx context.Context, e Event) (int, error) {
+	backoff := w.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := w.Deliver(ctx, e)
+		if err == nil {
+			return attempt, nil
+		}
+
+		var permanent *permanentError
+		if attempt > w.retries || errors.As(err, &permanent) {
+			return attempt, err
+		}
+
+		w.logger.Warn("failed to deliver event, retrying",
+			slog.String("id", e.ID),
+			slog.Int("attempt", attempt),
+			slog.String("retry_in", backoff.String()),
+			slog.String("err", err.Error()),
+		)
+
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// permanentError is a failed delivery that isn't retried, as the receiver rejected the event
+type permanentError struct {
+	statusCode int
+}
+
+func (e *permanentError) Error() string {
+	return fmt.Sprintf("the webhook rejected the event with status code %d", e.statusCode)
+}
+
+// Deliver sends the Event once to the webhook.
+// Any status code other than 2xx is an error, but only 408, 429 and 5xx are considered to be worth retrying.
+func (w *Webhook) Deliver(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "boring-registry")
+	req.Header.Set(HeaderEvent, string(e.Type))
+	req.Header.Set(HeaderDelivery, e.ID)
+	if len(w.secret) > 0 {
+		req.Header.Set(HeaderSignature, Sign(w.secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("the webhook responded with status code %d", resp.StatusCode)
+	default:
+		return &permanentError{statusCode: resp.StatusCode}
+	}
+}
+
+func (w *Webhook) deadLetter(e Event, attempts int, err error) {
+	d := DeadLetter{
+		Webhook:  w.name,
+		Event:    e,
+		Attempts: attempts,
+		Error:    err.Error(),
+		FailedAt: time.Now().UTC(),
+	}
+	if err := w.deadLetters.Write(d); err != nil {
+		w.logger.Error("failed to write dead letter", slog.String("id", e.ID), slog.String("err", err.Error()))
+	}
+}
+
+// WebhookOption provides additional options for the Webhook
+type WebhookOption func(*Webhook) error
+
+// WithSecret configures the secret with which the request bodies are signed
+func WithSecret(secret string) WebhookOption {
+	return func(w *Webhook) error {
+		w.secret = []byte(secret)
+		return nil
+	}
+}
+
+// WithEventTypes configures the types of events that are delivered. All events are delivered if no types are given.
+func WithEventTypes(types ...string) WebhookOption {
+	return func(w *Webhook) error {
+		for _, name := range types {
+			t, ok := ParseType(name)
+			if !ok {
+				return fmt.Errorf("unknown event type %q", name)
+			}
+			w.types[t] = true
+		}
+		return nil
+	}
+}
+
+// WithTenants configures the tenants whose events are delivered. The events of all tenants are delivered if no tenants are given.
+func WithTenants(tenants ...string) WebhookOption {
+	return func(w *Webhook) error {
+		for _, tenant := range tenants {
+			w.tenants[tenant] = true
+		}
+		return nil
+	}
+}
+
+// WithRetries configures how often a failed delivery is retried
+func WithRetries(n int) WebhookOption {
+	return func(w *Webhook) error {
+		if n < 0 {
+			return fmt.Errorf("the number of retries must not be negative")
+		}
+		w.retries = n
+		return nil
+	}
+}
+
+// WithRetryBackoff configures the duration before the first retry, which is doubled for each further retry
+func WithRetryBackoff(d time.Duration) WebhookOption {
+	return func(w *Webhook) error {
+		if d <= 0 {
+			return fmt.Errorf("the retry backoff must be positive")
+		}
+		w.retryBackoff = d
+		return nil
+	}
+}
+
+// WithTimeout configures the timeout of a single delivery attempt
+func WithTimeout(d time.Duration) WebhookOption {
+	return func(w *Webhook) error {
+		w.client.Timeout = d
+		return nil
+	}
+}
+
+// WithQueueSize configures how many events are queued for delivery, before further events are dead-lettered
+func WithQueueSize(n int) WebhookOption {
+	return func(w *Webhook) error {
+		if n <= 0 {
+			return fmt.Errorf("the queue size must be positive")
+		}
+		w.queueSize = n
+		return nil
+	}
+}
+
+// WithDeadLetterLog configures where the events that couldn't be delivered are recorded
+func WithDeadLetterLog(l DeadLetterLog) WebhookOption {
+	return func(w *Webhook) error {
+		w.deadLetters = l
+		return nil
+	}
+}
+
+// NewWebhook returns a Webhook which delivers events to the URL until it's closed
+func NewWebhook(rawURL string, options ...WebhookOption) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %s: expected an http or https URL", rawURL)
+	}
+
+	w := &Webhook{
+		url:     rawURL,
+		name:    u.Redacted(),
+		types:   map[Type]bool{},
+		tenants: map[string]bool{},
+		client: &http.Client{
+			Timeout:   defaultTimeout,
+			Transport: o11y.NewTransport(http.DefaultTransport),
+		},
+		retries:      defaultRetries,
+		retryBackoff: defaultRetryBackoff,
+		queueSize:    defaultQueueSize,
+		deadLetters:  NewLogDeadLetterLog(),
+		logger:       slog.Default().With(slog.String("component", "webhook"), slog.String("webhook", u.Redacted())),
+		done:         make(chan struct{}),
+	}
+
+	for _, option := range options {
+		if err := option(w); err != nil {
+			return nil, err
+		}
+	}
+
+	w.queue = make(chan Event, w.queueSize)
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	go w.run()
+
+	return w, nil
+}
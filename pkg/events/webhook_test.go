@@ -0,0 +1,272 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingDeadLetterLog struct {
+	mu          sync.Mutex
+	deadLetters []DeadLetter
+}
+
+func (l *recordingDeadLetterLog) Write(d DeadLetter) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.deadLetters = append(l.deadLetters, d)
+	return nil
+}
+
+// receiver is a local HTTP endpoint, which responds with the given status codes in order and records the received requests
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestWebhook(t *testing.T) {
+	testCases := []struct {
+		description     string
+		statuses        []int
+		wantRequests    int
+		wantDeadLetters int
+	}{
+		{
+			description:  "delivered at once",
+			wantRequests: 1,
+		},
+		{
+			description:  "delivered after retries",
+			statuses:     []int{http.StatusInternalServerError, http.StatusTooManyRequests},
+			wantRequests: 3,
+		},
+		{
+			description:     "retries are exhausted",
+			statuses:        []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantRequests:    3,
+			wantDeadLetters: 1,
+		},
+		{
+			description:     "rejected without retries",
+			statuses:        []int{http.StatusUnauthorized},
+			wantRequests:    1,
+			wantDeadLetters: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.description, func(t *testing.T) {
+			r := &receiver{statuses: tc.statuses}
+			server := httptest.NewServer(r)
+			defer server.Close()
+
+			deadLetters := &recordingDeadLetterLog{}
+			w, err := NewWebhook(server.URL,
+				WithSecret("s3cr3t"),
+				WithRetries(2),
+				WithRetryBackoff(time.Millisecond),
+				WithDeadLetterLog(deadLetters),
+			)
+			assert.NoError(t, err)
+
+			e := NewModuleEvent(ModulePublished, "acme", "vpc", "aws", "1.0.0")
+			w.Receive(e)
+			assert.NoError(t, w.Close(context.Background()))
+
+			assert.Len(t, r.requests, tc.wantRequests)
+			for i, req := range r.requests {
+				assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+				assert.Equal(t, string(ModulePublished), req.Header.Get(HeaderEvent))
+				assert.Equal(t, e.ID, req.Header.Get(HeaderDelivery))
+				assert.True(t, VerifySignature([]byte("s3cr3t"), r.bodies[i], req.Header.Get(HeaderSignature)))
+
+				var received Event
+				assert.NoError(t, json.Unmarshal(r.bodies[i], &received))
+				assert.Equal(t, e.Module, received.Module)
+			}
+
+			assert.Len(t, deadLetters.deadLetters, tc.wantDeadLetters)
+			for _, d := range deadLetters.deadLetters {
+				assert.Equal(t, e.ID, d.Event.ID)
+				assert.Equal(t, tc.wantRequests, d.Attempts)
+				assert.Equal(t, server.URL, d.Webhook)
+			}
+		})
+	}
+}
+
+func TestWebhook_EventTypes(t *testing.T) {
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	w, err := NewWebhook(server.URL, WithEventTypes(string(MirrorProviderCopied)))
+	assert.NoError(t, err)
+
+	w.Receive(NewModuleEvent(ModulePublished, "acme", "vpc", "aws", "1.0.0"))
+	w.Receive(NewProviderEvent(MirrorProviderCopied, &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.6.0"}))
+	assert.NoError(t, w.Close(context.Background()))
+
+	assert.Len(t, r.requests, 1)
+	assert.Equal(t, string(MirrorProviderCopied), r.requests[0].Header.Get(HeaderEvent))
+
+	_, err = NewWebhook(server.URL, WithEventTypes("module.deleted"))
+	assert.EqualError(t, err, `unknown event type "module.deleted"`)
+}
+
+func TestWebhook_Tenants(t *testing.T) {
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	w, err := NewWebhook(server.URL, WithTenants("payments", DefaultTenant))
+	assert.NoError(t, err)
+
+	bus := NewBus(w)
+	NewTenantPublisher(bus, "payments").Publish(NewModuleEvent(ModulePublished, "acme", "vpc", "aws", "1.0.0"))
+	NewTenantPublisher(bus, "platform").Publish(NewModuleEvent(ModulePublished, "acme", "vpc", "aws", "1.1.0"))
+	// Events of the default tenant don't have a tenant
+	bus.Publish(NewModuleEvent(ModulePublished, "acme", "vpc", "aws", "1.2.0"))
+	assert.NoError(t, bus.Close(context.Background()))
+
+	if !assert.Len(t, r.bodies, 2) {
+		return
+	}
+	var payments, defaults Event
+	assert.NoError(t, json.Unmarshal(r.bodies[0], &payments))
+	assert.Equal(t, "payments", payments.Tenant)
+	assert.Equal(t, "1.0.0", payments.Module.Version)
+	assert.NoError(t, json.Unmarshal(r.bodies[1], &defaults))
+	assert.Equal(t, "", defaults.Tenant)
+	assert.Equal(t, "1.2.0", defaults.Module.Version)
+}
+
+func TestWebhook_Close(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	deadLetters := &recordingDeadLetterLog{}
+	w, err := NewWebhook(server.URL,
+		WithRetryBackoff(time.Hour),
+		WithDeadLetterLog(deadLetters),
+	)
+	assert.NoError(t, err)
+
+	w.Receive(NewPingEvent())
+	w.Receive(NewPingEvent())
+
+	// The pending events are dead-lettered instead of waiting for the retry
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, w.Close(ctx), context.DeadlineExceeded)
+	assert.Len(t, deadLetters.deadLetters, 2)
+
+	// Events received after closing can't be delivered anymore
+	w.Receive(NewPingEvent())
+	assert.Len(t, deadLetters.deadLetters, 3)
+	assert.Equal(t, errClosed.Error(), deadLetters.deadLetters[2].Error)
+}
+
+func TestWebhook_QueueFull(t *testing.T) {
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-blocked
+	}))
+	defer server.Close()
+
+	deadLetters := &recordingDeadLetterLog{}
+	w, err := NewWebhook(server.URL, WithQueueSize(1), WithDeadLetterLog(deadLetters))
+	assert.NoError(t, err)
+
+	// The first event might be delivered already, so that at least one of three events doesn't fit in the queue
+	for i := 0; i < 3; i++ {
+		w.Receive(NewPingEvent())
+	}
+	deadLetters.mu.Lock()
+	assert.NotEmpty(t, deadLetters.deadLetters)
+	assert.Equal(t, errQueueFull.Error(), deadLetters.deadLetters[0].Error)
+	deadLetters.mu.Unlock()
+
+	close(blocked)
+	assert.NoError(t, w.Close(context.Background()))
+}
+
+func TestNewWebhook_InvalidURL(t *testing.T) {
+	for _, u := range []string{"registry.example.com/hook", "ftp://registry.example.com", "https://"} {
+		_, err := NewWebhook(u)
+		assert.Error(t, err, u)
+	}
+}
+
+func TestFileDeadLetterLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	l := NewFileDeadLetterLog(path)
+
+	e := NewPingEvent()
+	assert.NoError(t, l.Write(DeadLetter{Webhook: "https://hooks.example.com", Event: e, Attempts: 6, Error: "timeout"}))
+	assert.NoError(t, l.Write(DeadLetter{Webhook: "https://hooks.example.com", Event: e, Attempts: 1, Error: "rejected"}))
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	var got []DeadLetter
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	for decoder.More() {
+		var d DeadLetter
+		assert.NoError(t, decoder.Decode(&d))
+		got = append(got, d)
+	}
+	assert.Len(t, got, 2)
+	assert.Equal(t, e.ID, got[0].Event.ID)
+	assert.Equal(t, "rejected", got[1].Error)
+}
+
+func TestBus(t *testing.T) {
+	first, second := &receiver{}, &receiver{}
+	firstServer, secondServer := httptest.NewServer(first), httptest.NewServer(second)
+	defer firstServer.Close()
+	defer secondServer.Close()
+
+	w1, err := NewWebhook(firstServer.URL)
+	assert.NoError(t, err)
+	w2, err := NewWebhook(secondServer.URL)
+	assert.NoError(t, err)
+
+	bus := NewBus(w1, w2)
+	bus.Publish(NewPingEvent())
+	assert.NoError(t, bus.Close(context.Background()))
+
+	assert.Len(t, first.requests, 1)
+	assert.Len(t, second.requests, 1)
+}
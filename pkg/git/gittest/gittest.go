@@ -0,0 +1,159 @@
+// Package gittest builds git repositories with go-git and serves them in-process, so that tests don't depend on the git binary.
+package gittest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+)
+
+// Scheme is the scheme of the remote URLs of served repositories
+const Scheme = "gittest"
+
+// Date is the author and commit date of all commits and tags
+var Date = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var signature = object.Signature{Name: "test", Email: "test@example.com", When: Date}
+
+// Init creates a repository with a main branch in dir
+func Init(t testing.TB, dir string) *gogit.Repository {
+	t.Helper()
+	r, err := gogit.PlainInitWithOptions(dir, &gogit.PlainInitOptions{
+		InitOptions: gogit.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// Commit writes the files to the working tree and commits all changes of the working tree
+func Commit(t testing.TB, r *gogit.Repository, files map[string]string, message string) plumbing.Hash {
+	t.Helper()
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		p := filepath.Join(w.Filesystem.Root(), filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.AddWithOptions(&gogit.AddOptions{All: true}); err != nil {
+		t.Fatal(err)
+	}
+	h, err := w.Commit(message, &gogit.CommitOptions{Author: &signature, Committer: &signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// Tag tags the commit, the tag is annotated if the message isn't empty
+func Tag(t testing.TB, r *gogit.Repository, name string, commit plumbing.Hash, message string) {
+	t.Helper()
+	var opts *gogit.CreateTagOptions
+	if message != "" {
+		opts = &gogit.CreateTagOptions{Tagger: &signature, Message: message}
+	}
+	if _, err := r.CreateTag(name, commit, opts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var (
+	installOnce  sync.Once
+	mu           sync.Mutex
+	served       int
+	repositories = map[string]storer.Storer{}
+)
+
+// Serve serves the repository in-process until the test ends and returns its remote URL
+func Serve(t testing.TB, r *gogit.Repository) string {
+	t.Helper()
+	installOnce.Do(func() {
+		client.InstallProtocol(Scheme, uploadPackTransport{server.NewClient(loader{})})
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	served++
+	host := fmt.Sprintf("repository-%d", served)
+	repositories[host] = r.Storer
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(repositories, host)
+	})
+	return fmt.Sprintf("%s://%s", Scheme, host)
+}
+
+// loader loads the served repositories by the host of the remote URL
+type loader struct{}
+
+func (loader) Load(ep *transport.Endpoint) (storer.Storer, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	s, ok := repositories[ep.Host]
+	if !ok {
+		return nil, transport.ErrRepositoryNotFound
+	}
+	return s, nil
+}
+
+// uploadPackTransport allows fetching commits by name, like most hosting services do,
+// and serves fetches with a depth, which the server of go-git doesn't support, by sending the full history
+type uploadPackTransport struct {
+	transport.Transport
+}
+
+func (t uploadPackTransport) NewUploadPackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	s, err := t.Transport.NewUploadPackSession(ep, auth)
+	if err != nil {
+		return nil, err
+	}
+	return uploadPackSession{s}, nil
+}
+
+type uploadPackSession struct {
+	transport.UploadPackSession
+}
+
+func (s uploadPackSession) AdvertisedReferences() (*packp.AdvRefs, error) {
+	return s.AdvertisedReferencesContext(context.Background())
+}
+
+func (s uploadPackSession) AdvertisedReferencesContext(ctx context.Context) (*packp.AdvRefs, error) {
+	ar, err := s.UploadPackSession.AdvertisedReferencesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := ar.Capabilities.Set(capability.AllowReachableSHA1InWant); err != nil {
+		return nil, err
+	}
+	return ar, nil
+}
+
+func (s uploadPackSession) UploadPack(ctx context.Context, req *packp.UploadPackRequest) (*packp.UploadPackResponse, error) {
+	req.Capabilities.Delete(capability.Shallow)
+	req.Depth = packp.DepthCommits(0)
+	return s.UploadPackSession.UploadPack(ctx, req)
+}
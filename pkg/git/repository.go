@@ -0,0 +1,213 @@
+// Package git fetches refs and reads the tags and trees of git repositories with go-git, without depending on the git binary.
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrNotRepository is returned if no git repository can be found
+var ErrNotRepository = errors.New("not a git repository")
+
+// Hash is the SHA-1 name of a git object
+type Hash = plumbing.Hash
+
+// NewHash parses the hex-encoded name of an object
+func NewHash(s string) (Hash, error) {
+	s = strings.TrimSpace(s)
+	if !plumbing.IsHash(s) {
+		return Hash{}, fmt.Errorf("invalid object name %q", s)
+	}
+	return plumbing.NewHash(s), nil
+}
+
+// Repository is a local git repository
+type Repository struct {
+	repo     *gogit.Repository
+	worktree string
+}
+
+// Open opens the repository that contains the path by searching the path and its parents for a .git directory
+func Open(path string) (*Repository, error) {
+	repo, err := gogit.PlainOpenWithOptions(path, &gogit.PlainOpenOptions{
+		DetectDotGit: true,
+		// Linked worktrees share the objects and references of the main repository
+		EnableDotGitCommonDir: true,
+	})
+	if errors.Is(err, gogit.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("%w: %s", ErrNotRepository, path)
+	} else if err != nil {
+		return nil, err
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the working tree: %w", err)
+	}
+	return &Repository{repo: repo, worktree: w.Filesystem.Root()}, nil
+}
+
+// fetchedRef is the local reference that the fetched ref is stored as
+const fetchedRef = "refs/boring-registry/fetched"
+
+// Fetch fetches the ref of the remote repository with a depth of 1 into a new repository in dir.
+// The ref is a branch, a tag or the name of a commit. It returns the repository and the fetched object, which is a tag object for annotated tags.
+func Fetch(ctx context.Context, dir, remote, ref string) (*Repository, Hash, error) {
+	repo, err := gogit.PlainInit(dir, false)
+	if err != nil {
+		return nil, Hash{}, err
+	}
+	origin, err := repo.CreateRemote(&config.RemoteConfig{Name: gogit.DefaultRemoteName, URLs: []string{remote}})
+	if err != nil {
+		return nil, Hash{}, err
+	}
+
+	src := ref
+	if !plumbing.IsHash(ref) {
+		refs, err := origin.ListContext(ctx, &gogit.ListOptions{})
+		if err != nil {
+			return nil, Hash{}, fmt.Errorf("failed to list the refs of %s: %w", remote, err)
+		}
+		name, ok := resolveRef(refs, ref)
+		if !ok {
+			return nil, Hash{}, fmt.Errorf("couldn't find remote ref %s", ref)
+		}
+		src = name.String()
+	}
+
+	err = origin.FetchContext(ctx, &gogit.FetchOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", src, fetchedRef))},
+		Depth:    1,
+		Tags:     gogit.NoTags,
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return nil, Hash{}, fmt.Errorf("failed to fetch %s from %s: %w", ref, remote, err)
+	}
+
+	fetched, err := repo.Reference(fetchedRef, false)
+	if err != nil {
+		return nil, Hash{}, err
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, Hash{}, fmt.Errorf("failed to open the working tree: %w", err)
+	}
+	return &Repository{repo: repo, worktree: w.Filesystem.Root()}, fetched.Hash(), nil
+}
+
+// resolveRef expands a short ref like git does, e.g. a tag takes precedence over a branch of the same name
+func resolveRef(refs []*plumbing.Reference, ref string) (plumbing.ReferenceName, bool) {
+	names := make(map[plumbing.ReferenceName]bool, len(refs))
+	for _, r := range refs {
+		names[r.Name()] = true
+	}
+	for _, rule := range plumbing.RefRevParseRules {
+		if name := plumbing.ReferenceName(fmt.Sprintf(rule, ref)); names[name] {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Close closes the packfiles of the repository
+func (r *Repository) Close() error {
+	if c, ok := r.repo.Storer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// WorkTree returns the root directory of the working tree
+func (r *Repository) WorkTree() string {
+	return r.worktree
+}
+
+// Head returns the commit that HEAD points to
+func (r *Repository) Head() (Hash, error) {
+	ref, err := r.repo.Head()
+	if err != nil {
+		return Hash{}, err
+	}
+	return ref.Hash(), nil
+}
+
+// Tag is a lightweight or annotated tag
+type Tag struct {
+	// Name is the name of the tag without the refs/tags/ prefix
+	Name string
+	// Commit is the commit the tag points to
+	Commit    Hash
+	Annotated bool
+}
+
+// Tags returns all tags that point to commits, sorted by name
+func (r *Repository) Tags() ([]Tag, error) {
+	refs, err := r.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []Tag
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		commit, annotated, err := r.peel(ref.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to resolve tag %s: %w", name, err)
+		} else if commit == nil {
+			// Tags can point to trees or blobs, which aren't relevant
+			return nil
+		}
+		tags = append(tags, Tag{Name: name, Commit: *commit, Annotated: annotated})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+// Peel follows annotated tags until a commit is reached
+func (r *Repository) Peel(h Hash) (Hash, error) {
+	commit, _, err := r.peel(h)
+	if err != nil {
+		return Hash{}, err
+	} else if commit == nil {
+		return Hash{}, fmt.Errorf("%s doesn't point to a commit", h)
+	}
+	return *commit, nil
+}
+
+// peel follows annotated tags until a commit is reached. It returns nil if the object isn't a commit.
+func (r *Repository) peel(h Hash) (*Hash, bool, error) {
+	annotated := false
+	for i := 0; i < 10; i++ {
+		obj, err := r.repo.Object(plumbing.AnyObject, h)
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch o := obj.(type) {
+		case *object.Commit:
+			return &h, annotated, nil
+		case *object.Tag:
+			annotated = true
+			h = o.Target
+		default:
+			return nil, annotated, nil
+		}
+	}
+	return nil, false, fmt.Errorf("too many levels of nested tags for %s", h)
+}
@@ -0,0 +1,195 @@
+package git
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boring-registry/boring-registry/pkg/git/gittest"
+
+	gogit "github.com/go-git/go-git/v5"
+)
+
+// newTestRepository creates a repository with an annotated and a lightweight tag of a module in the vpc directory
+func newTestRepository(t *testing.T) (string, *gogit.Repository) {
+	t.Helper()
+
+	dir := t.TempDir()
+	r := gittest.Init(t, dir)
+	first := gittest.Commit(t, r, map[string]string{
+		"vpc/main.tf": strings.Repeat("# The first version of the module\n", 100),
+		"README.md":   "# Modules\n",
+	}, "Initial commit")
+	gittest.Tag(t, r, "vpc/v1.0.0", first, "Release vpc 1.0.0")
+
+	// The second version is stored as a delta against the first one once the repository is packed
+	second := gittest.Commit(t, r, map[string]string{
+		"vpc/main.tf":      strings.Repeat("# The first version of the module\n", 100) + "# The second version\n",
+		"vpc/variables.tf": `variable "cidr" {}`,
+	}, "Second commit")
+	gittest.Tag(t, r, "vpc/v1.1.0", second, "")
+
+	return dir, r
+}
+
+// packObjects packs all objects of the repository and removes the loose objects, as they're read before the packfiles
+func packObjects(t *testing.T, dir string, r *gogit.Repository) {
+	t.Helper()
+	if err := r.RepackObjects(&gogit.RepackConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	loose, err := filepath.Glob(filepath.Join(dir, ".git", "objects", "[0-9a-f][0-9a-f]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range loose {
+		if err := os.RemoveAll(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRepository(t *testing.T) {
+	dir, repo := newTestRepository(t)
+
+	verify := func(t *testing.T) {
+		r, err := Open(filepath.Join(dir, "vpc"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+
+		if r.WorkTree() != dir {
+			t.Errorf("WorkTree() = %s, want %s", r.WorkTree(), dir)
+		}
+
+		tags, err := r.Tags()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tags) != 2 || tags[0].Name != "vpc/v1.0.0" || !tags[0].Annotated || tags[1].Name != "vpc/v1.1.0" || tags[1].Annotated {
+			t.Fatalf("Tags() = %+v", tags)
+		}
+
+		head, err := r.Head()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head != tags[1].Commit {
+			t.Errorf("Head() = %s, want %s", head, tags[1].Commit)
+		}
+
+		for _, tt := range []struct {
+			tag   Tag
+			files []string
+			main  string
+		}{
+			{tag: tags[0], files: []string{"README.md", "vpc/main.tf"}, main: strings.Repeat("# The first version of the module\n", 100)},
+			{tag: tags[1], files: []string{"README.md", "vpc/main.tf", "vpc/variables.tf"}, main: strings.Repeat("# The first version of the module\n", 100) + "# The second version\n"},
+		} {
+			fsys, err := r.TreeFS(tt.tag.Commit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var files []string
+			err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() {
+					files = append(files, p)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(files, ",") != strings.Join(tt.files, ",") {
+				t.Errorf("files of %s = %v, want %v", tt.tag.Name, files, tt.files)
+			}
+
+			b, err := fs.ReadFile(fsys, "vpc/main.tf")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.main {
+				t.Errorf("vpc/main.tf of %s has unexpected content", tt.tag.Name)
+			}
+
+			info, err := fs.Stat(fsys, "vpc/main.tf")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.ModTime().Equal(gittest.Date) {
+				t.Errorf("ModTime() = %s, want %s", info.ModTime(), gittest.Date)
+			}
+			if _, err := fs.Stat(fsys, "vpc/missing.tf"); !os.IsNotExist(err) {
+				t.Errorf("Stat() of a missing file returned %v", err)
+			}
+		}
+	}
+
+	t.Run("loose objects", verify)
+
+	packObjects(t, dir, repo)
+	t.Run("packed objects", verify)
+}
+
+func TestOpen_notRepository(t *testing.T) {
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Error("Open() expected error")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	dir, repo := newTestRepository(t)
+	remote := gittest.Serve(t, repo)
+
+	r, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	tags, err := r.Tags()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		ref    string
+		commit Hash
+	}{
+		{ref: "vpc/v1.0.0", commit: tags[0].Commit},
+		{ref: "vpc/v1.1.0", commit: tags[1].Commit},
+		{ref: "main", commit: tags[1].Commit},
+		{ref: "refs/heads/main", commit: tags[1].Commit},
+		{ref: tags[0].Commit.String(), commit: tags[0].Commit},
+	} {
+		t.Run(tt.ref, func(t *testing.T) {
+			fetched, h, err := Fetch(context.Background(), t.TempDir(), remote, tt.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fetched.Close()
+
+			commit, err := fetched.Peel(h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if commit != tt.commit {
+				t.Errorf("Fetch() = %s, want %s", commit, tt.commit)
+			}
+			if _, err := fetched.TreeFS(commit); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	if _, _, err := Fetch(context.Background(), t.TempDir(), remote, "vpc/v2.0.0"); err == nil {
+		t.Error("Fetch() of a missing ref expected error")
+	}
+}
@@ -0,0 +1,162 @@
+package git
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// TreeFS returns the tree of the commit as a read-only fs.FS.
+// The modification time of all files is the commit time, which makes archives of the tree reproducible.
+// Submodules are omitted and symbolic links are not followed.
+func (r *Repository) TreeFS(commit Hash) (fs.FS, error) {
+	c, err := r.repo.CommitObject(commit)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return &treeFS{repo: r, root: tree, modTime: c.Committer.When}, nil
+}
+
+type treeFS struct {
+	repo    *Repository
+	root    *object.Tree
+	modTime time.Time
+}
+
+func (t *treeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	entry := object.TreeEntry{Name: ".", Mode: filemode.Dir, Hash: t.root.Hash}
+	if name != "." {
+		e, err := t.root.FindEntry(name)
+		if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) || (err == nil && e.Mode == filemode.Submodule) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		} else if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		entry = *e
+	}
+
+	if entry.Mode == filemode.Dir {
+		tree, err := t.repo.repo.TreeObject(entry.Hash)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		entries := append([]object.TreeEntry{}, tree.Entries...)
+		// Git sorts directories as if they had a trailing slash, whereas fs.ReadDir sorts by name
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+		return &treeDir{fsys: t, info: t.fileInfo(path.Base(name), entry, 0), entries: entries}, nil
+	}
+
+	blob, err := t.repo.repo.BlobObject(entry.Hash)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &treeFile{ReadCloser: reader, info: t.fileInfo(path.Base(name), entry, blob.Size)}, nil
+}
+
+func (t *treeFS) fileInfo(name string, e object.TreeEntry, size int64) *fileInfo {
+	mode := fs.FileMode(0o644)
+	switch e.Mode {
+	case filemode.Dir:
+		mode = fs.ModeDir | 0o755
+	case filemode.Symlink:
+		mode = fs.ModeSymlink | 0o777
+	case filemode.Executable:
+		mode = 0o755
+	}
+	return &fileInfo{name: name, size: size, mode: mode, modTime: t.modTime}
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *fileInfo) Name() string       { return i.name }
+func (i *fileInfo) Size() int64        { return i.size }
+func (i *fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *fileInfo) ModTime() time.Time { return i.modTime }
+func (i *fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *fileInfo) Sys() any           { return nil }
+
+// treeFile streams the content of a blob, so that large files aren't read into memory at once
+type treeFile struct {
+	io.ReadCloser
+	info *fileInfo
+}
+
+func (f *treeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+type treeDir struct {
+	fsys    *treeFS
+	info    *fileInfo
+	entries []object.TreeEntry
+	offset  int
+}
+
+func (d *treeDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *treeDir) Close() error               { return nil }
+
+func (d *treeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *treeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	for ; d.offset < len(d.entries) && (n <= 0 || len(entries) < n); d.offset++ {
+		e := d.entries[d.offset]
+		if e.Mode == filemode.Submodule {
+			continue
+		}
+		entries = append(entries, &dirEntry{fsys: d.fsys, entry: e})
+	}
+	if n > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	}
+	return entries, nil
+}
+
+type dirEntry struct {
+	fsys  *treeFS
+	entry object.TreeEntry
+}
+
+func (e *dirEntry) Name() string { return e.entry.Name }
+func (e *dirEntry) IsDir() bool  { return e.entry.Mode == filemode.Dir }
+
+func (e *dirEntry) Type() fs.FileMode {
+	return e.fsys.fileInfo(e.entry.Name, e.entry, 0).Mode().Type()
+}
+
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	var size int64
+	if !e.IsDir() {
+		blob, err := e.fsys.repo.repo.BlobObject(e.entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+		size = blob.Size
+	}
+	return e.fsys.fileInfo(e.entry.Name, e.entry, size), nil
+}
@@ -0,0 +1,184 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+
+	defaultCacheTTL = 10 * time.Second
+	defaultTimeout  = 3 * time.Second
+)
+
+// Check verifies that a dependency of the registry is operational
+type Check func(ctx context.Context) error
+
+// Result is the outcome of a single Check
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the outcome of all checks, which only has the StatusOK if every check succeeded
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the readiness checks and caches their results.
+// Concurrent callers wait for the same run of the checks, so that probes can't overload the dependencies.
+type Checker struct {
+	checks   []namedCheck
+	cacheTTL time.Duration
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	report  Report
+	expires time.Time
+	now     func() time.Time
+	logger  *slog.Logger
+}
+
+// Option provides additional options for the Checker.
+type Option func(*Checker)
+
+// WithCheck adds a named check to the Checker.
+func WithCheck(name string, check Check) Option {
+	return func(c *Checker) {
+		c.checks = append(c.checks, namedCheck{name: name, check: check})
+	}
+}
+
+// WithCacheTTL configures how long the results of the checks are cached. Setting it to 0 disables the cache.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *Checker) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithTimeout configures after which duration a check is considered to have failed.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Checker) {
+		c.timeout = timeout
+	}
+}
+
+// NewChecker returns a Checker which runs the given checks.
+func NewChecker(opts ...Option) *Checker {
+	c := &Checker{
+		cacheTTL: defaultCacheTTL,
+		timeout:  defaultTimeout,
+		now:      time.Now,
+		logger:   slog.Default().With(slog.String("component", "health")),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Check returns the cached Report, or runs all checks concurrently if the cached Report has expired.
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report.Status != "" && c.now().Before(c.expires) {
+		return c.report
+	}
+
+	// The checks are decoupled from the request, so that a client giving up doesn't fail the cached checks
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}(i, nc.check)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]Result, len(c.checks)),
+	}
+	for i, nc := range c.checks {
+		if results[i].Status != StatusOK {
+			report.Status = StatusFailed
+			c.logger.Warn("readiness check failed", slog.String("check", nc.name), slog.String("error", results[i].Error))
+		}
+		report.Checks[nc.name] = results[i]
+	}
+
+	c.report = report
+	c.expires = c.now().Add(c.cacheTTL)
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	begin := c.now()
+	err := check(ctx)
+
+	result := Result{
+		Status:    StatusOK,
+		Duration:  c.now().Sub(begin).String(),
+		CheckedAt: begin.UTC(),
+	}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler returns an http.Handler which responds as long as the process is able to serve requests.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, Report{Status: StatusOK})
+	})
+}
+
+// ReadinessHandler returns an http.Handler which reports the result of each check.
+// It responds with 503 Service Unavailable if any of the checks failed.
+func ReadinessHandler(c *Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Check(r.Context()))
+	})
+}
+
+// StatusHandler returns an http.Handler which only reports whether all checks succeeded.
+// The results of the checks aren't exposed, because their errors can contain details of the dependencies. Failed checks are logged instead.
+func StatusHandler(c *Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, Report{Status: c.Check(r.Context()).Status})
+	})
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
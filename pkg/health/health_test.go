@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Check(t *testing.T) {
+	var storageCalls, presignCalls int
+	var presignErr error
+
+	checker := NewChecker(
+		WithCacheTTL(10*time.Second),
+		WithCheck("storage", func(ctx context.Context) error {
+			storageCalls++
+			return nil
+		}),
+		WithCheck("presign", func(ctx context.Context) error {
+			presignCalls++
+			return presignErr
+		}),
+	)
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	checker.now = func() time.Time { return now }
+
+	report := checker.Check(context.Background())
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, Result{Status: StatusOK, Duration: "0s", CheckedAt: now}, report.Checks["storage"])
+	assert.Equal(t, Result{Status: StatusOK, Duration: "0s", CheckedAt: now}, report.Checks["presign"])
+
+	// The cached results are returned until they expire
+	presignErr = errors.New("failed to retrieve credentials")
+	now = now.Add(5 * time.Second)
+	report = checker.Check(context.Background())
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, 1, storageCalls)
+	assert.Equal(t, 1, presignCalls)
+
+	now = now.Add(5 * time.Second)
+	report = checker.Check(context.Background())
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["storage"].Status)
+	assert.Equal(t, StatusFailed, report.Checks["presign"].Status)
+	assert.Equal(t, "failed to retrieve credentials", report.Checks["presign"].Error)
+	assert.Equal(t, 2, storageCalls)
+	assert.Equal(t, 2, presignCalls)
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	checker := NewChecker(
+		WithTimeout(10*time.Millisecond),
+		WithCheck("upstream", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	)
+
+	report := checker.Check(context.Background())
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["upstream"].Error)
+}
+
+func TestReadinessHandler(t *testing.T) {
+	testCases := []struct {
+		description string
+		err         error
+		wantStatus  int
+		wantReport  Report
+	}{
+		{
+			description: "all checks succeed",
+			wantStatus:  http.StatusOK,
+			wantReport: Report{
+				Status: StatusOK,
+				Checks: map[string]Result{"storage": {Status: StatusOK}},
+			},
+		},
+		{
+			description: "a check fails",
+			err:         errors.New("bucket is unreachable"),
+			wantStatus:  http.StatusServiceUnavailable,
+			wantReport: Report{
+				Status: StatusFailed,
+				Checks: map[string]Result{"storage": {Status: StatusFailed, Error: "bucket is unreachable"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.description, func(t *testing.T) {
+			checker := NewChecker(WithCheck("storage", func(ctx context.Context) error {
+				return tc.err
+			}))
+
+			rec := httptest.NewRecorder()
+			ReadinessHandler(checker).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+			var report Report
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+			for name, result := range report.Checks {
+				assert.NotEmpty(t, result.Duration)
+				assert.False(t, result.CheckedAt.IsZero())
+				result.Duration = ""
+				result.CheckedAt = time.Time{}
+				report.Checks[name] = result
+			}
+			assert.Equal(t, tc.wantReport, report)
+		})
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+	checker := NewChecker(WithCheck("storage", func(ctx context.Context) error {
+		return errors.New("https response error StatusCode: 403")
+	}))
+
+	rec := httptest.NewRecorder()
+	StatusHandler(checker).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"failed"}`, rec.Body.String())
+}
+
+func TestLivenessHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
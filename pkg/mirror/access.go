@@ -0,0 +1,260 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+)
+
+// AccessLogFileName is the name of the file beneath each mirrored provider that records the last access per version and platform
+const AccessLogFileName = "access-log.json"
+
+// accessLog records when a mirrored provider archive was last requested
+type accessLog struct {
+	// Versions maps the version to the <os>_<arch> platform and the time of the last access
+	Versions map[string]map[string]time.Time `json:"versions"`
+}
+
+func (l *accessLog) lastAccess(version, os, arch string) time.Time {
+	return l.Versions[version][platformKey(os, arch)]
+}
+
+// record updates the time of the last access, unless a more recent access has been recorded already
+func (l *accessLog) record(version, platform string, t time.Time) {
+	if l.Versions[version] == nil {
+		l.Versions[version] = map[string]time.Time{}
+	}
+	if t.After(l.Versions[version][platform]) {
+		l.Versions[version][platform] = t
+	}
+}
+
+func (l *accessLog) remove(version, os, arch string) {
+	delete(l.Versions[version], platformKey(os, arch))
+	if len(l.Versions[version]) == 0 {
+		delete(l.Versions, version)
+	}
+}
+
+// readAccessLog returns the access log of a provider, or an empty one in case it doesn't exist yet
+func readAccessLog(ctx context.Context, s Storage, provider *core.Provider) (*accessLog, error) {
+	r, err := s.DownloadMirroredFile(ctx, provider, AccessLogFileName)
+	if errors.Is(err, core.ErrObjectNotFound) {
+		return decodeAccessLog(nil)
+	} else if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", AccessLogFileName, err)
+	}
+	return decodeAccessLog(b)
+}
+
+// updateAccessLog applies update to the access log of a provider.
+// The access log is updated with optimistic concurrency, as the AccessTracker of every replica and the GarbageCollector write it,
+// so update may be called again with the latest access log.
+func updateAccessLog(ctx context.Context, s Storage, provider *core.Provider, update func(*accessLog)) error {
+	return s.UpdateMirroredFile(ctx, provider, AccessLogFileName, func(b []byte) ([]byte, error) {
+		l, err := decodeAccessLog(b)
+		if err != nil {
+			return nil, err
+		}
+		update(l)
+		return json.Marshal(l)
+	})
+}
+
+// decodeAccessLog decodes an access log, which is empty if b is nil
+func decodeAccessLog(b []byte) (*accessLog, error) {
+	l := &accessLog{Versions: map[string]map[string]time.Time{}}
+	if b == nil {
+		return l, nil
+	}
+	if err := json.Unmarshal(b, l); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", AccessLogFileName, err)
+	}
+	if l.Versions == nil {
+		l.Versions = map[string]map[string]time.Time{}
+	}
+	return l, nil
+}
+
+// providerAddress identifies a provider independent of its version and platform
+type providerAddress struct {
+	hostname  string
+	namespace string
+	name      string
+}
+
+func (a providerAddress) String() string {
+	return fmt.Sprintf("%s/%s/%s", a.hostname, a.namespace, a.name)
+}
+
+func (a providerAddress) provider() *core.Provider {
+	return &core.Provider{
+		Hostname:  a.hostname,
+		Namespace: a.namespace,
+		Name:      a.name,
+	}
+}
+
+// AccessTracker records the access to mirrored provider archives.
+// The accesses are kept in memory and written periodically to the storage backend,
+// where they are used by the GarbageCollector to determine unused providers.
+type AccessTracker struct {
+	storage Storage
+	logger  *slog.Logger
+	now     func() time.Time
+
+	mu      sync.Mutex
+	pending map[providerAddress]*accessLog
+}
+
+// Record marks the version and platform of the provider as accessed
+func (t *AccessTracker) Record(provider *core.Provider) {
+	if provider.Version == "" || provider.OS == "" || provider.Arch == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	addr := providerAddress{hostname: provider.Hostname, namespace: provider.Namespace, name: provider.Name}
+	l, ok := t.pending[addr]
+	if !ok {
+		l = &accessLog{Versions: map[string]map[string]time.Time{}}
+		t.pending[addr] = l
+	}
+	l.record(provider.Version, platformKey(provider.OS, provider.Arch), t.now())
+}
+
+// Flush merges the pending accesses into the access logs in the storage backend
+func (t *AccessTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = map[providerAddress]*accessLog{}
+	t.mu.Unlock()
+
+	var errs []error
+	for addr, l := range pending {
+		if err := t.flush(ctx, addr.provider(), l); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush access log of %s: %w", addr, err))
+
+			// Keep the accesses, so that they are written with the next flush
+			t.mu.Lock()
+			if next, ok := t.pending[addr]; ok {
+				for version, platforms := range l.Versions {
+					for platform, accessed := range platforms {
+						next.record(version, platform, accessed)
+					}
+				}
+			} else {
+				t.pending[addr] = l
+			}
+			t.mu.Unlock()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *AccessTracker) flush(ctx context.Context, provider *core.Provider, pending *accessLog) error {
+	return updateAccessLog(ctx, t.storage, provider, func(stored *accessLog) {
+		for version, platforms := range pending.Versions {
+			for platform, accessed := range platforms {
+				stored.record(version, platform, accessed)
+			}
+		}
+	})
+}
+
+// Run flushes the pending accesses in the given interval until the context is canceled
+func (t *AccessTracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Flush the remaining accesses before terminating
+			flushCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := t.Flush(flushCtx); err != nil {
+				t.logger.Error("failed to flush access logs", slog.String("err", err.Error()))
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				t.logger.Error("failed to flush access logs", slog.String("err", err.Error()))
+			}
+		}
+	}
+}
+
+// NewAccessTracker returns an AccessTracker that writes the access logs to the storage backend
+func NewAccessTracker(s Storage) *AccessTracker {
+	return &AccessTracker{
+		storage: s,
+		logger:  slog.Default().With(slog.String("component", "access-tracker")),
+		now:     time.Now,
+		pending: map[providerAddress]*accessLog{},
+	}
+}
+
+type accessTrackingMiddleware struct {
+	tracker *AccessTracker
+	next    Service
+}
+
+func (mw accessTrackingMiddleware) ListProviderVersions(ctx context.Context, provider *core.Provider) (*ListProviderVersionsResponse, error) {
+	return mw.next.ListProviderVersions(ctx, provider)
+}
+
+// ListProviderInstallation returns the archives relative to the mirror instead of the download URLs of the storage backend,
+// so that Terraform downloads them through RetrieveProviderArchive, which records the access
+func (mw accessTrackingMiddleware) ListProviderInstallation(ctx context.Context, provider *core.Provider) (*ListProviderInstallationResponse, error) {
+	response, err := mw.next.ListProviderInstallation(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, a := range response.Archives {
+		os, arch, ok := strings.Cut(key, "_")
+		if !ok {
+			continue
+		}
+		archive := core.Provider{Name: provider.Name, Version: provider.Version, OS: os, Arch: arch}
+		a.Url = archive.ArchiveFileName()
+		response.Archives[key] = a
+	}
+	return response, nil
+}
+
+func (mw accessTrackingMiddleware) RetrieveProviderArchive(ctx context.Context, provider *core.Provider) (*retrieveProviderArchiveResponse, error) {
+	response, err := mw.next.RetrieveProviderArchive(ctx, provider)
+	if err == nil {
+		mw.tracker.Record(provider)
+	}
+	return response, err
+}
+
+// AccessTrackingMiddleware is a Service middleware that records successful provider archive downloads.
+// The archives of the mirror are listed relative to the mirror, so that all downloads are redirected by the mirror.
+func AccessTrackingMiddleware(tracker *AccessTracker) Middleware {
+	return func(next Service) Service {
+		return &accessTrackingMiddleware{
+			tracker: tracker,
+			next:    next,
+		}
+	}
+}
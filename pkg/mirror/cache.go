@@ -0,0 +1,269 @@
+package mirror
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultCacheMaxEntries bounds the cache, if CacheConfig.MaxEntries isn't set.
+// Negative entries are keyed by the requested providers, so the cache would otherwise grow with every unknown provider.
+const defaultCacheMaxEntries = 10000
+
+// CacheConfig configures the cache for upstream metadata of the pull-through mirror
+type CacheConfig struct {
+	// TTL is the duration for which an upstream response is considered fresh.
+	// The cache is disabled if the TTL is zero.
+	TTL time.Duration
+
+	// StaleTTL is the duration after the TTL has expired during which the stale response is still served,
+	// while the response is refreshed in the background (stale-while-revalidate).
+	StaleTTL time.Duration
+
+	// NegativeTTL is the duration for which upstream 404 responses are cached
+	NegativeTTL time.Duration
+
+	// MaxEntries is the number of entries after which the least recently used entries are evicted.
+	// It defaults to 10000.
+	MaxEntries int
+}
+
+type cacheEntry struct {
+	key        string
+	value      interface{}
+	err        error
+	fetched    time.Time
+	refreshing bool
+}
+
+type fetchFunc func(ctx context.Context) (interface{}, error)
+
+// cachedUpstreamProvider implements upstreamProvider and caches the responses of the wrapped upstreamProvider
+type cachedUpstreamProvider struct {
+	next   upstreamProvider
+	config CacheConfig
+
+	// refreshTimeout limits the duration of background refreshes
+	refreshTimeout time.Duration
+
+	mu sync.Mutex
+	// entries point to the elements of lru, which is ordered from the most to the least recently used entry
+	entries map[string]*list.Element
+	lru     *list.List
+	// group deduplicates concurrent fetches of the same key, so that a miss only results in a single upstream request
+	group  singleflight.Group
+	now    func() time.Time
+	logger *slog.Logger
+}
+
+func (c *cachedUpstreamProvider) listProviderVersions(ctx context.Context, provider *core.Provider) (*core.ProviderVersions, error) {
+	key := fmt.Sprintf("versions/%s/%s/%s", provider.Hostname, provider.Namespace, provider.Name)
+	v, err := c.get(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return c.next.listProviderVersions(ctx, provider)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyProviderVersions(v.(*core.ProviderVersions)), nil
+}
+
+func (c *cachedUpstreamProvider) getProvider(ctx context.Context, provider *core.Provider) (*core.Provider, error) {
+	key := fmt.Sprintf("provider/%s/%s/%s/%s/%s/%s", provider.Hostname, provider.Namespace, provider.Name, provider.Version, provider.OS, provider.Arch)
+	clone := *provider
+	v, err := c.get(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return c.next.getProvider(ctx, &clone)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Return a copy, so that callers can't modify the cached entry
+	return copyProvider(v.(*core.Provider)), nil
+}
+
+func (c *cachedUpstreamProvider) shaSums(ctx context.Context, provider *core.Provider) (*core.Sha256Sums, error) {
+	key := fmt.Sprintf("shasums/%s/%s/%s/%s", provider.Hostname, provider.Namespace, provider.Name, provider.Version)
+	clone := *provider
+	v, err := c.get(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return c.next.shaSums(ctx, &clone)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copySha256Sums(v.(*core.Sha256Sums)), nil
+}
+
+// get returns the cached value for the key.
+// Fresh entries are returned right away, stale entries are returned while being refreshed in the background.
+// Otherwise, the value is fetched synchronously and stored in the cache.
+// The returned value is shared with the cache and has to be copied before it's handed out.
+func (c *cachedUpstreamProvider) get(ctx context.Context, key string, fetch fetchFunc) (interface{}, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*cacheEntry)
+		age := c.now().Sub(e.fetched)
+		if e.err != nil {
+			if age < c.config.NegativeTTL {
+				c.lru.MoveToFront(elem)
+				c.mu.Unlock()
+				return nil, e.err
+			}
+		} else if age < c.config.TTL {
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			return e.value, nil
+		} else if age < c.config.TTL+c.config.StaleTTL {
+			if !e.refreshing {
+				e.refreshing = true
+				go c.refresh(key, fetch)
+			}
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			return e.value, nil
+		}
+
+		// The entry expired and can't be served anymore
+		c.remove(elem)
+	}
+	c.mu.Unlock()
+
+	// The fetch isn't canceled with the request that started it, as concurrent requests for the same key wait for it as well
+	result := c.group.DoChan(key, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.refreshTimeout)
+		defer cancel()
+
+		v, err := fetch(fetchCtx)
+		c.store(key, v, err)
+		return v, err
+	})
+	select {
+	case r := <-result:
+		return r.Val, r.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh fetches the value in the background and updates the cache entry
+func (c *cachedUpstreamProvider) refresh(key string, fetch fetchFunc) {
+	_, _, _ = c.group.Do(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), c.refreshTimeout)
+		defer cancel()
+
+		v, err := fetch(ctx)
+		if err != nil {
+			c.logger.Debug("failed to refresh cache entry", slog.String("key", key), slog.String("err", err.Error()))
+		}
+		c.store(key, v, err)
+		return v, err
+	})
+}
+
+// store caches the value, or the error if the provider wasn't found upstream.
+// Other errors, e.g. of unauthorized or rate-limited requests, are never cached.
+func (c *cachedUpstreamProvider) store(key string, value interface{}, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err == nil {
+		c.put(&cacheEntry{
+			key:     key,
+			value:   value,
+			fetched: c.now(),
+		})
+		return
+	}
+
+	if errors.Is(err, ErrUpstreamNotFound) && c.config.NegativeTTL > 0 {
+		c.put(&cacheEntry{
+			key:     key,
+			err:     err,
+			fetched: c.now(),
+		})
+		return
+	}
+
+	// Other errors are not cached. A stale entry is kept until it expires, so that it can be refreshed again.
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry).refreshing = false
+	}
+}
+
+// put adds or replaces the entry and evicts the least recently used entries beyond the maximum. c.mu has to be held.
+func (c *cachedUpstreamProvider) put(e *cacheEntry) {
+	if elem, ok := c.entries[e.key]; ok {
+		elem.Value = e
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[e.key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.config.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove deletes the entry of the element. c.mu has to be held.
+func (c *cachedUpstreamProvider) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
+
+func copyProviderVersions(v *core.ProviderVersions) *core.ProviderVersions {
+	versions := make([]core.ProviderVersion, len(v.Versions))
+	for i, version := range v.Versions {
+		version.Protocols = append([]string(nil), version.Protocols...)
+		version.Platforms = append([]core.Platform(nil), version.Platforms...)
+		if version.Deprecation != nil {
+			deprecation := *version.Deprecation
+			version.Deprecation = &deprecation
+		}
+		if version.Labels != nil {
+			labels := make(map[string]string, len(version.Labels))
+			for k, l := range version.Labels {
+				labels[k] = l
+			}
+			version.Labels = labels
+		}
+		versions[i] = version
+	}
+	return &core.ProviderVersions{Versions: versions}
+}
+
+func copyProvider(p *core.Provider) *core.Provider {
+	clone := *p
+	clone.SigningKeys.GPGPublicKeys = append([]core.GPGPublicKey(nil), p.SigningKeys.GPGPublicKeys...)
+	clone.Platforms = append([]core.Platform(nil), p.Platforms...)
+	return &clone
+}
+
+func copySha256Sums(s *core.Sha256Sums) *core.Sha256Sums {
+	entries := make(map[string][]byte, len(s.Entries))
+	for name, sum := range s.Entries {
+		entries[name] = append([]byte(nil), sum...)
+	}
+	return &core.Sha256Sums{Entries: entries, Filename: s.Filename}
+}
+
+func newCachedUpstreamProvider(next upstreamProvider, config CacheConfig, refreshTimeout time.Duration) *cachedUpstreamProvider {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultCacheMaxEntries
+	}
+	return &cachedUpstreamProvider{
+		next:           next,
+		config:         config,
+		refreshTimeout: refreshTimeout,
+		entries:        make(map[string]*list.Element),
+		lru:            list.New(),
+		now:            time.Now,
+		logger:         slog.Default().With(slog.String("component", "upstream-cache")),
+	}
+}
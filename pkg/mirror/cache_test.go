@@ -0,0 +1,265 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func Test_cachedUpstreamProvider_listProviderVersions(t *testing.T) {
+	config := CacheConfig{
+		TTL:         time.Minute,
+		StaleTTL:    time.Hour,
+		NegativeTTL: 10 * time.Second,
+	}
+
+	tests := []struct {
+		name string
+		// upstreamErr is returned by the mocked upstream
+		upstreamErr error
+		// advance is the duration the clock is advanced between both requests
+		advance   time.Duration
+		wantCalls int32
+		wantErr   bool
+	}{
+		{
+			name:      "fresh entry is served from cache",
+			advance:   30 * time.Second,
+			wantCalls: 1,
+		},
+		{
+			name:      "stale entry is served and refreshed in the background",
+			advance:   30 * time.Minute,
+			wantCalls: 2,
+		},
+		{
+			name:      "expired entry is fetched synchronously",
+			advance:   2 * time.Hour,
+			wantCalls: 2,
+		},
+		{
+			name:        "not found upstream is cached",
+			upstreamErr: fmt.Errorf("%w: status code is 404 instead of 200", ErrUpstreamNotFound),
+			advance:     5 * time.Second,
+			wantCalls:   1,
+			wantErr:     true,
+		},
+		{
+			name:        "negative cache entry expires",
+			upstreamErr: fmt.Errorf("%w: status code is 404 instead of 200", ErrUpstreamNotFound),
+			advance:     20 * time.Second,
+			wantCalls:   2,
+			wantErr:     true,
+		},
+		{
+			name:        "server errors are not cached",
+			upstreamErr: fmt.Errorf("%w: status code is 502 instead of 200", ErrUpstreamUnavailable),
+			advance:     time.Second,
+			wantCalls:   2,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			refreshed := make(chan struct{}, 2)
+			upstream := &mockedUpstreamProvider{
+				customListProviderVersions: func(ctx context.Context, provider *core.Provider) (*core.ProviderVersions, error) {
+					defer func() { refreshed <- struct{}{} }()
+					calls.Add(1)
+					if tt.upstreamErr != nil {
+						return nil, tt.upstreamErr
+					}
+					return &core.ProviderVersions{
+						Versions: []core.ProviderVersion{{Version: "1.0.0"}},
+					}, nil
+				},
+			}
+
+			clock := &fakeClock{now: time.Now()}
+			c := newCachedUpstreamProvider(upstream, config, time.Second)
+			c.now = clock.Now
+
+			provider := &core.Provider{Hostname: "registry.example.com", Namespace: "hashicorp", Name: "random"}
+			for i := 0; i < 2; i++ {
+				got, err := c.listProviderVersions(context.Background(), provider)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("listProviderVersions() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if tt.upstreamErr != nil && !errors.Is(err, tt.upstreamErr) {
+					t.Fatalf("listProviderVersions() error = %v, want %v", err, tt.upstreamErr)
+				}
+				if !tt.wantErr && len(got.Versions) != 1 {
+					t.Fatalf("listProviderVersions() got = %v", got)
+				}
+				clock.advance(tt.advance)
+			}
+
+			// Wait for all upstream calls, including background refreshes
+			for i := int32(0); i < tt.wantCalls; i++ {
+				select {
+				case <-refreshed:
+				case <-time.After(time.Second):
+					t.Fatalf("timed out waiting for upstream call %d", i+1)
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("upstream calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func Test_cachedUpstreamProvider_getProvider(t *testing.T) {
+	var calls atomic.Int32
+	upstream := &mockedUpstreamProvider{
+		customGetProvider: func(ctx context.Context, provider *core.Provider) (*core.Provider, error) {
+			calls.Add(1)
+			p := *provider
+			p.DownloadURL = fmt.Sprintf("https://example.com/%s_%s.zip", provider.OS, provider.Arch)
+			return &p, nil
+		},
+	}
+	c := newCachedUpstreamProvider(upstream, CacheConfig{TTL: time.Minute}, time.Second)
+
+	amd64 := &core.Provider{Hostname: "registry.example.com", Namespace: "hashicorp", Name: "random", Version: "1.0.0", OS: "linux", Arch: "amd64"}
+	arm64 := &core.Provider{Hostname: "registry.example.com", Namespace: "hashicorp", Name: "random", Version: "1.0.0", OS: "linux", Arch: "arm64"}
+
+	first, err := c.getProvider(context.Background(), amd64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Modifying the returned provider must not alter the cache
+	first.DownloadURL = "modified"
+
+	second, err := c.getProvider(context.Background(), amd64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.DownloadURL != "https://example.com/linux_amd64.zip" {
+		t.Errorf("getProvider() DownloadURL = %s", second.DownloadURL)
+	}
+
+	if _, err := c.getProvider(context.Background(), arm64); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("upstream calls = %d, want 2", got)
+	}
+}
+
+func Test_cachedUpstreamProvider_concurrentMisses(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	upstream := &mockedUpstreamProvider{
+		customListProviderVersions: func(ctx context.Context, provider *core.Provider) (*core.ProviderVersions, error) {
+			calls.Add(1)
+			<-release
+			return &core.ProviderVersions{Versions: []core.ProviderVersion{{Version: "1.0.0", Protocols: []string{"5.0"}}}}, nil
+		},
+	}
+	c := newCachedUpstreamProvider(upstream, CacheConfig{TTL: time.Minute}, time.Second)
+
+	provider := &core.Provider{Hostname: "registry.example.com", Namespace: "hashicorp", Name: "random"}
+	var wg sync.WaitGroup
+	results := make([]*core.ProviderVersions, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := c.listProviderVersions(context.Background(), provider)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i] = v
+		}(i)
+	}
+	// Give the goroutines time to wait for the same fetch
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("upstream calls = %d, want 1", got)
+	}
+
+	// Modifying a returned value must not alter the cache or the values of other callers
+	results[0].Versions[0].Protocols[0] = "modified"
+	cached, err := c.listProviderVersions(context.Background(), provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Versions[0].Protocols[0] != "5.0" || results[1].Versions[0].Protocols[0] != "5.0" {
+		t.Errorf("cached protocols were modified")
+	}
+}
+
+func Test_cachedUpstreamProvider_maxEntries(t *testing.T) {
+	var calls atomic.Int32
+	upstream := &mockedUpstreamProvider{
+		customListProviderVersions: func(ctx context.Context, provider *core.Provider) (*core.ProviderVersions, error) {
+			calls.Add(1)
+			return nil, fmt.Errorf("%w: status code is 404 instead of 200", ErrUpstreamNotFound)
+		},
+	}
+	c := newCachedUpstreamProvider(upstream, CacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, MaxEntries: 2}, time.Second)
+
+	for _, name := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, _ = c.listProviderVersions(context.Background(), &core.Provider{Hostname: "registry.example.com", Namespace: "hashicorp", Name: name})
+	}
+
+	// b is evicted by c, as a was used more recently
+	if got := calls.Load(); got != 4 {
+		t.Errorf("upstream calls = %d, want 4", got)
+	}
+	if got := len(c.entries); got != 2 {
+		t.Errorf("entries = %d, want 2", got)
+	}
+}
+
+func Test_upstreamStatusError(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{status: http.StatusNotFound, want: ErrUpstreamNotFound},
+		{status: http.StatusGone, want: ErrUpstreamNotFound},
+		{status: http.StatusUnauthorized, want: ErrUpstreamRejected},
+		{status: http.StatusForbidden, want: ErrUpstreamRejected},
+		{status: http.StatusTooManyRequests, want: ErrUpstreamRejected},
+		{status: http.StatusBadGateway, want: ErrUpstreamUnavailable},
+		{status: http.StatusServiceUnavailable, want: ErrUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		if err := upstreamStatusError(&http.Response{StatusCode: tt.status}); !errors.Is(err, tt.want) {
+			t.Errorf("upstreamStatusError(%d) = %v, want %v", tt.status, err, tt.want)
+		}
+	}
+}
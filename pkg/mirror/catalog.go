@@ -0,0 +1,89 @@
+package mirror
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/hashicorp/go-version"
+)
+
+// MirroredProvider is a provider in the mirror with its versions
+type MirroredProvider struct {
+	Hostname  string
+	Namespace string
+	Name      string
+	// Versions are sorted from the most recent to the oldest version
+	Versions []MirroredVersion
+}
+
+// MirroredVersion is a version of a mirrored provider with the platforms of its archives
+type MirroredVersion struct {
+	Version   string
+	Platforms []core.Platform
+}
+
+// CatalogService lists the contents of the provider network mirror.
+// Unlike the Service, it isn't part of the Provider Network Mirror Protocol and never contacts upstream registries.
+type CatalogService interface {
+	// ListMirroredProviders returns all mirrored providers sorted by hostname, namespace and name
+	ListMirroredProviders(ctx context.Context) ([]MirroredProvider, error)
+}
+
+type catalog struct {
+	storage Storage
+}
+
+// NewCatalogService returns a CatalogService of the providers in the storage
+func NewCatalogService(storage Storage) CatalogService {
+	return &catalog{storage: storage}
+}
+
+func (c *catalog) ListMirroredProviders(ctx context.Context) ([]MirroredProvider, error) {
+	files, err := c.storage.ListMirroredFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := map[providerAddress]map[string][]core.Platform{}
+	for _, f := range files {
+		p, err := core.NewProviderFromArchive(f.FileName)
+		if err != nil || !strings.HasSuffix(f.FileName, core.ProviderExtension) {
+			continue
+		}
+
+		addr := providerAddress{hostname: f.Hostname, namespace: f.Namespace, name: f.Name}
+		if _, ok := providers[addr]; !ok {
+			providers[addr] = map[string][]core.Platform{}
+		}
+		providers[addr][p.Version] = append(providers[addr][p.Version], core.Platform{OS: p.OS, Arch: p.Arch})
+	}
+
+	result := make([]MirroredProvider, 0, len(providers))
+	for addr, versions := range providers {
+		mp := MirroredProvider{Hostname: addr.hostname, Namespace: addr.namespace, Name: addr.name}
+		for v, platforms := range versions {
+			sort.Slice(platforms, func(i, j int) bool {
+				return platforms[i].OS+"_"+platforms[i].Arch < platforms[j].OS+"_"+platforms[j].Arch
+			})
+			mp.Versions = append(mp.Versions, MirroredVersion{Version: v, Platforms: platforms})
+		}
+		sort.Slice(mp.Versions, func(i, j int) bool {
+			a, errA := version.NewVersion(mp.Versions[i].Version)
+			b, errB := version.NewVersion(mp.Versions[j].Version)
+			if errA != nil || errB != nil {
+				return mp.Versions[i].Version > mp.Versions[j].Version
+			}
+			return a.GreaterThan(b)
+		})
+		result = append(result, mp)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		return a.Hostname+"/"+a.Namespace+"/"+a.Name < b.Hostname+"/"+b.Namespace+"/"+b.Name
+	})
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package mirror
+
+import (
+	"context"
+	"testing"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogService_ListMirroredProviders(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]MirroredFile{}
+	for _, f := range []MirroredFile{
+		{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", FileName: "terraform-provider-random_3.6.2_linux_amd64.zip"},
+		{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", FileName: "terraform-provider-random_3.6.2_darwin_arm64.zip"},
+		{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", FileName: "terraform-provider-random_3.6.2_SHA256SUMS"},
+		{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", FileName: "terraform-provider-random_3.10.0_linux_amd64.zip"},
+		{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", FileName: AccessLogFileName},
+		{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws", FileName: "terraform-provider-aws_5.0.0_linux_amd64.zip"},
+		{Hostname: "example.com", Namespace: "acme", Name: "dummy", FileName: "terraform-provider-dummy_1.0.0_linux_amd64.zip"},
+	} {
+		files[f.Hostname+"/"+f.Namespace+"/"+f.Name+"/"+f.FileName] = f
+	}
+
+	providers, err := NewCatalogService(newInmemMirroredFiles(files, nil)).ListMirroredProviders(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []MirroredProvider{
+		{
+			Hostname:  "example.com",
+			Namespace: "acme",
+			Name:      "dummy",
+			Versions:  []MirroredVersion{{Version: "1.0.0", Platforms: []core.Platform{{OS: "linux", Arch: "amd64"}}}},
+		},
+		{
+			Hostname:  "registry.terraform.io",
+			Namespace: "hashicorp",
+			Name:      "aws",
+			Versions:  []MirroredVersion{{Version: "5.0.0", Platforms: []core.Platform{{OS: "linux", Arch: "amd64"}}}},
+		},
+		{
+			Hostname:  "registry.terraform.io",
+			Namespace: "hashicorp",
+			Name:      "random",
+			Versions: []MirroredVersion{
+				{Version: "3.10.0", Platforms: []core.Platform{{OS: "linux", Arch: "amd64"}}},
+				{Version: "3.6.2", Platforms: []core.Platform{{OS: "darwin", Arch: "arm64"}, {OS: "linux", Arch: "amd64"}}},
+			},
+		},
+	}, providers)
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
 )
 
 type Copier interface {
@@ -24,6 +25,7 @@ type copier struct {
 	storage Storage
 	client  *http.Client
 	logger  *slog.Logger
+	policy  *Policy
 }
 
 // copy should be started in a separate goroutine
@@ -43,40 +45,52 @@ func (c *copier) copy(provider *core.Provider) {
 		}
 	}()
 
-	// We download the files from upstream and mirror them to our storage
-	if err := c.signingKeys(ctx, provider); err != nil {
-		c.logger.Error("failed to copy signing keys", logKeyValues(provider), slog.String("err", err.Error()))
+	if err := c.policy.Check(provider); err != nil {
+		c.logger.Warn("refusing to copy provider", logKeyValues(provider), slog.String("err", err.Error()))
 		return
 	}
 
-	if err := c.sha256Sums(ctx, provider); err != nil {
-		c.logger.Error("failed to copy SHA256SUMS", logKeyValues(provider), slog.String("err", err.Error()))
+	if err := c.copyProvider(ctx, provider); err != nil {
+		c.logger.Error("failed to copy provider", logKeyValues(provider), slog.String("err", err.Error()))
 		return
 	}
+	c.logger.Info("successfully copied provider", logKeyValues(provider), slog.String("took", time.Since(begin).String()))
+}
+
+// copyProvider downloads the signing keys, SHA256SUMS, SHA256SUMS.sig, and archive of the provider from upstream
+// and uploads them to the mirror
+func (c *copier) copyProvider(ctx context.Context, provider *core.Provider) error {
+	if err := c.signingKeys(ctx, provider); err != nil {
+		return fmt.Errorf("failed to copy signing keys: %w", err)
+	}
+
+	if err := c.sha256Sums(ctx, provider); err != nil {
+		return fmt.Errorf("failed to copy SHA256SUMS: %w", err)
+	}
 
 	if err := c.sha256SumsSignature(ctx, provider); err != nil {
-		c.logger.Error("failed to copy SHA256SUMS.sig", logKeyValues(provider), slog.String("err", err.Error()))
-		return
+		return fmt.Errorf("failed to copy SHA256SUMS.sig: %w", err)
 	}
 
 	// Request the provider archive
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.DownloadURL, nil)
 	if err != nil {
-		c.logger.Error("failed to create provider download request", logKeyValues(provider), slog.String("err", err.Error()))
-		return
+		return fmt.Errorf("failed to create provider download request: %w", err)
 	}
 	resp, err := c.client.Do(req)
 	if err != nil {
-		c.logger.Error("failed to download provider", logKeyValues(provider), slog.String("err", err.Error()))
-		return
+		return fmt.Errorf("failed to download provider: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download provider, statuscode is %v", resp.StatusCode)
+	}
 
 	fileName := provider.ArchiveFileName()
 	if err = c.storage.UploadMirroredFile(ctx, provider, fileName, resp.Body); err != nil {
-		c.logger.Error("failed to upload provider to mirror", logKeyValues(provider), slog.String("err", err.Error()))
+		return fmt.Errorf("failed to upload provider to mirror: %w", err)
 	}
-	c.logger.Info("successfully copied provider", logKeyValues(provider), slog.String("took", time.Since(begin).String()))
+	return nil
 }
 
 // check if the signing keys exist, if not add it
@@ -142,17 +156,33 @@ func (c *copier) shutdown(ctx context.Context) {
 	close(c.done)
 }
 
-func NewCopier(ctx context.Context, storage Storage) Copier {
+// CopierOption provides additional options for the Copier
+type CopierOption func(*copier)
+
+// WithCopierPolicy configures the Policy that providers have to comply with in order to be copied
+func WithCopierPolicy(policy *Policy) CopierOption {
+	return func(c *copier) {
+		c.policy = policy
+	}
+}
+
+func NewCopier(ctx context.Context, storage Storage, options ...CopierOption) Copier {
 	logger := slog.Default().With(slog.String("component", "copier"))
 	m := &copier{
 		done:   make(chan struct{}),
 		logger: logger,
 		client: &http.Client{
 			// This is also the timeout for reading the response body
-			Timeout: 2 * time.Minute,
+			Timeout:   2 * time.Minute,
+			Transport: o11y.NewTransport(http.DefaultTransport),
 		},
 		storage: storage,
 	}
+
+	for _, option := range options {
+		option(m)
+	}
+
 	go m.shutdown(ctx)
 	return m
 }
@@ -168,15 +198,22 @@ func logKeyValues(provider *core.Provider) slog.Attr {
 	)
 }
 
+// mergeGPGPublicKeys adds the upstream keys that are missing in the mirrored keys.
+// The returned boolean is true in case any key was added.
 func mergeGPGPublicKeys(upstreamKeys, mirroredKeys []core.GPGPublicKey) ([]core.GPGPublicKey, bool) {
-	var merged []core.GPGPublicKey
+	merged := append([]core.GPGPublicKey{}, mirroredKeys...)
 	for _, upstreamKey := range upstreamKeys {
+		exists := false
 		for _, storedKey := range mirroredKeys {
-			if storedKey.KeyID != upstreamKey.KeyID {
-				merged = append(merged, upstreamKey)
+			if storedKey.KeyID == upstreamKey.KeyID {
+				exists = true
+				break
 			}
 		}
+		if !exists {
+			merged = append(merged, upstreamKey)
+		}
 	}
 
-	return merged, len(merged) > len(upstreamKeys)
+	return merged, len(merged) > len(mirroredKeys)
 }
@@ -3,5 +3,7 @@ package mirror
 import "errors"
 
 var (
-	ErrUpstreamNotFound = errors.New("not found upstream")
+	ErrUpstreamNotFound    = errors.New("not found upstream")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	ErrUpstreamRejected    = errors.New("rejected by upstream")
 )
@@ -0,0 +1,693 @@
+package mirror
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/hashicorp/go-version"
+)
+
+// signingKeysFileName is the name of the file that contains the signing keys of a provider in a filesystem mirror.
+// Terraform ignores it, as it only considers archives in the packed layout.
+const signingKeysFileName = "signing-keys.json"
+
+// FileWriter writes the files of a filesystem mirror
+type FileWriter interface {
+	// WriteFile writes a file with the slash-separated name, which is relative to the root of the filesystem mirror
+	WriteFile(name string, size int64, modTime time.Time, r io.Reader) error
+	Close() error
+}
+
+type directoryWriter struct {
+	dir string
+}
+
+func (d *directoryWriter) WriteFile(name string, size int64, modTime time.Time, r io.Reader) error {
+	p := filepath.Join(d.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(p, modTime, modTime)
+}
+
+func (d *directoryWriter) Close() error {
+	return nil
+}
+
+// NewDirectoryWriter returns a FileWriter that writes the filesystem mirror to a directory
+func NewDirectoryWriter(dir string) FileWriter {
+	return &directoryWriter{dir: dir}
+}
+
+type tarGzWriter struct {
+	gw *gzip.Writer
+	tw *tar.Writer
+
+	// dirs contains the directories that have been written already
+	dirs map[string]struct{}
+}
+
+func (t *tarGzWriter) WriteFile(name string, size int64, modTime time.Time, r io.Reader) error {
+	// Directories are written explicitly, so that the tarball can be extracted by any tool
+	var parents []string
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		parents = append([]string{dir}, parents...)
+	}
+	for _, dir := range parents {
+		if _, ok := t.dirs[dir]; ok {
+			continue
+		}
+		if err := t.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0o755, ModTime: modTime}); err != nil {
+			return err
+		}
+		t.dirs[dir] = struct{}{}
+	}
+
+	if err := t.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: size, Mode: 0o644, ModTime: modTime}); err != nil {
+		return err
+	}
+	_, err := io.Copy(t.tw, r)
+	return err
+}
+
+func (t *tarGzWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	return t.gw.Close()
+}
+
+// NewTarGzWriter returns a FileWriter that writes the filesystem mirror as a gzip-compressed tarball
+func NewTarGzWriter(w io.Writer) FileWriter {
+	gw := gzip.NewWriter(w)
+	return &tarGzWriter{
+		gw:   gw,
+		tw:   tar.NewWriter(gw),
+		dirs: map[string]struct{}{},
+	}
+}
+
+// ExportResult summarizes the providers that were exported
+type ExportResult struct {
+	Providers []*core.Provider
+}
+
+// ExportOption provides additional options for Export
+type ExportOption func(*exporter) error
+
+// WithExportedProviders limits the export to providers matching one of the patterns in the form of <hostname>/<namespace>/<name>
+func WithExportedProviders(patterns ...string) ExportOption {
+	return func(e *exporter) error {
+		for _, s := range patterns {
+			p, err := parseProviderPattern(s)
+			if err != nil {
+				return err
+			}
+			e.patterns = append(e.patterns, p)
+		}
+		return nil
+	}
+}
+
+type exporter struct {
+	storage  Storage
+	writer   FileWriter
+	patterns []providerPattern
+}
+
+// Export writes the mirrored providers in the packed layout of a Terraform filesystem mirror.
+// Like `terraform providers mirror`, it writes an index.json and <version>.json file for every provider,
+// so that the exported directory can be served as a static network mirror as well.
+// The SHA256SUMS, SHA256SUMS.sig and signing keys are exported alongside the archives, so that they can be imported again.
+func Export(ctx context.Context, s Storage, w FileWriter, options ...ExportOption) (*ExportResult, error) {
+	e := &exporter{storage: s, writer: w}
+	for _, option := range options {
+		if err := option(e); err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := s.ListMirroredFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mirrored files: %w", err)
+	}
+
+	providers := map[providerAddress][]MirroredFile{}
+	for _, f := range files {
+		addr := providerAddress{hostname: f.Hostname, namespace: f.Namespace, name: f.Name}
+		if !e.matches(addr) {
+			continue
+		}
+		providers[addr] = append(providers[addr], f)
+	}
+
+	addresses := make([]providerAddress, 0, len(providers))
+	for addr := range providers {
+		addresses = append(addresses, addr)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].String() < addresses[j].String()
+	})
+
+	result := &ExportResult{}
+	for _, addr := range addresses {
+		exported, err := e.export(ctx, addr, providers[addr])
+		if err != nil {
+			return result, fmt.Errorf("failed to export %s: %w", addr, err)
+		}
+		result.Providers = append(result.Providers, exported...)
+	}
+	return result, nil
+}
+
+func (e *exporter) matches(addr providerAddress) bool {
+	if len(e.patterns) == 0 {
+		return true
+	}
+	for _, p := range e.patterns {
+		if p.matches(addr.provider()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *exporter) export(ctx context.Context, addr providerAddress, files []MirroredFile) ([]*core.Provider, error) {
+	dir := addr.String()
+	provider := addr.provider()
+
+	signingKeys, err := e.storage.MirroredSigningKeys(ctx, addr.hostname, addr.namespace)
+	if err != nil && !errors.Is(err, core.ErrObjectNotFound) {
+		return nil, err
+	}
+	if signingKeys != nil {
+		b, err := json.Marshal(signingKeys)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.writer.WriteFile(path.Join(dir, signingKeysFileName), int64(len(b)), time.Now(), bytes.NewReader(b)); err != nil {
+			return nil, err
+		}
+	}
+
+	var exported []*core.Provider
+	index := ListProviderVersionsResponse{Versions: map[string]EmptyObject{}}
+	for _, mv := range groupVersions(addr, files) {
+		if len(mv.archives) == 0 {
+			continue
+		}
+
+		for _, f := range mv.other {
+			if err := e.copyFile(ctx, provider, dir, f, nil); err != nil {
+				return nil, err
+			}
+		}
+
+		installation := ListProviderInstallationResponse{Archives: map[string]Archive{}}
+		for _, f := range mv.archives {
+			var hashes []string
+			if err := e.copyFile(ctx, provider, dir, f, func(archive *os.File) error {
+				hashes, err = archiveHashes(archive)
+				return err
+			}); err != nil {
+				return nil, err
+			}
+
+			p, _ := core.NewProviderFromArchive(f.FileName)
+			p.Hostname, p.Namespace = addr.hostname, addr.namespace
+			installation.Archives[platformKey(p.OS, p.Arch)] = Archive{Url: f.FileName, Hashes: hashes}
+			exported = append(exported, &p)
+		}
+
+		b, err := json.Marshal(installation)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.writer.WriteFile(path.Join(dir, fmt.Sprintf("%s.json", mv.version.Original())), int64(len(b)), time.Now(), bytes.NewReader(b)); err != nil {
+			return nil, err
+		}
+		index.Versions[mv.version.Original()] = EmptyObject{}
+	}
+
+	if len(index.Versions) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	return exported, e.writer.WriteFile(path.Join(dir, "index.json"), int64(len(b)), time.Now(), bytes.NewReader(b))
+}
+
+// copyFile copies a mirrored file via a temporary file to the FileWriter.
+// The inspect function can be used to read the temporary file before it's written.
+func (e *exporter) copyFile(ctx context.Context, provider *core.Provider, dir string, f MirroredFile, inspect func(*os.File) error) error {
+	r, err := e.storage.DownloadMirroredFile(ctx, provider, f.FileName)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", f.FileName, err)
+	}
+	defer r.Close()
+
+	tmp, err := os.CreateTemp("", "boring-registry-export-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", f.FileName, err)
+	}
+
+	if inspect != nil {
+		if err := inspect(tmp); err != nil {
+			return fmt.Errorf("failed to inspect %s: %w", f.FileName, err)
+		}
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return e.writer.WriteFile(path.Join(dir, f.FileName), size, f.LastModified, tmp)
+}
+
+// archiveHashes returns the `h1:` and `zh:` hashes of a provider archive as used by Terraform.
+// See https://developer.hashicorp.com/terraform/language/files/dependency-lock#checksum-verification
+func archiveHashes(f *os.File) ([]string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	zh, err := core.Sha256Checksum(f)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	h1, err := packageHashV1(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{h1, fmt.Sprintf("zh:%x", zh)}, nil
+}
+
+// packageHashV1 computes the `h1:` hash of the contents of a zip archive,
+// which is the same algorithm as the Hash1 of golang.org/x/mod/sumdb/dirhash
+func packageHashV1(r io.ReaderAt, size int64) (string, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", err
+	}
+
+	files := make([]*zip.File, len(z.File))
+	copy(files, z.File)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	summary := sha256.New()
+	for _, file := range files {
+		if strings.Contains(file.Name, "\n") {
+			return "", errors.New("file names with new lines are not supported")
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return "", err
+		}
+		sum, err := core.Sha256Checksum(rc)
+		_ = rc.Close()
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(summary, "%x  %s\n", sum, file.Name)
+	}
+	return fmt.Sprintf("h1:%s", base64.StdEncoding.EncodeToString(summary.Sum(nil))), nil
+}
+
+// SkippedVersion is a provider version that wasn't imported
+type SkippedVersion struct {
+	Provider *core.Provider
+	Reason   string
+}
+
+// ImportResult summarizes the providers that were imported
+type ImportResult struct {
+	Providers []*core.Provider
+	Skipped   []SkippedVersion
+	// Unsigned are the versions that were imported without a SHA256SUMS.sig
+	Unsigned []*core.Provider
+}
+
+// Import uploads the providers of a filesystem mirror in the packed layout to the storage.
+// Versions with a SHA256SUMS and SHA256SUMS.sig file, as written by Export, are verified with the checksums and the signature.
+// Versions without a SHA256SUMS, as written by `terraform providers mirror`, are verified with the hashes of their <version>.json,
+// and the SHA256SUMS is created from the archives, as it's required to serve the providers.
+// The unpacked layout isn't supported and returns an error.
+func Import(ctx context.Context, s Storage, fsys fs.FS) (*ImportResult, error) {
+	// The packed layout is <hostname>/<namespace>/<name>/<file>
+	dirs, err := fs.Glob(fsys, "*/*/*")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	for _, dir := range dirs {
+		if info, err := fs.Stat(fsys, dir); err != nil || !info.IsDir() {
+			continue
+		}
+		parts := strings.Split(dir, "/")
+		addr := providerAddress{hostname: parts[0], namespace: parts[1], name: parts[2]}
+		if err := importProvider(ctx, s, fsys, addr, result); err != nil {
+			return result, fmt.Errorf("failed to import %s: %w", addr, err)
+		}
+	}
+	return result, nil
+}
+
+func importProvider(ctx context.Context, s Storage, fsys fs.FS, addr providerAddress, result *ImportResult) error {
+	dir := addr.String()
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	var files []MirroredFile
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			files = append(files, MirroredFile{Hostname: addr.hostname, Namespace: addr.namespace, Name: addr.name, FileName: entry.Name()})
+		} else if _, err := version.NewVersion(entry.Name()); err == nil && entry.IsDir() {
+			// The unpacked layout is <hostname>/<namespace>/<name>/<version>/<os>_<arch>/ with the extracted archives,
+			// whose SHA256SUMS can't be recreated as the archives are gone
+			return fmt.Errorf("%s is in the unpacked layout, which isn't supported, only the packed layout of `terraform providers mirror` can be imported", path.Join(dir, entry.Name()))
+		}
+	}
+
+	signingKeys, err := importSigningKeys(ctx, s, fsys, addr)
+	if err != nil {
+		return err
+	}
+
+	for _, mv := range groupVersions(addr, files) {
+		if len(mv.archives) == 0 {
+			continue
+		}
+
+		p := addr.provider()
+		p.Version = mv.version.Original()
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, SkippedVersion{Provider: p, Reason: reason})
+		}
+
+		sumsBytes, err := fs.ReadFile(fsys, path.Join(dir, p.ShasumFileName()))
+		if errors.Is(err, fs.ErrNotExist) {
+			// The layout of `terraform providers mirror` only has the hashes of the archives in <version>.json
+			sumsBytes, err = verifyArchiveHashes(fsys, dir, mv)
+			if err != nil {
+				skip(err.Error())
+				continue
+			}
+			if err := s.UploadMirroredFile(ctx, p, p.ShasumFileName(), bytes.NewReader(sumsBytes)); err != nil {
+				return err
+			}
+			if err := importArchives(ctx, s, fsys, addr, p, mv.archives, result); err != nil {
+				return err
+			}
+			result.Unsigned = append(result.Unsigned, p)
+			continue
+		} else if err != nil {
+			return err
+		}
+		sigBytes, err := fs.ReadFile(fsys, path.Join(dir, p.ShasumSignatureFileName()))
+		if err != nil {
+			skip("SHA256SUMS.sig doesn't exist")
+			continue
+		}
+		if signingKeys == nil {
+			skip("signing keys don't exist")
+			continue
+		}
+		if err := signingKeys.IsValidSha256Sums(sumsBytes, sigBytes); err != nil {
+			skip(fmt.Sprintf("SHA256SUMS.sig is invalid: %v", err))
+			continue
+		}
+		sums, err := core.NewSha256Sums(p.ShasumFileName(), bytes.NewReader(sumsBytes))
+		if err != nil {
+			skip(fmt.Sprintf("SHA256SUMS is invalid: %v", err))
+			continue
+		}
+
+		if err := verifyArchives(fsys, dir, mv.archives, sums); err != nil {
+			skip(err.Error())
+			continue
+		}
+
+		if err := s.UploadMirroredFile(ctx, p, p.ShasumFileName(), bytes.NewReader(sumsBytes)); err != nil {
+			return err
+		}
+		if err := s.UploadMirroredFile(ctx, p, p.ShasumSignatureFileName(), bytes.NewReader(sigBytes)); err != nil {
+			return err
+		}
+		if err := importArchives(ctx, s, fsys, addr, p, mv.archives, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func importArchives(ctx context.Context, s Storage, fsys fs.FS, addr providerAddress, p *core.Provider, archives []MirroredFile, result *ImportResult) error {
+	for _, f := range archives {
+		if err := importFile(ctx, s, fsys, p, path.Join(addr.String(), f.FileName)); err != nil {
+			return err
+		}
+		archive, _ := core.NewProviderFromArchive(f.FileName)
+		archive.Hostname, archive.Namespace = addr.hostname, addr.namespace
+		result.Providers = append(result.Providers, &archive)
+	}
+	return nil
+}
+
+// verifyArchiveHashes verifies the archives of a version with the `h1:` and `zh:` hashes in its <version>.json
+// and returns the SHA256SUMS of the archives.
+// Every archive has to be listed with at least one of these hashes, and all of them have to match.
+func verifyArchiveHashes(fsys fs.FS, dir string, mv *mirroredVersion) ([]byte, error) {
+	name := fmt.Sprintf("%s.json", mv.version.Original())
+	b, err := fs.ReadFile(fsys, path.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("neither SHA256SUMS nor %s exist", name)
+	} else if err != nil {
+		return nil, err
+	}
+	var installation ListProviderInstallationResponse
+	if err := json.Unmarshal(b, &installation); err != nil {
+		return nil, fmt.Errorf("%s is invalid: %v", name, err)
+	}
+	listed := map[string][]string{}
+	for _, a := range installation.Archives {
+		listed[path.Base(a.Url)] = a.Hashes
+	}
+
+	var sums bytes.Buffer
+	archives := append([]MirroredFile(nil), mv.archives...)
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].FileName < archives[j].FileName
+	})
+	for _, f := range archives {
+		expected, ok := listed[f.FileName]
+		if !ok {
+			return nil, fmt.Errorf("%s isn't listed in %s", f.FileName, name)
+		}
+
+		hashes, err := fileArchiveHashes(fsys, path.Join(dir, f.FileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %v", f.FileName, err)
+		}
+		verified := false
+		for _, h := range expected {
+			if !strings.HasPrefix(h, "h1:") && !strings.HasPrefix(h, "zh:") {
+				// Other hash schemes can't be verified
+				continue
+			}
+			if !slices.Contains(hashes, h) {
+				return nil, fmt.Errorf("hash %s of %s in %s doesn't match", h, f.FileName, name)
+			}
+			verified = true
+		}
+		if !verified {
+			return nil, fmt.Errorf("%s has no h1: or zh: hash in %s", f.FileName, name)
+		}
+
+		zh := strings.TrimPrefix(hashes[1], "zh:")
+		fmt.Fprintf(&sums, "%s  %s\n", zh, f.FileName)
+	}
+	return sums.Bytes(), nil
+}
+
+// fileArchiveHashes returns the hashes of the archive in the filesystem, see archiveHashes
+func fileArchiveHashes(fsys fs.FS, name string) ([]string, error) {
+	r, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	tmp, err := os.CreateTemp("", "boring-registry-import-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := io.Copy(tmp, r); err != nil {
+		return nil, err
+	}
+	return archiveHashes(tmp)
+}
+
+// importSigningKeys merges the signing keys of the filesystem mirror into the mirrored signing keys of the namespace
+func importSigningKeys(ctx context.Context, s Storage, fsys fs.FS, addr providerAddress) (*core.SigningKeys, error) {
+	stored, err := s.MirroredSigningKeys(ctx, addr.hostname, addr.namespace)
+	if err != nil && !errors.Is(err, core.ErrObjectNotFound) {
+		return nil, err
+	}
+
+	b, err := fs.ReadFile(fsys, path.Join(addr.String(), signingKeysFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return stored, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var imported core.SigningKeys
+	if err := json.Unmarshal(b, &imported); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", signingKeysFileName, err)
+	}
+
+	if stored == nil {
+		stored = &imported
+	} else {
+		var updated bool
+		if stored.GPGPublicKeys, updated = mergeGPGPublicKeys(imported.GPGPublicKeys, stored.GPGPublicKeys); !updated {
+			return stored, nil
+		}
+	}
+	return stored, s.UploadMirroredSigningKeys(ctx, addr.hostname, addr.namespace, stored)
+}
+
+// verifyArchives verifies the checksums of the archives
+func verifyArchives(fsys fs.FS, dir string, archives []MirroredFile, sums *core.Sha256Sums) error {
+	for _, f := range archives {
+		expected, err := sums.Checksum(f.FileName)
+		if err != nil {
+			return err
+		}
+
+		r, err := fsys.Open(path.Join(dir, f.FileName))
+		if err != nil {
+			return err
+		}
+		actual, err := core.Sha256Checksum(r)
+		_ = r.Close()
+		if err != nil {
+			return err
+		}
+		if fmt.Sprintf("%x", actual) != expected {
+			return fmt.Errorf("checksum of %s doesn't match the SHA256SUMS", f.FileName)
+		}
+	}
+	return nil
+}
+
+func importFile(ctx context.Context, s Storage, fsys fs.FS, provider *core.Provider, name string) error {
+	r, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return s.UploadMirroredFile(ctx, provider, path.Base(name), r)
+}
+
+// ExtractTarGz extracts a gzip-compressed tarball into the directory
+func ExtractTarGz(r io.Reader, dir string) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// Prevent path traversal with entries like ../../etc/passwd
+		name := path.Clean(header.Name)
+		if !fs.ValidPath(name) {
+			return fmt.Errorf("invalid path %s in tarball", header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			f, err := os.Create(target)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				_ = f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package mirror
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+func newProviderArchive(t *testing.T, content string) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	z := zip.NewWriter(buf)
+	w, err := z.Create("terraform-provider-random")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportImport(t *testing.T) {
+	e, armored := newSigningEntity(t)
+	signingKeys := &core.SigningKeys{GPGPublicKeys: []core.GPGPublicKey{{KeyID: e.PrimaryKey.KeyIdString(), ASCIIArmor: armored}}}
+
+	linux := newProviderArchive(t, "linux_amd64")
+	darwin := newProviderArchive(t, "darwin_arm64")
+	sums := []byte(fmt.Sprintf("%x  terraform-provider-random_1.0.0_darwin_arm64.zip\n%x  terraform-provider-random_1.0.0_linux_amd64.zip\n",
+		sha256.Sum256(darwin), sha256.Sum256(linux)))
+	sig := new(bytes.Buffer)
+	if err := openpgp.DetachSign(sig, e, bytes.NewReader(sums), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	source := map[string][]byte{
+		"terraform-provider-random_1.0.0_linux_amd64.zip":  linux,
+		"terraform-provider-random_1.0.0_darwin_arm64.zip": darwin,
+		"terraform-provider-random_1.0.0_SHA256SUMS":       sums,
+		"terraform-provider-random_1.0.0_SHA256SUMS.sig":   sig.Bytes(),
+		AccessLogFileName: []byte(`{"versions":{}}`),
+	}
+	files := map[string]MirroredFile{}
+	content := map[string][]byte{}
+	for name, b := range source {
+		key := path.Join("registry.terraform.io/hashicorp/random", name)
+		files[key] = MirroredFile{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", FileName: name, Size: int64(len(b)), LastModified: time.Now()}
+		content[key] = b
+	}
+	s := newInmemMirroredFiles(files, content)
+	s.mirroredSigningKeys = func(ctx context.Context, hostname, namespace string) (*core.SigningKeys, error) {
+		return signingKeys, nil
+	}
+
+	t.Run("tarball", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		w := NewTarGzWriter(buf)
+		result, err := Export(context.Background(), s, w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Providers) != 2 {
+			t.Errorf("Export() exported %d providers, want 2", len(result.Providers))
+		}
+
+		dir := t.TempDir()
+		if err := ExtractTarGz(buf, dir); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"index.json", "1.0.0.json", signingKeysFileName, "terraform-provider-random_1.0.0_linux_amd64.zip"} {
+			if _, err := os.Stat(filepath.Join(dir, "registry.terraform.io/hashicorp/random", name)); err != nil {
+				t.Errorf("%s was not exported: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("import", func(t *testing.T) {
+		dir := t.TempDir()
+		w := NewDirectoryWriter(dir)
+		if _, err := Export(context.Background(), s, w, WithExportedProviders("registry.terraform.io/hashicorp/*")); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := os.ReadFile(filepath.Join(dir, "registry.terraform.io/hashicorp/random/1.0.0.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var installation ListProviderInstallationResponse
+		if err := json.Unmarshal(b, &installation); err != nil {
+			t.Fatal(err)
+		}
+		archive := installation.Archives["linux_amd64"]
+		if archive.Url != "terraform-provider-random_1.0.0_linux_amd64.zip" || len(archive.Hashes) != 2 ||
+			!strings.HasPrefix(archive.Hashes[0], "h1:") || archive.Hashes[1] != fmt.Sprintf("zh:%x", sha256.Sum256(linux)) {
+			t.Errorf("unexpected archive in 1.0.0.json: %+v", archive)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "registry.terraform.io/hashicorp/random", AccessLogFileName)); err == nil {
+			t.Error("the access log must not be exported")
+		}
+
+		// A version exported by `terraform providers mirror` doesn't have a SHA256SUMS
+		if err := os.WriteFile(filepath.Join(dir, "registry.terraform.io/hashicorp/random/terraform-provider-random_0.9.0_linux_amd64.zip"), linux, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		imported := map[string][]byte{}
+		target := newInmemMirroredFiles(map[string]MirroredFile{}, imported)
+		var uploadedKeys *core.SigningKeys
+		target.mirroredSigningKeys = func(ctx context.Context, hostname, namespace string) (*core.SigningKeys, error) {
+			return nil, core.ErrObjectNotFound
+		}
+		target.uploadMirroredSigningKeys = func(ctx context.Context, hostname, namespace string, signingKeys *core.SigningKeys) error {
+			uploadedKeys = signingKeys
+			return nil
+		}
+
+		result, err := Import(context.Background(), target, os.DirFS(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Providers) != 2 {
+			t.Errorf("Import() imported %d providers, want 2", len(result.Providers))
+		}
+		if len(result.Skipped) != 1 || result.Skipped[0].Provider.Version != "0.9.0" {
+			t.Errorf("Import() skipped %+v, want 0.9.0", result.Skipped)
+		}
+		if uploadedKeys == nil || len(uploadedKeys.GPGPublicKeys) != 1 {
+			t.Errorf("Import() uploaded signing keys %+v", uploadedKeys)
+		}
+		for name, b := range source {
+			if name == AccessLogFileName {
+				continue
+			}
+			if !bytes.Equal(imported[path.Join("registry.terraform.io/hashicorp/random", name)], b) {
+				t.Errorf("%s was not imported unmodified", name)
+			}
+		}
+	})
+}
+
+func TestImport_providersMirrorLayout(t *testing.T) {
+	linux := newProviderArchive(t, "linux_amd64")
+	darwin := newProviderArchive(t, "darwin_arm64")
+	h1, err := packageHashV1(bytes.NewReader(linux), int64(len(linux)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// `terraform providers mirror` writes the archives with the h1: hashes in <version>.json, but no SHA256SUMS
+	dir := t.TempDir()
+	providerDir := filepath.Join(dir, "registry.terraform.io/hashicorp/random")
+	files := map[string]string{
+		"terraform-provider-random_1.0.0_linux_amd64.zip": string(linux),
+		"1.0.0.json": fmt.Sprintf(`{"archives":{"linux_amd64":{"url":"terraform-provider-random_1.0.0_linux_amd64.zip","hashes":[%q]}}}`, h1),
+		"terraform-provider-random_0.9.0_darwin_arm64.zip": string(darwin),
+		// The archive was modified after it was mirrored
+		"0.9.0.json": fmt.Sprintf(`{"archives":{"darwin_arm64":{"url":"terraform-provider-random_0.9.0_darwin_arm64.zip","hashes":["zh:%x"]}}}`, sha256.Sum256(linux)),
+	}
+	if err := os.MkdirAll(providerDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(providerDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	imported := map[string][]byte{}
+	target := newInmemMirroredFiles(map[string]MirroredFile{}, imported)
+	target.mirroredSigningKeys = func(ctx context.Context, hostname, namespace string) (*core.SigningKeys, error) {
+		return nil, core.ErrObjectNotFound
+	}
+
+	result, err := Import(context.Background(), target, os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Providers) != 1 || len(result.Unsigned) != 1 || result.Unsigned[0].Version != "1.0.0" {
+		t.Errorf("Import() imported %+v, unsigned %+v, want 1.0.0", result.Providers, result.Unsigned)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Provider.Version != "0.9.0" {
+		t.Errorf("Import() skipped %+v, want 0.9.0", result.Skipped)
+	}
+	sums := fmt.Sprintf("%x  terraform-provider-random_1.0.0_linux_amd64.zip\n", sha256.Sum256(linux))
+	if got := string(imported["registry.terraform.io/hashicorp/random/terraform-provider-random_1.0.0_SHA256SUMS"]); got != sums {
+		t.Errorf("Import() created SHA256SUMS %q, want %q", got, sums)
+	}
+
+	// The unpacked layout is rejected
+	if err := os.MkdirAll(filepath.Join(providerDir, "1.1.0", "linux_amd64"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(context.Background(), target, os.DirFS(dir)); err == nil || !strings.Contains(err.Error(), "unpacked layout") {
+		t.Errorf("Import() error = %v, want an error about the unpacked layout", err)
+	}
+}
+
+func Test_packageHashV1(t *testing.T) {
+	// The hash of a zip archive with a single file `terraform-provider-random` containing `linux_amd64`
+	archive := newProviderArchive(t, "linux_amd64")
+	fileSum := sha256.Sum256([]byte("linux_amd64"))
+	summary := sha256.Sum256([]byte(fmt.Sprintf("%x  terraform-provider-random\n", fileSum)))
+
+	got, err := packageHashV1(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "h1:" + base64.StdEncoding.EncodeToString(summary[:]); got != want {
+		t.Errorf("packageHashV1() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,356 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Deletion is a file that is removed by the GarbageCollector
+type Deletion struct {
+	Provider *core.Provider
+	FileName string
+	Size     int64
+	Reason   string
+}
+
+// GarbageCollectionResult summarizes a run of the GarbageCollector
+type GarbageCollectionResult struct {
+	Deletions []Deletion
+
+	// DryRun is true in case the files were not actually deleted
+	DryRun bool
+}
+
+// FreedBytes returns the accumulated size of all deleted files
+func (r *GarbageCollectionResult) FreedBytes() int64 {
+	var size int64
+	for _, d := range r.Deletions {
+		size += d.Size
+	}
+	return size
+}
+
+// pin protects providers from the garbage collection
+type pin struct {
+	pattern     providerPattern
+	constraints version.Constraints
+}
+
+// parsePin parses a <hostname>/<namespace>/<name>[@<version constraints>] pin
+func parsePin(s string) (pin, error) {
+	address, constraints, found := strings.Cut(s, "@")
+
+	var p pin
+	var err error
+	if p.pattern, err = parseProviderPattern(address); err != nil {
+		return p, err
+	}
+	if found {
+		if p.constraints, err = version.NewConstraint(constraints); err != nil {
+			return p, fmt.Errorf("invalid version constraints in pin %q: %w", s, err)
+		}
+	}
+	return p, nil
+}
+
+func (p pin) matches(provider *core.Provider, v *version.Version) bool {
+	if !p.pattern.matches(provider) {
+		return false
+	}
+	return p.constraints == nil || p.constraints.Check(v)
+}
+
+// mirroredVersion groups the files of a single version of a mirrored provider
+type mirroredVersion struct {
+	version  *version.Version
+	archives []MirroredFile
+	// other contains all files of the version that aren't archives, like the SHA256SUMS and its signature
+	other []MirroredFile
+}
+
+// GarbageCollector deletes mirrored providers according to the retention rules
+type GarbageCollector struct {
+	storage Storage
+	logger  *slog.Logger
+	now     func() time.Time
+
+	keepLatest   int
+	maxUnusedAge time.Duration
+	pins         []pin
+}
+
+// Run deletes all mirrored providers that are not retained.
+// No files are deleted in case dryRun is true, but the result still contains all files that would have been deleted.
+func (g *GarbageCollector) Run(ctx context.Context, dryRun bool) (*GarbageCollectionResult, error) {
+	files, err := g.storage.ListMirroredFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mirrored files: %w", err)
+	}
+
+	providers := map[providerAddress][]MirroredFile{}
+	for _, f := range files {
+		addr := providerAddress{hostname: f.Hostname, namespace: f.Namespace, name: f.Name}
+		providers[addr] = append(providers[addr], f)
+	}
+
+	// Process the providers in a stable order for a reproducible output
+	addresses := make([]providerAddress, 0, len(providers))
+	for addr := range providers {
+		addresses = append(addresses, addr)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].String() < addresses[j].String()
+	})
+
+	result := &GarbageCollectionResult{DryRun: dryRun}
+	var errs []error
+	for _, addr := range addresses {
+		deletions, err := g.collect(ctx, addr, providers[addr], dryRun)
+		result.Deletions = append(result.Deletions, deletions...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// collect determines and deletes the files of a single provider that are not retained
+func (g *GarbageCollector) collect(ctx context.Context, addr providerAddress, files []MirroredFile, dryRun bool) ([]Deletion, error) {
+	provider := addr.provider()
+	log, err := readAccessLog(ctx, g.storage, provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access log of %s: %w", addr, err)
+	}
+
+	versions := groupVersions(addr, files)
+	var deletions []Deletion
+	for i, v := range versions {
+		if g.pinned(provider, v.version) {
+			continue
+		}
+
+		var deleted []MirroredFile
+		if g.keepLatest > 0 && i >= g.keepLatest {
+			for _, f := range v.archives {
+				deletions = append(deletions, newDeletion(f, fmt.Sprintf("not within the latest %d versions", g.keepLatest)))
+			}
+			deleted = v.archives
+		} else if g.maxUnusedAge > 0 {
+			for _, f := range v.archives {
+				p, _ := core.NewProviderFromArchive(f.FileName)
+				lastAccess := log.lastAccess(p.Version, p.OS, p.Arch)
+				if f.LastModified.After(lastAccess) {
+					lastAccess = f.LastModified
+				}
+
+				if g.now().Sub(lastAccess) > g.maxUnusedAge {
+					deletions = append(deletions, newDeletion(f, fmt.Sprintf("not requested since %s", lastAccess.Format(time.RFC3339))))
+					deleted = append(deleted, f)
+				}
+			}
+		}
+
+		// The SHA256SUMS and its signature are only removed together with the last archive of a version
+		if len(deleted) > 0 && len(deleted) == len(v.archives) {
+			for _, f := range v.other {
+				deletions = append(deletions, newDeletion(f, "all archives of the version are deleted"))
+			}
+		}
+	}
+
+	if dryRun || len(deletions) == 0 {
+		return deletions, nil
+	}
+
+	var errs []error
+	var completed []Deletion
+	for _, d := range deletions {
+		if err := g.storage.DeleteMirroredFile(ctx, provider, d.FileName); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		g.logger.Info("deleted mirrored file", slog.String("provider", addr.String()), slog.String("file", d.FileName), slog.String("reason", d.Reason))
+		completed = append(completed, d)
+	}
+
+	if len(completed) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// Only the deleted archives are removed, so that accesses recorded concurrently by the AccessTrackers are kept
+	err = updateAccessLog(ctx, g.storage, provider, func(l *accessLog) {
+		for _, d := range completed {
+			if d.Provider.OS != "" {
+				l.remove(d.Provider.Version, d.Provider.OS, d.Provider.Arch)
+			}
+		}
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to update access log of %s: %w", addr, err))
+	}
+	return completed, errors.Join(errs...)
+}
+
+func (g *GarbageCollector) pinned(provider *core.Provider, v *version.Version) bool {
+	for _, p := range g.pins {
+		if p.matches(provider, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupVersions groups the files by version, sorted from the most recent to the oldest version.
+// Files with an unparsable version are omitted, so that they are never deleted.
+func groupVersions(addr providerAddress, files []MirroredFile) []*mirroredVersion {
+	versions := map[string]*mirroredVersion{}
+	group := func(raw string) *mirroredVersion {
+		v, err := version.NewVersion(raw)
+		if err != nil {
+			return nil
+		}
+		if _, ok := versions[v.Original()]; !ok {
+			versions[v.Original()] = &mirroredVersion{version: v}
+		}
+		return versions[v.Original()]
+	}
+
+	for _, f := range files {
+		if p, err := core.NewProviderFromArchive(f.FileName); err == nil && strings.HasSuffix(f.FileName, core.ProviderExtension) {
+			if v := group(p.Version); v != nil {
+				v.archives = append(v.archives, f)
+			}
+			continue
+		}
+
+		// Assign the SHA256SUMS and its signature to the version
+		name, ok := strings.CutPrefix(f.FileName, fmt.Sprintf("%s%s_", core.ProviderPrefix, addr.name))
+		if !ok {
+			continue
+		}
+		if raw, ok := strings.CutSuffix(strings.TrimSuffix(name, ".sig"), "_SHA256SUMS"); ok {
+			if v := group(raw); v != nil {
+				v.other = append(v.other, f)
+			}
+		}
+	}
+
+	sorted := make([]*mirroredVersion, 0, len(versions))
+	for _, v := range versions {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].version.GreaterThan(sorted[j].version)
+	})
+	return sorted
+}
+
+func newDeletion(f MirroredFile, reason string) Deletion {
+	provider := &core.Provider{
+		Hostname:  f.Hostname,
+		Namespace: f.Namespace,
+		Name:      f.Name,
+	}
+	if p, err := core.NewProviderFromArchive(f.FileName); err == nil && strings.HasSuffix(f.FileName, core.ProviderExtension) {
+		provider.Version = p.Version
+		provider.OS = p.OS
+		provider.Arch = p.Arch
+	}
+
+	return Deletion{
+		Provider: provider,
+		FileName: f.FileName,
+		Size:     f.Size,
+		Reason:   reason,
+	}
+}
+
+// Schedule runs the garbage collection in the given interval until the context is canceled
+func (g *GarbageCollector) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := g.Run(ctx, false)
+			if err != nil {
+				g.logger.Error("garbage collection failed", slog.String("err", err.Error()))
+			}
+			if result != nil {
+				g.logger.Info("garbage collection finished", slog.Int("deleted", len(result.Deletions)), slog.Int64("freed_bytes", result.FreedBytes()))
+			}
+		}
+	}
+}
+
+// GarbageCollectorOption provides additional options for the GarbageCollector
+type GarbageCollectorOption func(*GarbageCollector) error
+
+// WithKeepLatest configures the number of most recent versions that are retained per provider.
+// All versions are retained if n is zero.
+func WithKeepLatest(n int) GarbageCollectorOption {
+	return func(g *GarbageCollector) error {
+		if n < 0 {
+			return fmt.Errorf("the number of retained versions must not be negative")
+		}
+		g.keepLatest = n
+		return nil
+	}
+}
+
+// WithMaxUnusedAge configures the duration after which platforms that haven't been requested are deleted.
+// Unused platforms are retained if d is zero.
+func WithMaxUnusedAge(d time.Duration) GarbageCollectorOption {
+	return func(g *GarbageCollector) error {
+		if d < 0 {
+			return fmt.Errorf("the maximum unused age must not be negative")
+		}
+		g.maxUnusedAge = d
+		return nil
+	}
+}
+
+// WithPins configures providers in the form of <hostname>/<namespace>/<name>[@<version constraints>] that are never deleted.
+// Example: registry.terraform.io/hashicorp/aws@~> 5.0
+func WithPins(pins ...string) GarbageCollectorOption {
+	return func(g *GarbageCollector) error {
+		for _, s := range pins {
+			p, err := parsePin(s)
+			if err != nil {
+				return err
+			}
+			g.pins = append(g.pins, p)
+		}
+		return nil
+	}
+}
+
+// NewGarbageCollector returns a GarbageCollector, which retains everything unless configured otherwise with options
+func NewGarbageCollector(s Storage, options ...GarbageCollectorOption) (*GarbageCollector, error) {
+	g := &GarbageCollector{
+		storage: s,
+		logger:  slog.Default().With(slog.String("component", "garbage-collector")),
+		now:     time.Now,
+	}
+
+	for _, option := range options {
+		if err := option(g); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
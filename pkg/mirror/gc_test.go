@@ -0,0 +1,289 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+)
+
+// newInmemMirroredFiles returns a mockedStorage that keeps the mirrored files in memory
+func newInmemMirroredFiles(files map[string]MirroredFile, content map[string][]byte) *mockedStorage {
+	var mu sync.Mutex
+	key := func(provider *core.Provider, fileName string) string {
+		return path.Join(provider.Hostname, provider.Namespace, provider.Name, fileName)
+	}
+
+	return &mockedStorage{
+		listMirroredFiles: func(ctx context.Context) ([]MirroredFile, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var result []MirroredFile
+			for _, f := range files {
+				result = append(result, f)
+			}
+			return result, nil
+		},
+		downloadMirroredFile: func(ctx context.Context, provider *core.Provider, fileName string) (io.ReadCloser, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			b, ok := content[key(provider, fileName)]
+			if !ok {
+				return nil, core.ErrObjectNotFound
+			}
+			return io.NopCloser(bytes.NewReader(b)), nil
+		},
+		uploadMirroredFile: func(ctx context.Context, provider *core.Provider, fileName string, reader io.Reader) error {
+			b, err := io.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			content[key(provider, fileName)] = b
+			return nil
+		},
+		updateMirroredFile: func(ctx context.Context, provider *core.Provider, fileName string, update func([]byte) ([]byte, error)) error {
+			mu.Lock()
+			defer mu.Unlock()
+			b, err := update(content[key(provider, fileName)])
+			if err != nil {
+				return err
+			}
+			content[key(provider, fileName)] = b
+			return nil
+		},
+		deleteMirroredFile: func(ctx context.Context, provider *core.Provider, fileName string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(files, key(provider, fileName))
+			delete(content, key(provider, fileName))
+			return nil
+		},
+	}
+}
+
+func TestGarbageCollector_Run(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	uploaded := now.Add(-180 * 24 * time.Hour)
+
+	newFiles := func() map[string]MirroredFile {
+		files := map[string]MirroredFile{}
+		for _, name := range []string{
+			"terraform-provider-random_3.6.2_linux_amd64.zip",
+			"terraform-provider-random_3.6.2_darwin_arm64.zip",
+			"terraform-provider-random_3.6.2_SHA256SUMS",
+			"terraform-provider-random_3.6.2_SHA256SUMS.sig",
+			"terraform-provider-random_3.5.1_linux_amd64.zip",
+			"terraform-provider-random_3.5.1_SHA256SUMS",
+			"terraform-provider-random_3.5.1_SHA256SUMS.sig",
+			"terraform-provider-random_3.10.0_linux_amd64.zip",
+			"terraform-provider-random_3.10.0_SHA256SUMS",
+			"terraform-provider-random_3.10.0_SHA256SUMS.sig",
+		} {
+			files[path.Join("registry.terraform.io/hashicorp/random", name)] = MirroredFile{
+				Hostname:     "registry.terraform.io",
+				Namespace:    "hashicorp",
+				Name:         "random",
+				FileName:     name,
+				Size:         1,
+				LastModified: uploaded,
+			}
+		}
+		return files
+	}
+
+	// linux_amd64 of 3.6.2 and 3.10.0 were requested recently
+	accessLog := []byte(`{"versions":{"3.6.2":{"linux_amd64":"2024-05-20T00:00:00Z"},"3.10.0":{"linux_amd64":"2024-05-30T00:00:00Z"}}}`)
+
+	tests := []struct {
+		name    string
+		options []GarbageCollectorOption
+		dryRun  bool
+		want    []string
+	}{
+		{
+			name: "retain everything by default",
+		},
+		{
+			name:    "keep latest versions",
+			options: []GarbageCollectorOption{WithKeepLatest(2)},
+			want: []string{
+				"terraform-provider-random_3.5.1_SHA256SUMS",
+				"terraform-provider-random_3.5.1_SHA256SUMS.sig",
+				"terraform-provider-random_3.5.1_linux_amd64.zip",
+			},
+		},
+		{
+			name:    "delete unused platforms",
+			options: []GarbageCollectorOption{WithMaxUnusedAge(90 * 24 * time.Hour)},
+			want: []string{
+				"terraform-provider-random_3.5.1_SHA256SUMS",
+				"terraform-provider-random_3.5.1_SHA256SUMS.sig",
+				"terraform-provider-random_3.5.1_linux_amd64.zip",
+				"terraform-provider-random_3.6.2_darwin_arm64.zip",
+			},
+		},
+		{
+			name: "pinned versions are retained",
+			options: []GarbageCollectorOption{
+				WithKeepLatest(1),
+				WithMaxUnusedAge(90 * 24 * time.Hour),
+				WithPins("registry.terraform.io/hashicorp/random@< 3.6"),
+			},
+			want: []string{
+				"terraform-provider-random_3.6.2_SHA256SUMS",
+				"terraform-provider-random_3.6.2_SHA256SUMS.sig",
+				"terraform-provider-random_3.6.2_darwin_arm64.zip",
+				"terraform-provider-random_3.6.2_linux_amd64.zip",
+			},
+		},
+		{
+			name:    "dry run",
+			options: []GarbageCollectorOption{WithKeepLatest(2)},
+			dryRun:  true,
+			want: []string{
+				"terraform-provider-random_3.5.1_SHA256SUMS",
+				"terraform-provider-random_3.5.1_SHA256SUMS.sig",
+				"terraform-provider-random_3.5.1_linux_amd64.zip",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newFiles()
+			content := map[string][]byte{
+				path.Join("registry.terraform.io/hashicorp/random", AccessLogFileName): accessLog,
+			}
+			s := newInmemMirroredFiles(files, content)
+
+			g, err := NewGarbageCollector(s, tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g.now = func() time.Time { return now }
+
+			result, err := g.Run(context.Background(), tt.dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, d := range result.Deletions {
+				got = append(got, d.FileName)
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Run() deleted %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Run() deleted %v, want %v", got, tt.want)
+				}
+			}
+
+			wantRemaining := len(newFiles())
+			if !tt.dryRun {
+				wantRemaining -= len(tt.want)
+			}
+			if len(files) != wantRemaining {
+				t.Errorf("%d files remain in the storage, want %d", len(files), wantRemaining)
+			}
+		})
+	}
+}
+
+func TestGarbageCollector_invalidOptions(t *testing.T) {
+	for _, option := range []GarbageCollectorOption{
+		WithKeepLatest(-1),
+		WithMaxUnusedAge(-time.Hour),
+		WithPins("registry.terraform.io/hashicorp/random@foo"),
+		WithPins("registry.terraform.io/hashicorp/random/extra"),
+	} {
+		if _, err := NewGarbageCollector(&mockedStorage{}, option); err == nil {
+			t.Error("NewGarbageCollector() expected error")
+		}
+	}
+}
+
+func TestAccessTracker_Flush(t *testing.T) {
+	content := map[string][]byte{
+		path.Join("registry.terraform.io/hashicorp/random", AccessLogFileName): []byte(`{"versions":{"3.6.2":{"linux_amd64":"2024-05-20T00:00:00Z","darwin_arm64":"2024-05-01T00:00:00Z"}}}`),
+	}
+	s := newInmemMirroredFiles(map[string]MirroredFile{}, content)
+
+	tracker := NewAccessTracker(s)
+	tracker.now = func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	provider := &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.6.2", OS: "linux", Arch: "amd64"}
+	tracker.Record(provider)
+	tracker.Record(&core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "null", Version: "3.2.2", OS: "linux", Arch: "amd64"})
+	if err := tracker.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := readAccessLog(context.Background(), s, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := log.lastAccess("3.6.2", "linux", "amd64"); !got.Equal(tracker.now()) {
+		t.Errorf("last access of linux_amd64 = %s, want %s", got, tracker.now())
+	}
+	if got := log.lastAccess("3.6.2", "darwin", "arm64"); !got.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("last access of darwin_arm64 = %s, want it to be retained", got)
+	}
+
+	if _, ok := content[path.Join("registry.terraform.io/hashicorp/null", AccessLogFileName)]; !ok {
+		t.Error("access log of registry.terraform.io/hashicorp/null was not written")
+	}
+}
+
+type fakeMirrorService struct {
+	Service
+	installation *ListProviderInstallationResponse
+}
+
+func (f *fakeMirrorService) ListProviderInstallation(ctx context.Context, provider *core.Provider) (*ListProviderInstallationResponse, error) {
+	return f.installation, nil
+}
+
+func (f *fakeMirrorService) RetrieveProviderArchive(ctx context.Context, provider *core.Provider) (*retrieveProviderArchiveResponse, error) {
+	return &retrieveProviderArchiveResponse{location: "https://bucket.example.com/archive.zip?signature=1"}, nil
+}
+
+func TestAccessTrackingMiddleware(t *testing.T) {
+	tracker := NewAccessTracker(&mockedStorage{})
+	svc := AccessTrackingMiddleware(tracker)(&fakeMirrorService{
+		installation: &ListProviderInstallationResponse{
+			Archives: map[string]Archive{
+				"linux_amd64": {Url: "https://bucket.example.com/terraform-provider-random_3.6.2_linux_amd64.zip?signature=1", Hashes: []string{"zh:abc"}},
+			},
+			mirrorSource: mirrorSource{isMirror: true},
+		},
+	})
+
+	// The archives are downloaded through the mirror, so that the downloads are recorded
+	provider := &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.6.2"}
+	installation, err := svc.ListProviderInstallation(context.Background(), provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := installation.Archives["linux_amd64"]; got.Url != "terraform-provider-random_3.6.2_linux_amd64.zip" || len(got.Hashes) != 1 {
+		t.Errorf("ListProviderInstallation() archive = %v, want it relative to the mirror", got)
+	}
+
+	archive := &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.6.2", OS: "linux", Arch: "amd64"}
+	if _, err := svc.RetrieveProviderArchive(context.Background(), archive); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tracker.pending[providerAddress{hostname: "registry.terraform.io", namespace: "hashicorp", name: "random"}]; !ok {
+		t.Error("RetrieveProviderArchive() didn't record the access")
+	}
+}
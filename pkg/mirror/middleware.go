@@ -6,6 +6,9 @@ import (
 	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type mirrorSource struct {
@@ -35,11 +38,11 @@ func (mw loggingMiddleware) ListProviderVersions(ctx context.Context, provider *
 		)
 
 		if err != nil {
-			logger.Error("failed to list provider versions", slog.String("err", err.Error()))
+			logger.ErrorContext(ctx, "failed to list provider versions", slog.String("err", err.Error()))
 			return
 		}
 
-		logger.Info("list provider version", slog.String("took", time.Since(begin).String()), slog.Bool("mirror", providerVersions.fromMirror()))
+		logger.InfoContext(ctx, "list provider version", slog.String("took", time.Since(begin).String()), slog.Bool("mirror", providerVersions.fromMirror()))
 	}(time.Now())
 
 	return mw.next.ListProviderVersions(ctx, provider)
@@ -57,11 +60,11 @@ func (mw loggingMiddleware) ListProviderInstallation(ctx context.Context, provid
 		)
 
 		if err != nil {
-			logger.Error("failed to list provider installation", slog.String("err", err.Error()))
+			logger.ErrorContext(ctx, "failed to list provider installation", slog.String("err", err.Error()))
 			return
 		}
 
-		logger.Info("list provider installation", slog.String("took", time.Since(begin).String()), slog.Bool("mirror", archives.fromMirror()))
+		logger.InfoContext(ctx, "list provider installation", slog.String("took", time.Since(begin).String()), slog.Bool("mirror", archives.fromMirror()))
 	}(time.Now())
 
 	return mw.next.ListProviderInstallation(ctx, provider)
@@ -82,11 +85,11 @@ func (mw loggingMiddleware) RetrieveProviderArchive(ctx context.Context, provide
 		)
 
 		if err != nil {
-			logger.Error("failed to retrieve provider archive", slog.String("err", err.Error()))
+			logger.ErrorContext(ctx, "failed to retrieve provider archive", slog.String("err", err.Error()))
 			return
 		}
 
-		logger.Info("retrieve provider archive", slog.String("took", time.Since(begin).String()), slog.Bool("mirror", response.fromMirror()))
+		logger.InfoContext(ctx, "retrieve provider archive", slog.String("took", time.Since(begin).String()), slog.Bool("mirror", response.fromMirror()))
 	}(time.Now())
 
 	return mw.next.RetrieveProviderArchive(ctx, provider)
@@ -100,3 +103,68 @@ func LoggingMiddleware() Middleware {
 		}
 	}
 }
+
+type tracingMiddleware struct {
+	next Service
+}
+
+// TracingMiddleware is a Service middleware, which starts a span for each call.
+// Whether the response was served from the mirror is recorded as attribute.
+func TracingMiddleware() Middleware {
+	return func(next Service) Service {
+		return &tracingMiddleware{
+			next: next,
+		}
+	}
+}
+
+func providerAttributes(provider *core.Provider) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		attribute.String(o11y.HostnameLabel, provider.Hostname),
+		attribute.String(o11y.NamespaceLabel, provider.Namespace),
+		attribute.String(o11y.NameLabel, provider.Name),
+	}
+	if provider.Version != "" {
+		attributes = append(attributes, attribute.String(o11y.VersionLabel, provider.Version))
+	}
+	if provider.OS != "" {
+		attributes = append(attributes, attribute.String(o11y.OsLabel, provider.OS), attribute.String(o11y.ArchLabel, provider.Arch))
+	}
+	return attributes
+}
+
+func (mw tracingMiddleware) ListProviderVersions(ctx context.Context, provider *core.Provider) (providerVersions *ListProviderVersionsResponse, err error) {
+	ctx, span := o11y.StartSpan(ctx, "mirror.ListProviderVersions", providerAttributes(provider)...)
+	defer func() {
+		if err == nil {
+			span.SetAttributes(attribute.Bool("mirror", providerVersions.fromMirror()))
+		}
+		o11y.EndSpan(span, err)
+	}()
+
+	return mw.next.ListProviderVersions(ctx, provider)
+}
+
+func (mw tracingMiddleware) ListProviderInstallation(ctx context.Context, provider *core.Provider) (archives *ListProviderInstallationResponse, err error) {
+	ctx, span := o11y.StartSpan(ctx, "mirror.ListProviderInstallation", providerAttributes(provider)...)
+	defer func() {
+		if err == nil {
+			span.SetAttributes(attribute.Bool("mirror", archives.fromMirror()))
+		}
+		o11y.EndSpan(span, err)
+	}()
+
+	return mw.next.ListProviderInstallation(ctx, provider)
+}
+
+func (mw tracingMiddleware) RetrieveProviderArchive(ctx context.Context, provider *core.Provider) (response *retrieveProviderArchiveResponse, err error) {
+	ctx, span := o11y.StartSpan(ctx, "mirror.RetrieveProviderArchive", providerAttributes(provider)...)
+	defer func() {
+		if err == nil {
+			span.SetAttributes(attribute.Bool("mirror", response.fromMirror()))
+		}
+		o11y.EndSpan(span, err)
+	}()
+
+	return mw.next.RetrieveProviderArchive(ctx, provider)
+}
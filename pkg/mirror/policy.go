@@ -0,0 +1,317 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Policy restricts which providers can be retrieved through the network mirror.
+// A provider is denied if it matches any deny pattern, or if allow patterns exist and none of them matches.
+// The rules of a Policy can be replaced at runtime with Update.
+type Policy struct {
+	mu    sync.RWMutex
+	rules policyRules
+}
+
+// policyRules are the rules of a Policy, which are never modified once the Policy is created
+type policyRules struct {
+	allow            []providerPattern
+	deny             []providerPattern
+	constraints      version.Constraints
+	allowPrereleases bool
+	platforms        map[string]struct{}
+}
+
+// providerPattern matches the <hostname>/<namespace>/<name> address of a provider.
+// Each segment can contain shell-style wildcards, omitted segments match everything.
+type providerPattern [3]string
+
+func parseProviderPattern(s string) (providerPattern, error) {
+	p := providerPattern{"*", "*", "*"}
+	segments := strings.Split(strings.ToLower(strings.TrimSpace(s)), "/")
+	if len(segments) > len(p) || segments[0] == "" {
+		return p, fmt.Errorf("invalid provider pattern %q, expected <hostname>[/<namespace>[/<name>]]", s)
+	}
+
+	for i, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return p, fmt.Errorf("invalid provider pattern %q: %w", s, err)
+		}
+		p[i] = segment
+	}
+	return p, nil
+}
+
+func (p providerPattern) matches(provider *core.Provider) bool {
+	values := [3]string{provider.Hostname, provider.Namespace, provider.Name}
+	for i, segment := range p {
+		if ok, _ := path.Match(segment, strings.ToLower(values[i])); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (p providerPattern) String() string {
+	return strings.Join(p[:], "/")
+}
+
+// checkProvider verifies that the hostname, namespace and name of the provider are allowed
+func (p policyRules) checkProvider(provider *core.Provider) error {
+	for _, pattern := range p.deny {
+		if pattern.matches(provider) {
+			return policyViolation(provider, fmt.Sprintf("provider is denied by pattern %s", pattern))
+		}
+	}
+
+	if len(p.allow) == 0 {
+		return nil
+	}
+	for _, pattern := range p.allow {
+		if pattern.matches(provider) {
+			return nil
+		}
+	}
+	return policyViolation(provider, "provider doesn't match any allowed pattern")
+}
+
+// checkVersion verifies that the version of the provider is allowed
+func (p policyRules) checkVersion(provider *core.Provider) error {
+	if provider.Version == "" {
+		return nil
+	}
+
+	v, err := version.NewVersion(provider.Version)
+	if err != nil {
+		return policyViolation(provider, fmt.Sprintf("version is invalid: %v", err))
+	}
+
+	if !p.allowPrereleases && v.Prerelease() != "" {
+		return policyViolation(provider, "pre-release versions are not allowed")
+	}
+
+	if p.constraints != nil && !p.constraints.Check(v) {
+		return policyViolation(provider, fmt.Sprintf("version doesn't meet the constraints %s", p.constraints.String()))
+	}
+	return nil
+}
+
+// checkPlatform verifies that the OS and architecture of the provider are allowed
+func (p policyRules) checkPlatform(provider *core.Provider) error {
+	if len(p.platforms) == 0 || provider.OS == "" || provider.Arch == "" {
+		return nil
+	}
+
+	if _, ok := p.platforms[platformKey(provider.OS, provider.Arch)]; !ok {
+		return policyViolation(provider, "platform is not allowed")
+	}
+	return nil
+}
+
+// Check verifies the provider against all rules of the policy
+func (p *Policy) Check(provider *core.Provider) error {
+	if p == nil {
+		return nil
+	}
+
+	rules := p.current()
+	if err := rules.checkProvider(provider); err != nil {
+		return err
+	}
+	if err := rules.checkVersion(provider); err != nil {
+		return err
+	}
+	return rules.checkPlatform(provider)
+}
+
+// Update replaces the rules of the Policy with the rules of next, e.g. when the configuration is reloaded
+func (p *Policy) Update(next *Policy) {
+	rules := next.current()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rules = rules
+}
+
+func (p *Policy) current() policyRules {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.rules
+}
+
+func platformKey(os, arch string) string {
+	return fmt.Sprintf("%s_%s", os, arch)
+}
+
+func policyViolation(provider *core.Provider, reason string) error {
+	return &core.ProviderError{
+		Reason:     fmt.Sprintf("denied by mirror policy: %s", reason),
+		Provider:   provider.Clone(),
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+// PolicyOption provides additional options for the Policy
+type PolicyOption func(*Policy) error
+
+// WithAllowedProviders configures patterns in the form of <hostname>/<namespace>/<name> of which one has to match.
+// Example: registry.terraform.io/hashicorp/*
+func WithAllowedProviders(patterns ...string) PolicyOption {
+	return func(p *Policy) error {
+		for _, s := range patterns {
+			pattern, err := parseProviderPattern(s)
+			if err != nil {
+				return err
+			}
+			p.rules.allow = append(p.rules.allow, pattern)
+		}
+		return nil
+	}
+}
+
+// WithDeniedProviders configures patterns in the form of <hostname>/<namespace>/<name> of which none may match
+func WithDeniedProviders(patterns ...string) PolicyOption {
+	return func(p *Policy) error {
+		for _, s := range patterns {
+			pattern, err := parseProviderPattern(s)
+			if err != nil {
+				return err
+			}
+			p.rules.deny = append(p.rules.deny, pattern)
+		}
+		return nil
+	}
+}
+
+// WithVersionConstraints configures the version constraints that provider versions have to meet
+func WithVersionConstraints(constraints string) PolicyOption {
+	return func(p *Policy) error {
+		if constraints == "" {
+			return nil
+		}
+		c, err := version.NewConstraint(constraints)
+		if err != nil {
+			return fmt.Errorf("invalid version constraints: %w", err)
+		}
+		p.rules.constraints = c
+		return nil
+	}
+}
+
+// WithPrereleases configures whether pre-release versions are allowed
+func WithPrereleases(allow bool) PolicyOption {
+	return func(p *Policy) error {
+		p.rules.allowPrereleases = allow
+		return nil
+	}
+}
+
+// WithPlatforms configures the allowed platforms in the form of <os>_<arch>
+func WithPlatforms(platforms ...string) PolicyOption {
+	return func(p *Policy) error {
+		for _, platform := range platforms {
+			parts := strings.Split(platform, "_")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return fmt.Errorf("invalid platform %q, expected <os>_<arch>", platform)
+			}
+			p.rules.platforms[platform] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// NewPolicy returns a Policy, which allows everything unless restricted by options
+func NewPolicy(options ...PolicyOption) (*Policy, error) {
+	p := &Policy{
+		rules: policyRules{
+			allowPrereleases: true,
+			platforms:        map[string]struct{}{},
+		},
+	}
+
+	for _, option := range options {
+		if err := option(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+type policyMiddleware struct {
+	policy *Policy
+	next   Service
+}
+
+func (mw policyMiddleware) ListProviderVersions(ctx context.Context, provider *core.Provider) (*ListProviderVersionsResponse, error) {
+	rules := mw.policy.current()
+	if err := rules.checkProvider(provider); err != nil {
+		return nil, err
+	}
+
+	response, err := mw.next.ListProviderVersions(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove all versions that are not allowed
+	for v := range response.Versions {
+		clone := provider.Clone()
+		clone.Version = v
+		if rules.checkVersion(clone) != nil {
+			delete(response.Versions, v)
+		}
+	}
+	return response, nil
+}
+
+func (mw policyMiddleware) ListProviderInstallation(ctx context.Context, provider *core.Provider) (*ListProviderInstallationResponse, error) {
+	rules := mw.policy.current()
+	if err := rules.checkProvider(provider); err != nil {
+		return nil, err
+	}
+	if err := rules.checkVersion(provider); err != nil {
+		return nil, err
+	}
+
+	response, err := mw.next.ListProviderInstallation(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove all platforms that are not allowed
+	if len(rules.platforms) > 0 {
+		for platform := range response.Archives {
+			if _, ok := rules.platforms[platform]; !ok {
+				delete(response.Archives, platform)
+			}
+		}
+	}
+	return response, nil
+}
+
+func (mw policyMiddleware) RetrieveProviderArchive(ctx context.Context, provider *core.Provider) (*retrieveProviderArchiveResponse, error) {
+	if err := mw.policy.Check(provider); err != nil {
+		return nil, err
+	}
+
+	return mw.next.RetrieveProviderArchive(ctx, provider)
+}
+
+// PolicyMiddleware is a Service middleware that enforces the Policy
+func PolicyMiddleware(policy *Policy) Middleware {
+	return func(next Service) Service {
+		return &policyMiddleware{
+			policy: policy,
+			next:   next,
+		}
+	}
+}
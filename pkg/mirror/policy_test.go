@@ -0,0 +1,241 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+)
+
+type mockedService struct {
+	listProviderVersions     func(ctx context.Context, provider *core.Provider) (*ListProviderVersionsResponse, error)
+	listProviderInstallation func(ctx context.Context, provider *core.Provider) (*ListProviderInstallationResponse, error)
+	retrieveProviderArchive  func(ctx context.Context, provider *core.Provider) (*retrieveProviderArchiveResponse, error)
+}
+
+func (m *mockedService) ListProviderVersions(ctx context.Context, provider *core.Provider) (*ListProviderVersionsResponse, error) {
+	return m.listProviderVersions(ctx, provider)
+}
+
+func (m *mockedService) ListProviderInstallation(ctx context.Context, provider *core.Provider) (*ListProviderInstallationResponse, error) {
+	return m.listProviderInstallation(ctx, provider)
+}
+
+func (m *mockedService) RetrieveProviderArchive(ctx context.Context, provider *core.Provider) (*retrieveProviderArchiveResponse, error) {
+	return m.retrieveProviderArchive(ctx, provider)
+}
+
+func TestPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []PolicyOption
+		provider *core.Provider
+		wantErr  bool
+	}{
+		{
+			name:     "empty policy allows everything",
+			provider: &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.0.0-beta1"},
+		},
+		{
+			name:     "provider matches allowed pattern",
+			options:  []PolicyOption{WithAllowedProviders("registry.terraform.io/hashicorp/*")},
+			provider: &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random"},
+		},
+		{
+			name:     "allowed pattern with omitted segments",
+			options:  []PolicyOption{WithAllowedProviders("registry.terraform.io")},
+			provider: &core.Provider{Hostname: "registry.terraform.io", Namespace: "integrations", Name: "github"},
+		},
+		{
+			name:     "patterns are case-insensitive",
+			options:  []PolicyOption{WithAllowedProviders("registry.terraform.io/HashiCorp/*")},
+			provider: &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "Random"},
+		},
+		{
+			name:     "provider doesn't match allowed pattern",
+			options:  []PolicyOption{WithAllowedProviders("registry.terraform.io/hashicorp/*")},
+			provider: &core.Provider{Hostname: "registry.terraform.io", Namespace: "integrations", Name: "github"},
+			wantErr:  true,
+		},
+		{
+			name: "deny takes precedence over allow",
+			options: []PolicyOption{
+				WithAllowedProviders("registry.terraform.io/hashicorp/*"),
+				WithDeniedProviders("*/*/random"),
+			},
+			provider: &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random"},
+			wantErr:  true,
+		},
+		{
+			name:     "pre-release denied",
+			options:  []PolicyOption{WithPrereleases(false)},
+			provider: &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.0.0-beta1"},
+			wantErr:  true,
+		},
+		{
+			name:     "version meets constraints",
+			options:  []PolicyOption{WithVersionConstraints(">= 3.0, < 4.0")},
+			provider: &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.6.2"},
+		},
+		{
+			name:     "version doesn't meet constraints",
+			options:  []PolicyOption{WithVersionConstraints(">= 3.0, < 4.0")},
+			provider: &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "2.3.0"},
+			wantErr:  true,
+		},
+		{
+			name:     "platform allowed",
+			options:  []PolicyOption{WithPlatforms("linux_amd64", "darwin_arm64")},
+			provider: &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.6.2", OS: "darwin", Arch: "arm64"},
+		},
+		{
+			name:     "platform denied",
+			options:  []PolicyOption{WithPlatforms("linux_amd64")},
+			provider: &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.6.2", OS: "windows", Arch: "amd64"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewPolicy(tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = policy.Check(tt.provider)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var providerErr *core.ProviderError
+			if tt.wantErr && (!errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusForbidden) {
+				t.Errorf("Check() error = %v, want ProviderError with status code %d", err, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestNewPolicy_invalidOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		option PolicyOption
+	}{
+		{name: "too many segments", option: WithAllowedProviders("registry.terraform.io/hashicorp/random/extra")},
+		{name: "empty hostname", option: WithDeniedProviders("")},
+		{name: "malformed glob", option: WithAllowedProviders("registry.terraform.io/[")},
+		{name: "invalid constraints", option: WithVersionConstraints(">= foo")},
+		{name: "invalid platform", option: WithPlatforms("linux")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPolicy(tt.option); err == nil {
+				t.Error("NewPolicy() expected error")
+			}
+		})
+	}
+}
+
+func TestPolicy_Update(t *testing.T) {
+	provider := &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.0.0"}
+
+	policy, err := NewPolicy(WithDeniedProviders("registry.terraform.io/hashicorp/random"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policy.Check(provider); err == nil {
+		t.Fatal("Check() expected error before the update")
+	}
+
+	next, err := NewPolicy(WithVersionConstraints("< 3.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.Update(next)
+
+	if err := policy.Check(provider); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("Check() error = %v, want a violation of the version constraints", err)
+	}
+	provider.Version = "2.3.0"
+	if err := policy.Check(provider); err != nil {
+		t.Errorf("Check() unexpected error after the update: %v", err)
+	}
+}
+
+func TestPolicyMiddleware(t *testing.T) {
+	policy, err := NewPolicy(
+		WithAllowedProviders("registry.terraform.io/hashicorp/*"),
+		WithVersionConstraints("< 4.0"),
+		WithPrereleases(false),
+		WithPlatforms("linux_amd64"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var called bool
+	next := &mockedService{
+		listProviderVersions: func(ctx context.Context, provider *core.Provider) (*ListProviderVersionsResponse, error) {
+			called = true
+			return &ListProviderVersionsResponse{
+				Versions: map[string]EmptyObject{"3.6.2": {}, "3.7.0-rc1": {}, "4.0.0": {}},
+			}, nil
+		},
+		listProviderInstallation: func(ctx context.Context, provider *core.Provider) (*ListProviderInstallationResponse, error) {
+			called = true
+			return &ListProviderInstallationResponse{
+				Archives: map[string]Archive{"linux_amd64": {}, "darwin_arm64": {}},
+			}, nil
+		},
+		retrieveProviderArchive: func(ctx context.Context, provider *core.Provider) (*retrieveProviderArchiveResponse, error) {
+			called = true
+			return &retrieveProviderArchiveResponse{location: "https://example.com/archive.zip"}, nil
+		},
+	}
+	svc := PolicyMiddleware(policy)(next)
+	ctx := context.Background()
+
+	t.Run("versions are filtered", func(t *testing.T) {
+		resp, err := svc.ListProviderVersions(ctx, &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := resp.Versions["3.6.2"]; !ok || len(resp.Versions) != 1 {
+			t.Errorf("ListProviderVersions() versions = %v, want only 3.6.2", resp.Versions)
+		}
+	})
+
+	t.Run("platforms are filtered", func(t *testing.T) {
+		resp, err := svc.ListProviderInstallation(ctx, &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.6.2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := resp.Archives["linux_amd64"]; !ok || len(resp.Archives) != 1 {
+			t.Errorf("ListProviderInstallation() archives = %v, want only linux_amd64", resp.Archives)
+		}
+	})
+
+	t.Run("denied requests don't reach the next service", func(t *testing.T) {
+		called = false
+		denied := []*core.Provider{
+			{Hostname: "registry.terraform.io", Namespace: "integrations", Name: "github"},
+			{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "4.0.0"},
+			{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Version: "3.6.2", OS: "windows", Arch: "amd64"},
+		}
+		for _, p := range denied {
+			if _, err := svc.RetrieveProviderArchive(ctx, p); err == nil {
+				t.Errorf("RetrieveProviderArchive() expected error for %v", p)
+			}
+		}
+		if _, err := svc.ListProviderInstallation(ctx, denied[1]); err == nil {
+			t.Error("ListProviderInstallation() expected error")
+		}
+		if called {
+			t.Error("next service was called for a denied request")
+		}
+	})
+}
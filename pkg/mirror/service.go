@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
@@ -78,14 +78,29 @@ func NewMirror(s Storage) Service {
 	}
 }
 
+const (
+	defaultUpstreamTimeout = 10 * time.Second
+)
+
 type pullThroughMirror struct {
 	upstream upstreamProvider
 	mirror   Service
 	copier   Copier
+
+	// upstreamTimeout limits the duration of requests to the upstream registry
+	upstreamTimeout time.Duration
+
+	// fallbackOnServerError determines whether the mirror is used in case the upstream registry responds with a 5xx status code
+	fallbackOnServerError bool
+
+	cacheConfig CacheConfig
+
+	// upstreamRoutes maps the hostnames of registries to the hosts at which they are reached
+	upstreamRoutes map[string]string
 }
 
 func (p *pullThroughMirror) ListProviderVersions(ctx context.Context, provider *core.Provider) (*ListProviderVersionsResponse, error) {
-	upstreamCtx, cancelUpstreamCtx := context.WithTimeout(ctx, 10*time.Second)
+	upstreamCtx, cancelUpstreamCtx := p.upstreamContext(ctx)
 	defer cancelUpstreamCtx()
 	providerVersionsResponse, err := p.upstream.listProviderVersions(upstreamCtx, provider)
 	if err == nil {
@@ -93,9 +108,7 @@ func (p *pullThroughMirror) ListProviderVersions(ctx context.Context, provider *
 		return toListProviderVersionsResponse(providerVersionsResponse), nil
 	}
 
-	var urlError *url.Error
-	if isUrlError := errors.As(err, &urlError); !isUrlError {
-		// It's not a network-related error
+	if !p.isFallbackError(err) {
 		return nil, err
 	}
 
@@ -104,15 +117,12 @@ func (p *pullThroughMirror) ListProviderVersions(ctx context.Context, provider *
 }
 
 func (p *pullThroughMirror) ListProviderInstallation(ctx context.Context, provider *core.Provider) (*ListProviderInstallationResponse, error) {
-	upstreamCtx, cancelUpstreamCtx := context.WithTimeout(ctx, 10*time.Second)
+	upstreamCtx, cancelUpstreamCtx := p.upstreamContext(ctx)
 	defer cancelUpstreamCtx()
 	response, err := p.upstream.listProviderVersions(upstreamCtx, provider)
-	if err != nil {
-		var urlError *url.Error
-		if isUrlError := errors.As(err, &urlError); !isUrlError {
-			// It's not a network-related error, therefore we abort the attempt
-			return nil, err
-		}
+	if err != nil && !p.isFallbackError(err) {
+		// The upstream registry can't be ignored, therefore we abort the attempt
+		return nil, err
 	}
 
 	if err == nil && versionExists(provider.Version, response) {
@@ -190,14 +200,85 @@ func (p *pullThroughMirror) upstreamSha256Sums(ctx context.Context, provider *co
 	return p.upstream.shaSums(ctx, providerUpstream)
 }
 
-func NewPullThroughMirror(s Storage, c Copier) Service {
-	remoteServiceDiscovery := discovery.NewRemoteServiceDiscovery(http.DefaultClient)
+// upstreamContext returns a context that is bounded by the upstream timeout
+func (p *pullThroughMirror) upstreamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := p.upstreamTimeout
+	if timeout == 0 {
+		timeout = defaultUpstreamTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// isFallbackError determines whether the response should be served from the mirror, as the upstream registry failed
+func (p *pullThroughMirror) isFallbackError(err error) bool {
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		// It's a network-related error
+		return true
+	}
+
+	return p.fallbackOnServerError && errors.Is(err, ErrUpstreamUnavailable)
+}
+
+// PullThroughMirrorOption provides additional options for the pull-through mirror
+type PullThroughMirrorOption func(*pullThroughMirror)
+
+// WithUpstreamTimeout configures the timeout for requests to the upstream registry
+func WithUpstreamTimeout(t time.Duration) PullThroughMirrorOption {
+	return func(p *pullThroughMirror) {
+		p.upstreamTimeout = t
+	}
+}
+
+// WithFallbackOnServerError configures whether the mirror is used in case the upstream registry responds with a 5xx status code
+func WithFallbackOnServerError(fallback bool) PullThroughMirrorOption {
+	return func(p *pullThroughMirror) {
+		p.fallbackOnServerError = fallback
+	}
+}
+
+// WithCache configures the cache for upstream metadata
+func WithCache(config CacheConfig) PullThroughMirrorOption {
+	return func(p *pullThroughMirror) {
+		p.cacheConfig = config
+	}
+}
+
+// WithUpstreamRoutes configures hosts, at which the upstream registries are reached instead of their hostnames.
+// The keys are the hostnames of the registries, e.g. registry.terraform.io, and the values are the hosts they are routed to.
+func WithUpstreamRoutes(routes map[string]string) PullThroughMirrorOption {
+	return func(p *pullThroughMirror) {
+		p.upstreamRoutes = make(map[string]string, len(routes))
+		for hostname, target := range routes {
+			p.upstreamRoutes[strings.ToLower(hostname)] = target
+		}
+	}
+}
+
+func NewPullThroughMirror(s Storage, c Copier, options ...PullThroughMirrorOption) Service {
 	svc := &pullThroughMirror{
-		upstream: newUpstreamProviderRegistry(remoteServiceDiscovery),
 		mirror: &mirror{
 			storage: s,
 		},
-		copier: c,
+		copier:          c,
+		upstreamTimeout: defaultUpstreamTimeout,
+	}
+
+	for _, option := range options {
+		option(svc)
+	}
+
+	var remoteServiceDiscovery discovery.ServiceDiscoveryResolver = newRemoteServiceDiscovery()
+	if len(svc.upstreamRoutes) > 0 {
+		remoteServiceDiscovery = &routedServiceDiscovery{
+			routes: svc.upstreamRoutes,
+			next:   remoteServiceDiscovery,
+		}
+	}
+	svc.upstream = newUpstreamProviderRegistry(remoteServiceDiscovery)
+
+	if svc.cacheConfig.TTL > 0 {
+		svc.upstream = newCachedUpstreamProvider(svc.upstream, svc.cacheConfig, svc.upstreamTimeout)
 	}
 
 	return svc
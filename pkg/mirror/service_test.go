@@ -38,6 +38,10 @@ type mockedStorage struct {
 	uploadMirroredFile        func(ctx context.Context, provider *core.Provider, filename string, reader io.Reader) error
 	mirroredSigningKeys       func(ctx context.Context, hostname, namespace string) (*core.SigningKeys, error)
 	uploadMirroredSigningKeys func(ctx context.Context, hostname, namespace string, signingKeys *core.SigningKeys) error
+	listMirroredFiles         func(ctx context.Context) ([]MirroredFile, error)
+	downloadMirroredFile      func(ctx context.Context, provider *core.Provider, fileName string) (io.ReadCloser, error)
+	updateMirroredFile        func(ctx context.Context, provider *core.Provider, fileName string, update func([]byte) ([]byte, error)) error
+	deleteMirroredFile        func(ctx context.Context, provider *core.Provider, fileName string) error
 }
 
 func (m *mockedStorage) ListMirroredProviders(ctx context.Context, provider *core.Provider) ([]*core.Provider, error) {
@@ -60,6 +64,22 @@ func (m *mockedStorage) UploadMirroredSigningKeys(ctx context.Context, hostname,
 	return m.uploadMirroredSigningKeys(ctx, hostname, namespace, signingKeys)
 }
 
+func (m *mockedStorage) ListMirroredFiles(ctx context.Context) ([]MirroredFile, error) {
+	return m.listMirroredFiles(ctx)
+}
+
+func (m *mockedStorage) DownloadMirroredFile(ctx context.Context, provider *core.Provider, fileName string) (io.ReadCloser, error) {
+	return m.downloadMirroredFile(ctx, provider, fileName)
+}
+
+func (m *mockedStorage) UpdateMirroredFile(ctx context.Context, provider *core.Provider, fileName string, update func([]byte) ([]byte, error)) error {
+	return m.updateMirroredFile(ctx, provider, fileName, update)
+}
+
+func (m *mockedStorage) DeleteMirroredFile(ctx context.Context, provider *core.Provider, fileName string) error {
+	return m.deleteMirroredFile(ctx, provider, fileName)
+}
+
 func (m *mockedStorage) MirroredSha256Sum(ctx context.Context, provider *core.Provider) (*core.Sha256Sums, error) {
 	return m.mirroredSha256Sum(ctx, provider)
 }
@@ -199,6 +219,61 @@ func Test_pullThroughMirror_ListProviderVersions(t *testing.T) {
 				mirrorSource: mirrorSource{isMirror: true},
 			},
 		},
+		{
+			name: "upstream server error without fallback",
+			svc: &pullThroughMirror{
+				upstream: &mockedUpstreamProvider{
+					customListProviderVersions: func(ctx context.Context, provider *core.Provider) (*core.ProviderVersions, error) {
+						return nil, fmt.Errorf("%w: status code is 503 instead of 200", ErrUpstreamUnavailable)
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "upstream rate limit with fallback",
+			svc: &pullThroughMirror{
+				upstream: &mockedUpstreamProvider{
+					customListProviderVersions: func(ctx context.Context, provider *core.Provider) (*core.ProviderVersions, error) {
+						return nil, fmt.Errorf("%w: status code is 429 instead of 200", ErrUpstreamRejected)
+					},
+				},
+				fallbackOnServerError: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "upstream server error, response from mirror",
+			svc: &pullThroughMirror{
+				upstream: &mockedUpstreamProvider{
+					customListProviderVersions: func(ctx context.Context, provider *core.Provider) (*core.ProviderVersions, error) {
+						return nil, fmt.Errorf("%w: status code is 503 instead of 200", ErrUpstreamUnavailable)
+					},
+				},
+				mirror: &mirror{
+					storage: &mockedStorage{
+						listMirrorProviders: func(ctx context.Context, provider *core.Provider) ([]*core.Provider, error) {
+							return []*core.Provider{
+								{
+									Namespace: "hashicorp",
+									Name:      "random",
+									Version:   "0.1.2",
+									OS:        "linux",
+									Arch:      "amd64",
+								},
+							}, nil
+						},
+					},
+				},
+				fallbackOnServerError: true,
+			},
+			want: &ListProviderVersionsResponse{
+				Versions: map[string]EmptyObject{
+					"0.1.2": {},
+				},
+				mirrorSource: mirrorSource{isMirror: true},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -3,6 +3,7 @@ package mirror
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
 )
@@ -27,4 +28,30 @@ type Storage interface {
 
 	// Retrieve the SHA256SUM from storage
 	MirroredSha256Sum(ctx context.Context, provider *core.Provider) (*core.Sha256Sums, error)
+
+	// ListMirroredFiles returns all files that belong to mirrored providers.
+	// Files that are not stored beneath a <hostname>/<namespace>/<name> prefix, like the signing keys, are omitted.
+	ListMirroredFiles(ctx context.Context) ([]MirroredFile, error)
+
+	// DownloadMirroredFile returns the content of a file that belongs to a mirrored provider
+	// or core.ErrObjectNotFound in case it doesn't exist
+	DownloadMirroredFile(ctx context.Context, provider *core.Provider, fileName string) (io.ReadCloser, error)
+
+	// UpdateMirroredFile updates a file that belongs to a mirrored provider with optimistic concurrency.
+	// The update receives the current content, or nil in case the file doesn't exist yet, and returns the new content.
+	// It's called again with the latest content, if the file is modified concurrently by another replica.
+	UpdateMirroredFile(ctx context.Context, provider *core.Provider, fileName string, update func([]byte) ([]byte, error)) error
+
+	// DeleteMirroredFile deletes a file that belongs to a mirrored provider
+	DeleteMirroredFile(ctx context.Context, provider *core.Provider, fileName string) error
+}
+
+// MirroredFile is a file in the storage backend that belongs to a mirrored provider
+type MirroredFile struct {
+	Hostname     string
+	Namespace    string
+	Name         string
+	FileName     string
+	Size         int64
+	LastModified time.Time
 }
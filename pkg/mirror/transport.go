@@ -159,25 +159,25 @@ func decodeRetrieveProviderArchiveRequest(ctx context.Context, _ *http.Request)
 
 }
 
+// addAuthToken appends the token of the request to the archive URLs that are relative to the mirror,
+// as Terraform doesn't send the credentials when it downloads the archives
 func addAuthToken(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	listResponse, ok := response.(*ListProviderInstallationResponse)
 	if !ok {
 		return errors.New("failed to type assert to listProviderInstallationResponse")
 	}
 
-	if !listResponse.isMirror {
-		t := ctx.Value(jwt.JWTContextKey)
-		token, ok := t.(string)
-		if !ok {
-			return errors.New("failed to type assert to string")
-		}
-
+	if token, ok := ctx.Value(jwt.JWTContextKey).(string); ok && token != "" {
 		for k, a := range listResponse.Archives {
 			parsed, err := url.Parse(a.Url)
 			if err != nil {
 				return err
 			}
-			parsed.RawQuery = fmt.Sprintf("token=%s", token)
+			if parsed.IsAbs() || parsed.Host != "" {
+				// The download URLs of the storage backend are signed already
+				continue
+			}
+			parsed.RawQuery = url.Values{"token": {token}}.Encode()
 			a.Url = parsed.String()
 			listResponse.Archives[k] = a
 		}
@@ -216,6 +216,8 @@ func ErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
 		w.WriteHeader(providerErr.StatusCode)
 	} else if errors.Is(err, ErrUpstreamNotFound) {
 		w.WriteHeader(http.StatusNotFound)
+	} else if errors.Is(err, ErrUpstreamUnavailable) || errors.Is(err, ErrUpstreamRejected) {
+		w.WriteHeader(http.StatusBadGateway)
 	} else {
 		w.WriteHeader(core.GenericError(err))
 	}
@@ -235,9 +237,23 @@ func extractMuxVars(keys ...muxVar) httptransport.RequestFunc {
 	}
 }
 
+// upstreamStatusError returns an error for an unsuccessful upstream response.
+// Only 404 and 410 are returned as ErrUpstreamNotFound, which is cached.
+// Server-side errors are returned as ErrUpstreamUnavailable, and all others, e.g. 401 and 429, as ErrUpstreamRejected.
+func upstreamStatusError(r *http.Response) error {
+	switch {
+	case r.StatusCode == http.StatusNotFound || r.StatusCode == http.StatusGone:
+		return fmt.Errorf("%w: status code is %d instead of 200", ErrUpstreamNotFound, r.StatusCode)
+	case r.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: status code is %d instead of 200", ErrUpstreamUnavailable, r.StatusCode)
+	default:
+		return fmt.Errorf("%w: status code is %d instead of 200", ErrUpstreamRejected, r.StatusCode)
+	}
+}
+
 func decodeUpstreamProviderResponse(r *http.Response) (*core.Provider, error) {
 	if r.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: status code is %d instead of 200", ErrUpstreamNotFound, r.StatusCode)
+		return nil, upstreamStatusError(r)
 	}
 
 	var response core.Provider
@@ -249,7 +265,7 @@ func decodeUpstreamProviderResponse(r *http.Response) (*core.Provider, error) {
 
 func decodeUpstreamListProviderVersionsResponse(r *http.Response) (*core.ProviderVersions, error) {
 	if r.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: status code is %d instead of 200", ErrUpstreamNotFound, r.StatusCode)
+		return nil, upstreamStatusError(r)
 	}
 
 	var response core.ProviderVersions
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
 	"github.com/boring-registry/boring-registry/pkg/discovery"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
 )
 
 type upstreamProvider interface {
@@ -21,7 +23,10 @@ type upstreamProviderRegistry struct {
 	remoteServiceDiscovery discovery.ServiceDiscoveryResolver
 }
 
-func (u *upstreamProviderRegistry) listProviderVersions(ctx context.Context, provider *core.Provider) (*core.ProviderVersions, error) {
+func (u *upstreamProviderRegistry) listProviderVersions(ctx context.Context, provider *core.Provider) (_ *core.ProviderVersions, err error) {
+	ctx, span := o11y.StartSpan(ctx, "upstream.listProviderVersions", providerAttributes(provider)...)
+	defer func() { o11y.EndSpan(span, err) }()
+
 	discovered, err := u.remoteServiceDiscovery.Resolve(ctx, provider.Hostname)
 	if err != nil {
 		return nil, err
@@ -41,7 +46,10 @@ func (u *upstreamProviderRegistry) listProviderVersions(ctx context.Context, pro
 	return decodeUpstreamListProviderVersionsResponse(resp)
 }
 
-func (u *upstreamProviderRegistry) getProvider(ctx context.Context, provider *core.Provider) (*core.Provider, error) {
+func (u *upstreamProviderRegistry) getProvider(ctx context.Context, provider *core.Provider) (_ *core.Provider, err error) {
+	ctx, span := o11y.StartSpan(ctx, "upstream.getProvider", providerAttributes(provider)...)
+	defer func() { o11y.EndSpan(span, err) }()
+
 	discovered, err := u.remoteServiceDiscovery.Resolve(ctx, provider.Hostname)
 	if err != nil {
 		return nil, err
@@ -72,7 +80,10 @@ func (u *upstreamProviderRegistry) getProvider(ctx context.Context, provider *co
 	return decoded, err
 }
 
-func (u *upstreamProviderRegistry) shaSums(ctx context.Context, provider *core.Provider) (*core.Sha256Sums, error) {
+func (u *upstreamProviderRegistry) shaSums(ctx context.Context, provider *core.Provider) (_ *core.Sha256Sums, err error) {
+	ctx, span := o11y.StartSpan(ctx, "upstream.shaSums", providerAttributes(provider)...)
+	defer func() { o11y.EndSpan(span, err) }()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.SHASumsURL, nil)
 	if err != nil {
 		return nil, err
@@ -82,6 +93,9 @@ func (u *upstreamProviderRegistry) shaSums(ctx context.Context, provider *core.P
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, upstreamStatusError(resp)
+	}
 
 	sha256Sums, err := core.NewSha256Sums(provider.ShasumFileName(), resp.Body)
 	if err != nil {
@@ -95,12 +109,40 @@ func newUpstreamProviderRegistry(remoteServiceDiscovery discovery.ServiceDiscove
 	transport.MaxIdleConnsPerHost = 100
 	return &upstreamProviderRegistry{
 		client: &http.Client{
-			Transport: transport,
+			Transport: o11y.NewTransport(transport),
 		},
 		remoteServiceDiscovery: remoteServiceDiscovery,
 	}
 }
 
+// newRemoteServiceDiscovery returns a ServiceDiscoveryResolver, which traces the requests to the well-known endpoints
+func newRemoteServiceDiscovery() discovery.ServiceDiscoveryResolver {
+	return discovery.NewRemoteServiceDiscovery(&http.Client{
+		Transport: o11y.NewTransport(http.DefaultTransport),
+	})
+}
+
+// routedServiceDiscovery resolves the services of a registry at the host it's routed to, e.g. an internal proxy of the public registry.
+// The mirrored providers are still stored under the hostname of the registry.
+type routedServiceDiscovery struct {
+	routes map[string]string
+	next   discovery.ServiceDiscoveryResolver
+}
+
+func (r *routedServiceDiscovery) Resolve(ctx context.Context, host string) (*discovery.DiscoveredRemoteService, error) {
+	if target, ok := r.routes[strings.ToLower(host)]; ok {
+		host = target
+	}
+	return r.next.Resolve(ctx, host)
+}
+
+// CheckUpstream verifies that the remote service discovery of the upstream registry responds.
+// A new resolver is used each time, as the resolver of the mirror caches the discovered services indefinitely.
+func CheckUpstream(ctx context.Context, hostname string) error {
+	_, err := newRemoteServiceDiscovery().Resolve(ctx, hostname)
+	return err
+}
+
 func upstreamURL(hostname, path string) string {
 	upstreamUrl := url.URL{
 		Scheme: "https",
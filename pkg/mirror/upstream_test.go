@@ -12,6 +12,13 @@ import (
 
 	"github.com/boring-registry/boring-registry/pkg/core"
 	"github.com/boring-registry/boring-registry/pkg/discovery"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type mockedRemoteServiceDiscovery struct {
@@ -271,3 +278,71 @@ func Test_upstreamProviderRegistry_shaSums(t *testing.T) {
 		})
 	}
 }
+
+func Test_upstreamProviderRegistry_tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(o11y.NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), 1, "test"))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceparent string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		_, _ = w.Write([]byte(`5f9c7aa76b7c34d722fc9123208e26b22d60440cb47150dd04733b9b94f4541a  terraform-provider-random_2.0.0_linux_amd64.zip`))
+	}))
+	defer server.Close()
+
+	u := &upstreamProviderRegistry{
+		client:                 &http.Client{Transport: o11y.NewTransport(server.Client().Transport)},
+		remoteServiceDiscovery: newMockedServiceDiscovery(server),
+	}
+	provider := &core.Provider{
+		Hostname:   "terraform.example.com",
+		Namespace:  "hashicorp",
+		Name:       "random",
+		Version:    "2.0.0",
+		OS:         "linux",
+		Arch:       "amd64",
+		SHASumsURL: server.URL,
+	}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	_, err := u.shaSums(ctx, provider)
+	parent.End()
+	assert.NoError(t, err)
+
+	// The trace context is propagated to the upstream registry
+	assert.Contains(t, traceparent, parent.SpanContext().TraceID().String())
+
+	spans := exporter.GetSpans()
+	names := map[string]tracetest.SpanStub{}
+	for _, s := range spans {
+		names[s.Name] = s
+	}
+	assert.Contains(t, names, "upstream.shaSums")
+	assert.Contains(t, names, "GET "+strings.TrimPrefix(server.URL, "https://"))
+	for _, s := range spans {
+		assert.Equal(t, parent.SpanContext().TraceID(), s.SpanContext.TraceID())
+	}
+	assert.Equal(t, names["parent"].SpanContext.SpanID(), names["upstream.shaSums"].Parent.SpanID())
+}
+
+func Test_routedServiceDiscovery(t *testing.T) {
+	var resolved []string
+	r := &routedServiceDiscovery{
+		routes: map[string]string{"registry.terraform.io": "terraform-proxy.example.com"},
+		next: &mockedRemoteServiceDiscovery{
+			resolve: func(ctx context.Context, host string) (*discovery.DiscoveredRemoteService, error) {
+				resolved = append(resolved, host)
+				return &discovery.DiscoveredRemoteService{}, nil
+			},
+		},
+	}
+
+	for _, host := range []string{"Registry.Terraform.io", "registry.opentofu.org"} {
+		if _, err := r.Resolve(context.Background(), host); err != nil {
+			t.Fatalf("Resolve() unexpected error: %v", err)
+		}
+	}
+
+	assert.Equal(t, []string{"terraform-proxy.example.com", "registry.opentofu.org"}, resolved)
+}
@@ -0,0 +1,368 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+)
+
+// IssueKind classifies the inconsistencies found by the Verifier
+type IssueKind string
+
+const (
+	// IssueChecksumMismatch means that the checksum of an archive differs from the SHA256SUMS
+	IssueChecksumMismatch IssueKind = "checksum-mismatch"
+	// IssueMissingChecksum means that an archive isn't listed in the SHA256SUMS
+	IssueMissingChecksum IssueKind = "missing-checksum"
+	// IssueMissingSha256Sums means that archives exist without the SHA256SUMS of their version
+	IssueMissingSha256Sums IssueKind = "missing-sha256sums"
+	// IssueMissingSignature means that the SHA256SUMS.sig of a version doesn't exist
+	IssueMissingSignature IssueKind = "missing-signature"
+	// IssueInvalidSignature means that the SHA256SUMS.sig can't be verified with the mirrored signing keys
+	IssueInvalidSignature IssueKind = "invalid-signature"
+	// IssueMissingPlatform means that a platform was requested, but its archive doesn't exist
+	IssueMissingPlatform IssueKind = "missing-platform"
+	// IssueOrphanedFile means that a file doesn't belong to any mirrored archive
+	IssueOrphanedFile IssueKind = "orphaned-file"
+)
+
+// Issue is an inconsistency of a mirrored provider
+type Issue struct {
+	Kind IssueKind
+	// Provider contains the OS and Arch in case the issue concerns a single platform
+	Provider *core.Provider
+	FileName string
+	Message  string
+
+	// Repaired is true in case the issue was repaired successfully
+	Repaired bool
+	// RepairErr is set in case the repair of the issue failed
+	RepairErr error
+}
+
+// VerificationResult summarizes a run of the Verifier
+type VerificationResult struct {
+	Issues []Issue
+
+	// Archives is the number of verified archives
+	Archives int
+}
+
+// Verifier checks the mirrored providers for consistency and optionally repairs them
+type Verifier struct {
+	storage  Storage
+	upstream upstreamProvider
+	copier   *copier
+	logger   *slog.Logger
+}
+
+// Run verifies all mirrored providers.
+// In case repair is true, the affected files are fetched again from upstream and orphaned files are deleted.
+func (v *Verifier) Run(ctx context.Context, repair bool) (*VerificationResult, error) {
+	files, err := v.storage.ListMirroredFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mirrored files: %w", err)
+	}
+
+	providers := map[providerAddress][]MirroredFile{}
+	for _, f := range files {
+		addr := providerAddress{hostname: f.Hostname, namespace: f.Namespace, name: f.Name}
+		providers[addr] = append(providers[addr], f)
+	}
+
+	addresses := make([]providerAddress, 0, len(providers))
+	for addr := range providers {
+		addresses = append(addresses, addr)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].String() < addresses[j].String()
+	})
+
+	result := &VerificationResult{}
+	for _, addr := range addresses {
+		issues, archives, err := v.verify(ctx, addr, providers[addr])
+		if err != nil {
+			return result, fmt.Errorf("failed to verify %s: %w", addr, err)
+		}
+		result.Archives += archives
+
+		if repair {
+			v.repair(ctx, addr, providers[addr], issues)
+		}
+		result.Issues = append(result.Issues, issues...)
+	}
+
+	return result, nil
+}
+
+// verify checks the files of a single provider and returns the found issues and the number of verified archives
+func (v *Verifier) verify(ctx context.Context, addr providerAddress, files []MirroredFile) ([]Issue, int, error) {
+	provider := addr.provider()
+	log, err := readAccessLog(ctx, v.storage, provider)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	signingKeys, err := v.storage.MirroredSigningKeys(ctx, addr.hostname, addr.namespace)
+	if err != nil && !errors.Is(err, core.ErrObjectNotFound) {
+		return nil, 0, err
+	}
+
+	var issues []Issue
+	archives := 0
+	grouped := map[string]*mirroredVersion{}
+	for _, mv := range groupVersions(addr, files) {
+		grouped[mv.version.Original()] = mv
+	}
+
+	// All files that can't be assigned to a version are orphaned
+	known := map[string]struct{}{AccessLogFileName: {}}
+	for _, mv := range grouped {
+		for _, f := range mv.archives {
+			known[f.FileName] = struct{}{}
+		}
+		for _, f := range mv.other {
+			known[f.FileName] = struct{}{}
+		}
+	}
+	for _, f := range files {
+		if _, ok := known[f.FileName]; !ok {
+			issues = append(issues, Issue{Kind: IssueOrphanedFile, Provider: provider.Clone(), FileName: f.FileName, Message: "file doesn't belong to any provider version"})
+		}
+	}
+
+	// Platforms that were requested but never completely copied to the mirror
+	for version, platforms := range log.Versions {
+		for platform := range platforms {
+			os, arch, ok := strings.Cut(platform, "_")
+			if !ok || os == "" || arch == "" {
+				continue
+			}
+			p := provider.Clone()
+			p.Version, p.OS, p.Arch = version, os, arch
+			if mv, ok := grouped[version]; ok && mv.hasArchive(p.ArchiveFileName()) {
+				continue
+			}
+			issues = append(issues, Issue{Kind: IssueMissingPlatform, Provider: p, FileName: p.ArchiveFileName(), Message: "platform was requested, but the archive doesn't exist"})
+		}
+	}
+
+	for raw, mv := range grouped {
+		p := provider.Clone()
+		p.Version = raw
+
+		sumsFile, sigFile := mv.file(p.ShasumFileName()), mv.file(p.ShasumSignatureFileName())
+		if len(mv.archives) == 0 {
+			if _, requested := log.Versions[raw]; !requested {
+				for _, f := range mv.other {
+					issues = append(issues, Issue{Kind: IssueOrphanedFile, Provider: p.Clone(), FileName: f.FileName, Message: "version doesn't have any archive"})
+				}
+			}
+			continue
+		}
+
+		if sumsFile == nil {
+			issues = append(issues, Issue{Kind: IssueMissingSha256Sums, Provider: p.Clone(), FileName: p.ShasumFileName(), Message: "SHA256SUMS doesn't exist"})
+			continue
+		}
+
+		sumsBytes, err := v.download(ctx, provider, sumsFile.FileName)
+		if err != nil {
+			return nil, archives, err
+		}
+		sums, err := core.NewSha256Sums(sumsFile.FileName, bytes.NewReader(sumsBytes))
+		if err != nil {
+			issues = append(issues, Issue{Kind: IssueMissingSha256Sums, Provider: p.Clone(), FileName: sumsFile.FileName, Message: fmt.Sprintf("SHA256SUMS is invalid: %v", err)})
+			continue
+		}
+
+		if sigFile == nil {
+			issues = append(issues, Issue{Kind: IssueMissingSignature, Provider: p.Clone(), FileName: p.ShasumSignatureFileName(), Message: "SHA256SUMS.sig doesn't exist"})
+		} else if signingKeys == nil {
+			issues = append(issues, Issue{Kind: IssueInvalidSignature, Provider: p.Clone(), FileName: sigFile.FileName, Message: "signing keys don't exist"})
+		} else {
+			sigBytes, err := v.download(ctx, provider, sigFile.FileName)
+			if err != nil {
+				return nil, archives, err
+			}
+			if err := signingKeys.IsValidSha256Sums(sumsBytes, sigBytes); err != nil {
+				issues = append(issues, Issue{Kind: IssueInvalidSignature, Provider: p.Clone(), FileName: sigFile.FileName, Message: err.Error()})
+			}
+		}
+
+		for _, f := range mv.archives {
+			archive, _ := core.NewProviderFromArchive(f.FileName)
+			ap := p.Clone()
+			ap.OS, ap.Arch = archive.OS, archive.Arch
+
+			expected, err := sums.Checksum(f.FileName)
+			if err != nil {
+				issues = append(issues, Issue{Kind: IssueMissingChecksum, Provider: ap, FileName: f.FileName, Message: err.Error()})
+				continue
+			}
+
+			actual, err := v.checksum(ctx, provider, f.FileName)
+			if err != nil {
+				return nil, archives, err
+			}
+			archives++
+			if actual != expected {
+				issues = append(issues, Issue{Kind: IssueChecksumMismatch, Provider: ap, FileName: f.FileName, Message: fmt.Sprintf("checksum is %s instead of %s", actual, expected)})
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].FileName < issues[j].FileName
+	})
+	return issues, archives, nil
+}
+
+// repair deletes orphaned files and copies the affected platforms again from upstream
+func (v *Verifier) repair(ctx context.Context, addr providerAddress, files []MirroredFile, issues []Issue) {
+	grouped := map[string]*mirroredVersion{}
+	for _, mv := range groupVersions(addr, files) {
+		grouped[mv.version.Original()] = mv
+	}
+
+	// Multiple issues can be repaired by copying the same platform
+	copied := map[string]error{}
+	for i := range issues {
+		issue := &issues[i]
+		if issue.Kind == IssueOrphanedFile {
+			issue.RepairErr = v.storage.DeleteMirroredFile(ctx, addr.provider(), issue.FileName)
+			issue.Repaired = issue.RepairErr == nil
+			continue
+		}
+
+		p := issue.Provider.Clone()
+		if p.OS == "" || p.Arch == "" {
+			// Version-level issues are repaired by copying any platform of the version
+			platform, ok := anyPlatform(grouped[p.Version])
+			if !ok {
+				issue.RepairErr = errors.New("no platform of the version to copy from upstream")
+				continue
+			}
+			p.OS, p.Arch = platform.OS, platform.Arch
+		}
+
+		key := p.ArchiveFileName()
+		err, ok := copied[key]
+		if !ok {
+			err = v.copyFromUpstream(ctx, p)
+			copied[key] = err
+		}
+		issue.RepairErr = err
+		issue.Repaired = err == nil
+	}
+}
+
+// anyPlatform returns the platform of any archive of the version
+func anyPlatform(mv *mirroredVersion) (core.Platform, bool) {
+	if mv == nil || len(mv.archives) == 0 {
+		return core.Platform{}, false
+	}
+	p, _ := core.NewProviderFromArchive(mv.archives[0].FileName)
+	return core.Platform{OS: p.OS, Arch: p.Arch}, true
+}
+
+func (v *Verifier) copyFromUpstream(ctx context.Context, provider *core.Provider) error {
+	if v.upstream == nil || v.copier == nil {
+		return errors.New("repairing from upstream is not configured")
+	}
+
+	upstreamProvider, err := v.upstream.getProvider(ctx, provider)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve provider from upstream: %w", err)
+	}
+	upstreamProvider.OS, upstreamProvider.Arch = provider.OS, provider.Arch
+
+	if err := v.copier.copyProvider(ctx, upstreamProvider); err != nil {
+		return err
+	}
+	v.logger.Info("repaired provider", logKeyValues(provider))
+	return nil
+}
+
+func (v *Verifier) download(ctx context.Context, provider *core.Provider, fileName string) ([]byte, error) {
+	r, err := v.storage.DownloadMirroredFile(ctx, provider, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", fileName, err)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (v *Verifier) checksum(ctx context.Context, provider *core.Provider, fileName string) (string, error) {
+	r, err := v.storage.DownloadMirroredFile(ctx, provider, fileName)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", fileName, err)
+	}
+	defer r.Close()
+
+	sum, err := core.Sha256Checksum(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute checksum of %s: %w", fileName, err)
+	}
+	return fmt.Sprintf("%x", sum), nil
+}
+
+func (mv *mirroredVersion) file(fileName string) *MirroredFile {
+	for i := range mv.other {
+		if mv.other[i].FileName == fileName {
+			return &mv.other[i]
+		}
+	}
+	return nil
+}
+
+func (mv *mirroredVersion) hasArchive(fileName string) bool {
+	for _, f := range mv.archives {
+		if f.FileName == fileName {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifierOption provides additional options for the Verifier
+type VerifierOption func(*Verifier)
+
+// WithUpstreamRepair enables repairing providers by copying them again from the upstream registry
+func WithUpstreamRepair() VerifierOption {
+	return func(v *Verifier) {
+		v.upstream = newUpstreamProviderRegistry(newRemoteServiceDiscovery())
+		v.copier = &copier{
+			storage: v.storage,
+			client: &http.Client{
+				Timeout:   2 * time.Minute,
+				Transport: o11y.NewTransport(http.DefaultTransport),
+			},
+			logger: v.logger,
+		}
+	}
+}
+
+// NewVerifier returns a Verifier for the mirrored providers in the storage backend
+func NewVerifier(s Storage, options ...VerifierOption) *Verifier {
+	v := &Verifier{
+		storage: s,
+		logger:  slog.Default().With(slog.String("component", "verifier")),
+	}
+
+	for _, option := range options {
+		option(v)
+	}
+
+	return v
+}
@@ -0,0 +1,175 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"path"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// newSigningEntity returns an OpenPGP entity and its ASCII-armored public key
+func newSigningEntity(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+	e, err := openpgp.NewEntity("boring-registry", "test", "boring-registry@example.com", &packet.Config{
+		Rand:    rand.New(rand.NewSource(42)),
+		RSABits: 2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	armored := new(bytes.Buffer)
+	w, err := armor.Encode(armored, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	return e, armored.String()
+}
+
+func TestVerifier_Run(t *testing.T) {
+	e, armored := newSigningEntity(t)
+
+	linuxArchive := []byte("linux_amd64")
+	sums := []byte(fmt.Sprintf("%x  terraform-provider-random_1.0.0_linux_amd64.zip\n%x  terraform-provider-random_1.0.0_darwin_arm64.zip\n",
+		sha256.Sum256(linuxArchive), sha256.Sum256([]byte("darwin_arm64"))))
+	sig := new(bytes.Buffer)
+	if err := openpgp.DetachSign(sig, e, bytes.NewReader(sums), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	content := map[string][]byte{
+		// Valid archive
+		"terraform-provider-random_1.0.0_linux_amd64.zip": linuxArchive,
+		"terraform-provider-random_1.0.0_SHA256SUMS":      sums,
+		"terraform-provider-random_1.0.0_SHA256SUMS.sig":  sig.Bytes(),
+		// The content doesn't match the checksum
+		"terraform-provider-random_1.0.0_darwin_arm64.zip": []byte("corrupted"),
+		// Version without archives
+		"terraform-provider-random_0.9.0_SHA256SUMS":     sums,
+		"terraform-provider-random_0.9.0_SHA256SUMS.sig": sig.Bytes(),
+		// Archive without SHA256SUMS
+		"terraform-provider-random_0.8.0_linux_amd64.zip": linuxArchive,
+		// Unknown file
+		"notes.txt": []byte("notes"),
+		// windows_amd64 was requested, but never copied
+		AccessLogFileName: []byte(`{"versions":{"1.0.0":{"linux_amd64":"2024-05-20T00:00:00Z","windows_amd64":"2024-05-20T00:00:00Z"}}}`),
+	}
+
+	newStorage := func() (*mockedStorage, map[string]MirroredFile) {
+		files := map[string]MirroredFile{}
+		data := map[string][]byte{}
+		for name, b := range content {
+			key := path.Join("registry.terraform.io/hashicorp/random", name)
+			files[key] = MirroredFile{
+				Hostname:     "registry.terraform.io",
+				Namespace:    "hashicorp",
+				Name:         "random",
+				FileName:     name,
+				Size:         int64(len(b)),
+				LastModified: time.Now(),
+			}
+			data[key] = b
+		}
+		s := newInmemMirroredFiles(files, data)
+		s.mirroredSigningKeys = func(ctx context.Context, hostname, namespace string) (*core.SigningKeys, error) {
+			return &core.SigningKeys{GPGPublicKeys: []core.GPGPublicKey{{KeyID: e.PrimaryKey.KeyIdString(), ASCIIArmor: armored}}}, nil
+		}
+		return s, files
+	}
+
+	want := []string{
+		"missing-sha256sums terraform-provider-random_0.8.0_SHA256SUMS",
+		"orphaned-file notes.txt",
+		"orphaned-file terraform-provider-random_0.9.0_SHA256SUMS",
+		"orphaned-file terraform-provider-random_0.9.0_SHA256SUMS.sig",
+		"checksum-mismatch terraform-provider-random_1.0.0_darwin_arm64.zip",
+		"missing-platform terraform-provider-random_1.0.0_windows_amd64.zip",
+	}
+
+	t.Run("verify", func(t *testing.T) {
+		s, files := newStorage()
+		result, err := NewVerifier(s).Run(context.Background(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, issue := range result.Issues {
+			got = append(got, fmt.Sprintf("%s %s", issue.Kind, issue.FileName))
+			if issue.Repaired || issue.RepairErr != nil {
+				t.Errorf("issue %s was repaired without repair being enabled", issue.FileName)
+			}
+		}
+		sort.Strings(want)
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Run() issues = %v, want %v", got, want)
+		}
+		if result.Archives != 2 {
+			t.Errorf("Run() verified %d archives, want 2", result.Archives)
+		}
+		if len(files) != len(content) {
+			t.Errorf("files were modified without repair being enabled")
+		}
+	})
+
+	t.Run("repair without upstream", func(t *testing.T) {
+		s, files := newStorage()
+		result, err := NewVerifier(s).Run(context.Background(), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, issue := range result.Issues {
+			if issue.Kind == IssueOrphanedFile && !issue.Repaired {
+				t.Errorf("orphaned file %s was not deleted: %v", issue.FileName, issue.RepairErr)
+			}
+			if issue.Kind != IssueOrphanedFile && issue.RepairErr == nil {
+				t.Errorf("issue %s was repaired without upstream", issue.FileName)
+			}
+		}
+		if _, ok := files["registry.terraform.io/hashicorp/random/notes.txt"]; ok {
+			t.Error("orphaned file notes.txt still exists")
+		}
+	})
+}
+
+func Test_mergeGPGPublicKeys(t *testing.T) {
+	a := core.GPGPublicKey{KeyID: "A"}
+	b := core.GPGPublicKey{KeyID: "B"}
+
+	tests := []struct {
+		name       string
+		upstream   []core.GPGPublicKey
+		mirrored   []core.GPGPublicKey
+		want       []core.GPGPublicKey
+		wantUpdate bool
+	}{
+		{name: "keys are identical", upstream: []core.GPGPublicKey{a}, mirrored: []core.GPGPublicKey{a}, want: []core.GPGPublicKey{a}},
+		{name: "upstream key was rotated", upstream: []core.GPGPublicKey{b}, mirrored: []core.GPGPublicKey{a}, want: []core.GPGPublicKey{a, b}, wantUpdate: true},
+		{name: "mirror has additional keys", upstream: []core.GPGPublicKey{a}, mirrored: []core.GPGPublicKey{a, b}, want: []core.GPGPublicKey{a, b}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, update := mergeGPGPublicKeys(tt.upstream, tt.mirrored)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) || update != tt.wantUpdate {
+				t.Errorf("mergeGPGPublicKeys() = %v, %v, want %v, %v", got, update, tt.want, tt.wantUpdate)
+			}
+		})
+	}
+}
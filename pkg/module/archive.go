@@ -0,0 +1,52 @@
+package module
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DefaultArchiveFormat is the archive format of modules, unless configured otherwise
+const DefaultArchiveFormat = "tar.gz"
+
+// ArchiveFormats are the module archive formats that go-getter can unpack, specified without the leading dot.
+// See https://github.com/hashicorp/go-getter#unarchiving
+var ArchiveFormats = []string{"tar.gz", "tgz", "zip", "tar.xz", "txz", "tar.bz2", "tbz2", "tar"}
+
+// IsArchiveFormat reports whether the format is a supported module archive format
+func IsArchiveFormat(format string) bool {
+	return slices.Contains(ArchiveFormats, format)
+}
+
+// ArchiveFormatFromName returns the archive format of a file name like example-s3-aws-1.0.0.tar.gz
+func ArchiveFormatFromName(name string) (string, bool) {
+	for _, format := range ArchiveFormats {
+		if strings.HasSuffix(name, "."+format) {
+			return format, true
+		}
+	}
+	return "", false
+}
+
+// ArchiveURL adds the archive hint of go-getter to the download URL.
+// go-getter otherwise guesses the format from the extension in the URL path, which fails for proxied or signed URLs without the extension.
+// The hint is removed by go-getter before the archive is downloaded, so it doesn't invalidate signed URLs.
+func ArchiveURL(downloadURL, format string) (string, error) {
+	if downloadURL == "" || format == "" {
+		return downloadURL, nil
+	}
+
+	u, err := url.Parse(downloadURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse download URL: %w", err)
+	}
+	// The query is appended instead of re-encoded to keep signed URLs byte for byte
+	hint := "archive=" + url.QueryEscape(format)
+	if u.RawQuery == "" {
+		u.RawQuery = hint
+	} else {
+		u.RawQuery += "&" + hint
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,68 @@
+package module
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveURL(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		url      string
+		format   string
+		expected string
+	}{
+		{
+			name:     "url without query",
+			url:      "https://example.com/modules/example-s3-aws-1.0.0.zip",
+			format:   "zip",
+			expected: "https://example.com/modules/example-s3-aws-1.0.0.zip?archive=zip",
+		},
+		{
+			name:     "signed url keeps its query",
+			url:      "https://example.com/example-s3-aws-1.0.0.tar.xz?X-Amz-Signature=a%2Fb&X-Amz-Expires=300",
+			format:   "tar.xz",
+			expected: "https://example.com/example-s3-aws-1.0.0.tar.xz?X-Amz-Signature=a%2Fb&X-Amz-Expires=300&archive=tar.xz",
+		},
+		{
+			name:     "proxy path",
+			url:      "/proxy/modules/example-s3-aws-1.0.0.tar.gz",
+			format:   "tar.gz",
+			expected: "/proxy/modules/example-s3-aws-1.0.0.tar.gz?archive=tar.gz",
+		},
+		{
+			name:     "without format",
+			url:      "https://example.com/example-s3-aws-1.0.0.tar.gz",
+			expected: "https://example.com/example-s3-aws-1.0.0.tar.gz",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ArchiveURL(tc.url, tc.format)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestArchiveFormatFromName(t *testing.T) {
+	t.Parallel()
+
+	for name, expected := range map[string]string{
+		"example-s3-aws-1.0.0.tar.gz":       "tar.gz",
+		"example-s3-aws-1.0.0.tar.xz":       "tar.xz",
+		"example-s3-aws-1.0.0.tar":          "tar",
+		"example-s3-aws-1.0.0.zip":          "zip",
+		"example-s3-aws-1.0.0.tgz":          "tgz",
+		"example-s3-aws-1.0.0.details.json": "",
+	} {
+		format, ok := ArchiveFormatFromName(name)
+		assert.Equal(t, expected != "", ok, name)
+		assert.Equal(t, expected, format, name)
+	}
+}
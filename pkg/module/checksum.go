@@ -0,0 +1,34 @@
+package module
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+// Checksum returns the h1 hash of the files of a module, which doesn't depend on the archive format or file metadata.
+// It follows the format of the dirhash package of Go modules, see https://pkg.go.dev/golang.org/x/mod/sumdb/dirhash#Hash1
+func Checksum(fsys fs.FS, files []string) (string, error) {
+	files = append([]string{}, files...)
+	sort.Strings(files)
+
+	summary := sha256.New()
+	for _, name := range files {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return "", err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(summary, "%x  %s\n", h.Sum(nil), name)
+	}
+
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
@@ -0,0 +1,35 @@
+package module
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksum(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"main.tf":             {Data: []byte(`variable "cidr" {}`), Mode: 0o644},
+		"modules/vpc/main.tf": {Data: []byte(`output "id" {}`), Mode: 0o755},
+	}
+
+	checksum, err := Checksum(fsys, []string{"modules/vpc/main.tf", "main.tf"})
+	assert.NoError(t, err)
+	assert.Regexp(t, `^h1:[A-Za-z0-9+/]{43}=$`, checksum)
+
+	// The order of the files and their metadata don't matter
+	fsys["main.tf"].Mode = 0o600
+	same, err := Checksum(fsys, []string{"main.tf", "modules/vpc/main.tf"})
+	assert.NoError(t, err)
+	assert.Equal(t, checksum, same)
+
+	fsys["main.tf"].Data = []byte(`variable "cidr" { default = "10.0.0.0/16" }`)
+	changed, err := Checksum(fsys, []string{"main.tf", "modules/vpc/main.tf"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, checksum, changed)
+
+	_, err = Checksum(fsys, []string{"missing.tf"})
+	assert.Error(t, err)
+}
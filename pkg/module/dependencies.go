@@ -0,0 +1,142 @@
+package module
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// ModuleDependency is an edge from a module version to a registry module it calls.
+type ModuleDependency struct {
+	// Hostname of the registry, which is empty for the public Terraform registry
+	Hostname  string `json:"hostname,omitempty"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Provider  string `json:"provider"`
+	// Version is the version constraint of the module call
+	Version string `json:"version,omitempty"`
+	// Path is the path of the submodule with the module call, which is empty for the root module
+	Path string `json:"path"`
+}
+
+// ModuleDependent is a module version that calls another module.
+type ModuleDependent struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Provider  string `json:"provider"`
+	Version   string `json:"version"`
+	// VersionConstraint is the version constraint of the module call
+	VersionConstraint string `json:"version_constraint,omitempty"`
+	// Hostname is the registry hostname in the source of the module call
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// RegistrySource is the address of a module in a module registry.
+// See https://developer.hashicorp.com/terraform/language/modules/sources#terraform-registry
+type RegistrySource struct {
+	Hostname  string
+	Namespace string
+	Name      string
+	Provider  string
+	Subdir    string
+}
+
+var (
+	registryPartPattern     = regexp.MustCompile(`^[0-9A-Za-z](?:[0-9A-Za-z-_]{0,62}[0-9A-Za-z])?$`)
+	registryProviderPattern = regexp.MustCompile(`^[0-9a-z]{1,64}$`)
+	registryHostnamePattern = regexp.MustCompile(`^[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*(?::[0-9]+)?$`)
+)
+
+// ParseRegistrySource parses a module source in the form of [<hostname>/]<namespace>/<name>/<provider>[//<subdir>].
+// It returns false for all other sources like local paths, git repositories or archives.
+func ParseRegistrySource(source string) (*RegistrySource, bool) {
+	if source == "" || strings.HasPrefix(source, ".") || strings.Contains(source, "::") || strings.Contains(source, "://") {
+		return nil, false
+	}
+
+	addr, subdir, _ := strings.Cut(source, "//")
+	parts := strings.Split(addr, "/")
+
+	s := &RegistrySource{Subdir: subdir}
+	switch len(parts) {
+	case 3:
+	case 4:
+		// GitHub and Bitbucket shorthands aren't registry sources, e.g. github.com/acme/terraform-aws-vpc
+		if !strings.Contains(parts[0], ".") && !strings.Contains(parts[0], ":") && parts[0] != "localhost" {
+			return nil, false
+		}
+		if !registryHostnamePattern.MatchString(parts[0]) {
+			return nil, false
+		}
+		s.Hostname = strings.ToLower(parts[0])
+		parts = parts[1:]
+	default:
+		return nil, false
+	}
+
+	if parts[0] == "github.com" || parts[0] == "bitbucket.org" {
+		return nil, false
+	}
+	if !registryPartPattern.MatchString(parts[0]) || !registryPartPattern.MatchString(parts[1]) || !registryProviderPattern.MatchString(parts[2]) {
+		return nil, false
+	}
+	s.Namespace, s.Name, s.Provider = parts[0], parts[1], parts[2]
+
+	return s, true
+}
+
+// Dependencies returns the registry modules that are called by the root module and the submodules.
+// Module calls of examples aren't dependencies of the module.
+func (d *Details) Dependencies() []ModuleDependency {
+	dependencies := []ModuleDependency{}
+	for _, s := range append([]Submodule{d.Root}, d.Submodules...) {
+		for _, dep := range s.Dependencies {
+			source, ok := ParseRegistrySource(dep.Source)
+			if !ok {
+				continue
+			}
+			dependencies = append(dependencies, ModuleDependency{
+				Hostname:  source.Hostname,
+				Namespace: source.Namespace,
+				Name:      source.Name,
+				Provider:  source.Provider,
+				Version:   dep.Version,
+				Path:      s.Path,
+			})
+		}
+	}
+
+	sort.SliceStable(dependencies, func(i, j int) bool {
+		a, b := dependencies[i], dependencies[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Namespace+"/"+a.Name+"/"+a.Provider < b.Namespace+"/"+b.Name+"/"+b.Provider
+	})
+	return dependencies
+}
+
+// SortModuleDependents sorts the dependents by module and by version, with the newest version first
+func SortModuleDependents(dependents []ModuleDependent) {
+	sort.SliceStable(dependents, func(i, j int) bool {
+		a, b := dependents[i], dependents[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		} else if a.Name != b.Name {
+			return a.Name < b.Name
+		} else if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		} else if a.Version == b.Version {
+			return a.Hostname < b.Hostname
+		}
+
+		va, errA := version.NewVersion(a.Version)
+		vb, errB := version.NewVersion(b.Version)
+		if errA != nil || errB != nil {
+			return a.Version > b.Version
+		}
+		return va.GreaterThan(vb)
+	})
+}
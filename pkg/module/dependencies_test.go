@@ -0,0 +1,78 @@
+package module
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRegistrySource(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		source   string
+		expected *RegistrySource
+	}{
+		{
+			name:     "public registry",
+			source:   "terraform-aws-modules/vpc/aws",
+			expected: &RegistrySource{Namespace: "terraform-aws-modules", Name: "vpc", Provider: "aws"},
+		},
+		{
+			name:     "private registry with a subdirectory",
+			source:   "registry.example.com/networking/vpc/aws//modules/endpoints",
+			expected: &RegistrySource{Hostname: "registry.example.com", Namespace: "networking", Name: "vpc", Provider: "aws", Subdir: "modules/endpoints"},
+		},
+		{
+			name:     "registry hostname with a port",
+			source:   "localhost:5601/networking/vpc/aws",
+			expected: &RegistrySource{Hostname: "localhost:5601", Namespace: "networking", Name: "vpc", Provider: "aws"},
+		},
+		{name: "local path", source: "../vpc"},
+		{name: "git source", source: "git::https://example.com/vpc.git?ref=v1.2.0"},
+		{name: "github shorthand", source: "github.com/acme/terraform-aws-vpc"},
+		{name: "url", source: "https://example.com/vpc-module.zip"},
+		{name: "too many parts", source: "registry.example.com/networking/vpc/aws/extra"},
+		{name: "invalid provider", source: "networking/vpc/AWS"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			source, ok := ParseRegistrySource(tc.source)
+			assert.Equal(t, tc.expected != nil, ok)
+			assert.Equal(t, tc.expected, source)
+		})
+	}
+}
+
+func TestDetailsDependencies(t *testing.T) {
+	t.Parallel()
+
+	details := &Details{
+		Root: Submodule{
+			Dependencies: []Dependency{
+				{Name: "vpc", Source: "registry.example.com/networking/vpc/aws", Version: "~> 1.0"},
+				{Name: "local", Source: "./modules/local"},
+			},
+		},
+		Submodules: []Submodule{
+			{
+				Path:         "modules/endpoints",
+				Dependencies: []Dependency{{Name: "labels", Source: "acme/labels/null"}},
+			},
+		},
+		Examples: []Submodule{
+			{
+				Path:         "examples/basic",
+				Dependencies: []Dependency{{Name: "self", Source: "registry.example.com/networking/service/aws"}},
+			},
+		},
+	}
+
+	assert.Equal(t, []ModuleDependency{
+		{Hostname: "registry.example.com", Namespace: "networking", Name: "vpc", Provider: "aws", Version: "~> 1.0"},
+		{Namespace: "acme", Name: "labels", Provider: "null", Path: "modules/endpoints"},
+	}, details.Dependencies())
+}
@@ -0,0 +1,366 @@
+package module
+
+import (
+	"encoding/json"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+const (
+	// submodulesDir and examplesDir follow the standard module structure.
+	// See https://developer.hashicorp.com/terraform/language/modules/develop/structure
+	submodulesDir = "modules"
+	examplesDir   = "examples"
+)
+
+// Details describes what a module version takes and produces.
+// It is extracted from the Terraform files of the module when it's uploaded.
+type Details struct {
+	Root       Submodule   `json:"root"`
+	Submodules []Submodule `json:"submodules"`
+	Examples   []Submodule `json:"examples"`
+	// Checksum is the h1 hash of the packaged files, which detects changed content without a version bump
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// Submodule describes the root module, a nested module or an example.
+type Submodule struct {
+	Path                 string               `json:"path"`
+	Inputs               []Input              `json:"inputs"`
+	Outputs              []Output             `json:"outputs"`
+	RequiredVersion      string               `json:"required_version,omitempty"`
+	ProviderDependencies []ProviderDependency `json:"provider_dependencies"`
+	Dependencies         []Dependency         `json:"dependencies"`
+	Resources            []Resource           `json:"resources"`
+}
+
+// Input is a variable of a module.
+type Input struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Default is the JSON representation of the default value. It's empty in case the default can't be evaluated statically.
+	Default   json.RawMessage `json:"default,omitempty"`
+	Required  bool            `json:"required"`
+	Sensitive bool            `json:"sensitive,omitempty"`
+}
+
+// Output is an output value of a module.
+type Output struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Sensitive   bool   `json:"sensitive,omitempty"`
+}
+
+// ProviderDependency is a provider in the required_providers block.
+type ProviderDependency struct {
+	Name    string `json:"name"`
+	Source  string `json:"source,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Dependency is a module call.
+type Dependency struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Version string `json:"version,omitempty"`
+}
+
+// Resource is a managed resource of a module.
+type Resource struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+var (
+	fileSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "terraform"},
+			{Type: "variable", LabelNames: []string{"name"}},
+			{Type: "output", LabelNames: []string{"name"}},
+			{Type: "module", LabelNames: []string{"name"}},
+			{Type: "resource", LabelNames: []string{"type", "name"}},
+		},
+	}
+	terraformSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "required_version"}},
+		Blocks:     []hcl.BlockHeaderSchema{{Type: "required_providers"}},
+	}
+	variableSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "type"}, {Name: "default"}, {Name: "description"}, {Name: "sensitive"}},
+	}
+	outputSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "description"}, {Name: "sensitive"}},
+	}
+	moduleSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "source"}, {Name: "version"}},
+	}
+)
+
+// ParseDetails extracts the details of the module in the root of fsys, as well as its submodules and examples.
+// The files are the slash-separated names of the packaged files, so that excluded files aren't part of the details.
+func ParseDetails(fsys fs.FS, files []string) (*Details, error) {
+	// The Terraform files are grouped by their directory
+	dirs := map[string][]string{}
+	for _, name := range files {
+		if isTerraformFile(path.Base(name)) {
+			dir := path.Dir(name)
+			dirs[dir] = append(dirs[dir], name)
+		}
+	}
+
+	details := &Details{
+		Root:       *newSubmodule(""),
+		Submodules: []Submodule{},
+		Examples:   []Submodule{},
+	}
+	if err := details.Root.parse(fsys, dirs["."]); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for dir := range dirs {
+		names = append(names, dir)
+	}
+	sort.Strings(names)
+	for _, dir := range names {
+		var submodules *[]Submodule
+		switch path.Dir(dir) {
+		case submodulesDir:
+			submodules = &details.Submodules
+		case examplesDir:
+			submodules = &details.Examples
+		default:
+			continue
+		}
+
+		s := newSubmodule(dir)
+		if err := s.parse(fsys, dirs[dir]); err != nil {
+			return nil, err
+		}
+		*submodules = append(*submodules, *s)
+	}
+
+	return details, nil
+}
+
+func newSubmodule(dir string) *Submodule {
+	return &Submodule{
+		Path:                 dir,
+		Inputs:               []Input{},
+		Outputs:              []Output{},
+		ProviderDependencies: []ProviderDependency{},
+		Dependencies:         []Dependency{},
+		Resources:            []Resource{},
+	}
+}
+
+// parse parses the Terraform files of the submodule
+func (s *Submodule) parse(fsys fs.FS, files []string) error {
+	sort.Strings(files)
+	for _, name := range files {
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		if strings.HasSuffix(name, ".json") {
+			file, diags = hcljson.Parse(src, name)
+		} else {
+			file, diags = hclsyntax.ParseConfig(src, name, hcl.InitialPos)
+		}
+		if diags.HasErrors() {
+			return diags
+		}
+
+		if err := s.decode(file.Body, src); err != nil {
+			return err
+		}
+	}
+
+	sort.Slice(s.ProviderDependencies, func(i, j int) bool {
+		return s.ProviderDependencies[i].Name < s.ProviderDependencies[j].Name
+	})
+	return nil
+}
+
+func isTerraformFile(name string) bool {
+	// Override files are merged into other files by Terraform, which is out of scope
+	if strings.HasSuffix(name, "_override.tf") || strings.HasSuffix(name, "_override.tf.json") || name == "override.tf" || name == "override.tf.json" {
+		return false
+	}
+	return strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")
+}
+
+func (s *Submodule) decode(body hcl.Body, src []byte) error {
+	content, _, diags := body.PartialContent(fileSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	for _, block := range content.Blocks {
+		var err error
+		switch block.Type {
+		case "terraform":
+			err = s.decodeTerraform(block)
+		case "variable":
+			err = s.decodeVariable(block, src)
+		case "output":
+			err = s.decodeOutput(block)
+		case "module":
+			err = s.decodeModule(block)
+		case "resource":
+			s.Resources = append(s.Resources, Resource{Type: block.Labels[0], Name: block.Labels[1]})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Submodule) decodeTerraform(block *hcl.Block) error {
+	content, _, diags := block.Body.PartialContent(terraformSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	if attr, ok := content.Attributes["required_version"]; ok {
+		s.RequiredVersion = stringValue(attr.Expr)
+	}
+
+	for _, b := range content.Blocks {
+		attrs, diags := b.Body.JustAttributes()
+		if diags.HasErrors() {
+			return diags
+		}
+
+		for name, attr := range attrs {
+			p := ProviderDependency{Name: name}
+
+			// The legacy syntax only specifies the version constraints, e.g. aws = "~> 5.0"
+			if v := stringValue(attr.Expr); v != "" {
+				p.Version = v
+				s.ProviderDependencies = append(s.ProviderDependencies, p)
+				continue
+			}
+
+			// The object can't be evaluated as a whole, as configuration_aliases contains references
+			pairs, diags := hcl.ExprMap(attr.Expr)
+			if diags.HasErrors() {
+				return diags
+			}
+			for _, pair := range pairs {
+				switch stringValue(pair.Key) {
+				case "source":
+					p.Source = stringValue(pair.Value)
+				case "version":
+					p.Version = stringValue(pair.Value)
+				}
+			}
+			s.ProviderDependencies = append(s.ProviderDependencies, p)
+		}
+	}
+
+	return nil
+}
+
+func (s *Submodule) decodeVariable(block *hcl.Block, src []byte) error {
+	content, _, diags := block.Body.PartialContent(variableSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	input := Input{Name: block.Labels[0], Required: true}
+	if attr, ok := content.Attributes["type"]; ok {
+		// Type constraints are keywords like list(string), which can't be evaluated.
+		// The JSON syntax uses strings instead.
+		if input.Type = stringValue(attr.Expr); input.Type == "" {
+			input.Type = string(attr.Expr.Range().SliceBytes(src))
+		}
+	}
+	if attr, ok := content.Attributes["default"]; ok {
+		input.Required = false
+		if v, diags := attr.Expr.Value(nil); !diags.HasErrors() && v.IsNull() {
+			input.Default = json.RawMessage("null")
+		} else if !diags.HasErrors() && v.IsWhollyKnown() {
+			if b, err := ctyjson.Marshal(v, v.Type()); err == nil {
+				input.Default = b
+			}
+		}
+	}
+	if attr, ok := content.Attributes["description"]; ok {
+		input.Description = stringValue(attr.Expr)
+	}
+	if attr, ok := content.Attributes["sensitive"]; ok {
+		input.Sensitive = boolValue(attr.Expr)
+	}
+
+	s.Inputs = append(s.Inputs, input)
+	return nil
+}
+
+func (s *Submodule) decodeOutput(block *hcl.Block) error {
+	content, _, diags := block.Body.PartialContent(outputSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	output := Output{Name: block.Labels[0]}
+	if attr, ok := content.Attributes["description"]; ok {
+		output.Description = stringValue(attr.Expr)
+	}
+	if attr, ok := content.Attributes["sensitive"]; ok {
+		output.Sensitive = boolValue(attr.Expr)
+	}
+
+	s.Outputs = append(s.Outputs, output)
+	return nil
+}
+
+func (s *Submodule) decodeModule(block *hcl.Block) error {
+	content, _, diags := block.Body.PartialContent(moduleSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	dependency := Dependency{Name: block.Labels[0]}
+	if attr, ok := content.Attributes["source"]; ok {
+		dependency.Source = stringValue(attr.Expr)
+	}
+	if attr, ok := content.Attributes["version"]; ok {
+		dependency.Version = stringValue(attr.Expr)
+	}
+
+	s.Dependencies = append(s.Dependencies, dependency)
+	return nil
+}
+
+// stringValue returns the value of a static string expression, or an empty string otherwise
+func stringValue(expr hcl.Expression) string {
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || v.IsNull() || !v.IsKnown() || v.Type() != cty.String {
+		return ""
+	}
+	return v.AsString()
+}
+
+// boolValue returns the value of a static bool expression, or false otherwise
+func boolValue(expr hcl.Expression) bool {
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || v.IsNull() || !v.IsKnown() || v.Type() != cty.Bool {
+		return false
+	}
+	return v.True()
+}
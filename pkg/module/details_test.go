@@ -0,0 +1,117 @@
+package module
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDetails(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"boring-registry.hcl": {Data: []byte(`metadata {}`)},
+		"main.tf": {Data: []byte(`
+terraform {
+  required_version = ">= 1.3"
+
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      version               = "~> 5.0"
+      configuration_aliases = [aws.replica]
+    }
+    random = "~> 3.0"
+  }
+}
+
+resource "aws_s3_bucket" "this" {
+  bucket = var.name
+}
+
+module "replica" {
+  source  = "terraform-aws-modules/s3-bucket/aws"
+  version = "4.1.0"
+}
+`)},
+		"variables.tf": {Data: []byte(`
+variable "name" {
+  type        = string
+  description = "Name of the bucket"
+}
+
+variable "tags" {
+  type    = map(string)
+  default = { "team" = "platform" }
+}
+
+variable "kms_key_id" {
+  type      = string
+  default   = null
+  sensitive = true
+}
+`)},
+		"outputs.tf.json": {Data: []byte(`{"output": {"arn": {"value": "${aws_s3_bucket.this.arn}", "description": "ARN of the bucket"}}}`)},
+		"modules/notification/main.tf": {Data: []byte(`
+variable "topic" {
+  type = string
+}
+`)},
+		"modules/README.md":      {Data: []byte(`# Submodules`)},
+		"modules/empty/.gitkeep": {Data: []byte{}},
+		"examples/complete/main.tf": {Data: []byte(`
+module "bucket" {
+  source = "../../"
+  name   = "example"
+}
+`)},
+		// Files that are excluded from the package aren't part of the details
+		"test.tf":                  {Data: []byte(`resource "null_resource" "test" {}`)},
+		"modules/internal/main.tf": {Data: []byte(`variable "internal" {}`)},
+	}
+	files := []string{
+		"boring-registry.hcl", "examples/complete/main.tf", "main.tf", "modules/README.md", "modules/empty/.gitkeep",
+		"modules/notification/main.tf", "outputs.tf.json", "variables.tf",
+	}
+
+	details, err := ParseDetails(fsys, files)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal("", details.Root.Path)
+	assert.Equal(">= 1.3", details.Root.RequiredVersion)
+	assert.Equal([]ProviderDependency{
+		{Name: "aws", Source: "hashicorp/aws", Version: "~> 5.0"},
+		{Name: "random", Version: "~> 3.0"},
+	}, details.Root.ProviderDependencies)
+	assert.Equal([]Resource{{Name: "this", Type: "aws_s3_bucket"}}, details.Root.Resources)
+	assert.Equal([]Dependency{{Name: "replica", Source: "terraform-aws-modules/s3-bucket/aws", Version: "4.1.0"}}, details.Root.Dependencies)
+	assert.Equal([]Output{{Name: "arn", Description: "ARN of the bucket"}}, details.Root.Outputs)
+	assert.Equal([]Input{
+		{Name: "name", Type: "string", Description: "Name of the bucket", Required: true},
+		{Name: "tags", Type: "map(string)", Default: json.RawMessage(`{"team":"platform"}`)},
+		{Name: "kms_key_id", Type: "string", Default: json.RawMessage(`null`), Sensitive: true},
+	}, details.Root.Inputs)
+
+	if assert.Len(details.Submodules, 1) {
+		assert.Equal("modules/notification", details.Submodules[0].Path)
+		assert.Equal([]Input{{Name: "topic", Type: "string", Required: true}}, details.Submodules[0].Inputs)
+	}
+	if assert.Len(details.Examples, 1) {
+		assert.Equal("examples/complete", details.Examples[0].Path)
+		assert.Equal([]Dependency{{Name: "bucket", Source: "../../"}}, details.Examples[0].Dependencies)
+	}
+}
+
+func TestParseDetails_invalidSyntax(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseDetails(fstest.MapFS{
+		"main.tf": {Data: []byte(`variable "name" {`)},
+	}, []string{"main.tf"})
+	assert.Error(t, err)
+}
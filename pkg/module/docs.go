@@ -0,0 +1,210 @@
+package module
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	readmeFileName    = "README.md"
+	changelogFileName = "CHANGELOG.md"
+
+	// maxDocsFileSize limits the size of a single file in the documentation bundle
+	maxDocsFileSize = 512 << 10
+)
+
+// Docs is the documentation bundle of a module version.
+// It's captured from the files of the module archive when the module is uploaded.
+type Docs struct {
+	Readme    string        `json:"readme,omitempty"`
+	Changelog string        `json:"changelog,omitempty"`
+	Examples  []ExampleDocs `json:"examples"`
+}
+
+// ExampleDocs is the documentation of a directory in the examples directory.
+type ExampleDocs struct {
+	// Path of the example relative to the module root, e.g. examples/complete
+	Path   string `json:"path"`
+	Readme string `json:"readme,omitempty"`
+	// Files are the Terraform files of the example
+	Files []DocsFile `json:"files"`
+}
+
+// DocsFile is a file of the documentation bundle.
+type DocsFile struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// ParseDocs captures the README.md and CHANGELOG.md in the module root and the examples from the files in fsys.
+// The files are the slash-separated names of the packaged files, so that excluded files aren't part of the bundle.
+// File names are matched case-insensitively.
+func ParseDocs(fsys fs.FS, files []string) (*Docs, error) {
+	docs := &Docs{Examples: []ExampleDocs{}}
+	examples := map[string]*ExampleDocs{}
+
+	read := func(name string) (string, error) {
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			return "", err
+		}
+		if info.Size() > maxDocsFileSize {
+			return "", fmt.Errorf("%s exceeds the maximum size of %d bytes for documentation", name, maxDocsFileSize)
+		}
+		b, err := fs.ReadFile(fsys, name)
+		return string(b), err
+	}
+
+	for _, name := range files {
+		var err error
+		dir, base := path.Split(name)
+		switch {
+		case dir == "" && strings.EqualFold(base, readmeFileName):
+			docs.Readme, err = read(name)
+
+		case dir == "" && strings.EqualFold(base, changelogFileName):
+			docs.Changelog, err = read(name)
+
+		case path.Dir(path.Clean(dir)) == examplesDir:
+			// Only files directly in an example directory are captured, e.g. examples/complete/main.tf
+			example := path.Clean(dir)
+			if _, ok := examples[example]; !ok {
+				examples[example] = &ExampleDocs{Path: example, Files: []DocsFile{}}
+			}
+
+			if strings.EqualFold(base, readmeFileName) {
+				examples[example].Readme, err = read(name)
+			} else if isTerraformFile(base) {
+				var content string
+				if content, err = read(name); err == nil {
+					examples[example].Files = append(examples[example].Files, DocsFile{Name: base, Content: content})
+				}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, e := range examples {
+		if e.Readme == "" && len(e.Files) == 0 {
+			continue
+		}
+		sort.Slice(e.Files, func(i, j int) bool { return e.Files[i].Name < e.Files[j].Name })
+		docs.Examples = append(docs.Examples, *e)
+	}
+	sort.Slice(docs.Examples, func(i, j int) bool { return docs.Examples[i].Path < docs.Examples[j].Path })
+
+	return docs, nil
+}
+
+// Example returns the example with the name of its directory, e.g. complete for examples/complete
+func (d *Docs) Example(name string) (*ExampleDocs, bool) {
+	for i := range d.Examples {
+		if d.Examples[i].Path == path.Join(examplesDir, name) {
+			return &d.Examples[i], true
+		}
+	}
+	return nil, false
+}
+
+// Markdown returns the README of the example followed by its Terraform files as code blocks
+func (e *ExampleDocs) Markdown() string {
+	var b strings.Builder
+	if e.Readme != "" {
+		b.WriteString(strings.TrimSpace(e.Readme))
+	} else {
+		fmt.Fprintf(&b, "# %s", e.Path)
+	}
+
+	for _, f := range e.Files {
+		fence := "```"
+		for strings.Contains(f.Content, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&b, "\n\n## %s\n\n%shcl\n%s\n%s", f.Name, fence, strings.TrimRight(f.Content, "\n"), fence)
+	}
+	return b.String() + "\n"
+}
+
+var docsPageTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{ .Title }} - {{ .Module }}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.5; color: #1f2328; max-width: 60rem; margin: 0 auto; padding: 1rem 2rem; }
+nav { border-bottom: 1px solid #d1d9e0; padding-bottom: .5rem; margin-bottom: 1rem; }
+nav a { margin-right: 1rem; }
+pre { background: #f6f8fa; padding: 1rem; overflow: auto; border-radius: 6px; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 85%; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d1d9e0; padding: .25rem .75rem; }
+blockquote { color: #59636e; border-left: .25rem solid #d1d9e0; margin: 0; padding: 0 1rem; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+<nav>
+<strong>{{ .Module }}</strong>
+{{ if .Readme }}<a href="{{ .Base }}/../docs">README</a>{{ end }}
+{{ if .Changelog }}<a href="{{ .Base }}/changelog">CHANGELOG</a>{{ end }}
+{{ range .Examples }}<a href="{{ $.Base }}/examples/{{ . }}">{{ . }}</a>
+{{ end }}<a href="?format=markdown">Markdown</a>
+</nav>
+<main>
+{{ .Content }}
+</main>
+</body>
+</html>
+`))
+
+// docsContentSecurityPolicy only allows the inline style of the docs page and images, so that the page can't run scripts,
+// even if the sanitizer of the rendered markdown is bypassed
+const docsContentSecurityPolicy = "default-src 'none'; img-src http: https:; style-src 'unsafe-inline'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// renderDocsPage renders a document of the bundle as HTML page with links to the other documents
+func renderDocsPage(w http.ResponseWriter, res docsResponse) error {
+	w.Header().Set("Content-Security-Policy", docsContentSecurityPolicy)
+
+	// Links are relative, as the registry can be served below any path.
+	// Base is the docs path relative to the current document.
+	base := "docs"
+	switch res.document {
+	case docsChangelog:
+		base = "."
+	case docsReadme:
+	default:
+		base = ".."
+	}
+
+	examples := make([]string, 0, len(res.docs.Examples))
+	for _, e := range res.docs.Examples {
+		examples = append(examples, path.Base(e.Path))
+	}
+
+	return docsPageTemplate.Execute(w, struct {
+		Module    string
+		Title     string
+		Base      string
+		Readme    bool
+		Changelog bool
+		Examples  []string
+		Content   template.HTML
+	}{
+		Module:    res.module,
+		Title:     res.title,
+		Base:      base,
+		Readme:    res.docs.Readme != "",
+		Changelog: res.docs.Changelog != "",
+		Examples:  examples,
+		// RenderMarkdown omits raw HTML and sanitizes the rendered HTML
+		Content: template.HTML(RenderMarkdown(res.markdown)),
+	})
+}
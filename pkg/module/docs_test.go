@@ -0,0 +1,166 @@
+package module
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDocs(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"readme.md":                       {Data: []byte("# VPC")},
+		"CHANGELOG.md":                    {Data: []byte("## 1.0.0")},
+		"main.tf":                         {Data: []byte(`resource "aws_vpc" "this" {}`)},
+		"modules/nat/README.md":           {Data: []byte("# NAT")},
+		"examples/complete/README.md":     {Data: []byte("# Complete")},
+		"examples/complete/main.tf":       {Data: []byte(`module "vpc" {}`)},
+		"examples/complete/outputs.tf":    {Data: []byte(`output "id" {}`)},
+		"examples/complete/files/main.tf": {Data: []byte(`# nested`)},
+		"examples/simple/main.tf":         {Data: []byte(`module "vpc" {}`)},
+		"examples/excluded/main.tf":       {Data: []byte(`module "vpc" {}`)},
+	}
+	files := []string{
+		"readme.md",
+		"CHANGELOG.md",
+		"main.tf",
+		"modules/nat/README.md",
+		"examples/simple/main.tf",
+		"examples/complete/README.md",
+		"examples/complete/outputs.tf",
+		"examples/complete/main.tf",
+		"examples/complete/files/main.tf",
+	}
+
+	docs, err := ParseDocs(fsys, files)
+	assert.NoError(err)
+	assert.Equal(&Docs{
+		Readme:    "# VPC",
+		Changelog: "## 1.0.0",
+		Examples: []ExampleDocs{
+			{
+				Path:   "examples/complete",
+				Readme: "# Complete",
+				Files: []DocsFile{
+					{Name: "main.tf", Content: `module "vpc" {}`},
+					{Name: "outputs.tf", Content: `output "id" {}`},
+				},
+			},
+			{
+				Path:  "examples/simple",
+				Files: []DocsFile{{Name: "main.tf", Content: `module "vpc" {}`}},
+			},
+		},
+	}, docs)
+
+	example, ok := docs.Example("simple")
+	assert.True(ok)
+	assert.Equal("# examples/simple\n\n## main.tf\n\n```hcl\nmodule \"vpc\" {}\n```\n", example.Markdown())
+
+	_, ok = docs.Example("excluded")
+	assert.False(ok)
+}
+
+func TestMakeHandler_Docs(t *testing.T) {
+	storage := NewInmemStorage()
+	assert.NoError(t, storage.UploadModuleDocs(context.Background(), "acme", "vpc", "aws", "1.0.0", &Docs{
+		Readme: "# VPC\n\n<img src=x onerror=alert(1)>",
+		Examples: []ExampleDocs{
+			{Path: "examples/simple", Files: []DocsFile{{Name: "main.tf", Content: `module "vpc" {}`}}},
+		},
+	}))
+
+	metrics := o11y.NewMetrics(nil)
+	handler := MakeHandler(
+		NewService(storage, nil),
+		func(next endpoint.Endpoint) endpoint.Endpoint { return next },
+		metrics.Module,
+		o11y.NewMiddleware(metrics.Http),
+		httptransport.ServerErrorEncoder(ErrorEncoder),
+	)
+
+	testCases := []struct {
+		name                string
+		path                string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        []string
+	}{
+		{
+			name:                "readme as html",
+			path:                "/acme/vpc/aws/1.0.0/docs",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody: []string{
+				`<h1 id="vpc">VPC</h1>`,
+				`<a href="docs/examples/simple">simple</a>`,
+			},
+		},
+		{
+			name:                "readme as markdown",
+			path:                "/acme/vpc/aws/1.0.0/docs?format=markdown",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/markdown; charset=utf-8",
+			expectedBody:        []string{"# VPC\n\n<img src=x onerror=alert(1)>"},
+		},
+		{
+			name:                "example",
+			path:                "/acme/vpc/aws/1.0.0/docs/examples/simple",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody:        []string{"<code class=\"language-hcl\">module &#34;vpc&#34; {}\n</code>"},
+		},
+		{
+			name:           "missing changelog",
+			path:           "/acme/vpc/aws/1.0.0/docs/changelog",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unknown example",
+			path:           "/acme/vpc/aws/1.0.0/docs/examples/unknown",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "version without docs",
+			path:           "/acme/vpc/aws/0.9.0/docs",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid format",
+			path:           "/acme/vpc/aws/1.0.0/docs?format=pdf",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedContentType != "" {
+				assert.Equal(t, tc.expectedContentType, rec.Header().Get("Content-Type"))
+			}
+			if tc.expectedContentType == "text/html; charset=utf-8" {
+				assert.Equal(t, docsContentSecurityPolicy, rec.Header().Get("Content-Security-Policy"))
+			}
+			body, _ := io.ReadAll(rec.Body)
+			for _, expected := range tc.expectedBody {
+				assert.True(t, strings.Contains(string(body), expected), "expected %q in %s", expected, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package module
+
+import (
+	"context"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+)
+
+// DownloadRecorder counts the downloads of module versions
+type DownloadRecorder interface {
+	RecordModuleDownload(namespace, name, provider, version string)
+}
+
+type downloadTrackingMiddleware struct {
+	recorder DownloadRecorder
+	Service
+}
+
+func (mw downloadTrackingMiddleware) GetModule(ctx context.Context, namespace, name, provider, version string) (core.Module, error) {
+	module, err := mw.Service.GetModule(ctx, namespace, name, provider, version)
+	if err == nil {
+		mw.recorder.RecordModuleDownload(namespace, name, provider, version)
+	}
+	return module, err
+}
+
+// DownloadTrackingMiddleware is a Service middleware that records successful downloads of module versions
+func DownloadTrackingMiddleware(recorder DownloadRecorder) Middleware {
+	return func(next Service) Service {
+		return &downloadTrackingMiddleware{
+			recorder: recorder,
+			Service:  next,
+		}
+	}
+}
@@ -2,7 +2,9 @@ package module
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/boring-registry/boring-registry/pkg/core"
 	o11y "github.com/boring-registry/boring-registry/pkg/observability"
 
 	"github.com/go-kit/kit/endpoint"
@@ -16,7 +18,9 @@ type listRequest struct {
 }
 
 type listResponseVersion struct {
-	Version string `json:"version,omitempty"`
+	Version     string            `json:"version,omitempty"`
+	Deprecation *core.Deprecation `json:"deprecation,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
 }
 
 type listResponseModule struct {
@@ -45,9 +49,14 @@ func listEndpoint(svc Service, metrics *o11y.ModuleMetrics) endpoint.Endpoint {
 		var versions []listResponseVersion
 
 		for _, module := range res {
-			versions = append(versions, listResponseVersion{
+			version := listResponseVersion{
 				Version: module.Version,
-			})
+			}
+			if module.Annotations != nil {
+				version.Deprecation = module.Annotations.Deprecation
+				version.Labels = module.Annotations.Labels
+			}
+			versions = append(versions, version)
 		}
 
 		return listResponse{
@@ -90,3 +99,135 @@ func downloadEndpoint(svc Service, metrics *o11y.ModuleMetrics) endpoint.Endpoin
 		}, nil
 	}
 }
+
+func detailsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(downloadRequest)
+
+		return svc.GetModuleDetails(ctx, req.namespace, req.name, req.provider, req.version)
+	}
+}
+
+type dependenciesResponse struct {
+	Dependencies []ModuleDependency `json:"dependencies"`
+}
+
+func dependenciesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(downloadRequest)
+
+		res, err := svc.GetModuleDependencies(ctx, req.namespace, req.name, req.provider, req.version)
+		if err != nil {
+			return nil, err
+		}
+
+		return dependenciesResponse{Dependencies: res}, nil
+	}
+}
+
+type dependentsResponse struct {
+	Dependents []ModuleDependent `json:"dependents"`
+}
+
+func dependentsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listRequest)
+
+		res, err := svc.ListModuleDependents(ctx, req.namespace, req.name, req.provider)
+		if err != nil {
+			return nil, err
+		}
+
+		return dependentsResponse{Dependents: res}, nil
+	}
+}
+
+// downloadsSummaryResponse is the downloads summary in the format of the public registry
+type downloadsSummaryResponse struct {
+	Data downloadsSummaryData `json:"data"`
+}
+
+type downloadsSummaryData struct {
+	Type       string               `json:"type"`
+	ID         string               `json:"id"`
+	Attributes core.DownloadSummary `json:"attributes"`
+}
+
+func downloadsSummaryEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listRequest)
+
+		res, err := svc.GetModuleDownloadsSummary(ctx, req.namespace, req.name, req.provider)
+		if err != nil {
+			return nil, err
+		}
+
+		return downloadsSummaryResponse{
+			Data: downloadsSummaryData{
+				Type:       "module-downloads-summary",
+				ID:         fmt.Sprintf("%s/%s/%s", req.namespace, req.name, req.provider),
+				Attributes: *res,
+			},
+		}, nil
+	}
+}
+
+const (
+	docsFormatHTML     = "html"
+	docsFormatMarkdown = "markdown"
+
+	docsReadme    = "readme"
+	docsChangelog = "changelog"
+)
+
+type docsRequest struct {
+	downloadRequest
+	// document is the readme, the changelog or the name of an example
+	document string
+	example  string
+	format   string
+}
+
+type docsResponse struct {
+	module   string
+	document string
+	title    string
+	markdown string
+	format   string
+	docs     *Docs
+}
+
+func docsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(docsRequest)
+
+		docs, err := svc.GetModuleDocs(ctx, req.namespace, req.name, req.provider, req.version)
+		if err != nil {
+			return nil, err
+		}
+
+		res := docsResponse{
+			module:   fmt.Sprintf("%s/%s/%s %s", req.namespace, req.name, req.provider, req.version),
+			document: req.document,
+			format:   req.format,
+			docs:     docs,
+		}
+		switch req.document {
+		case docsReadme:
+			res.title, res.markdown = readmeFileName, docs.Readme
+		case docsChangelog:
+			res.title, res.markdown = changelogFileName, docs.Changelog
+		default:
+			example, ok := docs.Example(req.example)
+			if !ok {
+				return nil, fmt.Errorf("%w: example %s", ErrModuleDocNotFound, req.example)
+			}
+			res.title, res.markdown = example.Path, example.Markdown()
+		}
+		if res.markdown == "" {
+			return nil, fmt.Errorf("%w: %s", ErrModuleDocNotFound, res.title)
+		}
+
+		return res, nil
+	}
+}
@@ -4,8 +4,16 @@ import "errors"
 
 var (
 	// Module errors
-	ErrModuleNotFound      = errors.New("failed to locate module")
-	ErrModuleUploadFailed  = errors.New("failed to upload module")
-	ErrModuleAlreadyExists = errors.New("module already exists")
-	ErrModuleListFailed    = errors.New("failed to list module versions")
+	ErrModuleNotFound        = errors.New("failed to locate module")
+	ErrModuleUploadFailed    = errors.New("failed to upload module")
+	ErrModuleAlreadyExists   = errors.New("module already exists")
+	ErrModuleListFailed      = errors.New("failed to list module versions")
+	ErrModuleDetailsNotFound = errors.New("failed to locate module details")
+	// ErrModuleDependenciesNotFound is returned for module versions that were uploaded before dependencies were stored
+	ErrModuleDependenciesNotFound = errors.New("failed to locate module dependencies")
+	// ErrModuleDocsNotFound is returned for module versions that were uploaded before documentation was captured
+	ErrModuleDocsNotFound = errors.New("failed to locate module documentation")
+	// ErrModuleDocNotFound is returned if the documentation bundle doesn't contain the requested document
+	ErrModuleDocNotFound = errors.New("failed to locate document")
+	ErrInvalidDocsFormat = errors.New("invalid documentation format")
 )
@@ -0,0 +1,194 @@
+package module
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the name of the file with patterns of files that are excluded from the module archive.
+// See https://developer.hashicorp.com/terraform/cli/cloud/settings#excluding-files-from-upload-with-terraformignore
+const IgnoreFileName = ".terraformignore"
+
+// DefaultExcludes are excluded from every module archive, unless they are included again with a negated pattern like !.terraform/modules/
+var DefaultExcludes = []string{
+	".git/",
+	".terraform/",
+	"*.tfstate",
+	"*.tfstate.*",
+	"*.tfvars",
+	"*.tfvars.json",
+}
+
+// FileFilter decides which files of a module are packaged into the archive
+type FileFilter struct {
+	include []pattern
+	rules   []pattern
+}
+
+type pattern struct {
+	re *regexp.Regexp
+	// negated patterns include files again that were excluded by a previous pattern
+	negated bool
+	// dirOnly patterns have a trailing slash and only match directories
+	dirOnly bool
+}
+
+// NewFileFilter returns the FileFilter for the module in the root of fsys.
+// The default excludes are followed by the patterns of the .terraformignore file and the exclude patterns of the spec.
+// If the spec has include patterns, only matching files are packaged.
+func NewFileFilter(fsys fs.FS, spec *Spec) (*FileFilter, error) {
+	lines := append([]string{}, DefaultExcludes...)
+
+	b, err := fs.ReadFile(fsys, IgnoreFileName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	f := &FileFilter{}
+	if spec != nil && spec.Package != nil {
+		lines = append(lines, spec.Package.Exclude...)
+		for _, s := range spec.Package.Include {
+			p, ok, err := compilePattern(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid include pattern %q: %w", s, err)
+			} else if ok {
+				f.include = append(f.include, p)
+			}
+		}
+	}
+
+	for _, s := range lines {
+		p, ok, err := compilePattern(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", s, err)
+		} else if ok {
+			f.rules = append(f.rules, p)
+		}
+	}
+
+	return f, nil
+}
+
+// Match reports whether the file with the slash-separated name should be packaged
+func (f *FileFilter) Match(name string) bool {
+	if len(f.include) > 0 {
+		included := false
+		for _, p := range f.include {
+			if p.matches(name) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+
+	// The last matching pattern wins, which allows re-including files with negated patterns
+	excluded := false
+	for _, p := range f.rules {
+		if p.matches(name) {
+			excluded = !p.negated
+		}
+	}
+	return !excluded
+}
+
+// matches reports whether the pattern matches the file or one of its parent directories
+func (p pattern) matches(name string) bool {
+	if !p.dirOnly && p.re.MatchString(name) {
+		return true
+	}
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if p.re.MatchString(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePattern compiles a pattern with the .gitignore syntax. It returns false for blank lines and comments.
+func compilePattern(s string) (pattern, bool, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.HasPrefix(s, "#") {
+		return pattern{}, false, nil
+	}
+
+	var p pattern
+	if p.negated = strings.HasPrefix(s, "!"); p.negated {
+		s = s[1:]
+	}
+	if p.dirOnly = strings.HasSuffix(s, "/"); p.dirOnly {
+		s = strings.TrimSuffix(s, "/")
+	}
+
+	// Patterns without a slash match at any depth, otherwise they are relative to the module root
+	anchored := strings.Contains(s, "/")
+	s = strings.TrimPrefix(s, "/")
+	if s == "" {
+		return pattern{}, false, errors.New("empty pattern")
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '*':
+			if i+1 < len(s) && s[i+1] == '*' {
+				i++
+				if i+1 < len(s) && s[i+1] == '/' {
+					// **/ matches zero or more directories
+					i++
+					re.WriteString("(?:.*/)?")
+				} else {
+					re.WriteString(".*")
+				}
+			} else {
+				re.WriteString("[^/]*")
+			}
+		case '?':
+			re.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return pattern{}, false, errors.New("unterminated character class")
+			}
+			class := s[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + class + "]")
+			i += end
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				re.WriteString(regexp.QuoteMeta(string(s[i])))
+			}
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+
+	var err error
+	if p.re, err = regexp.Compile(re.String()); err != nil {
+		return pattern{}, false, err
+	}
+	return p, true, nil
+}
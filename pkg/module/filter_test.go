@@ -0,0 +1,91 @@
+package module
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileFilter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		ignore      string
+		pkg         *Package
+		included    []string
+		excluded    []string
+		expectedErr bool
+	}{
+		{
+			name:     "default excludes",
+			included: []string{"main.tf", "modules/vpc/main.tf", ".terraformignore", "examples/terraform.tfvars.example"},
+			excluded: []string{".git/config", ".terraform/providers/foo", "terraform.tfstate", "terraform.tfstate.backup", "prod.tfvars", "examples/dev.tfvars.json", "modules/vpc/.terraform/modules/modules.json"},
+		},
+		{
+			name: "terraformignore",
+			ignore: `
+# comments and blank lines are ignored
+
+test/
+/docs/*.png
+**/fixtures/**
+*.log
+!important.log
+`,
+			included: []string{"main.tf", "modules/docs/diagram.png", "important.log", "testing/main.tf"},
+			excluded: []string{"test/main.tf", "docs/diagram.png", "modules/vpc/fixtures/a/b.tf", "fixtures/main.tf", "crash.log", "modules/vpc/crash.log"},
+		},
+		{
+			name:     "negated default exclude",
+			ignore:   "!.terraform/modules/",
+			included: []string{".terraform/modules/modules.json"},
+			excluded: []string{".terraform/providers/foo"},
+		},
+		{
+			name:     "character classes and single characters",
+			ignore:   "file[0-9].tf\nv?.tf\nx[!a].tf",
+			included: []string{"fileA.tf", "v10.tf", "xa.tf"},
+			excluded: []string{"file1.tf", "modules/file2.tf", "v1.tf", "xb.tf"},
+		},
+		{
+			name: "include and exclude in the spec",
+			pkg: &Package{
+				Include: []string{"*.tf", "modules/", "README.md"},
+				Exclude: []string{"modules/legacy/"},
+			},
+			included: []string{"main.tf", "README.md", "modules/vpc/main.tf", "modules/vpc/diagram.png"},
+			excluded: []string{"CHANGELOG.md", "docs/diagram.png", "modules/legacy/main.tf", "secrets.tfvars", "docs/index.html"},
+		},
+		{
+			name:        "invalid pattern",
+			ignore:      "file[0-9.tf",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			if tc.ignore != "" {
+				fsys[IgnoreFileName] = &fstest.MapFile{Data: []byte(tc.ignore)}
+			}
+
+			filter, err := NewFileFilter(fsys, &Spec{Package: tc.pkg})
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			for _, name := range tc.included {
+				assert.True(t, filter.Match(name), "expected %s to be included", name)
+			}
+			for _, name := range tc.excluded {
+				assert.False(t, filter.Match(name), "expected %s to be excluded", name)
+			}
+		})
+	}
+}
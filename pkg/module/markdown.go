@@ -0,0 +1,49 @@
+package module
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+var (
+	// markdown renders GitHub Flavored Markdown, which is what module READMEs are written in, see extension.GFM.
+	// Raw HTML is omitted, as the unsafe mode of the renderer isn't enabled.
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			extension.Linkify,
+			extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+			extension.Strikethrough,
+			extension.TaskList,
+		),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	)
+
+	// markdownPolicy sanitizes the rendered HTML, so that only URLs with safe schemes remain after all of their escapes are resolved
+	markdownPolicy = newMarkdownPolicy()
+)
+
+func newMarkdownPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[a-z0-9-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w.+-]+$`)).OnElements("code")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	return p
+}
+
+// RenderMarkdown renders GitHub Flavored Markdown to HTML.
+// Raw HTML is omitted and the output is sanitized, which makes it safe to embed into a page.
+func RenderMarkdown(src string) string {
+	var b bytes.Buffer
+	if err := markdown.Convert([]byte(strings.ReplaceAll(src, "\x00", "")), &b); err != nil {
+		// Rendering only fails if the writer fails, which a buffer doesn't
+		return ""
+	}
+	return markdownPolicy.Sanitize(b.String())
+}
@@ -0,0 +1,81 @@
+package module
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		markdown string
+		expected string
+	}{
+		{
+			name:     "heading",
+			markdown: "# Terraform AWS VPC",
+			expected: `<h1 id="terraform-aws-vpc">Terraform AWS VPC</h1>` + "\n",
+		},
+		{
+			name:     "paragraph with inline formatting",
+			markdown: "Creates a **VPC** with _private_ subnets, see `var.cidr`.",
+			expected: "<p>Creates a <strong>VPC</strong> with <em>private</em> subnets, see <code>var.cidr</code>.</p>\n",
+		},
+		{
+			name:     "fenced code block",
+			markdown: "```hcl\nmodule \"vpc\" {\n  source = \"acme/vpc/aws\"\n}\n```",
+			expected: "<pre><code class=\"language-hcl\">module &#34;vpc&#34; {\n  source = &#34;acme/vpc/aws&#34;\n}\n</code></pre>\n",
+		},
+		{
+			name:     "lists",
+			markdown: "- one\n- two\n\n1. first\n2. second",
+			expected: "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n",
+		},
+		{
+			name:     "table",
+			markdown: "| Name | Required |\n|------|:--------:|\n| cidr | yes |",
+			expected: "<table>\n<thead>\n<tr>\n<th>Name</th>\n<th align=\"center\">Required</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>cidr</td>\n<td align=\"center\">yes</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "link",
+			markdown: "[Docs](https://example.com/docs)",
+			expected: "<p><a href=\"https://example.com/docs\" rel=\"nofollow\">Docs</a></p>\n",
+		},
+		{
+			name:     "raw html is omitted",
+			markdown: "<script>alert(1)</script>\n\nText with <img src=x onerror=alert(1)> inline html",
+			expected: "\n<p>Text with  inline html</p>\n",
+		},
+		{
+			name:     "unsafe link is dropped",
+			markdown: "[click](javascript:alert)",
+			expected: "<p>click</p>\n",
+		},
+		{
+			name:     "escaped scheme of a link is dropped",
+			markdown: "[click](javascript\\:alert(1))",
+			expected: "<p>click</p>\n",
+		},
+		{
+			name:     "escaped scheme of an image is dropped",
+			markdown: "![logo](javascript\\:alert(1))",
+			expected: "<p><img alt=\"logo\"></p>\n",
+		},
+		{
+			name:     "entity in scheme is dropped",
+			markdown: "[click](jav&#x61;script:alert(1))",
+			expected: "<p>click</p>\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, RenderMarkdown(tc.markdown))
+		})
+	}
+}
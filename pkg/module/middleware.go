@@ -6,6 +6,9 @@ import (
 	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Middleware is a Service middleware.
@@ -35,11 +38,11 @@ func (mw loggingMiddleware) ListModuleVersions(ctx context.Context, namespace, n
 			),
 		)
 		if err != nil {
-			logger.Error("failed to list module", slog.String("err", err.Error()))
+			logger.ErrorContext(ctx, "failed to list module", slog.String("err", err.Error()))
 			return
 		}
 
-		logger.Info("list module version", slog.String("took", time.Since(begin).String()))
+		logger.InfoContext(ctx, "list module version", slog.String("took", time.Since(begin).String()))
 	}(time.Now())
 
 	return mw.next.ListModuleVersions(ctx, namespace, name, provider)
@@ -61,12 +64,211 @@ func (mw loggingMiddleware) GetModule(ctx context.Context, namespace, name, prov
 		)
 
 		if err != nil {
-			logger.Error("failed to get module", slog.String("err", err.Error()))
+			logger.ErrorContext(ctx, "failed to get module", slog.String("err", err.Error()))
+			return
+		}
+
+		logger.InfoContext(ctx, "get module", slog.String("took", time.Since(begin).String()), slog.String("module", module.ID(true)))
+	}(time.Now())
+
+	return mw.next.GetModule(ctx, namespace, name, provider, version)
+}
+
+func (mw loggingMiddleware) ListModules(ctx context.Context) (modules []core.Module, err error) {
+	defer func(begin time.Time) {
+		logger := slog.Default().With(slog.String("op", "ListModules"))
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to list modules", slog.String("err", err.Error()))
+			return
+		}
+
+		logger.InfoContext(ctx, "list modules", slog.String("took", time.Since(begin).String()), slog.Int("modules", len(modules)))
+	}(time.Now())
+
+	return mw.next.ListModules(ctx)
+}
+
+func (mw loggingMiddleware) GetModuleDetails(ctx context.Context, namespace, name, provider, version string) (details *Details, err error) {
+	defer func(begin time.Time) {
+		logger := slog.Default().With(
+			slog.String("op", "GetModuleDetails"),
+			slog.Group("module",
+				slog.String("namespace", namespace),
+				slog.String("name", name),
+				slog.String("provider", provider),
+				slog.String("version", version),
+			),
+		)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to get module details", slog.String("err", err.Error()))
+			return
+		}
+
+		logger.InfoContext(ctx, "get module details", slog.String("took", time.Since(begin).String()))
+	}(time.Now())
+
+	return mw.next.GetModuleDetails(ctx, namespace, name, provider, version)
+}
+
+func (mw loggingMiddleware) GetModuleDocs(ctx context.Context, namespace, name, provider, version string) (docs *Docs, err error) {
+	defer func(begin time.Time) {
+		logger := slog.Default().With(
+			slog.String("op", "GetModuleDocs"),
+			slog.Group("module",
+				slog.String("namespace", namespace),
+				slog.String("name", name),
+				slog.String("provider", provider),
+				slog.String("version", version),
+			),
+		)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to get module documentation", slog.String("err", err.Error()))
+			return
+		}
+
+		logger.InfoContext(ctx, "get module documentation", slog.String("took", time.Since(begin).String()))
+	}(time.Now())
+
+	return mw.next.GetModuleDocs(ctx, namespace, name, provider, version)
+}
+
+func (mw loggingMiddleware) GetModuleDependencies(ctx context.Context, namespace, name, provider, version string) (dependencies []ModuleDependency, err error) {
+	defer func(begin time.Time) {
+		logger := slog.Default().With(
+			slog.String("op", "GetModuleDependencies"),
+			slog.Group("module",
+				slog.String("namespace", namespace),
+				slog.String("name", name),
+				slog.String("provider", provider),
+				slog.String("version", version),
+			),
+		)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to get module dependencies", slog.String("err", err.Error()))
+			return
+		}
+
+		logger.InfoContext(ctx, "get module dependencies", slog.String("took", time.Since(begin).String()))
+	}(time.Now())
+
+	return mw.next.GetModuleDependencies(ctx, namespace, name, provider, version)
+}
+
+func (mw loggingMiddleware) ListModuleDependents(ctx context.Context, namespace, name, provider string) (dependents []ModuleDependent, err error) {
+	defer func(begin time.Time) {
+		logger := slog.Default().With(
+			slog.String("op", "ListModuleDependents"),
+			slog.Group("module",
+				slog.String("namespace", namespace),
+				slog.String("name", name),
+				slog.String("provider", provider),
+			),
+		)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to list module dependents", slog.String("err", err.Error()))
+			return
+		}
+
+		logger.InfoContext(ctx, "list module dependents", slog.String("took", time.Since(begin).String()), slog.Int("dependents", len(dependents)))
+	}(time.Now())
+
+	return mw.next.ListModuleDependents(ctx, namespace, name, provider)
+}
+
+func (mw loggingMiddleware) GetModuleDownloadsSummary(ctx context.Context, namespace, name, provider string) (summary *core.DownloadSummary, err error) {
+	defer func(begin time.Time) {
+		logger := slog.Default().With(
+			slog.String("op", "GetModuleDownloadsSummary"),
+			slog.Group("module",
+				slog.String("namespace", namespace),
+				slog.String("name", name),
+				slog.String("provider", provider),
+			),
+		)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to get module downloads summary", slog.String("err", err.Error()))
 			return
 		}
 
-		logger.Info("get module", slog.String("took", time.Since(begin).String()), slog.String("module", module.ID(true)))
+		logger.InfoContext(ctx, "get module downloads summary", slog.String("took", time.Since(begin).String()))
 	}(time.Now())
 
+	return mw.next.GetModuleDownloadsSummary(ctx, namespace, name, provider)
+}
+
+type tracingMiddleware struct {
+	next Service
+}
+
+// TracingMiddleware is a Service middleware, which starts a span for each call.
+func TracingMiddleware() Middleware {
+	return func(next Service) Service {
+		return &tracingMiddleware{
+			next: next,
+		}
+	}
+}
+
+func moduleAttributes(namespace, name, provider string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String(o11y.NamespaceLabel, namespace),
+		attribute.String(o11y.NameLabel, name),
+		attribute.String(o11y.ProviderLabel, provider),
+	}
+}
+
+func (mw tracingMiddleware) ListModuleVersions(ctx context.Context, namespace, name, provider string) (modules []core.Module, err error) {
+	ctx, span := o11y.StartSpan(ctx, "module.ListModuleVersions", moduleAttributes(namespace, name, provider)...)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	return mw.next.ListModuleVersions(ctx, namespace, name, provider)
+}
+
+func (mw tracingMiddleware) GetModule(ctx context.Context, namespace, name, provider, version string) (module core.Module, err error) {
+	ctx, span := o11y.StartSpan(ctx, "module.GetModule", append(moduleAttributes(namespace, name, provider), attribute.String(o11y.VersionLabel, version))...)
+	defer func() { o11y.EndSpan(span, err) }()
+
 	return mw.next.GetModule(ctx, namespace, name, provider, version)
 }
+
+func (mw tracingMiddleware) ListModules(ctx context.Context) (modules []core.Module, err error) {
+	ctx, span := o11y.StartSpan(ctx, "module.ListModules")
+	defer func() { o11y.EndSpan(span, err) }()
+
+	return mw.next.ListModules(ctx)
+}
+
+func (mw tracingMiddleware) GetModuleDetails(ctx context.Context, namespace, name, provider, version string) (details *Details, err error) {
+	ctx, span := o11y.StartSpan(ctx, "module.GetModuleDetails", append(moduleAttributes(namespace, name, provider), attribute.String(o11y.VersionLabel, version))...)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	return mw.next.GetModuleDetails(ctx, namespace, name, provider, version)
+}
+
+func (mw tracingMiddleware) GetModuleDocs(ctx context.Context, namespace, name, provider, version string) (docs *Docs, err error) {
+	ctx, span := o11y.StartSpan(ctx, "module.GetModuleDocs", append(moduleAttributes(namespace, name, provider), attribute.String(o11y.VersionLabel, version))...)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	return mw.next.GetModuleDocs(ctx, namespace, name, provider, version)
+}
+
+func (mw tracingMiddleware) GetModuleDependencies(ctx context.Context, namespace, name, provider, version string) (dependencies []ModuleDependency, err error) {
+	ctx, span := o11y.StartSpan(ctx, "module.GetModuleDependencies", append(moduleAttributes(namespace, name, provider), attribute.String(o11y.VersionLabel, version))...)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	return mw.next.GetModuleDependencies(ctx, namespace, name, provider, version)
+}
+
+func (mw tracingMiddleware) ListModuleDependents(ctx context.Context, namespace, name, provider string) (dependents []ModuleDependent, err error) {
+	ctx, span := o11y.StartSpan(ctx, "module.ListModuleDependents", moduleAttributes(namespace, name, provider)...)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	return mw.next.ListModuleDependents(ctx, namespace, name, provider)
+}
+
+func (mw tracingMiddleware) GetModuleDownloadsSummary(ctx context.Context, namespace, name, provider string) (summary *core.DownloadSummary, err error) {
+	ctx, span := o11y.StartSpan(ctx, "module.GetModuleDownloadsSummary", moduleAttributes(namespace, name, provider)...)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	return mw.next.GetModuleDownloadsSummary(ctx, namespace, name, provider)
+}
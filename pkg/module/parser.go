@@ -13,6 +13,7 @@ import (
 // Spec represents a module spec with metadata.
 type Spec struct {
 	Metadata Metadata `hcl:"metadata,block" json:"metadata"`
+	Package  *Package `hcl:"package,block" json:"package,omitempty"`
 }
 
 // Metadata provides information about a given module version.
@@ -20,7 +21,18 @@ type Metadata struct {
 	Namespace string `hcl:"namespace" json:"namespace"`
 	Name      string `hcl:"name" json:"name"`
 	Provider  string `hcl:"provider" json:"provider"`
-	Version   string `hcl:"version" json:"version"`
+	Version   string `hcl:"version,optional" json:"version"`
+}
+
+// Package declares which files are packaged into the module archive.
+// The patterns follow the .terraformignore syntax and are relative to the module root.
+type Package struct {
+	// Include limits the archive to the matching files, if set
+	Include []string `hcl:"include,optional" json:"include,omitempty"`
+	// Exclude removes the matching files from the archive, in addition to the default excludes and .terraformignore
+	Exclude []string `hcl:"exclude,optional" json:"exclude,omitempty"`
+	// Format is the archive format of the module, e.g. zip or tar.xz
+	Format string `hcl:"format,optional" json:"format,omitempty"`
 }
 
 // Validate ensures that a spec is valid.
@@ -47,6 +59,10 @@ func (s *Spec) Validate() error {
 		errs = append(errs, err)
 	}
 
+	if s.Package != nil && s.Package.Format != "" && !IsArchiveFormat(s.Package.Format) {
+		errs = append(errs, fmt.Errorf("package.format %q is not a supported archive format", s.Package.Format))
+	}
+
 	return errors.Join(errs...)
 }
 
@@ -67,6 +83,16 @@ func ParseFile(path string) (*Spec, error) {
 
 // Parse parses a module spec.
 func Parse(r io.Reader) (*Spec, error) {
+	return parse(r, "")
+}
+
+// ParseWithVersion parses a module spec and overrides its version.
+// The version is optional in the spec, as it's derived from elsewhere, e.g. git tags.
+func ParseWithVersion(r io.Reader, version string) (*Spec, error) {
+	return parse(r, version)
+}
+
+func parse(r io.Reader, version string) (*Spec, error) {
 	spec := &Spec{}
 
 	b, err := io.ReadAll(r)
@@ -78,6 +104,10 @@ func Parse(r io.Reader) (*Spec, error) {
 		return nil, err
 	}
 
+	if version != "" {
+		spec.Metadata.Version = version
+	}
+
 	if err := spec.Validate(); err != nil {
 		return nil, err
 	}
@@ -29,7 +29,7 @@ func TestParser(t *testing.T) {
             }
 			`),
 			expected: &Spec{
-				Metadata{
+				Metadata: Metadata{
 					Name:      "s3",
 					Namespace: "example",
 					Version:   "1.0.0",
@@ -37,6 +37,34 @@ func TestParser(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "spec with package rules",
+			input: strings.NewReader(`
+            metadata {
+              name      = "s3"
+              namespace = "example"
+              version   = "1.0.0"
+              provider  = "aws"
+            }
+
+            package {
+              include = ["*.tf", "modules/"]
+              exclude = ["test/"]
+            }
+			`),
+			expected: &Spec{
+				Metadata: Metadata{
+					Name:      "s3",
+					Namespace: "example",
+					Version:   "1.0.0",
+					Provider:  "aws",
+				},
+				Package: &Package{
+					Include: []string{"*.tf", "modules/"},
+					Exclude: []string{"test/"},
+				},
+			},
+		},
 		{
 			name:          "empty spec",
 			input:         strings.NewReader(``),
@@ -2,6 +2,8 @@ package module
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
 )
@@ -11,6 +13,14 @@ import (
 type Service interface {
 	GetModule(ctx context.Context, namespace, name, provider, version string) (core.Module, error)
 	ListModuleVersions(ctx context.Context, namespace, name, provider string) ([]core.Module, error)
+	// ListModules returns all modules sorted by namespace, name and provider
+	ListModules(ctx context.Context) ([]core.Module, error)
+	GetModuleDetails(ctx context.Context, namespace, name, provider, version string) (*Details, error)
+	GetModuleDocs(ctx context.Context, namespace, name, provider, version string) (*Docs, error)
+	GetModuleDependencies(ctx context.Context, namespace, name, provider, version string) ([]ModuleDependency, error)
+	ListModuleDependents(ctx context.Context, namespace, name, provider string) ([]ModuleDependent, error)
+	// GetModuleDownloadsSummary sums up the recorded downloads of all versions of the module
+	GetModuleDownloadsSummary(ctx context.Context, namespace, name, provider string) (*core.DownloadSummary, error)
 }
 
 type service struct {
@@ -32,7 +42,8 @@ func (s *service) GetModule(ctx context.Context, namespace, name, provider, vers
 		return core.Module{}, err
 	}
 
-	if s.proxy.IsProxyEnabled(ctx) {
+	// Sources like git repositories are downloaded by Terraform directly and can't be proxied
+	if s.proxy.IsProxyEnabled(ctx) && res.Source == "" {
 		downloadUrl, err := s.proxy.GetProxyUrl(ctx, res.DownloadURL)
 		if err != nil {
 			return core.Module{}, err
@@ -41,6 +52,12 @@ func (s *service) GetModule(ctx context.Context, namespace, name, provider, vers
 		res.DownloadURL = downloadUrl
 	}
 
+	// The archive hint lets go-getter unpack the module independently of the download URL
+	res.DownloadURL, err = ArchiveURL(res.DownloadURL, res.ArchiveFormat)
+	if err != nil {
+		return core.Module{}, err
+	}
+
 	return res, err
 }
 
@@ -50,5 +67,51 @@ func (s *service) ListModuleVersions(ctx context.Context, namespace, name, provi
 		return nil, err
 	}
 
+	annotations, err := s.storage.GetModuleAnnotations(ctx, namespace, name, provider)
+	if err != nil {
+		return nil, err
+	}
+	for i := range res {
+		if a, ok := annotations[res[i].Version]; ok && !a.IsZero() {
+			res[i].Annotations = &a
+		}
+	}
+
 	return res, nil
 }
+
+func (s *service) ListModules(ctx context.Context) ([]core.Module, error) {
+	res, err := s.storage.ListModules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].ID(false) < res[j].ID(false) })
+	return res, nil
+}
+
+func (s *service) GetModuleDetails(ctx context.Context, namespace, name, provider, version string) (*Details, error) {
+	return s.storage.GetModuleDetails(ctx, namespace, name, provider, version)
+}
+
+func (s *service) GetModuleDocs(ctx context.Context, namespace, name, provider, version string) (*Docs, error) {
+	return s.storage.GetModuleDocs(ctx, namespace, name, provider, version)
+}
+
+func (s *service) GetModuleDependencies(ctx context.Context, namespace, name, provider, version string) ([]ModuleDependency, error) {
+	return s.storage.GetModuleDependencies(ctx, namespace, name, provider, version)
+}
+
+func (s *service) ListModuleDependents(ctx context.Context, namespace, name, provider string) ([]ModuleDependent, error) {
+	return s.storage.ListModuleDependents(ctx, namespace, name, provider)
+}
+
+func (s *service) GetModuleDownloadsSummary(ctx context.Context, namespace, name, provider string) (*core.DownloadSummary, error) {
+	stats, err := s.storage.GetModuleDownloadStats(ctx, namespace, name, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := stats.Summary(time.Now())
+	return &summary, nil
+}
@@ -49,10 +49,11 @@ func TestService_GetModule(t *testing.T) {
 		{
 			name: "valid get",
 			module: core.Module{
-				Namespace: "example",
-				Name:      "s3",
-				Provider:  "aws",
-				Version:   "1.0.0",
+				Namespace:     "example",
+				Name:          "s3",
+				Provider:      "aws",
+				Version:       "1.0.0",
+				ArchiveFormat: "tar.gz",
 			},
 			data: testModuleData(map[string]string{
 				"main.tf": `name = "foo"`,
@@ -83,7 +84,7 @@ func TestService_GetModule(t *testing.T) {
 				svc     = NewService(storage, proxy)
 			)
 
-			_, err := storage.UploadModule(ctx, tc.module.Namespace, tc.module.Name, tc.module.Provider, tc.module.Version, tc.data)
+			_, err := storage.UploadModule(ctx, tc.module.Namespace, tc.module.Name, tc.module.Provider, tc.module.Version, "", tc.data)
 			switch tc.expectError {
 			case true:
 				assert.Error(err)
@@ -168,7 +169,7 @@ func TestService_ListModuleVersions(t *testing.T) {
 			assert.NotEmpty(tc.versions)
 
 			for _, version := range tc.versions {
-				_, err := storage.UploadModule(ctx, tc.module.Namespace, tc.module.Name, tc.module.Provider, version, tc.data)
+				_, err := storage.UploadModule(ctx, tc.module.Namespace, tc.module.Name, tc.module.Provider, version, "", tc.data)
 				switch tc.expectError {
 				case true:
 					assert.Error(err)
@@ -183,10 +184,18 @@ func TestService_ListModuleVersions(t *testing.T) {
 				assert.Error(err)
 			case false:
 				assert.NoError(err)
+				// Uploads without a format use the default format of the storage
+				format := tc.format
+				if format == "" {
+					format = DefaultArchiveFormat
+				}
+
 				versions := make([]string, 0)
 				for _, module := range modules {
-					assert.True(strings.HasSuffix(module.DownloadURL, "."+tc.format))
+					assert.True(strings.HasSuffix(module.DownloadURL, "."+format))
+					assert.Equal(format, module.ArchiveFormat)
 					module.DownloadURL = ""
+					module.ArchiveFormat = ""
 					versions = append(versions, module.Version)
 					module.Version = ""
 					assert.Equal(tc.module, module)
@@ -196,3 +205,68 @@ func TestService_ListModuleVersions(t *testing.T) {
 		})
 	}
 }
+
+func TestService_GetModuleSource(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		ctx     = context.Background()
+		storage = NewInmemStorage()
+		proxy   = core.NewProxyUrlService(true, "/proxy")
+		svc     = NewService(storage, proxy)
+		source  = "git::https://github.com/acme/terraform-aws-vpc.git?ref=v1.0.0"
+	)
+
+	_, err := storage.UploadModuleSource(ctx, "acme", "vpc", "aws", "1.0.0", "https://github.com/acme/terraform-aws-vpc.git")
+	assert.Error(err)
+
+	_, err = storage.UploadModuleSource(ctx, "acme", "vpc", "aws", "1.0.0", source)
+	assert.NoError(err)
+
+	// Sources are neither proxied nor do they carry an archive hint
+	module, err := svc.GetModule(ctx, "acme", "vpc", "aws", "1.0.0")
+	assert.NoError(err)
+	assert.Equal(source, module.DownloadURL)
+
+	// An archive replaces the source
+	_, err = storage.UploadModule(ctx, "acme", "vpc", "aws", "1.0.0", "", testModuleData(map[string]string{"main.tf": ""}))
+	assert.NoError(err)
+	module, err = storage.GetModule(ctx, "acme", "vpc", "aws", "1.0.0")
+	assert.NoError(err)
+	assert.Empty(module.Source)
+	assert.Equal("tar.gz", module.ArchiveFormat)
+
+	_, err = storage.UploadModuleSource(ctx, "acme", "vpc", "aws", "1.0.0", source)
+	assert.Error(err)
+}
+
+func TestService_ListModuleVersionsAnnotations(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		ctx     = context.Background()
+		storage = NewInmemStorage()
+		svc     = NewService(storage, core.NewProxyUrlService(false, ""))
+	)
+
+	for _, v := range []string{"1.0.0", "2.0.0"} {
+		_, err := storage.UploadModule(ctx, "acme", "vpc", "aws", v, "", testModuleData(map[string]string{"main.tf": ""}))
+		assert.NoError(err)
+	}
+	err := storage.UpdateModuleAnnotations(ctx, "acme", "vpc", "aws", func(annotations core.VersionAnnotations) {
+		annotations["1.0.0"] = core.Annotations{Deprecation: &core.Deprecation{Reason: "use 2.x", Link: "https://example.com/vpc/2.0.0"}, Labels: map[string]string{"team": "networking"}}
+		annotations["2.0.0"] = core.Annotations{}
+	})
+	assert.NoError(err)
+
+	modules, err := svc.ListModuleVersions(ctx, "acme", "vpc", "aws")
+	assert.NoError(err)
+	for _, m := range modules {
+		switch m.Version {
+		case "1.0.0":
+			assert.Equal(&core.Annotations{Deprecation: &core.Deprecation{Reason: "use 2.x", Link: "https://example.com/vpc/2.0.0"}, Labels: map[string]string{"team": "networking"}}, m.Annotations)
+		default:
+			assert.Nil(m.Annotations)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// GitSource is a go-getter git source like git::https://github.com/acme/terraform-aws-vpc.git//modules/vpc?ref=v1.2.0.
+// Module versions with a source are downloaded by Terraform from the repository instead of an archive in the storage.
+// See https://developer.hashicorp.com/terraform/language/modules/sources#generic-git-repository
+type GitSource struct {
+	// Remote is the URL of the repository
+	Remote string
+	// Subdir is the directory of the module in the repository, if it's not at the root
+	Subdir string
+	// Ref is the branch, tag or commit of the module version
+	Ref string
+}
+
+// ParseGitSource parses a go-getter git source. The ref is required, as a module version has to be pinned.
+func ParseGitSource(source string) (*GitSource, error) {
+	rest, ok := strings.CutPrefix(source, "git::")
+	if !ok {
+		return nil, fmt.Errorf("invalid module source %q: only git sources with the git:: prefix are supported", source)
+	}
+
+	remote, query, _ := strings.Cut(rest, "?")
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid module source %q: %w", source, err)
+	}
+
+	s := &GitSource{Ref: values.Get("ref")}
+	if s.Ref == "" {
+		return nil, fmt.Errorf("invalid module source %q: the ref query parameter is required to pin the version", source)
+	}
+
+	// The subdirectory is separated with a double slash after the scheme, e.g. https://example.com/repo.git//modules/vpc
+	offset := 0
+	if i := strings.Index(remote, "://"); i >= 0 {
+		offset = i + len("://")
+	}
+	if i := strings.Index(remote[offset:], "//"); i >= 0 {
+		s.Subdir = strings.Trim(remote[offset+i+2:], "/")
+		remote = remote[:offset+i]
+	}
+
+	if remote == "" {
+		return nil, fmt.Errorf("invalid module source %q: %w", source, errors.New("the repository is missing"))
+	}
+	s.Remote = remote
+
+	return s, nil
+}
@@ -0,0 +1,62 @@
+package module
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGitSource(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		source        string
+		expected      *GitSource
+		expectedError bool
+	}{
+		{
+			name:     "https repository",
+			source:   "git::https://github.com/acme/terraform-aws-vpc.git?ref=v1.2.0",
+			expected: &GitSource{Remote: "https://github.com/acme/terraform-aws-vpc.git", Ref: "v1.2.0"},
+		},
+		{
+			name:     "module in a subdirectory",
+			source:   "git::https://github.com/acme/modules.git//modules/vpc?ref=vpc/v1.2.0&depth=1",
+			expected: &GitSource{Remote: "https://github.com/acme/modules.git", Subdir: "modules/vpc", Ref: "vpc/v1.2.0"},
+		},
+		{
+			name:     "scp-like ssh repository",
+			source:   "git::git@github.com:acme/modules.git//vpc?ref=51d462976d84fdea54b47d80dcabbf680badcdb8",
+			expected: &GitSource{Remote: "git@github.com:acme/modules.git", Subdir: "vpc", Ref: "51d462976d84fdea54b47d80dcabbf680badcdb8"},
+		},
+		{
+			name:          "missing ref",
+			source:        "git::https://github.com/acme/terraform-aws-vpc.git",
+			expectedError: true,
+		},
+		{
+			name:          "not a git source",
+			source:        "https://example.com/vpc.zip?ref=v1.2.0",
+			expectedError: true,
+		},
+		{
+			name:          "missing repository",
+			source:        "git::?ref=v1.2.0",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ParseGitSource(tc.source)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
@@ -11,6 +11,40 @@ import (
 type Storage interface {
 	// GetModule should return an ErrModuleNotFound error if the requested module version cannot be found
 	GetModule(ctx context.Context, namespace, name, provider, version string) (core.Module, error)
+	// ListModuleVersions doesn't return the sources of the versions, which are only returned by GetModule
 	ListModuleVersions(ctx context.Context, namespace, name, provider string) ([]core.Module, error)
-	UploadModule(ctx context.Context, namespace, name, provider, version string, body io.Reader) (core.Module, error)
+	// ListModules returns the namespace, name and provider of all modules with at least one version
+	ListModules(ctx context.Context) ([]core.Module, error)
+	// UploadModule stores the archive in the given format. The default format of the storage is used if the format is empty.
+	// Versions that are only registered with a source can be uploaded, as archives take precedence over sources.
+	// The archive is written conditionally, so that only one of concurrent uploads of a version succeeds and the others fail with ErrModuleAlreadyExists.
+	UploadModule(ctx context.Context, namespace, name, provider, version, format string, body io.Reader) (core.Module, error)
+	// UploadModuleSource registers a module version that is downloaded from a git source instead of an archive
+	UploadModuleSource(ctx context.Context, namespace, name, provider, version, source string) (core.Module, error)
+	// DeleteModuleSource removes the source of a module version, e.g. after it was converted into an archive
+	DeleteModuleSource(ctx context.Context, namespace, name, provider, version string) error
+	// GetModuleDetails should return an ErrModuleDetailsNotFound error if the module version has no details
+	GetModuleDetails(ctx context.Context, namespace, name, provider, version string) (*Details, error)
+	// UploadModuleDetails stores the details next to the module archive and overwrites existing details
+	UploadModuleDetails(ctx context.Context, namespace, name, provider, version string, details *Details) error
+	// GetModuleDocs should return an ErrModuleDocsNotFound error if the module version has no documentation bundle
+	GetModuleDocs(ctx context.Context, namespace, name, provider, version string) (*Docs, error)
+	// UploadModuleDocs stores the documentation bundle next to the module archive and overwrites an existing bundle
+	UploadModuleDocs(ctx context.Context, namespace, name, provider, version string, docs *Docs) error
+	// UploadModuleDependencies stores the dependency edges of a module version and indexes them by the called modules.
+	// It returns an ErrModuleNotFound error if the module version isn't stored yet, so that it's called after UploadModule.
+	UploadModuleDependencies(ctx context.Context, namespace, name, provider, version string, dependencies []ModuleDependency) error
+	// GetModuleDependencies should return an ErrModuleDependenciesNotFound error if the dependencies of the module version weren't stored
+	GetModuleDependencies(ctx context.Context, namespace, name, provider, version string) ([]ModuleDependency, error)
+	// ListModuleDependents returns the module versions that call the module, independent of the registry hostname in their sources
+	ListModuleDependents(ctx context.Context, namespace, name, provider string) ([]ModuleDependent, error)
+	// GetModuleAnnotations returns the annotations of all versions of the module, which are empty if none were stored
+	GetModuleAnnotations(ctx context.Context, namespace, name, provider string) (core.VersionAnnotations, error)
+	// UpdateModuleAnnotations applies update to the stored annotations of all versions of the module without losing concurrent updates.
+	// The update can be called more than once, if the annotations are modified concurrently.
+	UpdateModuleAnnotations(ctx context.Context, namespace, name, provider string, update func(core.VersionAnnotations)) error
+	// GetModuleDownloadStats returns the daily downloads of all versions of the module, which are empty if none were stored
+	GetModuleDownloadStats(ctx context.Context, namespace, name, provider string) (core.DownloadStats, error)
+	// MergeModuleDownloadStats adds the daily downloads to the stored stats of the module without losing concurrent updates
+	MergeModuleDownloadStats(ctx context.Context, namespace, name, provider string, stats core.DownloadStats) error
 }
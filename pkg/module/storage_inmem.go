@@ -17,6 +17,11 @@ type InmemStorage struct {
 	mu            sync.RWMutex
 	modules       map[string]core.Module
 	moduleData    map[string]io.Reader
+	details       map[string]*Details
+	docs          map[string]*Docs
+	dependencies  map[string][]ModuleDependency
+	annotations   map[string]core.VersionAnnotations
+	downloads     map[string]core.DownloadStats
 	archiveFormat string
 }
 
@@ -34,7 +39,7 @@ func (s *InmemStorage) GetModule(_ context.Context, namespace, name, provider, v
 	id := m.ID(true)
 	module, ok := s.modules[id]
 	if !ok {
-		return core.Module{}, fmt.Errorf("module not found: %s", id)
+		return core.Module{}, fmt.Errorf("%w: %s", ErrModuleNotFound, id)
 	}
 
 	return module, nil
@@ -48,8 +53,10 @@ func (s *InmemStorage) ListModuleVersions(ctx context.Context, namespace, name,
 
 	for _, module := range s.modules {
 		if module.Namespace == namespace && module.Name == name && module.Provider == provider {
-			f := fmt.Sprintf("%s-%s-%s-%s.%s", namespace, name, provider, module.Version, s.archiveFormat)
-			module.DownloadURL = path.Join("prefix", "inmem", namespace, name, provider, f)
+			if module.Source == "" {
+				f := fmt.Sprintf("%s-%s-%s-%s.%s", namespace, name, provider, module.Version, module.ArchiveFormat)
+				module.DownloadURL = path.Join("prefix", "inmem", namespace, name, provider, f)
+			}
 			modules = append(modules, module)
 		}
 	}
@@ -61,7 +68,24 @@ func (s *InmemStorage) ListModuleVersions(ctx context.Context, namespace, name,
 	return modules, nil
 }
 
-func (s *InmemStorage) UploadModule(ctx context.Context, namespace, name, provider, version string, body io.Reader) (core.Module, error) {
+// ListModules lists the modules in the in-memory storage.
+func (s *InmemStorage) ListModules(_ context.Context) ([]core.Module, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := map[string]bool{}
+	var modules []core.Module
+	for _, m := range s.modules {
+		if id := m.ID(false); !seen[id] {
+			seen[id] = true
+			modules = append(modules, core.Module{Namespace: m.Namespace, Name: m.Name, Provider: m.Provider})
+		}
+	}
+
+	return modules, nil
+}
+
+func (s *InmemStorage) UploadModule(ctx context.Context, namespace, name, provider, version, format string, body io.Reader) (core.Module, error) {
 	if namespace == "" {
 		return core.Module{}, errors.New("namespace not defined")
 	}
@@ -78,18 +102,27 @@ func (s *InmemStorage) UploadModule(ctx context.Context, namespace, name, provid
 		return core.Module{}, errors.New("version not defined")
 	}
 
+	if format == "" {
+		format = s.archiveFormat
+	}
+	if !IsArchiveFormat(format) {
+		return core.Module{}, fmt.Errorf("unsupported module archive format: %s", format)
+	}
+
 	s.mu.Lock()
 
 	m := core.Module{
-		Namespace: namespace,
-		Name:      name,
-		Provider:  provider,
-		Version:   version,
+		Namespace:     namespace,
+		Name:          name,
+		Provider:      provider,
+		Version:       version,
+		ArchiveFormat: format,
 	}
 
 	id := m.ID(true)
-	if _, ok := s.modules[id]; ok {
-		return core.Module{}, fmt.Errorf("module exists already: %s", id)
+	if existing, ok := s.modules[id]; ok && existing.Source == "" {
+		s.mu.Unlock()
+		return core.Module{}, fmt.Errorf("%w: %s", ErrModuleAlreadyExists, id)
 	}
 
 	s.modules[id] = m
@@ -100,6 +133,212 @@ func (s *InmemStorage) UploadModule(ctx context.Context, namespace, name, provid
 	return s.GetModule(ctx, namespace, name, provider, version)
 }
 
+// UploadModuleSource registers a module version with a git source in the in-memory storage.
+func (s *InmemStorage) UploadModuleSource(ctx context.Context, namespace, name, provider, version, source string) (core.Module, error) {
+	if _, err := ParseGitSource(source); err != nil {
+		return core.Module{}, err
+	}
+
+	s.mu.Lock()
+	m := core.Module{
+		Namespace:   namespace,
+		Name:        name,
+		Provider:    provider,
+		Version:     version,
+		DownloadURL: source,
+		Source:      source,
+	}
+	id := m.ID(true)
+	if _, ok := s.modules[id]; ok {
+		s.mu.Unlock()
+		return core.Module{}, fmt.Errorf("%w: %s", ErrModuleAlreadyExists, id)
+	}
+	s.modules[id] = m
+	s.mu.Unlock()
+
+	return s.GetModule(ctx, namespace, name, provider, version)
+}
+
+// DeleteModuleSource removes the source of a module version from the in-memory storage.
+func (s *InmemStorage) DeleteModuleSource(_ context.Context, namespace, name, provider, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := core.Module{Namespace: namespace, Name: name, Provider: provider, Version: version}
+	if existing, ok := s.modules[m.ID(true)]; ok && existing.Source != "" {
+		delete(s.modules, m.ID(true))
+	}
+	return nil
+}
+
+// GetModuleDetails retrieves the details of a module version from the in-memory storage.
+func (s *InmemStorage) GetModuleDetails(_ context.Context, namespace, name, provider, version string) (*Details, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m := core.Module{Namespace: namespace, Name: name, Provider: provider, Version: version}
+	details, ok := s.details[m.ID(true)]
+	if !ok {
+		return nil, ErrModuleDetailsNotFound
+	}
+
+	return details, nil
+}
+
+// UploadModuleDetails stores the details of a module version in the in-memory storage.
+func (s *InmemStorage) UploadModuleDetails(_ context.Context, namespace, name, provider, version string, details *Details) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := core.Module{Namespace: namespace, Name: name, Provider: provider, Version: version}
+	s.details[m.ID(true)] = details
+
+	return nil
+}
+
+// GetModuleDocs retrieves the documentation bundle of a module version from the in-memory storage.
+func (s *InmemStorage) GetModuleDocs(_ context.Context, namespace, name, provider, version string) (*Docs, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m := core.Module{Namespace: namespace, Name: name, Provider: provider, Version: version}
+	docs, ok := s.docs[m.ID(true)]
+	if !ok {
+		return nil, ErrModuleDocsNotFound
+	}
+
+	return docs, nil
+}
+
+// UploadModuleDocs stores the documentation bundle of a module version in the in-memory storage.
+func (s *InmemStorage) UploadModuleDocs(_ context.Context, namespace, name, provider, version string, docs *Docs) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := core.Module{Namespace: namespace, Name: name, Provider: provider, Version: version}
+	s.docs[m.ID(true)] = docs
+
+	return nil
+}
+
+// UploadModuleDependencies stores the dependencies of a module version in the in-memory storage.
+func (s *InmemStorage) UploadModuleDependencies(_ context.Context, namespace, name, provider, version string, dependencies []ModuleDependency) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := core.Module{Namespace: namespace, Name: name, Provider: provider, Version: version}
+	if _, ok := s.modules[m.ID(true)]; !ok {
+		return ErrModuleNotFound
+	}
+	s.dependencies[m.ID(true)] = dependencies
+
+	return nil
+}
+
+// GetModuleDependencies retrieves the dependencies of a module version from the in-memory storage.
+func (s *InmemStorage) GetModuleDependencies(_ context.Context, namespace, name, provider, version string) ([]ModuleDependency, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m := core.Module{Namespace: namespace, Name: name, Provider: provider, Version: version}
+	dependencies, ok := s.dependencies[m.ID(true)]
+	if !ok {
+		return nil, ErrModuleDependenciesNotFound
+	}
+
+	return dependencies, nil
+}
+
+// ListModuleDependents searches the dependencies of all module versions in the in-memory storage for the module.
+func (s *InmemStorage) ListModuleDependents(_ context.Context, namespace, name, provider string) ([]ModuleDependent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dependents := []ModuleDependent{}
+	for id, dependencies := range s.dependencies {
+		// The dependencies are uploaded before the module, which only exists once its upload succeeded
+		m, ok := s.modules[id]
+		if !ok {
+			continue
+		}
+
+		// The module can be called several times, e.g. by the root module and a submodule, which is listed once per hostname
+		hostnames := map[string]bool{}
+		for _, d := range dependencies {
+			if d.Namespace != namespace || d.Name != name || d.Provider != provider || hostnames[d.Hostname] {
+				continue
+			}
+			hostnames[d.Hostname] = true
+
+			dependents = append(dependents, ModuleDependent{
+				Namespace:         m.Namespace,
+				Name:              m.Name,
+				Provider:          m.Provider,
+				Version:           m.Version,
+				VersionConstraint: d.Version,
+				Hostname:          d.Hostname,
+			})
+		}
+	}
+	SortModuleDependents(dependents)
+
+	return dependents, nil
+}
+
+// GetModuleAnnotations retrieves the annotations of a module from the in-memory storage.
+func (s *InmemStorage) GetModuleAnnotations(_ context.Context, namespace, name, provider string) (core.VersionAnnotations, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m := core.Module{Namespace: namespace, Name: name, Provider: provider}
+	annotations := core.VersionAnnotations{}
+	for version, a := range s.annotations[m.ID(false)] {
+		annotations[version] = a
+	}
+
+	return annotations, nil
+}
+
+// UpdateModuleAnnotations updates the annotations of a module in the in-memory storage.
+func (s *InmemStorage) UpdateModuleAnnotations(_ context.Context, namespace, name, provider string, update func(core.VersionAnnotations)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := core.Module{Namespace: namespace, Name: name, Provider: provider}
+	if s.annotations[m.ID(false)] == nil {
+		s.annotations[m.ID(false)] = core.VersionAnnotations{}
+	}
+	update(s.annotations[m.ID(false)])
+
+	return nil
+}
+
+// GetModuleDownloadStats retrieves the download stats of a module from the in-memory storage.
+func (s *InmemStorage) GetModuleDownloadStats(_ context.Context, namespace, name, provider string) (core.DownloadStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m := core.Module{Namespace: namespace, Name: name, Provider: provider}
+	stats := core.DownloadStats{}
+	stats.Merge(s.downloads[m.ID(false)])
+
+	return stats, nil
+}
+
+// MergeModuleDownloadStats adds the downloads to the download stats of a module in the in-memory storage.
+func (s *InmemStorage) MergeModuleDownloadStats(_ context.Context, namespace, name, provider string, stats core.DownloadStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := core.Module{Namespace: namespace, Name: name, Provider: provider}
+	if s.downloads[m.ID(false)] == nil {
+		s.downloads[m.ID(false)] = core.DownloadStats{}
+	}
+	s.downloads[m.ID(false)].Merge(stats)
+
+	return nil
+}
+
 func (s *InmemStorage) MigrateModules(ctx context.Context, dryRun bool) error {
 	panic("MigrateModules should not be called for InmemStorage")
 }
@@ -107,10 +346,12 @@ func (s *InmemStorage) MigrateModules(ctx context.Context, dryRun bool) error {
 // InmemStorageOption provides additional options for the InmemStorage.
 type InmemStorageOption func(*InmemStorage)
 
-// WithInmemArchiveFormat configures the module archive format (zip, tar, tgz, etc.)
+// WithInmemArchiveFormat configures the default module archive format (zip, tar, tgz, etc.)
 func WithInmemArchiveFormat(archiveFormat string) InmemStorageOption {
 	return func(s *InmemStorage) {
-		s.archiveFormat = archiveFormat
+		if archiveFormat != "" {
+			s.archiveFormat = archiveFormat
+		}
 	}
 }
 
@@ -119,7 +360,12 @@ func NewInmemStorage(options ...InmemStorageOption) Storage {
 	s := &InmemStorage{
 		modules:       make(map[string]core.Module),
 		moduleData:    make(map[string]io.Reader),
-		archiveFormat: "tar.gz",
+		details:       make(map[string]*Details),
+		docs:          make(map[string]*Docs),
+		dependencies:  make(map[string][]ModuleDependency),
+		annotations:   make(map[string]core.VersionAnnotations),
+		downloads:     make(map[string]core.DownloadStats),
+		archiveFormat: DefaultArchiveFormat,
 	}
 
 	for _, option := range options {
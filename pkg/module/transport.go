@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
@@ -22,6 +23,7 @@ const (
 	varName      muxVar = "name"
 	varProvider  muxVar = "provider"
 	varVersion   muxVar = "version"
+	varExample   muxVar = "example"
 )
 
 // MakeHandler returns a fully initialized http.Handler.
@@ -58,6 +60,87 @@ func MakeHandler(svc Service, auth endpoint.Middleware, metrics *o11y.ModuleMetr
 		),
 	)
 
+	r.Methods("GET").Path(`/{namespace}/{name}/{provider}/{version}/details`).Handler(
+		instrumentation.WrapHandler(
+			httptransport.NewServer(
+				auth(detailsEndpoint(svc)),
+				decodeDownloadRequest,
+				httptransport.EncodeJSONResponse,
+				append(
+					options,
+					httptransport.ServerBefore(extractMuxVars(varNamespace, varName, varProvider, varVersion)),
+					httptransport.ServerBefore(jwt.HTTPToContext()),
+				)...,
+			),
+		),
+	)
+
+	for path, document := range map[string]string{
+		`/{namespace}/{name}/{provider}/{version}/docs`:                    docsReadme,
+		`/{namespace}/{name}/{provider}/{version}/docs/changelog`:          docsChangelog,
+		`/{namespace}/{name}/{provider}/{version}/docs/examples/{example}`: "example",
+	} {
+		r.Methods("GET").Path(path).Handler(
+			instrumentation.WrapHandler(
+				httptransport.NewServer(
+					auth(docsEndpoint(svc)),
+					decodeDocsRequest(document),
+					encodeDocsResponse,
+					append(
+						options,
+						httptransport.ServerBefore(extractMuxVars(varNamespace, varName, varProvider, varVersion, varExample)),
+						httptransport.ServerBefore(jwt.HTTPToContext()),
+					)...,
+				),
+			),
+		)
+	}
+
+	r.Methods("GET").Path(`/{namespace}/{name}/{provider}/{version}/dependencies`).Handler(
+		instrumentation.WrapHandler(
+			httptransport.NewServer(
+				auth(dependenciesEndpoint(svc)),
+				decodeDownloadRequest,
+				httptransport.EncodeJSONResponse,
+				append(
+					options,
+					httptransport.ServerBefore(extractMuxVars(varNamespace, varName, varProvider, varVersion)),
+					httptransport.ServerBefore(jwt.HTTPToContext()),
+				)...,
+			),
+		),
+	)
+
+	r.Methods("GET").Path(`/{namespace}/{name}/{provider}/dependents`).Handler(
+		instrumentation.WrapHandler(
+			httptransport.NewServer(
+				auth(dependentsEndpoint(svc)),
+				decodeListRequest,
+				httptransport.EncodeJSONResponse,
+				append(
+					options,
+					httptransport.ServerBefore(extractMuxVars(varNamespace, varName, varProvider)),
+					httptransport.ServerBefore(jwt.HTTPToContext()),
+				)...,
+			),
+		),
+	)
+
+	r.Methods("GET").Path(`/{namespace}/{name}/{provider}/downloads/summary`).Handler(
+		instrumentation.WrapHandler(
+			httptransport.NewServer(
+				auth(downloadsSummaryEndpoint(svc)),
+				decodeListRequest,
+				httptransport.EncodeJSONResponse,
+				append(
+					options,
+					httptransport.ServerBefore(extractMuxVars(varNamespace, varName, varProvider)),
+					httptransport.ServerBefore(jwt.HTTPToContext()),
+				)...,
+			),
+		),
+	)
+
 	return r
 }
 
@@ -113,11 +196,42 @@ func decodeDownloadRequest(ctx context.Context, r *http.Request) (interface{}, e
 	}, nil
 }
 
+// decodeDocsRequest returns a decoder for the given document of the documentation bundle
+func decodeDocsRequest(document string) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		req, err := decodeDownloadRequest(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+
+		format := r.URL.Query().Get("format")
+		switch format {
+		case "":
+			format = docsFormatHTML
+		case docsFormatHTML, docsFormatMarkdown:
+		default:
+			return nil, fmt.Errorf("%w: %q, expected %s or %s", ErrInvalidDocsFormat, format, docsFormatHTML, docsFormatMarkdown)
+		}
+
+		example, _ := ctx.Value(varExample).(string)
+
+		return docsRequest{
+			downloadRequest: req.(downloadRequest),
+			document:        document,
+			example:         example,
+			format:          format,
+		}, nil
+	}
+}
+
 // ErrorEncoder translates domain specific errors to HTTP status codes
 func ErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
 
-	if errors.Is(err, ErrModuleNotFound) {
+	if errors.Is(err, ErrModuleNotFound) || errors.Is(err, ErrModuleDetailsNotFound) || errors.Is(err, ErrModuleDependenciesNotFound) ||
+		errors.Is(err, ErrModuleDocsNotFound) || errors.Is(err, ErrModuleDocNotFound) {
 		w.WriteHeader(http.StatusNotFound)
+	} else if errors.Is(err, ErrInvalidDocsFormat) {
+		w.WriteHeader(http.StatusBadRequest)
 	} else {
 		w.WriteHeader(core.GenericError(err))
 	}
@@ -143,3 +257,16 @@ func encodeDownloadResponse(ctx context.Context, w http.ResponseWriter, response
 	w.WriteHeader(http.StatusNoContent)
 	return nil
 }
+
+func encodeDocsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	res := response.(docsResponse)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if res.format == docsFormatMarkdown {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		_, err := io.WriteString(w, res.markdown)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return renderDocsPage(w, res)
+}
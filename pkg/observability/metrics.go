@@ -14,6 +14,10 @@ const (
 	OsLabel           = "os"
 	ArchLabel         = "arch"
 	ProxyFailureLabel = "failure"
+	BackendLabel      = "backend"
+	OperationLabel    = "operation"
+	ErrorClassLabel   = "class"
+	TenantLabel       = "tenant"
 
 	ProxyFailureUrl      = "bad-url"
 	ProxyFailureRequest  = "invalid-request"
@@ -44,6 +48,12 @@ type ProxyMetrics struct {
 	Download *prometheus.CounterVec
 	Failure  *prometheus.CounterVec
 }
+type StorageMetrics struct {
+	OperationDuration *prometheus.HistogramVec
+	Errors            *prometheus.CounterVec
+	UploadedBytes     *prometheus.CounterVec
+	PresignDuration   *prometheus.HistogramVec
+}
 type HttpMetrics struct {
 	RequestsTotal   *prometheus.CounterVec
 	RequestDuration *prometheus.HistogramVec
@@ -52,6 +62,20 @@ type HttpMetrics struct {
 }
 
 func NewMetrics(buckets []float64) *ServerMetrics {
+	return newMetrics(promauto.With(prometheus.DefaultRegisterer), buckets)
+}
+
+// NewTenantMetrics returns the ServerMetrics of a tenant, which are labelled with its name.
+// If tenants are served, the metrics of all tenants have to be created with NewTenantMetrics, as the labels of a metric have to be consistent.
+func NewTenantMetrics(tenant string, buckets []float64) *ServerMetrics {
+	return newMetrics(tenantFactory(tenant), buckets)
+}
+
+func tenantFactory(tenant string) promauto.Factory {
+	return promauto.With(prometheus.WrapRegistererWith(prometheus.Labels{TenantLabel: tenant}, prometheus.DefaultRegisterer))
+}
+
+func newMetrics(factory promauto.Factory, buckets []float64) *ServerMetrics {
 	boringNamespace := "boring_registry"
 	httpNamespace := "http"
 
@@ -68,7 +92,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 
 	metrics := &ServerMetrics{
 		Mirror: &MirrorMetrics{
-			ListProviderVersions: promauto.NewCounterVec(
+			ListProviderVersions: factory.NewCounterVec(
 				prometheus.CounterOpts{
 					Namespace: boringNamespace,
 					Subsystem: mirrorsSubsystem,
@@ -77,7 +101,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 				},
 				[]string{HostnameLabel, NamespaceLabel, NameLabel},
 			),
-			ListProviderInstallation: promauto.NewCounterVec(
+			ListProviderInstallation: factory.NewCounterVec(
 				prometheus.CounterOpts{
 					Namespace: boringNamespace,
 					Subsystem: mirrorsSubsystem,
@@ -86,7 +110,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 				},
 				[]string{HostnameLabel, NamespaceLabel, NameLabel, VersionLabel},
 			),
-			RetrieveProviderArchive: promauto.NewCounterVec(
+			RetrieveProviderArchive: factory.NewCounterVec(
 				prometheus.CounterOpts{
 					Namespace: boringNamespace,
 					Subsystem: mirrorsSubsystem,
@@ -97,7 +121,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 			),
 		},
 		Provider: &ProviderMetrics{
-			ListVersions: promauto.NewCounterVec(
+			ListVersions: factory.NewCounterVec(
 				prometheus.CounterOpts{
 					Namespace: boringNamespace,
 					Subsystem: providersSubsystem,
@@ -106,7 +130,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 				},
 				[]string{NamespaceLabel, NameLabel},
 			),
-			Download: promauto.NewCounterVec(
+			Download: factory.NewCounterVec(
 				prometheus.CounterOpts{
 					Namespace: boringNamespace,
 					Subsystem: providersSubsystem,
@@ -117,7 +141,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 			),
 		},
 		Module: &ModuleMetrics{
-			ListVersions: promauto.NewCounterVec(
+			ListVersions: factory.NewCounterVec(
 				prometheus.CounterOpts{
 					Namespace: boringNamespace,
 					Subsystem: modulesSubsystem,
@@ -126,7 +150,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 				},
 				[]string{NamespaceLabel, NameLabel, ProviderLabel},
 			),
-			Download: promauto.NewCounterVec(
+			Download: factory.NewCounterVec(
 				prometheus.CounterOpts{
 					Namespace: boringNamespace,
 					Subsystem: modulesSubsystem,
@@ -137,7 +161,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 			),
 		},
 		Proxy: &ProxyMetrics{
-			Download: promauto.NewCounterVec(
+			Download: factory.NewCounterVec(
 				prometheus.CounterOpts{
 					Namespace: boringNamespace,
 					Subsystem: proxySubsystem,
@@ -146,7 +170,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 				},
 				[]string{},
 			),
-			Failure: promauto.NewCounterVec(
+			Failure: factory.NewCounterVec(
 				prometheus.CounterOpts{
 					Namespace: boringNamespace,
 					Subsystem: proxySubsystem,
@@ -157,7 +181,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 			),
 		},
 		Http: &HttpMetrics{
-			RequestsTotal: promauto.NewCounterVec(
+			RequestsTotal: factory.NewCounterVec(
 				prometheus.CounterOpts{
 					Namespace: httpNamespace,
 					Subsystem: requestSubsystem,
@@ -165,7 +189,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 					Help:      "The total number of HTTP requests",
 				}, []string{"method", "code"},
 			),
-			RequestDuration: promauto.NewHistogramVec(
+			RequestDuration: factory.NewHistogramVec(
 				prometheus.HistogramOpts{
 					Namespace: httpNamespace,
 					Subsystem: requestSubsystem,
@@ -175,7 +199,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 				},
 				[]string{"method", "code"},
 			),
-			RequestSize: promauto.NewSummaryVec(
+			RequestSize: factory.NewSummaryVec(
 				prometheus.SummaryOpts{
 					Namespace: httpNamespace,
 					Subsystem: requestSubsystem,
@@ -184,7 +208,7 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 				},
 				[]string{"method", "code"},
 			),
-			ResponseSize: promauto.NewSummaryVec(
+			ResponseSize: factory.NewSummaryVec(
 				prometheus.SummaryOpts{
 					Namespace: httpNamespace,
 					Subsystem: responseSubsystem,
@@ -198,3 +222,60 @@ func NewMetrics(buckets []float64) *ServerMetrics {
 
 	return metrics
 }
+
+// NewStorageMetrics returns the metrics of the storage backend.
+// They are separate from the ServerMetrics, as the storage backend is used by the CLI commands as well.
+func NewStorageMetrics() *StorageMetrics {
+	return newStorageMetrics(promauto.With(prometheus.DefaultRegisterer))
+}
+
+// NewTenantStorageMetrics returns the StorageMetrics of a tenant, which are labelled with its name
+func NewTenantStorageMetrics(tenant string) *StorageMetrics {
+	return newStorageMetrics(tenantFactory(tenant))
+}
+
+func newStorageMetrics(factory promauto.Factory) *StorageMetrics {
+	boringNamespace := "boring_registry"
+	storageSubsystem := "storage"
+
+	return &StorageMetrics{
+		OperationDuration: factory.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: boringNamespace,
+				Subsystem: storageSubsystem,
+				Name:      "operation_duration_seconds",
+				Help:      "The latencies of storage backend operations in seconds",
+				Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+			},
+			[]string{BackendLabel, OperationLabel},
+		),
+		Errors: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: boringNamespace,
+				Subsystem: storageSubsystem,
+				Name:      "errors_total",
+				Help:      "The total number of failed storage backend operations by error class",
+			},
+			[]string{BackendLabel, OperationLabel, ErrorClassLabel},
+		),
+		UploadedBytes: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: boringNamespace,
+				Subsystem: storageSubsystem,
+				Name:      "uploaded_bytes_total",
+				Help:      "The total number of bytes uploaded to the storage backend",
+			},
+			[]string{BackendLabel, OperationLabel},
+		),
+		PresignDuration: factory.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: boringNamespace,
+				Subsystem: storageSubsystem,
+				Name:      "presign_duration_seconds",
+				Help:      "The latencies of signing download URLs in seconds",
+				Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+			},
+			[]string{BackendLabel},
+		),
+	}
+}
@@ -18,6 +18,7 @@ type middleware struct {
 // WrapHandler wraps the given HTTP handler for instrumentation:
 // It reports HTTP metrics to the registered collectors.
 // Each has a constant label named "handler" with the provided handlerName as value.
+// The span of the request is named after the matched route.
 func (m *middleware) WrapHandler(handler http.Handler) http.HandlerFunc {
 	wrappedHandler := promhttp.InstrumentHandlerCounter(
 		m.metrics.RequestsTotal,
@@ -33,7 +34,10 @@ func (m *middleware) WrapHandler(handler http.Handler) http.HandlerFunc {
 		),
 	)
 
-	return wrappedHandler.ServeHTTP
+	return func(w http.ResponseWriter, r *http.Request) {
+		nameSpanAfterRoute(r)
+		wrappedHandler.ServeHTTP(w, r)
+	}
 }
 
 // NewMiddleware returns a Middleware interface.
@@ -0,0 +1,167 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TracerName is the name of the tracer of the boring-registry
+	TracerName = "github.com/boring-registry/boring-registry"
+
+	serviceName = "boring-registry"
+
+	PropagatorTraceContext = "tracecontext"
+	PropagatorBaggage      = "baggage"
+)
+
+// TracingConfig configures the export of traces
+type TracingConfig struct {
+	// Endpoint of the OTLP/HTTP receiver in the form of <host>:<port>. Traces aren't exported if it's empty
+	Endpoint string
+	// Insecure disables TLS for the connection to the OTLP/HTTP receiver
+	Insecure bool
+	// SampleRatio is the ratio of traces that are sampled, unless the parent span has already been sampled
+	SampleRatio float64
+	// Propagators are the formats in which the trace context is extracted from requests and injected into upstream requests
+	Propagators []string
+	// ServiceVersion is the version of the boring-registry reported as resource attribute
+	ServiceVersion string
+}
+
+// SetupTracing configures the global TracerProvider and TextMapPropagator.
+// The returned function flushes the remaining spans and shuts the exporter down.
+func SetupTracing(ctx context.Context, config TracingConfig) (func(context.Context) error, error) {
+	propagator, err := newPropagator(config.Propagators)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetTextMapPropagator(propagator)
+
+	if config.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	tp := NewTracerProvider(sdktrace.NewBatchSpanProcessor(exporter), config.SampleRatio, config.ServiceVersion)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// NewTracerProvider returns a TracerProvider which passes the sampled spans to the processor.
+// Tests can use a processor of an in-memory exporter, e.g. sdktrace.NewSimpleSpanProcessor(tracetest.NewInMemoryExporter()).
+func NewTracerProvider(processor sdktrace.SpanProcessor, sampleRatio float64, serviceVersion string) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(serviceVersion),
+		)),
+	)
+}
+
+func newPropagator(names []string) (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+	for _, name := range names {
+		switch name {
+		case PropagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case PropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		default:
+			return nil, fmt.Errorf("unsupported trace propagator %q, expected %s or %s", name, PropagatorTraceContext, PropagatorBaggage)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
+
+// StartSpan starts a span with the tracer of the boring-registry of the global TracerProvider
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// EndSpan records the error, if any, and ends the span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// NewTracingHandler returns a handler which starts a server span for each request.
+// The trace context is extracted from the request headers with the global TextMapPropagator.
+// Requests to the metrics, health and pprof endpoints aren't traced.
+func NewTracingHandler(handler *http.ServeMux) http.Handler {
+	return otelhttp.NewHandler(handler, serviceName,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			// The span is renamed to the route of the request by the instrumentation Middleware
+			_, pattern := handler.Handler(r)
+			return fmt.Sprintf("%s %s", r.Method, pattern)
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			switch r.URL.Path {
+			case "/metrics", "/healthz", "/readyz":
+				return false
+			}
+			return !strings.HasPrefix(r.URL.Path, "/debug/pprof/")
+		}),
+	)
+}
+
+// NewTransport returns a RoundTripper which starts a client span for each request to an upstream service.
+// The trace context is injected into the request headers with the global TextMapPropagator.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return fmt.Sprintf("%s %s", r.Method, r.URL.Host)
+		}),
+	)
+}
+
+// nameSpanAfterRoute renames the server span of the request to the path template of the matched route
+func nameSpanAfterRoute(r *http.Request) {
+	span := trace.SpanFromContext(r.Context())
+	route := mux.CurrentRoute(r)
+	if !span.IsRecording() || route == nil {
+		return
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return
+	}
+
+	// The handlers are served below a prefix, which is stripped from the URL path but not from the RequestURI
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		template = strings.TrimSuffix(u.Path, r.URL.Path) + template
+	}
+
+	span.SetName(fmt.Sprintf("%s %s", r.Method, template))
+	span.SetAttributes(semconv.HTTPRoute(template))
+}
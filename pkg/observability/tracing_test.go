@@ -0,0 +1,122 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupTestTracing() *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), 1, "test"))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return exporter
+}
+
+func TestNewTracingHandler(t *testing.T) {
+	exporter := setupTestTracing()
+	instrumentation := NewMiddleware(NewMetrics(nil).Http)
+
+	var spanContext trace.SpanContext
+	r := mux.NewRouter()
+	r.Methods("GET").Path("/{namespace}/{name}/versions").Handler(instrumentation.WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := StartSpan(r.Context(), "child")
+		spanContext = span.SpanContext()
+		span.End()
+	})))
+
+	serveMux := http.NewServeMux()
+	serveMux.Handle("/v1/modules/", http.StripPrefix("/v1/modules", r))
+	serveMux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {})
+	handler := NewTracingHandler(serveMux)
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+
+	t.Run("request with trace context", func(t *testing.T) {
+		exporter.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/v1/modules/example/vpc/versions", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := exporter.GetSpans()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, "child", spans[0].Name)
+		assert.Equal(t, "GET /v1/modules/{namespace}/{name}/versions", spans[1].Name)
+		assert.Equal(t, trace.SpanKindServer, spans[1].SpanKind)
+		assert.Equal(t, parent.TraceID(), spans[1].SpanContext.TraceID())
+		assert.Equal(t, parent.SpanID(), spans[1].Parent.SpanID())
+		assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+		assert.Equal(t, parent.TraceID(), spanContext.TraceID())
+	})
+
+	t.Run("metrics aren't traced", func(t *testing.T) {
+		exporter.Reset()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Empty(t, exporter.GetSpans())
+	})
+}
+
+func TestEndSpan(t *testing.T) {
+	exporter := setupTestTracing()
+
+	_, span := StartSpan(context.Background(), "ok")
+	EndSpan(span, nil)
+	_, span = StartSpan(context.Background(), "failed")
+	EndSpan(span, errors.New("not found"))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.Equal(t, "not found", spans[1].Status.Description)
+	assert.Len(t, spans[1].Events, 1)
+}
+
+func TestSetupTracing(t *testing.T) {
+	tests := []struct {
+		name        string
+		propagators []string
+		wantErr     bool
+	}{
+		{
+			name:        "default propagators",
+			propagators: []string{PropagatorTraceContext, PropagatorBaggage},
+		},
+		{
+			name: "no propagators",
+		},
+		{
+			name:        "unsupported propagator",
+			propagators: []string{"b3"},
+			wantErr:     true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			shutdown, err := SetupTracing(context.Background(), TracingConfig{Propagators: tc.propagators})
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+)
+
+// DownloadRecorder counts the downloads of provider versions
+type DownloadRecorder interface {
+	RecordProviderDownload(namespace, name, version string)
+}
+
+type downloadTrackingMiddleware struct {
+	recorder DownloadRecorder
+	Service
+}
+
+func (mw downloadTrackingMiddleware) GetProvider(ctx context.Context, namespace, name, version, os, arch string) (*core.Provider, error) {
+	provider, err := mw.Service.GetProvider(ctx, namespace, name, version, os, arch)
+	if err == nil {
+		mw.recorder.RecordProviderDownload(namespace, name, version)
+	}
+	return provider, err
+}
+
+// DownloadTrackingMiddleware is a Service middleware that records successful downloads of provider versions
+func DownloadTrackingMiddleware(recorder DownloadRecorder) Middleware {
+	return func(next Service) Service {
+		return &downloadTrackingMiddleware{
+			recorder: recorder,
+			Service:  next,
+		}
+	}
+}
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
 	o11y "github.com/boring-registry/boring-registry/pkg/observability"
@@ -76,3 +77,33 @@ func downloadEndpoint(svc Service, metrics *o11y.ProviderMetrics) endpoint.Endpo
 		}, nil
 	}
 }
+
+// downloadsSummaryResponse is the downloads summary in the format of the public registry
+type downloadsSummaryResponse struct {
+	Data downloadsSummaryData `json:"data"`
+}
+
+type downloadsSummaryData struct {
+	Type       string               `json:"type"`
+	ID         string               `json:"id"`
+	Attributes core.DownloadSummary `json:"attributes"`
+}
+
+func downloadsSummaryEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listRequest)
+
+		res, err := svc.GetProviderDownloadsSummary(ctx, req.namespace, req.name)
+		if err != nil {
+			return nil, err
+		}
+
+		return downloadsSummaryResponse{
+			Data: downloadsSummaryData{
+				Type:       "provider-downloads-summary",
+				ID:         fmt.Sprintf("%s/%s", req.namespace, req.name),
+				Attributes: *res,
+			},
+		}, nil
+	}
+}
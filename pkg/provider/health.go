@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// signingKeysNamespacesTTL is the duration for which the SigningKeysCheck caches the namespaces,
+// because listing them reads the keys of all providers
+const signingKeysNamespacesTTL = 5 * time.Minute
+
+// SigningKeysCheck verifies that the signing keys of every namespace with providers can be read and parsed,
+// as the releases of the providers can't be served without them.
+type SigningKeysCheck struct {
+	storage Storage
+
+	mu         sync.Mutex
+	namespaces []string
+	expires    time.Time
+	now        func() time.Time
+}
+
+// NewSigningKeysCheck returns a SigningKeysCheck of the providers in storage.
+func NewSigningKeysCheck(storage Storage) *SigningKeysCheck {
+	return &SigningKeysCheck{
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+// Check reads and parses the signing keys of each namespace.
+func (c *SigningKeysCheck) Check(ctx context.Context) error {
+	namespaces, err := c.listNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, namespace := range namespaces {
+		keys, err := c.storage.SigningKeys(ctx, namespace)
+		if err != nil {
+			return fmt.Errorf("failed to read the signing keys of namespace %s: %w", namespace, err)
+		}
+		if len(keys.GPGPublicKeys) == 0 {
+			return fmt.Errorf("namespace %s has no signing keys", namespace)
+		}
+		for _, key := range keys.GPGPublicKeys {
+			if _, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key.ASCIIArmor)); err != nil {
+				return fmt.Errorf("failed to parse the signing key %s of namespace %s: %w", key.KeyID, namespace, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *SigningKeysCheck) listNamespaces(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.namespaces != nil && c.now().Before(c.expires) {
+		return c.namespaces, nil
+	}
+
+	providers, err := c.storage.ListProviders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the providers: %w", err)
+	}
+
+	namespaces := []string{}
+	seen := make(map[string]bool)
+	for _, p := range providers {
+		if !seen[p.Namespace] {
+			seen[p.Namespace] = true
+			namespaces = append(namespaces, p.Namespace)
+		}
+	}
+
+	c.namespaces = namespaces
+	c.expires = c.now().Add(signingKeysNamespacesTTL)
+	return namespaces, nil
+}
@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type signingKeysStorage struct {
+	Storage
+	providers   []core.Provider
+	signingKeys map[string]*core.SigningKeys
+	listCalls   int
+}
+
+func (s *signingKeysStorage) ListProviders(ctx context.Context) ([]core.Provider, error) {
+	s.listCalls++
+	return s.providers, nil
+}
+
+func (s *signingKeysStorage) SigningKeys(ctx context.Context, namespace string) (*core.SigningKeys, error) {
+	keys, ok := s.signingKeys[namespace]
+	if !ok {
+		return nil, core.ErrObjectNotFound
+	}
+	return keys, nil
+}
+
+func armoredPublicKey(t *testing.T) string {
+	entity, err := openpgp.NewEntity("registry", "", "registry@example.com", nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	return buf.String()
+}
+
+func TestSigningKeysCheck_Check(t *testing.T) {
+	key := core.GPGPublicKey{KeyID: "51852D87348FFC4C", ASCIIArmor: armoredPublicKey(t)}
+	providers := []core.Provider{
+		{Namespace: "hashicorp", Name: "aws"},
+		{Namespace: "hashicorp", Name: "random"},
+	}
+
+	testCases := []struct {
+		description string
+		signingKeys map[string]*core.SigningKeys
+		wantErr     string
+	}{
+		{
+			description: "signing keys are valid",
+			signingKeys: map[string]*core.SigningKeys{"hashicorp": {GPGPublicKeys: []core.GPGPublicKey{key}}},
+		},
+		{
+			description: "signing keys are missing",
+			wantErr:     "failed to read the signing keys of namespace hashicorp: failed to locate object",
+		},
+		{
+			description: "signing keys are empty",
+			signingKeys: map[string]*core.SigningKeys{"hashicorp": {}},
+			wantErr:     "namespace hashicorp has no signing keys",
+		},
+		{
+			description: "signing key is invalid",
+			signingKeys: map[string]*core.SigningKeys{"hashicorp": {GPGPublicKeys: []core.GPGPublicKey{{KeyID: "ABC", ASCIIArmor: "test"}}}},
+			wantErr:     "failed to parse the signing key ABC of namespace hashicorp",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.description, func(t *testing.T) {
+			check := NewSigningKeysCheck(&signingKeysStorage{providers: providers, signingKeys: tc.signingKeys})
+
+			err := check.Check(context.Background())
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSigningKeysCheck_listNamespaces(t *testing.T) {
+	s := &signingKeysStorage{providers: []core.Provider{
+		{Namespace: "hashicorp", Name: "aws"},
+		{Namespace: "hashicorp", Name: "random"},
+		{Namespace: "integrations", Name: "github"},
+	}}
+	check := NewSigningKeysCheck(s)
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	check.now = func() time.Time { return now }
+
+	namespaces, err := check.listNamespaces(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hashicorp", "integrations"}, namespaces)
+
+	// The namespaces are cached, so that the providers aren't listed on every check
+	now = now.Add(signingKeysNamespacesTTL - time.Second)
+	_, err = check.listNamespaces(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.listCalls)
+
+	now = now.Add(time.Second)
+	_, err = check.listNamespaces(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.listCalls)
+}
@@ -6,6 +6,9 @@ import (
 	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Middleware is a Service middleware.
@@ -35,11 +38,11 @@ func (mw loggingMiddleware) ListProviderVersions(ctx context.Context, namespace,
 		)
 
 		if err != nil {
-			logger.Error("failed to list provider versions", slog.String("err", err.Error()))
+			logger.ErrorContext(ctx, "failed to list provider versions", slog.String("err", err.Error()))
 			return
 		}
 
-		logger.Info("list provider version", slog.String("took", time.Since(begin).String()))
+		logger.InfoContext(ctx, "list provider version", slog.String("took", time.Since(begin).String()))
 	}(time.Now())
 
 	return mw.next.ListProviderVersions(ctx, namespace, name)
@@ -59,12 +62,125 @@ func (mw loggingMiddleware) GetProvider(ctx context.Context, namespace, name, ve
 		)
 
 		if err != nil {
-			logger.Error("failed to get provider", slog.String("err", err.Error()))
+			logger.ErrorContext(ctx, "failed to get provider", slog.String("err", err.Error()))
+			return
+		}
+
+		logger.InfoContext(ctx, "get provider", slog.String("took", time.Since(begin).String()))
+	}(time.Now())
+
+	return mw.next.GetProvider(ctx, namespace, name, version, os, arch)
+}
+
+func (mw loggingMiddleware) ListProviders(ctx context.Context) (providers []core.Provider, err error) {
+	defer func(begin time.Time) {
+		logger := slog.Default().With(slog.String("op", "ListProviders"))
+
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to list providers", slog.String("err", err.Error()))
+			return
+		}
+
+		logger.InfoContext(ctx, "list providers", slog.String("took", time.Since(begin).String()), slog.Int("providers", len(providers)))
+	}(time.Now())
+
+	return mw.next.ListProviders(ctx)
+}
+
+func (mw loggingMiddleware) GetSigningKeys(ctx context.Context, namespace string) (keys *core.SigningKeys, err error) {
+	defer func(begin time.Time) {
+		logger := slog.Default().With(
+			slog.String("op", "GetSigningKeys"),
+			slog.String("namespace", namespace),
+		)
+
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to get signing keys", slog.String("err", err.Error()))
+			return
+		}
+
+		logger.InfoContext(ctx, "get signing keys", slog.String("took", time.Since(begin).String()))
+	}(time.Now())
+
+	return mw.next.GetSigningKeys(ctx, namespace)
+}
+
+func (mw loggingMiddleware) GetProviderDownloadsSummary(ctx context.Context, namespace, name string) (summary *core.DownloadSummary, err error) {
+	defer func(begin time.Time) {
+		logger := slog.Default().With(
+			slog.String("op", "GetProviderDownloadsSummary"),
+			slog.Group("provider",
+				slog.String("namespace", namespace),
+				slog.String("name", name),
+			),
+		)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to get provider downloads summary", slog.String("err", err.Error()))
 			return
 		}
 
-		logger.Info("get provider", slog.String("took", time.Since(begin).String()))
+		logger.InfoContext(ctx, "get provider downloads summary", slog.String("took", time.Since(begin).String()))
 	}(time.Now())
 
+	return mw.next.GetProviderDownloadsSummary(ctx, namespace, name)
+}
+
+type tracingMiddleware struct {
+	next Service
+}
+
+// TracingMiddleware is a Service middleware, which starts a span for each call.
+func TracingMiddleware() Middleware {
+	return func(next Service) Service {
+		return &tracingMiddleware{
+			next: next,
+		}
+	}
+}
+
+func (mw tracingMiddleware) ListProviderVersions(ctx context.Context, namespace, name string) (versions *core.ProviderVersions, err error) {
+	ctx, span := o11y.StartSpan(ctx, "provider.ListProviderVersions",
+		attribute.String(o11y.NamespaceLabel, namespace),
+		attribute.String(o11y.NameLabel, name),
+	)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	return mw.next.ListProviderVersions(ctx, namespace, name)
+}
+
+func (mw tracingMiddleware) GetProvider(ctx context.Context, namespace, name, version, os, arch string) (provider *core.Provider, err error) {
+	ctx, span := o11y.StartSpan(ctx, "provider.GetProvider",
+		attribute.String(o11y.NamespaceLabel, namespace),
+		attribute.String(o11y.NameLabel, name),
+		attribute.String(o11y.VersionLabel, version),
+		attribute.String(o11y.OsLabel, os),
+		attribute.String(o11y.ArchLabel, arch),
+	)
+	defer func() { o11y.EndSpan(span, err) }()
+
 	return mw.next.GetProvider(ctx, namespace, name, version, os, arch)
 }
+
+func (mw tracingMiddleware) ListProviders(ctx context.Context) (providers []core.Provider, err error) {
+	ctx, span := o11y.StartSpan(ctx, "provider.ListProviders")
+	defer func() { o11y.EndSpan(span, err) }()
+
+	return mw.next.ListProviders(ctx)
+}
+
+func (mw tracingMiddleware) GetSigningKeys(ctx context.Context, namespace string) (keys *core.SigningKeys, err error) {
+	ctx, span := o11y.StartSpan(ctx, "provider.GetSigningKeys", attribute.String(o11y.NamespaceLabel, namespace))
+	defer func() { o11y.EndSpan(span, err) }()
+
+	return mw.next.GetSigningKeys(ctx, namespace)
+}
+
+func (mw tracingMiddleware) GetProviderDownloadsSummary(ctx context.Context, namespace, name string) (summary *core.DownloadSummary, err error) {
+	ctx, span := o11y.StartSpan(ctx, "provider.GetProviderDownloadsSummary",
+		attribute.String(o11y.NamespaceLabel, namespace),
+		attribute.String(o11y.NameLabel, name),
+	)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	return mw.next.GetProviderDownloadsSummary(ctx, namespace, name)
+}
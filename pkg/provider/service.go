@@ -2,6 +2,8 @@ package provider
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
 )
@@ -11,6 +13,12 @@ import (
 type Service interface {
 	GetProvider(ctx context.Context, namespace, name, version, os, arch string) (*core.Provider, error)
 	ListProviderVersions(ctx context.Context, namespace, name string) (*core.ProviderVersions, error)
+	// ListProviders returns all providers sorted by namespace and name
+	ListProviders(ctx context.Context) ([]core.Provider, error)
+	// GetSigningKeys returns the keys that sign the providers of a namespace
+	GetSigningKeys(ctx context.Context, namespace string) (*core.SigningKeys, error)
+	// GetProviderDownloadsSummary sums up the recorded downloads of all versions of the provider
+	GetProviderDownloadsSummary(ctx context.Context, namespace, name string) (*core.DownloadSummary, error)
 }
 
 type service struct {
@@ -56,5 +64,50 @@ func (s *service) GetProvider(ctx context.Context, namespace, name, version, os,
 }
 
 func (s *service) ListProviderVersions(ctx context.Context, namespace, name string) (*core.ProviderVersions, error) {
-	return s.storage.ListProviderVersions(ctx, namespace, name)
+	res, err := s.storage.ListProviderVersions(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations, err := s.storage.GetProviderAnnotations(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range res.Versions {
+		if a, ok := annotations[v.Version]; ok {
+			res.Versions[i].Deprecation = a.Deprecation
+			res.Versions[i].Labels = a.Labels
+		}
+	}
+
+	return res, nil
+}
+
+func (s *service) ListProviders(ctx context.Context) ([]core.Provider, error) {
+	res, err := s.storage.ListProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Namespace != res[j].Namespace {
+			return res[i].Namespace < res[j].Namespace
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res, nil
+}
+
+func (s *service) GetSigningKeys(ctx context.Context, namespace string) (*core.SigningKeys, error) {
+	return s.storage.SigningKeys(ctx, namespace)
+}
+
+func (s *service) GetProviderDownloadsSummary(ctx context.Context, namespace, name string) (*core.DownloadSummary, error) {
+	stats, err := s.storage.GetProviderDownloadStats(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := stats.Summary(time.Now())
+	return &summary, nil
 }
@@ -11,6 +11,8 @@ import (
 type Storage interface {
 	GetProvider(ctx context.Context, namespace, name, version, os, arch string) (*core.Provider, error)
 	ListProviderVersions(ctx context.Context, namespace, name string) (*core.ProviderVersions, error)
+	// ListProviders returns the namespace and name of all providers with at least one release
+	ListProviders(ctx context.Context) ([]core.Provider, error)
 
 	// UploadProviderReleaseFiles is used to upload all artifacts which make up a provider release
 	// https://developer.hashicorp.com/terraform/registry/providers/publishing#manually-preparing-a-release
@@ -18,4 +20,17 @@ type Storage interface {
 
 	// SigningKeys downloads and returns the keys for a given namespace from the configured storage backend
 	SigningKeys(ctx context.Context, namespace string) (*core.SigningKeys, error)
+	// UploadSigningKeys stores the keys for a given namespace and overwrites existing keys
+	UploadSigningKeys(ctx context.Context, namespace string, signingKeys *core.SigningKeys) error
+
+	// GetProviderAnnotations returns the annotations of all versions of the provider, which are empty if none were stored
+	GetProviderAnnotations(ctx context.Context, namespace, name string) (core.VersionAnnotations, error)
+	// UpdateProviderAnnotations applies update to the stored annotations of all versions of the provider without losing concurrent updates.
+	// The update can be called more than once, if the annotations are modified concurrently.
+	UpdateProviderAnnotations(ctx context.Context, namespace, name string, update func(core.VersionAnnotations)) error
+
+	// GetProviderDownloadStats returns the daily downloads of all versions of the provider, which are empty if none were stored
+	GetProviderDownloadStats(ctx context.Context, namespace, name string) (core.DownloadStats, error)
+	// MergeProviderDownloadStats adds the daily downloads to the stored stats of the provider without losing concurrent updates
+	MergeProviderDownloadStats(ctx context.Context, namespace, name string, stats core.DownloadStats) error
 }
@@ -59,6 +59,21 @@ func MakeHandler(svc Service, auth endpoint.Middleware, metrics *o11y.ProviderMe
 		),
 	)
 
+	r.Methods("GET").Path(`/{namespace}/{name}/downloads/summary`).Handler(
+		instrumentation.WrapHandler(
+			httptransport.NewServer(
+				auth(downloadsSummaryEndpoint(svc)),
+				decodeListRequest,
+				httptransport.EncodeJSONResponse,
+				append(
+					options,
+					httptransport.ServerBefore(extractMuxVars(varNamespace, varName)),
+					httptransport.ServerBefore(jwt.HTTPToContext()),
+				)...,
+			),
+		),
+	)
+
 	return r
 }
 
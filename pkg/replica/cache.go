@@ -0,0 +1,68 @@
+package replica
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// versionsCacheTTL is how long the version lists of the upstream are reused.
+	// It's short, so that new versions of the central registry are resolvable after a moment.
+	versionsCacheTTL = 30 * time.Second
+	// maxVersionsCacheEntries limits the memory of the cache, expired entries are evicted once it's reached
+	maxVersionsCacheEntries = 10000
+)
+
+// versionsCache briefly caches the version lists of the upstream, so that not every version listing of Terraform is requested from the central registry.
+// Lists that aren't found upstream are cached as well, but other errors aren't, so that an unavailable upstream is retried.
+type versionsCache[T any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]versionsCacheEntry[T]
+}
+
+type versionsCacheEntry[T any] struct {
+	value   T
+	err     error
+	expires time.Time
+}
+
+func newVersionsCache[T any](ttl time.Duration) *versionsCache[T] {
+	return &versionsCache[T]{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]versionsCacheEntry[T]),
+	}
+}
+
+// get returns the cached list of the key, or loads and caches it
+func (c *versionsCache[T]) get(key string, load func() (T, error)) (T, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.value, entry.err
+	}
+
+	value, err := load()
+	if err != nil && !errors.Is(err, ErrUpstreamNotFound) {
+		return value, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.entries) >= maxVersionsCacheEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) < maxVersionsCacheEntries {
+		c.entries[key] = versionsCacheEntry[T]{value: value, err: err, expires: now.Add(c.ttl)}
+	}
+	return value, err
+}
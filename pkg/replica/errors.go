@@ -0,0 +1,9 @@
+package replica
+
+import "errors"
+
+var (
+	ErrUpstreamNotFound    = errors.New("not found upstream")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	ErrUntrustedSignature  = errors.New("untrusted signature")
+)
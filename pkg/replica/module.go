@@ -0,0 +1,159 @@
+package replica
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
+
+	"github.com/hashicorp/go-version"
+)
+
+// moduleStorage reads the module versions through from the upstream, which are missing in the storage of the edge registry.
+// The versions are stored when they're requested for the first time, and are served from the storage afterwards.
+type moduleStorage struct {
+	module.Storage
+	upstream *Upstream
+	logger   *slog.Logger
+}
+
+// GetModule fetches the module version from the upstream, in case it isn't stored yet
+func (s *moduleStorage) GetModule(ctx context.Context, namespace, name, provider, version string) (core.Module, error) {
+	m, err := s.Storage.GetModule(ctx, namespace, name, provider, version)
+	if !errors.Is(err, module.ErrModuleNotFound) {
+		return m, err
+	}
+
+	if fetchErr := s.fetch(ctx, namespace, name, provider, version); fetchErr != nil {
+		if errors.Is(fetchErr, ErrUpstreamNotFound) {
+			return m, err
+		}
+		return core.Module{}, fmt.Errorf("failed to read module %s/%s/%s/%s through from the upstream: %w", namespace, name, provider, version, fetchErr)
+	}
+	s.logger.InfoContext(ctx, "read module through from the upstream",
+		slog.String("namespace", namespace), slog.String("name", name), slog.String("provider", provider), slog.String("version", version))
+
+	return s.Storage.GetModule(ctx, namespace, name, provider, version)
+}
+
+// fetch stores the archive or the git source of the module version in the storage
+func (s *moduleStorage) fetch(ctx context.Context, namespace, name, provider, version string) error {
+	location, err := s.upstream.moduleDownloadURL(ctx, namespace, name, provider, version)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(location, "git::") {
+		_, err = s.Storage.UploadModuleSource(ctx, namespace, name, provider, version, location)
+	} else {
+		var format string
+		if location, format, err = archiveLocation(location); err != nil {
+			return err
+		}
+
+		body, err := s.upstream.download(ctx, location)
+		if err != nil {
+			return fmt.Errorf("failed to download the archive: %w", err)
+		}
+		defer body.Close()
+		_, err = s.Storage.UploadModule(ctx, namespace, name, provider, version, format, body)
+	}
+
+	// The version was stored by a concurrent request in the meantime
+	if errors.Is(err, module.ErrModuleAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+// ListModuleVersions lists the versions of the upstream and of the storage, so that Terraform can resolve versions that aren't stored yet.
+// The versions of the storage are listed, in case the upstream is unavailable.
+func (s *moduleStorage) ListModuleVersions(ctx context.Context, namespace, name, provider string) ([]core.Module, error) {
+	modules, err := s.Storage.ListModuleVersions(ctx, namespace, name, provider)
+
+	upstream, upstreamErr := s.upstream.listModuleVersions(ctx, namespace, name, provider)
+	if upstreamErr != nil {
+		if !errors.Is(upstreamErr, ErrUpstreamNotFound) {
+			s.logger.WarnContext(ctx, "failed to list the module versions of the upstream",
+				slog.String("namespace", namespace), slog.String("name", name), slog.String("provider", provider), slog.String("err", upstreamErr.Error()))
+		}
+		return modules, err
+	}
+	if len(upstream) == 0 {
+		return modules, err
+	}
+
+	stored := make(map[string]bool, len(modules))
+	for _, m := range modules {
+		stored[m.Version] = true
+	}
+	for _, m := range upstream {
+		if !stored[m.Version] {
+			modules = append(modules, m)
+		}
+	}
+
+	sort.Slice(modules, func(i, j int) bool {
+		return versionLess(modules[i].Version, modules[j].Version)
+	})
+	return modules, nil
+}
+
+// archiveLocation removes the archive hint from the download URL and returns the archive format.
+// The format is derived from the file name, in case the upstream didn't add the hint.
+func archiveLocation(location string) (string, string, error) {
+	var format string
+	if base, query, ok := strings.Cut(location, "?"); ok {
+		// The query is filtered instead of re-encoded to keep signed URLs byte for byte
+		var kept []string
+		for _, pair := range strings.Split(query, "&") {
+			if value, ok := strings.CutPrefix(pair, "archive="); ok {
+				format, _ = url.QueryUnescape(value)
+				continue
+			}
+			kept = append(kept, pair)
+		}
+		location = base
+		if len(kept) > 0 {
+			location += "?" + strings.Join(kept, "&")
+		}
+	}
+
+	if format == "" {
+		u, err := url.Parse(location)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse the download URL: %w", err)
+		}
+		format, _ = module.ArchiveFormatFromName(path.Base(u.Path))
+	}
+	if !module.IsArchiveFormat(format) {
+		return "", "", fmt.Errorf("unsupported module archive format %q of the download URL", format)
+	}
+	return location, format, nil
+}
+
+// versionLess compares semantic versions and falls back to comparing the strings for invalid versions
+func versionLess(a, b string) bool {
+	va, errA := version.NewVersion(a)
+	vb, errB := version.NewVersion(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return va.LessThan(vb)
+}
+
+// NewModuleStorage returns a module.Storage that reads the module versions through from the upstream, which are missing in the storage
+func NewModuleStorage(storage module.Storage, upstream *Upstream) module.Storage {
+	return &moduleStorage{
+		Storage:  storage,
+		upstream: upstream,
+		logger:   slog.Default().With(slog.String("component", "replica")),
+	}
+}
@@ -0,0 +1,178 @@
+package replica
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleStorage_GetModule(t *testing.T) {
+	var downloads atomic.Int32
+	_, upstream := newCentralRegistry(t, map[string]http.HandlerFunc{
+		"/v1/modules/acme/vpc/aws/1.0.0/download": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Terraform-Get", "/v1/proxy/modules/acme/vpc/aws/acme-vpc-aws-1.0.0.zip?archive=zip")
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"/v1/proxy/modules/acme/vpc/aws/acme-vpc-aws-1.0.0.zip": func(w http.ResponseWriter, r *http.Request) {
+			downloads.Add(1)
+			_, _ = w.Write([]byte("archive"))
+		},
+		"/v1/modules/acme/vpc/aws/2.0.0/download": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Terraform-Get", "git::https://git.example.com/vpc.git?ref=v2.0.0")
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"/v1/modules/acme/vpc/aws/3.0.0/download": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	})
+
+	testCases := []struct {
+		name           string
+		version        string
+		expected       core.Module
+		expectNotFound bool
+		expectError    bool
+	}{
+		{
+			name:     "archive",
+			version:  "1.0.0",
+			expected: core.Module{Namespace: "acme", Name: "vpc", Provider: "aws", Version: "1.0.0", ArchiveFormat: "zip"},
+		},
+		{
+			name:    "git source",
+			version: "2.0.0",
+			expected: core.Module{Namespace: "acme", Name: "vpc", Provider: "aws", Version: "2.0.0",
+				DownloadURL: "git::https://git.example.com/vpc.git?ref=v2.0.0", Source: "git::https://git.example.com/vpc.git?ref=v2.0.0"},
+		},
+		{
+			name:        "unavailable upstream",
+			version:     "3.0.0",
+			expectError: true,
+		},
+		{
+			name:           "not found upstream",
+			version:        "4.0.0",
+			expectNotFound: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			s := NewModuleStorage(module.NewInmemStorage(), upstream)
+
+			m, err := s.GetModule(context.Background(), "acme", "vpc", "aws", tc.version)
+			switch {
+			case tc.expectNotFound:
+				assert.ErrorIs(err, module.ErrModuleNotFound)
+			case tc.expectError:
+				assert.Error(err)
+				assert.NotErrorIs(err, module.ErrModuleNotFound)
+			default:
+				assert.NoError(err)
+				assert.Equal(tc.expected, m)
+			}
+		})
+	}
+
+	// Stored versions are served from the storage
+	downloads.Store(0)
+	s := NewModuleStorage(module.NewInmemStorage(), upstream)
+	for i := 0; i < 2; i++ {
+		_, err := s.GetModule(context.Background(), "acme", "vpc", "aws", "1.0.0")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), downloads.Load())
+}
+
+func TestModuleStorage_ListModuleVersions(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	_, upstream := newCentralRegistry(t, map[string]http.HandlerFunc{
+		"/v1/modules/acme/vpc/aws/versions": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"modules":[{"versions":[{"version":"1.10.0","deprecation":{"reason":"use 2.0.0"}},{"version":"1.2.0"}]}]}`))
+		},
+		"/v1/modules/acme/subnet/aws/versions": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+	})
+
+	storage := module.NewInmemStorage()
+	for _, m := range []core.Module{{Name: "vpc", Version: "1.2.0"}, {Name: "vpc", Version: "0.9.0"}, {Name: "subnet", Version: "1.0.0"}} {
+		_, err := storage.UploadModuleSource(ctx, "acme", m.Name, "aws", m.Version, "git::https://git.example.com/"+m.Name+".git?ref=v"+m.Version)
+		assert.NoError(err)
+	}
+	s := NewModuleStorage(storage, upstream)
+
+	modules, err := s.ListModuleVersions(ctx, "acme", "vpc", "aws")
+	if assert.NoError(err) {
+		var versions []string
+		for _, m := range modules {
+			versions = append(versions, m.Version)
+		}
+		assert.Equal([]string{"0.9.0", "1.2.0", "1.10.0"}, versions)
+		assert.Equal(&core.Annotations{Deprecation: &core.Deprecation{Reason: "use 2.0.0"}}, modules[2].Annotations)
+	}
+
+	// The stored versions are listed, if the upstream is unavailable
+	modules, err = s.ListModuleVersions(ctx, "acme", "subnet", "aws")
+	if assert.NoError(err) && assert.Len(modules, 1) {
+		assert.Equal("1.0.0", modules[0].Version)
+	}
+}
+
+func TestArchiveLocation(t *testing.T) {
+	testCases := []struct {
+		name             string
+		location         string
+		expectedLocation string
+		expectedFormat   string
+		expectError      bool
+	}{
+		{
+			name:             "archive hint of a signed URL",
+			location:         "https://storage.example.com/acme-vpc-aws-1.0.0?X-Amz-Signature=a%2Fb&archive=tar.gz",
+			expectedLocation: "https://storage.example.com/acme-vpc-aws-1.0.0?X-Amz-Signature=a%2Fb",
+			expectedFormat:   "tar.gz",
+		},
+		{
+			name:             "archive hint only",
+			location:         "https://registry.example.com/v1/proxy/acme-vpc-aws-1.0.0.zip?archive=zip",
+			expectedLocation: "https://registry.example.com/v1/proxy/acme-vpc-aws-1.0.0.zip",
+			expectedFormat:   "zip",
+		},
+		{
+			name:             "file extension",
+			location:         "https://storage.example.com/acme-vpc-aws-1.0.0.tgz?X-Amz-Signature=abc",
+			expectedLocation: "https://storage.example.com/acme-vpc-aws-1.0.0.tgz?X-Amz-Signature=abc",
+			expectedFormat:   "tgz",
+		},
+		{
+			name:        "unknown format",
+			location:    "https://storage.example.com/acme-vpc-aws-1.0.0",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			location, format, err := archiveLocation(tc.location)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedLocation, location)
+			assert.Equal(t, tc.expectedFormat, format)
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package replica
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/provider"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// providerStorage reads the provider releases through from the upstream, which are missing in the storage of the edge registry.
+// The release files are stored when a platform is requested for the first time.
+// Their signature is verified with the signing keys of the namespace in the edge registry or the trusted keys, never with the keys of the upstream.
+type providerStorage struct {
+	provider.Storage
+	upstream    *Upstream
+	trustedKeys []core.GPGPublicKey
+	logger      *slog.Logger
+}
+
+// GetProvider fetches the provider package from the upstream, in case it isn't stored yet
+func (s *providerStorage) GetProvider(ctx context.Context, namespace, name, version, os, arch string) (*core.Provider, error) {
+	p, err := s.Storage.GetProvider(ctx, namespace, name, version, os, arch)
+	if !isProviderNotFound(err) {
+		return p, err
+	}
+
+	if fetchErr := s.fetch(ctx, namespace, name, version, os, arch); fetchErr != nil {
+		if errors.Is(fetchErr, ErrUpstreamNotFound) {
+			return p, err
+		}
+		return nil, fmt.Errorf("failed to read provider %s/%s/%s/%s_%s through from the upstream: %w", namespace, name, version, os, arch, fetchErr)
+	}
+	s.logger.InfoContext(ctx, "read provider through from the upstream",
+		slog.String("namespace", namespace), slog.String("name", name), slog.String("version", version), slog.String("os", os), slog.String("arch", arch))
+
+	return s.Storage.GetProvider(ctx, namespace, name, version, os, arch)
+}
+
+// fetch stores the signing keys, SHA256SUMS, SHA256SUMS.sig and archive of the provider package in the storage.
+// The signature and the checksum of the archive are verified, before anything is stored.
+func (s *providerStorage) fetch(ctx context.Context, namespace, name, version, os, arch string) error {
+	upstream, err := s.upstream.getProvider(ctx, namespace, name, version, os, arch)
+	if err != nil {
+		return err
+	}
+
+	sums, err := s.downloadFile(ctx, upstream.SHASumsURL)
+	if err != nil {
+		return fmt.Errorf("failed to download SHA256SUMS: %w", err)
+	}
+	sig, err := s.downloadFile(ctx, upstream.SHASumsSignatureURL)
+	if err != nil {
+		return fmt.Errorf("failed to download SHA256SUMS.sig: %w", err)
+	}
+	trusted, err := s.verifySignature(ctx, namespace, sums, sig)
+	if err != nil {
+		return err
+	}
+
+	sha256Sums, err := core.NewSha256Sums(upstream.ShasumFileName(), bytes.NewReader(sums))
+	if err != nil {
+		return fmt.Errorf("failed to parse SHA256SUMS: %w", err)
+	}
+	checksum, err := sha256Sums.Checksum(upstream.ArchiveFileName())
+	if err != nil {
+		return err
+	}
+
+	// The archive is buffered in a temporary file, so that it's only stored if its checksum matches
+	archive, err := s.downloadArchive(ctx, upstream.DownloadURL, checksum)
+	if err != nil {
+		return err
+	}
+	defer removeTemp(archive)
+
+	if trusted != nil {
+		if err := s.addSigningKey(ctx, namespace, *trusted); err != nil {
+			return fmt.Errorf("failed to store the signing key: %w", err)
+		}
+	}
+
+	// The archive is stored last, as the storage only finds the provider once its archive exists
+	files := []struct {
+		name string
+		body io.Reader
+	}{
+		{upstream.ShasumFileName(), bytes.NewReader(sums)},
+		{upstream.ShasumSignatureFileName(), bytes.NewReader(sig)},
+		{upstream.ArchiveFileName(), archive},
+	}
+	for _, f := range files {
+		// The SHA256SUMS are shared by all platforms of the version, so they're likely stored already
+		err := s.Storage.UploadProviderReleaseFiles(ctx, namespace, name, f.name, f.body)
+		if err != nil && !errors.Is(err, core.ErrObjectAlreadyExists) {
+			return fmt.Errorf("failed to store %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *providerStorage) downloadFile(ctx context.Context, location string) ([]byte, error) {
+	body, err := s.upstream.download(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// downloadArchive writes the archive to a temporary file and verifies its checksum.
+// The returned file is positioned at its start, and has to be closed and removed by the caller.
+func (s *providerStorage) downloadArchive(ctx context.Context, location, checksum string) (*os.File, error) {
+	body, err := s.upstream.download(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the archive: %w", err)
+	}
+	defer body.Close()
+
+	f, err := os.CreateTemp("", "boring-registry-replica-*")
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), body)
+	if err == nil {
+		if sum := hex.EncodeToString(h.Sum(nil)); sum != checksum {
+			err = fmt.Errorf("checksum %s of the archive doesn't match the checksum %s in SHA256SUMS", sum, checksum)
+		}
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		removeTemp(f)
+		return nil, err
+	}
+	return f, nil
+}
+
+func removeTemp(f *os.File) {
+	f.Close()
+	_ = os.Remove(f.Name())
+}
+
+// verifySignature verifies the signature of SHA256SUMS with the signing keys of the namespace, which are stored in the edge registry.
+// Otherwise, the trusted key that signed it is returned, which has to be added to the signing keys of the namespace.
+// The keys of the upstream aren't trusted, as the upstream could sign a tampered release with a key of its own.
+func (s *providerStorage) verifySignature(ctx context.Context, namespace string, sums, sig []byte) (*core.GPGPublicKey, error) {
+	stored, err := s.Storage.SigningKeys(ctx, namespace)
+	if err != nil && !errors.Is(err, core.ErrObjectNotFound) {
+		return nil, fmt.Errorf("failed to read the signing keys of the namespace: %w", err)
+	}
+	if stored != nil && len(stored.GPGPublicKeys) > 0 && stored.IsValidSha256Sums(sums, sig) == nil {
+		return nil, nil
+	}
+
+	for _, key := range s.trustedKeys {
+		keys := core.SigningKeys{GPGPublicKeys: []core.GPGPublicKey{key}}
+		if keys.IsValidSha256Sums(sums, sig) == nil {
+			return &key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: SHA256SUMS isn't signed by a signing key of namespace %s or a trusted key", ErrUntrustedSignature, namespace)
+}
+
+// addSigningKey adds the trusted key to the signing keys of the namespace, unless it's stored already
+func (s *providerStorage) addSigningKey(ctx context.Context, namespace string, key core.GPGPublicKey) error {
+	stored, err := s.Storage.SigningKeys(ctx, namespace)
+	if errors.Is(err, core.ErrObjectNotFound) {
+		return s.Storage.UploadSigningKeys(ctx, namespace, &core.SigningKeys{GPGPublicKeys: []core.GPGPublicKey{key}})
+	} else if err != nil {
+		return err
+	}
+
+	for _, k := range stored.GPGPublicKeys {
+		if k.KeyID == key.KeyID {
+			return nil
+		}
+	}
+	stored.GPGPublicKeys = append(stored.GPGPublicKeys, key)
+	return s.Storage.UploadSigningKeys(ctx, namespace, stored)
+}
+
+// ListProviderVersions lists the versions of the upstream and of the storage, so that Terraform can resolve versions that aren't stored yet.
+// The platforms of the upstream are listed, as each of them is read through when it's requested.
+// The versions of the storage are listed, in case the upstream is unavailable.
+func (s *providerStorage) ListProviderVersions(ctx context.Context, namespace, name string) (*core.ProviderVersions, error) {
+	versions, err := s.Storage.ListProviderVersions(ctx, namespace, name)
+
+	upstream, upstreamErr := s.upstream.listProviderVersions(ctx, namespace, name)
+	if upstreamErr != nil {
+		if !errors.Is(upstreamErr, ErrUpstreamNotFound) {
+			s.logger.WarnContext(ctx, "failed to list the provider versions of the upstream",
+				slog.String("namespace", namespace), slog.String("name", name), slog.String("err", upstreamErr.Error()))
+		}
+		return versions, err
+	}
+	if len(upstream.Versions) == 0 {
+		return versions, err
+	}
+
+	merged := make(map[string]core.ProviderVersion)
+	if versions != nil {
+		for _, v := range versions.Versions {
+			merged[v.Version] = v
+		}
+	}
+	for _, v := range upstream.Versions {
+		stored, ok := merged[v.Version]
+		if !ok {
+			merged[v.Version] = v
+			continue
+		}
+		stored.Platforms = mergePlatforms(stored.Platforms, v.Platforms)
+		merged[v.Version] = stored
+	}
+
+	result := &core.ProviderVersions{}
+	for _, v := range merged {
+		result.Versions = append(result.Versions, v)
+	}
+	sort.Slice(result.Versions, func(i, j int) bool {
+		return versionLess(result.Versions[i].Version, result.Versions[j].Version)
+	})
+	return result, nil
+}
+
+// mergePlatforms adds the upstream platforms that are missing in the stored platforms
+func mergePlatforms(stored, upstream []core.Platform) []core.Platform {
+	seen := make(map[core.Platform]bool, len(stored))
+	for _, p := range stored {
+		seen[p] = true
+	}
+	for _, p := range upstream {
+		if !seen[p] {
+			stored = append(stored, p)
+			seen[p] = true
+		}
+	}
+	return stored
+}
+
+// isProviderNotFound reports whether the provider package or the signing keys of its namespace aren't stored
+func isProviderNotFound(err error) bool {
+	var providerError *core.ProviderError
+	return errors.Is(err, provider.ErrProviderNotFound) || errors.Is(err, core.ErrObjectNotFound) ||
+		(errors.As(err, &providerError) && providerError.StatusCode == http.StatusNotFound)
+}
+
+// ProviderStorageOption provides additional options for the provider.Storage of a replica
+type ProviderStorageOption func(*providerStorage)
+
+// WithTrustedKeys configures the signing keys, which are trusted to sign the providers of every namespace.
+// A trusted key is added to the signing keys of a namespace, once a provider signed by it is read through.
+func WithTrustedKeys(keys []core.GPGPublicKey) ProviderStorageOption {
+	return func(s *providerStorage) {
+		s.trustedKeys = keys
+	}
+}
+
+// NewProviderStorage returns a provider.Storage that reads the provider packages through from the upstream, which are missing in the storage
+func NewProviderStorage(storage provider.Storage, upstream *Upstream, options ...ProviderStorageOption) provider.Storage {
+	s := &providerStorage{
+		Storage:  storage,
+		upstream: upstream,
+		logger:   slog.Default().With(slog.String("component", "replica")),
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// ParseTrustedKeys parses the ASCII armored public keys, e.g. of a file with the exported keys of the publishers
+func ParseTrustedKeys(armored []byte) ([]core.GPGPublicKey, error) {
+	const header = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+
+	var keys []core.GPGPublicKey
+	blocks := strings.Split(string(armored), header)
+	for _, block := range blocks[1:] {
+		block = header + block
+		keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(block))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the trusted keys: %w", err)
+		}
+		for _, entity := range keyring {
+			keys = append(keys, core.GPGPublicKey{KeyID: entity.PrimaryKey.KeyIdString(), ASCIIArmor: strings.TrimSpace(block) + "\n"})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no ASCII armored public keys were found")
+	}
+	return keys, nil
+}
@@ -0,0 +1,351 @@
+package replica
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/provider"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryProviderStorage keeps the release files and signing keys of providers in memory
+type memoryProviderStorage struct {
+	provider.Storage
+
+	mu          sync.Mutex
+	files       map[string][]byte
+	signingKeys map[string]*core.SigningKeys
+}
+
+func newMemoryProviderStorage() *memoryProviderStorage {
+	return &memoryProviderStorage{files: map[string][]byte{}, signingKeys: map[string]*core.SigningKeys{}}
+}
+
+func (s *memoryProviderStorage) GetProvider(_ context.Context, namespace, name, version, os, arch string) (*core.Provider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := &core.Provider{Namespace: namespace, Name: name, Version: version, OS: os, Arch: arch}
+	if _, ok := s.files[path.Join(namespace, name, p.ArchiveFileName())]; !ok {
+		return nil, &core.ProviderError{Reason: "failed to find matching providers", Provider: p, StatusCode: http.StatusNotFound}
+	}
+	keys, ok := s.signingKeys[namespace]
+	if !ok {
+		return nil, core.ErrObjectNotFound
+	}
+	p.Filename = p.ArchiveFileName()
+	p.SigningKeys = *keys
+	return p, nil
+}
+
+func (s *memoryProviderStorage) ListProviderVersions(_ context.Context, namespace, name string) (*core.ProviderVersions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := map[string]*core.ProviderVersion{}
+	for key := range s.files {
+		p, err := core.NewProviderFromArchive(path.Base(key))
+		if err != nil || path.Dir(key) != path.Join(namespace, name) {
+			continue
+		}
+		v, ok := versions[p.Version]
+		if !ok {
+			v = &core.ProviderVersion{Namespace: namespace, Name: name, Version: p.Version}
+			versions[p.Version] = v
+		}
+		v.Platforms = append(v.Platforms, core.Platform{OS: p.OS, Arch: p.Arch})
+	}
+	if len(versions) == 0 {
+		return nil, &core.ProviderError{Reason: "failed to find matching providers", Provider: &core.Provider{Namespace: namespace, Name: name}, StatusCode: http.StatusNotFound}
+	}
+
+	result := &core.ProviderVersions{}
+	for _, v := range versions {
+		result.Versions = append(result.Versions, *v)
+	}
+	return result, nil
+}
+
+func (s *memoryProviderStorage) UploadProviderReleaseFiles(_ context.Context, namespace, name, filename string, file io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := path.Join(namespace, name, filename)
+	if _, ok := s.files[key]; ok {
+		return fmt.Errorf("failed to upload key %s: %w", key, core.ErrObjectAlreadyExists)
+	}
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	s.files[key] = b
+	return nil
+}
+
+func (s *memoryProviderStorage) SigningKeys(_ context.Context, namespace string) (*core.SigningKeys, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, ok := s.signingKeys[namespace]
+	if !ok {
+		return nil, core.ErrObjectNotFound
+	}
+	return &core.SigningKeys{GPGPublicKeys: append([]core.GPGPublicKey{}, keys.GPGPublicKeys...)}, nil
+}
+
+func (s *memoryProviderStorage) UploadSigningKeys(_ context.Context, namespace string, signingKeys *core.SigningKeys) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.signingKeys[namespace] = signingKeys
+	return nil
+}
+
+// signedRelease returns the SHA256SUMS of the archives, its signature and the signing keys
+func signedRelease(t *testing.T, archives map[string][]byte) ([]byte, []byte, core.SigningKeys) {
+	t.Helper()
+
+	e, err := openpgp.NewEntity("boring-registry", "test", "boring-registry@example.com", &packet.Config{
+		Rand:    rand.New(rand.NewSource(42)),
+		RSABits: 2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	armored := new(bytes.Buffer)
+	w, err := armor.Encode(armored, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+
+	sums := new(bytes.Buffer)
+	for name, content := range archives {
+		fmt.Fprintf(sums, "%x  %s\n", sha256.Sum256(content), name)
+	}
+	sig := new(bytes.Buffer)
+	if err := openpgp.DetachSign(sig, e, bytes.NewReader(sums.Bytes()), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := core.SigningKeys{GPGPublicKeys: []core.GPGPublicKey{{KeyID: e.PrimaryKey.KeyIdString(), ASCIIArmor: armored.String()}}}
+	return sums.Bytes(), sig.Bytes(), keys
+}
+
+func TestProviderStorage_GetProvider(t *testing.T) {
+	linux := []byte("linux_amd64")
+	sums, sig, keys := signedRelease(t, map[string][]byte{
+		"terraform-provider-dummy_1.0.0_linux_amd64.zip":  linux,
+		"terraform-provider-dummy_1.0.0_darwin_arm64.zip": []byte("darwin_arm64"),
+	})
+
+	_, upstream := newCentralRegistry(t, map[string]http.HandlerFunc{
+		"/v1/providers/acme/dummy/1.0.0/download/": func(w http.ResponseWriter, r *http.Request) {
+			platform := path.Base(path.Dir(r.URL.Path)) + "_" + path.Base(r.URL.Path)
+			if platform != "linux_amd64" && platform != "darwin_arm64" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(core.Provider{
+				Filename:            "terraform-provider-dummy_1.0.0_" + platform + ".zip",
+				DownloadURL:         "/v1/proxy/terraform-provider-dummy_1.0.0_" + platform + ".zip",
+				SHASumsURL:          "/v1/proxy/terraform-provider-dummy_1.0.0_SHA256SUMS",
+				SHASumsSignatureURL: "/v1/proxy/terraform-provider-dummy_1.0.0_SHA256SUMS.sig",
+				SigningKeys:         keys,
+			})
+		},
+		"/v1/proxy/terraform-provider-dummy_1.0.0_linux_amd64.zip": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(linux)
+		},
+		"/v1/proxy/terraform-provider-dummy_1.0.0_darwin_arm64.zip": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("tampered"))
+		},
+		"/v1/proxy/terraform-provider-dummy_1.0.0_SHA256SUMS": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(sums)
+		},
+		"/v1/proxy/terraform-provider-dummy_1.0.0_SHA256SUMS.sig": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(sig)
+		},
+	})
+
+	testCases := []struct {
+		name           string
+		os             string
+		arch           string
+		storedKeys     bool
+		trustedKeys    bool
+		expectNotFound bool
+		expectError    bool
+	}{
+		{
+			name:        "signed by a trusted key",
+			os:          "linux",
+			arch:        "amd64",
+			trustedKeys: true,
+		},
+		{
+			name:       "signed by a stored key",
+			os:         "linux",
+			arch:       "amd64",
+			storedKeys: true,
+		},
+		{
+			name:        "keys of the upstream aren't trusted",
+			os:          "linux",
+			arch:        "amd64",
+			expectError: true,
+		},
+		{
+			name:        "checksum mismatch",
+			os:          "darwin",
+			arch:        "arm64",
+			trustedKeys: true,
+			expectError: true,
+		},
+		{
+			name:           "not found upstream",
+			os:             "windows",
+			arch:           "amd64",
+			trustedKeys:    true,
+			expectNotFound: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			storage := newMemoryProviderStorage()
+			if tc.storedKeys {
+				storage.signingKeys["acme"] = &keys
+			}
+			var options []ProviderStorageOption
+			if tc.trustedKeys {
+				options = append(options, WithTrustedKeys(keys.GPGPublicKeys))
+			}
+			s := NewProviderStorage(storage, upstream, options...)
+
+			p, err := s.GetProvider(context.Background(), "acme", "dummy", "1.0.0", tc.os, tc.arch)
+			switch {
+			case tc.expectNotFound:
+				assert.True(isProviderNotFound(err))
+			case tc.expectError:
+				assert.Error(err)
+				assert.False(isProviderNotFound(err))
+				// Nothing is stored, if the verification fails
+				assert.Empty(storage.files)
+				if !tc.storedKeys {
+					assert.Empty(storage.signingKeys)
+				}
+			default:
+				if !assert.NoError(err) {
+					return
+				}
+				assert.Equal("terraform-provider-dummy_1.0.0_linux_amd64.zip", p.Filename)
+				assert.Equal(keys, p.SigningKeys)
+				assert.Equal(linux, storage.files["acme/dummy/terraform-provider-dummy_1.0.0_linux_amd64.zip"])
+				assert.Equal(sums, storage.files["acme/dummy/terraform-provider-dummy_1.0.0_SHA256SUMS"])
+				assert.Equal(sig, storage.files["acme/dummy/terraform-provider-dummy_1.0.0_SHA256SUMS.sig"])
+			}
+		})
+	}
+}
+
+func TestProviderStorage_addSigningKey(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	storage := newMemoryProviderStorage()
+	storage.signingKeys["acme"] = &core.SigningKeys{GPGPublicKeys: []core.GPGPublicKey{{KeyID: "A"}}}
+	s := NewProviderStorage(storage, nil).(*providerStorage)
+
+	// The keys of the namespace, which were uploaded to the edge registry directly, are kept
+	assert.NoError(s.addSigningKey(ctx, "acme", core.GPGPublicKey{KeyID: "B"}))
+	assert.NoError(s.addSigningKey(ctx, "acme", core.GPGPublicKey{KeyID: "A"}))
+	assert.Equal([]core.GPGPublicKey{{KeyID: "A"}, {KeyID: "B"}}, storage.signingKeys["acme"].GPGPublicKeys)
+
+	assert.NoError(s.addSigningKey(ctx, "example", core.GPGPublicKey{KeyID: "C"}))
+	assert.Equal([]core.GPGPublicKey{{KeyID: "C"}}, storage.signingKeys["example"].GPGPublicKeys)
+}
+
+func TestParseTrustedKeys(t *testing.T) {
+	assert := assert.New(t)
+	sums, sig, keys := signedRelease(t, map[string][]byte{"terraform-provider-dummy_1.0.0_linux_amd64.zip": []byte("linux_amd64")})
+
+	armored := keys.GPGPublicKeys[0].ASCIIArmor
+	parsed, err := ParseTrustedKeys([]byte(armored + "\n" + armored))
+	if assert.NoError(err) && assert.Len(parsed, 2) {
+		assert.Equal(keys.GPGPublicKeys[0].KeyID, parsed[0].KeyID)
+		assert.Equal(keys.GPGPublicKeys[0].KeyID, parsed[1].KeyID)
+		assert.NoError((&core.SigningKeys{GPGPublicKeys: parsed[1:]}).IsValidSha256Sums(sums, sig))
+	}
+
+	_, err = ParseTrustedKeys([]byte("not a key"))
+	assert.Error(err)
+}
+
+func TestProviderStorage_ListProviderVersions(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	requests := 0
+	_, upstream := newCentralRegistry(t, map[string]http.HandlerFunc{
+		"/v1/providers/acme/dummy/versions": func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			_, _ = w.Write([]byte(`{"versions":[
+				{"version":"1.10.0","protocols":["5.0"],"platforms":[{"os":"linux","arch":"amd64"}]},
+				{"version":"1.2.0","protocols":["5.0"],"platforms":[{"os":"linux","arch":"amd64"},{"os":"darwin","arch":"arm64"}]}
+			]}`))
+		},
+	})
+
+	storage := newMemoryProviderStorage()
+	storage.files["acme/dummy/terraform-provider-dummy_1.2.0_linux_amd64.zip"] = []byte("linux_amd64")
+	storage.files["acme/dummy/terraform-provider-dummy_0.9.0_linux_amd64.zip"] = []byte("linux_amd64")
+	s := NewProviderStorage(storage, upstream)
+
+	versions, err := s.ListProviderVersions(ctx, "acme", "dummy")
+	if !assert.NoError(err) || !assert.Len(versions.Versions, 3) {
+		return
+	}
+	assert.Equal("0.9.0", versions.Versions[0].Version)
+	assert.Equal("1.2.0", versions.Versions[1].Version)
+	assert.Equal([]core.Platform{{OS: "linux", Arch: "amd64"}, {OS: "darwin", Arch: "arm64"}}, versions.Versions[1].Platforms)
+	assert.Equal("1.10.0", versions.Versions[2].Version)
+	assert.Equal([]string{"5.0"}, versions.Versions[2].Protocols)
+
+	// The stored versions are listed, if the provider doesn't exist upstream
+	storage.files["acme/other/terraform-provider-other_1.0.0_linux_amd64.zip"] = []byte("linux_amd64")
+	versions, err = s.ListProviderVersions(ctx, "acme", "other")
+	if assert.NoError(err) && assert.Len(versions.Versions, 1) {
+		assert.Equal("1.0.0", versions.Versions[0].Version)
+	}
+
+	// The versions of the upstream are cached briefly
+	now := time.Now()
+	upstream.providerVersions.now = func() time.Time { return now }
+	_, err = s.ListProviderVersions(ctx, "acme", "dummy")
+	assert.NoError(err)
+	assert.Equal(1, requests)
+	now = now.Add(versionsCacheTTL)
+	_, err = s.ListProviderVersions(ctx, "acme", "dummy")
+	assert.NoError(err)
+	assert.Equal(2, requests)
+}
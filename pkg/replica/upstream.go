@@ -0,0 +1,346 @@
+package replica
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/discovery"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Upstream is the client of the central registry, from which an edge registry reads the modules and providers that it doesn't store yet.
+// The central registry is accessed over the module and provider registry protocols like Terraform does, so any boring-registry can be the upstream.
+type Upstream struct {
+	hostname  string
+	client    *http.Client
+	discovery discovery.ServiceDiscoveryResolver
+	timeout   time.Duration
+
+	moduleVersions   *versionsCache[[]core.Module]
+	providerVersions *versionsCache[*core.ProviderVersions]
+}
+
+// upstreamModuleVersions is the response of the module versions endpoint
+type upstreamModuleVersions struct {
+	Modules []struct {
+		Versions []struct {
+			Version     string            `json:"version"`
+			Deprecation *core.Deprecation `json:"deprecation,omitempty"`
+			Labels      map[string]string `json:"labels,omitempty"`
+		} `json:"versions"`
+	} `json:"modules"`
+}
+
+// listModuleVersions returns the module versions of the upstream, which are cached briefly
+func (u *Upstream) listModuleVersions(ctx context.Context, namespace, name, provider string) ([]core.Module, error) {
+	return u.moduleVersions.get(path.Join(namespace, name, provider), func() ([]core.Module, error) {
+		return u.requestModuleVersions(ctx, namespace, name, provider)
+	})
+}
+
+func (u *Upstream) requestModuleVersions(ctx context.Context, namespace, name, provider string) (_ []core.Module, err error) {
+	ctx, span := o11y.StartSpan(ctx, "replica.listModuleVersions", moduleAttributes(namespace, name, provider, "")...)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	resp, err := u.get(ctx, modulesService, fmt.Sprintf("%s/%s/%s/versions", namespace, name, provider))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, upstreamStatusError(resp)
+	}
+
+	var response upstreamModuleVersions
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode the module versions of the upstream: %w", err)
+	}
+
+	var modules []core.Module
+	for _, m := range response.Modules {
+		for _, v := range m.Versions {
+			module := core.Module{Namespace: namespace, Name: name, Provider: provider, Version: v.Version}
+			if v.Deprecation != nil || len(v.Labels) > 0 {
+				module.Annotations = &core.Annotations{Deprecation: v.Deprecation, Labels: v.Labels}
+			}
+			modules = append(modules, module)
+		}
+	}
+	return modules, nil
+}
+
+// moduleDownloadURL returns the X-Terraform-Get header of the module version, which is either the URL of an archive or a git source
+func (u *Upstream) moduleDownloadURL(ctx context.Context, namespace, name, provider, version string) (_ string, err error) {
+	ctx, span := o11y.StartSpan(ctx, "replica.moduleDownloadURL", moduleAttributes(namespace, name, provider, version)...)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	resp, err := u.get(ctx, modulesService, fmt.Sprintf("%s/%s/%s/%s/download", namespace, name, provider, version))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return "", upstreamStatusError(resp)
+	}
+
+	location := resp.Header.Get("X-Terraform-Get")
+	if location == "" {
+		return "", fmt.Errorf("the upstream didn't return the X-Terraform-Get header for module %s/%s/%s/%s", namespace, name, provider, version)
+	}
+	// Sources are downloaded by Terraform from the repository, so they're stored as they are
+	if strings.HasPrefix(location, "git::") {
+		return location, nil
+	}
+	return u.resolve(resp.Request.URL, location)
+}
+
+// listProviderVersions returns the provider versions of the upstream, which are cached briefly
+func (u *Upstream) listProviderVersions(ctx context.Context, namespace, name string) (*core.ProviderVersions, error) {
+	return u.providerVersions.get(path.Join(namespace, name), func() (*core.ProviderVersions, error) {
+		return u.requestProviderVersions(ctx, namespace, name)
+	})
+}
+
+func (u *Upstream) requestProviderVersions(ctx context.Context, namespace, name string) (_ *core.ProviderVersions, err error) {
+	ctx, span := o11y.StartSpan(ctx, "replica.listProviderVersions", providerAttributes(namespace, name, "")...)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	resp, err := u.get(ctx, providersService, fmt.Sprintf("%s/%s/versions", namespace, name))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, upstreamStatusError(resp)
+	}
+
+	var versions core.ProviderVersions
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, fmt.Errorf("failed to decode the provider versions of the upstream: %w", err)
+	}
+	return &versions, nil
+}
+
+// getProvider returns the provider package of the platform with absolute URLs, even if the upstream proxies the downloads
+func (u *Upstream) getProvider(ctx context.Context, namespace, name, version, os, arch string) (_ *core.Provider, err error) {
+	ctx, span := o11y.StartSpan(ctx, "replica.getProvider", append(providerAttributes(namespace, name, version), attribute.String(o11y.OsLabel, os), attribute.String(o11y.ArchLabel, arch))...)
+	defer func() { o11y.EndSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	resp, err := u.get(ctx, providersService, fmt.Sprintf("%s/%s/%s/download/%s/%s", namespace, name, version, os, arch))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, upstreamStatusError(resp)
+	}
+
+	var p core.Provider
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to decode the provider of the upstream: %w", err)
+	}
+	p.Namespace, p.Name, p.Version, p.OS, p.Arch = namespace, name, version, os, arch
+
+	for _, location := range []*string{&p.DownloadURL, &p.SHASumsURL, &p.SHASumsSignatureURL} {
+		if *location, err = u.resolve(resp.Request.URL, *location); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+// download requests a file of a module or provider, which the caller has to close
+func (u *Upstream) download(ctx context.Context, location string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, upstreamStatusError(resp)
+	}
+	return resp.Body, nil
+}
+
+type service func(*discovery.DiscoveredRemoteService) string
+
+func modulesService(d *discovery.DiscoveredRemoteService) string {
+	return d.ModulesV1
+}
+
+func providersService(d *discovery.DiscoveredRemoteService) string {
+	return d.ProvidersV1
+}
+
+// get requests the path relative to the discovered service of the upstream
+func (u *Upstream) get(ctx context.Context, s service, path string) (*http.Response, error) {
+	discovered, err := u.discovery.Resolve(ctx, u.hostname)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+	}
+	if s(discovered) == "" {
+		return nil, fmt.Errorf("the upstream %s doesn't serve the service", u.hostname)
+	}
+
+	// The services can be absolute URLs or paths relative to the host of the service discovery
+	base, err := discovered.URL.Parse(s(discovered))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the service of the upstream: %w", err)
+	}
+	target, err := base.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+	}
+	return resp, nil
+}
+
+// resolve returns the absolute URL of a download location, which is relative to the request in case the upstream proxies the downloads.
+// Absolute URLs are returned unchanged, so that signed URLs stay valid.
+func (u *Upstream) resolve(request *url.URL, location string) (string, error) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the download URL of the upstream: %w", err)
+	}
+	if parsed.IsAbs() {
+		return location, nil
+	}
+	return request.ResolveReference(parsed).String(), nil
+}
+
+func upstreamStatusError(r *http.Response) error {
+	switch {
+	case r.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: status code is %d", ErrUpstreamNotFound, r.StatusCode)
+	case r.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: status code is %d", ErrUpstreamUnavailable, r.StatusCode)
+	default:
+		return fmt.Errorf("the upstream responded with status code %d", r.StatusCode)
+	}
+}
+
+// tokenTransport authenticates the requests to the upstream with its API token.
+// The token is only sent to the hostname of the upstream, so that it isn't leaked to the storage backends of signed download URLs.
+type tokenTransport struct {
+	hostname string
+	token    string
+	next     http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.token == "" || !strings.EqualFold(req.URL.Host, t.hostname) || req.Header.Get("Authorization") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	// The request must not be modified by a RoundTripper
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
+
+// UpstreamOption provides additional options for the Upstream
+type UpstreamOption func(*Upstream)
+
+// WithToken configures the API token, with which the requests to the upstream are authenticated
+func WithToken(token string) UpstreamOption {
+	return func(u *Upstream) {
+		u.client.Transport.(*tokenTransport).token = token
+	}
+}
+
+// WithTimeout configures the timeout for the requests of the module and provider metadata.
+// The downloads of archives aren't limited by it, as they can be considerably larger.
+func WithTimeout(timeout time.Duration) UpstreamOption {
+	return func(u *Upstream) {
+		if timeout > 0 {
+			u.timeout = timeout
+		}
+	}
+}
+
+// NewUpstream returns the client of the central registry at the given hostname, e.g. registry.example.com
+func NewUpstream(hostname string, options ...UpstreamOption) *Upstream {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 100
+	return newUpstream(hostname, o11y.NewTransport(transport), options...)
+}
+
+func newUpstream(hostname string, transport http.RoundTripper, options ...UpstreamOption) *Upstream {
+	client := &http.Client{
+		// This is also the timeout for reading the response body of the downloads
+		Timeout:   5 * time.Minute,
+		Transport: &tokenTransport{hostname: hostname, next: transport},
+	}
+	u := &Upstream{
+		hostname:  hostname,
+		client:    client,
+		discovery: discovery.NewRemoteServiceDiscovery(client),
+		timeout:   10 * time.Second,
+
+		moduleVersions:   newVersionsCache[[]core.Module](versionsCacheTTL),
+		providerVersions: newVersionsCache[*core.ProviderVersions](versionsCacheTTL),
+	}
+
+	for _, option := range options {
+		option(u)
+	}
+
+	return u
+}
+
+func moduleAttributes(namespace, name, provider, version string) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		attribute.String(o11y.NamespaceLabel, namespace),
+		attribute.String(o11y.NameLabel, name),
+		attribute.String(o11y.ProviderLabel, provider),
+	}
+	if version != "" {
+		attributes = append(attributes, attribute.String(o11y.VersionLabel, version))
+	}
+	return attributes
+}
+
+func providerAttributes(namespace, name, version string) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		attribute.String(o11y.NamespaceLabel, namespace),
+		attribute.String(o11y.NameLabel, name),
+	}
+	if version != "" {
+		attributes = append(attributes, attribute.String(o11y.VersionLabel, version))
+	}
+	return attributes
+}
@@ -0,0 +1,105 @@
+package replica
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testToken = "secret"
+
+// newCentralRegistry serves the given routes next to the service discovery of a central registry.
+// The routes require the token like an authenticated registry.
+func newCentralRegistry(t *testing.T, routes map[string]http.HandlerFunc) (*httptest.Server, *Upstream) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"modules.v1":"/v1/modules/","providers.v1":"/v1/providers/"}`))
+	})
+	for pattern, handler := range routes {
+		handler := handler
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+testToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			handler(w, r)
+		})
+	}
+
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+	return server, newUpstream(server.Listener.Addr().String(), server.Client().Transport, WithToken(testToken))
+}
+
+func TestTokenTransport(t *testing.T) {
+	var authorization string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name     string
+		hostname string
+		expected string
+	}{
+		{
+			name:     "upstream",
+			hostname: server.Listener.Addr().String(),
+			expected: "Bearer " + testToken,
+		},
+		{
+			// Signed download URLs of storage backends reject requests with another authorization
+			name:     "other host",
+			hostname: "registry.example.com",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			authorization = ""
+			u := newUpstream(tc.hostname, server.Client().Transport, WithToken(testToken))
+
+			body, err := u.download(context.Background(), server.URL)
+			if assert.NoError(t, err) {
+				body.Close()
+			}
+			assert.Equal(t, tc.expected, authorization)
+		})
+	}
+}
+
+func TestUpstream_getProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	server, u := newCentralRegistry(t, map[string]http.HandlerFunc{
+		"/v1/providers/acme/dummy/1.0.0/download/linux/amd64": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{
+				"filename": "terraform-provider-dummy_1.0.0_linux_amd64.zip",
+				"download_url": "/v1/proxy/terraform-provider-dummy_1.0.0_linux_amd64.zip",
+				"shasums_url": "https://storage.example.com/terraform-provider-dummy_1.0.0_SHA256SUMS?X-Amz-Signature=abc%2F",
+				"shasums_signature_url": "../../../../../../proxy/terraform-provider-dummy_1.0.0_SHA256SUMS.sig"
+			}`))
+		},
+	})
+
+	p, err := u.getProvider(context.Background(), "acme", "dummy", "1.0.0", "linux", "amd64")
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(server.URL+"/v1/proxy/terraform-provider-dummy_1.0.0_linux_amd64.zip", p.DownloadURL)
+	assert.Equal("https://storage.example.com/terraform-provider-dummy_1.0.0_SHA256SUMS?X-Amz-Signature=abc%2F", p.SHASumsURL)
+	assert.Equal(server.URL+"/v1/proxy/terraform-provider-dummy_1.0.0_SHA256SUMS.sig", p.SHASumsSignatureURL)
+	assert.Equal("linux", p.OS)
+
+	_, err = u.getProvider(context.Background(), "acme", "dummy", "2.0.0", "linux", "amd64")
+	assert.ErrorIs(err, ErrUpstreamNotFound)
+}
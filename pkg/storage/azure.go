@@ -3,6 +3,7 @@ package storage
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,16 +13,23 @@ import (
 	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/mirror"
 	"github.com/boring-registry/boring-registry/pkg/module"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 )
 
+// userDelegationKeyExpiry is the validity of the user delegation keys, which sign the download URLs
+const userDelegationKeyExpiry = 4 * time.Hour
+
 // AzureStorage is a Storage implementation backed by Azure Blob Storage.
 // AzureStorage implements module.Storage, provider.Storage, and mirror.Storage
 type AzureStorage struct {
@@ -31,31 +39,72 @@ type AzureStorage struct {
 	prefix              string
 	moduleArchiveFormat string
 	signedURLExpiry     time.Duration
+
+	// presignCache reuses signed download URLs, if it's enabled
+	presignCache *presignCache
+	// cdn signs the download URLs for a CDN instead of the storage backend, if it's configured
+	cdn *CDN
+
+	// presignObserver is notified about the duration of signing a download URL
+	presignObserver func(time.Duration)
 }
 
 // GetModule retrieves information about a module from the Azure Storage.
 func (s *AzureStorage) GetModule(ctx context.Context, namespace, name, provider, version string) (core.Module, error) {
-	key := modulePath(s.prefix, namespace, name, provider, version, s.moduleArchiveFormat)
-
-	exists, err := s.objectExists(ctx, key)
+	key, err := s.moduleObject(ctx, namespace, name, provider, version)
 	if err != nil {
 		return core.Module{}, err
-	} else if !exists {
-		return core.Module{}, module.ErrModuleNotFound
 	}
 
-	presigned, err := s.presignedURL(ctx, key)
+	m, err := moduleVersionFromObject(key)
 	if err != nil {
 		return core.Module{}, err
 	}
+	if err := moduleDownloadURL(ctx, m, key, s.downloadURL, s.download); err != nil {
+		return core.Module{}, err
+	}
+
+	return *m, nil
+}
+
+// moduleObject returns the key of the archive or source of a module version.
+// The archive in the configured format is looked up first, before the archives in other formats and sources are listed.
+func (s *AzureStorage) moduleObject(ctx context.Context, namespace, name, provider, version string) (string, error) {
+	key := modulePath(s.prefix, namespace, name, provider, version, s.moduleArchiveFormat)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return "", err
+	} else if exists {
+		return key, nil
+	}
+
+	source := ""
+	prefix := moduleArchivePathPrefix(s.prefix, namespace, name, provider, version)
+	pager := s.client.NewListBlobsFlatPager(s.container, &azblob.ListBlobsFlatOptions{
+		Prefix: &prefix,
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, obj := range page.Segment.BlobItems {
+			m, err := moduleVersionFromObject(*obj.Name)
+			if err != nil || m.Version != version {
+				continue
+			}
+			// Archives take precedence over sources
+			if m.ArchiveFormat != "" {
+				return *obj.Name, nil
+			}
+			source = *obj.Name
+		}
+	}
 
-	return core.Module{
-		Namespace:   namespace,
-		Name:        name,
-		Provider:    provider,
-		Version:     version,
-		DownloadURL: presigned,
-	}, nil
+	if source == "" {
+		return "", module.ErrModuleNotFound
+	}
+	return source, nil
 }
 
 func (s *AzureStorage) ListModuleVersions(ctx context.Context, namespace, name, provider string) ([]core.Module, error) {
@@ -72,26 +121,37 @@ func (s *AzureStorage) ListModuleVersions(ctx context.Context, namespace, name,
 		}
 
 		for _, obj := range page.Segment.BlobItems {
-			m, err := moduleFromObject(*obj.Name, s.moduleArchiveFormat)
+			m, err := moduleVersionFromObject(*obj.Name)
 			if err != nil {
 				continue
 			}
 
-			m.DownloadURL, err = s.presignedURL(ctx, modulePath(prefix, m.Namespace, m.Name, m.Provider, m.Version, s.moduleArchiveFormat))
-			if err != nil {
-				return []core.Module{}, err
+			// The sources are only read by GetModule, so that listing doesn't download an object per version
+			if m.ArchiveFormat != "" {
+				if err := moduleDownloadURL(ctx, m, *obj.Name, s.downloadURL, s.download); err != nil {
+					return []core.Module{}, err
+				}
 			}
 
 			modules = append(modules, *m)
 		}
 	}
 
-	return modules, nil
+	return uniqueModuleVersions(modules), nil
 }
 
-// UploadModule uploads a module to the Azure Storage.
+// ListModules lists the modules in Azure Blob Storage.
+func (s *AzureStorage) ListModules(ctx context.Context) ([]core.Module, error) {
+	keys, err := s.listKeys(ctx, path.Join(s.prefix, string(internalModuleType))+"/")
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", module.ErrModuleListFailed, err)
+	}
+	return modulesFromKeys(s.prefix, keys), nil
+}
 
-func (s *AzureStorage) UploadModule(ctx context.Context, namespace, name, provider, version string, body io.Reader) (core.Module, error) {
+// UploadModule uploads a module to the Azure Storage.
+// The archive is stored in the configured format, unless another format is given.
+func (s *AzureStorage) UploadModule(ctx context.Context, namespace, name, provider, version, format string, body io.Reader) (core.Module, error) {
 	if namespace == "" {
 		return core.Module{}, errors.New("namespace not defined")
 	}
@@ -108,19 +168,184 @@ func (s *AzureStorage) UploadModule(ctx context.Context, namespace, name, provid
 		return core.Module{}, errors.New("version not defined")
 	}
 
-	key := modulePath(s.prefix, namespace, name, provider, version, DefaultModuleArchiveFormat)
+	format, err := uploadArchiveFormat(format, s.moduleArchiveFormat)
+	if err != nil {
+		return core.Module{}, err
+	}
+	key := modulePath(s.prefix, namespace, name, provider, version, format)
+
+	if m, err := s.GetModule(ctx, namespace, name, provider, version); err == nil && m.Source == "" {
+		return core.Module{}, fmt.Errorf("%w: %s", module.ErrModuleAlreadyExists, key)
+	}
+
+	_, err = s.client.UploadStream(ctx, s.container, key, body, &azblob.UploadStreamOptions{
+		AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: to.Ptr(azcore.ETagAny)}},
+	})
+	if bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists) {
+		return core.Module{}, fmt.Errorf("%w: %s", module.ErrModuleAlreadyExists, key)
+	} else if err != nil {
+		return core.Module{}, fmt.Errorf("%v: %w", module.ErrModuleUploadFailed, err)
+	}
 
+	return s.GetModule(ctx, namespace, name, provider, version)
+}
+
+// UploadModuleSource registers a module version with a git source in the Azure Storage.
+func (s *AzureStorage) UploadModuleSource(ctx context.Context, namespace, name, provider, version, source string) (core.Module, error) {
+	if err := validateModuleSource(namespace, name, provider, version, source); err != nil {
+		return core.Module{}, err
+	}
+
+	key := moduleSourcePath(s.prefix, namespace, name, provider, version)
 	if _, err := s.GetModule(ctx, namespace, name, provider, version); err == nil {
 		return core.Module{}, fmt.Errorf("%w: %s", module.ErrModuleAlreadyExists, key)
 	}
 
-	if _, err := s.client.UploadStream(ctx, s.container, key, body, nil); err != nil {
+	b, err := json.Marshal(moduleSource{Source: source})
+	if err != nil {
+		return core.Module{}, err
+	}
+	if err := s.upload(ctx, key, bytes.NewReader(b), false); err != nil {
 		return core.Module{}, fmt.Errorf("%v: %w", module.ErrModuleUploadFailed, err)
 	}
 
 	return s.GetModule(ctx, namespace, name, provider, version)
 }
 
+// DeleteModuleSource deletes the source of a module version from the Azure Storage.
+func (s *AzureStorage) DeleteModuleSource(ctx context.Context, namespace, name, provider, version string) error {
+	key := moduleSourcePath(s.prefix, namespace, name, provider, version)
+	if _, err := s.client.DeleteBlob(ctx, s.container, key, nil); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetModuleDetails downloads the details of a module version from Azure Blob Storage.
+func (s *AzureStorage) GetModuleDetails(ctx context.Context, namespace, name, provider, version string) (*module.Details, error) {
+	key := moduleDetailsPath(s.prefix, namespace, name, provider, version)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, module.ErrModuleDetailsNotFound
+	}
+
+	b, err := s.download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalModuleDetails(b)
+}
+
+// UploadModuleDetails uploads the details of a module version to Azure Blob Storage.
+func (s *AzureStorage) UploadModuleDetails(ctx context.Context, namespace, name, provider, version string, details *module.Details) error {
+	b, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	key := moduleDetailsPath(s.prefix, namespace, name, provider, version)
+	return s.upload(ctx, key, bytes.NewReader(b), true)
+}
+
+// GetModuleDocs downloads the documentation bundle of a module version from Azure Blob Storage.
+func (s *AzureStorage) GetModuleDocs(ctx context.Context, namespace, name, provider, version string) (*module.Docs, error) {
+	key := moduleDocsPath(s.prefix, namespace, name, provider, version)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, module.ErrModuleDocsNotFound
+	}
+
+	b, err := s.download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalModuleDocs(b)
+}
+
+// UploadModuleDocs uploads the documentation bundle of a module version to Azure Blob Storage.
+func (s *AzureStorage) UploadModuleDocs(ctx context.Context, namespace, name, provider, version string, docs *module.Docs) error {
+	b, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	key := moduleDocsPath(s.prefix, namespace, name, provider, version)
+	return s.upload(ctx, key, bytes.NewReader(b), true)
+}
+
+// UploadModuleDependencies uploads the dependencies of a module version to Azure Blob Storage and indexes them by the called modules.
+func (s *AzureStorage) UploadModuleDependencies(ctx context.Context, namespace, name, provider, version string, dependencies []module.ModuleDependency) error {
+	// Only stored versions are indexed, so that a failed upload never leaves dependents of a version that doesn't exist
+	if _, err := s.moduleObject(ctx, namespace, name, provider, version); err != nil {
+		return err
+	}
+
+	previous, err := s.GetModuleDependencies(ctx, namespace, name, provider, version)
+	if err != nil && !errors.Is(err, module.ErrModuleDependenciesNotFound) {
+		return err
+	}
+	if err := updateModuleDependents(ctx, s.prefix, namespace, name, provider, version, previous, dependencies, s.readVersioned, s.writeConditional); err != nil {
+		return err
+	}
+
+	// The dependencies are uploaded last, so that the dependents are updated again if the upload is retried
+	b, err := marshalModuleDependencies(dependencies)
+	if err != nil {
+		return err
+	}
+	return s.upload(ctx, moduleDependenciesPath(s.prefix, namespace, name, provider, version), bytes.NewReader(b), true)
+}
+
+// GetModuleDependencies downloads the dependencies of a module version from Azure Blob Storage.
+func (s *AzureStorage) GetModuleDependencies(ctx context.Context, namespace, name, provider, version string) ([]module.ModuleDependency, error) {
+	key := moduleDependenciesPath(s.prefix, namespace, name, provider, version)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, module.ErrModuleDependenciesNotFound
+	}
+
+	b, err := s.download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalModuleDependencies(b)
+}
+
+// ListModuleDependents lists the module versions in Azure Blob Storage that call the module.
+func (s *AzureStorage) ListModuleDependents(ctx context.Context, namespace, name, provider string) ([]module.ModuleDependent, error) {
+	dependents, err := listModuleDependents(ctx, moduleDependentsPath(s.prefix, namespace, name, provider), s.readVersioned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list module dependents: %w", err)
+	}
+	return dependents, nil
+}
+
+// GetModuleAnnotations downloads the annotations of a module from Azure Blob Storage.
+func (s *AzureStorage) GetModuleAnnotations(ctx context.Context, namespace, name, provider string) (core.VersionAnnotations, error) {
+	return downloadJSONObject[core.VersionAnnotations](ctx, moduleAnnotationsPath(s.prefix, namespace, name, provider), s.readVersioned)
+}
+
+// UpdateModuleAnnotations updates the annotations of a module in Azure Blob Storage with a conditional write.
+func (s *AzureStorage) UpdateModuleAnnotations(ctx context.Context, namespace, name, provider string, update func(core.VersionAnnotations)) error {
+	return updateJSONObject(ctx, moduleAnnotationsPath(s.prefix, namespace, name, provider), s.readVersioned, s.writeConditional, update)
+}
+
+// GetModuleDownloadStats downloads the download stats of a module from Azure Blob Storage.
+func (s *AzureStorage) GetModuleDownloadStats(ctx context.Context, namespace, name, provider string) (core.DownloadStats, error) {
+	return downloadJSONObject[core.DownloadStats](ctx, moduleDownloadStatsPath(s.prefix, namespace, name, provider), s.readVersioned)
+}
+
+// MergeModuleDownloadStats adds the downloads to the download stats of a module in Azure Blob Storage.
+func (s *AzureStorage) MergeModuleDownloadStats(ctx context.Context, namespace, name, provider string, stats core.DownloadStats) error {
+	return updateJSONObject(ctx, moduleDownloadStatsPath(s.prefix, namespace, name, provider), s.readVersioned, s.writeConditional, func(stored core.DownloadStats) {
+		stored.Merge(stats)
+	})
+}
+
 // GetProvider retrieves information about a provider from the Azure Storage.
 func (s *AzureStorage) getProvider(ctx context.Context, pt providerType, provider *core.Provider) (*core.Provider, error) {
 	var archivePath, shasumPath, shasumSigPath string
@@ -137,15 +362,15 @@ func (s *AzureStorage) getProvider(ctx context.Context, pt providerType, provide
 	}
 
 	var err error
-	provider.DownloadURL, err = s.presignedURL(ctx, archivePath)
+	provider.DownloadURL, err = s.downloadURL(ctx, archivePath)
 	if err != nil {
 		return nil, err
 	}
-	provider.SHASumsURL, err = s.presignedURL(ctx, shasumPath)
+	provider.SHASumsURL, err = s.downloadURL(ctx, shasumPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate presigned url for %s: %w", shasumPath, err)
 	}
-	provider.SHASumsSignatureURL, err = s.presignedURL(ctx, shasumSigPath)
+	provider.SHASumsSignatureURL, err = s.downloadURL(ctx, shasumSigPath)
 	if err != nil {
 		return nil, err
 	}
@@ -215,7 +440,7 @@ func (s *AzureStorage) listProviderVersions(ctx context.Context, pt providerType
 
 			p.Hostname = provider.Hostname
 			p.Namespace = provider.Namespace
-			archiveUrl, err := s.presignedURL(ctx, *obj.Name)
+			archiveUrl, err := s.downloadURL(ctx, *obj.Name)
 			if err != nil {
 				return nil, err
 			}
@@ -241,6 +466,15 @@ func (s *AzureStorage) ListProviderVersions(ctx context.Context, namespace, name
 	return collection.List(), nil
 }
 
+// ListProviders lists the providers in Azure Blob Storage.
+func (s *AzureStorage) ListProviders(ctx context.Context) ([]core.Provider, error) {
+	keys, err := s.listKeys(ctx, path.Join(s.prefix, string(internalProviderType))+"/")
+	if err != nil {
+		return nil, err
+	}
+	return providersFromKeys(s.prefix, keys), nil
+}
+
 func (s *AzureStorage) ListMirroredProviders(ctx context.Context, provider *core.Provider) ([]*core.Provider, error) {
 	return s.listProviderVersions(ctx, mirrorProviderType, provider)
 }
@@ -263,6 +497,28 @@ func (s *AzureStorage) UploadProviderReleaseFiles(ctx context.Context, namespace
 	return s.upload(ctx, key, file, false)
 }
 
+// GetProviderAnnotations downloads the annotations of a provider from Azure Blob Storage.
+func (s *AzureStorage) GetProviderAnnotations(ctx context.Context, namespace, name string) (core.VersionAnnotations, error) {
+	return downloadJSONObject[core.VersionAnnotations](ctx, providerAnnotationsPath(s.prefix, namespace, name), s.readVersioned)
+}
+
+// UpdateProviderAnnotations updates the annotations of a provider in Azure Blob Storage with a conditional write.
+func (s *AzureStorage) UpdateProviderAnnotations(ctx context.Context, namespace, name string, update func(core.VersionAnnotations)) error {
+	return updateJSONObject(ctx, providerAnnotationsPath(s.prefix, namespace, name), s.readVersioned, s.writeConditional, update)
+}
+
+// GetProviderDownloadStats downloads the download stats of a provider from Azure Blob Storage.
+func (s *AzureStorage) GetProviderDownloadStats(ctx context.Context, namespace, name string) (core.DownloadStats, error) {
+	return downloadJSONObject[core.DownloadStats](ctx, providerDownloadStatsPath(s.prefix, namespace, name), s.readVersioned)
+}
+
+// MergeProviderDownloadStats adds the downloads to the download stats of a provider in Azure Blob Storage.
+func (s *AzureStorage) MergeProviderDownloadStats(ctx context.Context, namespace, name string, stats core.DownloadStats) error {
+	return updateJSONObject(ctx, providerDownloadStatsPath(s.prefix, namespace, name), s.readVersioned, s.writeConditional, func(stored core.DownloadStats) {
+		stored.Merge(stats)
+	})
+}
+
 func (s *AzureStorage) signingKeys(ctx context.Context, pt providerType, hostname, namespace string) (*core.SigningKeys, error) {
 	if namespace == "" {
 		return nil, fmt.Errorf("namespace argument is empty")
@@ -301,6 +557,11 @@ func (s *AzureStorage) uploadSigningKeys(ctx context.Context, pt providerType, h
 	return s.upload(ctx, key, bytes.NewReader(b), true)
 }
 
+// UploadSigningKeys uploads the JSON of the signing keys to the namespace
+func (s *AzureStorage) UploadSigningKeys(ctx context.Context, namespace string, signingKeys *core.SigningKeys) error {
+	return s.uploadSigningKeys(ctx, internalProviderType, "", namespace, signingKeys)
+}
+
 func (s *AzureStorage) UploadMirroredSigningKeys(ctx context.Context, hostname, namespace string, signingKeys *core.SigningKeys) error {
 	return s.uploadSigningKeys(ctx, mirrorProviderType, hostname, namespace, signingKeys)
 }
@@ -322,31 +583,128 @@ func (s *AzureStorage) UploadMirroredFile(ctx context.Context, provider *core.Pr
 	return s.upload(ctx, key, reader, true)
 }
 
-func (s *AzureStorage) presignedURL(ctx context.Context, key string) (string, error) {
+func (s *AzureStorage) ListMirroredFiles(ctx context.Context) ([]mirror.MirroredFile, error) {
+	prefix := mirrorStoragePrefix(s.prefix)
+
+	var files []mirror.MirroredFile
+	pager := s.client.NewListBlobsFlatPager(s.container, &azblob.ListBlobsFlatOptions{
+		Prefix: &prefix,
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to page next page: %w", err)
+		}
+
+		for _, obj := range page.Segment.BlobItems {
+			f, ok := mirroredFileFromKey(s.prefix, *obj.Name)
+			if !ok {
+				continue
+			}
+			if obj.Properties != nil {
+				if obj.Properties.ContentLength != nil {
+					f.Size = *obj.Properties.ContentLength
+				}
+				if obj.Properties.LastModified != nil {
+					f.LastModified = *obj.Properties.LastModified
+				}
+			}
+			files = append(files, f)
+		}
+	}
+
+	return files, nil
+}
+
+func (s *AzureStorage) DownloadMirroredFile(ctx context.Context, provider *core.Provider, fileName string) (io.ReadCloser, error) {
+	prefix := providerStoragePrefix(s.prefix, mirrorProviderType, provider.Hostname, provider.Namespace, provider.Name)
+	key := path.Join(prefix, fileName)
+	r, err := s.client.DownloadStream(ctx, s.container, key, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, core.ErrObjectNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return r.Body, nil
+}
+
+func (s *AzureStorage) UpdateMirroredFile(ctx context.Context, provider *core.Provider, fileName string, update func([]byte) ([]byte, error)) error {
+	prefix := providerStoragePrefix(s.prefix, mirrorProviderType, provider.Hostname, provider.Namespace, provider.Name)
+	return updateObject(ctx, path.Join(prefix, fileName), s.readVersioned, s.writeConditional, update)
+}
+
+func (s *AzureStorage) DeleteMirroredFile(ctx context.Context, provider *core.Provider, fileName string) error {
+	prefix := providerStoragePrefix(s.prefix, mirrorProviderType, provider.Hostname, provider.Namespace, provider.Name)
+	key := path.Join(prefix, fileName)
+	if _, err := s.client.DeleteBlob(ctx, s.container, key, nil); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// CheckReachable lists at most one blob below the prefix to verify that the container is reachable
+func (s *AzureStorage) CheckReachable(ctx context.Context) error {
+	pager := s.client.NewListBlobsFlatPager(s.container, &azblob.ListBlobsFlatOptions{
+		Prefix:     &s.prefix,
+		MaxResults: to.Ptr(int32(1)),
+	})
+	_, err := pager.NextPage(ctx)
+	return err
+}
+
+// CheckPresign signs a download URL to verify that a user delegation key can be retrieved
+func (s *AzureStorage) CheckPresign(ctx context.Context) error {
+	// The storage backend signs the URL, bypassing the cache and the CDN, so that its credentials are verified
+	_, _, err := s.presignedURL(ctx, path.Join(s.prefix, healthCheckObject), time.Now().Add(s.signedURLExpiry))
+	return err
+}
+
+// downloadURL returns the download URL of the object at key, which is signed for the CDN or the storage backend
+func (s *AzureStorage) downloadURL(ctx context.Context, key string) (string, error) {
+	return signDownloadURL(ctx, key, s.signedURLExpiry, s.presignCache, s.cdn, s.presignedURL)
+}
+
+func (s *AzureStorage) observePresign(observer func(time.Duration)) {
+	s.presignObserver = observer
+}
+
+// presignedURL signs the URL with a user delegation key.
+// The URL expires with the user delegation key at the latest, which is valid for userDelegationKeyExpiry.
+func (s *AzureStorage) presignedURL(ctx context.Context, key string, expires time.Time) (_ string, _ time.Time, err error) {
+	ctx, span := startPresignSpan(ctx, backendAzure, key)
+	defer func() { o11y.EndSpan(span, err) }()
+	defer observePresign(s.presignObserver, time.Now())
+
+	// The expiry of the key is truncated to seconds by its format
+	now := time.Now().UTC().Truncate(time.Second)
+	keyExpires := now.Add(userDelegationKeyExpiry)
 	info := service.KeyInfo{
-		Start:  to.Ptr(time.Now().UTC().Format(sas.TimeFormat)),
-		Expiry: to.Ptr(time.Now().UTC().Add(4 * time.Hour).Format(sas.TimeFormat)),
+		Start:  to.Ptr(now.Format(sas.TimeFormat)),
+		Expiry: to.Ptr(keyExpires.Format(sas.TimeFormat)),
+	}
+	if keyExpires.Before(expires) {
+		expires = keyExpires
 	}
 
 	udc, err := s.client.ServiceClient().GetUserDelegationCredential(ctx, info, nil)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
 	params, err := sas.BlobSignatureValues{
 		Protocol:      sas.ProtocolHTTPS,
-		ExpiryTime:    time.Now().Add(s.signedURLExpiry),
+		ExpiryTime:    expires,
 		Permissions:   to.Ptr(sas.BlobPermissions{Read: true}).String(),
 		ContainerName: s.container,
 		BlobName:      key,
 	}.SignWithUserDelegation(udc)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
 	url := fmt.Sprintf("%s?%s", s.client.ServiceClient().NewContainerClient(s.container).NewBlobClient(key).URL(), params.Encode())
 
-	return url, nil
+	return url, expires, nil
 }
 
 func (s *AzureStorage) objectExists(ctx context.Context, key string) (bool, error) {
@@ -393,6 +751,153 @@ func (s *AzureStorage) download(ctx context.Context, key string) ([]byte, error)
 	return data, nil
 }
 
+// readVersioned downloads the blob at key together with its ETag, or returns core.ErrObjectNotFound
+func (s *AzureStorage) readVersioned(ctx context.Context, key string) ([]byte, string, error) {
+	r, err := s.client.DownloadStream(ctx, s.container, key, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, "", core.ErrObjectNotFound
+	} else if err != nil {
+		return nil, "", fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	var etag string
+	if r.ETag != nil {
+		etag = string(*r.ETag)
+	}
+	return b, etag, nil
+}
+
+// writeConditional uploads the blob at key, if its ETag still matches etag, or if it doesn't exist in case etag is empty.
+// It returns core.ErrObjectModified otherwise.
+func (s *AzureStorage) writeConditional(ctx context.Context, key string, b []byte, etag string) error {
+	conditions := &blob.ModifiedAccessConditions{}
+	if etag == "" {
+		conditions.IfNoneMatch = to.Ptr(azcore.ETagAny)
+	} else {
+		conditions.IfMatch = to.Ptr(azcore.ETag(etag))
+	}
+
+	_, err := s.client.UploadBuffer(ctx, s.container, key, b, &azblob.UploadBufferOptions{
+		AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: conditions},
+	})
+	if bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists) {
+		return fmt.Errorf("failed to upload %s: %w", key, core.ErrObjectModified)
+	} else if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return nil
+}
+
+// listKeys returns the names of all blobs with the prefix
+func (s *AzureStorage) listKeys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	pager := s.client.NewListBlobsFlatPager(s.container, &azblob.ListBlobsFlatOptions{
+		Prefix: &prefix,
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to page next page: %w", err)
+		}
+		for _, obj := range page.Segment.BlobItems {
+			keys = append(keys, *obj.Name)
+		}
+	}
+
+	return keys, nil
+}
+
+func (s *AzureStorage) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	listPrefix := objectListPrefix(s.prefix, prefix)
+
+	var objects []Object
+	pager := s.client.NewListBlobsFlatPager(s.container, &azblob.ListBlobsFlatOptions{
+		Prefix: &listPrefix,
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to page next page: %w", err)
+		}
+		for _, obj := range page.Segment.BlobItems {
+			o := Object{Key: relativeObjectKey(s.prefix, *obj.Name)}
+			if obj.Properties != nil {
+				if obj.Properties.ContentLength != nil {
+					o.Size = *obj.Properties.ContentLength
+				}
+				if obj.Properties.ETag != nil {
+					o.ETag = string(*obj.Properties.ETag)
+				}
+				if obj.Properties.LastModified != nil {
+					o.LastModified = *obj.Properties.LastModified
+				}
+				o.MD5 = hex.EncodeToString(obj.Properties.ContentMD5)
+			}
+			objects = append(objects, o)
+		}
+	}
+
+	return objects, nil
+}
+
+func (s *AzureStorage) StatObject(ctx context.Context, key string) (Object, error) {
+	fullKey := objectKey(s.prefix, key)
+	props, err := s.client.ServiceClient().NewContainerClient(s.container).NewBlobClient(fullKey).GetProperties(ctx, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return Object{}, core.ErrObjectNotFound
+	} else if err != nil {
+		return Object{}, fmt.Errorf("failed to stat %s: %w", fullKey, err)
+	}
+	return azureObject(key, props.ContentLength, props.ETag, props.LastModified, props.ContentMD5), nil
+}
+
+func (s *AzureStorage) ReadObject(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	fullKey := objectKey(s.prefix, key)
+	r, err := s.client.DownloadStream(ctx, s.container, fullKey, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, Object{}, core.ErrObjectNotFound
+	} else if err != nil {
+		return nil, Object{}, fmt.Errorf("failed to download %s: %w", fullKey, err)
+	}
+	return r.Body, azureObject(key, r.ContentLength, r.ETag, r.LastModified, r.ContentMD5), nil
+}
+
+// azureObject returns the Object with the properties of a blob, which are only set if they're returned by Azure
+func azureObject(key string, size *int64, etag *azcore.ETag, lastModified *time.Time, contentMD5 []byte) Object {
+	o := Object{Key: key, MD5: hex.EncodeToString(contentMD5)}
+	if size != nil {
+		o.Size = *size
+	}
+	if etag != nil {
+		o.ETag = string(*etag)
+	}
+	if lastModified != nil {
+		o.LastModified = *lastModified
+	}
+	return o
+}
+
+func (s *AzureStorage) WriteObject(ctx context.Context, key string, r io.Reader) error {
+	return s.upload(ctx, objectKey(s.prefix, key), r, true)
+}
+
+func (s *AzureStorage) UpdateObject(ctx context.Context, key string, update func([]byte) ([]byte, error)) error {
+	return updateObject(ctx, objectKey(s.prefix, key), s.readVersioned, s.writeConditional, update)
+}
+
+func (s *AzureStorage) DeleteObject(ctx context.Context, key string) error {
+	key = objectKey(s.prefix, key)
+	if _, err := s.client.DeleteBlob(ctx, s.container, key, nil); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
 func (s *AzureStorage) GetDownloadUrl(ctx context.Context, url string) (string, error) {
 	return fmt.Sprintf("%s%s", s.client.URL(), url), nil
 }
@@ -407,10 +912,30 @@ func WithAzureStoragePrefix(prefix string) AzureStorageOption {
 	}
 }
 
-// WithAzureStorageArchiveFormat configures the module archive format (zip, tar, tgz, etc.)
+// WithAzureStorageArchiveFormat configures the default module archive format (zip, tar, tgz, etc.).
+// Modules in other formats are served as well.
 func WithAzureStorageArchiveFormat(archiveFormat string) AzureStorageOption {
 	return func(s *AzureStorage) {
-		s.moduleArchiveFormat = archiveFormat
+		if archiveFormat != "" {
+			s.moduleArchiveFormat = archiveFormat
+		}
+	}
+}
+
+// WithAzureStorageSignedURLCache configures the reuse of signed download URLs until they're valid for less than minValidity.
+// The URLs aren't reused if minValidity is 0.
+func WithAzureStorageSignedURLCache(minValidity time.Duration) AzureStorageOption {
+	return func(s *AzureStorage) {
+		if minValidity > 0 {
+			s.presignCache = newPresignCache(minValidity)
+		}
+	}
+}
+
+// WithAzureStorageCDN configures the CDN, for which the download URLs are signed instead of the storage backend
+func WithAzureStorageCDN(cdn *CDN) AzureStorageOption {
+	return func(s *AzureStorage) {
+		s.cdn = cdn
 	}
 }
 
@@ -424,8 +949,9 @@ func WithAzureStorageSignedUrlExpiry(t time.Duration) AzureStorageOption {
 // NewAzureStorage returns a fully initialized Azure Storage.
 func NewAzureStorage(account string, container string, options ...AzureStorageOption) (Storage, error) {
 	s := &AzureStorage{
-		account:   account,
-		container: container,
+		account:             account,
+		container:           container,
+		moduleArchiveFormat: DefaultModuleArchiveFormat,
 	}
 
 	for _, option := range options {
@@ -439,7 +965,11 @@ func NewAzureStorage(account string, container string, options ...AzureStorageOp
 		return nil, err
 	}
 
-	client, err := azblob.NewClient(url, cred, nil)
+	client, err := azblob.NewClient(url, cred, &azblob.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			TracingProvider: azureTracingProvider(),
+		},
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CDNSignerCloudFront = "cloudfront"
+	CDNSignerCloudCDN   = "cloud-cdn"
+)
+
+// CDNSigners are the supported signers of CDN URLs
+var CDNSigners = []string{CDNSignerCloudFront, CDNSignerCloudCDN}
+
+// CDN returns the download URLs of objects on the domain of a CDN, whose origin is the storage backend.
+// The path of an URL is the key of the object, so that hot objects are served from the edge of the CDN rather than from the storage backend.
+type CDN struct {
+	url    *url.URL
+	signer CDNSigner
+}
+
+// CDNSigner signs the URLs of a CDN, so that they can only be downloaded until they expire
+type CDNSigner interface {
+	SignURL(rawURL string, expires time.Time) (string, error)
+}
+
+// NewCDN returns a CDN at the base URL rawURL.
+// The URLs aren't signed if the signer is nil, which requires the CDN to be protected otherwise.
+func NewCDN(rawURL string, signer CDNSigner) (*CDN, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CDN URL: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("CDN URL %s has to be an http or https URL", rawURL)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("CDN URL %s cannot have a query or fragment", rawURL)
+	}
+
+	return &CDN{
+		url:    u,
+		signer: signer,
+	}, nil
+}
+
+// signedURL returns the URL of the object at key on the CDN, which expires at expires
+func (c *CDN) signedURL(key string, expires time.Time) (string, error) {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	rawURL := strings.TrimSuffix(c.url.String(), "/") + "/" + strings.Join(segments, "/")
+
+	if c.signer == nil {
+		return rawURL, nil
+	}
+	return c.signer.SignURL(rawURL, expires)
+}
+
+// NewCDNSigner returns the CDNSigner with the name, which is one of CDNSigners.
+// The keyID is the key pair ID of CloudFront or the key name of Cloud CDN, and key the corresponding key.
+func NewCDNSigner(name, keyID string, key []byte) (CDNSigner, error) {
+	switch name {
+	case CDNSignerCloudFront:
+		return NewCloudFrontSigner(keyID, key)
+	case CDNSignerCloudCDN:
+		return NewCloudCDNSigner(keyID, key)
+	default:
+		return nil, fmt.Errorf("unsupported CDN signer %q, has to be one of %v", name, CDNSigners)
+	}
+}
+
+type cloudFrontSigner struct {
+	keyPairID  string
+	privateKey *rsa.PrivateKey
+}
+
+// NewCloudFrontSigner returns a CDNSigner for CloudFront signed URLs with a canned policy.
+// The keyPairID is the ID of the public key in the trusted key group of the distribution, and privateKey the PEM encoded RSA private key.
+func NewCloudFrontSigner(keyPairID string, privateKey []byte) (CDNSigner, error) {
+	if keyPairID == "" {
+		return nil, errors.New("the key pair ID of CloudFront cannot be empty")
+	}
+
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, errors.New("failed to decode the PEM encoded private key of CloudFront")
+	}
+
+	var key *rsa.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the private key of CloudFront: %w", err)
+		}
+		key = k
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the private key of CloudFront: %w", err)
+		}
+		rsaKey, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("the private key of CloudFront has to be an RSA key")
+		}
+		key = rsaKey
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %s of the private key of CloudFront", block.Type)
+	}
+
+	return &cloudFrontSigner{
+		keyPairID:  keyPairID,
+		privateKey: key,
+	}, nil
+}
+
+// cloudFrontPolicy is the canned policy of a CloudFront signed URL
+type cloudFrontPolicy struct {
+	Statement []cloudFrontStatement `json:"Statement"`
+}
+
+type cloudFrontStatement struct {
+	Resource  string `json:"Resource"`
+	Condition struct {
+		DateLessThan struct {
+			EpochTime int64 `json:"AWS:EpochTime"`
+		} `json:"DateLessThan"`
+	} `json:"Condition"`
+}
+
+// SignURL signs the URL with a canned policy.
+// https://docs.aws.amazon.com/AmazonCloudFront/latest/DeveloperGuide/private-content-creating-signed-url-canned-policy.html
+func (s *cloudFrontSigner) SignURL(rawURL string, expires time.Time) (string, error) {
+	statement := cloudFrontStatement{Resource: rawURL}
+	statement.Condition.DateLessThan.EpochTime = expires.Unix()
+
+	var policy strings.Builder
+	encoder := json.NewEncoder(&policy)
+	// The policy is signed as is, so the URL mustn't be escaped
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(cloudFrontPolicy{Statement: []cloudFrontStatement{statement}}); err != nil {
+		return "", fmt.Errorf("failed to marshal CloudFront policy: %w", err)
+	}
+
+	hash := sha1.Sum([]byte(strings.TrimSuffix(policy.String(), "\n")))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA1, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign CloudFront URL: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("Expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("Signature", cloudFrontEncoding.Replace(base64.StdEncoding.EncodeToString(signature)))
+	query.Set("Key-Pair-Id", s.keyPairID)
+
+	return rawURL + "?" + query.Encode(), nil
+}
+
+// cloudFrontEncoding replaces the characters of base64, which are invalid in a query string
+var cloudFrontEncoding = strings.NewReplacer("+", "-", "=", "_", "/", "~")
+
+type cloudCDNSigner struct {
+	keyName string
+	key     []byte
+}
+
+// NewCloudCDNSigner returns a CDNSigner for Cloud CDN signed URLs.
+// The keyName is the name of the signed request key of the backend bucket, and key its base64url encoded value.
+func NewCloudCDNSigner(keyName string, key []byte) (CDNSigner, error) {
+	if keyName == "" {
+		return nil, errors.New("the key name of Cloud CDN cannot be empty")
+	}
+
+	decoded, err := base64.URLEncoding.DecodeString(strings.TrimSpace(string(key)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the key of Cloud CDN: %w", err)
+	}
+
+	return &cloudCDNSigner{
+		keyName: keyName,
+		key:     decoded,
+	}, nil
+}
+
+// SignURL signs the URL with the key.
+// https://cloud.google.com/cdn/docs/using-signed-urls#programmatically_creating_signed_urls
+func (s *cloudCDNSigner) SignURL(rawURL string, expires time.Time) (string, error) {
+	signedURL := fmt.Sprintf("%s?Expires=%d&KeyName=%s", rawURL, expires.Unix(), url.QueryEscape(s.keyName))
+
+	mac := hmac.New(sha1.New, s.key)
+	mac.Write([]byte(signedURL))
+	signature := base64.URLEncoding.EncodeToString(mac.Sum(nil))
+
+	return signedURL + "&Signature=" + signature, nil
+}
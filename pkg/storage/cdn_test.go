@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCDN(t *testing.T) {
+	testCases := []struct {
+		name        string
+		url         string
+		expectError bool
+	}{
+		{name: "https", url: "https://downloads.example.com"},
+		{name: "path", url: "https://example.com/downloads/"},
+		{name: "no scheme", url: "downloads.example.com", expectError: true},
+		{name: "query", url: "https://downloads.example.com?a=b", expectError: true},
+		{name: "unsupported scheme", url: "ftp://downloads.example.com", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewCDN(tc.url, nil)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCDN_signedURL(t *testing.T) {
+	assert := assert.New(t)
+
+	cdn, err := NewCDN("https://example.com/downloads/", nil)
+	assert.NoError(err)
+
+	url, err := cdn.signedURL("boring/modules/acme/vpc/aws/acme-vpc-aws-1.0.0+build.tar.gz", time.Now())
+	assert.NoError(err)
+	assert.Equal("https://example.com/downloads/boring/modules/acme/vpc/aws/acme-vpc-aws-1.0.0+build.tar.gz", url)
+
+	url, err = cdn.signedURL("providers/acme/dummy/dummy 1.0.0.zip", time.Now())
+	assert.NoError(err)
+	assert.Equal("https://example.com/downloads/providers/acme/dummy/dummy%201.0.0.zip", url)
+}
+
+func TestCloudFrontSigner(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(err)
+
+	for _, block := range []*pem.Block{
+		{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		{Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		signer, err := NewCloudFrontSigner("K2JCJMDEHXQW5F", pem.EncodeToMemory(block))
+		if !assert.NoError(err) {
+			continue
+		}
+
+		expires := time.Unix(1700000000, 0)
+		signed, err := signer.SignURL("https://d111111abcdef8.cloudfront.net/providers/a&b.zip", expires)
+		assert.NoError(err)
+
+		rawURL, rawQuery, _ := strings.Cut(signed, "?")
+		assert.Equal("https://d111111abcdef8.cloudfront.net/providers/a&b.zip", rawURL)
+		query, err := url.ParseQuery(rawQuery)
+		assert.NoError(err)
+		assert.Equal("1700000000", query.Get("Expires"))
+		assert.Equal("K2JCJMDEHXQW5F", query.Get("Key-Pair-Id"))
+
+		signature, err := base64.StdEncoding.DecodeString(strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(query.Get("Signature")))
+		assert.NoError(err)
+		policy := `{"Statement":[{"Resource":"https://d111111abcdef8.cloudfront.net/providers/a&b.zip","Condition":{"DateLessThan":{"AWS:EpochTime":1700000000}}}]}`
+		hash := sha1.Sum([]byte(policy))
+		assert.NoError(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, hash[:], signature))
+	}
+
+	_, err = NewCloudFrontSigner("K2JCJMDEHXQW5F", []byte("not a key"))
+	assert.Error(err)
+	_, err = NewCloudFrontSigner("", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	assert.Error(err)
+}
+
+func TestCloudCDNSigner(t *testing.T) {
+	assert := assert.New(t)
+
+	key := []byte("0123456789abcdef")
+	signer, err := NewCloudCDNSigner("boring-registry", []byte(base64.URLEncoding.EncodeToString(key)+"\n"))
+	assert.NoError(err)
+
+	signed, err := signer.SignURL("https://cdn.example.com/modules/acme-vpc-aws-1.0.0.tar.gz", time.Unix(1700000000, 0))
+	assert.NoError(err)
+
+	unsigned := "https://cdn.example.com/modules/acme-vpc-aws-1.0.0.tar.gz?Expires=1700000000&KeyName=boring-registry"
+	mac := hmac.New(sha1.New, key)
+	mac.Write([]byte(unsigned))
+	assert.Equal(fmt.Sprintf("%s&Signature=%s", unsigned, base64.URLEncoding.EncodeToString(mac.Sum(nil))), signed)
+
+	_, err = NewCloudCDNSigner("boring-registry", []byte("not base64!"))
+	assert.Error(err)
+}
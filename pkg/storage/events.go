@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/events"
+)
+
+// eventStorage publishes an event for each successful change of the next Storage that downstream systems might react to
+type eventStorage struct {
+	Storage
+	publisher events.Publisher
+}
+
+// NewEventStorage returns a Storage, which publishes events when module and provider versions are published,
+// and when providers are copied into or deleted from the network mirror.
+// This covers the uploads of the CLI, as well as the copier and the garbage collection of the mirror.
+func NewEventStorage(next Storage, publisher events.Publisher) Storage {
+	return &eventStorage{
+		Storage:   next,
+		publisher: publisher,
+	}
+}
+
+func (s *eventStorage) UploadModule(ctx context.Context, namespace, name, provider, version, format string, body io.Reader) (core.Module, error) {
+	m, err := s.Storage.UploadModule(ctx, namespace, name, provider, version, format, body)
+	if err == nil {
+		s.publisher.Publish(events.NewModuleEvent(events.ModulePublished, namespace, name, provider, version))
+	}
+	return m, err
+}
+
+func (s *eventStorage) UploadModuleSource(ctx context.Context, namespace, name, provider, version, source string) (core.Module, error) {
+	m, err := s.Storage.UploadModuleSource(ctx, namespace, name, provider, version, source)
+	if err == nil {
+		s.publisher.Publish(events.NewModuleEvent(events.ModulePublished, namespace, name, provider, version))
+	}
+	return m, err
+}
+
+// UploadProviderReleaseFiles publishes an event once the SHA256SUMS signature is uploaded,
+// as it's uploaded last and completes the release
+func (s *eventStorage) UploadProviderReleaseFiles(ctx context.Context, namespace, name, filename string, file io.Reader) error {
+	if err := s.Storage.UploadProviderReleaseFiles(ctx, namespace, name, filename, file); err != nil {
+		return err
+	}
+
+	prefix := fmt.Sprintf("%s%s_", core.ProviderPrefix, name)
+	suffix := "_SHA256SUMS.sig"
+	if strings.HasPrefix(filename, prefix) && strings.HasSuffix(filename, suffix) {
+		version := strings.TrimSuffix(strings.TrimPrefix(filename, prefix), suffix)
+		s.publisher.Publish(events.NewProviderEvent(events.ProviderPublished, &core.Provider{
+			Namespace: namespace,
+			Name:      name,
+			Version:   version,
+		}))
+	}
+	return nil
+}
+
+func (s *eventStorage) UploadMirroredFile(ctx context.Context, provider *core.Provider, fileName string, r io.Reader) error {
+	if err := s.Storage.UploadMirroredFile(ctx, provider, fileName, r); err != nil {
+		return err
+	}
+	if p, ok := mirroredArchive(provider, fileName); ok {
+		s.publisher.Publish(events.NewProviderEvent(events.MirrorProviderCopied, p))
+	}
+	return nil
+}
+
+func (s *eventStorage) DeleteMirroredFile(ctx context.Context, provider *core.Provider, fileName string) error {
+	if err := s.Storage.DeleteMirroredFile(ctx, provider, fileName); err != nil {
+		return err
+	}
+	if p, ok := mirroredArchive(provider, fileName); ok {
+		s.publisher.Publish(events.NewProviderEvent(events.MirrorProviderDeleted, p))
+	}
+	return nil
+}
+
+// mirroredArchive returns the mirrored provider platform, if the file is a provider archive.
+// The version and platform are taken from the file name, as the provider only identifies the location of the file.
+func mirroredArchive(provider *core.Provider, fileName string) (*core.Provider, bool) {
+	if !strings.HasSuffix(fileName, core.ProviderExtension) {
+		return nil, false
+	}
+	archive, err := core.NewProviderFromArchive(fileName)
+	if err != nil {
+		return nil, false
+	}
+
+	return &core.Provider{
+		Hostname:  provider.Hostname,
+		Namespace: provider.Namespace,
+		Name:      provider.Name,
+		Version:   archive.Version,
+		OS:        archive.OS,
+		Arch:      archive.Arch,
+	}, true
+}
+
+func (s *eventStorage) CheckReachable(ctx context.Context) error {
+	if checker, ok := s.Storage.(HealthChecker); ok {
+		return checker.CheckReachable(ctx)
+	}
+	return nil
+}
+
+func (s *eventStorage) CheckPresign(ctx context.Context) error {
+	if checker, ok := s.Storage.(HealthChecker); ok {
+		return checker.CheckPresign(ctx)
+	}
+	return nil
+}
+
+// ListObjects forwards the objects of the next Storage.
+// Writing and deleting objects doesn't publish events, as the replication only copies versions that were published to another storage already.
+func (s *eventStorage) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	if o, ok := s.Storage.(ObjectStorage); ok {
+		return o.ListObjects(ctx, prefix)
+	}
+	return nil, errObjectStorageUnsupported
+}
+
+func (s *eventStorage) StatObject(ctx context.Context, key string) (Object, error) {
+	if o, ok := s.Storage.(ObjectStorage); ok {
+		return o.StatObject(ctx, key)
+	}
+	return Object{}, errObjectStorageUnsupported
+}
+
+func (s *eventStorage) ReadObject(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	if o, ok := s.Storage.(ObjectStorage); ok {
+		return o.ReadObject(ctx, key)
+	}
+	return nil, Object{}, errObjectStorageUnsupported
+}
+
+func (s *eventStorage) WriteObject(ctx context.Context, key string, r io.Reader) error {
+	if o, ok := s.Storage.(ObjectStorage); ok {
+		return o.WriteObject(ctx, key, r)
+	}
+	return errObjectStorageUnsupported
+}
+
+func (s *eventStorage) UpdateObject(ctx context.Context, key string, update func([]byte) ([]byte, error)) error {
+	if o, ok := s.Storage.(ObjectStorage); ok {
+		return o.UpdateObject(ctx, key, update)
+	}
+	return errObjectStorageUnsupported
+}
+
+func (s *eventStorage) DeleteObject(ctx context.Context, key string) error {
+	if o, ok := s.Storage.(ObjectStorage); ok {
+		return o.DeleteObject(ctx, key)
+	}
+	return errObjectStorageUnsupported
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/events"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(e events.Event) {
+	p.events = append(p.events, e)
+}
+
+type changingFakeStorage struct {
+	Storage
+	err error
+}
+
+func (f *changingFakeStorage) UploadModule(ctx context.Context, namespace, name, provider, version, format string, body io.Reader) (core.Module, error) {
+	return core.Module{}, f.err
+}
+
+func (f *changingFakeStorage) UploadProviderReleaseFiles(ctx context.Context, namespace, name, filename string, file io.Reader) error {
+	return f.err
+}
+
+func (f *changingFakeStorage) UploadMirroredFile(ctx context.Context, provider *core.Provider, fileName string, r io.Reader) error {
+	return f.err
+}
+
+func (f *changingFakeStorage) DeleteMirroredFile(ctx context.Context, provider *core.Provider, fileName string) error {
+	return f.err
+}
+
+func TestEventStorage(t *testing.T) {
+	ctx := context.Background()
+	publisher := &recordingPublisher{}
+	next := &changingFakeStorage{}
+	s := NewEventStorage(next, publisher)
+
+	_, err := s.UploadModule(ctx, "acme", "vpc", "aws", "1.0.0", "tar.gz", strings.NewReader(""))
+	assert.NoError(t, err)
+
+	// Only the signature completes a provider release
+	for _, f := range []string{"terraform-provider-dummy_1.0.0_linux_amd64.zip", "terraform-provider-dummy_1.0.0_SHA256SUMS", "terraform-provider-dummy_1.0.0_SHA256SUMS.sig"} {
+		assert.NoError(t, s.UploadProviderReleaseFiles(ctx, "acme", "dummy", f, strings.NewReader("")))
+	}
+
+	// Only archives are copied or deleted providers
+	mirrored := &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random"}
+	for _, f := range []string{"terraform-provider-random_3.6.0_SHA256SUMS", "access-log.json", "terraform-provider-random_3.6.0_darwin_arm64.zip"} {
+		assert.NoError(t, s.UploadMirroredFile(ctx, mirrored, f, strings.NewReader("")))
+	}
+	assert.NoError(t, s.DeleteMirroredFile(ctx, mirrored, "terraform-provider-random_3.6.0_darwin_arm64.zip"))
+	assert.NoError(t, s.DeleteMirroredFile(ctx, mirrored, "terraform-provider-random_3.6.0_SHA256SUMS.sig"))
+
+	// Failed changes aren't published
+	next.err = errors.New("access denied")
+	_, err = s.UploadModule(ctx, "acme", "vpc", "aws", "2.0.0", "tar.gz", strings.NewReader(""))
+	assert.Error(t, err)
+	assert.Error(t, s.DeleteMirroredFile(ctx, mirrored, "terraform-provider-random_3.5.0_darwin_arm64.zip"))
+
+	var types []events.Type
+	for _, e := range publisher.events {
+		assert.NotEmpty(t, e.ID)
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []events.Type{events.ModulePublished, events.ProviderPublished, events.MirrorProviderCopied, events.MirrorProviderDeleted}, types)
+	assert.Equal(t, &events.Module{Namespace: "acme", Name: "vpc", Provider: "aws", Version: "1.0.0"}, publisher.events[0].Module)
+	assert.Equal(t, &events.Provider{Namespace: "acme", Name: "dummy", Version: "1.0.0"}, publisher.events[1].Provider)
+	assert.Equal(t, &events.Provider{
+		Hostname:  "registry.terraform.io",
+		Namespace: "hashicorp",
+		Name:      "random",
+		Version:   "3.6.0",
+		OS:        "darwin",
+		Arch:      "arm64",
+	}, publisher.events[2].Provider)
+}
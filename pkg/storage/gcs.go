@@ -3,21 +3,27 @@ package storage
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"path"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/mirror"
 	"github.com/boring-registry/boring-registry/pkg/module"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
 
 	credentials "cloud.google.com/go/iam/credentials/apiv1"
 	"cloud.google.com/go/iam/credentials/apiv1/credentialspb"
 	"cloud.google.com/go/storage"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 )
 
@@ -30,28 +36,73 @@ type GCSStorage struct {
 	signedURLExpiry     time.Duration
 	serviceAccount      string
 	moduleArchiveFormat string
+
+	// presignCache reuses signed download URLs, if it's enabled
+	presignCache *presignCache
+	// cdn signs the download URLs for a CDN instead of the storage backend, if it's configured
+	cdn *CDN
+
+	// presignObserver is notified about the duration of signing a download URL
+	presignObserver func(time.Duration)
 }
 
 func (s *GCSStorage) GetModule(ctx context.Context, namespace, name, provider, version string) (core.Module, error) {
-	o := s.sc.Bucket(s.bucket).Object(modulePath(s.bucketPrefix, namespace, name, provider, version, s.moduleArchiveFormat))
-	attrs, err := o.Attrs(ctx)
+	key, err := s.moduleObject(ctx, namespace, name, provider, version)
 	if err != nil {
-		return core.Module{}, fmt.Errorf("%v: %w", module.ErrModuleNotFound, err)
+		return core.Module{}, err
 	}
-	url, err := s.presignedURL(ctx, attrs.Name)
+
+	m, err := moduleVersionFromObject(key)
 	if err != nil {
+		return core.Module{}, err
+	}
+	/* https://www.terraform.io/docs/internals/module-registry-protocol.html#sample-response-1
+	e.g. "gcs::https://www.googleapis.com/storage/v1/modules/foomodule.zip
+	*/
+	if err := moduleDownloadURL(ctx, m, key, s.downloadURL, s.download); err != nil {
 		return core.Module{}, fmt.Errorf("%v: %w", module.ErrModuleNotFound, err)
 	}
-	return core.Module{
-		Namespace: namespace,
-		Name:      attrs.Name,
-		Provider:  provider,
-		Version:   version,
-		/* https://www.terraform.io/docs/internals/module-registry-protocol.html#sample-response-1
-		e.g. "gcs::https://www.googleapis.com/storage/v1/modules/foomodule.zip
-		*/
-		DownloadURL: url,
-	}, nil
+	return *m, nil
+}
+
+// moduleObject returns the key of the archive or source of a module version.
+// The archive in the configured format is looked up first, before the archives in other formats and sources are listed.
+func (s *GCSStorage) moduleObject(ctx context.Context, namespace, name, provider, version string) (string, error) {
+	key := modulePath(s.bucketPrefix, namespace, name, provider, version, s.moduleArchiveFormat)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return "", err
+	} else if exists {
+		return key, nil
+	}
+
+	source := ""
+	it := s.sc.Bucket(s.bucket).Objects(ctx, &storage.Query{
+		Prefix: moduleArchivePathPrefix(s.bucketPrefix, namespace, name, provider, version),
+	})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		m, err := moduleVersionFromObject(attrs.Name)
+		if err != nil || m.Version != version {
+			continue
+		}
+		// Archives take precedence over sources
+		if m.ArchiveFormat != "" {
+			return attrs.Name, nil
+		}
+		source = attrs.Name
+	}
+
+	if source == "" {
+		return "", module.ErrModuleNotFound
+	}
+	return source, nil
 }
 
 func (s *GCSStorage) ListModuleVersions(ctx context.Context, namespace, name, provider string) ([]core.Module, error) {
@@ -71,17 +122,28 @@ func (s *GCSStorage) ListModuleVersions(ctx context.Context, namespace, name, pr
 		if err != nil {
 			return modules, err
 		}
-		m, err := moduleFromObject(attrs.Name, s.moduleArchiveFormat)
+		m, err := moduleVersionFromObject(attrs.Name)
 		if err != nil {
 			// TODO: we're skipping possible failures silently
 			continue
 		}
 		modules = append(modules, *m)
 	}
-	return modules, nil
+	return uniqueModuleVersions(modules), nil
+}
+
+// ListModules lists the modules in GCS.
+func (s *GCSStorage) ListModules(ctx context.Context) ([]core.Module, error) {
+	keys, err := s.listKeys(ctx, path.Join(s.bucketPrefix, string(internalModuleType))+"/")
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", module.ErrModuleListFailed, err)
+	}
+	return modulesFromKeys(s.bucketPrefix, keys), nil
 }
 
-func (s *GCSStorage) UploadModule(ctx context.Context, namespace, name, provider, version string, body io.Reader) (core.Module, error) {
+// UploadModule uploads a module to GCS.
+// The archive is stored in the configured format, unless another format is given.
+func (s *GCSStorage) UploadModule(ctx context.Context, namespace, name, provider, version, format string, body io.Reader) (core.Module, error) {
 	if namespace == "" {
 		return core.Module{}, errors.New("namespace not defined")
 	}
@@ -98,22 +160,187 @@ func (s *GCSStorage) UploadModule(ctx context.Context, namespace, name, provider
 		return core.Module{}, errors.New("version not defined")
 	}
 
-	key := modulePath(s.bucketPrefix, namespace, name, provider, version, s.moduleArchiveFormat)
-	if _, err := s.GetModule(ctx, namespace, name, provider, version); err == nil {
+	format, err := uploadArchiveFormat(format, s.moduleArchiveFormat)
+	if err != nil {
+		return core.Module{}, err
+	}
+	key := modulePath(s.bucketPrefix, namespace, name, provider, version, format)
+	if m, err := s.GetModule(ctx, namespace, name, provider, version); err == nil && m.Source == "" {
 		return core.Module{}, fmt.Errorf("%w: %s", module.ErrModuleAlreadyExists, key)
 	}
 
-	wc := s.sc.Bucket(s.bucket).Object(key).NewWriter(ctx)
+	wc := s.sc.Bucket(s.bucket).Object(key).If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
 	if _, err := io.Copy(wc, body); err != nil {
+		_ = wc.Close()
 		return core.Module{}, fmt.Errorf("%v: %w", module.ErrModuleUploadFailed, err)
 	}
 	if err := wc.Close(); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return core.Module{}, fmt.Errorf("%w: %s", module.ErrModuleAlreadyExists, key)
+		}
+		return core.Module{}, fmt.Errorf("%v: %w", module.ErrModuleUploadFailed, err)
+	}
+
+	return s.GetModule(ctx, namespace, name, provider, version)
+}
+
+// UploadModuleSource registers a module version with a git source in GCS.
+func (s *GCSStorage) UploadModuleSource(ctx context.Context, namespace, name, provider, version, source string) (core.Module, error) {
+	if err := validateModuleSource(namespace, name, provider, version, source); err != nil {
+		return core.Module{}, err
+	}
+
+	key := moduleSourcePath(s.bucketPrefix, namespace, name, provider, version)
+	if _, err := s.GetModule(ctx, namespace, name, provider, version); err == nil {
+		return core.Module{}, fmt.Errorf("%w: %s", module.ErrModuleAlreadyExists, key)
+	}
+
+	b, err := json.Marshal(moduleSource{Source: source})
+	if err != nil {
+		return core.Module{}, err
+	}
+	if err := s.upload(ctx, key, bytes.NewReader(b), false); err != nil {
 		return core.Module{}, fmt.Errorf("%v: %w", module.ErrModuleUploadFailed, err)
 	}
 
 	return s.GetModule(ctx, namespace, name, provider, version)
 }
 
+// DeleteModuleSource deletes the source of a module version from GCS.
+func (s *GCSStorage) DeleteModuleSource(ctx context.Context, namespace, name, provider, version string) error {
+	key := moduleSourcePath(s.bucketPrefix, namespace, name, provider, version)
+	if err := s.sc.Bucket(s.bucket).Object(key).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetModuleDetails downloads the details of a module version from GCS.
+func (s *GCSStorage) GetModuleDetails(ctx context.Context, namespace, name, provider, version string) (*module.Details, error) {
+	key := moduleDetailsPath(s.bucketPrefix, namespace, name, provider, version)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, module.ErrModuleDetailsNotFound
+	}
+
+	b, err := s.download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalModuleDetails(b)
+}
+
+// UploadModuleDetails uploads the details of a module version to GCS.
+func (s *GCSStorage) UploadModuleDetails(ctx context.Context, namespace, name, provider, version string, details *module.Details) error {
+	b, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	key := moduleDetailsPath(s.bucketPrefix, namespace, name, provider, version)
+	return s.upload(ctx, key, bytes.NewReader(b), true)
+}
+
+// GetModuleDocs downloads the documentation bundle of a module version from GCS.
+func (s *GCSStorage) GetModuleDocs(ctx context.Context, namespace, name, provider, version string) (*module.Docs, error) {
+	key := moduleDocsPath(s.bucketPrefix, namespace, name, provider, version)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, module.ErrModuleDocsNotFound
+	}
+
+	b, err := s.download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalModuleDocs(b)
+}
+
+// UploadModuleDocs uploads the documentation bundle of a module version to GCS.
+func (s *GCSStorage) UploadModuleDocs(ctx context.Context, namespace, name, provider, version string, docs *module.Docs) error {
+	b, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	key := moduleDocsPath(s.bucketPrefix, namespace, name, provider, version)
+	return s.upload(ctx, key, bytes.NewReader(b), true)
+}
+
+// UploadModuleDependencies uploads the dependencies of a module version to GCS and indexes them by the called modules.
+func (s *GCSStorage) UploadModuleDependencies(ctx context.Context, namespace, name, provider, version string, dependencies []module.ModuleDependency) error {
+	// Only stored versions are indexed, so that a failed upload never leaves dependents of a version that doesn't exist
+	if _, err := s.moduleObject(ctx, namespace, name, provider, version); err != nil {
+		return err
+	}
+
+	previous, err := s.GetModuleDependencies(ctx, namespace, name, provider, version)
+	if err != nil && !errors.Is(err, module.ErrModuleDependenciesNotFound) {
+		return err
+	}
+	if err := updateModuleDependents(ctx, s.bucketPrefix, namespace, name, provider, version, previous, dependencies, s.readVersioned, s.writeConditional); err != nil {
+		return err
+	}
+
+	// The dependencies are uploaded last, so that the dependents are updated again if the upload is retried
+	b, err := marshalModuleDependencies(dependencies)
+	if err != nil {
+		return err
+	}
+	return s.upload(ctx, moduleDependenciesPath(s.bucketPrefix, namespace, name, provider, version), bytes.NewReader(b), true)
+}
+
+// GetModuleDependencies downloads the dependencies of a module version from GCS.
+func (s *GCSStorage) GetModuleDependencies(ctx context.Context, namespace, name, provider, version string) ([]module.ModuleDependency, error) {
+	key := moduleDependenciesPath(s.bucketPrefix, namespace, name, provider, version)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, module.ErrModuleDependenciesNotFound
+	}
+
+	b, err := s.download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalModuleDependencies(b)
+}
+
+// ListModuleDependents lists the module versions in GCS that call the module.
+func (s *GCSStorage) ListModuleDependents(ctx context.Context, namespace, name, provider string) ([]module.ModuleDependent, error) {
+	dependents, err := listModuleDependents(ctx, moduleDependentsPath(s.bucketPrefix, namespace, name, provider), s.readVersioned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list module dependents: %w", err)
+	}
+	return dependents, nil
+}
+
+// GetModuleAnnotations downloads the annotations of a module from GCS.
+func (s *GCSStorage) GetModuleAnnotations(ctx context.Context, namespace, name, provider string) (core.VersionAnnotations, error) {
+	return downloadJSONObject[core.VersionAnnotations](ctx, moduleAnnotationsPath(s.bucketPrefix, namespace, name, provider), s.readVersioned)
+}
+
+// UpdateModuleAnnotations updates the annotations of a module in GCS with a conditional write.
+func (s *GCSStorage) UpdateModuleAnnotations(ctx context.Context, namespace, name, provider string, update func(core.VersionAnnotations)) error {
+	return updateJSONObject(ctx, moduleAnnotationsPath(s.bucketPrefix, namespace, name, provider), s.readVersioned, s.writeConditional, update)
+}
+
+// GetModuleDownloadStats downloads the download stats of a module from GCS.
+func (s *GCSStorage) GetModuleDownloadStats(ctx context.Context, namespace, name, provider string) (core.DownloadStats, error) {
+	return downloadJSONObject[core.DownloadStats](ctx, moduleDownloadStatsPath(s.bucketPrefix, namespace, name, provider), s.readVersioned)
+}
+
+// MergeModuleDownloadStats adds the downloads to the download stats of a module in GCS.
+func (s *GCSStorage) MergeModuleDownloadStats(ctx context.Context, namespace, name, provider string, stats core.DownloadStats) error {
+	return updateJSONObject(ctx, moduleDownloadStatsPath(s.bucketPrefix, namespace, name, provider), s.readVersioned, s.writeConditional, func(stored core.DownloadStats) {
+		stored.Merge(stats)
+	})
+}
+
 // GetProvider implements provider.Storage
 func (s *GCSStorage) getProvider(ctx context.Context, pt providerType, provider *core.Provider) (*core.Provider, error) {
 	var archivePath, shasumPath, shasumSigPath string
@@ -130,15 +357,15 @@ func (s *GCSStorage) getProvider(ctx context.Context, pt providerType, provider
 	}
 
 	var err error
-	provider.DownloadURL, err = s.presignedURL(ctx, archivePath)
+	provider.DownloadURL, err = s.downloadURL(ctx, archivePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pre-signed url for %s: %w", archivePath, err)
 	}
-	provider.SHASumsURL, err = s.presignedURL(ctx, shasumPath)
+	provider.SHASumsURL, err = s.downloadURL(ctx, shasumPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pre-signed url for %s: %w", archivePath, err)
 	}
-	provider.SHASumsSignatureURL, err = s.presignedURL(ctx, shasumSigPath)
+	provider.SHASumsSignatureURL, err = s.downloadURL(ctx, shasumSigPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pre-signed url for %s: %w", archivePath, err)
 	}
@@ -214,7 +441,7 @@ func (s *GCSStorage) listProviderVersions(ctx context.Context, pt providerType,
 
 		p.Hostname = provider.Hostname
 		p.Namespace = provider.Namespace
-		archiveUrl, err := s.presignedURL(ctx, attrs.Name)
+		archiveUrl, err := s.downloadURL(ctx, attrs.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -248,6 +475,15 @@ func (s *GCSStorage) ListProviderVersions(ctx context.Context, namespace, name s
 	return collection.List(), nil
 }
 
+// ListProviders lists the providers in GCS.
+func (s *GCSStorage) ListProviders(ctx context.Context) ([]core.Provider, error) {
+	keys, err := s.listKeys(ctx, path.Join(s.bucketPrefix, string(internalProviderType))+"/")
+	if err != nil {
+		return nil, err
+	}
+	return providersFromKeys(s.bucketPrefix, keys), nil
+}
+
 func (s *GCSStorage) ListMirroredProviders(ctx context.Context, provider *core.Provider) ([]*core.Provider, error) {
 	return s.listProviderVersions(ctx, mirrorProviderType, provider)
 }
@@ -270,6 +506,28 @@ func (s *GCSStorage) UploadProviderReleaseFiles(ctx context.Context, namespace,
 	return s.upload(ctx, key, file, false)
 }
 
+// GetProviderAnnotations downloads the annotations of a provider from GCS.
+func (s *GCSStorage) GetProviderAnnotations(ctx context.Context, namespace, name string) (core.VersionAnnotations, error) {
+	return downloadJSONObject[core.VersionAnnotations](ctx, providerAnnotationsPath(s.bucketPrefix, namespace, name), s.readVersioned)
+}
+
+// UpdateProviderAnnotations updates the annotations of a provider in GCS with a conditional write.
+func (s *GCSStorage) UpdateProviderAnnotations(ctx context.Context, namespace, name string, update func(core.VersionAnnotations)) error {
+	return updateJSONObject(ctx, providerAnnotationsPath(s.bucketPrefix, namespace, name), s.readVersioned, s.writeConditional, update)
+}
+
+// GetProviderDownloadStats downloads the download stats of a provider from GCS.
+func (s *GCSStorage) GetProviderDownloadStats(ctx context.Context, namespace, name string) (core.DownloadStats, error) {
+	return downloadJSONObject[core.DownloadStats](ctx, providerDownloadStatsPath(s.bucketPrefix, namespace, name), s.readVersioned)
+}
+
+// MergeProviderDownloadStats adds the downloads to the download stats of a provider in GCS.
+func (s *GCSStorage) MergeProviderDownloadStats(ctx context.Context, namespace, name string, stats core.DownloadStats) error {
+	return updateJSONObject(ctx, providerDownloadStatsPath(s.bucketPrefix, namespace, name), s.readVersioned, s.writeConditional, func(stored core.DownloadStats) {
+		stored.Merge(stats)
+	})
+}
+
 func (s *GCSStorage) UploadMirroredFile(ctx context.Context, provider *core.Provider, fileName string, reader io.Reader) error {
 	prefix := providerStoragePrefix(s.bucketPrefix, mirrorProviderType, provider.Hostname, provider.Namespace, provider.Name)
 
@@ -314,6 +572,11 @@ func (s *GCSStorage) uploadSigningKeys(ctx context.Context, pt providerType, hos
 	return s.upload(ctx, key, bytes.NewReader(b), true)
 }
 
+// UploadSigningKeys uploads the JSON of the signing keys to the namespace
+func (s *GCSStorage) UploadSigningKeys(ctx context.Context, namespace string, signingKeys *core.SigningKeys) error {
+	return s.uploadSigningKeys(ctx, internalProviderType, "", namespace, signingKeys)
+}
+
 func (s *GCSStorage) UploadMirroredSigningKeys(ctx context.Context, hostname, namespace string, signingKeys *core.SigningKeys) error {
 	return s.uploadSigningKeys(ctx, mirrorProviderType, hostname, namespace, signingKeys)
 }
@@ -328,6 +591,61 @@ func (s *GCSStorage) MirroredSha256Sum(ctx context.Context, provider *core.Provi
 	return core.NewSha256Sums(provider.ShasumFileName(), bytes.NewReader(shaSumBytes))
 }
 
+func (s *GCSStorage) ListMirroredFiles(ctx context.Context) ([]mirror.MirroredFile, error) {
+	query := &storage.Query{
+		Prefix: mirrorStoragePrefix(s.bucketPrefix),
+	}
+
+	it := s.sc.Bucket(s.bucket).Objects(ctx, query)
+
+	var files []mirror.MirroredFile
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		f, ok := mirroredFileFromKey(s.bucketPrefix, attrs.Name)
+		if !ok {
+			continue
+		}
+		f.Size = attrs.Size
+		f.LastModified = attrs.Updated
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+func (s *GCSStorage) DownloadMirroredFile(ctx context.Context, provider *core.Provider, fileName string) (io.ReadCloser, error) {
+	prefix := providerStoragePrefix(s.bucketPrefix, mirrorProviderType, provider.Hostname, provider.Namespace, provider.Name)
+	key := path.Join(prefix, fileName)
+	r, err := s.sc.Bucket(s.bucket).Object(key).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, core.ErrObjectNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return r, nil
+}
+
+func (s *GCSStorage) UpdateMirroredFile(ctx context.Context, provider *core.Provider, fileName string, update func([]byte) ([]byte, error)) error {
+	prefix := providerStoragePrefix(s.bucketPrefix, mirrorProviderType, provider.Hostname, provider.Namespace, provider.Name)
+	return updateObject(ctx, path.Join(prefix, fileName), s.readVersioned, s.writeConditional, update)
+}
+
+func (s *GCSStorage) DeleteMirroredFile(ctx context.Context, provider *core.Provider, fileName string) error {
+	prefix := providerStoragePrefix(s.bucketPrefix, mirrorProviderType, provider.Hostname, provider.Namespace, provider.Name)
+	key := path.Join(prefix, fileName)
+	if err := s.sc.Bucket(s.bucket).Object(key).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
 func (s *GCSStorage) upload(ctx context.Context, key string, reader io.Reader, overwrite bool) error {
 	if !overwrite {
 		exists, err := s.objectExists(ctx, key)
@@ -366,13 +684,183 @@ func (s *GCSStorage) download(ctx context.Context, key string) ([]byte, error) {
 	return data, nil
 }
 
+// readVersioned downloads the object at key together with its generation, or returns core.ErrObjectNotFound
+func (s *GCSStorage) readVersioned(ctx context.Context, key string) ([]byte, string, error) {
+	r, err := s.sc.Bucket(s.bucket).Object(key).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, "", core.ErrObjectNotFound
+	} else if err != nil {
+		return nil, "", fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return b, strconv.FormatInt(r.Attrs.Generation, 10), nil
+}
+
+// writeConditional uploads the object at key, if its generation still matches generation, or if it doesn't exist in case generation is empty.
+// It returns core.ErrObjectModified otherwise.
+func (s *GCSStorage) writeConditional(ctx context.Context, key string, b []byte, generation string) error {
+	conditions := storage.Conditions{DoesNotExist: true}
+	if generation != "" {
+		g, err := strconv.ParseInt(generation, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid generation %s of %s: %w", generation, key, err)
+		}
+		conditions = storage.Conditions{GenerationMatch: g}
+	}
+
+	wc := s.sc.Bucket(s.bucket).Object(key).If(conditions).NewWriter(ctx)
+	if _, err := wc.Write(b); err != nil {
+		_ = wc.Close()
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	if err := wc.Close(); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return fmt.Errorf("failed to upload %s: %w", key, core.ErrObjectModified)
+		}
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return nil
+}
+
+// listKeys returns the names of all objects with the prefix
+func (s *GCSStorage) listKeys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	it := s.sc.Bucket(s.bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, attrs.Name)
+	}
+
+	return keys, nil
+}
+
+func (s *GCSStorage) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	it := s.sc.Bucket(s.bucket).Objects(ctx, &storage.Query{Prefix: objectListPrefix(s.bucketPrefix, prefix)})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, gcsObject(s.bucketPrefix, attrs))
+	}
+
+	return objects, nil
+}
+
+func (s *GCSStorage) StatObject(ctx context.Context, key string) (Object, error) {
+	fullKey := objectKey(s.bucketPrefix, key)
+	attrs, err := s.sc.Bucket(s.bucket).Object(fullKey).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return Object{}, core.ErrObjectNotFound
+	} else if err != nil {
+		return Object{}, fmt.Errorf("failed to stat %s: %w", fullKey, err)
+	}
+	return gcsObject(s.bucketPrefix, attrs), nil
+}
+
+func (s *GCSStorage) ReadObject(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	fullKey := objectKey(s.bucketPrefix, key)
+	attrs, err := s.sc.Bucket(s.bucket).Object(fullKey).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, Object{}, core.ErrObjectNotFound
+	} else if err != nil {
+		return nil, Object{}, fmt.Errorf("failed to download %s: %w", fullKey, err)
+	}
+
+	// The generation is pinned, so that the content matches the attributes even if the object is overwritten in between
+	r, err := s.sc.Bucket(s.bucket).Object(fullKey).Generation(attrs.Generation).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, Object{}, core.ErrObjectNotFound
+	} else if err != nil {
+		return nil, Object{}, fmt.Errorf("failed to download %s: %w", fullKey, err)
+	}
+	return r, gcsObject(s.bucketPrefix, attrs), nil
+}
+
+// gcsObject returns the Object with the attributes of a GCS object
+func gcsObject(prefix string, attrs *storage.ObjectAttrs) Object {
+	o := Object{
+		Key:          relativeObjectKey(prefix, attrs.Name),
+		Size:         attrs.Size,
+		ETag:         attrs.Etag,
+		LastModified: attrs.Updated,
+	}
+	// Composite objects don't have an MD5 checksum
+	if len(attrs.MD5) > 0 {
+		o.MD5 = hex.EncodeToString(attrs.MD5)
+	}
+	return o
+}
+
+func (s *GCSStorage) WriteObject(ctx context.Context, key string, r io.Reader) error {
+	return s.upload(ctx, objectKey(s.bucketPrefix, key), r, true)
+}
+
+func (s *GCSStorage) UpdateObject(ctx context.Context, key string, update func([]byte) ([]byte, error)) error {
+	return updateObject(ctx, objectKey(s.bucketPrefix, key), s.readVersioned, s.writeConditional, update)
+}
+
+func (s *GCSStorage) DeleteObject(ctx context.Context, key string) error {
+	key = objectKey(s.bucketPrefix, key)
+	if err := s.sc.Bucket(s.bucket).Object(key).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// CheckReachable lists at most one object below the prefix to verify that the bucket is reachable
+func (s *GCSStorage) CheckReachable(ctx context.Context) error {
+	it := s.sc.Bucket(s.bucket).Objects(ctx, &storage.Query{Prefix: s.bucketPrefix})
+	it.PageInfo().MaxSize = 1
+	if _, err := it.Next(); err != nil && !errors.Is(err, iterator.Done) {
+		return err
+	}
+	return nil
+}
+
+// CheckPresign signs a download URL to verify that the credentials and the service account can sign URLs
+func (s *GCSStorage) CheckPresign(ctx context.Context) error {
+	// The storage backend signs the URL, bypassing the cache and the CDN, so that its credentials are verified
+	_, _, err := s.presignedURL(ctx, path.Join(s.bucketPrefix, healthCheckObject), time.Now().Add(s.signedURLExpiry))
+	return err
+}
+
+// downloadURL returns the download URL of the object at key, which is signed for the CDN or the storage backend
+func (s *GCSStorage) downloadURL(ctx context.Context, key string) (string, error) {
+	return signDownloadURL(ctx, key, s.signedURLExpiry, s.presignCache, s.cdn, s.presignedURL)
+}
+
+func (s *GCSStorage) observePresign(observer func(time.Duration)) {
+	s.presignObserver = observer
+}
+
 // https://github.com/GoogleCloudPlatform/golang-samples/blob/73d60a5de091dcdda5e4f753b594ef18eee67906/storage/objects/generate_v4_get_object_signed_url.go#L28
 // presignedURL generates object signed URL with GET method.
-func (s *GCSStorage) presignedURL(ctx context.Context, object string) (string, error) {
+func (s *GCSStorage) presignedURL(ctx context.Context, object string, expires time.Time) (_ string, _ time.Time, err error) {
+	ctx, span := startPresignSpan(ctx, backendGCS, object)
+	defer func() { o11y.EndSpan(span, err) }()
+	defer observePresign(s.presignObserver, time.Now())
+
 	//https://godoc.org/golang.org/x/oauth2/google#DefaultClient
 	cred, err := google.FindDefaultCredentials(ctx, "cloud-platform")
 	if err != nil {
-		return "", fmt.Errorf("google.FindDefaultCredentials: %v", err)
+		return "", time.Time{}, fmt.Errorf("google.FindDefaultCredentials: %v", err)
 	}
 
 	var url string
@@ -380,14 +868,14 @@ func (s *GCSStorage) presignedURL(ctx context.Context, object string) (string, e
 		// needs Service Account Token Creator role
 		c, err := credentials.NewIamCredentialsClient(ctx)
 		if err != nil {
-			return "", fmt.Errorf("credentials.NewIamCredentialsClient: %v", err)
+			return "", time.Time{}, fmt.Errorf("credentials.NewIamCredentialsClient: %v", err)
 		}
 
 		url, err = storage.SignedURL(s.bucket, object, &storage.SignedURLOptions{
 			Scheme:         storage.SigningSchemeV4,
 			Method:         "GET",
 			GoogleAccessID: s.serviceAccount,
-			Expires:        time.Now().Add(s.signedURLExpiry),
+			Expires:        expires,
 			SignBytes: func(b []byte) ([]byte, error) {
 				req := &credentialspb.SignBlobRequest{
 					Payload: b,
@@ -401,27 +889,28 @@ func (s *GCSStorage) presignedURL(ctx context.Context, object string) (string, e
 			},
 		})
 		if err != nil {
-			return "", fmt.Errorf("storage.signedURL: %v", err)
+			return "", time.Time{}, fmt.Errorf("storage.signedURL: %v", err)
 		}
 	} else {
 		conf, err := google.JWTConfigFromJSON(cred.JSON)
 		if err != nil {
-			return "", fmt.Errorf("could not get jwt config: %w", err)
+			return "", time.Time{}, fmt.Errorf("could not get jwt config: %w", err)
 		}
 		opts := &storage.SignedURLOptions{
 			Scheme:         storage.SigningSchemeV4,
 			Method:         "GET",
 			GoogleAccessID: conf.Email,
 			PrivateKey:     conf.PrivateKey,
-			Expires:        time.Now().Add(s.signedURLExpiry),
+			Expires:        expires,
 		}
 		url, err = storage.SignedURL(s.bucket, object, opts)
 		if err != nil {
-			return "", fmt.Errorf("storage.signedURL: %v", err)
+			return "", time.Time{}, fmt.Errorf("storage.signedURL: %v", err)
 		}
 	}
 
-	return url, nil
+	// The URLs are signed with the key of the service account, so they don't depend on the expiry of the credentials
+	return url, expires, nil
 }
 
 func (s *GCSStorage) objectExists(ctx context.Context, key string) (bool, error) {
@@ -456,6 +945,23 @@ func WithGCSServiceAccount(sa string) GCSStorageOption {
 	}
 }
 
+// WithGCSSignedURLCache configures the reuse of signed download URLs until they're valid for less than minValidity.
+// The URLs aren't reused if minValidity is 0.
+func WithGCSSignedURLCache(minValidity time.Duration) GCSStorageOption {
+	return func(s *GCSStorage) {
+		if minValidity > 0 {
+			s.presignCache = newPresignCache(minValidity)
+		}
+	}
+}
+
+// WithGCSCDN configures the CDN, for which the download URLs are signed instead of the storage backend
+func WithGCSCDN(cdn *CDN) GCSStorageOption {
+	return func(s *GCSStorage) {
+		s.cdn = cdn
+	}
+}
+
 // WithGCSSignedUrlExpiry configures the duration until the signed url expires
 func WithGCSSignedUrlExpiry(t time.Duration) GCSStorageOption {
 	return func(s *GCSStorage) {
@@ -463,10 +969,13 @@ func WithGCSSignedUrlExpiry(t time.Duration) GCSStorageOption {
 	}
 }
 
-// WithGCSArchiveFormat configures the module archive format (zip, tar, tgz, etc.)
+// WithGCSArchiveFormat configures the default module archive format (zip, tar, tgz, etc.).
+// Modules in other formats are served as well.
 func WithGCSArchiveFormat(archiveFormat string) GCSStorageOption {
 	return func(s *GCSStorage) {
-		s.moduleArchiveFormat = archiveFormat
+		if archiveFormat != "" {
+			s.moduleArchiveFormat = archiveFormat
+		}
 	}
 }
 
@@ -477,8 +986,9 @@ func NewGCSStorage(bucket string, options ...GCSStorageOption) (*GCSStorage, err
 		return nil, err
 	}
 	s := &GCSStorage{
-		sc:     client,
-		bucket: bucket,
+		sc:                  client,
+		bucket:              bucket,
+		moduleArchiveFormat: DefaultModuleArchiveFormat,
 	}
 
 	for _, option := range options {
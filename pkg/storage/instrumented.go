@@ -0,0 +1,442 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/mirror"
+	"github.com/boring-registry/boring-registry/pkg/module"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+	"github.com/boring-registry/boring-registry/pkg/provider"
+
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	ErrorClassNotFound = "not_found"
+	ErrorClassConflict = "conflict"
+	ErrorClassAuth     = "auth"
+	ErrorClassTimeout  = "timeout"
+	ErrorClassOther    = "other"
+)
+
+// presignObservable is implemented by the storage backends, which report how long signing a download URL took
+type presignObservable interface {
+	observePresign(observer func(time.Duration))
+}
+
+// observePresign reports the duration since begin to the observer, if there is one
+func observePresign(observer func(time.Duration), begin time.Time) {
+	if observer != nil {
+		observer(time.Since(begin))
+	}
+}
+
+type instrumentedStorage struct {
+	next    Storage
+	backend string
+	metrics *o11y.StorageMetrics
+}
+
+// NewInstrumentedStorage returns a Storage, which records the latency, the errors and the uploaded bytes of each operation of the next Storage.
+// The durations of signing download URLs are recorded as well, if the next Storage is one of the storage backends.
+func NewInstrumentedStorage(next Storage, metrics *o11y.StorageMetrics) Storage {
+	s := &instrumentedStorage{
+		next:    next,
+		backend: storageBackend(next),
+		metrics: metrics,
+	}
+
+	if p, ok := next.(presignObservable); ok {
+		p.observePresign(func(d time.Duration) {
+			metrics.PresignDuration.With(prometheus.Labels{o11y.BackendLabel: s.backend}).Observe(d.Seconds())
+		})
+	}
+
+	return s
+}
+
+func storageBackend(s Storage) string {
+	switch s.(type) {
+	case *S3Storage:
+		return backendS3
+	case *GCSStorage:
+		return backendGCS
+	case *AzureStorage:
+		return backendAzure
+	default:
+		return "unknown"
+	}
+}
+
+// ErrorClass classifies errors of the storage backends, so that they can be told apart in metrics
+func ErrorClass(err error) string {
+	var (
+		netErr     net.Error
+		statusCode int
+	)
+	switch {
+	case errors.Is(err, core.ErrObjectNotFound), errors.Is(err, module.ErrModuleNotFound), errors.Is(err, provider.ErrProviderNotFound),
+		errors.Is(err, module.ErrModuleDetailsNotFound), errors.Is(err, module.ErrModuleDocsNotFound),
+		errors.Is(err, module.ErrModuleDependenciesNotFound), errors.Is(err, storage.ErrObjectNotExist):
+		return ErrorClassNotFound
+	case errors.Is(err, core.ErrObjectAlreadyExists), errors.Is(err, module.ErrModuleAlreadyExists):
+		return ErrorClassConflict
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	}
+
+	var (
+		awsErr    interface{ HTTPStatusCode() int }
+		azureErr  *azcore.ResponseError
+		googleErr *googleapi.Error
+	)
+	switch {
+	case errors.As(err, &awsErr):
+		statusCode = awsErr.HTTPStatusCode()
+	case errors.As(err, &azureErr):
+		statusCode = azureErr.StatusCode
+	case errors.As(err, &googleErr):
+		statusCode = googleErr.Code
+	}
+
+	switch statusCode {
+	case http.StatusNotFound:
+		return ErrorClassNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ErrorClassConflict
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrorClassAuth
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return ErrorClassTimeout
+	default:
+		return ErrorClassOther
+	}
+}
+
+func (s *instrumentedStorage) observe(operation string, begin time.Time, err error) {
+	s.metrics.OperationDuration.With(prometheus.Labels{
+		o11y.BackendLabel:   s.backend,
+		o11y.OperationLabel: operation,
+	}).Observe(time.Since(begin).Seconds())
+
+	if err != nil {
+		s.metrics.Errors.With(prometheus.Labels{
+			o11y.BackendLabel:    s.backend,
+			o11y.OperationLabel:  operation,
+			o11y.ErrorClassLabel: ErrorClass(err),
+		}).Inc()
+	}
+}
+
+func (s *instrumentedStorage) observeUpload(operation string, reader *countingReader, err error) {
+	if err != nil {
+		return
+	}
+	s.metrics.UploadedBytes.With(prometheus.Labels{
+		o11y.BackendLabel:   s.backend,
+		o11y.OperationLabel: operation,
+	}).Add(float64(reader.n))
+}
+
+// countingReader counts the bytes that are read from the underlying reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (s *instrumentedStorage) GetModule(ctx context.Context, namespace, name, provider, version string) (m core.Module, err error) {
+	defer func(begin time.Time) { s.observe("GetModule", begin, err) }(time.Now())
+	return s.next.GetModule(ctx, namespace, name, provider, version)
+}
+
+func (s *instrumentedStorage) ListModuleVersions(ctx context.Context, namespace, name, provider string) (modules []core.Module, err error) {
+	defer func(begin time.Time) { s.observe("ListModuleVersions", begin, err) }(time.Now())
+	return s.next.ListModuleVersions(ctx, namespace, name, provider)
+}
+
+func (s *instrumentedStorage) ListModules(ctx context.Context) (modules []core.Module, err error) {
+	defer func(begin time.Time) { s.observe("ListModules", begin, err) }(time.Now())
+	return s.next.ListModules(ctx)
+}
+
+func (s *instrumentedStorage) UploadModule(ctx context.Context, namespace, name, provider, version, format string, body io.Reader) (m core.Module, err error) {
+	reader := &countingReader{r: body}
+	defer func(begin time.Time) {
+		s.observe("UploadModule", begin, err)
+		s.observeUpload("UploadModule", reader, err)
+	}(time.Now())
+	return s.next.UploadModule(ctx, namespace, name, provider, version, format, reader)
+}
+
+func (s *instrumentedStorage) UploadModuleSource(ctx context.Context, namespace, name, provider, version, source string) (m core.Module, err error) {
+	defer func(begin time.Time) { s.observe("UploadModuleSource", begin, err) }(time.Now())
+	return s.next.UploadModuleSource(ctx, namespace, name, provider, version, source)
+}
+
+func (s *instrumentedStorage) DeleteModuleSource(ctx context.Context, namespace, name, provider, version string) (err error) {
+	defer func(begin time.Time) { s.observe("DeleteModuleSource", begin, err) }(time.Now())
+	return s.next.DeleteModuleSource(ctx, namespace, name, provider, version)
+}
+
+func (s *instrumentedStorage) GetModuleDetails(ctx context.Context, namespace, name, provider, version string) (details *module.Details, err error) {
+	defer func(begin time.Time) { s.observe("GetModuleDetails", begin, err) }(time.Now())
+	return s.next.GetModuleDetails(ctx, namespace, name, provider, version)
+}
+
+func (s *instrumentedStorage) UploadModuleDetails(ctx context.Context, namespace, name, provider, version string, details *module.Details) (err error) {
+	defer func(begin time.Time) { s.observe("UploadModuleDetails", begin, err) }(time.Now())
+	return s.next.UploadModuleDetails(ctx, namespace, name, provider, version, details)
+}
+
+func (s *instrumentedStorage) GetModuleDocs(ctx context.Context, namespace, name, provider, version string) (docs *module.Docs, err error) {
+	defer func(begin time.Time) { s.observe("GetModuleDocs", begin, err) }(time.Now())
+	return s.next.GetModuleDocs(ctx, namespace, name, provider, version)
+}
+
+func (s *instrumentedStorage) UploadModuleDocs(ctx context.Context, namespace, name, provider, version string, docs *module.Docs) (err error) {
+	defer func(begin time.Time) { s.observe("UploadModuleDocs", begin, err) }(time.Now())
+	return s.next.UploadModuleDocs(ctx, namespace, name, provider, version, docs)
+}
+
+func (s *instrumentedStorage) UploadModuleDependencies(ctx context.Context, namespace, name, provider, version string, dependencies []module.ModuleDependency) (err error) {
+	defer func(begin time.Time) { s.observe("UploadModuleDependencies", begin, err) }(time.Now())
+	return s.next.UploadModuleDependencies(ctx, namespace, name, provider, version, dependencies)
+}
+
+func (s *instrumentedStorage) GetModuleDependencies(ctx context.Context, namespace, name, provider, version string) (dependencies []module.ModuleDependency, err error) {
+	defer func(begin time.Time) { s.observe("GetModuleDependencies", begin, err) }(time.Now())
+	return s.next.GetModuleDependencies(ctx, namespace, name, provider, version)
+}
+
+func (s *instrumentedStorage) ListModuleDependents(ctx context.Context, namespace, name, provider string) (dependents []module.ModuleDependent, err error) {
+	defer func(begin time.Time) { s.observe("ListModuleDependents", begin, err) }(time.Now())
+	return s.next.ListModuleDependents(ctx, namespace, name, provider)
+}
+
+func (s *instrumentedStorage) GetModuleAnnotations(ctx context.Context, namespace, name, provider string) (annotations core.VersionAnnotations, err error) {
+	defer func(begin time.Time) { s.observe("GetModuleAnnotations", begin, err) }(time.Now())
+	return s.next.GetModuleAnnotations(ctx, namespace, name, provider)
+}
+
+func (s *instrumentedStorage) UpdateModuleAnnotations(ctx context.Context, namespace, name, provider string, update func(core.VersionAnnotations)) (err error) {
+	defer func(begin time.Time) { s.observe("UpdateModuleAnnotations", begin, err) }(time.Now())
+	return s.next.UpdateModuleAnnotations(ctx, namespace, name, provider, update)
+}
+
+func (s *instrumentedStorage) GetModuleDownloadStats(ctx context.Context, namespace, name, provider string) (stats core.DownloadStats, err error) {
+	defer func(begin time.Time) { s.observe("GetModuleDownloadStats", begin, err) }(time.Now())
+	return s.next.GetModuleDownloadStats(ctx, namespace, name, provider)
+}
+
+func (s *instrumentedStorage) MergeModuleDownloadStats(ctx context.Context, namespace, name, provider string, stats core.DownloadStats) (err error) {
+	defer func(begin time.Time) { s.observe("MergeModuleDownloadStats", begin, err) }(time.Now())
+	return s.next.MergeModuleDownloadStats(ctx, namespace, name, provider, stats)
+}
+
+func (s *instrumentedStorage) GetProvider(ctx context.Context, namespace, name, version, os, arch string) (p *core.Provider, err error) {
+	defer func(begin time.Time) { s.observe("GetProvider", begin, err) }(time.Now())
+	return s.next.GetProvider(ctx, namespace, name, version, os, arch)
+}
+
+func (s *instrumentedStorage) ListProviderVersions(ctx context.Context, namespace, name string) (versions *core.ProviderVersions, err error) {
+	defer func(begin time.Time) { s.observe("ListProviderVersions", begin, err) }(time.Now())
+	return s.next.ListProviderVersions(ctx, namespace, name)
+}
+
+func (s *instrumentedStorage) ListProviders(ctx context.Context) (providers []core.Provider, err error) {
+	defer func(begin time.Time) { s.observe("ListProviders", begin, err) }(time.Now())
+	return s.next.ListProviders(ctx)
+}
+
+func (s *instrumentedStorage) UploadProviderReleaseFiles(ctx context.Context, namespace, name, filename string, file io.Reader) (err error) {
+	reader := &countingReader{r: file}
+	defer func(begin time.Time) {
+		s.observe("UploadProviderReleaseFiles", begin, err)
+		s.observeUpload("UploadProviderReleaseFiles", reader, err)
+	}(time.Now())
+	return s.next.UploadProviderReleaseFiles(ctx, namespace, name, filename, reader)
+}
+
+func (s *instrumentedStorage) SigningKeys(ctx context.Context, namespace string) (keys *core.SigningKeys, err error) {
+	defer func(begin time.Time) { s.observe("SigningKeys", begin, err) }(time.Now())
+	return s.next.SigningKeys(ctx, namespace)
+}
+
+func (s *instrumentedStorage) GetProviderAnnotations(ctx context.Context, namespace, name string) (annotations core.VersionAnnotations, err error) {
+	defer func(begin time.Time) { s.observe("GetProviderAnnotations", begin, err) }(time.Now())
+	return s.next.GetProviderAnnotations(ctx, namespace, name)
+}
+
+func (s *instrumentedStorage) UpdateProviderAnnotations(ctx context.Context, namespace, name string, update func(core.VersionAnnotations)) (err error) {
+	defer func(begin time.Time) { s.observe("UpdateProviderAnnotations", begin, err) }(time.Now())
+	return s.next.UpdateProviderAnnotations(ctx, namespace, name, update)
+}
+
+func (s *instrumentedStorage) GetProviderDownloadStats(ctx context.Context, namespace, name string) (stats core.DownloadStats, err error) {
+	defer func(begin time.Time) { s.observe("GetProviderDownloadStats", begin, err) }(time.Now())
+	return s.next.GetProviderDownloadStats(ctx, namespace, name)
+}
+
+func (s *instrumentedStorage) MergeProviderDownloadStats(ctx context.Context, namespace, name string, stats core.DownloadStats) (err error) {
+	defer func(begin time.Time) { s.observe("MergeProviderDownloadStats", begin, err) }(time.Now())
+	return s.next.MergeProviderDownloadStats(ctx, namespace, name, stats)
+}
+
+func (s *instrumentedStorage) ListMirroredProviders(ctx context.Context, provider *core.Provider) (providers []*core.Provider, err error) {
+	defer func(begin time.Time) { s.observe("ListMirroredProviders", begin, err) }(time.Now())
+	return s.next.ListMirroredProviders(ctx, provider)
+}
+
+func (s *instrumentedStorage) GetMirroredProvider(ctx context.Context, provider *core.Provider) (p *core.Provider, err error) {
+	defer func(begin time.Time) { s.observe("GetMirroredProvider", begin, err) }(time.Now())
+	return s.next.GetMirroredProvider(ctx, provider)
+}
+
+func (s *instrumentedStorage) UploadMirroredFile(ctx context.Context, provider *core.Provider, fileName string, r io.Reader) (err error) {
+	reader := &countingReader{r: r}
+	defer func(begin time.Time) {
+		s.observe("UploadMirroredFile", begin, err)
+		s.observeUpload("UploadMirroredFile", reader, err)
+	}(time.Now())
+	return s.next.UploadMirroredFile(ctx, provider, fileName, reader)
+}
+
+func (s *instrumentedStorage) UploadSigningKeys(ctx context.Context, namespace string, signingKeys *core.SigningKeys) (err error) {
+	defer func(begin time.Time) { s.observe("UploadSigningKeys", begin, err) }(time.Now())
+	return s.next.UploadSigningKeys(ctx, namespace, signingKeys)
+}
+
+func (s *instrumentedStorage) MirroredSigningKeys(ctx context.Context, hostname, namespace string) (keys *core.SigningKeys, err error) {
+	defer func(begin time.Time) { s.observe("MirroredSigningKeys", begin, err) }(time.Now())
+	return s.next.MirroredSigningKeys(ctx, hostname, namespace)
+}
+
+func (s *instrumentedStorage) UploadMirroredSigningKeys(ctx context.Context, hostname, namespace string, signingKeys *core.SigningKeys) (err error) {
+	defer func(begin time.Time) { s.observe("UploadMirroredSigningKeys", begin, err) }(time.Now())
+	return s.next.UploadMirroredSigningKeys(ctx, hostname, namespace, signingKeys)
+}
+
+func (s *instrumentedStorage) MirroredSha256Sum(ctx context.Context, provider *core.Provider) (sums *core.Sha256Sums, err error) {
+	defer func(begin time.Time) { s.observe("MirroredSha256Sum", begin, err) }(time.Now())
+	return s.next.MirroredSha256Sum(ctx, provider)
+}
+
+func (s *instrumentedStorage) ListMirroredFiles(ctx context.Context) (files []mirror.MirroredFile, err error) {
+	defer func(begin time.Time) { s.observe("ListMirroredFiles", begin, err) }(time.Now())
+	return s.next.ListMirroredFiles(ctx)
+}
+
+func (s *instrumentedStorage) DownloadMirroredFile(ctx context.Context, provider *core.Provider, fileName string) (body io.ReadCloser, err error) {
+	defer func(begin time.Time) { s.observe("DownloadMirroredFile", begin, err) }(time.Now())
+	return s.next.DownloadMirroredFile(ctx, provider, fileName)
+}
+
+func (s *instrumentedStorage) UpdateMirroredFile(ctx context.Context, provider *core.Provider, fileName string, update func([]byte) ([]byte, error)) (err error) {
+	defer func(begin time.Time) { s.observe("UpdateMirroredFile", begin, err) }(time.Now())
+	return s.next.UpdateMirroredFile(ctx, provider, fileName, update)
+}
+
+func (s *instrumentedStorage) DeleteMirroredFile(ctx context.Context, provider *core.Provider, fileName string) (err error) {
+	defer func(begin time.Time) { s.observe("DeleteMirroredFile", begin, err) }(time.Now())
+	return s.next.DeleteMirroredFile(ctx, provider, fileName)
+}
+
+func (s *instrumentedStorage) GetDownloadUrl(ctx context.Context, url string) (u string, err error) {
+	defer func(begin time.Time) { s.observe("GetDownloadUrl", begin, err) }(time.Now())
+	return s.next.GetDownloadUrl(ctx, url)
+}
+
+// CheckReachable forwards the health check to the next Storage.
+// Storage without health checks is considered to be reachable.
+func (s *instrumentedStorage) CheckReachable(ctx context.Context) (err error) {
+	checker, ok := s.next.(HealthChecker)
+	if !ok {
+		return nil
+	}
+	defer func(begin time.Time) { s.observe("CheckReachable", begin, err) }(time.Now())
+	return checker.CheckReachable(ctx)
+}
+
+// CheckPresign forwards the health check to the next Storage.
+// Storage without health checks is considered to be able to sign URLs.
+func (s *instrumentedStorage) CheckPresign(ctx context.Context) (err error) {
+	checker, ok := s.next.(HealthChecker)
+	if !ok {
+		return nil
+	}
+	defer func(begin time.Time) { s.observe("CheckPresign", begin, err) }(time.Now())
+	return checker.CheckPresign(ctx)
+}
+
+func (s *instrumentedStorage) ListObjects(ctx context.Context, prefix string) (objects []Object, err error) {
+	o, ok := s.next.(ObjectStorage)
+	if !ok {
+		return nil, errObjectStorageUnsupported
+	}
+	defer func(begin time.Time) { s.observe("ListObjects", begin, err) }(time.Now())
+	return o.ListObjects(ctx, prefix)
+}
+
+func (s *instrumentedStorage) StatObject(ctx context.Context, key string) (object Object, err error) {
+	o, ok := s.next.(ObjectStorage)
+	if !ok {
+		return Object{}, errObjectStorageUnsupported
+	}
+	defer func(begin time.Time) { s.observe("StatObject", begin, err) }(time.Now())
+	return o.StatObject(ctx, key)
+}
+
+func (s *instrumentedStorage) ReadObject(ctx context.Context, key string) (body io.ReadCloser, object Object, err error) {
+	o, ok := s.next.(ObjectStorage)
+	if !ok {
+		return nil, Object{}, errObjectStorageUnsupported
+	}
+	defer func(begin time.Time) { s.observe("ReadObject", begin, err) }(time.Now())
+	return o.ReadObject(ctx, key)
+}
+
+func (s *instrumentedStorage) WriteObject(ctx context.Context, key string, r io.Reader) (err error) {
+	o, ok := s.next.(ObjectStorage)
+	if !ok {
+		return errObjectStorageUnsupported
+	}
+	reader := &countingReader{r: r}
+	defer func(begin time.Time) {
+		s.observe("WriteObject", begin, err)
+		s.observeUpload("WriteObject", reader, err)
+	}(time.Now())
+	return o.WriteObject(ctx, key, reader)
+}
+
+func (s *instrumentedStorage) UpdateObject(ctx context.Context, key string, update func([]byte) ([]byte, error)) (err error) {
+	o, ok := s.next.(ObjectStorage)
+	if !ok {
+		return errObjectStorageUnsupported
+	}
+	defer func(begin time.Time) { s.observe("UpdateObject", begin, err) }(time.Now())
+	return o.UpdateObject(ctx, key, update)
+}
+
+func (s *instrumentedStorage) DeleteObject(ctx context.Context, key string) (err error) {
+	o, ok := s.next.(ObjectStorage)
+	if !ok {
+		return errObjectStorageUnsupported
+	}
+	defer func(begin time.Time) { s.observe("DeleteObject", begin, err) }(time.Now())
+	return o.DeleteObject(ctx, key)
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+)
+
+// storageMetrics can only be registered once
+var storageMetrics = o11y.NewStorageMetrics()
+
+type fakeStorage struct {
+	Storage
+	getModule                  func() (core.Module, error)
+	uploadProviderReleaseFiles func(io.Reader) error
+}
+
+func (f *fakeStorage) GetModule(ctx context.Context, namespace, name, provider, version string) (core.Module, error) {
+	return f.getModule()
+}
+
+func (f *fakeStorage) UploadProviderReleaseFiles(ctx context.Context, namespace, name, filename string, file io.Reader) error {
+	return f.uploadProviderReleaseFiles(file)
+}
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	assert.NoError(t, c.Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func histogramCount(t *testing.T, o prometheus.Observer) uint64 {
+	m := &dto.Metric{}
+	assert.NoError(t, o.(prometheus.Metric).Write(m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestInstrumentedStorage(t *testing.T) {
+	labels := func(operation string) prometheus.Labels {
+		return prometheus.Labels{o11y.BackendLabel: "unknown", o11y.OperationLabel: operation}
+	}
+	s := NewInstrumentedStorage(&fakeStorage{
+		getModule: func() (core.Module, error) {
+			return core.Module{}, fmt.Errorf("failed to get module: %w", module.ErrModuleNotFound)
+		},
+		uploadProviderReleaseFiles: func(r io.Reader) error {
+			_, err := io.ReadAll(r)
+			return err
+		},
+	}, storageMetrics)
+
+	_, err := s.GetModule(context.Background(), "example", "vpc", "aws", "1.0.0")
+	assert.ErrorIs(t, err, module.ErrModuleNotFound)
+	assert.Equal(t, uint64(1), histogramCount(t, storageMetrics.OperationDuration.With(labels("GetModule"))))
+	assert.Equal(t, float64(1), counterValue(t, storageMetrics.Errors.With(prometheus.Labels{
+		o11y.BackendLabel:    "unknown",
+		o11y.OperationLabel:  "GetModule",
+		o11y.ErrorClassLabel: ErrorClassNotFound,
+	})))
+
+	err = s.UploadProviderReleaseFiles(context.Background(), "hashicorp", "random", "terraform-provider-random_2.0.0_SHA256SUMS", strings.NewReader("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(5), counterValue(t, storageMetrics.UploadedBytes.With(labels("UploadProviderReleaseFiles"))))
+}
+
+func TestInstrumentedStorage_presign(t *testing.T) {
+	client := s3.New(s3.Options{
+		Region:      "eu-central-1",
+		Credentials: credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+	backend := &S3Storage{
+		client:        client,
+		presignClient: s3.NewPresignClient(client),
+		bucket:        "registry",
+	}
+	NewInstrumentedStorage(backend, storageMetrics)
+
+	_, _, err := backend.presignedURL(context.Background(), "modules/example/vpc/aws/example-vpc-aws-1.0.0.tar.gz", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), histogramCount(t, storageMetrics.PresignDuration.With(prometheus.Labels{o11y.BackendLabel: backendS3})))
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "object not found",
+			err:  fmt.Errorf("failed to download: %w", core.ErrObjectNotFound),
+			want: ErrorClassNotFound,
+		},
+		{
+			name: "module already exists",
+			err:  fmt.Errorf("%w: modules/example/vpc/aws/example-vpc-aws-1.0.0.tar.gz", module.ErrModuleAlreadyExists),
+			want: ErrorClassConflict,
+		},
+		{
+			name: "S3 access denied",
+			err: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusForbidden}},
+				Err:      errors.New("AccessDenied"),
+			},
+			want: ErrorClassAuth,
+		},
+		{
+			name: "Azure blob not found",
+			err:  &azcore.ResponseError{StatusCode: http.StatusNotFound, ErrorCode: "BlobNotFound"},
+			want: ErrorClassNotFound,
+		},
+		{
+			name: "GCS precondition failed",
+			err:  fmt.Errorf("failed to upload: %w", &googleapi.Error{Code: http.StatusPreconditionFailed}),
+			want: ErrorClassConflict,
+		},
+		{
+			name: "deadline exceeded",
+			err:  fmt.Errorf("failed to list: %w", context.DeadlineExceeded),
+			want: ErrorClassTimeout,
+		},
+		{
+			name: "other",
+			err:  errors.New("failed to page next page"),
+			want: ErrorClassOther,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ErrorClass(tc.err))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// errObjectStorageUnsupported is returned by the wrappers of a Storage, whose next Storage doesn't implement ObjectStorage
+var errObjectStorageUnsupported = errors.New("the storage backend doesn't support accessing its objects")
+
+// Object is an object of the registry.
+// Its key is relative to the prefix of the storage backend, so that the same object has the same key in every backend.
+type Object struct {
+	Key  string
+	Size int64
+	// ETag changes whenever the content of the object changes.
+	// It can only be compared with the ETags of the same storage backend.
+	ETag         string
+	LastModified time.Time
+	// MD5 is the hex-encoded MD5 checksum of the content, if the storage backend reports it
+	MD5 string
+}
+
+// ObjectStorage is implemented by the storage backends, whose objects can be copied as they are to another storage backend.
+// All keys are relative to the prefix of the storage backend.
+type ObjectStorage interface {
+	// ListObjects returns all objects whose keys start with the prefix
+	ListObjects(ctx context.Context, prefix string) ([]Object, error)
+
+	// StatObject returns the object without its content, or core.ErrObjectNotFound if it doesn't exist
+	StatObject(ctx context.Context, key string) (Object, error)
+
+	// ReadObject returns the content of the object together with the object that is read,
+	// or core.ErrObjectNotFound if it doesn't exist
+	ReadObject(ctx context.Context, key string) (io.ReadCloser, Object, error)
+
+	// WriteObject creates or overwrites the object
+	WriteObject(ctx context.Context, key string, r io.Reader) error
+
+	// UpdateObject applies update to the object with optimistic concurrency.
+	// The update receives nil, if the object doesn't exist yet, and is called again if the object is modified concurrently.
+	UpdateObject(ctx context.Context, key string, update func([]byte) ([]byte, error)) error
+
+	// DeleteObject deletes the object
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// objectKey returns the key of the object in the storage backend with the prefix
+func objectKey(prefix, key string) string {
+	return path.Join(prefix, key)
+}
+
+// relativeObjectKey returns the key relative to the prefix of the storage backend
+func relativeObjectKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// objectListPrefix returns the prefix with which the objects below the relative prefix are listed.
+// A trailing slash is kept, so that the prefix modules/ doesn't match the object modules.json.
+func objectListPrefix(prefix, relative string) string {
+	p := objectKey(prefix, relative)
+	if strings.HasSuffix(relative, "/") || relative == "" && prefix != "" {
+		p += "/"
+	}
+	return p
+}
+
+// etagMD5 returns the MD5 checksum in an ETag, which is the hex-encoded MD5 checksum of the content in quotes,
+// or an empty string if the ETag isn't an MD5 checksum
+func etagMD5(etag string) string {
+	etag = strings.Trim(etag, `"`)
+	if len(etag) != hex.EncodedLen(md5.Size) {
+		return ""
+	}
+	if _, err := hex.DecodeString(etag); err != nil {
+		return ""
+	}
+	return strings.ToLower(etag)
+}
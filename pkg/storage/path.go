@@ -8,12 +8,17 @@ import (
 	"strings"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/mirror"
+	"github.com/boring-registry/boring-registry/pkg/module"
 )
 
 const (
 	internalProviderType = providerType("providers")
 	mirrorProviderType   = providerType("mirror/providers")
 	internalModuleType   = moduleType("modules")
+
+	// moduleDependentsDir is the directory of the index of the module versions that call a module
+	moduleDependentsDir = "dependents"
 )
 
 type providerType string
@@ -63,6 +68,84 @@ func mirrorProviderPath(prefix, hostname, namespace, name, version, os, arch str
 	return providerPath(prefix, mirrorProviderType, hostname, namespace, name, version, os, arch)
 }
 
+// mirrorStoragePrefix returns the <prefix>/mirror/providers/ prefix beneath which all mirrored providers are stored
+func mirrorStoragePrefix(prefix string) string {
+	return fmt.Sprintf("%s/", path.Join(prefix, string(mirrorProviderType)))
+}
+
+// mirroredFileFromKey parses a <prefix>/mirror/providers/<hostname>/<namespace>/<name>/<file> key.
+// It returns false for keys that are not stored beneath a provider, like the signing keys of a namespace.
+func mirroredFileFromKey(prefix, key string) (mirror.MirroredFile, bool) {
+	trimmed := strings.TrimPrefix(key, mirrorStoragePrefix(prefix))
+	if trimmed == key {
+		return mirror.MirroredFile{}, false
+	}
+
+	parts := strings.Split(trimmed, "/")
+	if len(parts) != 4 {
+		return mirror.MirroredFile{}, false
+	}
+	for _, part := range parts {
+		if part == "" {
+			return mirror.MirroredFile{}, false
+		}
+	}
+
+	return mirror.MirroredFile{
+		Hostname:  parts[0],
+		Namespace: parts[1],
+		Name:      parts[2],
+		FileName:  parts[3],
+	}, true
+}
+
+// modulesFromKeys returns the unique modules of the <prefix>/modules/<namespace>/<name>/<provider>/<file> keys.
+// Keys of sidecars like the module details are skipped, so that only modules with at least one version are returned.
+func modulesFromKeys(prefix string, keys []string) []core.Module {
+	seen := map[string]bool{}
+	var modules []core.Module
+	for _, key := range keys {
+		trimmed := strings.TrimPrefix(key, path.Join(prefix, string(internalModuleType))+"/")
+		parts := strings.Split(trimmed, "/")
+		if trimmed == key || len(parts) != 4 {
+			continue
+		}
+		if _, err := moduleVersionFromObject(key); err != nil {
+			continue
+		}
+
+		m := core.Module{Namespace: parts[0], Name: parts[1], Provider: parts[2]}
+		if id := m.ID(false); !seen[id] {
+			seen[id] = true
+			modules = append(modules, m)
+		}
+	}
+	return modules
+}
+
+// providersFromKeys returns the unique providers of the <prefix>/providers/<namespace>/<name>/<file> keys.
+// Keys of other files like the signing keys and the SHA256SUMS are skipped, so that only providers with at least one archive are returned.
+func providersFromKeys(prefix string, keys []string) []core.Provider {
+	seen := map[string]bool{}
+	var providers []core.Provider
+	for _, key := range keys {
+		trimmed := strings.TrimPrefix(key, path.Join(prefix, string(internalProviderType))+"/")
+		parts := strings.Split(trimmed, "/")
+		if trimmed == key || len(parts) != 3 {
+			continue
+		}
+		if _, err := core.NewProviderFromArchive(parts[2]); err != nil {
+			continue
+		}
+
+		if id := parts[0] + "/" + parts[1]; !seen[id] {
+			seen[id] = true
+			providers = append(providers, core.Provider{Namespace: parts[0], Name: parts[1]})
+		}
+	}
+	return providers
+}
+
 // modulePathPrefix returns a <prefix>/modules/<namespace>/<name>/<provider> prefix
 func modulePathPrefix(prefix, namespace, name, provider string) string {
 	return path.Join(prefix, string(internalModuleType), namespace, name, provider)
@@ -73,6 +156,56 @@ func modulePath(prefix, namespace, name, provider, version, archiveFormat string
 	return path.Join(modulePathPrefix(prefix, namespace, name, provider), f)
 }
 
+// moduleArchivePathPrefix returns the path of a module version without the archive format, which is shared by the archives of all formats
+func moduleArchivePathPrefix(prefix, namespace, name, provider, version string) string {
+	return modulePath(prefix, namespace, name, provider, version, "")
+}
+
+// moduleSourcePath returns the path of the object with the source of a module version, which is stored in place of the archive
+func moduleSourcePath(prefix, namespace, name, provider, version string) string {
+	return modulePath(prefix, namespace, name, provider, version, moduleSourceExtension)
+}
+
+// moduleDetailsPath returns the path of the JSON sidecar with the module details, which is stored next to the archive
+func moduleDetailsPath(prefix, namespace, name, provider, version string) string {
+	return modulePath(prefix, namespace, name, provider, version, moduleDetailsExtension)
+}
+
+// moduleDocsPath returns the path of the JSON sidecar with the documentation bundle of a module version
+func moduleDocsPath(prefix, namespace, name, provider, version string) string {
+	return modulePath(prefix, namespace, name, provider, version, moduleDocsExtension)
+}
+
+// moduleDependenciesPath returns the path of the JSON sidecar with the dependencies of a module version
+func moduleDependenciesPath(prefix, namespace, name, provider, version string) string {
+	return modulePath(prefix, namespace, name, provider, version, moduleDependenciesExtension)
+}
+
+// moduleDependentsPath returns the <prefix>/dependents/<namespace>/<name>/<provider>.json path of the index of the module versions that call the module
+func moduleDependentsPath(prefix, namespace, name, provider string) string {
+	return path.Join(prefix, moduleDependentsDir, namespace, name, provider+".json")
+}
+
+// moduleAnnotationsPath returns the path of the JSON object with the annotations of all versions of a module
+func moduleAnnotationsPath(prefix, namespace, name, provider string) string {
+	return path.Join(modulePathPrefix(prefix, namespace, name, provider), annotationsFileName)
+}
+
+// moduleDownloadStatsPath returns the path of the JSON object with the daily downloads of all versions of a module
+func moduleDownloadStatsPath(prefix, namespace, name, provider string) string {
+	return path.Join(modulePathPrefix(prefix, namespace, name, provider), downloadStatsFileName)
+}
+
+// providerDownloadStatsPath returns the path of the JSON object with the daily downloads of all versions of a provider
+func providerDownloadStatsPath(prefix, namespace, name string) string {
+	return path.Join(providerStoragePrefix(prefix, internalProviderType, "", namespace, name), downloadStatsFileName)
+}
+
+// providerAnnotationsPath returns the path of the JSON object with the annotations of all versions of a provider
+func providerAnnotationsPath(prefix, namespace, name string) string {
+	return path.Join(providerStoragePrefix(prefix, internalProviderType, "", namespace, name), annotationsFileName)
+}
+
 func signingKeysPath(prefix string, pt providerType, hostname, namespace string) string {
 	return path.Join(
 		prefix,
@@ -106,6 +239,43 @@ func readSHASums(r io.Reader, name string) (string, error) {
 	return sha, nil
 }
 
+// moduleVersionFromObject parses the key of a module archive in any of the supported archive formats, or of a module source.
+// The archive format is empty for sources.
+func moduleVersionFromObject(key string) (*core.Module, error) {
+	if strings.HasSuffix(key, "."+moduleSourceExtension) {
+		return moduleFromObject(key, moduleSourceExtension)
+	}
+
+	format, ok := module.ArchiveFormatFromName(path.Base(key))
+	if !ok {
+		return nil, fmt.Errorf("module key is invalid: unsupported archive format of %s", path.Base(key))
+	}
+
+	m, err := moduleFromObject(key, format)
+	if err != nil {
+		return nil, err
+	}
+	m.ArchiveFormat = format
+	return m, nil
+}
+
+// uniqueModuleVersions removes duplicate versions, which are stored in several formats or with both an archive and a source.
+// The first module of a version is kept, unless it's a source and an archive follows.
+func uniqueModuleVersions(modules []core.Module) []core.Module {
+	index := map[string]int{}
+	unique := modules[:0]
+	for _, m := range modules {
+		i, ok := index[m.Version]
+		if !ok {
+			index[m.Version] = len(unique)
+			unique = append(unique, m)
+		} else if unique[i].ArchiveFormat == "" && m.ArchiveFormat != "" {
+			unique[i] = m
+		}
+	}
+	return unique
+}
+
 func moduleFromObject(key string, fileExtension string) (*core.Module, error) {
 	dir, file := path.Split(key)
 
@@ -1,10 +1,13 @@
 package storage
 
 import (
+	"context"
 	"strings"
 	"testing"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/mirror"
+	"github.com/boring-registry/boring-registry/pkg/module"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -311,6 +314,12 @@ func TestModuleFromObject(t *testing.T) {
 				Version:   "0.11.0-beta1",
 			},
 		},
+		{
+			annotation:    "module details are not an archive",
+			key:           moduleDetailsPath("/boring-registry", "hashicorp", "consul", "aws", "0.11.0"),
+			fileExtension: "tar.gz",
+			expectedError: true,
+		},
 	}
 
 	for _, tc := range testCase {
@@ -328,3 +337,224 @@ func TestModuleFromObject(t *testing.T) {
 		})
 	}
 }
+
+func TestModuleVersionFromObject(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		annotation    string
+		key           string
+		expectedError bool
+		result        core.Module
+	}{
+		{
+			annotation: "tar.gz archive",
+			key:        "/boring-registry/modules/hashicorp/consul/aws/hashicorp-consul-aws-0.11.0.tar.gz",
+			result:     core.Module{Namespace: "hashicorp", Name: "consul", Provider: "aws", Version: "0.11.0", ArchiveFormat: "tar.gz"},
+		},
+		{
+			annotation: "zip archive next to archives of other formats",
+			key:        "/boring-registry/modules/hashicorp/consul/aws/hashicorp-consul-aws-0.12.0.zip",
+			result:     core.Module{Namespace: "hashicorp", Name: "consul", Provider: "aws", Version: "0.12.0", ArchiveFormat: "zip"},
+		},
+		{
+			annotation: "tar.xz archive of a pre-release",
+			key:        "/modules/hashicorp/consul/aws/hashicorp-consul-aws-0.12.0-rc1.tar.xz",
+			result:     core.Module{Namespace: "hashicorp", Name: "consul", Provider: "aws", Version: "0.12.0-rc1", ArchiveFormat: "tar.xz"},
+		},
+		{
+			annotation: "module source",
+			key:        moduleSourcePath("/boring-registry", "hashicorp", "consul", "aws", "0.13.0"),
+			result:     core.Module{Namespace: "hashicorp", Name: "consul", Provider: "aws", Version: "0.13.0"},
+		},
+		{
+			annotation:    "unsupported archive format",
+			key:           "/modules/hashicorp/consul/aws/hashicorp-consul-aws-0.12.0.rar",
+			expectedError: true,
+		},
+		{
+			annotation:    "module details are not an archive",
+			key:           moduleDetailsPath("/boring-registry", "hashicorp", "consul", "aws", "0.11.0"),
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.annotation, func(t *testing.T) {
+			result, err := moduleVersionFromObject(tc.key)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.result, *result)
+		})
+	}
+}
+
+func TestUniqueModuleVersions(t *testing.T) {
+	t.Parallel()
+
+	modules := []core.Module{
+		{Version: "1.0.0", Source: "git::https://example.com/vpc.git?ref=v1.0.0"},
+		{Version: "1.0.0", ArchiveFormat: "tar.gz"},
+		{Version: "1.1.0", ArchiveFormat: "zip"},
+		{Version: "1.1.0", ArchiveFormat: "tar.gz"},
+		{Version: "1.2.0", Source: "git::https://example.com/vpc.git?ref=v1.2.0"},
+	}
+
+	assert.Equal(t, []core.Module{
+		{Version: "1.0.0", ArchiveFormat: "tar.gz"},
+		{Version: "1.1.0", ArchiveFormat: "zip"},
+		{Version: "1.2.0", Source: "git::https://example.com/vpc.git?ref=v1.2.0"},
+	}, uniqueModuleVersions(modules))
+}
+
+func TestUpdateModuleDependents(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	objects := map[string][]byte{}
+	read := func(ctx context.Context, key string) ([]byte, string, error) {
+		b, ok := objects[key]
+		if !ok {
+			return nil, "", core.ErrObjectNotFound
+		}
+		return b, "", nil
+	}
+	write := func(ctx context.Context, key string, b []byte, version string) error {
+		objects[key] = b
+		return nil
+	}
+	list := func(namespace, name, provider string) []module.ModuleDependent {
+		dependents, err := listModuleDependents(ctx, moduleDependentsPath("prefix", namespace, name, provider), read)
+		assert.NoError(t, err)
+		return dependents
+	}
+
+	dependencies := []module.ModuleDependency{
+		{Hostname: "registry.example.com", Namespace: "networking", Name: "vpc", Provider: "aws", Version: "~> 1.0"},
+		{Hostname: "registry.example.com", Namespace: "networking", Name: "vpc", Provider: "aws", Version: "~> 1.0", Path: "modules/endpoints"},
+		{Namespace: "networking", Name: "vpc", Provider: "aws", Version: ">= 1.0", Path: "modules/peering"},
+		{Hostname: "registry.example.com", Namespace: "networking", Name: "subnet", Provider: "aws", Version: "~> 2.0"},
+	}
+	assert.NoError(t, updateModuleDependents(ctx, "prefix", "acme", "service", "aws", "1.0.0", nil, dependencies, read, write))
+	assert.NoError(t, updateModuleDependents(ctx, "prefix", "acme", "service", "aws", "1.1.0", nil, dependencies[:1], read, write))
+
+	assert.Contains(t, objects, "prefix/dependents/networking/vpc/aws.json")
+	// The calls are listed once per hostname
+	assert.Equal(t, []module.ModuleDependent{
+		{Namespace: "acme", Name: "service", Provider: "aws", Version: "1.1.0", VersionConstraint: "~> 1.0", Hostname: "registry.example.com"},
+		{Namespace: "acme", Name: "service", Provider: "aws", Version: "1.0.0", VersionConstraint: ">= 1.0"},
+		{Namespace: "acme", Name: "service", Provider: "aws", Version: "1.0.0", VersionConstraint: "~> 1.0", Hostname: "registry.example.com"},
+	}, list("networking", "vpc", "aws"))
+	assert.Len(t, list("networking", "subnet", "aws"), 1)
+
+	// The version is republished without calling the subnet module, which replaces its calls
+	assert.NoError(t, updateModuleDependents(ctx, "prefix", "acme", "service", "aws", "1.0.0", dependencies, dependencies[2:3], read, write))
+	assert.Equal(t, []module.ModuleDependent{
+		{Namespace: "acme", Name: "service", Provider: "aws", Version: "1.1.0", VersionConstraint: "~> 1.0", Hostname: "registry.example.com"},
+		{Namespace: "acme", Name: "service", Provider: "aws", Version: "1.0.0", VersionConstraint: ">= 1.0"},
+	}, list("networking", "vpc", "aws"))
+	assert.Empty(t, list("networking", "subnet", "aws"))
+	assert.Empty(t, list("networking", "nat", "aws"))
+}
+
+func TestMirroredFileFromKey(t *testing.T) {
+	t.Parallel()
+
+	testCase := []struct {
+		annotation string
+		prefix     string
+		key        string
+		ok         bool
+		result     mirror.MirroredFile
+	}{
+		{
+			annotation: "archive without prefix",
+			key:        "mirror/providers/registry.terraform.io/hashicorp/random/terraform-provider-random_3.6.2_linux_amd64.zip",
+			ok:         true,
+			result: mirror.MirroredFile{
+				Hostname:  "registry.terraform.io",
+				Namespace: "hashicorp",
+				Name:      "random",
+				FileName:  "terraform-provider-random_3.6.2_linux_amd64.zip",
+			},
+		},
+		{
+			annotation: "SHA256SUMS with prefix",
+			prefix:     "boring-registry",
+			key:        "boring-registry/mirror/providers/registry.terraform.io/hashicorp/random/terraform-provider-random_3.6.2_SHA256SUMS",
+			ok:         true,
+			result: mirror.MirroredFile{
+				Hostname:  "registry.terraform.io",
+				Namespace: "hashicorp",
+				Name:      "random",
+				FileName:  "terraform-provider-random_3.6.2_SHA256SUMS",
+			},
+		},
+		{
+			annotation: "signing keys of a namespace",
+			key:        "mirror/providers/registry.terraform.io/hashicorp/signing-keys.json",
+		},
+		{
+			annotation: "key with different prefix",
+			prefix:     "boring-registry",
+			key:        "mirror/providers/registry.terraform.io/hashicorp/random/terraform-provider-random_3.6.2_SHA256SUMS",
+		},
+		{
+			annotation: "internal provider",
+			key:        "providers/hashicorp/random/terraform-provider-random_3.6.2_linux_amd64.zip",
+		},
+	}
+
+	for _, tc := range testCase {
+		tc := tc
+		t.Run(tc.annotation, func(t *testing.T) {
+			t.Parallel()
+			result, ok := mirroredFileFromKey(tc.prefix, tc.key)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.result, result)
+		})
+	}
+}
+
+func TestModulesFromKeys(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"prefix/modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz",
+		"prefix/modules/acme/vpc/aws/acme-vpc-aws-1.0.0.details.json",
+		"prefix/modules/acme/vpc/aws/acme-vpc-aws-1.1.0.zip",
+		"prefix/modules/acme/vpc/aws/annotations.json",
+		"prefix/modules/acme/subnet/aws/acme-subnet-aws-1.0.0.source.json",
+		"prefix/modules/acme/empty/aws/acme-empty-aws-1.0.0.docs.json",
+		"prefix/modules/acme/acme-invalid-aws-1.0.0.tar.gz",
+	}
+
+	assert.Equal(t, []core.Module{
+		{Namespace: "acme", Name: "vpc", Provider: "aws"},
+		{Namespace: "acme", Name: "subnet", Provider: "aws"},
+	}, modulesFromKeys("prefix", keys))
+}
+
+func TestProvidersFromKeys(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"providers/acme/signing-keys.json",
+		"providers/acme/dummy/terraform-provider-dummy_1.0.0_linux_amd64.zip",
+		"providers/acme/dummy/terraform-provider-dummy_1.0.0_darwin_arm64.zip",
+		"providers/acme/dummy/terraform-provider-dummy_1.0.0_SHA256SUMS",
+		"providers/acme/dummy/terraform-provider-dummy_1.0.0_SHA256SUMS.sig",
+		"providers/acme/dummy/annotations.json",
+		"providers/acme/other/terraform-provider-other_0.1.0_SHA256SUMS",
+		"providers/hashicorp/random/terraform-provider-random_3.6.2_linux_amd64.zip",
+	}
+
+	assert.Equal(t, []core.Provider{
+		{Namespace: "acme", Name: "dummy"},
+		{Namespace: "hashicorp", Name: "random"},
+	}, providersFromKeys("", keys))
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxPresignCacheEntries bounds the memory of the presign cache, as every object of the storage can be downloaded
+const maxPresignCacheEntries = 10000
+
+// presignCache reuses the signed download URLs of objects until they're valid for less than minValidity.
+// A nil presignCache doesn't cache.
+type presignCache struct {
+	minValidity time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedURL
+}
+
+type cachedURL struct {
+	url     string
+	expires time.Time
+}
+
+func newPresignCache(minValidity time.Duration) *presignCache {
+	return &presignCache{
+		minValidity: minValidity,
+		now:         time.Now,
+		entries:     make(map[string]cachedURL),
+	}
+}
+
+// get returns the cached URL of the object at key, if it's still valid for at least minValidity
+func (c *presignCache) get(key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || e.expires.Sub(c.now()) < c.minValidity {
+		return "", false
+	}
+	return e.url, true
+}
+
+// put caches the URL of the object at key, which expires at expires
+func (c *presignCache) put(key, url string, expires time.Time) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if expires.Sub(now) < c.minValidity {
+		// The URL would never be reused
+		return
+	}
+	if len(c.entries) >= maxPresignCacheEntries {
+		for k, e := range c.entries {
+			if e.expires.Sub(now) < c.minValidity {
+				delete(c.entries, k)
+			}
+		}
+		// Start over, if all URLs are still valid, instead of tracking which one was used least recently
+		if len(c.entries) >= maxPresignCacheEntries {
+			c.entries = make(map[string]cachedURL)
+		}
+	}
+	c.entries[key] = cachedURL{url: url, expires: expires}
+}
+
+// presignFunc signs the download URL of the object at key, which is valid until expires.
+// It returns when the URL expires actually, which is earlier if the credentials that signed it expire before, e.g. of an STS session.
+type presignFunc func(ctx context.Context, key string, expires time.Time) (string, time.Time, error)
+
+// signDownloadURL returns the download URL of the object at key, which is valid for expiry.
+// The URL is signed for the CDN instead of the storage backend, if a CDN is configured.
+// It's reused from the cache, as long as it's valid long enough.
+func signDownloadURL(ctx context.Context, key string, expiry time.Duration, cache *presignCache, cdn *CDN, presign presignFunc) (string, error) {
+	if url, ok := cache.get(key); ok {
+		return url, nil
+	}
+
+	// The URL expires after expiry, counted from before it's signed
+	expires := time.Now().Add(expiry)
+	var url string
+	var err error
+	if cdn != nil {
+		url, err = cdn.signedURL(key, expires)
+	} else {
+		url, expires, err = presign(ctx, key, expires)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	cache.put(key, url, expires)
+	return url, nil
+}
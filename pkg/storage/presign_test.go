@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresignCache(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newPresignCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.put("a", "https://a", now.Add(5*time.Minute))
+	// URLs that are valid for less than the minimum validity are never reused
+	c.put("b", "https://b", now.Add(30*time.Second))
+
+	url, ok := c.get("a")
+	assert.True(ok)
+	assert.Equal("https://a", url)
+	_, ok = c.get("b")
+	assert.False(ok)
+
+	// The URL is reused until it's valid for less than the minimum validity
+	now = now.Add(4 * time.Minute)
+	_, ok = c.get("a")
+	assert.True(ok)
+	now = now.Add(time.Second)
+	_, ok = c.get("a")
+	assert.False(ok)
+
+	// Expired URLs are evicted once the cache is full
+	for i := 0; i < maxPresignCacheEntries; i++ {
+		c.put(fmt.Sprintf("key-%d", i), "https://key", now.Add(5*time.Minute))
+	}
+	assert.Len(c.entries, maxPresignCacheEntries)
+	now = now.Add(10 * time.Minute)
+	c.put("c", "https://c", now.Add(5*time.Minute))
+	assert.Len(c.entries, 1)
+
+	// A nil cache doesn't cache
+	var disabled *presignCache
+	disabled.put("a", "https://a", now.Add(time.Hour))
+	_, ok = disabled.get("a")
+	assert.False(ok)
+}
+
+func TestSignDownloadURL(t *testing.T) {
+	cdn, err := NewCDN("https://downloads.example.com", nil)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name            string
+		cache           *presignCache
+		cdn             *CDN
+		credentials     time.Duration
+		presignErr      error
+		expectedURL     string
+		expectedPresign int
+		expectError     bool
+	}{
+		{
+			name:            "without cache",
+			expectedURL:     "https://bucket.example.com/modules/a.tar.gz?signature=1",
+			expectedPresign: 2,
+		},
+		{
+			name:            "with cache",
+			cache:           newPresignCache(time.Minute),
+			expectedURL:     "https://bucket.example.com/modules/a.tar.gz?signature=1",
+			expectedPresign: 1,
+		},
+		{
+			name:            "cdn",
+			cache:           newPresignCache(time.Minute),
+			cdn:             cdn,
+			expectedURL:     "https://downloads.example.com/modules/a.tar.gz",
+			expectedPresign: 0,
+		},
+		{
+			name:            "URLs of expiring credentials aren't cached",
+			cache:           newPresignCache(time.Minute),
+			credentials:     30 * time.Second,
+			expectedURL:     "https://bucket.example.com/modules/a.tar.gz?signature=1",
+			expectedPresign: 2,
+		},
+		{
+			name:            "presign error isn't cached",
+			cache:           newPresignCache(time.Minute),
+			presignErr:      errors.New("no credentials"),
+			expectedPresign: 2,
+			expectError:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			presigned := 0
+			presign := func(ctx context.Context, key string, expires time.Time) (string, time.Time, error) {
+				presigned++
+				if tc.presignErr != nil {
+					return "", time.Time{}, tc.presignErr
+				}
+				// The URL expires with the credentials
+				if tc.credentials > 0 {
+					expires = time.Now().Add(tc.credentials)
+				}
+				return fmt.Sprintf("https://bucket.example.com/%s?signature=%d", key, presigned), expires, nil
+			}
+
+			for i := 0; i < 2; i++ {
+				url, err := signDownloadURL(context.Background(), "modules/a.tar.gz", 5*time.Minute, tc.cache, tc.cdn, presign)
+				if tc.expectError {
+					assert.Error(err)
+					continue
+				}
+				assert.NoError(err)
+				// The URL is only reused, if it was cached
+				if i == 0 || tc.expectedPresign < 2 {
+					assert.Equal(tc.expectedURL, url)
+				}
+			}
+			assert.Equal(tc.expectedPresign, presigned)
+		})
+	}
+}
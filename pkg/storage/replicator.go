@@ -0,0 +1,575 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/mirror"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// ReplicationScopeModules replicates the modules with their sidecars and annotations
+	ReplicationScopeModules = "modules"
+
+	// ReplicationScopeProviders replicates the provider releases with their signing keys and annotations
+	ReplicationScopeProviders = "providers"
+
+	// ReplicationScopeMirror replicates the mirrored providers with their signing keys
+	ReplicationScopeMirror = "mirror"
+
+	// replicationManifestKey is the key of the object in the destination, which records the objects that were copied from the source
+	replicationManifestKey = "replication.json"
+
+	// replicationLeaseKey is the key of the object in the destination, which records the replica that is replicating
+	replicationLeaseKey = "replication-lease.json"
+
+	// defaultReplicationLeaseDuration is how long a lease is valid without being renewed
+	defaultReplicationLeaseDuration = 5 * time.Minute
+)
+
+// ErrReplicationInProgress is returned, if another replica holds the lease of the destination
+var ErrReplicationInProgress = errors.New("another replica is replicating to the destination")
+
+// replicationExcludedFiles are the sidecars that are never replicated, as every registry records its own usage in them
+var replicationExcludedFiles = map[string]bool{
+	downloadStatsFileName:    true,
+	mirror.AccessLogFileName: true,
+}
+
+// replicationMergedFiles are the sidecars that are merged into the destination instead of being overwritten,
+// so that the annotations of versions that were only annotated in the destination are kept
+var replicationMergedFiles = map[string]bool{
+	annotationsFileName: true,
+}
+
+// ReplicationScopes are the parts of the registry that can be replicated
+var ReplicationScopes = []string{ReplicationScopeModules, ReplicationScopeProviders, ReplicationScopeMirror}
+
+// replicationScopePrefixes are the prefixes of the objects of each scope
+var replicationScopePrefixes = map[string]string{
+	ReplicationScopeModules:   string(internalModuleType) + "/",
+	ReplicationScopeProviders: string(internalProviderType) + "/",
+	ReplicationScopeMirror:    string(mirrorProviderType) + "/",
+}
+
+// replicationManifest records the objects that were copied from the source, so that unchanged objects aren't copied again
+type replicationManifest struct {
+	Objects map[string]replicatedObject `json:"objects"`
+}
+
+type replicatedObject struct {
+	// SourceETag is the ETag of the object in the source, when it was copied
+	SourceETag string `json:"source_etag"`
+	Size       int64  `json:"size"`
+	SHA256     string `json:"sha256"`
+	// Versions are the versions of merged annotations, that were copied from the source
+	Versions []string `json:"versions,omitempty"`
+}
+
+// replicationLease is held by the replica that is replicating, so that replicas don't replicate to the same destination concurrently
+type replicationLease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// ReplicationResult summarizes a run of the Replicator
+type ReplicationResult struct {
+	// Copied are the objects that were new or changed in the source
+	Copied []Object
+	// Deleted are the keys of the replicated objects that were deleted from the source
+	Deleted []string
+	// Unchanged is the number of objects that were replicated already
+	Unchanged int
+
+	// DryRun is true in case the objects were not actually copied or deleted
+	DryRun bool
+}
+
+// CopiedBytes returns the accumulated size of all copied objects
+func (r *ReplicationResult) CopiedBytes() int64 {
+	var size int64
+	for _, o := range r.Copied {
+		size += o.Size
+	}
+	return size
+}
+
+// Replicator copies the objects of the registry from one storage backend to another, e.g. from S3 to GCS.
+// The layout of the objects is the same in every storage backend, so they are copied as they are.
+// Objects are only copied if they are new or changed since the last run, and their sizes and checksums are verified after copying.
+type Replicator struct {
+	source      ObjectStorage
+	destination ObjectStorage
+	logger      *slog.Logger
+
+	prefixes    []string
+	concurrency int
+	delete      bool
+
+	// id identifies the Replicator in the lease of the destination
+	id            string
+	leaseDuration time.Duration
+	now           func() time.Time
+}
+
+// Run copies the new and changed objects of the source to the destination.
+// Nothing is copied or deleted in case dryRun is true, but the result still contains all objects that would have been copied or deleted.
+// Run returns ErrReplicationInProgress, if another replica replicates to the destination.
+func (r *Replicator) Run(ctx context.Context, dryRun bool) (*ReplicationResult, error) {
+	if dryRun {
+		return r.run(ctx, true)
+	}
+
+	ctx, release, err := r.acquireLease(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result, err := r.run(ctx, false)
+	if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		err = errors.Join(err, cause)
+	}
+	return result, err
+}
+
+func (r *Replicator) run(ctx context.Context, dryRun bool) (*ReplicationResult, error) {
+	manifest, err := r.readManifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReplicationResult{DryRun: dryRun}
+	var copies []Object
+	var deletions []string
+	for _, prefix := range r.prefixes {
+		sources, err := r.source.ListObjects(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the objects of the source: %w", err)
+		}
+		destinations, err := r.destination.ListObjects(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the objects of the destination: %w", err)
+		}
+
+		sizes := make(map[string]int64, len(destinations))
+		for _, o := range destinations {
+			sizes[o.Key] = o.Size
+		}
+
+		listed := make(map[string]bool, len(sources))
+		for _, o := range sources {
+			if replicationExcludedFiles[path.Base(o.Key)] {
+				continue
+			}
+			listed[o.Key] = true
+			size, exists := sizes[o.Key]
+			replicated, ok := manifest.Objects[o.Key]
+			// The size of merged objects differs from the source, if the destination has versions of its own
+			sameSize := size == o.Size || replicationMergedFiles[path.Base(o.Key)]
+			// The object is copied again, if it was changed or deleted in the destination
+			if exists && ok && sameSize && replicated.Size == o.Size && replicated.SourceETag == o.ETag {
+				result.Unchanged++
+				continue
+			}
+			copies = append(copies, o)
+		}
+
+		if r.delete {
+			// Only objects that were replicated are deleted, so that objects which were written to the destination directly are kept
+			for key := range manifest.Objects {
+				if strings.HasPrefix(key, prefix) && !listed[key] {
+					deletions = append(deletions, key)
+				}
+			}
+		}
+	}
+	sort.Strings(deletions)
+
+	if dryRun {
+		result.Copied, result.Deleted = copies, deletions
+		return result, nil
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	var group errgroup.Group
+	group.SetLimit(r.concurrency)
+	for _, o := range copies {
+		o := o
+		group.Go(func() error {
+			mu.Lock()
+			previous := manifest.Objects[o.Key]
+			mu.Unlock()
+
+			var copied Object
+			var replicated replicatedObject
+			var err error
+			if replicationMergedFiles[path.Base(o.Key)] {
+				copied, replicated, err = r.mergeAnnotations(ctx, o.Key, previous.Versions)
+			} else {
+				copied, replicated, err = r.copy(ctx, o.Key)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return nil
+			}
+			manifest.Objects[o.Key] = replicated
+			result.Copied = append(result.Copied, copied)
+			return nil
+		})
+	}
+	_ = group.Wait()
+	sort.Slice(result.Copied, func(i, j int) bool {
+		return result.Copied[i].Key < result.Copied[j].Key
+	})
+
+	for _, key := range deletions {
+		var err error
+		if replicationMergedFiles[path.Base(key)] {
+			// Only the replicated versions are removed, so that the versions of the destination are kept
+			err = r.destination.UpdateObject(ctx, key, removeAnnotations(key, manifest.Objects[key].Versions, nil))
+		} else {
+			err = r.destination.DeleteObject(ctx, key)
+		}
+		if err != nil && !errors.Is(err, core.ErrObjectNotFound) {
+			errs = append(errs, err)
+			continue
+		}
+		delete(manifest.Objects, key)
+		result.Deleted = append(result.Deleted, key)
+	}
+
+	// The manifest is written even if some objects failed, so that the copied objects aren't copied again
+	if len(result.Copied) > 0 || len(result.Deleted) > 0 {
+		if err := r.writeManifest(ctx, manifest); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// copy copies the object and verifies that its size and checksum in the destination match the source.
+// It returns the object that was read from the source, which can differ from the listed object if it was overwritten in between.
+func (r *Replicator) copy(ctx context.Context, key string) (Object, replicatedObject, error) {
+	body, o, err := r.source.ReadObject(ctx, key)
+	if err != nil {
+		return Object{}, replicatedObject{}, fmt.Errorf("failed to read %s from the source: %w", key, err)
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	m := md5.New()
+	counter := &countingWriter{}
+	if err := r.destination.WriteObject(ctx, key, io.TeeReader(body, io.MultiWriter(h, m, counter))); err != nil {
+		return Object{}, replicatedObject{}, fmt.Errorf("failed to write %s to the destination: %w", key, err)
+	}
+	if counter.n != o.Size {
+		return Object{}, replicatedObject{}, fmt.Errorf("read %d bytes of %s from the source instead of %d", counter.n, key, o.Size)
+	}
+
+	// The object is verified with the size and MD5 checksum that the destination reports, so that it isn't downloaded again
+	written, err := r.destination.StatObject(ctx, key)
+	if err != nil {
+		return Object{}, replicatedObject{}, fmt.Errorf("failed to verify %s in the destination: %w", key, err)
+	}
+	if written.Size != o.Size {
+		return Object{}, replicatedObject{}, fmt.Errorf("size %d of %s in the destination doesn't match the size %d of the source", written.Size, key, o.Size)
+	}
+	// Some storage backends don't report the MD5 checksum, e.g. S3 for objects encrypted with KMS
+	if sum := hex.EncodeToString(m.Sum(nil)); written.MD5 != "" && written.MD5 != sum {
+		return Object{}, replicatedObject{}, fmt.Errorf("checksum %s of %s in the destination doesn't match the checksum %s of the source", written.MD5, key, sum)
+	}
+
+	r.logger.Debug("replicated object", slog.String("key", key), slog.Int64("size", o.Size))
+	return o, replicatedObject{SourceETag: o.ETag, Size: o.Size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// mergeAnnotations merges the annotations of the source into the annotations of the destination.
+// The annotations of the source replace the annotations of the same versions, and the previously replicated versions,
+// which were removed from the source, are removed from the destination.
+func (r *Replicator) mergeAnnotations(ctx context.Context, key string, previous []string) (Object, replicatedObject, error) {
+	body, o, err := r.source.ReadObject(ctx, key)
+	if err != nil {
+		return Object{}, replicatedObject{}, fmt.Errorf("failed to read %s from the source: %w", key, err)
+	}
+	defer body.Close()
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return Object{}, replicatedObject{}, fmt.Errorf("failed to read %s from the source: %w", key, err)
+	}
+	source, err := unmarshalJSONObject[core.VersionAnnotations](key, b)
+	if err != nil {
+		return Object{}, replicatedObject{}, err
+	}
+
+	if err := r.destination.UpdateObject(ctx, key, removeAnnotations(key, previous, source)); err != nil {
+		return Object{}, replicatedObject{}, fmt.Errorf("failed to merge %s into the destination: %w", key, err)
+	}
+
+	versions := make([]string, 0, len(source))
+	for version := range source {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	sum := sha256.Sum256(b)
+	r.logger.Debug("merged annotations", slog.String("key", key), slog.Int("versions", len(versions)))
+	return o, replicatedObject{SourceETag: o.ETag, Size: o.Size, SHA256: hex.EncodeToString(sum[:]), Versions: versions}, nil
+}
+
+// removeAnnotations returns the update of annotations, which removes the versions and then sets the annotations of source
+func removeAnnotations(key string, versions []string, source core.VersionAnnotations) func([]byte) ([]byte, error) {
+	return func(b []byte) ([]byte, error) {
+		stored := core.VersionAnnotations{}
+		if b != nil {
+			var err error
+			if stored, err = unmarshalJSONObject[core.VersionAnnotations](key, b); err != nil {
+				return nil, err
+			}
+		}
+		for _, version := range versions {
+			delete(stored, version)
+		}
+		for version, a := range source {
+			stored[version] = a
+		}
+		return json.Marshal(stored)
+	}
+}
+
+// countingWriter counts the bytes that are written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// acquireLease acquires the lease of the destination and renews it in the background until release is called.
+// The returned context is canceled, if the lease is lost, e.g. because it couldn't be renewed before it expired.
+func (r *Replicator) acquireLease(ctx context.Context) (context.Context, func(), error) {
+	if err := r.destination.UpdateObject(ctx, replicationLeaseKey, r.updateLease(r.leaseDuration)); err != nil {
+		if errors.Is(err, ErrReplicationInProgress) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to acquire the replication lease: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(r.leaseDuration / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := r.destination.UpdateObject(ctx, replicationLeaseKey, r.updateLease(r.leaseDuration)); err != nil {
+				cancel(fmt.Errorf("lost the replication lease: %w", err))
+				return
+			}
+		}
+	}()
+
+	release := func() {
+		close(done)
+		wg.Wait()
+		cancel(nil)
+		// The lease is released even if the replication was canceled, so that the next run doesn't wait until it expires
+		if err := r.destination.UpdateObject(context.WithoutCancel(ctx), replicationLeaseKey, r.updateLease(0)); err != nil && !errors.Is(err, ErrReplicationInProgress) {
+			r.logger.Warn("failed to release the replication lease", slog.String("err", err.Error()))
+		}
+	}
+	return ctx, release, nil
+}
+
+// updateLease returns the update of the lease, which takes it over for the duration unless another replica holds it
+func (r *Replicator) updateLease(duration time.Duration) func([]byte) ([]byte, error) {
+	return func(b []byte) ([]byte, error) {
+		now := r.now()
+		if b != nil {
+			var lease replicationLease
+			if err := json.Unmarshal(b, &lease); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal the replication lease: %w", err)
+			}
+			if lease.Holder != r.id && lease.Expires.After(now) {
+				return nil, fmt.Errorf("%w: %s holds the lease until %s", ErrReplicationInProgress, lease.Holder, lease.Expires.Format(time.RFC3339))
+			}
+		}
+		return json.Marshal(replicationLease{Holder: r.id, Expires: now.Add(duration)})
+	}
+}
+
+// readManifest reads the manifest of the destination, which is empty if nothing was replicated yet
+func (r *Replicator) readManifest(ctx context.Context) (*replicationManifest, error) {
+	manifest := &replicationManifest{Objects: map[string]replicatedObject{}}
+
+	body, _, err := r.destination.ReadObject(ctx, replicationManifestKey)
+	if errors.Is(err, core.ErrObjectNotFound) {
+		return manifest, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the replication manifest: %w", err)
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the replication manifest: %w", err)
+	}
+	if manifest.Objects == nil {
+		manifest.Objects = map[string]replicatedObject{}
+	}
+	for key := range manifest.Objects {
+		// The sidecars were replicated by previous releases, they are forgotten so that they're never deleted from the destination
+		if replicationExcludedFiles[path.Base(key)] {
+			delete(manifest.Objects, key)
+		}
+	}
+	return manifest, nil
+}
+
+func (r *Replicator) writeManifest(ctx context.Context, manifest *replicationManifest) error {
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := r.destination.WriteObject(ctx, replicationManifestKey, bytes.NewReader(b)); err != nil {
+		return fmt.Errorf("failed to write the replication manifest: %w", err)
+	}
+	return nil
+}
+
+// Schedule replicates immediately and then in the given interval until the context is canceled
+func (r *Replicator) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := r.Run(ctx, false)
+		if errors.Is(err, ErrReplicationInProgress) {
+			r.logger.InfoContext(ctx, "replication skipped", slog.String("reason", err.Error()))
+		} else if err != nil {
+			r.logger.ErrorContext(ctx, "replication failed", slog.String("err", err.Error()))
+		}
+		if result != nil {
+			r.logger.InfoContext(ctx, "replication finished", slog.Int("copied", len(result.Copied)), slog.Int64("copied_bytes", result.CopiedBytes()), slog.Int("deleted", len(result.Deleted)), slog.Int("unchanged", result.Unchanged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReplicatorOption provides additional options for the Replicator
+type ReplicatorOption func(*Replicator) error
+
+// WithReplicationScopes configures the parts of the registry that are replicated, which are all of ReplicationScopes by default
+func WithReplicationScopes(scopes ...string) ReplicatorOption {
+	return func(r *Replicator) error {
+		if len(scopes) == 0 {
+			return nil
+		}
+		r.prefixes = nil
+		for _, scope := range scopes {
+			prefix, ok := replicationScopePrefixes[scope]
+			if !ok {
+				return fmt.Errorf("unsupported replication scope %q, expected one of %v", scope, ReplicationScopes)
+			}
+			r.prefixes = append(r.prefixes, prefix)
+		}
+		return nil
+	}
+}
+
+// WithReplicationConcurrency configures the number of objects that are copied concurrently
+func WithReplicationConcurrency(n int) ReplicatorOption {
+	return func(r *Replicator) error {
+		if n < 1 {
+			return fmt.Errorf("the replication concurrency must be at least 1")
+		}
+		r.concurrency = n
+		return nil
+	}
+}
+
+// WithReplicatedDeletions configures the Replicator to delete the replicated objects from the destination, which were deleted from the source.
+// Objects that were written to the destination directly are never deleted.
+func WithReplicatedDeletions(enabled bool) ReplicatorOption {
+	return func(r *Replicator) error {
+		r.delete = enabled
+		return nil
+	}
+}
+
+// NewReplicator returns a Replicator, which copies all objects of the registry from the source to the destination
+func NewReplicator(source, destination ObjectStorage, options ...ReplicatorOption) (*Replicator, error) {
+	r := &Replicator{
+		source:      source,
+		destination: destination,
+		logger:      slog.Default().With(slog.String("component", "replicator")),
+		concurrency: 4,
+
+		id:            replicatorID(),
+		leaseDuration: defaultReplicationLeaseDuration,
+		now:           time.Now,
+	}
+	for _, scope := range ReplicationScopes {
+		r.prefixes = append(r.prefixes, replicationScopePrefixes[scope])
+	}
+
+	for _, option := range options {
+		if err := option(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// replicatorID returns the hostname with a random suffix, which identifies the Replicator in the lease
+func replicatorID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return hostname + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryObjectStorage is an ObjectStorage, which keeps its objects in memory
+type memoryObjectStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	// corrupt modifies the content of written objects without changing their size
+	corrupt bool
+}
+
+func newMemoryObjectStorage(objects map[string]string) *memoryObjectStorage {
+	s := &memoryObjectStorage{objects: map[string][]byte{}}
+	for key, content := range objects {
+		s.objects[key] = []byte(content)
+	}
+	return s
+}
+
+func (s *memoryObjectStorage) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var objects []Object
+	for key, b := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, memoryObject(key, b))
+		}
+	}
+	return objects, nil
+}
+
+func (s *memoryObjectStorage) StatObject(ctx context.Context, key string) (Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.objects[key]
+	if !ok {
+		return Object{}, core.ErrObjectNotFound
+	}
+	return memoryObject(key, b), nil
+}
+
+func (s *memoryObjectStorage) ReadObject(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.objects[key]
+	if !ok {
+		return nil, Object{}, core.ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(b)), memoryObject(key, b), nil
+}
+
+func (s *memoryObjectStorage) UpdateObject(ctx context.Context, key string, update func([]byte) ([]byte, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := update(s.objects[key])
+	if err != nil {
+		return err
+	}
+	s.objects[key] = b
+	return nil
+}
+
+// memoryObject returns the object with the MD5 checksum of its content as ETag
+func memoryObject(key string, b []byte) Object {
+	sum := md5.Sum(b)
+	return Object{Key: key, Size: int64(len(b)), ETag: hex.EncodeToString(sum[:]), MD5: hex.EncodeToString(sum[:])}
+}
+
+func (s *memoryObjectStorage) WriteObject(ctx context.Context, key string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if s.corrupt && len(b) > 0 {
+		b[len(b)-1] ^= 0xff
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = b
+	return nil
+}
+
+func (s *memoryObjectStorage) DeleteObject(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memoryObjectStorage) content(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return string(s.objects[key])
+}
+
+func keys(objects []Object) []string {
+	var keys []string
+	for _, o := range objects {
+		keys = append(keys, o.Key)
+	}
+	return keys
+}
+
+func TestReplicator(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	source := newMemoryObjectStorage(map[string]string{
+		"modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz":                                                     "module",
+		"providers/acme/signing-keys.json":                                                                   "keys",
+		"providers/acme/dummy/terraform-provider-dummy_1.0.0_linux_amd64.zip":                                "provider",
+		"mirror/providers/registry.terraform.io/hashicorp/random/terraform-provider-random_3.0.0_SHA256SUMS": "sums",
+		"healthz": "not replicated",
+	})
+	destination := newMemoryObjectStorage(map[string]string{
+		"modules/acme/vpc/aws/acme-vpc-aws-0.1.0.tar.gz": "written directly",
+	})
+
+	r, err := NewReplicator(source, destination, WithReplicatedDeletions(true))
+	if !assert.NoError(err) {
+		return
+	}
+
+	// A dry run doesn't copy anything
+	result, err := r.Run(ctx, true)
+	assert.NoError(err)
+	assert.Len(result.Copied, 4)
+	assert.Equal("", destination.content("providers/acme/signing-keys.json"))
+
+	result, err = r.Run(ctx, false)
+	assert.NoError(err)
+	assert.Equal([]string{
+		"mirror/providers/registry.terraform.io/hashicorp/random/terraform-provider-random_3.0.0_SHA256SUMS",
+		"modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz",
+		"providers/acme/dummy/terraform-provider-dummy_1.0.0_linux_amd64.zip",
+		"providers/acme/signing-keys.json",
+	}, keys(result.Copied))
+	assert.Equal(int64(22), result.CopiedBytes())
+	assert.Equal("keys", destination.content("providers/acme/signing-keys.json"))
+	assert.Equal("", destination.content("healthz"))
+	assert.NotEmpty(destination.content(replicationManifestKey))
+
+	// Unchanged objects aren't copied again
+	result, err = r.Run(ctx, false)
+	assert.NoError(err)
+	assert.Empty(result.Copied)
+	assert.Equal(4, result.Unchanged)
+
+	// Changed objects and objects that were modified in the destination are copied again
+	assert.NoError(source.WriteObject(ctx, "providers/acme/signing-keys.json", strings.NewReader("rotated keys")))
+	assert.NoError(destination.WriteObject(ctx, "modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz", strings.NewReader("truncated module")))
+	assert.NoError(source.DeleteObject(ctx, "providers/acme/dummy/terraform-provider-dummy_1.0.0_linux_amd64.zip"))
+
+	result, err = r.Run(ctx, false)
+	assert.NoError(err)
+	assert.Equal([]string{"modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz", "providers/acme/signing-keys.json"}, keys(result.Copied))
+	assert.Equal([]string{"providers/acme/dummy/terraform-provider-dummy_1.0.0_linux_amd64.zip"}, result.Deleted)
+	assert.Equal("rotated keys", destination.content("providers/acme/signing-keys.json"))
+	assert.Equal("module", destination.content("modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz"))
+	// Objects that weren't replicated are kept
+	assert.Equal("written directly", destination.content("modules/acme/vpc/aws/acme-vpc-aws-0.1.0.tar.gz"))
+}
+
+func TestReplicator_checksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	source := newMemoryObjectStorage(map[string]string{"modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz": "module"})
+	destination := newMemoryObjectStorage(nil)
+	destination.corrupt = true
+
+	r, err := NewReplicator(source, destination, WithReplicationScopes(ReplicationScopeModules))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	result, err := r.Run(ctx, false)
+	assert.ErrorContains(t, err, "doesn't match the checksum")
+	assert.Empty(t, result.Copied)
+	assert.Equal(t, "", destination.content(replicationManifestKey))
+}
+
+func TestReplicator_sidecars(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	source := newMemoryObjectStorage(map[string]string{
+		"modules/acme/vpc/aws/annotations.json":                                   `{"1.0.0":{"deprecation":{"reason":"old"}},"1.1.0":{"labels":{"team":"network"}}}`,
+		"modules/acme/vpc/aws/downloads.json":                                     `{"1.0.0":100}`,
+		"mirror/providers/registry.terraform.io/hashicorp/random/access-log.json": `{}`,
+	})
+	destination := newMemoryObjectStorage(map[string]string{
+		"modules/acme/vpc/aws/annotations.json": `{"0.9.0":{"deprecation":{"reason":"old"}},"1.0.0":{"labels":{"team":"platform"}}}`,
+		"modules/acme/vpc/aws/downloads.json":   `{"1.0.0":7}`,
+	})
+
+	r, err := NewReplicator(source, destination, WithReplicatedDeletions(true))
+	if !assert.NoError(err) {
+		return
+	}
+
+	result, err := r.Run(ctx, false)
+	assert.NoError(err)
+	// The download stats and access logs of each registry are kept
+	assert.Equal([]string{"modules/acme/vpc/aws/annotations.json"}, keys(result.Copied))
+	assert.Equal(`{"1.0.0":7}`, destination.content("modules/acme/vpc/aws/downloads.json"))
+	assert.Equal("", destination.content("mirror/providers/registry.terraform.io/hashicorp/random/access-log.json"))
+	// The annotations of the source win, and the annotations of versions that only exist in the destination are kept
+	assert.JSONEq(`{"0.9.0":{"deprecation":{"reason":"old"}},"1.0.0":{"deprecation":{"reason":"old"}},"1.1.0":{"labels":{"team":"network"}}}`, destination.content("modules/acme/vpc/aws/annotations.json"))
+
+	result, err = r.Run(ctx, false)
+	assert.NoError(err)
+	assert.Empty(result.Copied)
+	assert.Equal(1, result.Unchanged)
+
+	// Versions that were removed from the source are removed from the destination
+	assert.NoError(source.WriteObject(ctx, "modules/acme/vpc/aws/annotations.json", strings.NewReader(`{"1.0.0":{"deprecation":{"reason":"old"}}}`)))
+	_, err = r.Run(ctx, false)
+	assert.NoError(err)
+	assert.JSONEq(`{"0.9.0":{"deprecation":{"reason":"old"}},"1.0.0":{"deprecation":{"reason":"old"}}}`, destination.content("modules/acme/vpc/aws/annotations.json"))
+
+	// Deleting the annotations in the source only removes the replicated versions
+	assert.NoError(source.DeleteObject(ctx, "modules/acme/vpc/aws/annotations.json"))
+	result, err = r.Run(ctx, false)
+	assert.NoError(err)
+	assert.Equal([]string{"modules/acme/vpc/aws/annotations.json"}, result.Deleted)
+	assert.JSONEq(`{"0.9.0":{"deprecation":{"reason":"old"}}}`, destination.content("modules/acme/vpc/aws/annotations.json"))
+}
+
+func TestReplicator_lease(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := newMemoryObjectStorage(map[string]string{"modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz": "module"})
+	destination := newMemoryObjectStorage(map[string]string{
+		replicationLeaseKey: fmt.Sprintf(`{"holder":"other","expires":%q}`, now.Add(time.Minute).Format(time.RFC3339)),
+	})
+
+	r, err := NewReplicator(source, destination)
+	if !assert.NoError(err) {
+		return
+	}
+	r.now = func() time.Time { return now }
+
+	// Another replica holds the lease
+	_, err = r.Run(ctx, false)
+	assert.ErrorIs(err, ErrReplicationInProgress)
+	assert.Equal("", destination.content("modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz"))
+
+	// A dry run doesn't need the lease
+	result, err := r.Run(ctx, true)
+	assert.NoError(err)
+	assert.Len(result.Copied, 1)
+
+	// The lease is taken over once it expired, and released after the run
+	now = now.Add(2 * time.Minute)
+	result, err = r.Run(ctx, false)
+	assert.NoError(err)
+	assert.Len(result.Copied, 1)
+
+	var lease replicationLease
+	assert.NoError(json.Unmarshal([]byte(destination.content(replicationLeaseKey)), &lease))
+	assert.Equal(r.id, lease.Holder)
+	assert.False(lease.Expires.After(now))
+}
+
+func TestNewReplicator_invalidOptions(t *testing.T) {
+	_, err := NewReplicator(nil, nil, WithReplicationScopes("signing-keys"))
+	assert.Error(t, err)
+
+	_, err = NewReplicator(nil, nil, WithReplicationConcurrency(0))
+	assert.Error(t, err)
+}
+
+func TestObjectKeys(t *testing.T) {
+	testCases := []struct {
+		prefix     string
+		key        string
+		expectKey  string
+		expectList string
+	}{
+		{prefix: "", key: "modules/", expectKey: "modules", expectList: "modules/"},
+		{prefix: "registry", key: "modules/", expectKey: "registry/modules", expectList: "registry/modules/"},
+		{prefix: "registry/", key: "replication.json", expectKey: "registry/replication.json", expectList: "registry/replication.json"},
+		{prefix: "registry", key: "", expectKey: "registry", expectList: "registry/"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.prefix+"/"+tc.key, func(t *testing.T) {
+			assert.Equal(t, tc.expectKey, objectKey(tc.prefix, tc.key))
+			assert.Equal(t, tc.expectList, objectListPrefix(tc.prefix, tc.key))
+		})
+	}
+
+	assert.Equal(t, "modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz", relativeObjectKey("", "modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz"))
+	assert.Equal(t, "modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz", relativeObjectKey("registry/", "registry/modules/acme/vpc/aws/acme-vpc-aws-1.0.0.tar.gz"))
+}
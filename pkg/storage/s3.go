@@ -13,7 +13,9 @@ import (
 	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/mirror"
 	"github.com/boring-registry/boring-registry/pkg/module"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	signer "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
@@ -21,6 +23,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	s3manager "github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 // s3ClientAPI is used to mock the AWS APIs
@@ -29,6 +32,9 @@ type s3ClientAPI interface {
 	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
 	ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, f ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 }
 
 // s3UploaderAPI is used to mock the AWS APIs
@@ -54,6 +60,7 @@ type s3PresignClientAPI interface {
 type S3Storage struct {
 	client              s3ClientAPI
 	presignClient       s3PresignClientAPI
+	credentials         aws.CredentialsProvider
 	downloader          s3DownloaderAPI
 	uploader            s3UploaderAPI
 	bucket              string
@@ -63,31 +70,73 @@ type S3Storage struct {
 	moduleArchiveFormat string
 	forcePathStyle      bool
 	signedURLExpiry     time.Duration
+
+	// presignCache reuses signed download URLs, if it's enabled
+	presignCache *presignCache
+	// cdn signs the download URLs for a CDN instead of the storage backend, if it's configured
+	cdn *CDN
+
+	// presignObserver is notified about the duration of signing a download URL
+	presignObserver func(time.Duration)
 }
 
 // GetModule retrieves information about a module from the S3 storage.
 func (s *S3Storage) GetModule(ctx context.Context, namespace, name, provider, version string) (core.Module, error) {
-	key := modulePath(s.bucketPrefix, namespace, name, provider, version, s.moduleArchiveFormat)
-
-	exists, err := s.objectExists(ctx, key)
+	key, err := s.moduleObject(ctx, namespace, name, provider, version)
 	if err != nil {
 		return core.Module{}, err
-	} else if !exists {
-		return core.Module{}, module.ErrModuleNotFound
 	}
 
-	presigned, err := s.presignedURL(ctx, key)
+	m, err := moduleVersionFromObject(key)
 	if err != nil {
 		return core.Module{}, err
 	}
+	if err := moduleDownloadURL(ctx, m, key, s.downloadURL, s.download); err != nil {
+		return core.Module{}, err
+	}
 
-	return core.Module{
-		Namespace:   namespace,
-		Name:        name,
-		Provider:    provider,
-		Version:     version,
-		DownloadURL: presigned,
-	}, nil
+	return *m, nil
+}
+
+// moduleObject returns the key of the archive or source of a module version.
+// The archive in the configured format is looked up first, before the archives in other formats and sources are listed.
+func (s *S3Storage) moduleObject(ctx context.Context, namespace, name, provider, version string) (string, error) {
+	key := modulePath(s.bucketPrefix, namespace, name, provider, version, s.moduleArchiveFormat)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return "", err
+	} else if exists {
+		return key, nil
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(moduleArchivePathPrefix(s.bucketPrefix, namespace, name, provider, version)),
+	}
+	source := ""
+	paginator := s3.NewListObjectsV2Paginator(s.client, input)
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, obj := range resp.Contents {
+			m, err := moduleVersionFromObject(*obj.Key)
+			if err != nil || m.Version != version {
+				continue
+			}
+			// Archives take precedence over sources
+			if m.ArchiveFormat != "" {
+				return *obj.Key, nil
+			}
+			source = *obj.Key
+		}
+	}
+
+	if source == "" {
+		return "", module.ErrModuleNotFound
+	}
+	return source, nil
 }
 
 func (s *S3Storage) ListModuleVersions(ctx context.Context, namespace, name, provider string) ([]core.Module, error) {
@@ -105,27 +154,38 @@ func (s *S3Storage) ListModuleVersions(ctx context.Context, namespace, name, pro
 		}
 
 		for _, obj := range resp.Contents {
-			m, err := moduleFromObject(*obj.Key, s.moduleArchiveFormat)
+			m, err := moduleVersionFromObject(*obj.Key)
 			if err != nil {
 				// TODO: we're skipping possible failures silently
 				continue
 			}
 
-			// The download URL is probably not necessary for ListModules
-			m.DownloadURL, err = s.presignedURL(ctx, modulePath(s.bucketPrefix, m.Namespace, m.Name, m.Provider, m.Version, s.moduleArchiveFormat))
-			if err != nil {
-				return []core.Module{}, err
+			// The sources are only read by GetModule, so that listing doesn't download an object per version
+			if m.ArchiveFormat != "" {
+				if err := moduleDownloadURL(ctx, m, *obj.Key, s.downloadURL, s.download); err != nil {
+					return []core.Module{}, err
+				}
 			}
 
 			modules = append(modules, *m)
 		}
 	}
 
-	return modules, nil
+	return uniqueModuleVersions(modules), nil
+}
+
+// ListModules lists the modules in S3.
+func (s *S3Storage) ListModules(ctx context.Context) ([]core.Module, error) {
+	keys, err := s.listKeys(ctx, path.Join(s.bucketPrefix, string(internalModuleType))+"/")
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", module.ErrModuleListFailed, err)
+	}
+	return modulesFromKeys(s.bucketPrefix, keys), nil
 }
 
 // UploadModule uploads a module to the S3 storage.
-func (s *S3Storage) UploadModule(ctx context.Context, namespace, name, provider, version string, body io.Reader) (core.Module, error) {
+// The archive is stored in the configured format, unless another format is given.
+func (s *S3Storage) UploadModule(ctx context.Context, namespace, name, provider, version, format string, body io.Reader) (core.Module, error) {
 	if namespace == "" {
 		return core.Module{}, errors.New("namespace not defined")
 	}
@@ -142,23 +202,187 @@ func (s *S3Storage) UploadModule(ctx context.Context, namespace, name, provider,
 		return core.Module{}, errors.New("version not defined")
 	}
 
-	key := modulePath(s.bucketPrefix, namespace, name, provider, version, DefaultModuleArchiveFormat)
+	format, err := uploadArchiveFormat(format, s.moduleArchiveFormat)
+	if err != nil {
+		return core.Module{}, err
+	}
+	key := modulePath(s.bucketPrefix, namespace, name, provider, version, format)
+
+	if m, err := s.GetModule(ctx, namespace, name, provider, version); err == nil && m.Source == "" {
+		return core.Module{}, fmt.Errorf("%w: %s", module.ErrModuleAlreadyExists, key)
+	}
+
+	// The archive is written with a single conditional request, as the uploader doesn't make multipart uploads conditional
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return core.Module{}, fmt.Errorf("%v: %w", module.ErrModuleUploadFailed, err)
+	}
+	if err := s.writeConditional(ctx, key, b, ""); errors.Is(err, core.ErrObjectModified) {
+		return core.Module{}, fmt.Errorf("%w: %s", module.ErrModuleAlreadyExists, key)
+	} else if err != nil {
+		return core.Module{}, fmt.Errorf("%v: %w", module.ErrModuleUploadFailed, err)
+	}
+
+	return s.GetModule(ctx, namespace, name, provider, version)
+}
+
+// UploadModuleSource registers a module version with a git source in S3.
+func (s *S3Storage) UploadModuleSource(ctx context.Context, namespace, name, provider, version, source string) (core.Module, error) {
+	if err := validateModuleSource(namespace, name, provider, version, source); err != nil {
+		return core.Module{}, err
+	}
 
+	key := moduleSourcePath(s.bucketPrefix, namespace, name, provider, version)
 	if _, err := s.GetModule(ctx, namespace, name, provider, version); err == nil {
 		return core.Module{}, fmt.Errorf("%w: %s", module.ErrModuleAlreadyExists, key)
 	}
 
-	input := &s3.PutObjectInput{
+	b, err := json.Marshal(moduleSource{Source: source})
+	if err != nil {
+		return core.Module{}, err
+	}
+	if err := s.upload(ctx, key, bytes.NewReader(b), false); err != nil {
+		return core.Module{}, fmt.Errorf("%v: %w", module.ErrModuleUploadFailed, err)
+	}
+
+	return s.GetModule(ctx, namespace, name, provider, version)
+}
+
+// DeleteModuleSource deletes the source of a module version from S3.
+func (s *S3Storage) DeleteModuleSource(ctx context.Context, namespace, name, provider, version string) error {
+	key := moduleSourcePath(s.bucketPrefix, namespace, name, provider, version)
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
-		Body:   body,
+	}); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
 	}
+	return nil
+}
 
-	if _, err := s.uploader.Upload(ctx, input); err != nil {
-		return core.Module{}, fmt.Errorf("%v: %w", module.ErrModuleUploadFailed, err)
+// GetModuleDetails downloads the details of a module version from S3.
+func (s *S3Storage) GetModuleDetails(ctx context.Context, namespace, name, provider, version string) (*module.Details, error) {
+	key := moduleDetailsPath(s.bucketPrefix, namespace, name, provider, version)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, module.ErrModuleDetailsNotFound
 	}
 
-	return s.GetModule(ctx, namespace, name, provider, version)
+	b, err := s.download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalModuleDetails(b)
+}
+
+// UploadModuleDetails uploads the details of a module version to S3.
+func (s *S3Storage) UploadModuleDetails(ctx context.Context, namespace, name, provider, version string, details *module.Details) error {
+	b, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	key := moduleDetailsPath(s.bucketPrefix, namespace, name, provider, version)
+	return s.upload(ctx, key, bytes.NewReader(b), true)
+}
+
+// GetModuleDocs downloads the documentation bundle of a module version from S3.
+func (s *S3Storage) GetModuleDocs(ctx context.Context, namespace, name, provider, version string) (*module.Docs, error) {
+	key := moduleDocsPath(s.bucketPrefix, namespace, name, provider, version)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, module.ErrModuleDocsNotFound
+	}
+
+	b, err := s.download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalModuleDocs(b)
+}
+
+// UploadModuleDocs uploads the documentation bundle of a module version to S3.
+func (s *S3Storage) UploadModuleDocs(ctx context.Context, namespace, name, provider, version string, docs *module.Docs) error {
+	b, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	key := moduleDocsPath(s.bucketPrefix, namespace, name, provider, version)
+	return s.upload(ctx, key, bytes.NewReader(b), true)
+}
+
+// UploadModuleDependencies uploads the dependencies of a module version to S3 and indexes them by the called modules.
+func (s *S3Storage) UploadModuleDependencies(ctx context.Context, namespace, name, provider, version string, dependencies []module.ModuleDependency) error {
+	// Only stored versions are indexed, so that a failed upload never leaves dependents of a version that doesn't exist
+	if _, err := s.moduleObject(ctx, namespace, name, provider, version); err != nil {
+		return err
+	}
+
+	previous, err := s.GetModuleDependencies(ctx, namespace, name, provider, version)
+	if err != nil && !errors.Is(err, module.ErrModuleDependenciesNotFound) {
+		return err
+	}
+	if err := updateModuleDependents(ctx, s.bucketPrefix, namespace, name, provider, version, previous, dependencies, s.readVersioned, s.writeConditional); err != nil {
+		return err
+	}
+
+	// The dependencies are uploaded last, so that the dependents are updated again if the upload is retried
+	b, err := marshalModuleDependencies(dependencies)
+	if err != nil {
+		return err
+	}
+	return s.upload(ctx, moduleDependenciesPath(s.bucketPrefix, namespace, name, provider, version), bytes.NewReader(b), true)
+}
+
+// GetModuleDependencies downloads the dependencies of a module version from S3.
+func (s *S3Storage) GetModuleDependencies(ctx context.Context, namespace, name, provider, version string) ([]module.ModuleDependency, error) {
+	key := moduleDependenciesPath(s.bucketPrefix, namespace, name, provider, version)
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, module.ErrModuleDependenciesNotFound
+	}
+
+	b, err := s.download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalModuleDependencies(b)
+}
+
+// ListModuleDependents lists the module versions in S3 that call the module.
+func (s *S3Storage) ListModuleDependents(ctx context.Context, namespace, name, provider string) ([]module.ModuleDependent, error) {
+	dependents, err := listModuleDependents(ctx, moduleDependentsPath(s.bucketPrefix, namespace, name, provider), s.readVersioned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list module dependents: %w", err)
+	}
+	return dependents, nil
+}
+
+// GetModuleAnnotations downloads the annotations of a module from S3.
+func (s *S3Storage) GetModuleAnnotations(ctx context.Context, namespace, name, provider string) (core.VersionAnnotations, error) {
+	return downloadJSONObject[core.VersionAnnotations](ctx, moduleAnnotationsPath(s.bucketPrefix, namespace, name, provider), s.readVersioned)
+}
+
+// UpdateModuleAnnotations updates the annotations of a module in S3 with a conditional write.
+func (s *S3Storage) UpdateModuleAnnotations(ctx context.Context, namespace, name, provider string, update func(core.VersionAnnotations)) error {
+	return updateJSONObject(ctx, moduleAnnotationsPath(s.bucketPrefix, namespace, name, provider), s.readVersioned, s.writeConditional, update)
+}
+
+// GetModuleDownloadStats downloads the download stats of a module from S3.
+func (s *S3Storage) GetModuleDownloadStats(ctx context.Context, namespace, name, provider string) (core.DownloadStats, error) {
+	return downloadJSONObject[core.DownloadStats](ctx, moduleDownloadStatsPath(s.bucketPrefix, namespace, name, provider), s.readVersioned)
+}
+
+// MergeModuleDownloadStats adds the downloads to the download stats of a module in S3.
+func (s *S3Storage) MergeModuleDownloadStats(ctx context.Context, namespace, name, provider string, stats core.DownloadStats) error {
+	return updateJSONObject(ctx, moduleDownloadStatsPath(s.bucketPrefix, namespace, name, provider), s.readVersioned, s.writeConditional, func(stored core.DownloadStats) {
+		stored.Merge(stats)
+	})
 }
 
 // GetProvider retrieves information about a provider from the S3 storage.
@@ -177,15 +401,15 @@ func (s *S3Storage) getProvider(ctx context.Context, pt providerType, provider *
 	}
 
 	var err error
-	provider.DownloadURL, err = s.presignedURL(ctx, archivePath)
+	provider.DownloadURL, err = s.downloadURL(ctx, archivePath)
 	if err != nil {
 		return nil, err
 	}
-	provider.SHASumsURL, err = s.presignedURL(ctx, shasumPath)
+	provider.SHASumsURL, err = s.downloadURL(ctx, shasumPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate presigned url for %s: %w", shasumPath, err)
 	}
-	provider.SHASumsSignatureURL, err = s.presignedURL(ctx, shasumSigPath)
+	provider.SHASumsSignatureURL, err = s.downloadURL(ctx, shasumSigPath)
 	if err != nil {
 		return nil, err
 	}
@@ -260,7 +484,7 @@ func (s *S3Storage) listProviderVersions(ctx context.Context, pt providerType, p
 
 			p.Hostname = provider.Hostname
 			p.Namespace = provider.Namespace
-			archiveUrl, err := s.presignedURL(ctx, *obj.Key)
+			archiveUrl, err := s.downloadURL(ctx, *obj.Key)
 			if err != nil {
 				return nil, err
 			}
@@ -290,6 +514,15 @@ func (s *S3Storage) ListProviderVersions(ctx context.Context, namespace, name st
 	return collection.List(), nil
 }
 
+// ListProviders lists the providers in S3.
+func (s *S3Storage) ListProviders(ctx context.Context) ([]core.Provider, error) {
+	keys, err := s.listKeys(ctx, path.Join(s.bucketPrefix, string(internalProviderType))+"/")
+	if err != nil {
+		return nil, err
+	}
+	return providersFromKeys(s.bucketPrefix, keys), nil
+}
+
 func (s *S3Storage) ListMirroredProviders(ctx context.Context, provider *core.Provider) ([]*core.Provider, error) {
 	return s.listProviderVersions(ctx, mirrorProviderType, provider)
 }
@@ -312,6 +545,28 @@ func (s *S3Storage) UploadProviderReleaseFiles(ctx context.Context, namespace, n
 	return s.upload(ctx, key, file, false)
 }
 
+// GetProviderAnnotations downloads the annotations of a provider from S3.
+func (s *S3Storage) GetProviderAnnotations(ctx context.Context, namespace, name string) (core.VersionAnnotations, error) {
+	return downloadJSONObject[core.VersionAnnotations](ctx, providerAnnotationsPath(s.bucketPrefix, namespace, name), s.readVersioned)
+}
+
+// UpdateProviderAnnotations updates the annotations of a provider in S3 with a conditional write.
+func (s *S3Storage) UpdateProviderAnnotations(ctx context.Context, namespace, name string, update func(core.VersionAnnotations)) error {
+	return updateJSONObject(ctx, providerAnnotationsPath(s.bucketPrefix, namespace, name), s.readVersioned, s.writeConditional, update)
+}
+
+// GetProviderDownloadStats downloads the download stats of a provider from S3.
+func (s *S3Storage) GetProviderDownloadStats(ctx context.Context, namespace, name string) (core.DownloadStats, error) {
+	return downloadJSONObject[core.DownloadStats](ctx, providerDownloadStatsPath(s.bucketPrefix, namespace, name), s.readVersioned)
+}
+
+// MergeProviderDownloadStats adds the downloads to the download stats of a provider in S3.
+func (s *S3Storage) MergeProviderDownloadStats(ctx context.Context, namespace, name string, stats core.DownloadStats) error {
+	return updateJSONObject(ctx, providerDownloadStatsPath(s.bucketPrefix, namespace, name), s.readVersioned, s.writeConditional, func(stored core.DownloadStats) {
+		stored.Merge(stats)
+	})
+}
+
 func (s *S3Storage) signingKeys(ctx context.Context, pt providerType, hostname, namespace string) (*core.SigningKeys, error) {
 	if namespace == "" {
 		return nil, fmt.Errorf("namespace argument is empty")
@@ -350,6 +605,11 @@ func (s *S3Storage) uploadSigningKeys(ctx context.Context, pt providerType, host
 	return s.upload(ctx, key, bytes.NewReader(b), true)
 }
 
+// UploadSigningKeys uploads the JSON of the signing keys to the namespace
+func (s *S3Storage) UploadSigningKeys(ctx context.Context, namespace string, signingKeys *core.SigningKeys) error {
+	return s.uploadSigningKeys(ctx, internalProviderType, "", namespace, signingKeys)
+}
+
 func (s *S3Storage) UploadMirroredSigningKeys(ctx context.Context, hostname, namespace string, signingKeys *core.SigningKeys) error {
 	return s.uploadSigningKeys(ctx, mirrorProviderType, hostname, namespace, signingKeys)
 }
@@ -371,16 +631,126 @@ func (s *S3Storage) UploadMirroredFile(ctx context.Context, provider *core.Provi
 	return s.upload(ctx, key, reader, true)
 }
 
-func (s *S3Storage) presignedURL(ctx context.Context, key string) (string, error) {
+func (s *S3Storage) ListMirroredFiles(ctx context.Context) ([]mirror.MirroredFile, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(mirrorStoragePrefix(s.bucketPrefix)),
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(s.client, input)
+
+	var files []mirror.MirroredFile
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to page next page: %w", err)
+		}
+
+		for _, obj := range resp.Contents {
+			f, ok := mirroredFileFromKey(s.bucketPrefix, *obj.Key)
+			if !ok {
+				continue
+			}
+			f.Size = aws.ToInt64(obj.Size)
+			f.LastModified = aws.ToTime(obj.LastModified)
+			files = append(files, f)
+		}
+	}
+
+	return files, nil
+}
+
+func (s *S3Storage) DownloadMirroredFile(ctx context.Context, provider *core.Provider, fileName string) (io.ReadCloser, error) {
+	prefix := providerStoragePrefix(s.bucketPrefix, mirrorProviderType, provider.Hostname, provider.Namespace, provider.Name)
+	key := path.Join(prefix, fileName)
+	if exists, err := s.objectExists(ctx, key); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, core.ErrObjectNotFound
+	}
+
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Storage) UpdateMirroredFile(ctx context.Context, provider *core.Provider, fileName string, update func([]byte) ([]byte, error)) error {
+	prefix := providerStoragePrefix(s.bucketPrefix, mirrorProviderType, provider.Hostname, provider.Namespace, provider.Name)
+	return updateObject(ctx, path.Join(prefix, fileName), s.readVersioned, s.writeConditional, update)
+}
+
+func (s *S3Storage) DeleteMirroredFile(ctx context.Context, provider *core.Provider, fileName string) error {
+	prefix := providerStoragePrefix(s.bucketPrefix, mirrorProviderType, provider.Hostname, provider.Namespace, provider.Name)
+	key := path.Join(prefix, fileName)
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// CheckReachable lists at most one object below the prefix to verify that the bucket is reachable
+func (s *S3Storage) CheckReachable(ctx context.Context) error {
+	_, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.bucket),
+		Prefix:  aws.String(s.bucketPrefix),
+		MaxKeys: aws.Int32(1),
+	})
+	return err
+}
+
+// CheckPresign signs a download URL to verify that the credentials can be retrieved
+func (s *S3Storage) CheckPresign(ctx context.Context) error {
+	// The storage backend signs the URL, bypassing the cache and the CDN, so that its credentials are verified
+	_, _, err := s.presignedURL(ctx, path.Join(s.bucketPrefix, healthCheckObject), time.Now().Add(s.signedURLExpiry))
+	return err
+}
+
+// downloadURL returns the download URL of the object at key, which is signed for the CDN or the storage backend
+func (s *S3Storage) downloadURL(ctx context.Context, key string) (string, error) {
+	return signDownloadURL(ctx, key, s.signedURLExpiry, s.presignCache, s.cdn, s.presignedURL)
+}
+
+func (s *S3Storage) observePresign(observer func(time.Duration)) {
+	s.presignObserver = observer
+}
+
+// presignedURL signs the URL with the credentials of the client, which are retrieved to limit the expiry of the URL.
+// URLs that are signed with temporary credentials, e.g. of STS or IRSA, expire with the credentials at the latest.
+func (s *S3Storage) presignedURL(ctx context.Context, key string, expires time.Time) (_ string, _ time.Time, err error) {
+	ctx, span := startPresignSpan(ctx, backendS3, key)
+	defer func() { o11y.EndSpan(span, err) }()
+	defer observePresign(s.presignObserver, time.Now())
+
+	if s.credentials != nil {
+		creds, err := s.credentials.Retrieve(ctx)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if creds.CanExpire && creds.Expires.Before(expires) {
+			expires = creds.Expires
+		}
+	}
+
 	presignResult, err := s.presignClient.PresignGetObject(ctx,
 		&s3.GetObjectInput{
 			Bucket: aws.String(s.bucket),
 			Key:    aws.String(key),
 		},
-		s3.WithPresignExpires(s.signedURLExpiry),
+		s3.WithPresignExpires(time.Until(expires)),
 	)
+	if err != nil {
+		return "", time.Time{}, err
+	}
 
-	return presignResult.URL, err
+	return presignResult.URL, expires, nil
 }
 
 func (s *S3Storage) objectExists(ctx context.Context, key string) (bool, error) {
@@ -439,6 +809,169 @@ func (s *S3Storage) download(ctx context.Context, key string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// readVersioned downloads the object at key together with its ETag, or returns core.ErrObjectNotFound
+func (s *S3Storage) readVersioned(ctx context.Context, key string) ([]byte, string, error) {
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var responseError *awshttp.ResponseError
+		if errors.As(err, &responseError) && responseError.ResponseError.HTTPStatusCode() == http.StatusNotFound {
+			return nil, "", core.ErrObjectNotFound
+		}
+		return nil, "", fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return b, aws.ToString(resp.ETag), nil
+}
+
+// writeConditional uploads the object at key, if its ETag still matches etag, or if it doesn't exist in case etag is empty.
+// It returns core.ErrObjectModified otherwise.
+func (s *S3Storage) writeConditional(ctx context.Context, key string, b []byte, etag string) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(b),
+	}
+	if etag == "" {
+		input.IfNoneMatch = aws.String("*")
+	} else {
+		input.IfMatch = aws.String(etag)
+	}
+
+	if _, err := s.client.PutObject(ctx, input); err != nil {
+		var responseError *awshttp.ResponseError
+		if errors.As(err, &responseError) {
+			// S3 returns 409 Conflict, if a concurrent conditional write is in progress
+			if code := responseError.ResponseError.HTTPStatusCode(); code == http.StatusPreconditionFailed || code == http.StatusConflict {
+				return fmt.Errorf("failed to upload %s: %w", key, core.ErrObjectModified)
+			}
+		}
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return nil
+}
+
+// listKeys returns the keys of all objects with the prefix
+func (s *S3Storage) listKeys(ctx context.Context, prefix string) ([]string, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(s.client, input)
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to page next page: %w", err)
+		}
+		for _, obj := range resp.Contents {
+			keys = append(keys, *obj.Key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (s *S3Storage) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(objectListPrefix(s.bucketPrefix, prefix)),
+	}
+
+	var objects []Object
+	paginator := s3.NewListObjectsV2Paginator(s.client, input)
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to page next page: %w", err)
+		}
+		for _, obj := range resp.Contents {
+			objects = append(objects, Object{
+				Key:          relativeObjectKey(s.bucketPrefix, aws.ToString(obj.Key)),
+				Size:         aws.ToInt64(obj.Size),
+				ETag:         aws.ToString(obj.ETag),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		}
+	}
+
+	return objects, nil
+}
+
+func (s *S3Storage) StatObject(ctx context.Context, key string) (Object, error) {
+	fullKey := objectKey(s.bucketPrefix, key)
+	resp, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(fullKey),
+	})
+	if err != nil {
+		var responseError *awshttp.ResponseError
+		if errors.As(err, &responseError) && responseError.ResponseError.HTTPStatusCode() == http.StatusNotFound {
+			return Object{}, core.ErrObjectNotFound
+		}
+		return Object{}, fmt.Errorf("failed to stat %s: %w", fullKey, err)
+	}
+
+	o := Object{
+		Key:          key,
+		Size:         aws.ToInt64(resp.ContentLength),
+		ETag:         aws.ToString(resp.ETag),
+		LastModified: aws.ToTime(resp.LastModified),
+	}
+	// The ETag isn't the MD5 checksum of objects that are encrypted with KMS or a customer key, or that were uploaded in parts
+	if resp.ServerSideEncryption != types.ServerSideEncryptionAwsKms && resp.ServerSideEncryption != types.ServerSideEncryptionAwsKmsDsse && resp.SSECustomerAlgorithm == nil {
+		o.MD5 = etagMD5(o.ETag)
+	}
+	return o, nil
+}
+
+func (s *S3Storage) ReadObject(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	fullKey := objectKey(s.bucketPrefix, key)
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(fullKey),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, Object{}, core.ErrObjectNotFound
+	} else if err != nil {
+		return nil, Object{}, fmt.Errorf("failed to download %s: %w", fullKey, err)
+	}
+	return resp.Body, Object{
+		Key:          key,
+		Size:         aws.ToInt64(resp.ContentLength),
+		ETag:         aws.ToString(resp.ETag),
+		LastModified: aws.ToTime(resp.LastModified),
+	}, nil
+}
+
+func (s *S3Storage) WriteObject(ctx context.Context, key string, r io.Reader) error {
+	return s.upload(ctx, objectKey(s.bucketPrefix, key), r, true)
+}
+
+func (s *S3Storage) UpdateObject(ctx context.Context, key string, update func([]byte) ([]byte, error)) error {
+	return updateObject(ctx, objectKey(s.bucketPrefix, key), s.readVersioned, s.writeConditional, update)
+}
+
+func (s *S3Storage) DeleteObject(ctx context.Context, key string) error {
+	key = objectKey(s.bucketPrefix, key)
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
 func (s *S3Storage) GetDownloadUrl(ctx context.Context, url string) (string, error) {
 	return fmt.Sprintf("%s/%s", s.bucketEndpoint, url), nil
 }
@@ -468,10 +1001,13 @@ func WithS3StorageBucketEndpoint(endpoint string) S3StorageOption {
 	}
 }
 
-// WithS3ArchiveFormat configures the module archive format (zip, tar, tgz, etc.)
+// WithS3ArchiveFormat configures the default module archive format (zip, tar, tgz, etc.).
+// Modules in other formats are served as well.
 func WithS3ArchiveFormat(archiveFormat string) S3StorageOption {
 	return func(s *S3Storage) {
-		s.moduleArchiveFormat = archiveFormat
+		if archiveFormat != "" {
+			s.moduleArchiveFormat = archiveFormat
+		}
 	}
 }
 
@@ -482,6 +1018,23 @@ func WithS3StoragePathStyle(forcePathStyle bool) S3StorageOption {
 	}
 }
 
+// WithS3StorageSignedURLCache configures the reuse of signed download URLs until they're valid for less than minValidity.
+// The URLs aren't reused if minValidity is 0.
+func WithS3StorageSignedURLCache(minValidity time.Duration) S3StorageOption {
+	return func(s *S3Storage) {
+		if minValidity > 0 {
+			s.presignCache = newPresignCache(minValidity)
+		}
+	}
+}
+
+// WithS3StorageCDN configures the CDN, for which the download URLs are signed instead of the storage backend
+func WithS3StorageCDN(cdn *CDN) S3StorageOption {
+	return func(s *S3Storage) {
+		s.cdn = cdn
+	}
+}
+
 // WithS3StorageSignedUrlExpiry configures the duration until the signed url expires
 func WithS3StorageSignedUrlExpiry(t time.Duration) S3StorageOption {
 	return func(s *S3Storage) {
@@ -493,7 +1046,8 @@ func WithS3StorageSignedUrlExpiry(t time.Duration) S3StorageOption {
 func NewS3Storage(ctx context.Context, bucket string, options ...S3StorageOption) (Storage, error) {
 	// Required- and default-values should be set here
 	s := &S3Storage{
-		bucket: bucket,
+		bucket:              bucket,
+		moduleArchiveFormat: DefaultModuleArchiveFormat,
 	}
 
 	for _, option := range options {
@@ -520,9 +1074,10 @@ func NewS3Storage(ctx context.Context, bucket string, options ...S3StorageOption
 		return nil, err
 	}
 
-	client := s3.NewFromConfig(cfg)
+	client := s3.NewFromConfig(cfg, withS3Tracing(s.bucket))
 	s.client = client
 	s.presignClient = s3.NewPresignClient(client)
+	s.credentials = cfg.Credentials
 	s.uploader = s3manager.NewUploader(client)
 	s.downloader = s3manager.NewDownloader(client)
 
@@ -11,19 +11,26 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/module"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	signer "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	s3manager "github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	smithyhttp "github.com/aws/smithy-go/transport/http"
 	assertion "github.com/stretchr/testify/assert"
 )
 
 type mockS3Client struct {
-	headObject func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	headObject    func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	listObjectsV2 func(ctx context.Context, input *s3.ListObjectsV2Input, f ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	getObject     func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	putObject     func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 }
 
 func (m *mockS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
@@ -31,13 +38,25 @@ func (m *mockS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInpu
 }
 
 func (m *mockS3Client) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, f ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
-	panic("not yet implemented, as we don't have tests using it")
+	return m.listObjectsV2(ctx, input, f...)
 }
 
 func (m *mockS3Client) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
 	panic("not yet implemented, as we don't have tests using it")
 }
 
+func (m *mockS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return m.getObject(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	panic("not yet implemented, as we don't have tests using it")
+}
+
+func (m *mockS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return m.putObject(ctx, params, optFns...)
+}
+
 type mockS3Uploader struct {
 	b   *bytes.Buffer
 	err error
@@ -84,9 +103,12 @@ func (m *mockS3Downloader) Download(ctx context.Context, w io.WriterAt, input *s
 	return 0, nil
 }
 
-type mockS3PresignClient struct{}
+type mockS3PresignClient struct {
+	calls int
+}
 
 func (m *mockS3PresignClient) PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*signer.PresignedHTTPRequest, error) {
+	m.calls++
 	return &signer.PresignedHTTPRequest{
 		URL: fmt.Sprintf("%s?presigned=true", *params.Key),
 	}, nil
@@ -415,3 +437,277 @@ func TestS3Storage_getProvider(t *testing.T) {
 		})
 	}
 }
+
+func TestS3Storage_CheckReachable(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		description string
+		err         error
+		wantErr     assertion.ErrorAssertionFunc
+	}{
+		{
+			description: "bucket is reachable",
+			wantErr:     assertion.NoError,
+		},
+		{
+			description: "access is denied",
+			err: &awshttp.ResponseError{
+				ResponseError: &smithyhttp.ResponseError{
+					Response: &smithyhttp.Response{
+						Response: &http.Response{
+							StatusCode: http.StatusForbidden,
+						},
+					},
+				},
+			},
+			wantErr: assertion.Error,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.description, func(t *testing.T) {
+			t.Parallel()
+
+			s := &S3Storage{
+				client: &mockS3Client{
+					listObjectsV2: func(ctx context.Context, input *s3.ListObjectsV2Input, f ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+						assertion.Equal(t, "registry", *input.Bucket)
+						assertion.Equal(t, "boring", *input.Prefix)
+						assertion.Equal(t, int32(1), *input.MaxKeys)
+						return &s3.ListObjectsV2Output{}, tc.err
+					},
+				},
+				bucket:       "registry",
+				bucketPrefix: "boring",
+			}
+			tc.wantErr(t, s.CheckReachable(context.Background()))
+		})
+	}
+}
+
+func TestS3Storage_ListModuleVersions(t *testing.T) {
+	t.Parallel()
+
+	s := &S3Storage{
+		client: &mockS3Client{
+			listObjectsV2: func(ctx context.Context, input *s3.ListObjectsV2Input, f ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+				return &s3.ListObjectsV2Output{
+					Contents: []types.Object{
+						{Key: aws.String(modulePath("boring", "acme", "vpc", "aws", "1.0.0", "tar.gz"))},
+						{Key: aws.String(moduleSourcePath("boring", "acme", "vpc", "aws", "1.1.0"))},
+					},
+				}, nil
+			},
+		},
+		// The downloader panics, as the sources aren't read while listing
+		downloader:    &mockS3Downloader{},
+		presignClient: &mockS3PresignClient{},
+		bucket:        "registry",
+		bucketPrefix:  "boring",
+	}
+
+	modules, err := s.ListModuleVersions(context.Background(), "acme", "vpc", "aws")
+	assertion.NoError(t, err)
+	assertion.Len(t, modules, 2)
+	assertion.Equal(t, "1.0.0", modules[0].Version)
+	assertion.Equal(t, "tar.gz", modules[0].ArchiveFormat)
+	assertion.NotEmpty(t, modules[0].DownloadURL)
+	assertion.Equal(t, "1.1.0", modules[1].Version)
+	assertion.Empty(t, modules[1].ArchiveFormat)
+	assertion.Empty(t, modules[1].Source)
+}
+
+func TestS3Storage_UploadModule(t *testing.T) {
+	t.Parallel()
+
+	o := &conditionalS3Object{}
+	client := o.client()
+	client.headObject = headNonExistingObject
+	client.listObjectsV2 = func(ctx context.Context, input *s3.ListObjectsV2Input, f ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+		return &s3.ListObjectsV2Output{}, nil
+	}
+	s := &S3Storage{client: client, bucket: "registry", moduleArchiveFormat: "tar.gz"}
+
+	// A concurrent upload stores the archive after the version was looked up, so the conditional write fails
+	o.onWrite = func(o *conditionalS3Object) {
+		o.body, o.exists = []byte("concurrent"), true
+	}
+	_, err := s.UploadModule(context.Background(), "acme", "vpc", "aws", "1.0.0", "", strings.NewReader("archive"))
+	assertion.ErrorIs(t, err, module.ErrModuleAlreadyExists)
+	assertion.Equal(t, "concurrent", string(o.body))
+}
+
+func TestS3Storage_UploadModuleDependencies(t *testing.T) {
+	t.Parallel()
+
+	s := &S3Storage{
+		client: &mockS3Client{
+			headObject: headNonExistingObject,
+			listObjectsV2: func(ctx context.Context, input *s3.ListObjectsV2Input, f ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+				return &s3.ListObjectsV2Output{}, nil
+			},
+			putObject: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+				t.Errorf("unexpected upload of %s", aws.ToString(params.Key))
+				return &s3.PutObjectOutput{}, nil
+			},
+		},
+		uploader:            &mockS3Uploader{err: errors.New("unexpected upload")},
+		bucket:              "registry",
+		moduleArchiveFormat: "tar.gz",
+	}
+
+	// The dependents aren't indexed before the archive of the version is stored
+	dependencies := []module.ModuleDependency{{Hostname: "registry.example.com", Namespace: "acme", Name: "subnet", Provider: "aws"}}
+	err := s.UploadModuleDependencies(context.Background(), "acme", "vpc", "aws", "1.0.0", dependencies)
+	assertion.ErrorIs(t, err, module.ErrModuleNotFound)
+}
+
+func TestS3Storage_CheckPresign(t *testing.T) {
+	t.Parallel()
+
+	cdn, err := NewCDN("https://downloads.example.com", nil)
+	assertion.NoError(t, err)
+	presign := &mockS3PresignClient{}
+	s := &S3Storage{
+		presignClient: presign,
+		bucket:        "registry",
+		bucketPrefix:  "boring",
+		cdn:           cdn,
+	}
+	// The storage backend signs the URL, even if the download URLs are signed for the CDN
+	assertion.NoError(t, s.CheckPresign(context.Background()))
+	assertion.Equal(t, 1, presign.calls)
+}
+
+func TestS3Storage_presignedURL(t *testing.T) {
+	t.Parallel()
+	assert := assertion.New(t)
+
+	credentialsExpire := time.Now().Add(10 * time.Minute)
+	s := &S3Storage{
+		presignClient: &mockS3PresignClient{},
+		bucket:        "registry",
+		credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{CanExpire: true, Expires: credentialsExpire}, nil
+		}),
+	}
+
+	// The URL expires with the temporary credentials that signed it
+	_, expires, err := s.presignedURL(context.Background(), "modules/a.tar.gz", time.Now().Add(time.Hour))
+	assert.NoError(err)
+	assert.Equal(credentialsExpire, expires)
+
+	requested := time.Now().Add(5 * time.Minute)
+	_, expires, err = s.presignedURL(context.Background(), "modules/a.tar.gz", requested)
+	assert.NoError(err)
+	assert.Equal(requested, expires)
+}
+
+// conditionalS3Object is a single object, whose conditional writes fail like S3 once it's modified concurrently
+type conditionalS3Object struct {
+	body    []byte
+	etag    int
+	exists  bool
+	onWrite func(o *conditionalS3Object)
+}
+
+func (o *conditionalS3Object) client() *mockS3Client {
+	preconditionFailed := &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{
+				Response: &http.Response{
+					StatusCode: http.StatusPreconditionFailed,
+				},
+			},
+		},
+	}
+
+	return &mockS3Client{
+		getObject: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			if !o.exists {
+				_, err := headNonExistingObject(ctx, nil)
+				return nil, err
+			}
+			return &s3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(o.body)),
+				ETag: aws.String(fmt.Sprintf(`"%d"`, o.etag)),
+			}, nil
+		},
+		putObject: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			if o.onWrite != nil {
+				o.onWrite(o)
+			}
+			if params.IfNoneMatch != nil && o.exists {
+				return nil, preconditionFailed
+			}
+			if params.IfMatch != nil && (!o.exists || *params.IfMatch != fmt.Sprintf(`"%d"`, o.etag)) {
+				return nil, preconditionFailed
+			}
+			b, err := io.ReadAll(params.Body)
+			if err != nil {
+				return nil, err
+			}
+			o.body, o.exists = b, true
+			o.etag++
+			return &s3.PutObjectOutput{}, nil
+		},
+	}
+}
+
+func TestS3Storage_UpdateMirroredFile(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+	provider := &core.Provider{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random"}
+	appendByte := func(c byte) func([]byte) ([]byte, error) {
+		return func(b []byte) ([]byte, error) {
+			return append(b, c), nil
+		}
+	}
+
+	o := &conditionalS3Object{}
+	s := &S3Storage{client: o.client(), bucket: "registry"}
+	assert.NoError(s.UpdateMirroredFile(ctx, provider, "access-log.json", appendByte('a')))
+	assert.Equal("a", string(o.body))
+
+	// Another replica modifies the object between the read and the write, so the update is applied again to its content
+	concurrent := true
+	o.onWrite = func(o *conditionalS3Object) {
+		if concurrent {
+			concurrent = false
+			o.body = append(o.body, 'b')
+			o.etag++
+		}
+	}
+	assert.NoError(s.UpdateMirroredFile(ctx, provider, "access-log.json", appendByte('c')))
+	assert.Equal("abc", string(o.body))
+
+	// The update gives up, if the object keeps being modified
+	o.onWrite = func(o *conditionalS3Object) { o.etag++ }
+	assert.ErrorIs(s.UpdateMirroredFile(ctx, provider, "access-log.json", appendByte('d')), core.ErrObjectModified)
+
+	// Errors of the update are returned as is
+	o.onWrite = nil
+	errUpdate := errors.New("update")
+	assert.ErrorIs(s.UpdateMirroredFile(ctx, provider, "access-log.json", func([]byte) ([]byte, error) { return nil, errUpdate }), errUpdate)
+}
+
+func TestS3Storage_GetModuleAnnotations(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	// The annotations are downloaded without checking whether the object exists first
+	o := &conditionalS3Object{}
+	s := &S3Storage{client: o.client(), bucket: "registry"}
+	annotations, err := s.GetModuleAnnotations(ctx, "acme", "vpc", "aws")
+	assert.NoError(err)
+	assert.Equal(core.VersionAnnotations{}, annotations)
+
+	assert.NoError(s.UpdateModuleAnnotations(ctx, "acme", "vpc", "aws", func(annotations core.VersionAnnotations) {
+		annotations["1.0.0"] = core.Annotations{Labels: map[string]string{"team": "networking"}}
+	}))
+	annotations, err = s.GetModuleAnnotations(ctx, "acme", "vpc", "aws")
+	assert.NoError(err)
+	assert.Equal(core.VersionAnnotations{"1.0.0": {Labels: map[string]string{"team": "networking"}}}, annotations)
+}
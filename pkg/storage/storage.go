@@ -1,8 +1,14 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"path"
+	"slices"
+	"time"
 
 	"github.com/boring-registry/boring-registry/pkg/core"
 	"github.com/boring-registry/boring-registry/pkg/mirror"
@@ -12,9 +18,228 @@ import (
 )
 
 const (
-	DefaultModuleArchiveFormat = "tar.gz"
+	DefaultModuleArchiveFormat = module.DefaultArchiveFormat
+
+	// moduleDetailsExtension is the extension of the JSON sidecar with the module details
+	moduleDetailsExtension = "details.json"
+
+	// moduleDocsExtension is the extension of the JSON sidecar with the documentation bundle of a module version
+	moduleDocsExtension = "docs.json"
+
+	// moduleSourceExtension is the extension of the JSON object with the source of a module version, which is stored instead of an archive
+	moduleSourceExtension = "source.json"
+
+	// moduleDependenciesExtension is the extension of the JSON sidecar with the dependencies of a module version
+	moduleDependenciesExtension = "dependencies.json"
+
+	// annotationsFileName is the name of the JSON object with the annotations of all versions of a module or provider
+	annotationsFileName = "annotations.json"
+
+	// downloadStatsFileName is the name of the JSON object with the daily downloads of all versions of a module or provider
+	downloadStatsFileName = "downloads.json"
+
+	// healthCheckObject is the name of the object for which a download URL is signed by the health check.
+	// Signing doesn't require the object to exist.
+	healthCheckObject = "healthz"
 )
 
+// moduleSource is the content of the object with the source of a module version
+type moduleSource struct {
+	Source string `json:"source"`
+}
+
+// moduleDownloadURL sets the download URL of a module version, which is either a presigned URL of the archive or the source of the version
+func moduleDownloadURL(ctx context.Context, m *core.Module, key string, presignedURL func(context.Context, string) (string, error), download func(context.Context, string) ([]byte, error)) error {
+	if m.ArchiveFormat != "" {
+		url, err := presignedURL(ctx, key)
+		if err != nil {
+			return err
+		}
+		m.DownloadURL = url
+		return nil
+	}
+
+	b, err := download(ctx, key)
+	if err != nil {
+		return err
+	}
+	var source moduleSource
+	if err := json.Unmarshal(b, &source); err != nil {
+		return fmt.Errorf("failed to unmarshal the source of %s: %w", key, err)
+	}
+	m.Source = source.Source
+	m.DownloadURL = source.Source
+	return nil
+}
+
+// validateModuleSource validates the module version and its source before it's uploaded
+func validateModuleSource(namespace, name, provider, version, source string) error {
+	if namespace == "" || name == "" || provider == "" || version == "" {
+		return errors.New("namespace, name, provider and version have to be defined")
+	}
+	_, err := module.ParseGitSource(source)
+	return err
+}
+
+// moduleDependents is the index of the module versions that call a module.
+// The calls are keyed by the registry hostname and the calling module version, so that calls with different hostnames are kept apart.
+type moduleDependents map[string]module.ModuleDependent
+
+func moduleDependentKey(d module.ModuleDependent) string {
+	return path.Join(d.Hostname, d.Namespace, d.Name, d.Provider, d.Version)
+}
+
+// updateModuleDependents replaces the calls of a module version in the dependents of the modules that it calls.
+// The modules of the previous dependencies are updated as well, so that the version is removed from the modules that it doesn't call anymore.
+func updateModuleDependents(ctx context.Context, prefix, namespace, name, provider, version string, previous, dependencies []module.ModuleDependency, read func(context.Context, string) ([]byte, string, error), write func(context.Context, string, []byte, string) error) error {
+	called := map[string]module.ModuleDependency{}
+	var keys []string
+	for _, d := range slices.Concat(previous, dependencies) {
+		key := moduleDependentsPath(prefix, d.Namespace, d.Name, d.Provider)
+		if _, ok := called[key]; !ok {
+			called[key] = d
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range keys {
+		c := called[key]
+		err := updateJSONObject(ctx, key, read, write, func(stored moduleDependents) {
+			for k, d := range stored {
+				if d.Namespace == namespace && d.Name == name && d.Provider == provider && d.Version == version {
+					delete(stored, k)
+				}
+			}
+			for _, d := range dependencies {
+				if d.Namespace != c.Namespace || d.Name != c.Name || d.Provider != c.Provider {
+					continue
+				}
+				dependent := module.ModuleDependent{
+					Namespace:         namespace,
+					Name:              name,
+					Provider:          provider,
+					Version:           version,
+					VersionConstraint: d.Version,
+					Hostname:          d.Hostname,
+				}
+				// The module can be called several times, e.g. by the root module and a submodule
+				if _, ok := stored[moduleDependentKey(dependent)]; !ok {
+					stored[moduleDependentKey(dependent)] = dependent
+				}
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update the dependents of %s/%s/%s: %w", c.Namespace, c.Name, c.Provider, err)
+		}
+	}
+	return nil
+}
+
+func marshalModuleDependencies(dependencies []module.ModuleDependency) ([]byte, error) {
+	if dependencies == nil {
+		dependencies = []module.ModuleDependency{}
+	}
+	return json.Marshal(dependencies)
+}
+
+func unmarshalModuleDependencies(b []byte) ([]module.ModuleDependency, error) {
+	var dependencies []module.ModuleDependency
+	if err := json.Unmarshal(b, &dependencies); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal module dependencies: %w", err)
+	}
+	return dependencies, nil
+}
+
+// listModuleDependents downloads the index of the module versions that call the module with the key
+func listModuleDependents(ctx context.Context, key string, read func(context.Context, string) ([]byte, string, error)) ([]module.ModuleDependent, error) {
+	stored, err := downloadJSONObject[moduleDependents](ctx, key, read)
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := make([]module.ModuleDependent, 0, len(stored))
+	for _, d := range stored {
+		dependents = append(dependents, d)
+	}
+	module.SortModuleDependents(dependents)
+	return dependents, nil
+}
+
+// downloadJSONObject downloads the JSON object with the key, which is empty if the object doesn't exist.
+// A missing object is reported by the read itself, so that the object is downloaded with a single request.
+func downloadJSONObject[T ~map[K]V, K comparable, V any](ctx context.Context, key string, read func(context.Context, string) ([]byte, string, error)) (T, error) {
+	b, _, err := read(ctx, key)
+	if errors.Is(err, core.ErrObjectNotFound) {
+		return T{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return unmarshalJSONObject[T](key, b)
+}
+
+// updateJSONObject applies update to the JSON object with the key with optimistic concurrency, see updateObject.
+// The update receives an empty object, if the object doesn't exist yet.
+func updateJSONObject[T ~map[K]V, K comparable, V any](ctx context.Context, key string, read func(context.Context, string) ([]byte, string, error), write func(context.Context, string, []byte, string) error, update func(T)) error {
+	return updateObject(ctx, key, read, write, func(b []byte) ([]byte, error) {
+		stored := T{}
+		if b != nil {
+			var err error
+			if stored, err = unmarshalJSONObject[T](key, b); err != nil {
+				return nil, err
+			}
+		}
+		update(stored)
+		return json.Marshal(stored)
+	})
+}
+
+func unmarshalJSONObject[T ~map[K]V, K comparable, V any](key string, b []byte) (T, error) {
+	v := T{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", key, err)
+	}
+	if v == nil {
+		// The object contains null
+		v = T{}
+	}
+	return v, nil
+}
+
+// maxUpdateAttempts bounds how often updateObject retries, when the object is modified concurrently
+const maxUpdateAttempts = 10
+
+// updateObject applies update to the object with the key with optimistic concurrency.
+// The object is read with its version, and written only if it wasn't modified in between, otherwise it's read and updated again.
+// This keeps the changes of other replicas and processes, which update the same object.
+// The update receives nil, if the object doesn't exist yet.
+func updateObject(ctx context.Context, key string, read func(context.Context, string) ([]byte, string, error), write func(context.Context, string, []byte, string) error, update func([]byte) ([]byte, error)) error {
+	for attempt := 1; ; attempt++ {
+		b, version, err := read(ctx, key)
+		if errors.Is(err, core.ErrObjectNotFound) {
+			b, version = nil, ""
+		} else if err != nil {
+			return err
+		}
+
+		updated, err := update(b)
+		if err != nil {
+			return err
+		}
+
+		err = write(ctx, key, updated, version)
+		if !errors.Is(err, core.ErrObjectModified) || attempt == maxUpdateAttempts {
+			return err
+		}
+
+		// Back off randomly, so that the concurrent writers don't collide again
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * time.Duration(10+rand.IntN(40)) * time.Millisecond):
+		}
+	}
+}
+
 type Storage interface {
 	provider.Storage
 	module.Storage
@@ -22,6 +247,26 @@ type Storage interface {
 	proxy.Storage
 }
 
+// HealthChecker is implemented by storage backends, which can verify that they are operational
+type HealthChecker interface {
+	// CheckReachable verifies that the objects of the registry can be listed
+	CheckReachable(ctx context.Context) error
+
+	// CheckPresign verifies that download URLs can be signed with the configured credentials
+	CheckPresign(ctx context.Context) error
+}
+
+// uploadArchiveFormat returns the format of a module archive that is uploaded, which falls back to the configured format of the storage
+func uploadArchiveFormat(format, configured string) (string, error) {
+	if format == "" {
+		format = configured
+	}
+	if !module.IsArchiveFormat(format) {
+		return "", fmt.Errorf("unsupported module archive format %q, expected one of %v", format, module.ArchiveFormats)
+	}
+	return format, nil
+}
+
 // unmarshalSigningKeys tries to unmarshal the byte-array into core.SigningKeys, and if that fails into core.GPGPublicKey.
 // A full core.SigningKeys is always returned for backward-compatibility reasons.
 func unmarshalSigningKeys(b []byte) (*core.SigningKeys, error) {
@@ -46,3 +291,19 @@ func unmarshalSigningKeys(b []byte) (*core.SigningKeys, error) {
 
 	return &signingKeys, nil
 }
+
+func unmarshalModuleDetails(b []byte) (*module.Details, error) {
+	var details module.Details
+	if err := json.Unmarshal(b, &details); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal module details: %w", err)
+	}
+	return &details, nil
+}
+
+func unmarshalModuleDocs(b []byte) (*module.Docs, error) {
+	var docs module.Docs
+	if err := json.Unmarshal(b, &docs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal module documentation: %w", err)
+	}
+	return &docs, nil
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/tracing"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	backendS3    = "s3"
+	backendGCS   = "gcs"
+	backendAzure = "azure"
+
+	backendAttribute = "storage.backend"
+)
+
+// startPresignSpan starts a span for signing the URL of an object.
+// Signing doesn't necessarily involve a request to the storage backend, e.g. GCS signs locally unless a service account is configured.
+func startPresignSpan(ctx context.Context, backend, key string) (context.Context, trace.Span) {
+	return o11y.StartSpan(ctx, fmt.Sprintf("%s.presignedURL", backend),
+		attribute.String(backendAttribute, backend),
+		attribute.String("storage.key", key),
+	)
+}
+
+// withS3Tracing adds a middleware to the S3 client, which starts a span for each operation.
+// The spans of the GCS client are started by the client library itself.
+func withS3Tracing(bucket string) func(*s3.Options) {
+	tracingMiddleware := middleware.InitializeMiddlewareFunc("Tracing", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (out middleware.InitializeOutput, metadata middleware.Metadata, err error) {
+		operation := awsmiddleware.GetOperationName(ctx)
+		ctx, span := o11y.StartSpan(ctx, fmt.Sprintf("S3.%s", operation),
+			attribute.String(backendAttribute, backendS3),
+			semconv.RPCSystemKey.String("aws-api"),
+			semconv.RPCService("S3"),
+			semconv.RPCMethod(operation),
+			semconv.AWSS3Bucket(bucket),
+		)
+		defer func() { o11y.EndSpan(span, err) }()
+
+		return next.HandleInitialize(ctx, in)
+	})
+
+	return func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(tracingMiddleware, middleware.After)
+		})
+	}
+}
+
+// azureTracingProvider returns a tracing.Provider for the Azure client, which starts the spans with the global TracerProvider
+func azureTracingProvider() tracing.Provider {
+	return tracing.NewProvider(func(name, version string) tracing.Tracer {
+		tracer := otel.Tracer(name, trace.WithInstrumentationVersion(version))
+
+		return tracing.NewTracer(func(ctx context.Context, spanName string, options *tracing.SpanOptions) (context.Context, tracing.Span) {
+			opts := []trace.SpanStartOption{
+				trace.WithAttributes(attribute.String(backendAttribute, backendAzure)),
+			}
+			if options != nil {
+				// The values of the span kinds are the same as the ones of OpenTelemetry
+				opts = append(opts, trace.WithSpanKind(trace.SpanKind(options.Kind)), trace.WithAttributes(azureAttributes(options.Attributes)...))
+			}
+
+			ctx, span := tracer.Start(ctx, spanName, opts...)
+			return ctx, tracing.NewSpan(tracing.SpanImpl{
+				End: func() { span.End() },
+				SetAttributes: func(attributes ...tracing.Attribute) {
+					span.SetAttributes(azureAttributes(attributes)...)
+				},
+				AddEvent: func(name string, attributes ...tracing.Attribute) {
+					span.AddEvent(name, trace.WithAttributes(azureAttributes(attributes)...))
+				},
+				SetStatus: func(status tracing.SpanStatus, description string) {
+					switch status {
+					case tracing.SpanStatusError:
+						span.SetStatus(codes.Error, description)
+					case tracing.SpanStatusOK:
+						span.SetStatus(codes.Ok, description)
+					}
+				},
+			})
+		}, nil)
+	}, nil)
+}
+
+func azureAttributes(attributes []tracing.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		switch v := a.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(a.Key, v))
+		case int:
+			kvs = append(kvs, attribute.Int(a.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(a.Key, v))
+		case float64:
+			kvs = append(kvs, attribute.Float64(a.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(a.Key, v))
+		default:
+			kvs = append(kvs, attribute.String(a.Key, fmt.Sprintf("%v", v)))
+		}
+	}
+	return kvs
+}
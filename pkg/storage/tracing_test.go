@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type httpClientFunc func(*http.Request) (*http.Response, error)
+
+func (f httpClientFunc) Do(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestWithS3Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(o11y.NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), 1, "test"))
+
+	client := s3.New(s3.Options{
+		Region:      "eu-central-1",
+		Credentials: credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		HTTPClient: httpClientFunc(func(r *http.Request) (*http.Response, error) {
+			status := http.StatusOK
+			if strings.HasSuffix(r.URL.Path, "/missing") {
+				status = http.StatusNotFound
+			}
+			return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+		RetryMaxAttempts: 1,
+	}, withS3Tracing("registry"))
+	s := &S3Storage{
+		client:        client,
+		presignClient: s3.NewPresignClient(client),
+		bucket:        "registry",
+	}
+
+	exists, err := s.objectExists(context.Background(), "modules/example/vpc/aws/example-vpc-aws-1.0.0.tar.gz")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	_, err = client.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("registry"), Key: aws.String("missing")})
+	assert.Error(t, err)
+
+	_, _, err = s.presignedURL(context.Background(), "modules/example/vpc/aws/example-vpc-aws-1.0.0.tar.gz", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	if !assert.Len(t, spans, 4) {
+		return
+	}
+
+	assert.Equal(t, "S3.HeadObject", spans[0].Name)
+	assert.Contains(t, spans[0].Attributes, attribute.String(backendAttribute, backendS3))
+	assert.Contains(t, spans[0].Attributes, attribute.String("aws.s3.bucket", "registry"))
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+
+	assert.Equal(t, "S3.HeadObject", spans[1].Name)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+
+	// Presigning runs the middlewares of the GetObject operation without sending the request
+	assert.Equal(t, "S3.GetObject", spans[2].Name)
+	assert.Equal(t, "s3.presignedURL", spans[3].Name)
+	assert.Equal(t, spans[3].SpanContext.SpanID(), spans[2].Parent.SpanID())
+}
@@ -0,0 +1,37 @@
+package tenant
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogKey is the attribute with which log records are labelled with the tenant
+const LogKey = "tenant"
+
+type logHandler struct {
+	next slog.Handler
+}
+
+// NewLogHandler returns a slog.Handler, which labels the records that are logged with the context of a routed request with its tenant
+func NewLogHandler(next slog.Handler) slog.Handler {
+	return &logHandler{next: next}
+}
+
+func (h *logHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *logHandler) Handle(ctx context.Context, record slog.Record) error {
+	if name, ok := FromContext(ctx); ok {
+		record.AddAttrs(slog.String(LogKey, name))
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,80 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type contextKey struct{}
+
+// NewContext returns a context, which carries the name of the tenant that serves the request
+func NewContext(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, contextKey{}, name)
+}
+
+// FromContext returns the name of the tenant that serves the request, if the request was routed by a Router
+func FromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(contextKey{}).(string)
+	return name, ok
+}
+
+type route struct {
+	name    string
+	handler http.Handler
+}
+
+// Router dispatches requests to the handler of the tenant, one of whose hostnames matches the Host header of the request.
+// Requests for other hostnames are dispatched to the default tenant, or rejected if there is none.
+// The name of the tenant is added to the request context.
+type Router struct {
+	routes   map[string]route
+	fallback *route
+}
+
+// NewRouter returns a Router without tenants
+func NewRouter() *Router {
+	return &Router{
+		routes: map[string]route{},
+	}
+}
+
+// Handle dispatches the requests for the hostnames to the handler of the tenant
+func (r *Router) Handle(name string, hostnames []string, handler http.Handler) error {
+	for _, h := range hostnames {
+		hostname := strings.ToLower(h)
+		if other, ok := r.routes[hostname]; ok {
+			return fmt.Errorf("hostname %s of tenant %s is already served by tenant %s", h, name, other.name)
+		}
+		r.routes[hostname] = route{name: name, handler: handler}
+	}
+	return nil
+}
+
+// HandleDefault dispatches the requests for all other hostnames to the handler of the default tenant
+func (r *Router) HandleDefault(name string, handler http.Handler) {
+	r.fallback = &route{name: name, handler: handler}
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rt, ok := r.routes[hostname(req.Host)]
+	if !ok {
+		if r.fallback == nil {
+			http.Error(w, fmt.Sprintf("no registry is served for %s", req.Host), http.StatusMisdirectedRequest)
+			return
+		}
+		rt = *r.fallback
+	}
+
+	rt.handler.ServeHTTP(w, req.WithContext(NewContext(req.Context(), rt.name)))
+}
+
+// hostname returns the lowercase hostname of the Host header without the port
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,72 @@
+package tenant
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tenantHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, _ := FromContext(r.Context())
+		_, _ = w.Write([]byte(name))
+	})
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter()
+	assert.NoError(t, router.Handle("payments", []string{"registry.payments.example.com", "Payments.Example.com"}, tenantHandler()))
+	assert.NoError(t, router.Handle("platform", []string{"registry.platform.example.com"}, tenantHandler()))
+	assert.Error(t, router.Handle("other", []string{"registry.PLATFORM.example.com"}, tenantHandler()))
+
+	testCases := []struct {
+		name         string
+		host         string
+		fallback     bool
+		expectStatus int
+		expectTenant string
+	}{
+		{name: "hostname", host: "registry.payments.example.com", expectStatus: http.StatusOK, expectTenant: "payments"},
+		{name: "case-insensitive hostname", host: "payments.example.com", expectStatus: http.StatusOK, expectTenant: "payments"},
+		{name: "hostname with port", host: "registry.platform.example.com:8443", expectStatus: http.StatusOK, expectTenant: "platform"},
+		{name: "unknown hostname without default tenant", host: "registry.example.com", expectStatus: http.StatusMisdirectedRequest},
+		{name: "unknown hostname with default tenant", host: "registry.example.com", fallback: true, expectStatus: http.StatusOK, expectTenant: "default"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.fallback {
+				router.HandleDefault("default", tenantHandler())
+				defer func() { router.fallback = nil }()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/.well-known/terraform.json", nil)
+			req.Host = tc.host
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			if tc.expectTenant != "" {
+				assert.Equal(t, tc.expectTenant, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewTextHandler(&buf, nil))).With(slog.String("op", "test"))
+
+	logger.InfoContext(NewContext(context.Background(), "payments"), "routed")
+	assert.Contains(t, buf.String(), "op=test tenant=payments")
+
+	buf.Reset()
+	logger.InfoContext(context.Background(), "not routed")
+	assert.NotContains(t, buf.String(), "tenant=")
+}
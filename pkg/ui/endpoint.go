@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/endpoint"
+)
+
+const (
+	pageNamespaces = "namespaces.html"
+	pageNamespace  = "namespace.html"
+	pageModule     = "module.html"
+	pageProvider   = "provider.html"
+	pageMirror     = "mirror.html"
+	pageLogin      = "login.html"
+	pageError      = "error.html"
+)
+
+type namespaceRequest struct {
+	namespace string
+}
+
+type moduleRequest struct {
+	namespace string
+	name      string
+	provider  string
+}
+
+type providerRequest struct {
+	namespace string
+	name      string
+}
+
+type loginRequest struct {
+	token string
+	// next is the page that's redirected to after the login
+	next string
+	// secure is true for requests over TLS, including those forwarded by a proxy that terminates TLS
+	secure bool
+}
+
+// pageResponse is a page that's rendered with the template of the same name
+type pageResponse struct {
+	page  string
+	title string
+	data  interface{}
+}
+
+// loginResponse sets the cookie with the verified token
+type loginResponse struct {
+	token  string
+	next   string
+	secure bool
+}
+
+func namespacesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		res, err := svc.ListNamespaces(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return pageResponse{page: pageNamespaces, title: "Namespaces", data: res}, nil
+	}
+}
+
+func namespaceEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(namespaceRequest)
+
+		res, err := svc.GetNamespace(ctx, req.namespace)
+		if err != nil {
+			return nil, err
+		}
+		return pageResponse{page: pageNamespace, title: res.Name, data: res}, nil
+	}
+}
+
+func moduleEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(moduleRequest)
+
+		res, err := svc.GetModule(ctx, req.namespace, req.name, req.provider)
+		if err != nil {
+			return nil, err
+		}
+		return pageResponse{page: pageModule, title: fmt.Sprintf("%s/%s/%s", res.Namespace, res.Name, res.Provider), data: res}, nil
+	}
+}
+
+func providerEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(providerRequest)
+
+		res, err := svc.GetProvider(ctx, req.namespace, req.name)
+		if err != nil {
+			return nil, err
+		}
+		return pageResponse{page: pageProvider, title: fmt.Sprintf("%s/%s", res.Namespace, res.Name), data: res}, nil
+	}
+}
+
+func mirrorEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		res, err := svc.ListMirroredProviders(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return pageResponse{page: pageMirror, title: "Mirror", data: res}, nil
+	}
+}
+
+func loginFormEndpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(loginRequest)
+		return pageResponse{page: pageLogin, title: "Login", data: loginPage{Next: req.next}}, nil
+	}
+}
+
+// loginEndpoint verifies the token with the same auth middleware as the registry protocols
+func loginEndpoint(auth endpoint.Middleware) endpoint.Endpoint {
+	verify := auth(func(context.Context, interface{}) (interface{}, error) { return nil, nil })
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(loginRequest)
+
+		if _, err := verify(context.WithValue(ctx, jwt.JWTContextKey, req.token), nil); err != nil {
+			return nil, loginError{next: req.next, err: fmt.Errorf("%w: %w", ErrLoginFailed, err)}
+		}
+		return loginResponse{token: req.token, next: req.next, secure: req.secure}, nil
+	}
+}
+
+// loginPage is the data of the login form
+type loginPage struct {
+	Next  string
+	Error string
+}
+
+// loginError keeps the page to redirect to after a failed login
+type loginError struct {
+	next string
+	err  error
+}
+
+func (e loginError) Error() string { return e.err.Error() }
+func (e loginError) Unwrap() error { return e.err }
@@ -0,0 +1,12 @@
+package ui
+
+import "errors"
+
+var (
+	// ErrNotFound is returned for namespaces, modules and providers that don't exist
+	ErrNotFound = errors.New("failed to locate page")
+	// ErrMirrorDisabled is returned for the mirror pages if the provider network mirror is disabled
+	ErrMirrorDisabled = errors.New("provider network mirror is disabled")
+	// ErrLoginFailed is returned for tokens that aren't accepted by the auth middleware
+	ErrLoginFailed = errors.New("login failed")
+)
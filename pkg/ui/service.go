@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/mirror"
+	"github.com/boring-registry/boring-registry/pkg/module"
+	"github.com/boring-registry/boring-registry/pkg/provider"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Service provides the read-only views of the web UI.
+// It's built on the module, provider and mirror services, so that the UI never reads from the storage directly.
+type Service interface {
+	ListNamespaces(ctx context.Context) ([]Namespace, error)
+	GetNamespace(ctx context.Context, namespace string) (*Namespace, error)
+	GetModule(ctx context.Context, namespace, name, provider string) (*Module, error)
+	GetProvider(ctx context.Context, namespace, name string) (*Provider, error)
+	// ListMirroredProviders returns an ErrMirrorDisabled error if the provider network mirror is disabled
+	ListMirroredProviders(ctx context.Context) ([]MirrorHost, error)
+}
+
+// Namespace groups the modules and providers of a namespace
+type Namespace struct {
+	Name      string
+	Modules   []core.Module
+	Providers []core.Provider
+}
+
+// Module is a module with all of its versions
+type Module struct {
+	Namespace string
+	Name      string
+	Provider  string
+	// Versions are sorted from the most recent to the oldest version
+	Versions []core.Module
+}
+
+// Provider is a provider with all of its versions and the signing keys of its namespace
+type Provider struct {
+	Namespace string
+	Name      string
+	// Versions are sorted from the most recent to the oldest version
+	Versions    []core.ProviderVersion
+	SigningKeys []core.GPGPublicKey
+}
+
+// MirrorHost groups the mirrored providers by their upstream registry
+type MirrorHost struct {
+	Hostname  string
+	Providers []mirror.MirroredProvider
+}
+
+// namespacesTTL is the duration for which the namespaces are cached,
+// because listing them lists all modules and providers in the storage
+const namespacesTTL = time.Minute
+
+type service struct {
+	modules   module.Service
+	providers provider.Service
+	mirror    mirror.CatalogService
+
+	mu         sync.Mutex
+	namespaces []Namespace
+	expires    time.Time
+	now        func() time.Time
+}
+
+// NewService returns a fully initialized Service.
+// The mirror is nil if the provider network mirror is disabled.
+func NewService(modules module.Service, providers provider.Service, mirror mirror.CatalogService) Service {
+	return &service{
+		modules:   modules,
+		providers: providers,
+		mirror:    mirror,
+		now:       time.Now,
+	}
+}
+
+func (s *service) ListNamespaces(ctx context.Context) ([]Namespace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.namespaces != nil && s.now().Before(s.expires) {
+		return s.namespaces, nil
+	}
+
+	modules, err := s.modules.ListModules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	providers, err := s.providers.ListProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string]*Namespace{}
+	namespace := func(name string) *Namespace {
+		if _, ok := index[name]; !ok {
+			index[name] = &Namespace{Name: name}
+		}
+		return index[name]
+	}
+	for _, m := range modules {
+		ns := namespace(m.Namespace)
+		ns.Modules = append(ns.Modules, m)
+	}
+	for _, p := range providers {
+		ns := namespace(p.Namespace)
+		ns.Providers = append(ns.Providers, p)
+	}
+
+	namespaces := make([]Namespace, 0, len(index))
+	for _, ns := range index {
+		namespaces = append(namespaces, *ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Name < namespaces[j].Name })
+
+	s.namespaces = namespaces
+	s.expires = s.now().Add(namespacesTTL)
+	return namespaces, nil
+}
+
+func (s *service) GetNamespace(ctx context.Context, namespace string) (*Namespace, error) {
+	namespaces, err := s.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(namespaces), func(i int) bool { return namespaces[i].Name >= namespace })
+	if i < len(namespaces) && namespaces[i].Name == namespace {
+		ns := namespaces[i]
+		return &ns, nil
+	}
+	return nil, fmt.Errorf("%w: namespace %s", ErrNotFound, namespace)
+}
+
+func (s *service) GetModule(ctx context.Context, namespace, name, provider string) (*Module, error) {
+	versions, err := s.modules.ListModuleVersions(ctx, namespace, name, provider)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: module %s/%s/%s", ErrNotFound, namespace, name, provider)
+	}
+
+	// The versions are listed without their sources, which are only shown for the versions without an archive
+	for i := range versions {
+		if versions[i].ArchiveFormat != "" || versions[i].Source != "" {
+			continue
+		}
+		m, err := s.modules.GetModule(ctx, namespace, name, provider, versions[i].Version)
+		if err != nil {
+			return nil, err
+		}
+		versions[i].Source = m.Source
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return newerVersion(versions[i].Version, versions[j].Version) })
+	return &Module{
+		Namespace: namespace,
+		Name:      name,
+		Provider:  provider,
+		Versions:  versions,
+	}, nil
+}
+
+func (s *service) GetProvider(ctx context.Context, namespace, name string) (*Provider, error) {
+	res, err := s.providers.ListProviderVersions(ctx, namespace, name)
+	if err != nil {
+		var providerErr *core.ProviderError
+		if errors.As(err, &providerErr) && providerErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: provider %s/%s", ErrNotFound, namespace, name)
+		}
+		return nil, err
+	}
+	if len(res.Versions) == 0 {
+		return nil, fmt.Errorf("%w: provider %s/%s", ErrNotFound, namespace, name)
+	}
+
+	versions := res.Versions
+	sort.Slice(versions, func(i, j int) bool { return newerVersion(versions[i].Version, versions[j].Version) })
+
+	p := &Provider{
+		Namespace: namespace,
+		Name:      name,
+		Versions:  versions,
+	}
+
+	// Providers can be listed before their signing keys are uploaded
+	keys, err := s.providers.GetSigningKeys(ctx, namespace)
+	if err != nil && !errors.Is(err, core.ErrObjectNotFound) {
+		return nil, err
+	} else if err == nil {
+		p.SigningKeys = keys.GPGPublicKeys
+	}
+
+	return p, nil
+}
+
+func (s *service) ListMirroredProviders(ctx context.Context) ([]MirrorHost, error) {
+	if s.mirror == nil {
+		return nil, ErrMirrorDisabled
+	}
+
+	providers, err := s.mirror.ListMirroredProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The providers are sorted by hostname already
+	var hosts []MirrorHost
+	for _, p := range providers {
+		if len(hosts) == 0 || hosts[len(hosts)-1].Hostname != p.Hostname {
+			hosts = append(hosts, MirrorHost{Hostname: p.Hostname})
+		}
+		hosts[len(hosts)-1].Providers = append(hosts[len(hosts)-1].Providers, p)
+	}
+	return hosts, nil
+}
+
+// newerVersion reports whether a is a more recent version than b.
+// Versions that can't be parsed are compared lexically.
+func newerVersion(a, b string) bool {
+	va, errA := version.NewVersion(a)
+	vb, errB := version.NewVersion(b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	return va.GreaterThan(vb)
+}
@@ -0,0 +1,291 @@
+package ui
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boring-registry/boring-registry/pkg/auth"
+	"github.com/boring-registry/boring-registry/pkg/core"
+	"github.com/boring-registry/boring-registry/pkg/mirror"
+	"github.com/boring-registry/boring-registry/pkg/module"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeProviderService struct {
+	providers   []core.Provider
+	versions    map[string]*core.ProviderVersions
+	signingKeys map[string]*core.SigningKeys
+	listCalls   int
+}
+
+func (f *fakeProviderService) GetProvider(_ context.Context, _, _, _, _, _ string) (*core.Provider, error) {
+	return nil, core.ErrObjectNotFound
+}
+
+func (f *fakeProviderService) ListProviderVersions(_ context.Context, namespace, name string) (*core.ProviderVersions, error) {
+	v, ok := f.versions[namespace+"/"+name]
+	if !ok {
+		return nil, &core.ProviderError{Reason: "failed to find matching providers", Provider: &core.Provider{Namespace: namespace, Name: name}, StatusCode: http.StatusNotFound}
+	}
+	return v, nil
+}
+
+func (f *fakeProviderService) ListProviders(_ context.Context) ([]core.Provider, error) {
+	f.listCalls++
+	return f.providers, nil
+}
+
+func (f *fakeProviderService) GetSigningKeys(_ context.Context, namespace string) (*core.SigningKeys, error) {
+	keys, ok := f.signingKeys[namespace]
+	if !ok {
+		return nil, core.ErrObjectNotFound
+	}
+	return keys, nil
+}
+
+func (f *fakeProviderService) GetProviderDownloadsSummary(_ context.Context, _, _ string) (*core.DownloadSummary, error) {
+	return &core.DownloadSummary{}, nil
+}
+
+type fakeCatalogService []mirror.MirroredProvider
+
+func (f fakeCatalogService) ListMirroredProviders(_ context.Context) ([]mirror.MirroredProvider, error) {
+	return f, nil
+}
+
+func newTestService(t *testing.T) Service {
+	ctx := context.Background()
+	modules := module.NewInmemStorage()
+	for _, m := range []core.Module{
+		{Namespace: "acme", Name: "vpc", Provider: "aws", Version: "1.0.0"},
+		{Namespace: "acme", Name: "vpc", Provider: "aws", Version: "1.10.0"},
+		{Namespace: "acme", Name: "vpc", Provider: "aws", Version: "1.9.0"},
+		{Namespace: "platform", Name: "bucket", Provider: "gcp", Version: "0.1.0"},
+	} {
+		_, err := modules.UploadModule(ctx, m.Namespace, m.Name, m.Provider, m.Version, "", strings.NewReader("data"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, modules.UpdateModuleAnnotations(ctx, "acme", "vpc", "aws", func(annotations core.VersionAnnotations) {
+		annotations["1.0.0"] = core.Annotations{Deprecation: &core.Deprecation{Reason: "<b>use 1.10.0</b>"}}
+	}))
+
+	providers := &fakeProviderService{
+		providers: []core.Provider{{Namespace: "acme", Name: "dummy"}},
+		versions: map[string]*core.ProviderVersions{
+			"acme/dummy": {Versions: []core.ProviderVersion{
+				{Namespace: "acme", Name: "dummy", Version: "0.9.0", Protocols: []string{"5.0"}, Platforms: []core.Platform{{OS: "linux", Arch: "amd64"}}},
+				{Namespace: "acme", Name: "dummy", Version: "1.0.0", Protocols: []string{"5.0"}, Platforms: []core.Platform{{OS: "darwin", Arch: "arm64"}}},
+			}},
+		},
+		signingKeys: map[string]*core.SigningKeys{
+			"acme": {GPGPublicKeys: []core.GPGPublicKey{{KeyID: "51852D87348FFC4C", Source: "ACME"}}},
+		},
+	}
+
+	catalog := fakeCatalogService{
+		{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "random", Versions: []mirror.MirroredVersion{{Version: "3.6.2", Platforms: []core.Platform{{OS: "linux", Arch: "amd64"}}}}},
+	}
+
+	return NewService(module.NewService(modules, core.NewProxyUrlService(false, "")), providers, catalog)
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	namespaces, err := svc.ListNamespaces(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []Namespace{
+		{
+			Name:      "acme",
+			Modules:   []core.Module{{Namespace: "acme", Name: "vpc", Provider: "aws"}},
+			Providers: []core.Provider{{Namespace: "acme", Name: "dummy"}},
+		},
+		{
+			Name:    "platform",
+			Modules: []core.Module{{Namespace: "platform", Name: "bucket", Provider: "gcp"}},
+		},
+	}, namespaces)
+
+	ns, err := svc.GetNamespace(ctx, "platform")
+	assert.NoError(t, err)
+	assert.Equal(t, &namespaces[1], ns)
+
+	_, err = svc.GetNamespace(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	m, err := svc.GetModule(ctx, "acme", "vpc", "aws")
+	assert.NoError(t, err)
+	var versions []string
+	for _, v := range m.Versions {
+		versions = append(versions, v.Version)
+	}
+	assert.Equal(t, []string{"1.10.0", "1.9.0", "1.0.0"}, versions)
+
+	p, err := svc.GetProvider(ctx, "acme", "dummy")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0", p.Versions[0].Version)
+	assert.Equal(t, "51852D87348FFC4C", p.SigningKeys[0].KeyID)
+
+	_, err = svc.GetProvider(ctx, "acme", "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	hosts, err := svc.ListMirroredProviders(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, hosts, 1)
+	assert.Equal(t, "registry.terraform.io", hosts[0].Hostname)
+
+	_, err = NewService(nil, nil, nil).ListMirroredProviders(ctx)
+	assert.ErrorIs(t, err, ErrMirrorDisabled)
+}
+
+func TestService_ListNamespaces(t *testing.T) {
+	ctx := context.Background()
+	providers := &fakeProviderService{providers: []core.Provider{{Namespace: "acme", Name: "dummy"}}}
+	svc := NewService(module.NewService(module.NewInmemStorage(), core.NewProxyUrlService(false, "")), providers, nil).(*service)
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	_, err := svc.ListNamespaces(ctx)
+	assert.NoError(t, err)
+
+	// The namespaces are cached, so that the storage isn't listed for every page
+	now = now.Add(namespacesTTL - time.Second)
+	_, err = svc.GetNamespace(ctx, "acme")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, providers.listCalls)
+
+	now = now.Add(time.Second)
+	_, err = svc.ListNamespaces(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, providers.listCalls)
+}
+
+func TestMakeHandler(t *testing.T) {
+	handler := http.StripPrefix("/ui", MakeHandler(
+		newTestService(t),
+		auth.Middleware(auth.NewStaticProvider("secret")),
+		o11y.NewMiddleware(o11y.NewMetrics(nil).Http),
+		"/ui",
+		false,
+	))
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("redirect to login", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/ui/modules/acme/vpc/aws", nil))
+		assert.Equal(t, http.StatusSeeOther, rec.Code)
+		assert.Equal(t, "/ui/login?next=%2Fui%2Fmodules%2Facme%2Fvpc%2Faws", rec.Header().Get("Location"))
+	})
+
+	t.Run("login with invalid token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/ui/login", strings.NewReader(url.Values{"token": {"invalid"}, "next": {"/ui/mirror"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := serve(req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "The token was rejected.")
+		assert.Contains(t, rec.Body.String(), `value="/ui/mirror"`)
+	})
+
+	t.Run("login with open redirect", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/ui/login", strings.NewReader(url.Values{"token": {"secret"}, "next": {"//evil.example.com/ui/"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := serve(req)
+		assert.Equal(t, http.StatusSeeOther, rec.Code)
+		assert.Equal(t, "/ui/", rec.Header().Get("Location"))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/ui/login", strings.NewReader(url.Values{"token": {"secret"}, "next": {"/ui/modules/acme/vpc/aws"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := serve(req)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/ui/modules/acme/vpc/aws", rec.Header().Get("Location"))
+	cookies := rec.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.False(t, cookies[0].Secure)
+	assert.Equal(t, "/ui/", cookies[0].Path)
+
+	t.Run("login behind a proxy that terminates TLS", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/ui/login", strings.NewReader(url.Values{"token": {"secret"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rec := serve(req)
+		assert.Equal(t, http.StatusSeeOther, rec.Code)
+		assert.True(t, rec.Result().Cookies()[0].Secure)
+	})
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name:           "namespaces",
+			path:           "/ui/",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`<a href="/ui/namespaces/acme">acme</a>`, `<a href="/ui/namespaces/platform">platform</a>`, "Log out"},
+		},
+		{
+			name:           "namespace",
+			path:           "/ui/namespaces/acme",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`href="/ui/modules/acme/vpc/aws"`, `href="/ui/providers/acme/dummy"`},
+		},
+		{
+			name:           "module",
+			path:           "/ui/modules/acme/vpc/aws",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`source  = "registry.example.com/acme/vpc/aws"`, `version = "1.10.0"`, `&lt;b&gt;use 1.10.0&lt;/b&gt;`},
+		},
+		{
+			name:           "provider",
+			path:           "/ui/providers/acme/dummy",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`source  = "registry.example.com/acme/dummy"`, `version = "1.0.0"`, "darwin_arm64", "51852D87348FFC4C"},
+		},
+		{
+			name:           "mirror",
+			path:           "/ui/mirror",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`source  = "registry.terraform.io/hashicorp/random"`, "linux_amd64"},
+		},
+		{
+			name:           "unknown provider",
+			path:           "/ui/providers/acme/unknown",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "static files",
+			path:           "/ui/static/style.css",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Host = "registry.example.com"
+			req.AddCookie(cookies[0])
+			rec := serve(req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			for _, expected := range tc.expectedBody {
+				assert.Contains(t, rec.Body.String(), expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package ui
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/boring-registry/boring-registry/pkg/core"
+	o11y "github.com/boring-registry/boring-registry/pkg/observability"
+
+	"github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+type muxVar string
+
+type contextKey string
+
+const (
+	varNamespace muxVar = "namespace"
+	varName      muxVar = "name"
+	varProvider  muxVar = "provider"
+
+	// loggedInKey is set in the context of requests with a token cookie
+	loggedInKey contextKey = "logged-in"
+
+	// tokenCookieName is the name of the cookie with the token that's set by the login form
+	tokenCookieName = "boring-registry-token"
+)
+
+var (
+	//go:embed templates
+	templateFS embed.FS
+	//go:embed static
+	staticFS embed.FS
+
+	templates = parseTemplates()
+)
+
+// parseTemplates parses every page together with the layout
+func parseTemplates() map[string]*template.Template {
+	pages, err := fs.Glob(templateFS, "templates/*.html")
+	if err != nil {
+		panic(err)
+	}
+
+	t := map[string]*template.Template{}
+	for _, page := range pages {
+		name := strings.TrimPrefix(page, "templates/")
+		if name == "layout.html" {
+			continue
+		}
+		t[name] = template.Must(template.New(name).ParseFS(templateFS, "templates/layout.html", page))
+	}
+	return t
+}
+
+// MakeHandler returns a fully initialized http.Handler.
+// The root is the path the handler is served at, e.g. /ui, which is used for links and redirects.
+// The session cookie is only marked as Secure for requests over TLS, unless secureCookie is set,
+// e.g. if TLS is terminated by a proxy that doesn't set the X-Forwarded-Proto header.
+func MakeHandler(svc Service, auth endpoint.Middleware, instrumentation o11y.Middleware, root string, secureCookie bool, options ...httptransport.ServerOption) http.Handler {
+	r := mux.NewRouter().StrictSlash(true)
+
+	root = strings.TrimSuffix(root, "/")
+	options = append(
+		options,
+		httptransport.ServerBefore(httptransport.PopulateRequestContext),
+		httptransport.ServerBefore(jwt.HTTPToContext()),
+		httptransport.ServerBefore(cookieToContext()),
+		httptransport.ServerErrorEncoder(errorEncoder(root)),
+	)
+
+	handle := func(method, path string, e endpoint.Endpoint, dec httptransport.DecodeRequestFunc, enc httptransport.EncodeResponseFunc, vars ...muxVar) {
+		r.Methods(method).Path(path).Handler(
+			instrumentation.WrapHandler(
+				httptransport.NewServer(
+					e,
+					dec,
+					enc,
+					append(options, httptransport.ServerBefore(extractMuxVars(vars...)))...,
+				),
+			),
+		)
+	}
+
+	encode := encodePage(root)
+	handle("GET", "/", auth(namespacesEndpoint(svc)), decodeEmptyRequest, encode)
+	handle("GET", "/namespaces/{namespace}", auth(namespaceEndpoint(svc)), decodeNamespaceRequest, encode, varNamespace)
+	handle("GET", "/modules/{namespace}/{name}/{provider}", auth(moduleEndpoint(svc)), decodeModuleRequest, encode, varNamespace, varName, varProvider)
+	handle("GET", "/providers/{namespace}/{name}", auth(providerEndpoint(svc)), decodeProviderRequest, encode, varNamespace, varName)
+	handle("GET", "/mirror", auth(mirrorEndpoint(svc)), decodeEmptyRequest, encode)
+
+	// The login isn't protected, as it verifies the token itself
+	handle("GET", "/login", loginFormEndpoint(), decodeLoginRequest(root, secureCookie), encode)
+	handle("POST", "/login", loginEndpoint(auth), decodeLoginRequest(root, secureCookie), encodeLoginResponse(root))
+	r.Methods("POST").Path("/logout").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: tokenCookieName, Path: root + "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		http.Redirect(w, r, root+"/", http.StatusSeeOther)
+	})
+
+	r.Methods("GET").PathPrefix("/static/").Handler(http.FileServer(http.FS(staticFS)))
+
+	return r
+}
+
+func decodeEmptyRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeNamespaceRequest(ctx context.Context, _ *http.Request) (interface{}, error) {
+	namespace, ok := ctx.Value(varNamespace).(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: namespace", core.ErrVarMissing)
+	}
+	return namespaceRequest{namespace: namespace}, nil
+}
+
+func decodeModuleRequest(ctx context.Context, _ *http.Request) (interface{}, error) {
+	var req moduleRequest
+	for k, v := range map[muxVar]*string{varNamespace: &req.namespace, varName: &req.name, varProvider: &req.provider} {
+		s, ok := ctx.Value(k).(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", core.ErrVarMissing, k)
+		}
+		*v = s
+	}
+	return req, nil
+}
+
+func decodeProviderRequest(ctx context.Context, _ *http.Request) (interface{}, error) {
+	var req providerRequest
+	for k, v := range map[muxVar]*string{varNamespace: &req.namespace, varName: &req.name} {
+		s, ok := ctx.Value(k).(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", core.ErrVarMissing, k)
+		}
+		*v = s
+	}
+	return req, nil
+}
+
+func decodeLoginRequest(root string, secureCookie bool) httptransport.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		req := loginRequest{
+			next:   safeRedirect(root, r.FormValue("next")),
+			secure: secureCookie || isTLS(r),
+		}
+		if r.Method == http.MethodPost {
+			req.token = strings.TrimSpace(r.PostFormValue("token"))
+		}
+		return req, nil
+	}
+}
+
+// isTLS reports whether the request was sent over TLS, either to the server or to a proxy in front of it
+func isTLS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
+
+// view is the data of the layout, the data of the page is in Data
+type view struct {
+	Root     string
+	Title    string
+	Host     string
+	LoggedIn bool
+	Data     interface{}
+}
+
+func newView(ctx context.Context, root, title string, data interface{}) view {
+	host, _ := ctx.Value(httptransport.ContextKeyRequestHost).(string)
+	loggedIn, _ := ctx.Value(loggedInKey).(bool)
+	return view{Root: root, Title: title, Host: host, LoggedIn: loggedIn, Data: data}
+}
+
+func renderPage(w http.ResponseWriter, status int, page string, v view) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	return templates[page].ExecuteTemplate(w, "layout", v)
+}
+
+func encodePage(root string) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		res := response.(pageResponse)
+		return renderPage(w, http.StatusOK, res.page, newView(ctx, root, res.title, res.data))
+	}
+}
+
+func encodeLoginResponse(root string) httptransport.EncodeResponseFunc {
+	return func(_ context.Context, w http.ResponseWriter, response interface{}) error {
+		res := response.(loginResponse)
+		http.SetCookie(w, &http.Cookie{
+			Name:     tokenCookieName,
+			Value:    res.token,
+			Path:     root + "/",
+			HttpOnly: true,
+			Secure:   res.secure,
+			SameSite: http.SameSiteStrictMode,
+		})
+		w.Header().Set("Location", res.next)
+		w.WriteHeader(http.StatusSeeOther)
+		return nil
+	}
+}
+
+// errorEncoder renders the error pages and redirects unauthorized requests to the login form
+func errorEncoder(root string) httptransport.ErrorEncoder {
+	return func(ctx context.Context, err error, w http.ResponseWriter) {
+		var loginErr loginError
+		var providerErr *core.ProviderError
+		switch {
+		case errors.As(err, &loginErr):
+			_ = renderPage(w, http.StatusUnauthorized, pageLogin, newView(ctx, root, "Login", loginPage{Next: loginErr.next, Error: "The token was rejected."}))
+
+		case errors.Is(err, core.ErrUnauthorized) || errors.Is(err, core.ErrInvalidToken):
+			uri, _ := ctx.Value(httptransport.ContextKeyRequestURI).(string)
+			w.Header().Set("Location", fmt.Sprintf("%s/login?next=%s", root, url.QueryEscape(safeRedirect(root, uri))))
+			w.WriteHeader(http.StatusSeeOther)
+
+		case errors.Is(err, ErrNotFound) || errors.Is(err, ErrMirrorDisabled) || (errors.As(err, &providerErr) && providerErr.StatusCode == http.StatusNotFound):
+			_ = renderPage(w, http.StatusNotFound, pageError, newView(ctx, root, "Not found", err.Error()))
+
+		case errors.Is(err, core.ErrVarMissing):
+			_ = renderPage(w, http.StatusBadRequest, pageError, newView(ctx, root, "Bad request", err.Error()))
+
+		default:
+			// Internal errors aren't shown, as they can contain details of the storage
+			slog.Error("failed to render page", slog.String("err", err.Error()))
+			_ = renderPage(w, http.StatusInternalServerError, pageError, newView(ctx, root, "Error", "The page could not be loaded."))
+		}
+	}
+}
+
+// safeRedirect returns the target if it's a page of the UI, and the root page otherwise
+func safeRedirect(root, target string) string {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, root+"/") || strings.HasPrefix(target, "//") || strings.Contains(target, `\`) {
+		return root + "/"
+	}
+	return target
+}
+
+// cookieToContext moves the token of the login form into the context, unless the request has an Authorization header
+func cookieToContext() httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		c, err := r.Cookie(tokenCookieName)
+		if err != nil || c.Value == "" {
+			return ctx
+		}
+
+		ctx = context.WithValue(ctx, loggedInKey, true)
+		if _, ok := ctx.Value(jwt.JWTContextKey).(string); !ok {
+			ctx = context.WithValue(ctx, jwt.JWTContextKey, c.Value)
+		}
+		return ctx
+	}
+}
+
+func extractMuxVars(keys ...muxVar) httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		for _, k := range keys {
+			if v, ok := mux.Vars(r)[string(k)]; ok {
+				ctx = context.WithValue(ctx, k, v)
+			}
+		}
+
+		return ctx
+	}
+}